@@ -0,0 +1,527 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// abChatTimeout bounds one reply, prompt processing included.
+const abChatTimeout = 10 * time.Minute
+
+// chatTurn is a message of an A/B conversation, in the chat API's shape.
+type chatTurn struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	// A reply's latency and speed, shown under it
+	firstToken      time.Duration
+	elapsed         time.Duration
+	tokens          int
+	tokensPerSecond float64
+	// replaced is how many earlier exchanges a summary stands in for
+	replaced int
+}
+
+// stats sums up a reply's latency and speed.
+func (t chatTurn) stats() string {
+	stats := []string{fmt.Sprintf("first token %.2fs", t.firstToken.Seconds()), fmt.Sprintf("total %.2fs", t.elapsed.Seconds())}
+	if t.tokens > 0 {
+		stats = append(stats, pluralize(t.tokens, "token"))
+	}
+	if t.tokensPerSecond > 0 {
+		stats = append(stats, fmt.Sprintf("%.1f t/s", t.tokensPerSecond))
+	}
+	return strings.Join(stats, " · ")
+}
+
+// averageStats sums up a side's replies so far.
+func (s abSide) averageStats() string {
+	var n, rated int
+	var firstToken time.Duration
+	var tps float64
+	for _, t := range s.turns {
+		if t.Role != "assistant" {
+			continue
+		}
+		n++
+		firstToken += t.firstToken
+		if t.tokensPerSecond > 0 {
+			rated++
+			tps += t.tokensPerSecond
+		}
+	}
+	if n == 0 {
+		return ""
+	}
+	replies := fmt.Sprintf("%d replies", n)
+	if n == 1 {
+		replies = "1 reply"
+	}
+	stats := []string{replies, fmt.Sprintf("avg first token %.2fs", (firstToken / time.Duration(n)).Seconds())}
+	if rated > 0 {
+		stats = append(stats, fmt.Sprintf("avg %.1f t/s", tps/float64(rated)))
+	}
+	return strings.Join(stats, " · ")
+}
+
+// abChunk is a piece of one side's streamed reply.
+type abChunk struct {
+	text      string
+	reasoning string
+	// From the last chunk: the completion's token count, and
+	// llama-server's own generation speed when it reports timings
+	tokens          int
+	tokensPerSecond float64
+	// promptTokens is the server's count of the conversation it was sent
+	promptTokens int
+	// summary replaced the summarized turns dropped to fit the context,
+	// or summaryErr says why it couldn't
+	summary    string
+	summarized int
+	summaryErr error
+	err        error
+}
+
+// abSide is one server in the A/B chat and its side of the conversation;
+// replies differ, so each keeps its own history.
+type abSide struct {
+	model string
+	port  string
+	color int
+	turns []chatTurn
+	// The reply being streamed
+	reply, reasoning string
+	ch               chan abChunk
+	sent             time.Time
+	firstToken       time.Duration
+	tokens           int
+	tokensPerSecond  float64
+	promptTokens     int
+	err              error
+	// nCtx is the server's context per conversation, 0 until known, and
+	// used how much of it the conversation takes
+	nCtx int
+	used int
+	// notice tells how the conversation was cut to fit the context
+	notice string
+}
+
+func (s abSide) streaming() bool {
+	return s.ch != nil
+}
+
+// abChat is the A/B overlay: one prompt goes to two running servers and
+// their replies stream side by side.
+type abChat struct {
+	input  textinput.Model
+	sides  [2]abSide
+	cancel context.CancelFunc
+	// warning holds back a prompt that would overflow a context until
+	// [enter] is pressed again for it
+	warning string
+	warned  string
+}
+
+// abCandidates are the servers that can answer now: the managed server
+// and ready side servers, with the managed one first.
+func (m appModel) abCandidates() []abSide {
+	var sides []abSide
+	if m.server == serverReady && m.attached == nil {
+		sides = append(sides, abSide{model: m.currentModelName, port: m.currentPort, color: m.serverColor})
+	}
+	for _, s := range m.sideServers {
+		if s.state == serverReady {
+			sides = append(sides, abSide{model: s.item.name, port: s.port, color: s.color})
+		}
+	}
+	return sides
+}
+
+// openABChat compares the server on port ("" for the managed one) with
+// another ready server, the managed one on the left when it takes part.
+func (m appModel) openABChat(port string) (appModel, tea.Cmd) {
+	candidates := m.abCandidates()
+	if len(candidates) < 2 {
+		m.statusLineText = "A/B chat needs two ready servers - [enter] on another model starts one beside the served one"
+		return m, nil
+	}
+	if port == "" {
+		port = m.currentPort
+	}
+	first := 0
+	for i, c := range candidates {
+		if c.port == port {
+			first = i
+		}
+	}
+	second := 0
+	if first == 0 {
+		second = 1
+	}
+	if second < first {
+		first, second = second, first
+	}
+	input := textinput.New()
+	input.Prompt = "> "
+	input.Placeholder = "prompt for both models"
+	input.CharLimit = 4000
+	input.Focus()
+	m.portInput.Blur()
+	m.abChat = &abChat{input: input, sides: [2]abSide{candidates[first], candidates[second]}}
+	return m, tea.Batch(fetchChatContextCmd(candidates[first].port), fetchChatContextCmd(candidates[second].port))
+}
+
+func (m appModel) handleABChatKey(msg tea.KeyMsg) (appModel, tea.Cmd) {
+	v := *m.abChat
+	switch msg.String() {
+	case "esc":
+		if v.cancel != nil {
+			v.cancel()
+		}
+		m.abChat = nil
+		return m, nil
+	case "ctrl+l":
+		// A new conversation; replies still streaming are dropped
+		if v.cancel != nil {
+			v.cancel()
+			v.cancel = nil
+		}
+		for i := range v.sides {
+			s := &v.sides[i]
+			s.turns, s.ch, s.reply, s.reasoning, s.err = nil, nil, "", "", nil
+			s.used, s.notice = 0, ""
+		}
+		v.warning, v.warned = "", ""
+		m.abChat = &v
+		return m, nil
+	case "enter":
+		return m.sendABPrompt(v)
+	}
+	var cmd tea.Cmd
+	v.input, cmd = v.input.Update(msg)
+	m.abChat = &v
+	return m, cmd
+}
+
+// sendABPrompt sends the typed prompt to both servers.
+func (m appModel) sendABPrompt(v abChat) (appModel, tea.Cmd) {
+	prompt := strings.TrimSpace(v.input.Value())
+	if prompt == "" || v.sides[0].streaming() || v.sides[1].streaming() {
+		m.abChat = &v
+		return m, nil
+	}
+	// Make room in each context first; in "warn" mode a second [enter]
+	// sends the prompt as it is
+	policy := m.config.ChatContext
+	sides := v.sides
+	var summarize [2][]chatTurn
+	for i := range sides {
+		sides[i].notice = ""
+		dropped, warning := policy.fitContext(&sides[i], prompt)
+		if warning != "" && !(policy.strategy() == "warn" && v.warned == prompt) {
+			v.warning, v.warned = warning, prompt
+			m.abChat = &v
+			return m, nil
+		}
+		summarize[i] = dropped
+	}
+	v.sides = sides
+	v.warning, v.warned = "", ""
+	if v.cancel != nil {
+		v.cancel()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), abChatTimeout)
+	v.cancel = cancel
+	v.input.SetValue("")
+	var cmds []tea.Cmd
+	for i := range v.sides {
+		s := &v.sides[i]
+		s.turns = append(append([]chatTurn(nil), s.turns...), chatTurn{Role: "user", Content: prompt})
+		s.reply, s.reasoning, s.err = "", "", nil
+		s.firstToken, s.tokens, s.tokensPerSecond, s.promptTokens = 0, 0, 0, 0
+		s.ch = make(chan abChunk, 64)
+		s.sent = time.Now()
+		cmds = append(cmds, startABStreamCmd(ctx, s.port, s.turns, summarize[i], summaryTokens(s.nCtx), s.ch), waitForABChunk(i, s.ch))
+	}
+	m.abChat = &v
+	return m, tea.Batch(cmds...)
+}
+
+// streamABReply streams a chat completion of turns from the server on
+// port into out, closing it when the reply ends. Turns dropped to fit the
+// context are summarized first when given.
+func streamABReply(ctx context.Context, port string, turns, summarize []chatTurn, summaryMax int, out chan<- abChunk) {
+	defer close(out)
+	// A closed chat stops reading; the canceled context lets this go too
+	send := func(c abChunk) {
+		select {
+		case out <- c:
+		case <-ctx.Done():
+		}
+	}
+	if len(summarize) > 0 {
+		summary, err := summarizeTurns(ctx, port, summarize, summaryMax)
+		if err != nil {
+			send(abChunk{summarized: exchanges(summarize), summaryErr: err})
+		} else {
+			send(abChunk{summary: summary, summarized: exchanges(summarize)})
+			turns = append([]chatTurn{summaryTurn(summary, exchanges(summarize))}, turns...)
+		}
+	}
+	body, err := json.Marshal(map[string]any{
+		"messages":       turns,
+		"stream":         true,
+		"stream_options": map[string]bool{"include_usage": true},
+	})
+	if err != nil {
+		send(abChunk{err: err})
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://127.0.0.1:"+port+"/v1/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		send(abChunk{err: err})
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		send(abChunk{err: err})
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		send(abChunk{err: fmt.Errorf("request failed: %s", resp.Status)})
+		return
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			return
+		}
+		var event struct {
+			Choices []struct {
+				Delta struct {
+					Content          string `json:"content"`
+					ReasoningContent string `json:"reasoning_content"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Usage *struct {
+				PromptTokens     int `json:"prompt_tokens"`
+				CompletionTokens int `json:"completion_tokens"`
+			} `json:"usage"`
+			Timings *struct {
+				PredictedPerSecond float64 `json:"predicted_per_second"`
+			} `json:"timings"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			continue
+		}
+		var chunk abChunk
+		if event.Error != nil {
+			chunk.err = fmt.Errorf("server error: %s", event.Error.Message)
+		}
+		for _, c := range event.Choices {
+			chunk.text += c.Delta.Content
+			chunk.reasoning += c.Delta.ReasoningContent
+		}
+		if event.Usage != nil {
+			chunk.tokens = event.Usage.CompletionTokens
+			chunk.promptTokens = event.Usage.PromptTokens
+		}
+		if event.Timings != nil {
+			chunk.tokensPerSecond = event.Timings.PredictedPerSecond
+		}
+		send(chunk)
+	}
+	if err := scanner.Err(); err != nil {
+		send(abChunk{err: err})
+	}
+}
+
+func startABStreamCmd(ctx context.Context, port string, turns, summarize []chatTurn, summaryMax int, out chan abChunk) tea.Cmd {
+	return func() tea.Msg {
+		go streamABReply(ctx, port, turns, summarize, summaryMax, out)
+		return nil
+	}
+}
+
+func waitForABChunk(side int, ch chan abChunk) tea.Cmd {
+	return func() tea.Msg {
+		chunk, ok := <-ch
+		if !ok {
+			return abDoneMsg{side: side, ch: ch}
+		}
+		return abChunkMsg{side: side, ch: ch, chunk: chunk}
+	}
+}
+
+// handleABChunk adds a piece of a reply, timing the first one.
+func (m appModel) handleABChunk(msg abChunkMsg) (appModel, tea.Cmd) {
+	if m.abChat == nil || m.abChat.sides[msg.side].ch != msg.ch {
+		return m, nil
+	}
+	v := *m.abChat
+	s := &v.sides[msg.side]
+	c := msg.chunk
+	if c.err != nil {
+		s.err = c.err
+	}
+	switch {
+	case c.summary != "":
+		summary := summaryTurn(c.summary, c.summarized)
+		summary.tokens = estimateTokens(summary.Content)
+		s.turns = append([]chatTurn{summary}, s.turns...)
+		s.used += summary.tokens
+		s.notice = fmt.Sprintf("summarized %s to fit the context", pluralize(c.summarized, "earlier exchange"))
+	case c.summaryErr != nil:
+		s.notice = fmt.Sprintf("dropped %s to fit the context (no summary: %v)", pluralize(c.summarized, "earlier exchange"), c.summaryErr)
+	}
+	if c.promptTokens > 0 {
+		s.promptTokens = c.promptTokens
+	}
+	if (c.text != "" || c.reasoning != "") && s.firstToken == 0 {
+		s.firstToken = time.Since(s.sent)
+	}
+	s.reply += c.text
+	s.reasoning += c.reasoning
+	if c.tokens > 0 {
+		s.tokens = c.tokens
+	}
+	if c.tokensPerSecond > 0 {
+		s.tokensPerSecond = c.tokensPerSecond
+	}
+	m.abChat = &v
+	return m, waitForABChunk(msg.side, msg.ch)
+}
+
+// handleABDone files a finished reply in its side's conversation with its
+// latency.
+func (m appModel) handleABDone(msg abDoneMsg) appModel {
+	if m.abChat == nil || m.abChat.sides[msg.side].ch != msg.ch {
+		return m
+	}
+	v := *m.abChat
+	s := &v.sides[msg.side]
+	elapsed := time.Since(s.sent)
+	s.ch = nil
+	if s.err != nil {
+		// The prompt stays out of the history the next one is sent with
+		s.turns = s.turns[:len(s.turns)-1]
+		m.abChat = &v
+		return m
+	}
+	tps := s.tokensPerSecond
+	if tps == 0 && s.tokens > 0 && elapsed > s.firstToken {
+		tps = float64(s.tokens) / (elapsed - s.firstToken).Seconds()
+	}
+	// The server's count of the conversation corrects the estimates
+	prompt := &s.turns[len(s.turns)-1]
+	if s.promptTokens > 0 {
+		prompt.tokens = max(s.promptTokens-s.used, 1)
+		s.used = s.promptTokens
+	} else {
+		s.used += prompt.turnTokens()
+	}
+	reply := chatTurn{Role: "assistant", Content: s.reply, firstToken: s.firstToken, elapsed: elapsed, tokens: s.tokens, tokensPerSecond: tps}
+	s.used += reply.turnTokens()
+	s.turns = append(s.turns, reply)
+	s.reply, s.reasoning = "", ""
+	m.abChat = &v
+	return m
+}
+
+// renderABSide draws one column: the server, then the end of its
+// conversation, newest at the bottom.
+func (m appModel) renderABSide(s abSide, width, height int) string {
+	wrap := func(style lipgloss.Style, text string) []string {
+		return strings.Split(style.Width(width).Render(text), "\n")
+	}
+	header := m.instanceStyle(s.color).Render("●") + " " + ellipsize(fmt.Sprintf("%s:%s", m.displayName(s.model), s.port), width-2)
+	status := s.averageStats()
+	if usage := s.contextUsage(); usage != "" {
+		status = strings.TrimPrefix(status+" · "+usage, " · ")
+	}
+	switch {
+	case s.streaming() && s.firstToken == 0:
+		status = "waiting for the first token..."
+	case s.streaming():
+		status = fmt.Sprintf("first token %.2fs · streaming...", s.firstToken.Seconds())
+	}
+	var body []string
+	if s.notice != "" {
+		body = append(body, wrap(m.styles.usageWarn, "["+s.notice+"]")...)
+	}
+	for _, t := range s.turns {
+		if t.Role == "user" {
+			body = append(body, wrap(m.styles.accent, "> "+t.Content)...)
+			continue
+		}
+		if t.Role == "system" {
+			body = append(body, wrap(m.styles.disabled, fmt.Sprintf("[%s, summarized] %s", pluralize(t.replaced, "earlier exchange"), strings.TrimPrefix(t.Content, "Summary of the earlier conversation: ")))...)
+			body = append(body, "")
+			continue
+		}
+		body = append(body, wrap(lipgloss.NewStyle(), strings.TrimSpace(t.Content))...)
+		body = append(body, wrap(m.styles.help, t.stats())...)
+		body = append(body, "")
+	}
+	if s.reasoning != "" && s.reply == "" {
+		body = append(body, wrap(m.styles.disabled, strings.TrimSpace(s.reasoning))...)
+	}
+	if s.reply != "" {
+		body = append(body, wrap(lipgloss.NewStyle(), strings.TrimSpace(s.reply))...)
+	}
+	if s.err != nil {
+		body = append(body, wrap(m.styles.logError, s.err.Error())...)
+	}
+	rows := max(height-3, 1)
+	if len(body) > rows {
+		body = body[len(body)-rows:]
+	}
+	lines := append([]string{header, m.styles.help.Render(ellipsize(status, width)), ""}, body...)
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return lipgloss.NewStyle().Width(width).Render(strings.Join(lines, "\n"))
+}
+
+// renderABChat puts the two conversations side by side over the prompt.
+func (m appModel) renderABChat(width int) string {
+	v := m.abChat
+	height := max(m.height-10, 6)
+	colWidth := max((width-3)/2, 10)
+	if len(v.sides[0].turns) == 0 && !v.sides[0].streaming() {
+		hint := m.styles.disabled.Render("Type a prompt: both servers get it and their replies stream side by side")
+		return hint + "\n\n" + strings.Repeat("\n", height-3) + m.renderABInput(width)
+	}
+	sep := strings.TrimSuffix(strings.Repeat(m.styles.help.Render(" │ ")+"\n", height), "\n")
+	columns := lipgloss.JoinHorizontal(lipgloss.Top, m.renderABSide(v.sides[0], colWidth, height), sep, m.renderABSide(v.sides[1], colWidth, height))
+	return columns + "\n\n" + m.renderABInput(width)
+}
+
+func (m appModel) renderABInput(width int) string {
+	input := m.abChat.input
+	input.Width = max(width-len(input.Prompt)-2, 10)
+	footer := m.styles.help.Render("[enter] send to both  [ctrl+l] new conversation  [esc] close")
+	if w := m.abChat.warning; w != "" {
+		footer = m.styles.usageWarn.Render(ellipsize(w, width))
+	}
+	return input.View() + "\n" + footer
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	psnet "github.com/shirou/gopsutil/v4/net"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+const attachPollInterval = 2 * time.Second
+
+// attachTarget is a llama-server started outside llama-tui and monitored
+// with --attach. pid is 0 when the process listening on the port could not
+// be found (e.g. it belongs to another user), which leaves CPU and memory
+// unknown.
+type attachTarget struct {
+	pid   int32
+	port  string
+	model string
+	// logPath is the server's log file, tailed into the logs panel
+	logPath string
+}
+
+// resolveAttachTarget finds the server named by --attach: "pid:N",
+// "port:N", or a bare number, tried as a listening port first and then as a
+// process ID.
+func resolveAttachTarget(spec string) (attachTarget, error) {
+	kind, value, explicit := strings.Cut(spec, ":")
+	if !explicit {
+		kind, value = "", spec
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return attachTarget{}, fmt.Errorf("%q is not a port or PID", spec)
+	}
+	switch kind {
+	case "port":
+		return attachToPort(value)
+	case "pid":
+		return attachToPID(int32(n))
+	case "":
+		if _, err := validatePort(value); err == nil && !portFree(n) {
+			return attachToPort(value)
+		}
+		if ok, _ := process.PidExists(int32(n)); ok {
+			return attachToPID(int32(n))
+		}
+		return attachTarget{}, fmt.Errorf("nothing is listening on port %d and no process has PID %d", n, n)
+	}
+	return attachTarget{}, fmt.Errorf("%q: use pid:N or port:N", spec)
+}
+
+func attachToPort(port string) (attachTarget, error) {
+	portNum, err := validatePort(port)
+	if err != nil {
+		return attachTarget{}, err
+	}
+	if portFree(portNum) {
+		return attachTarget{}, fmt.Errorf("nothing is listening on port %s", port)
+	}
+	t := attachTarget{port: port, pid: listenerPID(portNum)}
+	t.model = attachModelName(t.pid, port)
+	return t, nil
+}
+
+func attachToPID(pid int32) (attachTarget, error) {
+	proc, err := process.NewProcess(pid)
+	if err != nil {
+		return attachTarget{}, fmt.Errorf("no process with PID %d", pid)
+	}
+	port := ""
+	if argv, err := proc.CmdlineSlice(); err == nil {
+		for _, f := range parseFlagArgs(argv) {
+			if f.name == "--port" {
+				port = f.value
+			}
+		}
+	}
+	if port == "" {
+		conns, _ := psnet.ConnectionsPid("tcp", pid)
+		for _, c := range conns {
+			if c.Status == "LISTEN" {
+				port = strconv.Itoa(int(c.Laddr.Port))
+				break
+			}
+		}
+	}
+	if port == "" {
+		// llama-server's own default
+		port = defaultPort
+	}
+	if _, err := validatePort(port); err != nil {
+		return attachTarget{}, fmt.Errorf("PID %d: %w", pid, err)
+	}
+	return attachTarget{pid: pid, port: port, model: attachModelName(pid, port)}, nil
+}
+
+// listenerPID finds the process listening on port, or 0.
+func listenerPID(port int) int32 {
+	conns, err := psnet.Connections("tcp")
+	if err != nil {
+		return 0
+	}
+	for _, c := range conns {
+		if c.Status == "LISTEN" && int(c.Laddr.Port) == port && c.Pid > 0 {
+			return c.Pid
+		}
+	}
+	return 0
+}
+
+// attachModelName names the served model from the process's command line
+// (--alias, -hf, or the -m file), else from /v1/models.
+func attachModelName(pid int32, port string) string {
+	if pid > 0 {
+		if proc, err := process.NewProcess(pid); err == nil {
+			if argv, err := proc.CmdlineSlice(); err == nil {
+				name := ""
+				for _, f := range parseFlagArgs(argv) {
+					switch f.name {
+					case "--model":
+						if name == "" {
+							name = filepath.Base(f.value)
+						}
+					case "-hf", "--hf-repo":
+						name = "hf:" + f.value
+					case "-a", "--alias":
+						return f.value
+					}
+				}
+				if name != "" {
+					return name
+				}
+			}
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://127.0.0.1:"+port+"/v1/models", nil)
+	if err == nil {
+		if resp, err := http.DefaultClient.Do(req); err == nil {
+			defer resp.Body.Close()
+			var models struct {
+				Data []struct {
+					ID string `json:"id"`
+				} `json:"data"`
+			}
+			if json.NewDecoder(resp.Body).Decode(&models) == nil && len(models.Data) > 0 {
+				return filepath.Base(models.Data[0].ID)
+			}
+		}
+	}
+	return "llama-server"
+}
+
+// attach switches the model to monitoring target instead of managing a
+// server. The monitor takes neither the lock nor the status file, which
+// belong to whoever started the server.
+func (m *appModel) attach(target attachTarget) {
+	if !m.readOnly {
+		releaseLock(m.lockPath)
+	}
+	m.lockPath, m.statusFilePath, m.clientConfigDir = "", "", ""
+	m.readOnly = false
+	m.attached = &target
+	m.currentModelName = target.model
+	m.currentPort = target.port
+	m.serverStartedAt = time.Now()
+	if target.pid > 0 {
+		if proc, err := process.NewProcess(target.pid); err == nil {
+			if created, err := proc.CreateTime(); err == nil {
+				m.serverStartedAt = time.UnixMilli(created)
+			}
+		}
+	}
+	m.proxy.setTarget(target.port)
+	m.statusLineText = fmt.Sprintf("Attached to %s on port %s", target.model, target.port)
+	if target.pid > 0 {
+		m.statusLineText += fmt.Sprintf(" (pid %d)", target.pid)
+	}
+}
+
+// attachHealthCmd polls the attached server's /health after delay. With
+// lookup set the listening process is looked up again, for a server that
+// was restarted.
+func attachHealthCmd(port string, lookup bool, delay time.Duration) tea.Cmd {
+	return tea.Tick(delay, func(time.Time) tea.Msg {
+		client := http.Client{Timeout: 2 * time.Second}
+		resp, err := client.Get("http://127.0.0.1:" + port + "/health")
+		if err != nil {
+			return attachHealthMsg{err: err}
+		}
+		resp.Body.Close()
+		msg := attachHealthMsg{status: resp.StatusCode}
+		if lookup {
+			if portNum, err := strconv.Atoi(port); err == nil {
+				msg.pid = listenerPID(portNum)
+				msg.model = attachModelName(msg.pid, port)
+			}
+		}
+		return msg
+	})
+}
+
+// handleAttachHealth follows the attached server between loading, ready,
+// and gone, starting the usage and client polls whenever it comes up.
+func (m appModel) handleAttachHealth(msg attachHealthMsg) (appModel, tea.Cmd) {
+	if m.attached == nil {
+		return m, nil
+	}
+	prev := m.server
+	switch {
+	case msg.err != nil:
+		m.server = serverStopped
+	case msg.status == http.StatusOK:
+		m.server = serverReady
+	default:
+		// 503 while the model loads
+		m.server = serverLoading
+	}
+	var cmds []tea.Cmd
+	if prev == serverIdle && m.attached.logPath != "" {
+		cmds = append(cmds, m.beginTail(m.attached.logPath))
+	}
+	switch {
+	case prev.serving() && !m.server.serving():
+		m.resetUsage()
+		m.statusLineText = fmt.Sprintf("Server on port %s stopped responding - waiting for it to come back", m.attached.port)
+		m.logEvent(fmt.Sprintf("[attach] Port %s stopped responding: %v", m.attached.port, msg.err))
+	case prev == serverIdle && !m.server.serving():
+		m.statusLineText = fmt.Sprintf("Nothing answers on port %s yet - waiting for it", m.attached.port)
+	case !prev.serving() && m.server.serving():
+		if msg.pid > 0 && msg.pid != m.attached.pid {
+			target := *m.attached
+			target.pid = msg.pid
+			target.model = msg.model
+			m.attached = &target
+			m.currentModelName = target.model
+		}
+		if prev == serverStopped {
+			m.serverStartedAt = time.Now()
+			m.statusLineText = fmt.Sprintf("Server on port %s is back", m.attached.port)
+		}
+		m.logEvent(fmt.Sprintf("[attach] %s is up on port %s", m.currentModelName, m.attached.port))
+		cmds = append(cmds, m.startClientsPoll(m.attached.port))
+		cmds = append(cmds, m.pollResourceUsageCmd())
+		cmds = append(cmds, m.startHealthPoll())
+	}
+	if prev == serverLoading && m.server == serverReady {
+		m.logEvent(fmt.Sprintf("[attach] %s is ready on port %s", m.currentModelName, m.attached.port))
+	}
+	cmds = append(cmds, attachHealthCmd(m.attached.port, !m.server.serving(), attachPollInterval))
+	return m, tea.Batch(cmds...)
+}
+
+// beginTail starts streaming path into the logs panel.
+func (m *appModel) beginTail(path string) tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.tailPath = path
+	m.tailChan = make(chan tailLine, 256)
+	m.tailCancel = cancel
+	m.logEvent("[tail] Following " + path)
+	return tea.Batch(startTailCmd(ctx, path, m.tailChan), waitForTailLine(m.tailChan))
+}
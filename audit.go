@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// The session history doubles as an audit trail: each record carries the
+// hash of the one before it and its own, so an edited, removed, or
+// reordered record breaks the chain. A record's hash is the SHA-256 of its
+// line without the trailing "hash" member.
+
+// sessionsMu keeps appends in order when sessions end together.
+var sessionsMu sync.Mutex
+
+const hashMember = `,"hash":"`
+
+// fingerprintSample is how much of each end of a model file its
+// fingerprint covers.
+const fingerprintSample = 1 << 20
+
+// chainRecord is rec's line chained after prev.
+func chainRecord(rec sessionRecord, prev string) ([]byte, error) {
+	rec.Prev, rec.Hash = prev, ""
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	line := append(data[:len(data)-1:len(data)-1], hashMember+hex.EncodeToString(sum[:])+`"}`...)
+	return line, nil
+}
+
+// splitChainedLine separates a chained line into the bytes its hash
+// covers and the hash, or reports it unchained.
+func splitChainedLine(line []byte) ([]byte, string, bool) {
+	i := bytes.LastIndex(line, []byte(hashMember))
+	if i < 0 || !bytes.HasSuffix(line, []byte(`"}`)) {
+		return nil, "", false
+	}
+	hash := string(line[i+len(hashMember) : len(line)-2])
+	covered := append(append([]byte(nil), line[:i]...), '}')
+	return covered, hash, true
+}
+
+// lastSessionHash is the hash of the history's last chained record.
+func lastSessionHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	last := ""
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 4<<20)
+	for sc.Scan() {
+		if _, hash, ok := splitChainedLine(sc.Bytes()); ok {
+			last = hash
+		}
+	}
+	return last, sc.Err()
+}
+
+// appendSession chains rec onto the history and syncs it to disk.
+func appendSession(path string, rec sessionRecord) error {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	prev, err := lastSessionHash(path)
+	if err != nil {
+		return err
+	}
+	line, err := chainRecord(rec, prev)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// modelChecksum is the SHA-256 of item's file when it is already known,
+// from its download or the blob store; hashing a whole model at every
+// stop would take too long.
+func modelChecksum(item modelItem) string {
+	if item.blob != "" {
+		return item.blob
+	}
+	if item.provenance != nil && item.provenance.SHA256 != "" && (item.provenance.Size == 0 || item.provenance.Size == item.size) {
+		return item.provenance.SHA256
+	}
+	return ""
+}
+
+// fingerprintFile tells versions of a model file apart cheaply: the
+// SHA-256 of its size and its first and last MiB.
+func fingerprintFile(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, "", err
+	}
+	size := info.Size()
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\n", size)
+	if _, err := io.Copy(h, io.LimitReader(f, fingerprintSample)); err != nil {
+		return 0, "", err
+	}
+	if size > 2*fingerprintSample {
+		if _, err := f.Seek(-fingerprintSample, io.SeekEnd); err != nil {
+			return 0, "", err
+		}
+		if _, err := io.Copy(h, f); err != nil {
+			return 0, "", err
+		}
+	}
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// historyCheck is the outcome of verifying the history's chain.
+type historyCheck struct {
+	records []sessionRecord
+	// legacy counts records written before the chain began, which can't
+	// be verified
+	legacy int
+	head   string
+	// broken describes the first line that breaks the chain
+	broken string
+}
+
+// verifyHistory walks the history from its first chained record, checking
+// every hash and link.
+func verifyHistory(path string) (historyCheck, error) {
+	var check historyCheck
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return check, nil
+	}
+	if err != nil {
+		return check, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 4<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		covered, hash, ok := splitChainedLine(line)
+		if !ok {
+			if check.head == "" {
+				check.legacy++
+				continue
+			}
+			check.broken = fmt.Sprintf("line %d is not chained", n)
+			break
+		}
+		var rec sessionRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			check.broken = fmt.Sprintf("line %d does not parse: %v", n, err)
+			break
+		}
+		if sum := sha256.Sum256(covered); hex.EncodeToString(sum[:]) != hash {
+			check.broken = fmt.Sprintf("line %d was changed after it was written (hash mismatch)", n)
+			break
+		}
+		if rec.Prev != check.head {
+			check.broken = fmt.Sprintf("line %d does not follow the record before it (a record was removed or moved)", n)
+			break
+		}
+		check.head = hash
+		check.records = append(check.records, rec)
+	}
+	return check, sc.Err()
+}
+
+func (c historyCheck) summary() string {
+	s := pluralize(len(c.records), "chained session")
+	if c.legacy > 0 {
+		s += fmt.Sprintf(" (after %s from before the chain, not verifiable)", pluralize(c.legacy, "older record"))
+	}
+	if c.head != "" {
+		s += ", head " + c.head
+	}
+	return s
+}
+
+// writeHistoryCSV writes the verified sessions as a spreadsheet.
+func writeHistoryCSV(out io.Writer, records []sessionRecord) error {
+	w := csv.NewWriter(out)
+	_ = w.Write([]string{"start", "end", "model", "path", "sha256", "size", "fingerprint", "port", "preset", "prompt", "prompt_use", "crashed", "args", "hash"})
+	for _, r := range records {
+		size := ""
+		if r.Size > 0 {
+			size = strconv.FormatInt(r.Size, 10)
+		}
+		_ = w.Write([]string{r.Start.Format(time.RFC3339), r.End.Format(time.RFC3339), r.Model, r.Path, r.SHA256, size, r.Fingerprint,
+			r.Port, r.Preset, r.Prompt, r.PromptUse, strconv.FormatBool(r.Crashed), strings.Join(r.Args, " "), r.Hash})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// historyExport is the JSON export: the verified records and the head
+// hash to note down, so later exports can be checked to extend this one.
+type historyExport struct {
+	ExportedAt time.Time       `json:"exported_at"`
+	File       string          `json:"file"`
+	Head       string          `json:"head"`
+	Legacy     int             `json:"unverifiable_records,omitempty"`
+	Sessions   []sessionRecord `json:"sessions"`
+}
+
+// newHistoryCmd verifies and exports the session history.
+func newHistoryCmd() *cobra.Command {
+	var workspace string
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Verify and export the hash-chained session history",
+		Long: "Every server session is appended to sessions.jsonl when it ends, with the model's path, checksum or " +
+			"fingerprint, and command line, chained to the record before it by SHA-256. verify checks the chain; " +
+			"export checks it and writes the sessions for an audit.",
+	}
+	cmd.PersistentFlags().StringVarP(&workspace, "workspace", "w", "", "use a named workspace's history")
+	verify := &cobra.Command{
+		Use:   "verify",
+		Short: "Check that no session record was changed, removed, or reordered",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := sessionsPath(historyDir(workspace))
+			check, err := verifyHistory(path)
+			if err != nil {
+				return err
+			}
+			if check.broken != "" {
+				return fmt.Errorf("%s: chain broken: %s; %s verified before it", path, check.broken, pluralize(len(check.records), "session"))
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", path, check.summary())
+			return nil
+		},
+	}
+	var format, output string
+	var since string
+	export := &cobra.Command{
+		Use:   "export",
+		Short: "Write the verified sessions as JSON or CSV",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "json" && format != "csv" {
+				return fmt.Errorf("--format %q: want json or csv", format)
+			}
+			var from time.Time
+			if since != "" {
+				t, err := time.ParseInLocation("2006-01-02", since, time.Local)
+				if err != nil {
+					return fmt.Errorf("--since %q: want YYYY-MM-DD", since)
+				}
+				from = t
+			}
+			path := sessionsPath(historyDir(workspace))
+			check, err := verifyHistory(path)
+			if err != nil {
+				return err
+			}
+			if check.broken != "" {
+				return fmt.Errorf("%s: chain broken: %s; not exporting", path, check.broken)
+			}
+			records := check.records[:0:0]
+			for _, r := range check.records {
+				if !r.End.Before(from) {
+					records = append(records, r)
+				}
+			}
+			out := cmd.OutOrStdout()
+			if output != "" {
+				f, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				out = f
+			}
+			if format == "csv" {
+				err = writeHistoryCSV(out, records)
+			} else {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				err = enc.Encode(historyExport{ExportedAt: time.Now().UTC(), File: path, Head: check.head, Legacy: check.legacy, Sessions: records})
+			}
+			if err == nil && output != "" {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Exported %s to %s; %s\n", pluralize(len(records), "session"), output, check.summary())
+			}
+			return err
+		},
+	}
+	export.Flags().StringVar(&format, "format", "json", "json or csv")
+	export.Flags().StringVarP(&output, "output", "o", "", "write to this file instead of stdout")
+	export.Flags().StringVar(&since, "since", "", "only sessions ending on or after this date (YYYY-MM-DD)")
+	cmd.AddCommand(verify, export)
+	return cmd
+}
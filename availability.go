@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// pathCheckTimeout bounds reading a model file's first bytes; a
+	// dropped network mount can block the read for minutes.
+	pathCheckTimeout = 3 * time.Second
+	// pathCheckConcurrency is how many models are checked at once, so a
+	// volume of dead files costs one timeout rather than one each
+	pathCheckConcurrency = 16
+)
+
+// checkModelPath tells why item's files can't be read right now, or ""
+// when they can. Models not on disk yet (catalog entries, Hugging Face
+// repos) are never unavailable.
+func checkModelPath(item modelItem) string {
+	if item.remoteURL != "" || item.hfRepo != "" || item.path == "" {
+		return ""
+	}
+	paths := []string{item.path}
+	if item.mmproj != "" {
+		paths = append(paths, item.mmproj)
+	}
+	for _, p := range paths {
+		if reason := checkReadable(p); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+// checkReadable opens path and reads its first byte, giving up after
+// pathCheckTimeout.
+func checkReadable(path string) string {
+	done := make(chan error, 1)
+	go func() {
+		f, err := os.Open(path)
+		if err != nil {
+			done <- err
+			return
+		}
+		defer f.Close()
+		_, err = f.Read(make([]byte, 1))
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		switch {
+		case err == nil:
+			return ""
+		case errors.Is(err, os.ErrNotExist):
+			return "file not found (volume unmounted?)"
+		case errors.Is(err, os.ErrPermission):
+			return "permission denied"
+		}
+		return err.Error()
+	case <-time.After(pathCheckTimeout):
+		return fmt.Sprintf("not responding after %s (network volume down?)", pathCheckTimeout)
+	}
+}
+
+// checkPathsCmd re-validates items' files off the UI loop.
+func checkPathsCmd(items []list.Item) tea.Cmd {
+	if len(items) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		results := make(map[string]string, len(items))
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, pathCheckConcurrency)
+		for _, it := range items {
+			item, ok := it.(modelItem)
+			if !ok || item.remoteURL != "" || item.hfRepo != "" {
+				continue
+			}
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer func() { <-sem; wg.Done() }()
+				reason := checkModelPath(item)
+				mu.Lock()
+				defer mu.Unlock()
+				results[item.path] = reason
+			}()
+		}
+		wg.Wait()
+		return pathsCheckedMsg{results: results}
+	}
+}
+
+// checkSelectedPathCmd validates the selected model when the selection
+// moved off prev, so a missing file shows before launching it.
+func (m appModel) checkSelectedPathCmd(prev string) tea.Cmd {
+	item, ok := m.modelsList.SelectedItem().(modelItem)
+	if !ok || item.path == prev {
+		return nil
+	}
+	return checkPathsCmd([]list.Item{item})
+}
+
+// selectedPath is the path of the selected model, or "".
+func (m appModel) selectedPath() string {
+	if item, ok := m.modelsList.SelectedItem().(modelItem); ok {
+		return item.path
+	}
+	return ""
+}
+
+// withAvailability records check results by path; "" marks a path
+// available again.
+func (m appModel) withAvailability(results map[string]string) appModel {
+	next := make(map[string]string, len(m.unavailable)+len(results))
+	for k, v := range m.unavailable {
+		next[k] = v
+	}
+	changed := false
+	for path, reason := range results {
+		if next[path] == reason {
+			continue
+		}
+		changed = true
+		if reason == "" {
+			delete(next, path)
+		} else {
+			next[path] = reason
+		}
+	}
+	if !changed {
+		return m
+	}
+	m.unavailable = next
+	m.modelsList.SetDelegate(m.listDelegate())
+	return m
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// serverBackends is what the resolved llama-server build can offload to.
+// known is false when the build could not be asked, e.g. an old build
+// without --list-devices that names no backend in its version output.
+type serverBackends struct {
+	known bool
+	// devices are the lines of --list-devices, e.g. "CUDA0: NVIDIA
+	// GeForce RTX 4090 (24080 MiB, 23500 MiB free)"
+	devices []string
+	// names are the GPU backends found, e.g. "CUDA" or "Metal"
+	names []string
+}
+
+func (b serverBackends) gpu() bool {
+	return len(b.names) > 0
+}
+
+func (b serverBackends) describe() string {
+	switch {
+	case !b.known:
+		return "unknown"
+	case len(b.devices) > 0:
+		return strings.Join(b.devices, "; ")
+	case b.gpu():
+		return strings.Join(b.names, ", ")
+	}
+	return "CPU only"
+}
+
+// gpuBackendNames are the GPU backends ggml builds name in their output.
+var gpuBackendNames = []string{"CUDA", "Metal", "Vulkan", "ROCm", "HIP", "SYCL", "OpenCL", "MUSA", "CANN"}
+
+// deviceLine matches an entry under "Available devices:", e.g.
+// "  Vulkan0: AMD Radeon RX 7900 XTX (24560 MiB, 24000 MiB free)".
+var deviceLine = regexp.MustCompile(`^\s+([A-Za-z]+)\d*: (.+)$`)
+
+var (
+	backendsMu    sync.Mutex
+	backendsCache = map[string]serverBackends{}
+)
+
+// detectServerBackends asks bin which devices it can use, once per binary
+// (keyed by path and modification time, so an upgrade is noticed).
+func detectServerBackends(bin string) serverBackends {
+	key := bin
+	if info, err := os.Stat(bin); err == nil {
+		key += "@" + info.ModTime().String()
+	}
+	backendsMu.Lock()
+	cached, ok := backendsCache[key]
+	backendsMu.Unlock()
+	if ok {
+		return cached
+	}
+	b := probeServerBackends(bin)
+	backendsMu.Lock()
+	backendsCache[key] = b
+	backendsMu.Unlock()
+	return b
+}
+
+func probeServerBackends(bin string) serverBackends {
+	run := func(arg string) (string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		out, err := exec.CommandContext(ctx, bin, arg).CombinedOutput()
+		return string(out), err
+	}
+	if out, err := run("--list-devices"); err == nil && strings.Contains(out, "Available devices") {
+		return parseDeviceList(out)
+	}
+	// Older builds: GPU builds name their backend while initializing
+	out, _ := run("--version")
+	b := serverBackends{names: backendNamesIn(out)}
+	b.known = b.gpu()
+	return b
+}
+
+// parseDeviceList reads --list-devices output. CPU-only builds list no
+// devices.
+func parseDeviceList(out string) serverBackends {
+	b := serverBackends{known: true}
+	listing := false
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "Available devices") {
+			listing = true
+			continue
+		}
+		m := deviceLine.FindStringSubmatch(line)
+		if !listing || m == nil {
+			continue
+		}
+		b.devices = append(b.devices, strings.TrimSpace(line))
+		for _, name := range backendNamesIn(m[1]) {
+			if !containsString(b.names, name) {
+				b.names = append(b.names, name)
+			}
+		}
+	}
+	return b
+}
+
+func backendNamesIn(text string) []string {
+	var names []string
+	for _, name := range gpuBackendNames {
+		if strings.Contains(text, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// gpuOnlyFlags only do something when the build can offload.
+var gpuOnlyFlags = map[string]bool{
+	"--n-gpu-layers": true,
+	"--main-gpu":     true, "-mg": true,
+	"--tensor-split": true, "-ts": true,
+	"--split-mode": true, "-sm": true,
+}
+
+// checkBackendFlags warns about GPU options for a build that can't use
+// them, which would otherwise run on the CPU without a word.
+func checkBackendFlags(argv []string, b serverBackends) []string {
+	if !b.known || b.gpu() {
+		return nil
+	}
+	var warnings []string
+	for _, f := range parseFlagArgs(argv) {
+		if !gpuOnlyFlags[f.name] || (f.name == "--n-gpu-layers" && f.value == "0") {
+			continue
+		}
+		warnings = append(warnings, fmt.Sprintf("%s %s has no effect: this llama-server build has no GPU backend, so the model runs on the CPU (install a CUDA, Metal, or Vulkan build)", f.name, f.value))
+	}
+	return warnings
+}
+
+// checkServerBackend reports the devices the build can offload to.
+func checkServerBackend() diagnosticCheck {
+	c := diagnosticCheck{name: "Backend"}
+	bin, err := getLlamaServerBinary()
+	if err != nil {
+		c.detail = "unknown (llama-server not found)"
+		return c
+	}
+	b := detectServerBackends(bin)
+	c.detail = b.describe()
+	if b.known && !b.gpu() {
+		c.status = checkWarn
+		c.hint = "This build can't offload to a GPU; -ngl is ignored. Install a CUDA, Metal, or Vulkan build"
+	}
+	return c
+}
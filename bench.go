@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// benchResult is one llama-bench measurement, accumulated across runs.
+type benchResult struct {
+	Model     string  `json:"model"`
+	Test      string  `json:"test"`
+	Depth     int     `json:"depth"`
+	TokensSec float64 `json:"tokens_per_sec"`
+	Build     string  `json:"build,omitempty"`
+	Time      string  `json:"time"`
+}
+
+// column is the matrix column label, e.g. "pp512@4096".
+func (r benchResult) column() string {
+	return fmt.Sprintf("%s@%d", r.Test, r.Depth)
+}
+
+// llamaBenchRecord is the subset of llama-bench's "-o json" output we use.
+type llamaBenchRecord struct {
+	NPrompt     int     `json:"n_prompt"`
+	NGen        int     `json:"n_gen"`
+	NDepth      int     `json:"n_depth"`
+	AvgTS       float64 `json:"avg_ts"`
+	BuildCommit string  `json:"build_commit"`
+}
+
+// getLlamaBenchBinary resolves llama-bench.
+// Priority:
+// 1) LLAMA_BENCH_BIN environment variable
+// 2) next to the resolved llama-server
+// 3) Look up "llama-bench" in PATH
+func getLlamaBenchBinary() (string, error) {
+	if envPath := strings.TrimSpace(os.Getenv("LLAMA_BENCH_BIN")); envPath != "" {
+		if info, err := os.Stat(envPath); err == nil && !info.IsDir() {
+			return envPath, nil
+		}
+		return "", fmt.Errorf("LLAMA_BENCH_BIN points to an invalid path: %q", envPath)
+	}
+	if server, err := getLlamaServerBinary(); err == nil {
+		sibling := filepath.Join(filepath.Dir(server), "llama-bench")
+		if info, err := os.Stat(sibling); err == nil && !info.IsDir() {
+			return sibling, nil
+		}
+	}
+	bin, err := exec.LookPath("llama-bench")
+	if err != nil {
+		return "", fmt.Errorf("llama-bench not found in PATH. Set LLAMA_BENCH_BIN to its absolute path")
+	}
+	return bin, nil
+}
+
+func benchResultsPath(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "bench-results.json")
+}
+
+func loadBenchResultsCmd(dir string) tea.Cmd {
+	return func() tea.Msg {
+		results, err := loadBenchResults(dir)
+		return benchResultsLoadedMsg{results: results, err: err}
+	}
+}
+
+func loadBenchResults(dir string) ([]benchResult, error) {
+	path := benchResultsPath(dir)
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var results []benchResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return results, nil
+}
+
+func saveBenchResults(dir string, results []benchResult) error {
+	path := benchResultsPath(dir)
+	if path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func (c appConfig) benchDepths() []int {
+	if len(c.BenchDepths) > 0 {
+		return c.BenchDepths
+	}
+	return []int{0, 4096, 16384}
+}
+
+// runBenchCmd runs llama-bench for a model across the configured context
+// depths and appends the results to the accumulated history in dir.
+func runBenchCmd(ctx context.Context, dir string, item modelItem, cfg appConfig) tea.Cmd {
+	return func() tea.Msg {
+		bin, err := getLlamaBenchBinary()
+		if err != nil {
+			return benchDoneMsg{model: item.name, err: err}
+		}
+		depths := make([]string, 0, len(cfg.benchDepths()))
+		for _, d := range cfg.benchDepths() {
+			depths = append(depths, strconv.Itoa(d))
+		}
+		args := []string{"-m", item.path, "-p", "512", "-n", "128", "-d", strings.Join(depths, ","), "-o", "json"}
+		cmd := exec.CommandContext(ctx, bin, args...)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			if ctx.Err() != nil {
+				return benchDoneMsg{model: item.name, err: fmt.Errorf("cancelled")}
+			}
+			detail := strings.TrimSpace(stderr.String())
+			if i := strings.LastIndex(detail, "\n"); i >= 0 {
+				detail = detail[i+1:]
+			}
+			return benchDoneMsg{model: item.name, err: fmt.Errorf("llama-bench failed: %v %s", err, detail)}
+		}
+		var records []llamaBenchRecord
+		if err := json.Unmarshal(out, &records); err != nil {
+			return benchDoneMsg{model: item.name, err: fmt.Errorf("invalid llama-bench output: %w", err)}
+		}
+
+		now := time.Now().Format(time.RFC3339)
+		var fresh []benchResult
+		for _, r := range records {
+			test := fmt.Sprintf("pp%d", r.NPrompt)
+			if r.NGen > 0 {
+				test = fmt.Sprintf("tg%d", r.NGen)
+			}
+			fresh = append(fresh, benchResult{Model: item.name, Test: test, Depth: r.NDepth, TokensSec: r.AvgTS, Build: r.BuildCommit, Time: now})
+		}
+		history, err := loadBenchResults(dir)
+		if err != nil {
+			return benchDoneMsg{model: item.name, results: fresh, err: err}
+		}
+		history = append(history, fresh...)
+		return benchDoneMsg{model: item.name, results: fresh, history: history, err: saveBenchResults(dir, history)}
+	}
+}
+
+// benchMatrix is the latest result per model and column.
+type benchMatrix struct {
+	models  []string
+	columns []string
+	cells   map[string]map[string]float64
+}
+
+func buildBenchMatrix(results []benchResult) benchMatrix {
+	mx := benchMatrix{cells: make(map[string]map[string]float64)}
+	colDepth := make(map[string]int)
+	for _, r := range results {
+		col := r.column()
+		if _, ok := mx.cells[r.Model]; !ok {
+			mx.cells[r.Model] = make(map[string]float64)
+			mx.models = append(mx.models, r.Model)
+		}
+		if _, ok := colDepth[col]; !ok {
+			colDepth[col] = r.Depth
+			mx.columns = append(mx.columns, col)
+		}
+		// Later runs replace earlier ones
+		mx.cells[r.Model][col] = r.TokensSec
+	}
+	sort.Strings(mx.models)
+	// Order columns by depth, prompt processing before generation
+	sort.Slice(mx.columns, func(i, j int) bool {
+		ci, cj := mx.columns[i], mx.columns[j]
+		if colDepth[ci] != colDepth[cj] {
+			return colDepth[ci] < colDepth[cj]
+		}
+		return ci < cj
+	})
+	return mx
+}
+
+func (mx benchMatrix) best(col string) float64 {
+	var best float64
+	for _, row := range mx.cells {
+		if v := row[col]; v > best {
+			best = v
+		}
+	}
+	return best
+}
+
+// renderBenchMatrix draws models × tests in tokens/s with the best value in
+// each column highlighted.
+func (m appModel) renderBenchMatrix() string {
+	mx := buildBenchMatrix(m.benchResults)
+	if len(mx.models) == 0 {
+		return "No benchmark results yet.\n\nSelect a model and press [B] to run llama-bench.\n\nPress [X] or [esc] to close"
+	}
+	nameW := 10
+	for _, name := range mx.models {
+		if w := lipgloss.Width(name); w > nameW {
+			nameW = w
+		}
+	}
+	if nameW > 40 {
+		nameW = 40
+	}
+	const colW = 14
+	var b strings.Builder
+	b.WriteString(m.styles.sectionTitle.Render(fmt.Sprintf("%-*s", nameW, "model")))
+	for _, col := range mx.columns {
+		b.WriteString(m.styles.sectionTitle.Render(fmt.Sprintf("%*s", colW, col)))
+	}
+	b.WriteString("\n")
+	for _, name := range mx.models {
+		b.WriteString(fmt.Sprintf("%-*s", nameW, ellipsize(name, nameW)))
+		for _, col := range mx.columns {
+			v, ok := mx.cells[name][col]
+			if !ok {
+				b.WriteString(m.styles.disabled.Render(fmt.Sprintf("%*s", colW, "-")))
+				continue
+			}
+			cell := fmt.Sprintf("%*.1f", colW, v)
+			if v == mx.best(col) && len(mx.models) > 1 {
+				b.WriteString(m.styles.servingBadge.Render(cell))
+			} else {
+				b.WriteString(cell)
+			}
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n" + m.styles.help.Render("tokens/s (pp = prompt processing, tg = generation, @ = context depth); best per column highlighted"))
+	b.WriteString("\n" + m.styles.help.Render("[e] export CSV  [tab] sweeps  [X] or [esc] close"))
+	return b.String()
+}
+
+// exportBenchCSVCmd writes the matrix as CSV next to the results history.
+func exportBenchCSVCmd(results []benchResult) tea.Cmd {
+	return func() tea.Msg {
+		mx := buildBenchMatrix(results)
+		cacheDir := getCacheDir()
+		if cacheDir == "" {
+			return benchExportedMsg{err: fmt.Errorf("no cache directory available")}
+		}
+		path := filepath.Join(cacheDir, "bench-matrix-"+time.Now().Format("20060102_150405")+".csv")
+		if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+			return benchExportedMsg{err: err}
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return benchExportedMsg{err: err}
+		}
+		w := csv.NewWriter(f)
+		_ = w.Write(append([]string{"model"}, mx.columns...))
+		for _, name := range mx.models {
+			row := []string{name}
+			for _, col := range mx.columns {
+				if v, ok := mx.cells[name][col]; ok {
+					row = append(row, strconv.FormatFloat(v, 'f', 2, 64))
+				} else {
+					row = append(row, "")
+				}
+			}
+			_ = w.Write(row)
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			_ = f.Close()
+			return benchExportedMsg{err: err}
+		}
+		return benchExportedMsg{path: path, err: f.Close()}
+	}
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// benchSweepConfig sets the grid [Z] measures: every batch size at every
+// context depth.
+type benchSweepConfig struct {
+	Depths     []int `json:"depths"`
+	BatchSizes []int `json:"batch_sizes"`
+}
+
+func (c benchSweepConfig) depths() []int {
+	if len(c.Depths) > 0 {
+		return c.Depths
+	}
+	return []int{0, 4096, 16384, 32768}
+}
+
+func (c benchSweepConfig) batchSizes() []int {
+	if len(c.BatchSizes) > 0 {
+		return c.BatchSizes
+	}
+	return []int{256, 512, 1024, 2048}
+}
+
+// sweepPoint is one cell of a sweep: throughput at a batch size and depth.
+type sweepPoint struct {
+	Batch     int     `json:"batch"`
+	Depth     int     `json:"depth"`
+	PromptTPS float64 `json:"prompt_tps"`
+	GenTPS    float64 `json:"gen_tps"`
+}
+
+// benchSweep is one sweep of a model, kept in the sweep history.
+type benchSweep struct {
+	Model  string       `json:"model"`
+	Time   string       `json:"time"`
+	Build  string       `json:"build,omitempty"`
+	Points []sweepPoint `json:"points"`
+}
+
+// sweepRun is a sweep in progress; batches are the sizes still to run.
+type sweepRun struct {
+	item    modelItem
+	ctx     context.Context
+	batches []int
+	total   int
+	sweep   benchSweep
+}
+
+func benchSweepsPath(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "bench-sweeps.json")
+}
+
+func loadBenchSweepsCmd(dir string) tea.Cmd {
+	return func() tea.Msg {
+		sweeps, err := loadBenchSweeps(dir)
+		return benchSweepsLoadedMsg{sweeps: sweeps, err: err}
+	}
+}
+
+func loadBenchSweeps(dir string) ([]benchSweep, error) {
+	path := benchSweepsPath(dir)
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var sweeps []benchSweep
+	if err := json.Unmarshal(data, &sweeps); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return sweeps, nil
+}
+
+// saveBenchSweepCmd appends a finished sweep to the history.
+func saveBenchSweepCmd(dir string, sweep benchSweep) tea.Cmd {
+	return func() tea.Msg {
+		sweeps, err := loadBenchSweeps(dir)
+		if err != nil {
+			return benchSweepSavedMsg{err: err}
+		}
+		sweeps = append(sweeps, sweep)
+		path := benchSweepsPath(dir)
+		if path == "" {
+			return benchSweepSavedMsg{sweeps: sweeps}
+		}
+		data, err := json.MarshalIndent(sweeps, "", "  ")
+		if err != nil {
+			return benchSweepSavedMsg{err: err}
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return benchSweepSavedMsg{err: err}
+		}
+		return benchSweepSavedMsg{sweeps: sweeps, err: os.WriteFile(path, data, 0o644)}
+	}
+}
+
+// sweepStepCmd runs llama-bench at one batch size across the depths.
+func sweepStepCmd(ctx context.Context, item modelItem, batch int, depths []int) tea.Cmd {
+	return func() tea.Msg {
+		msg := benchSweepStepMsg{batch: batch}
+		bin, err := getLlamaBenchBinary()
+		if err != nil {
+			msg.err = err
+			return msg
+		}
+		ds := make([]string, 0, len(depths))
+		for _, d := range depths {
+			ds = append(ds, strconv.Itoa(d))
+		}
+		b := strconv.Itoa(batch)
+		args := []string{"-m", item.path, "-p", "512", "-n", "128", "-b", b, "-ub", b, "-d", strings.Join(ds, ","), "-o", "json"}
+		cmd := exec.CommandContext(ctx, bin, args...)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			if ctx.Err() != nil {
+				msg.err = fmt.Errorf("cancelled")
+				return msg
+			}
+			detail := strings.TrimSpace(stderr.String())
+			if i := strings.LastIndex(detail, "\n"); i >= 0 {
+				detail = detail[i+1:]
+			}
+			msg.err = fmt.Errorf("llama-bench failed at batch %d: %v %s", batch, err, detail)
+			return msg
+		}
+		var records []llamaBenchRecord
+		if err := json.Unmarshal(out, &records); err != nil {
+			msg.err = fmt.Errorf("invalid llama-bench output: %w", err)
+			return msg
+		}
+		byDepth := map[int]*sweepPoint{}
+		for _, r := range records {
+			msg.build = r.BuildCommit
+			p, ok := byDepth[r.NDepth]
+			if !ok {
+				p = &sweepPoint{Batch: batch, Depth: r.NDepth}
+				byDepth[r.NDepth] = p
+			}
+			if r.NGen > 0 {
+				p.GenTPS = r.AvgTS
+			} else {
+				p.PromptTPS = r.AvgTS
+			}
+		}
+		for _, p := range byDepth {
+			msg.points = append(msg.points, *p)
+		}
+		sort.Slice(msg.points, func(i, j int) bool { return msg.points[i].Depth < msg.points[j].Depth })
+		return msg
+	}
+}
+
+// startSweep benchmarks the selected model at every configured batch size,
+// one llama-bench run per size.
+func (m appModel) startSweep() (appModel, tea.Cmd) {
+	if m.readOnly {
+		m.statusLineText = "Read-only: cannot run benchmarks"
+		return m, nil
+	}
+	if m.server.busy() {
+		m.statusLineText = "Stop the server before benchmarking (results would be skewed)"
+		return m, nil
+	}
+	if m.benchCancel != nil {
+		m.statusLineText = "Benchmark of " + m.benchModel + " already running..."
+		return m, nil
+	}
+	item, ok := m.modelsList.SelectedItem().(modelItem)
+	if !ok || item.path == "" {
+		m.statusLineText = "No local model selected"
+		return m, nil
+	}
+	if item.kind == kindWhisper {
+		m.statusLineText = "llama-bench cannot run whisper models"
+		return m, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.benchCancel = cancel
+	m.benchModel = item.name
+	batches := m.config.BenchSweep.batchSizes()
+	m.sweep = &sweepRun{
+		item:    item,
+		ctx:     ctx,
+		batches: batches[1:],
+		total:   len(batches),
+		sweep:   benchSweep{Model: item.name, Time: time.Now().Format(time.RFC3339)},
+	}
+	m.statusLineText = fmt.Sprintf("Sweeping %s: batch %d (1/%d)...", item.name, batches[0], len(batches))
+	m.logEvent(fmt.Sprintf("[sweep] %s: %s × depths %v (this can take a long time)", item.name, pluralize(len(batches), "batch size"), m.config.BenchSweep.depths()))
+	return m, sweepStepCmd(ctx, item, batches[0], m.config.BenchSweep.depths())
+}
+
+// handleSweepStep records a finished step and starts the next, saving the
+// sweep after the last one.
+func (m appModel) handleSweepStep(msg benchSweepStepMsg) (appModel, tea.Cmd) {
+	if m.sweep == nil {
+		return m, nil
+	}
+	run := *m.sweep
+	if msg.err != nil {
+		m.sweep, m.benchCancel, m.benchModel = nil, nil, ""
+		m.statusLineText = fmt.Sprintf("Sweep of %s: %v", run.item.name, msg.err)
+		m.logEvent(fmt.Sprintf("[sweep] ERROR (%s): %v", run.item.name, msg.err))
+		return m, nil
+	}
+	run.sweep.Points = append(append([]sweepPoint(nil), run.sweep.Points...), msg.points...)
+	if msg.build != "" {
+		run.sweep.Build = msg.build
+	}
+	for _, p := range msg.points {
+		m.logEvent(fmt.Sprintf("[sweep] %s b%d@%d: pp %.1f t/s, tg %.1f t/s", run.item.name, p.Batch, p.Depth, p.PromptTPS, p.GenTPS))
+	}
+	if len(run.batches) > 0 {
+		next := run.batches[0]
+		run.batches = run.batches[1:]
+		m.sweep = &run
+		m.statusLineText = fmt.Sprintf("Sweeping %s: batch %d (%d/%d)...", run.item.name, next, run.total-len(run.batches), run.total)
+		return m, sweepStepCmd(run.ctx, run.item, next, m.config.BenchSweep.depths())
+	}
+	m.sweep, m.benchCancel, m.benchModel = nil, nil, ""
+	m.statusLineText = fmt.Sprintf("Sweep of %s done - [X] then [tab] to view it", run.item.name)
+	m.event("bench", run.item.name, fmt.Sprintf("Sweep done: %s", pluralize(len(run.sweep.Points), "point")))
+	return m, saveBenchSweepCmd(m.historyDir, run.sweep)
+}
+
+// latestSweep is the newest sweep of model, else the newest of any.
+func latestSweep(sweeps []benchSweep, model string) (benchSweep, bool) {
+	for i := len(sweeps) - 1; i >= 0; i-- {
+		if sweeps[i].Model == model {
+			return sweeps[i], true
+		}
+	}
+	if len(sweeps) == 0 {
+		return benchSweep{}, false
+	}
+	return sweeps[len(sweeps)-1], true
+}
+
+// renderSweepView draws the latest sweep of the selected model as a table
+// of batch sizes × depths, then a bar chart of prompt throughput.
+func (m appModel) renderSweepView(width int) string {
+	footer := m.styles.help.Render("[Z] sweep the selected model  [tab] matrix  [X] or [esc] close")
+	selected := ""
+	if mi, ok := m.modelsList.SelectedItem().(modelItem); ok {
+		selected = mi.name
+	}
+	sweep, ok := latestSweep(m.benchSweeps, selected)
+	if !ok {
+		return "No sweeps yet.\n\nSelect a model and press [Z] to measure it across batch sizes and context depths.\n\n" + footer
+	}
+	var batches, depths []int
+	cells := map[[2]int]sweepPoint{}
+	top := 0.0
+	for _, p := range sweep.Points {
+		if _, ok := cells[[2]int{p.Batch, p.Depth}]; !ok {
+			if !containsInt(batches, p.Batch) {
+				batches = append(batches, p.Batch)
+			}
+			if !containsInt(depths, p.Depth) {
+				depths = append(depths, p.Depth)
+			}
+		}
+		cells[[2]int{p.Batch, p.Depth}] = p
+		top = max(top, p.PromptTPS)
+	}
+	sort.Ints(batches)
+	sort.Ints(depths)
+
+	var b strings.Builder
+	when := sweep.Time
+	if t, err := time.Parse(time.RFC3339, sweep.Time); err == nil {
+		when = m.config.Timestamps.dateTime(t)
+	}
+	header := fmt.Sprintf("%s · %s", sweep.Model, when)
+	if sweep.Build != "" {
+		header += " · build " + sweep.Build
+	}
+	b.WriteString(m.styles.sectionTitle.Render(ellipsize(header, width)) + "\n\n")
+	const colW = 16
+	b.WriteString(m.styles.sectionTitle.Render(fmt.Sprintf("%-8s", "batch")))
+	for _, d := range depths {
+		b.WriteString(m.styles.sectionTitle.Render(fmt.Sprintf("%*s", colW, fmt.Sprintf("ctx %d", d))))
+	}
+	b.WriteString("\n")
+	for _, batch := range batches {
+		b.WriteString(fmt.Sprintf("%-8d", batch))
+		for _, d := range depths {
+			p, ok := cells[[2]int{batch, d}]
+			if !ok {
+				b.WriteString(m.styles.disabled.Render(fmt.Sprintf("%*s", colW, "-")))
+				continue
+			}
+			b.WriteString(fmt.Sprintf("%*s", colW, fmt.Sprintf("%.0f / %.1f", p.PromptTPS, p.GenTPS)))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(m.styles.help.Render("prompt / generation tokens/s") + "\n\n")
+
+	// Prompt throughput by depth, one bar per batch size
+	barW := max(width-30, 10)
+	for _, d := range depths {
+		b.WriteString(m.styles.help.Render(fmt.Sprintf("ctx %d", d)) + "\n")
+		for _, batch := range batches {
+			p, ok := cells[[2]int{batch, d}]
+			if !ok || top <= 0 {
+				continue
+			}
+			n := int(p.PromptTPS / top * float64(barW))
+			b.WriteString(fmt.Sprintf("  b%-6d ", batch) + m.styles.accent.Render(strings.Repeat("█", max(n, 1))) + fmt.Sprintf(" %.0f\n", p.PromptTPS))
+		}
+	}
+	b.WriteString("\n" + footer)
+	return b.String()
+}
+
+func containsInt(list []int, v int) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
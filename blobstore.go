@@ -0,0 +1,394 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// blobStoreDirName is the content-addressed store inside the models
+// directory: each file is kept once as "sha256-<hex>", and the names in the
+// models list are relative symlinks to it, much like ollama's blobs but
+// with the human-readable names kept where llama-server and other tools
+// expect them.
+const blobStoreDirName = ".blobs"
+
+const blobPrefix = "sha256-"
+
+func blobStoreDir(barnDir string) string {
+	return filepath.Join(barnDir, blobStoreDirName)
+}
+
+// downloadBlobDir is the store downloads go into, or "" when blob_store is
+// off.
+func (m appModel) downloadBlobDir() string {
+	if !m.config.BlobStore {
+		return ""
+	}
+	return blobStoreDir(m.barnDir)
+}
+
+// fileSHA256 checksums a file the way provenance records it.
+func fileSHA256(path string) (string, error) {
+	hash := sha256.New()
+	if err := hashFile(hash, path); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// storeBlob moves the file at path into the store under its checksum and
+// leaves a relative symlink in its place; a file whose content is already
+// stored is just replaced by a link. path names the same content at every
+// step, so a server reading it is not disturbed.
+func storeBlob(storeDir, path, sum string) error {
+	if err := os.MkdirAll(storeDir, 0o755); err != nil {
+		return err
+	}
+	blob := filepath.Join(storeDir, blobPrefix+sum)
+	moved := false
+	if _, err := os.Stat(blob); errors.Is(err, os.ErrNotExist) {
+		if err := os.Link(path, blob); err != nil {
+			// No hard links on this filesystem: move it instead
+			if err := os.Rename(path, blob); err != nil {
+				return err
+			}
+			moved = true
+		}
+	} else if err != nil {
+		return err
+	}
+	target, err := filepath.Rel(filepath.Dir(path), blob)
+	if err != nil {
+		target = blob
+	}
+	tmp := path + ".blob-link"
+	_ = os.Remove(tmp)
+	err = os.Symlink(target, tmp)
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		if moved {
+			_ = os.Rename(blob, path)
+		}
+		return err
+	}
+	return nil
+}
+
+// blobOf is the checksum of the stored blob path links to, or "" when it
+// is an ordinary file. store is the store's resolved path.
+func blobOf(store, path string) string {
+	if store == "" {
+		return ""
+	}
+	if info, err := os.Lstat(path); err != nil || info.Mode()&fs.ModeSymlink == 0 {
+		return ""
+	}
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil || filepath.Dir(target) != store {
+		return ""
+	}
+	return strings.TrimPrefix(filepath.Base(target), blobPrefix)
+}
+
+// resolvedBlobStore is the barn's store with symlinks resolved, or "" when
+// there is none.
+func resolvedBlobStore(barnDir string) string {
+	store, err := filepath.EvalSymlinks(blobStoreDir(barnDir))
+	if err != nil {
+		return ""
+	}
+	return store
+}
+
+// isModelFile is what dedup stores: models, projectors, and shards.
+func isModelFile(path string) bool {
+	return isGGUFFileName(filepath.Base(path)) || isWhisperModelFile(path)
+}
+
+// walkBarnFiles visits the barn's files outside the store: regular files
+// and symlinks alike.
+func walkBarnFiles(barnDir string, visit func(path string, d fs.DirEntry) error) error {
+	store := blobStoreDir(barnDir)
+	return filepath.WalkDir(barnDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path == store {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return visit(path, d)
+	})
+}
+
+// dedupBarn moves every model file in the barn into the store, linking
+// files with the same content to one blob. Symlinks, including those
+// already into the store, are left alone.
+func dedupBarn(barnDir string, dryRun bool, out io.Writer) error {
+	store := blobStoreDir(barnDir)
+	seen := map[string]string{}
+	var stored, linked int
+	var saved int64
+	err := walkBarnFiles(barnDir, func(path string, d fs.DirEntry) error {
+		if !d.Type().IsRegular() || !isModelFile(path) {
+			return nil
+		}
+		rel, _ := filepath.Rel(barnDir, path)
+		sum, err := fileSHA256(path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		first, dup := seen[sum]
+		if !dup {
+			seen[sum] = rel
+			if _, err := os.Stat(filepath.Join(store, blobPrefix+sum)); err == nil {
+				dup, first = true, "a stored blob"
+			}
+		}
+		switch {
+		case dup:
+			linked++
+			saved += info.Size()
+			fmt.Fprintf(out, "link   %s (same as %s)\n", rel, first)
+		default:
+			stored++
+			fmt.Fprintf(out, "store  %s\n", rel)
+		}
+		if dryRun {
+			return nil
+		}
+		if err := storeBlob(store, path, sum); err != nil {
+			return fmt.Errorf("%s: %w", rel, err)
+		}
+		return nil
+	})
+	verb := "stored"
+	if dryRun {
+		verb = "would store"
+	}
+	fmt.Fprintf(out, "%s %s, linked %s to existing blobs, freeing %s\n",
+		verb, pluralize(stored, "file"), pluralize(linked, "duplicate"), formatBytes(uint64(saved)))
+	return err
+}
+
+// blobLinks maps each stored checksum to the names linking to it.
+func blobLinks(barnDir string) (map[string][]string, error) {
+	store := resolvedBlobStore(barnDir)
+	links := map[string][]string{}
+	err := walkBarnFiles(barnDir, func(path string, d fs.DirEntry) error {
+		if d.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+		rel, _ := filepath.Rel(barnDir, path)
+		if sum := blobOf(store, path); sum != "" {
+			links[sum] = append(links[sum], rel)
+		} else if target, err := os.Readlink(path); err == nil && strings.HasPrefix(filepath.Base(target), blobPrefix) {
+			// A link whose blob is gone no longer resolves
+			sum := strings.TrimPrefix(filepath.Base(target), blobPrefix)
+			links[sum] = append(links[sum], rel)
+		}
+		return nil
+	})
+	return links, err
+}
+
+// storedBlobs lists the checksums in the store.
+func storedBlobs(barnDir string) ([]string, error) {
+	entries, err := os.ReadDir(blobStoreDir(barnDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sums []string
+	for _, e := range entries {
+		if e.Type().IsRegular() && strings.HasPrefix(e.Name(), blobPrefix) {
+			sums = append(sums, strings.TrimPrefix(e.Name(), blobPrefix))
+		}
+	}
+	sort.Strings(sums)
+	return sums, nil
+}
+
+// verifyBlobs rechecks every blob's content against its name and reports
+// links whose blob is missing and blobs nothing links to. It returns the
+// number of problems found.
+func verifyBlobs(barnDir string, out io.Writer) (int, error) {
+	sums, err := storedBlobs(barnDir)
+	if err != nil {
+		return 0, err
+	}
+	links, err := blobLinks(barnDir)
+	if err != nil {
+		return 0, err
+	}
+	problems, unused := 0, 0
+	stored := map[string]bool{}
+	for _, sum := range sums {
+		stored[sum] = true
+		got, err := fileSHA256(filepath.Join(blobStoreDir(barnDir), blobPrefix+sum))
+		names := strings.Join(links[sum], ", ")
+		switch {
+		case err != nil:
+			problems++
+			fmt.Fprintf(out, "unreadable  %s: %v\n", blobPrefix+sum, err)
+		case got != sum:
+			problems++
+			fmt.Fprintf(out, "corrupt     %s (content is %s) used by %s\n", blobPrefix+sum, got, names)
+		case names == "":
+			unused++
+			fmt.Fprintf(out, "unused      %s\n", blobPrefix+sum)
+		default:
+			fmt.Fprintf(out, "ok          %s %s\n", blobPrefix+sum, names)
+		}
+	}
+	var missing []string
+	for sum := range links {
+		if !stored[sum] {
+			missing = append(missing, sum)
+		}
+	}
+	sort.Strings(missing)
+	for _, sum := range missing {
+		problems++
+		fmt.Fprintf(out, "missing     %s linked from %s\n", blobPrefix+sum, strings.Join(links[sum], ", "))
+	}
+	fmt.Fprintf(out, "%s checked, %s, %s unused\n", pluralize(len(sums), "blob"), pluralize(problems, "problem"), pluralize(unused, "blob"))
+	if unused > 0 {
+		fmt.Fprintf(out, "run %s blobs prune to remove unused blobs\n", appTitle)
+	}
+	return problems, nil
+}
+
+// pruneBlobs removes blobs no name links to any more.
+func pruneBlobs(barnDir string, dryRun bool, out io.Writer) error {
+	sums, err := storedBlobs(barnDir)
+	if err != nil {
+		return err
+	}
+	links, err := blobLinks(barnDir)
+	if err != nil {
+		return err
+	}
+	var removed int
+	var freed int64
+	for _, sum := range sums {
+		if len(links[sum]) > 0 {
+			continue
+		}
+		path := filepath.Join(blobStoreDir(barnDir), blobPrefix+sum)
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "remove %s (%s)\n", blobPrefix+sum, formatBytes(uint64(info.Size())))
+		if !dryRun {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+		removed++
+		freed += info.Size()
+	}
+	verb := "removed"
+	if dryRun {
+		verb = "would remove"
+	}
+	fmt.Fprintf(out, "%s %s, freeing %s\n", verb, pluralize(removed, "blob"), formatBytes(uint64(freed)))
+	return nil
+}
+
+// newBlobsCmd groups the blob store's maintenance commands.
+func newBlobsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "blobs",
+		Short: "Keep models in a content-addressed store with symlinked names",
+		Long: "Models in the blob store are kept once under <models dir>/" + blobStoreDirName + "/sha256-<hex>, " +
+			"and each name in the models directory is a relative symlink to its blob, so copies of the same file " +
+			"take the space of one. Set blob_store in the config file to store downloads there too.",
+	}
+	var dryRun bool
+	dedup := &cobra.Command{
+		Use:   "dedup",
+		Short: "Move the models directory's files into the store, linking duplicates",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			barn, err := getDefaultBarnDir()
+			if err != nil {
+				return err
+			}
+			return dedupBarn(barn, dryRun, cmd.OutOrStdout())
+		},
+	}
+	dedup.Flags().BoolVar(&dryRun, "dry-run", false, "only list what would be stored and linked")
+	verify := &cobra.Command{
+		Use:   "verify",
+		Short: "Check each blob against its checksum and each link against its blob",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			barn, err := getDefaultBarnDir()
+			if err != nil {
+				return err
+			}
+			problems, err := verifyBlobs(barn, cmd.OutOrStdout())
+			if err == nil && problems > 0 {
+				err = fmt.Errorf("%s found", pluralize(problems, "problem"))
+			}
+			return err
+		},
+	}
+	prune := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove blobs no name links to",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			barn, err := getDefaultBarnDir()
+			if err != nil {
+				return err
+			}
+			return pruneBlobs(barn, dryRun, cmd.OutOrStdout())
+		},
+	}
+	prune.Flags().BoolVar(&dryRun, "dry-run", false, "only list what would be removed")
+	cmd.AddCommand(dedup, verify, prune)
+	return cmd
+}
+
+// blobSummary describes the stored blob mi's file links to and how many
+// names in the list share it.
+func (m appModel) blobSummary(mi modelItem) string {
+	if mi.blob == "" {
+		return ""
+	}
+	names := 0
+	for _, it := range m.allModelItems() {
+		if other, ok := it.(modelItem); ok && other.blob == mi.blob {
+			names++
+		}
+	}
+	s := blobPrefix + ellipsize(mi.blob, 12)
+	if names > 1 {
+		s += fmt.Sprintf(" · shared by %d names", names)
+	}
+	return s
+}
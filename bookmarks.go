@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// logBookmark marks a line of the server's logs. line counts from the
+// start of the session, so it survives the oldest lines being trimmed
+// from the buffer.
+type logBookmark struct {
+	line  int
+	label string
+	text  string
+}
+
+// bookmarkListView is the ['] overlay listing the bookmarks.
+type bookmarkListView struct {
+	cursor int
+}
+
+// bookmarkJump remembers the last bookmark jumped to and where that left
+// the logs panel; near the end of the logs the panel can't scroll the
+// line to the top, so its offset alone doesn't tell where [{ }] are.
+type bookmarkJump struct {
+	line   int
+	offset int
+}
+
+// bookmarkPosition is the line a new bookmark marks: the newest line while
+// the panel follows the output, else the top of the panel.
+func (m appModel) bookmarkPosition() (int, bool) {
+	lines := m.plainLogLines()
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return 0, false
+	}
+	if m.logsViewport.AtBottom() {
+		return len(lines) - 1, true
+	}
+	return min(m.logsViewport.YOffset, len(lines)-1), true
+}
+
+// currentLogLine is where [{ }] step from: the bookmark last jumped to
+// while the panel hasn't moved since, else the line a new bookmark would
+// mark.
+func (m appModel) currentLogLine() int {
+	if j := m.bookmarkJump; j != nil && j.offset == m.logsViewport.YOffset {
+		return j.line
+	}
+	pos, _ := m.bookmarkPosition()
+	return m.logLinesDropped + pos
+}
+
+// beginBookmark asks for the label of a bookmark at the current position.
+func (m appModel) beginBookmark() (appModel, tea.Cmd) {
+	if m.logView != "" {
+		m.statusLineText = "Bookmarks are kept in the main server's logs - [v] to switch back"
+		return m, nil
+	}
+	if len(m.hiddenComponents) > 0 {
+		m.statusLineText = "Bookmarks count every log line - [x] then [a] to show all components"
+		return m, nil
+	}
+	pos, ok := m.bookmarkPosition()
+	if !ok {
+		m.statusLineText = "No logs to bookmark"
+		return m, nil
+	}
+	m.bookmarkLine = m.logLinesDropped + pos
+	m.portInput.Blur()
+	m.bookmarkInput.SetValue("")
+	m.bookmarkInput.Focus()
+	m.statusLineText = fmt.Sprintf("Bookmark line %d - type a label (optional), enter to save, esc to cancel", m.bookmarkLine+1)
+	return m, nil
+}
+
+// saveBookmark records the pending bookmark, relabelling one already on
+// that line.
+func (m appModel) saveBookmark(label string) appModel {
+	lines := m.plainLogLines()
+	i := m.bookmarkLine - m.logLinesDropped
+	if i < 0 || i >= len(lines) {
+		m.statusLineText = "The line is no longer in the logs"
+		return m
+	}
+	b := logBookmark{line: m.bookmarkLine, label: label, text: strings.TrimSpace(lines[i])}
+	marks := make([]logBookmark, 0, len(m.bookmarks)+1)
+	for _, e := range m.bookmarks {
+		if e.line != b.line {
+			marks = append(marks, e)
+		}
+	}
+	marks = append(marks, b)
+	sort.Slice(marks, func(i, j int) bool { return marks[i].line < marks[j].line })
+	m.bookmarks = marks
+	m.statusLineText = fmt.Sprintf("Bookmarked line %d (%s) - ['] lists bookmarks, [{ }] jump between them", b.line+1, pluralize(len(marks), "bookmark"))
+	return m
+}
+
+// dropTrimmedBookmarks forgets bookmarks on lines trimmed from the buffer.
+func (m *appModel) dropTrimmedBookmarks() {
+	kept := m.bookmarks[:0:0]
+	for _, b := range m.bookmarks {
+		if b.line >= m.logLinesDropped {
+			kept = append(kept, b)
+		}
+	}
+	m.bookmarks = kept
+}
+
+// clearBookmarks starts over with a new session's logs.
+func (m *appModel) clearBookmarks() {
+	m.logSession++
+	m.bookmarks = nil
+	m.logLinesDropped = 0
+	m.bookmarkList = nil
+	m.bookmarkJump = nil
+}
+
+// jumpToBookmark scrolls the logs panel to put bookmark i at the top, or
+// as near as the end of the logs allows.
+func (m *appModel) jumpToBookmark(i int) {
+	b := m.bookmarks[i]
+	// The logs take the panel back from a model preview
+	m.previewPath = ""
+	m.logsViewport.SetYOffset(b.line - m.logLinesDropped)
+	m.bookmarkJump = &bookmarkJump{line: b.line, offset: m.logsViewport.YOffset}
+	name := b.label
+	if name == "" {
+		name = ellipsize(b.text, 60)
+	}
+	m.statusLineText = fmt.Sprintf("Bookmark %d/%d, line %d: %s", i+1, len(m.bookmarks), b.line+1, name)
+}
+
+// stepBookmark jumps to the next bookmark after the current position, or
+// before it when dir is -1, wrapping around at the ends.
+func (m appModel) stepBookmark(dir int) appModel {
+	if m.logView != "" {
+		m.statusLineText = "Bookmarks are kept in the main server's logs - [v] to switch back"
+		return m
+	}
+	if len(m.hiddenComponents) > 0 {
+		m.statusLineText = "Bookmarks count every log line - [x] then [a] to show all components"
+		return m
+	}
+	if len(m.bookmarks) == 0 {
+		m.statusLineText = "No bookmarks - [z] drops one at the current position"
+		return m
+	}
+	current := m.currentLogLine()
+	next := -1
+	if dir > 0 {
+		for i, b := range m.bookmarks {
+			if b.line > current {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			next = 0
+		}
+	} else {
+		for i := len(m.bookmarks) - 1; i >= 0; i-- {
+			if m.bookmarks[i].line < current {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			next = len(m.bookmarks) - 1
+		}
+	}
+	m.jumpToBookmark(next)
+	return m
+}
+
+// openBookmarkList shows the bookmarks, starting at the last one at or
+// before the current position.
+func (m appModel) openBookmarkList() appModel {
+	if len(m.hiddenComponents) > 0 {
+		m.statusLineText = "Bookmarks count every log line - [x] then [a] to show all components"
+		return m
+	}
+	if len(m.bookmarks) == 0 {
+		m.statusLineText = "No bookmarks - [z] drops one at the current position"
+		return m
+	}
+	current := m.currentLogLine()
+	cursor := 0
+	for i, b := range m.bookmarks {
+		if b.line <= current {
+			cursor = i
+		}
+	}
+	m.bookmarkList = &bookmarkListView{cursor: cursor}
+	return m
+}
+
+func (m appModel) handleBookmarkListKey(keyStr string) (appModel, tea.Cmd) {
+	v := *m.bookmarkList
+	switch keyStr {
+	case "up", "k":
+		v.cursor = max(v.cursor-1, 0)
+	case "down", "j":
+		v.cursor = min(v.cursor+1, len(m.bookmarks)-1)
+	case "enter":
+		m.bookmarkList = nil
+		m.jumpToBookmark(v.cursor)
+		return m, nil
+	case "d":
+		b := m.bookmarks[v.cursor]
+		m.bookmarks = append(m.bookmarks[:v.cursor:v.cursor], m.bookmarks[v.cursor+1:]...)
+		m.statusLineText = fmt.Sprintf("Removed the bookmark on line %d", b.line+1)
+		if len(m.bookmarks) == 0 {
+			m.bookmarkList = nil
+			return m, nil
+		}
+		v.cursor = min(v.cursor, len(m.bookmarks)-1)
+	case "esc", "'", "q":
+		m.bookmarkList = nil
+		return m, nil
+	}
+	m.bookmarkList = &v
+	return m, nil
+}
+
+// renderBookmarkList lists the bookmarks with their line numbers, labels,
+// and the text of the marked line.
+func (m appModel) renderBookmarkList(width int) string {
+	var lines []string
+	numWidth := len(fmt.Sprint(m.bookmarks[len(m.bookmarks)-1].line + 1))
+	labelWidth := 0
+	for _, b := range m.bookmarks {
+		labelWidth = max(labelWidth, len([]rune(b.label)))
+	}
+	labelWidth = min(labelWidth, width/3)
+	for i, b := range m.bookmarks {
+		cursor := "  "
+		if i == m.bookmarkList.cursor {
+			cursor = m.styles.accent.Render("▶ ")
+		}
+		row := fmt.Sprintf("%*d  ", numWidth, b.line+1)
+		if labelWidth > 0 {
+			row += fmt.Sprintf("%-*s  ", labelWidth, ellipsize(b.label, labelWidth))
+		}
+		text := ellipsize(b.text, max(width-2-len([]rune(row)), 10))
+		if i == m.bookmarkList.cursor {
+			row = m.styles.accent.Render(row)
+		}
+		lines = append(lines, cursor+row+m.styles.help.Render(text))
+	}
+	footer := m.styles.help.Render("[enter] jump  [d] remove  ['] or [esc] close  ·  [{ }] jump from the logs")
+	return strings.Join(lines, "\n") + "\n\n" + footer
+}
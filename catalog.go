@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+// catalogSource is a read-only remote listing of GGUF files: either a
+// static JSON index or a public S3 bucket.
+type catalogSource struct {
+	Name string `json:"name"`
+	// Type is "json" (default) or "s3"
+	Type string `json:"type"`
+	// URL of the JSON index, or the bucket endpoint for S3
+	// (e.g. https://my-bucket.s3.amazonaws.com)
+	URL string `json:"url"`
+	// Prefix limits an S3 listing to keys under it
+	Prefix string `json:"prefix"`
+}
+
+// catalogIndexEntry is one model in a JSON index. The index may be a bare
+// array of entries or an object with a "models" array.
+type catalogIndexEntry struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	Size int64  `json:"size"`
+}
+
+type catalogEntry struct {
+	name string
+	url  string
+	size int64
+}
+
+// s3ListResult is the subset of an S3 ListObjectsV2 response we use.
+type s3ListResult struct {
+	Contents []struct {
+		Key  string `xml:"Key"`
+		Size int64  `xml:"Size"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func httpGetBody(ctx context.Context, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", rawURL, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func fetchJSONCatalog(ctx context.Context, src catalogSource) ([]catalogEntry, error) {
+	data, err := httpGetBody(ctx, src.URL)
+	if err != nil {
+		return nil, err
+	}
+	var entries []catalogIndexEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		var wrapped struct {
+			Models []catalogIndexEntry `json:"models"`
+		}
+		if err2 := json.Unmarshal(data, &wrapped); err2 != nil {
+			return nil, fmt.Errorf("invalid catalog index %s: %w", src.URL, err)
+		}
+		entries = wrapped.Models
+	}
+	base, _ := url.Parse(src.URL)
+	var out []catalogEntry
+	for _, e := range entries {
+		if e.URL == "" {
+			continue
+		}
+		// Relative URLs resolve against the index location
+		if u, err := url.Parse(e.URL); err == nil && base != nil {
+			e.URL = base.ResolveReference(u).String()
+		}
+		name := e.Name
+		if name == "" {
+			name = path.Base(e.URL)
+		}
+		out = append(out, catalogEntry{name: name, url: e.URL, size: e.Size})
+	}
+	return out, nil
+}
+
+func fetchS3Catalog(ctx context.Context, src catalogSource) ([]catalogEntry, error) {
+	endpoint := strings.TrimRight(src.URL, "/")
+	var out []catalogEntry
+	token := ""
+	for {
+		q := url.Values{}
+		q.Set("list-type", "2")
+		if src.Prefix != "" {
+			q.Set("prefix", src.Prefix)
+		}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		data, err := httpGetBody(ctx, endpoint+"/?"+q.Encode())
+		if err != nil {
+			return nil, err
+		}
+		var res s3ListResult
+		if err := xml.Unmarshal(data, &res); err != nil {
+			return nil, fmt.Errorf("invalid S3 listing from %s: %w", endpoint, err)
+		}
+		for _, c := range res.Contents {
+			if !strings.HasSuffix(strings.ToLower(c.Key), ".gguf") {
+				continue
+			}
+			name := strings.TrimPrefix(strings.TrimPrefix(c.Key, src.Prefix), "/")
+			out = append(out, catalogEntry{name: name, url: endpoint + "/" + c.Key, size: c.Size})
+		}
+		if !res.IsTruncated || res.NextContinuationToken == "" {
+			break
+		}
+		token = res.NextContinuationToken
+	}
+	return out, nil
+}
+
+// catalogEntryPath is where a catalog entry downloads to. The entry's name
+// comes from the remote index, so a name that is absolute or climbs out
+// with ".." is refused rather than written outside the barn.
+func catalogEntryPath(barnDir, catalog, name string) (string, error) {
+	if name == "" || path.IsAbs(name) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("refusing entry %q: not a relative path", name)
+	}
+	for _, seg := range strings.FieldsFunc(name, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if seg == ".." {
+			return "", fmt.Errorf("refusing entry %q: leaves the catalog directory", name)
+		}
+	}
+	dest := filepath.Join(barnDir, sanitizeFileComponent(catalog), filepath.FromSlash(name))
+	rel, err := filepath.Rel(barnDir, dest)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("refusing entry %q: outside the barn", name)
+	}
+	return dest, nil
+}
+
+// scanCatalogs lists remote models that are not yet in the barn. Each entry
+// downloads to <barn>/<catalog name>/<model name>.
+func scanCatalogs(sources []catalogSource, barnDir string) ([]list.Item, error) {
+	items := []list.Item{}
+	if len(sources) == 0 {
+		return items, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var errs []string
+	for _, src := range sources {
+		var entries []catalogEntry
+		var err error
+		switch src.Type {
+		case "s3":
+			entries, err = fetchS3Catalog(ctx, src)
+		case "", "json":
+			entries, err = fetchJSONCatalog(ctx, src)
+		default:
+			err = fmt.Errorf("unknown catalog type %q", src.Type)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("catalog %s: %v", src.Name, err))
+			continue
+		}
+		for _, e := range entries {
+			dest, err := catalogEntryPath(barnDir, src.Name, e.name)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("catalog %s: %v", src.Name, err))
+				continue
+			}
+			if _, err := os.Stat(dest); err == nil {
+				// Already downloaded; the local scan lists it
+				continue
+			}
+			rel, _ := filepath.Rel(barnDir, dest)
+			items = append(items, modelItem{
+				name:      src.Name + ":" + e.name,
+				path:      dest,
+				relPath:   rel,
+				size:      e.size,
+				remoteURL: e.url,
+			})
+		}
+	}
+	if len(errs) > 0 {
+		return items, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return items, nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// chatFlags are the launch options that mean the same to llama-cli as to
+// llama-server; the rest (port, host, slots, ...) would be rejected.
+var chatFlags = map[string]bool{
+	"--ctx-size":       true,
+	"--n-gpu-layers":   true,
+	"--threads":        true,
+	"--flash-attn":     true,
+	"--cache-type-k":   true,
+	"--cache-type-v":   true,
+	"--override-kv":    true,
+	"--jinja":          true,
+	"--chat-template":  true,
+	"--temp":           true,
+	"--top-k":          true,
+	"--top-p":          true,
+	"--min-p":          true,
+	"--repeat-penalty": true,
+	"--lora":           true,
+	"--rope-scaling":   true,
+	"--rope-scale":     true,
+	"--rope-freq-base": true,
+}
+
+// getLlamaCLIBinary resolves llama-cli.
+// Priority:
+// 1) LLAMA_CLI_BIN environment variable
+// 2) next to the resolved llama-server
+// 3) Look up "llama-cli" in PATH
+func getLlamaCLIBinary() (string, error) {
+	if envPath := strings.TrimSpace(os.Getenv("LLAMA_CLI_BIN")); envPath != "" {
+		if info, err := os.Stat(envPath); err == nil && !info.IsDir() {
+			return envPath, nil
+		}
+		return "", fmt.Errorf("LLAMA_CLI_BIN points to an invalid path: %q", envPath)
+	}
+	if server, err := getLlamaServerBinary(); err == nil {
+		sibling := filepath.Join(filepath.Dir(server), "llama-cli")
+		if info, err := os.Stat(sibling); err == nil && !info.IsDir() {
+			return sibling, nil
+		}
+	}
+	bin, err := exec.LookPath("llama-cli")
+	if err != nil {
+		return "", fmt.Errorf("llama-cli not found in PATH. Set LLAMA_CLI_BIN to its absolute path")
+	}
+	return bin, nil
+}
+
+// chatArgs is the llama-cli command line for a conversation with item,
+// keeping the launch options llama-cli understands.
+func chatArgs(item modelItem, launchArgs []string) []string {
+	args := []string{"-m", item.path}
+	if item.hfRepo != "" {
+		args = []string{"-hf", item.hfRepo}
+	}
+	args = append(args, "-cnv")
+	for _, f := range parseFlagArgs(launchArgs) {
+		if !chatFlags[f.name] {
+			continue
+		}
+		args = append(args, f.name)
+		if f.value != "" {
+			args = append(args, f.value)
+		}
+	}
+	return args
+}
+
+// openChatCmd suspends the TUI and runs llama-cli in the terminal,
+// resuming once it exits.
+func (m appModel) openChatCmd(item modelItem) (tea.Cmd, error) {
+	if item.kind == kindWhisper {
+		return nil, fmt.Errorf("%s is a whisper model", item.name)
+	}
+	bin, err := getLlamaCLIBinary()
+	if err != nil {
+		return nil, err
+	}
+	c := exec.Command(bin, chatArgs(item, withKVOverrides(item.name, m.argsFor(item.name)))...)
+	return tea.ExecProcess(c, func(err error) tea.Msg {
+		return chatClosedMsg{model: item.name, err: err}
+	}), nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	defaultChatReserveTokens = 512
+	// chatSummaryTokens caps the summary that replaces older turns, at
+	// most an eighth of the context.
+	chatSummaryTokens = 256
+	chatSummaryPrompt = "Summarize the conversation so far in a few sentences, keeping the facts, names, and decisions needed to continue it. Reply with the summary only."
+)
+
+// chatContextPolicy is how the A/B chat keeps a conversation inside the
+// servers' context.
+type chatContextPolicy struct {
+	// Strategy when a prompt would overflow the context: "warn" (default)
+	// holds it until [enter] is pressed again, "truncate" drops the oldest
+	// turns, and "summarize" replaces them with a summary the model writes.
+	Strategy string `json:"strategy"`
+	// ReserveTokens is room kept for the reply; default 512.
+	ReserveTokens int `json:"reserve_tokens"`
+}
+
+func (p chatContextPolicy) strategy() string {
+	switch p.Strategy {
+	case "truncate", "summarize":
+		return p.Strategy
+	}
+	return "warn"
+}
+
+// reserve is the room kept for the reply, at most a quarter of nCtx.
+func (p chatContextPolicy) reserve(nCtx int) int {
+	r := p.ReserveTokens
+	if r <= 0 {
+		r = defaultChatReserveTokens
+	}
+	return min(r, nCtx/4)
+}
+
+// estimateTokens guesses the tokens text adds to a conversation before the
+// server has counted it: about four bytes a token, plus the chat
+// template's markers around the message.
+func estimateTokens(text string) int {
+	return (len(text)+3)/4 + 4
+}
+
+// turnTokens is what a turn takes up in the context: the server's count
+// when known, else an estimate.
+func (t chatTurn) turnTokens() int {
+	if t.tokens > 0 {
+		return t.tokens
+	}
+	return estimateTokens(t.Content)
+}
+
+// contextUsage is a side's share of its context, e.g. "context 1.2k/4k".
+func (s abSide) contextUsage() string {
+	if s.nCtx == 0 {
+		return ""
+	}
+	return fmt.Sprintf("context %s/%s", formatTokenCount(s.used), formatTokenCount(s.nCtx))
+}
+
+func formatTokenCount(n int) string {
+	if n < 1000 {
+		return fmt.Sprint(n)
+	}
+	return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/1000), ".0") + "k"
+}
+
+// fetchChatContextCmd reads the per-slot context of the server on port,
+// which bounds each conversation.
+func fetchChatContextCmd(port string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		raw, _, err := fetchProps(ctx, "http://127.0.0.1:"+port)
+		if err != nil {
+			return abContextMsg{port: port, err: err}
+		}
+		var props struct {
+			Settings struct {
+				NCtx int `json:"n_ctx"`
+			} `json:"default_generation_settings"`
+		}
+		err = json.Unmarshal(raw, &props)
+		return abContextMsg{port: port, nCtx: props.Settings.NCtx, err: err}
+	}
+}
+
+func (m appModel) handleABContext(msg abContextMsg) appModel {
+	if m.abChat == nil {
+		return m
+	}
+	v := *m.abChat
+	for i := range v.sides {
+		if v.sides[i].port == msg.port && msg.err == nil {
+			v.sides[i].nCtx = msg.nCtx
+		}
+	}
+	m.abChat = &v
+	return m
+}
+
+// fitContext makes room for prompt in s's context by the chat_context
+// strategy, dropping turns from the front of its conversation. It returns
+// the dropped turns for "summarize", and a note for the chat when the
+// prompt can't be sent as it stands.
+func (p chatContextPolicy) fitContext(s *abSide, prompt string) ([]chatTurn, string) {
+	if s.nCtx == 0 {
+		return nil, ""
+	}
+	need := s.used + estimateTokens(prompt) + p.reserve(s.nCtx)
+	if need <= s.nCtx {
+		return nil, ""
+	}
+	name := s.model + ":" + s.port
+	if p.strategy() == "warn" {
+		return nil, fmt.Sprintf("%s: this prompt needs ~%s of %s context tokens with %s kept for the reply - [enter] sends it anyway, [ctrl+l] starts over",
+			name, formatTokenCount(need), formatTokenCount(s.nCtx), formatTokenCount(p.reserve(s.nCtx)))
+	}
+	budget := s.nCtx - estimateTokens(prompt) - p.reserve(s.nCtx)
+	if p.strategy() == "summarize" {
+		budget -= summaryTokens(s.nCtx)
+	}
+	// Turns go in user and reply pairs, oldest first; a summary from
+	// before goes with the first pair
+	used, drop := s.used, 0
+	for drop < len(s.turns) && used > budget {
+		used -= s.turns[drop].turnTokens()
+		drop++
+		for drop < len(s.turns) && s.turns[drop].Role != "user" {
+			used -= s.turns[drop].turnTokens()
+			drop++
+		}
+	}
+	if used > budget {
+		return nil, fmt.Sprintf("%s: the prompt alone needs ~%s of %s context tokens - shorten it", name, formatTokenCount(estimateTokens(prompt)+p.reserve(s.nCtx)), formatTokenCount(s.nCtx))
+	}
+	dropped := append([]chatTurn(nil), s.turns[:drop]...)
+	s.turns = append([]chatTurn(nil), s.turns[drop:]...)
+	s.used = max(used, 0)
+	if p.strategy() == "summarize" {
+		s.notice = fmt.Sprintf("summarizing %s to fit the context...", pluralize(exchanges(dropped), "earlier exchange"))
+		return dropped, ""
+	}
+	s.notice = fmt.Sprintf("dropped %s to fit the context", pluralize(exchanges(dropped), "earlier exchange"))
+	return nil, ""
+}
+
+// exchanges counts the prompts in turns, including those a summary among
+// them stands in for.
+func exchanges(turns []chatTurn) int {
+	n := 0
+	for _, t := range turns {
+		switch t.Role {
+		case "user":
+			n++
+		case "system":
+			n += t.replaced
+		}
+	}
+	return n
+}
+
+func summaryTokens(nCtx int) int {
+	return min(chatSummaryTokens, nCtx/8)
+}
+
+// summarizeTurns asks the server on port for a summary of turns of at most
+// maxTokens.
+func summarizeTurns(ctx context.Context, port string, turns []chatTurn, maxTokens int) (string, error) {
+	messages := append(append([]chatTurn(nil), turns...), chatTurn{Role: "user", Content: chatSummaryPrompt})
+	body, err := json.Marshal(map[string]any{"messages": messages, "max_tokens": maxTokens})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://127.0.0.1:"+port+"/v1/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("summary request failed: %s", resp.Status)
+	}
+	var reply struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return "", err
+	}
+	if len(reply.Choices) == 0 || strings.TrimSpace(reply.Choices[0].Message.Content) == "" {
+		return "", fmt.Errorf("summary request returned nothing")
+	}
+	return strings.TrimSpace(reply.Choices[0].Message.Content), nil
+}
+
+// summaryTurn carries a summary of replaced earlier exchanges into the
+// conversation.
+func summaryTurn(summary string, replaced int) chatTurn {
+	return chatTurn{Role: "system", Content: "Summary of the earlier conversation: " + summary, replaced: replaced}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// clientEndpoint is a running server as OpenAI-compatible clients see it.
+type clientEndpoint struct {
+	Alias   string `json:"alias"`
+	BaseURL string `json:"base_url"`
+	// APIKey is the server's --api-key, if it was started with one
+	APIKey string `json:"api_key,omitempty"`
+}
+
+// key is the API key clients send, "none" for servers that take any.
+func (e clientEndpoint) key() string {
+	if e.APIKey == "" {
+		return "none"
+	}
+	return e.APIKey
+}
+
+// getClientConfigDir resolves where client configs are written.
+// Priority:
+// 1) client_config_dir in the config ("off" disables them)
+// 2) <user cache dir>/llama-tui/clients
+func getClientConfigDir(cfg appConfig) string {
+	if d := strings.TrimSpace(cfg.ClientConfigDir); d != "" {
+		if d == "off" {
+			return ""
+		}
+		return d
+	}
+	cacheDir := getCacheDir()
+	if cacheDir == "" {
+		return ""
+	}
+	return filepath.Join(cacheDir, "clients")
+}
+
+// endpointFor names a server by its --alias, else its model, and reaches
+// it on the interface it is bound to with the key it was given.
+func endpointFor(argv []string, model, port string) clientEndpoint {
+	alias := strings.TrimSuffix(filepath.Base(model), ".gguf")
+	host, key := "127.0.0.1", ""
+	for _, f := range parseFlagArgs(argv) {
+		switch f.name {
+		case "-a", "--alias":
+			alias = f.value
+		case "--api-key":
+			key = f.value
+		case "--host":
+			switch f.value {
+			case "", "0.0.0.0", "::", "localhost", "127.0.0.1":
+			default:
+				host = f.value
+			}
+		}
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	return clientEndpoint{Alias: alias, BaseURL: fmt.Sprintf("http://%s:%s/v1", host, port), APIKey: key}
+}
+
+// clientEndpoints lists the servers answering requests: the served model
+// (through the request proxy when there is one) and the side servers. The
+// standby is left out until it is promoted.
+func (m appModel) clientEndpoints() []clientEndpoint {
+	// whisper-server has no OpenAI-style API for these clients
+	var endpoints []clientEndpoint
+	if m.server.serving() && !m.servingWhisper() {
+		var argv []string
+		if m.process != nil {
+			argv = m.process.args
+		}
+		e := endpointFor(argv, m.currentModelName, m.currentPort)
+		if m.proxy != nil {
+			e.BaseURL = "http://127.0.0.1:" + m.proxy.port + "/v1"
+		}
+		endpoints = append(endpoints, e)
+	}
+	for _, s := range m.sideServers {
+		if !s.state.serving() || s.item.kind == kindWhisper {
+			continue
+		}
+		var argv []string
+		if s.started != nil && s.started.process != nil {
+			argv = s.started.process.args
+		}
+		endpoints = append(endpoints, endpointFor(argv, s.item.name, s.port))
+	}
+	return endpoints
+}
+
+// liteLLMConfig is a LiteLLM proxy config with one model_list entry per
+// endpoint. JSON-quoted strings are valid YAML.
+func liteLLMConfig(endpoints []clientEndpoint) string {
+	var b strings.Builder
+	b.WriteString("# Generated by llama-tui; rewritten when the running servers change\n")
+	if len(endpoints) == 0 {
+		b.WriteString("model_list: []\n")
+		return b.String()
+	}
+	b.WriteString("model_list:\n")
+	for _, e := range endpoints {
+		b.WriteString("  - model_name: " + strconv.Quote(e.Alias) + "\n")
+		b.WriteString("    litellm_params:\n")
+		b.WriteString("      model: " + strconv.Quote("openai/"+e.Alias) + "\n")
+		b.WriteString("      api_base: " + strconv.Quote(e.BaseURL) + "\n")
+		b.WriteString("      api_key: " + strconv.Quote(e.key()) + "\n")
+	}
+	return b.String()
+}
+
+// openWebUIEnv sets Open WebUI's OpenAI connections, one per endpoint.
+func openWebUIEnv(endpoints []clientEndpoint) string {
+	urls := make([]string, 0, len(endpoints))
+	keys := make([]string, 0, len(endpoints))
+	for _, e := range endpoints {
+		urls = append(urls, e.BaseURL)
+		keys = append(keys, e.key())
+	}
+	return "# Generated by llama-tui; rewritten when the running servers change\n" +
+		"ENABLE_OPENAI_API=true\n" +
+		"OPENAI_API_BASE_URLS=" + strconv.Quote(strings.Join(urls, ";")) + "\n" +
+		"OPENAI_API_KEYS=" + strconv.Quote(strings.Join(keys, ";")) + "\n"
+}
+
+// writeClientConfigs rewrites the LiteLLM config, the Open WebUI
+// environment file, and a plain endpoints.json, each atomically. Files
+// holding an API key are readable by the user only.
+func writeClientConfigs(dir string, endpoints []clientEndpoint) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	data := []byte("[]")
+	if endpoints != nil {
+		var err error
+		if data, err = json.MarshalIndent(endpoints, "", "  "); err != nil {
+			return err
+		}
+	}
+	perm := os.FileMode(0o644)
+	if slices.ContainsFunc(endpoints, func(e clientEndpoint) bool { return e.APIKey != "" }) {
+		perm = 0o600
+	}
+	files := map[string]string{
+		"litellm.yaml":   liteLLMConfig(endpoints),
+		"openwebui.env":  openWebUIEnv(endpoints),
+		"endpoints.json": string(data) + "\n",
+	}
+	for name, content := range files {
+		if err := writeFileAtomic(filepath.Join(dir, name), []byte(content), perm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeClientConfigsCmd(dir string, endpoints []clientEndpoint) tea.Cmd {
+	if dir == "" {
+		return nil
+	}
+	return func() tea.Msg {
+		// Best-effort, like the status file
+		_ = writeClientConfigs(dir, endpoints)
+		return nil
+	}
+}
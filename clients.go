@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const clientPollInterval = 2 * time.Second
+
+// tcpEstablished is the ESTABLISHED state code in /proc/net/tcp
+const tcpEstablished = "01"
+
+// countClientConnections counts established TCP connections whose local end
+// is port, i.e. clients connected to the server. Linux reads /proc/net;
+// other systems fall back to lsof.
+func countClientConnections(port int) (int, error) {
+	if runtime.GOOS == "linux" {
+		if n, err := countProcNetConnections(port); err == nil {
+			return n, nil
+		}
+	}
+	return countLsofConnections(port)
+}
+
+func countProcNetConnections(port int) (int, error) {
+	total := 0
+	read := 0
+	for _, path := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		read++
+		scanner := bufio.NewScanner(f)
+		scanner.Scan() // header
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 4 || fields[3] != tcpEstablished {
+				continue
+			}
+			// local_address is HEXIP:HEXPORT
+			_, hexPort, ok := strings.Cut(fields[1], ":")
+			if !ok {
+				continue
+			}
+			if p, err := strconv.ParseUint(hexPort, 16, 16); err == nil && int(p) == port {
+				total++
+			}
+		}
+		_ = f.Close()
+	}
+	if read == 0 {
+		return 0, fmt.Errorf("/proc/net not available")
+	}
+	return total, nil
+}
+
+func countLsofConnections(port int) (int, error) {
+	out, err := exec.Command("lsof", "-nP", fmt.Sprintf("-iTCP:%d", port), "-sTCP:ESTABLISHED").Output()
+	if err != nil {
+		// lsof exits 1 when nothing matches
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return 0, nil
+		}
+		return 0, err
+	}
+	// Local clients show up twice; count only the server end, "host:port->peer"
+	local := fmt.Sprintf(":%d->", port)
+	total := 0
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.Contains(line, local) {
+			total++
+		}
+	}
+	return total, nil
+}
+
+// pollClientsCmd samples the client count for port after the poll interval.
+func pollClientsCmd(port string, seq int, delay time.Duration) tea.Cmd {
+	return tea.Tick(delay, func(time.Time) tea.Msg {
+		portNum, err := strconv.Atoi(port)
+		if err != nil {
+			return clientsMsg{port: port, seq: seq, err: err}
+		}
+		n, err := countClientConnections(portNum)
+		return clientsMsg{port: port, seq: seq, count: n, err: err}
+	})
+}
+
+// startClientsPoll starts a new client count loop for port; any older loop
+// ends when its sample arrives.
+func (m *appModel) startClientsPoll(port string) tea.Cmd {
+	m.clientsSeq++
+	return pollClientsCmd(port, m.clientsSeq, 0)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultClipboardLimitKB is the most log text ctrl+y copies without
+// asking; terminal clipboards (OSC 52 in particular) and some clipboard
+// managers break on multi-megabyte payloads.
+const defaultClipboardLimitKB = 256
+
+// clipboardLimit is clipboard_limit_kb in bytes.
+func (c appConfig) clipboardLimit() int {
+	if c.ClipboardLimitKB > 0 {
+		return c.ClipboardLimitKB * 1024
+	}
+	return defaultClipboardLimitKB * 1024
+}
+
+// plainLogs is the logs panel's text without styling, as it would be
+// pasted.
+func (m appModel) plainLogs() string {
+	return ansiEscape.ReplaceAllString(m.logsContent(), "")
+}
+
+// copyLogs copies the logs panel to the clipboard, or, when it is over
+// the limit, asks whether to copy only its tail or save it to a file.
+func (m appModel) copyLogs() (appModel, tea.Cmd) {
+	text := m.plainLogs()
+	if strings.TrimSpace(text) == "" {
+		m.statusLineText = "No logs to copy"
+		return m, nil
+	}
+	limit := m.config.clipboardLimit()
+	if len(text) <= limit {
+		return m, copyTextCmd(text, formatBytes(uint64(len(text)))+" of logs")
+	}
+	m.statusLineText = fmt.Sprintf("Logs are %s: [1] copy the last %s  [2] save to a file and copy its path  [esc] cancel",
+		formatBytes(uint64(len(text))), formatBytes(uint64(limit)))
+	return m, m.askConfirm(confirmCopyLogs)
+}
+
+// handleCopyLogsChoice acts on the answer to copyLogs' prompt.
+func (m appModel) handleCopyLogsChoice(keyStr string) (appModel, tea.Cmd) {
+	m.confirmAction = confirmNone
+	text := m.plainLogs()
+	switch keyStr {
+	case "1":
+		tail := logTail(text, m.config.clipboardLimit())
+		return m, copyTextCmd(tail, "the last "+formatBytes(uint64(len(tail)))+" of logs")
+	case "2":
+		m.statusLineText = "Saving logs..."
+		return m, saveLogsCopyPathCmd(text)
+	}
+	return m, nil
+}
+
+// logTail is at most limit bytes from the end of text, starting on a
+// whole line.
+func logTail(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	tail := text[len(text)-limit:]
+	if i := strings.IndexByte(tail, '\n'); i >= 0 && i < len(tail)-1 {
+		tail = tail[i+1:]
+	}
+	return tail
+}
+
+// copyTextCmd writes text to the clipboard, reporting it as what.
+func copyTextCmd(text, what string) tea.Cmd {
+	return func() tea.Msg {
+		return clipboardCopiedMsg{text: what, err: clipboard.WriteAll(text)}
+	}
+}
+
+// saveLogsCopyPathCmd writes the logs to a temporary file and copies its
+// path, for logs too large to paste.
+func saveLogsCopyPathCmd(text string) tea.Cmd {
+	return func() tea.Msg {
+		f, err := os.CreateTemp("", appTitle+"-logs-*.log")
+		if err != nil {
+			return clipboardCopiedMsg{err: err}
+		}
+		_, err = f.WriteString(text)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			_ = os.Remove(f.Name())
+			return clipboardCopiedMsg{err: err}
+		}
+		return clipboardCopiedMsg{text: f.Name(), err: clipboard.WriteAll(f.Name())}
+	}
+}
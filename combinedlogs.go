@@ -0,0 +1,50 @@
+package main
+
+import "bytes"
+
+// allLogsView is the logView that interleaves every server's lines.
+const allLogsView = "all"
+
+// Once a side server starts, lines from every server are also kept in one
+// buffer, each tagged with its port in the instance's color, so events in
+// an embedder and a chat model can be read in the order they happened.
+
+// startCombinedLogs begins the interleaved buffer, if not already kept.
+func (m *appModel) startCombinedLogs() {
+	if m.combinedLogs == nil {
+		m.combinedLogs = &bytes.Buffer{}
+	}
+}
+
+// appendCombinedLine adds a colored line with its instance tag to the
+// interleaved buffer, refreshing the panel when it is shown.
+func (m *appModel) appendCombinedLine(tag, line string) {
+	buf := m.combinedLogs
+	if buf == nil {
+		return
+	}
+	_, _ = buf.WriteString(tag + line + "\n")
+	if buf.Len() > m.logBufferLimit() {
+		trimmed := trimLogBuffer(buf.Bytes())
+		buf.Reset()
+		_, _ = buf.Write(trimmed)
+	}
+	if m.logView != allLogsView {
+		return
+	}
+	if m.lowMemory {
+		m.logsDirty = true
+		return
+	}
+	m.logsViewport.SetContent(m.logsContent())
+	m.logsViewport.GotoBottom()
+}
+
+// mainInstanceTag tags the managed server's lines in the interleaved
+// buffer; llama-tui's own notes go untagged until a server has a port.
+func (m appModel) mainInstanceTag() string {
+	if m.currentPort == "" {
+		return ""
+	}
+	return m.instanceTag(m.serverColor, m.currentPort)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+)
+
+// newRootCmd builds the command tree: the TUI itself plus non-interactive
+// helpers. Cobra adds `completion bash|zsh|fish|powershell`.
+func newRootCmd() *cobra.Command {
+	var o runOptions
+	root := &cobra.Command{
+		Use:     appTitle,
+		Short:   "Terminal UI for running llama-server with local GGUF models",
+		Long:    "llama-tui browses GGUF models in ~/.llamabarn, starts and stops llama-server, and streams its logs.",
+		Version: version,
+		Args:    cobra.NoArgs,
+		// Errors are printed once by main
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTUI(o)
+		},
+	}
+	root.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return usageError{err}
+	})
+
+	flags := root.Flags()
+	flags.BoolVar(&o.noMouse, "no-mouse", false, "start without mouse capture so native text selection works")
+	flags.StringVar(&o.start, "start", "", "start serving the named model (exact name or unique substring)")
+	flags.StringVar(&o.port, "port", "", "port to serve on (default "+defaultPort+")")
+	flags.StringVar(&o.preset, "preset", "", "start a named preset from the config file")
+	flags.StringArrayVar(&o.vars, "var", nil, "fill a {{placeholder}} in the preset's args, as name=value (repeatable)")
+	flags.BoolVar(&o.autostartLast, "autostart-last", false, "start the most recently served model again")
+	flags.BoolVar(&o.noColor, "no-color", false, "render without colors (also when NO_COLOR is set)")
+	flags.BoolVar(&o.lowMemory, "low-memory", false, "reduce the TUI's own overhead: uncolored logs, a smaller log buffer, fewer redraws")
+	flags.StringVarP(&o.workspace, "workspace", "w", "", "use a named workspace from the config file (its models directory, presets, and history)")
+	flags.StringVar(&o.attach, "attach", "", "monitor a llama-server started elsewhere, by port or PID (port:N or pid:N when ambiguous)")
+	flags.StringVar(&o.attachLog, "attach-log", "", "with --attach, tail the server's log file into the logs panel")
+	flags.BoolVar(&o.readOnly, "read-only", false, "observer mode: turn off every key that starts, stops, or deletes anything (--start still applies)")
+	flags.StringVar(&o.fakeServer, "fake-server", "", "launch a built-in fake llama-server instead of the real one, for development (options: load=3s,crash=2m,token=30ms,crash-on-load)")
+	flags.Lookup("fake-server").NoOptDefVal = "on"
+	flags.BoolVar(&o.watch, "watch", false, "dev mode: restart the server when its model or LoRA adapter files change")
+	flags.StringVar(&o.socket, "socket", "", "also serve the running model on this unix domain socket (overrides socket_path)")
+	root.PersistentFlags().StringVar(&barnDirOverride, "models-dir", "", "models directory holding the config file (default ~/.llamabarn, or LLAMA_TUI_MODELS_DIR)")
+	_ = root.RegisterFlagCompletionFunc("preset", completePresets)
+	_ = root.RegisterFlagCompletionFunc("workspace", completeWorkspaces)
+
+	root.AddCommand(newManCmd(root), newImportScriptsCmd(), newEmbedCmd(), newPathsCmd(), newControlTokenCmd(), newBlobsCmd(), newNotifyTestCmd(), newImportModelsCmd(), newHistoryCmd(), newQuickCmd())
+	return root
+}
+
+// completePresets offers preset names from the config file.
+func completePresets(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	barn, err := getDefaultBarnDir()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	cfg, err := loadConfig(getConfigPath(barn))
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for name := range cfg.Presets {
+		if strings.HasPrefix(name, toComplete) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeWorkspaces offers workspace names from the config file.
+func completeWorkspaces(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	barn, err := getDefaultBarnDir()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	cfg, err := loadConfig(getConfigPath(barn))
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for _, name := range cfg.workspaceNames() {
+		if strings.HasPrefix(name, toComplete) {
+			names = append(names, name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// newManCmd writes a man page for the command tree to stdout.
+func newManCmd(root *cobra.Command) *cobra.Command {
+	return &cobra.Command{
+		Use:   "man",
+		Short: "Print the llama-tui man page (e.g. llama-tui man > llama-tui.1)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			header := &doc.GenManHeader{Title: "LLAMA-TUI", Section: "1", Source: appTitle + " " + version}
+			return doc.GenMan(root, header, cmd.OutOrStdout())
+		},
+	}
+}
+
+// newImportScriptsCmd converts existing llama-server launch scripts into
+// presets in the config file, named after each script.
+func newImportScriptsCmd() *cobra.Command {
+	var force bool
+	var name string
+	cmd := &cobra.Command{
+		Use:   "import-scripts script.sh...",
+		Short: "Import llama-server launch scripts as presets",
+		Long: "Reads each shell script's llama-server command line and saves it as a preset named after the script. " +
+			"The -m path becomes the model, --port the port, and the remaining flags the arguments.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if name != "" && len(args) > 1 {
+				return usageError{fmt.Errorf("--name needs exactly one script")}
+			}
+			out := cmd.OutOrStdout()
+			presets := map[string]launchPreset{}
+			for _, path := range args {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				presetName := name
+				if presetName == "" {
+					presetName = presetNameForScript(path)
+				}
+				imp, err := parseLaunchScript(presetName, data)
+				if err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+				for _, w := range imp.warnings {
+					fmt.Fprintf(out, "%s: %s\n", path, w)
+				}
+				presets[imp.name] = imp.preset
+			}
+			barn, err := getDefaultBarnDir()
+			if err != nil {
+				return err
+			}
+			configPath := getConfigPath(barn)
+			skipped, err := savePresets(configPath, presets, force)
+			if err != nil {
+				return err
+			}
+			for _, s := range skipped {
+				fmt.Fprintf(out, "preset %q exists; kept it (use --force to replace)\n", s)
+				delete(presets, s)
+			}
+			names := make([]string, 0, len(presets))
+			for n := range presets {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			for _, n := range names {
+				fmt.Fprintf(out, "imported preset %q (llama-tui --preset %s)\n", n, n)
+			}
+			if len(names) > 0 {
+				fmt.Fprintln(out, "saved to", configPath)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "replace presets that already exist")
+	cmd.Flags().StringVar(&name, "name", "", "preset name when importing a single script")
+	return cmd
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// appConfig holds user settings loaded from the JSON config file.
+// Every field is optional; zero values fall back to built-in defaults.
+type appConfig struct {
+	// CommandTemplate is the full child command line. Placeholders:
+	// {{bin}} resolved llama-server path, {{model}} model path,
+	// {{port}} chosen port, {{args}} ExtraArgs expanded as separate arguments,
+	// {{mmproj}} "--mmproj <path>" when the model has a paired projector.
+	// Example: "nice -n 10 {{bin}} -m {{model}} --port {{port}} {{args}}"
+	CommandTemplate string   `json:"command_template"`
+	ExtraArgs       []string `json:"extra_args"`
+	// WhisperCommandTemplate and WhisperExtraArgs do the same for
+	// whisper.cpp models, launched with whisper-server; {{mmproj}} is
+	// always empty.
+	WhisperCommandTemplate string   `json:"whisper_command_template"`
+	WhisperExtraArgs       []string `json:"whisper_extra_args"`
+	// MetadataCommand is run once per scanned model with its path as the
+	// last argument; the JSON object it prints is shown in the details
+	// pane and can be filtered and sorted on.
+	MetadataCommand []string `json:"metadata_command"`
+	// ScanCommand is run on each downloaded file with its path as the last
+	// argument before it is listed, e.g. ["clamscan", "--no-summary"]; a
+	// non-zero exit quarantines the file. The verdict goes in its
+	// provenance.
+	ScanCommand []string `json:"scan_command"`
+	// CheckForUpdates opts in to a GitHub releases check on startup.
+	CheckForUpdates bool `json:"check_for_updates"`
+	// LogRetention prunes old log files on startup and after each stop.
+	LogRetention logRetention `json:"log_retention"`
+	// StatusFile overrides where the JSON status file is written; "off"
+	// disables it.
+	StatusFile string `json:"status_file"`
+	// ClientConfigDir overrides where LiteLLM and Open WebUI configs for
+	// the running servers are written; "off" disables them.
+	ClientConfigDir string `json:"client_config_dir"`
+	// VisionTestImage is the image sent by the vision test; a generated
+	// sample is used when empty.
+	VisionTestImage string `json:"vision_test_image"`
+	// WarmupPrompt is sent once the server is ready, with the streamed reply
+	// previewed in the footer. Empty disables warm-up.
+	WarmupPrompt    string `json:"warmup_prompt"`
+	WarmupMaxTokens int    `json:"warmup_max_tokens"`
+	// BasicMode starts in basic mode, with only the model list, port, and
+	// start/stop; [u] switches to advanced mode.
+	BasicMode bool `json:"basic_mode"`
+	// DisableMouse starts without mouse capture, trading wheel scrolling for
+	// native terminal text selection.
+	DisableMouse bool `json:"disable_mouse"`
+	// LowMemory reduces the TUI's own overhead: uncolored logs, a smaller
+	// log buffer, and fewer redraws.
+	LowMemory bool `json:"low_memory"`
+	// MemoryWarnPercent and MemoryCritPercent are the shares of system
+	// memory at which the server's memory turns yellow and red; defaults
+	// 75 and 90.
+	MemoryWarnPercent float64 `json:"memory_warn_percent"`
+	MemoryCritPercent float64 `json:"memory_crit_percent"`
+	// Presets are named launch configurations usable with --preset.
+	Presets map[string]launchPreset `json:"presets"`
+	// BenchDepths are the context depths llama-bench measures at.
+	BenchDepths []int `json:"bench_depths"`
+	// BenchSweep is the grid of batch sizes and depths [Z] measures.
+	BenchSweep benchSweepConfig `json:"bench_sweep"`
+	// Catalogs are remote listings whose models download on first launch.
+	Catalogs []catalogSource `json:"catalogs"`
+	// AutoPorts assigns each model a stable port from this range when the
+	// port input is left empty.
+	AutoPorts portRange `json:"auto_ports"`
+	// StartupChecks shows the diagnostics checklist at startup: "on_failure"
+	// (default) when a check fails, "always", or "off".
+	StartupChecks string `json:"startup_checks"`
+	// Theme is the color palette: "mocha" (default), "high-contrast", or
+	// "colorblind". The accessible themes also mark server states with
+	// symbols.
+	Theme string `json:"theme"`
+	// HideDetailsPane keeps the two-column layout on wide terminals.
+	HideDetailsPane bool `json:"hide_details_pane"`
+	// GroupModelFamilies starts with the models list grouped by family.
+	GroupModelFamilies bool `json:"group_model_families"`
+	// ProxyPort runs a proxy on this port that forwards to the running
+	// server, giving clients a stable address and recording latencies.
+	ProxyPort string `json:"proxy_port"`
+	// ProxyHost is the address the proxy listens on; 127.0.0.1 unless set.
+	// The proxy has no authentication, so a wider address such as 0.0.0.0
+	// lets anyone on the network use the model.
+	ProxyHost string `json:"proxy_host"`
+	// ProxyMirrorPort is a second running server that also receives each
+	// POST through the proxy; both responses are recorded for comparison.
+	ProxyMirrorPort string `json:"proxy_mirror_port"`
+	// ProxyMirrorRecord also writes each mirrored request and both
+	// responses, full prompts included, to the mirror log. Off by default.
+	ProxyMirrorRecord bool `json:"proxy_mirror_record"`
+	// Failover relaunches a server that crashes behind the proxy, on an
+	// alternate port if set, and retargets the proxy once it is ready.
+	Failover failoverPolicy `json:"failover"`
+	// ProxyRateLimit answers 429 to proxy clients over these limits.
+	ProxyRateLimit proxyRateLimit `json:"proxy_rate_limit"`
+	// QuickShare is the tunnel [Q] opens to the running server; a
+	// cloudflared quick tunnel by default.
+	QuickShare quickShareConfig `json:"quick_share"`
+	// SocketPath also serves the running model on this unix domain socket.
+	SocketPath string `json:"socket_path"`
+	// LogStream publishes the server logs, read-only, on this port or
+	// host:port for nc, curl, or websocat. A bare port is localhost only.
+	LogStream string `json:"log_stream"`
+	// ControlAPI answers status, start, and stop requests on this port or
+	// host:port, authenticated with the token from control-token. A bare
+	// port is localhost only.
+	ControlAPI string `json:"control_api"`
+	// Notifications push ready, crash, download, and benchmark events to
+	// webhooks, Slack, or ntfy topics; check them with notify-test.
+	Notifications []notificationSink `json:"notifications"`
+	// DockerImage is the image compose exports use; llama.cpp's server
+	// image by default.
+	DockerImage string `json:"docker_image"`
+	// MinFreeDiskGB is the free space launches expect where they write logs
+	// and slot saves; less is a preflight warning. Default 5, negative
+	// disables the check.
+	MinFreeDiskGB float64 `json:"min_free_disk_gb"`
+	// Workspaces are named models directories and presets with separate
+	// history, chosen with --workspace or [w].
+	Workspaces map[string]workspaceConfig `json:"workspaces"`
+	// DisableTmuxStatus stops publishing the server state to tmux options
+	// and the pane title when running inside tmux.
+	DisableTmuxStatus bool `json:"disable_tmux_status"`
+	// DisableTerminalTitle stops setting the terminal's title and progress
+	// to the server state.
+	DisableTerminalTitle bool `json:"disable_terminal_title"`
+	// TerminalProgress sends OSC 9;4 progress while loading and
+	// downloading: "auto" (default) on terminals known to show it, "on",
+	// or "off".
+	TerminalProgress string `json:"terminal_progress"`
+	// DisableSessionRestore stops saving the session for the next start to
+	// restore.
+	DisableSessionRestore bool `json:"disable_session_restore"`
+	// Timestamps sets the zone and layouts of times in file names, log
+	// files, and history.
+	Timestamps timestampConfig `json:"timestamps"`
+	// Readiness configures how a launched server is detected as ready.
+	Readiness readinessProbe `json:"readiness"`
+	// Power pauses or stops the server on low battery.
+	Power powerPolicy `json:"power"`
+	// Flaky marks models that crashed repeatedly in recent sessions.
+	Flaky flakyPolicy `json:"flaky"`
+	// ChatContext keeps A/B chat conversations inside the servers'
+	// context: warn, truncate, or summarize before a prompt overflows it.
+	ChatContext chatContextPolicy `json:"chat_context"`
+	// DependsOn lists, by model name or part of it, the models each model
+	// needs running; several servers stop dependents first.
+	DependsOn map[string][]string `json:"depends_on"`
+	// ExternalEndpoints are OpenAI-compatible servers run elsewhere, polled
+	// for the [I] dashboard beside the local servers.
+	ExternalEndpoints []externalEndpoint `json:"external_endpoints"`
+	// SLO is a latency objective checked against each request's timings.
+	SLO latencySLO `json:"slo"`
+	// Watchdog flags a server that stops answering without exiting.
+	Watchdog watchdogPolicy `json:"watchdog"`
+	// RestartSchedule restarts the running server on a cron schedule,
+	// e.g. "0 4 * * *" for 4am daily.
+	RestartSchedule string `json:"restart_schedule"`
+	// ClipboardLimitKB is the most log text ctrl+y copies without offering
+	// the tail or a file instead; default 256.
+	ClipboardLimitKB int `json:"clipboard_limit_kb"`
+	// BlobStore keeps downloads in the content-addressed store under
+	// <barn>/.blobs, linked into place by name.
+	BlobStore bool `json:"blob_store"`
+	// DownloadsDir is the folder [m] sweeps GGUF files from into the
+	// barn; "" is ~/Downloads.
+	DownloadsDir string `json:"downloads_dir"`
+	// ResourceCapacity replaces the detected total VRAM or RAM that preset
+	// reservations are scheduled against.
+	ResourceCapacity resourceReservation `json:"resource_capacity"`
+}
+
+// barnDirOverride is the --models-dir flag, or the directory entered at
+// the startup prompt.
+var barnDirOverride string
+
+// getDefaultBarnDir resolves the models directory, which also holds the
+// config file, before any workspace applies.
+// Priority:
+// 1) --models-dir
+// 2) LLAMA_TUI_MODELS_DIR environment variable
+// 3) $HOME/.llamabarn
+func getDefaultBarnDir() (string, error) {
+	if barnDirOverride != "" {
+		return filepath.Abs(barnDirOverride)
+	}
+	if envPath := strings.TrimSpace(os.Getenv("LLAMA_TUI_MODELS_DIR")); envPath != "" {
+		return filepath.Abs(envPath)
+	}
+	home, err := userHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("no home directory (%w); pass --models-dir or set LLAMA_TUI_MODELS_DIR", err)
+	}
+	return filepath.Join(home, llamaBarnRelativeDir), nil
+}
+
+// getConfigPath resolves the config file location.
+// Priority:
+// 1) LLAMA_TUI_CONFIG environment variable
+// 2) <user config dir>/llama-tui/llama-tui.json, if it exists
+// 3) $HOME/.llamabarn/llama-tui.json
+func getConfigPath(barnDir string) string {
+	if envPath := strings.TrimSpace(os.Getenv("LLAMA_TUI_CONFIG")); envPath != "" {
+		return envPath
+	}
+	if configDir := appConfigDir(); configDir != "" {
+		path := filepath.Join(configDir, configFileName)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(barnDir, configFileName)
+}
+
+// loadConfig reads the config file. A missing file is not an error and yields
+// the default configuration.
+func loadConfig(path string) (appConfig, error) {
+	var cfg appConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return appConfig{}, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	if err := cfg.Timestamps.resolve(); err != nil {
+		return appConfig{}, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	if err := checkExternalEndpoints(cfg.ExternalEndpoints); err != nil {
+		return appConfig{}, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// buildServerCommand expands the command template into argv.
+// launchArgs are appended to ExtraArgs for this launch only.
+func (c appConfig) buildServerCommand(bin, modelPath, mmprojPath, port string, launchArgs []string) ([]string, error) {
+	tmpl := c.CommandTemplate
+	if strings.TrimSpace(tmpl) == "" {
+		tmpl = defaultCommandTemplate
+	}
+	return expandCommandTemplate("command_template", tmpl, c.ExtraArgs, bin, modelPath, mmprojPath, port, launchArgs)
+}
+
+// buildWhisperCommand is buildServerCommand for whisper-server.
+func (c appConfig) buildWhisperCommand(bin, modelPath, port string, launchArgs []string) ([]string, error) {
+	tmpl := c.WhisperCommandTemplate
+	if strings.TrimSpace(tmpl) == "" {
+		tmpl = defaultWhisperCommandTemplate
+	}
+	return expandCommandTemplate("whisper_command_template", tmpl, c.WhisperExtraArgs, bin, modelPath, "", port, launchArgs)
+}
+
+// expandCommandTemplate substitutes the placeholders in tmpl, the value of
+// the config key named key.
+func expandCommandTemplate(key, tmpl string, extraArgs []string, bin, modelPath, mmprojPath, port string, launchArgs []string) ([]string, error) {
+	tokens, err := splitCommandLine(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	replacer := strings.NewReplacer("{{bin}}", bin, "{{model}}", modelPath, "{{port}}", port)
+	argv := make([]string, 0, len(tokens)+len(extraArgs)+len(launchArgs))
+	for _, tok := range tokens {
+		if tok == "{{args}}" {
+			argv = append(argv, extraArgs...)
+			argv = append(argv, launchArgs...)
+			continue
+		}
+		if tok == "{{mmproj}}" {
+			if mmprojPath != "" {
+				argv = append(argv, "--mmproj", mmprojPath)
+			}
+			continue
+		}
+		argv = append(argv, replacer.Replace(tok))
+	}
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("%s expands to an empty command", key)
+	}
+	return argv, nil
+}
+
+// splitCommandLine splits s into words, honoring single and double quotes
+// and backslash escapes the way a POSIX shell would for simple cases.
+func splitCommandLine(s string) ([]string, error) {
+	var words []string
+	var cur strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words, nil
+}
@@ -1,9 +1,26 @@
 package main
 
+import "time"
+
 const (
-	appTitle                     = "llama-tui"
-	llamaBarnRelativeDir         = ".llamabarn"
-	logsRelativeDir              = "llama-server-logs"
-	defaultPort                  = "8080"
-	logBufferSoftLimitCharacters = 2_000_000
+	appTitle                      = "llama-tui"
+	llamaBarnRelativeDir          = ".llamabarn"
+	legacyLogsRelativeDir         = "llama-server-logs"
+	defaultPort                   = "8080"
+	logBufferSoftLimitCharacters  = 2_000_000
+	maxLogLineBytes               = 64 * 1024
+	minTerminalWidth              = 80
+	minTerminalHeight             = 24
+	detailsPaneMinTerminalWidth   = 180
+	defaultMemWarnPercent         = 75.0
+	defaultMemCritPercent         = 90.0
+	configFileName                = "llama-tui.json"
+	lockFileName                  = "llama-tui.lock"
+	lockCheckInterval             = 2 * time.Second
+	confirmTimeout                = 5 * time.Second
+	warmupTimeout                 = 10 * time.Minute
+	defaultWarmupMaxTokens        = 64
+	releasesAPIURL                = "https://api.github.com/repos/takaf3/llama-tui/releases/latest"
+	defaultCommandTemplate        = "{{bin}} -m {{model}} --port {{port}} --jinja {{mmproj}} {{args}}"
+	defaultWhisperCommandTemplate = "{{bin}} -m {{model}} --port {{port}} {{args}}"
 )
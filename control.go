@@ -0,0 +1,311 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+)
+
+// controlReplyTimeout bounds how long a request waits for the UI to act
+// on it.
+const controlReplyTimeout = 5 * time.Second
+
+// controlAPI answers HTTP requests to check, start, and stop the server,
+// for home automation and scripts. Every request needs the token from
+// controlTokenPath as a bearer token. Starts and stops are handed to the
+// UI, which acts on them as if the keys were pressed.
+type controlAPI struct {
+	addr     string
+	token    string
+	requests chan controlRequest
+}
+
+// controlRequest is a start or stop waiting for the UI.
+type controlRequest struct {
+	action string
+	model  string
+	reply  chan controlReply
+}
+
+type controlReply struct {
+	code int
+	body any
+}
+
+// newControlAPI resolves control_api like log_stream: a bare port listens
+// on localhost only. The token is created on first use.
+func newControlAPI(listen string) (*controlAPI, error) {
+	addr := strings.TrimSpace(listen)
+	if _, err := validatePort(addr); err == nil {
+		addr = net.JoinHostPort("127.0.0.1", addr)
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := validatePort(port); err != nil {
+		return nil, err
+	}
+	token, err := loadControlToken()
+	if err != nil {
+		return nil, err
+	}
+	return &controlAPI{addr: addr, token: token, requests: make(chan controlRequest)}, nil
+}
+
+// controlTokenPath keeps the token with the configuration, readable only
+// by the user.
+func controlTokenPath() string {
+	dir := appConfigDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "control-token")
+}
+
+// loadControlToken reads the token, generating one the first time.
+func loadControlToken() (string, error) {
+	path := controlTokenPath()
+	if path == "" {
+		return "", fmt.Errorf("no config directory for the token")
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		if token := strings.TrimSpace(string(data)); token != "" {
+			return token, nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0o600); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// serveControlCmd listens for the lifetime of the app; it only returns if
+// the listener fails.
+func serveControlCmd(c *controlAPI) tea.Cmd {
+	return func() tea.Msg {
+		ln, err := net.Listen("tcp", c.addr)
+		if err != nil {
+			return controlStoppedMsg{err: err}
+		}
+		return controlStoppedMsg{err: http.Serve(ln, c)}
+	}
+}
+
+// waitControlCmd delivers the next start or stop to the UI.
+func waitControlCmd(c *controlAPI) tea.Cmd {
+	return func() tea.Msg {
+		return controlRequestMsg{req: <-c.requests}
+	}
+}
+
+func (c *controlAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(auth), []byte(c.token)) != 1 {
+		writeControlJSON(w, http.StatusUnauthorized, controlError("missing or wrong token"))
+		return
+	}
+	switch r.URL.Path {
+	case "/status":
+		if r.Method != http.MethodGet {
+			writeControlJSON(w, http.StatusMethodNotAllowed, controlError("use GET"))
+			return
+		}
+		st := serverStatus{State: "stopped"}
+		if m := lastModel.Load(); m != nil {
+			st = m.serverStatus()
+		}
+		writeControlJSON(w, http.StatusOK, st)
+	case "/start", "/stop":
+		if r.Method != http.MethodPost {
+			writeControlJSON(w, http.StatusMethodNotAllowed, controlError("use POST"))
+			return
+		}
+		req := controlRequest{action: strings.TrimPrefix(r.URL.Path, "/"), reply: make(chan controlReply, 1)}
+		if req.action == "start" {
+			req.model = r.URL.Query().Get("model")
+			if req.model == "" {
+				var body struct {
+					Model string `json:"model"`
+				}
+				_ = json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&body)
+				req.model = body.Model
+			}
+			if req.model == "" {
+				writeControlJSON(w, http.StatusBadRequest, controlError(`name the model: {"model": "..."} or ?model=`))
+				return
+			}
+		}
+		timeout := time.After(controlReplyTimeout)
+		select {
+		case c.requests <- req:
+		case <-timeout:
+			writeControlJSON(w, http.StatusServiceUnavailable, controlError("llama-tui is busy"))
+			return
+		case <-r.Context().Done():
+			return
+		}
+		select {
+		case reply := <-req.reply:
+			writeControlJSON(w, reply.code, reply.body)
+		case <-timeout:
+			writeControlJSON(w, http.StatusServiceUnavailable, controlError("llama-tui is busy"))
+		}
+	default:
+		writeControlJSON(w, http.StatusNotFound, controlError("endpoints: GET /status, POST /start, POST /stop"))
+	}
+}
+
+func controlError(msg string) map[string]string {
+	return map[string]string{"error": msg}
+}
+
+func writeControlJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// handleControlRequest carries out a start or stop from the control API
+// and answers it with the resulting status.
+func (m appModel) handleControlRequest(req controlRequest) (appModel, tea.Cmd) {
+	reply := func(code int, body any) {
+		req.reply <- controlReply{code: code, body: body}
+	}
+	refuse := func(code int, msg string) (appModel, tea.Cmd) {
+		reply(code, controlError(msg))
+		return m, waitControlCmd(m.control)
+	}
+	switch {
+	case m.attached != nil:
+		return refuse(http.StatusConflict, "attached to a server started elsewhere")
+	case m.readOnly:
+		return refuse(http.StatusConflict, fmt.Sprintf("read-only: llama-tui pid %d manages this barn", m.lockOwner))
+	case m.observer:
+		return refuse(http.StatusForbidden, "started with --read-only")
+	}
+
+	var cmd tea.Cmd
+	var item modelItem
+	switch req.action {
+	case "start":
+		var err error
+		if item, err = m.controlModel(req.model); err != nil {
+			return refuse(http.StatusNotFound, err.Error())
+		}
+		if m.server.running() {
+			if m.currentModelName == item.name {
+				reply(http.StatusOK, m.serverStatus())
+				return m, waitControlCmd(m.control)
+			}
+			return refuse(http.StatusConflict, "already serving "+m.currentModelName+"; stop it first")
+		}
+		if m.queued(item) {
+			// Asking again doesn't take it off the queue, as [enter] would
+			reply(http.StatusAccepted, serverStatus{State: "queued", Model: item.name})
+			return m, waitControlCmd(m.control)
+		}
+		m.logEvent("[control] Start " + item.name + " requested over the control API")
+		m.unattendedLaunch = item.name
+		m, cmd = m.requestStart(item)
+		if cmd == nil && m.queued(item) {
+			// Starts by itself once it fits; launch warnings are accepted
+			// then too
+			reply(http.StatusAccepted, serverStatus{State: "queued", Model: item.name})
+			return m, waitControlCmd(m.control)
+		}
+	case "stop":
+		if !m.server.serving() {
+			reply(http.StatusOK, m.serverStatus())
+			return m, waitControlCmd(m.control)
+		}
+		m.logEvent("[control] Stop requested over the control API")
+		m, cmd = m.handleStop()
+	}
+	if cmd == nil {
+		// Refused outright; the status line says why
+		m.unattendedLaunch = ""
+		return refuse(http.StatusConflict, m.statusLineText)
+	}
+	st := m.serverStatus()
+	if req.action == "start" && st.State == "stopped" {
+		// Launch checks run first; poll /status to follow it
+		st = serverStatus{State: "starting", Model: item.name}
+	}
+	reply(http.StatusAccepted, st)
+	return m, tea.Batch(cmd, waitControlCmd(m.control))
+}
+
+// controlModel finds the model a request names: its file name, with or
+// without the extension, or a part of it that only one model matches.
+func (m appModel) controlModel(name string) (modelItem, error) {
+	if item, ok := m.findModelByName(name); ok {
+		return item, nil
+	}
+	want := strings.ToLower(strings.TrimSuffix(name, ".gguf"))
+	var matches []modelItem
+	for _, it := range m.allModelItems() {
+		mi, ok := it.(modelItem)
+		if !ok {
+			continue
+		}
+		base := strings.ToLower(strings.TrimSuffix(mi.name, ".gguf"))
+		if base == want {
+			return mi, nil
+		}
+		if strings.Contains(base, want) {
+			matches = append(matches, mi)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return modelItem{}, fmt.Errorf("no model named %q", name)
+	case 1:
+		return matches[0], nil
+	}
+	names := make([]string, len(matches))
+	for i, mi := range matches {
+		names[i] = mi.name
+	}
+	return modelItem{}, fmt.Errorf("%q matches %s", name, strings.Join(names, ", "))
+}
+
+// newControlTokenCmd prints the control API token, creating it if needed,
+// for pasting into an automation's configuration.
+func newControlTokenCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "control-token",
+		Short: "Print the token the control API expects",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			token, err := loadControlToken()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), token)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// lastModel is the most recent state returned by Update, kept so a crash
+// report can describe the app and stop its server after Run returns.
+var lastModel atomic.Pointer[appModel]
+
+// crashInfo holds the first panic caught in Update or View; later panics
+// during unwinding add nothing useful.
+var crashInfo struct {
+	once  sync.Once
+	value any
+	stack []byte
+	model *appModel
+}
+
+// recordPanic keeps the panic, its stack, and the model that was being
+// updated or rendered. Callers re-panic so Bubble Tea restores the terminal.
+func recordPanic(r any, m *appModel) {
+	crashInfo.once.Do(func() {
+		crashInfo.value = r
+		crashInfo.stack = debug.Stack()
+		crashInfo.model = m
+	})
+}
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// writeCrashReport writes the panic, an app state snapshot, and the
+// in-memory logs to a file in the state directory and returns its path.
+func writeCrashReport(runErr error) (string, error) {
+	m := crashInfo.model
+	if m == nil {
+		m = lastModel.Load()
+	}
+	stateDir := appStateDir()
+	if stateDir == "" {
+		return "", errors.New("no state directory available")
+	}
+	if err := os.MkdirAll(stateDir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(stateDir, "crash-"+time.Now().Format("20060102-150405")+".log")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "llama-tui %s crash report\n", version)
+	fmt.Fprintf(&b, "time: %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&b, "go: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&b, "error: %v\n", runErr)
+	if crashInfo.value != nil {
+		fmt.Fprintf(&b, "\npanic: %v\n\n%s", crashInfo.value, crashInfo.stack)
+	} else {
+		// Panics in commands are caught by Bubble Tea before we see them
+		b.WriteString("\npanic: in a background command (stack printed to the terminal)\n")
+	}
+	if m != nil {
+		b.WriteString("\n== state ==\n")
+		fmt.Fprintf(&b, "barn: %s\n", m.barnDir)
+		plain := *m
+		plain.styles.statusSymbols = false
+		label, _ := plain.statusChip()
+		fmt.Fprintf(&b, "server: %s paused=%t\n", strings.Fields(label)[0], m.powerPaused)
+		fmt.Fprintf(&b, "model: %s\n", m.currentModelName)
+		if mi, ok := m.findModelByName(m.currentModelName); ok {
+			for _, line := range provenanceLines(mi) {
+				fmt.Fprintf(&b, "  %s\n", line)
+			}
+		}
+		fmt.Fprintf(&b, "port: %s\n", m.currentPort)
+		if m.process != nil {
+			fmt.Fprintf(&b, "pid: %d\n", m.process.pid)
+		}
+		fmt.Fprintf(&b, "launch args: %s\n", strings.Join(m.launchArgs, " "))
+		fmt.Fprintf(&b, "log file: %s\n", m.logFilePath)
+		fmt.Fprintf(&b, "status: %s\n", m.statusLineText)
+		b.WriteString("\n== logs ==\n")
+		b.WriteString(ansiEscape.ReplaceAllString(m.logBuffer.String(), ""))
+		b.WriteString("\n")
+	}
+	return path, os.WriteFile(path, []byte(b.String()), 0o644)
+}
+
+// stopServerAfterCrash stops the managed server and the side servers so
+// they are not orphaned, escalating to a kill if one ignores the interrupt.
+func stopServerAfterCrash() {
+	m := crashInfo.model
+	if m == nil {
+		m = lastModel.Load()
+	}
+	if m != nil && m.standby != nil {
+		m.standby.stop()
+	}
+	if m == nil {
+		return
+	}
+	// The supervisor resumes a paused server and kills the group if it
+	// ignores the interrupt
+	sides := make(chan struct{})
+	go func() {
+		defer close(sides)
+		m.waitForSideServers()
+	}()
+	m.process.stopAndWait(stopGrace + time.Second)
+	<-sides
+}
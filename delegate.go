@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	servingBadge = "▶ "
+	remoteBadge  = "☁ "
+	pinnedBadge  = "★ "
+	standbyBadge = "◇ "
+	flakyMark    = "⚠ "
+)
+
+// modelDelegate renders a model as two lines: the name with a serving badge
+// and size/quant/params on the right, then the relative path dimmed.
+type modelDelegate struct {
+	styles       uiStyles
+	servingPath  string
+	servingStyle lipgloss.Style
+	// standbyPath is the model preloaded as a standby, if any
+	standbyPath  string
+	standbyStyle lipgloss.Style
+	// smoke holds first-run test results by model path
+	smoke map[string]smokeResult
+	// flaky holds models that crashed repeatedly, by name
+	flaky map[string]bool
+	// unavailable holds why a model's files can't be read, by path
+	unavailable map[string]string
+}
+
+func newModelDelegate(styles uiStyles, servingPath string) modelDelegate {
+	return modelDelegate{styles: styles, servingPath: servingPath, servingStyle: styles.servingBadge}
+}
+
+func (d modelDelegate) Height() int                             { return 2 }
+func (d modelDelegate) Spacing() int                            { return 1 }
+func (d modelDelegate) Update(_ tea.Msg, _ *list.Model) tea.Cmd { return nil }
+
+func (d modelDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	width := m.Width()
+	if width <= 0 {
+		return
+	}
+	if f, ok := item.(familyItem); ok {
+		d.renderFamily(w, m, index, f)
+		return
+	}
+	mi, ok := item.(modelItem)
+	if !ok {
+		return
+	}
+	selected := index == m.Index()
+
+	// Selection marker column, as in the default delegate
+	gutter := "  "
+	if selected {
+		gutter = d.styles.accent.Render("│ ")
+	}
+	if mi.inFamily {
+		// Indented under its family's header
+		gutter += "  "
+	}
+	avail := width - lipgloss.Width(gutter)
+
+	badge := ""
+	badgeStyle := d.styles.servingBadge
+	if d.servingPath != "" && mi.path == d.servingPath {
+		badge, badgeStyle = servingBadge, d.servingStyle
+	} else if d.standbyPath != "" && mi.path == d.standbyPath {
+		badge, badgeStyle = standbyBadge, d.standbyStyle
+	} else if mi.remoteURL != "" || (mi.hfRepo != "" && mi.hfCache == "") {
+		badge = remoteBadge
+	} else if mi.pinned {
+		badge = pinnedBadge
+	}
+	details := modelDetails(mi)
+	_, unavailable := d.unavailable[mi.path]
+	if unavailable {
+		details = "unavailable"
+	}
+	smoke := ""
+	if d.flaky[mi.name] {
+		smoke = d.styles.usageCritical.Render(flakyMark)
+	}
+	if r, ok := d.smoke[mi.path]; ok {
+		if r.Passed {
+			smoke += d.styles.servingBadge.Render(smokeMark)
+		} else {
+			smoke += d.styles.usageCritical.Render(smokeFailMark)
+		}
+	}
+	nameWidth := avail - lipgloss.Width(badge) - lipgloss.Width(smoke) - lipgloss.Width(details) - 1
+	if nameWidth < 8 {
+		// Too narrow for details; give the name the whole line
+		details, smoke = "", ""
+		nameWidth = avail - lipgloss.Width(badge)
+	}
+	name := ellipsize(mi.name, nameWidth)
+	gap := avail - lipgloss.Width(badge) - lipgloss.Width(name) - lipgloss.Width(smoke) - lipgloss.Width(details)
+	if gap < 0 {
+		gap = 0
+	}
+
+	titleStyle := lipgloss.NewStyle()
+	if selected {
+		titleStyle = d.styles.accent.Bold(true)
+	}
+	if unavailable {
+		titleStyle = d.styles.disabled
+	}
+	title := badgeStyle.Render(badge) +
+		titleStyle.Render(name) + strings.Repeat(" ", gap) + smoke + d.styles.status.Render(details)
+	desc := d.styles.disabled.Render(ellipsize(mi.Description(), avail))
+
+	fmt.Fprintf(w, "%s%s\n%s%s", gutter, title, gutter, desc)
+}
+
+// modelDetails summarizes params, quantization, and size, skipping unknowns.
+func modelDetails(mi modelItem) string {
+	var parts []string
+	if mi.params != "" {
+		parts = append(parts, mi.params)
+	}
+	if mi.quant != "" {
+		parts = append(parts, mi.quant)
+	}
+	if mi.size > 0 {
+		parts = append(parts, formatBytes(uint64(mi.size)))
+	}
+	if mi.remoteURL != "" || (mi.hfRepo != "" && mi.hfCache == "") {
+		parts = append(parts, "not downloaded")
+	}
+	return strings.Join(parts, " · ")
+}
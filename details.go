@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// renderDetailsPane is the third column on wide terminals: the selected
+// model's metadata, then live metrics for the running server.
+func (m appModel) renderDetailsPane() string {
+	width := m.detailsWidth
+	row := func(label, value string) string {
+		if value == "" {
+			return ""
+		}
+		return m.styles.help.Render(fmt.Sprintf("%-9s", label)) + ellipsize(value, width-9)
+	}
+	var lines []string
+	add := func(s string) {
+		if s != "" {
+			lines = append(lines, s)
+		}
+	}
+
+	if mi, ok := m.modelsList.SelectedItem().(modelItem); ok {
+		add(m.styles.accent.Bold(true).Render(ellipsize(m.displayName(mi.name), width)))
+		if reason, ok := m.unavailable[mi.path]; ok {
+			add(m.styles.usageCritical.Render(ellipsize("Unavailable: "+reason, width)))
+		}
+		add(row("Arch", mi.arch))
+		if mi.kind == kindWhisper {
+			add(row("Server", mi.kind.serverName()))
+		}
+		add(row("Params", mi.params))
+		add(row("Quant", mi.quant))
+		if l := mi.license; l.known() || l.gated {
+			add(row("License", l.summary()))
+			if l.restricted() {
+				note := "  restricted terms: acknowledged on first launch"
+				style := m.styles.usageWarn
+				if ack, ok := m.licenseAcks[mi.name]; ok && !m.needsLicenseAck(mi) {
+					note, style = "  acknowledged "+m.config.Timestamps.date(ack.At), m.styles.help
+				}
+				add(style.Render(ellipsize(note, width)))
+			}
+			add(row("Terms", l.link))
+		}
+		if mi.size > 0 {
+			add(row("Size", formatBytes(uint64(mi.size))))
+		}
+		if mi.contextLength > 0 {
+			add(row("Context", fmt.Sprintf("%d (%s)", mi.contextLength, formatContextLength(mi.contextLength))))
+		}
+		if mi.mmproj != "" {
+			add(row("Vision", filepath.Base(mi.mmproj)))
+		}
+		if mi.pinned {
+			add(row("Pinned", "yes"))
+		}
+		if mi.remoteURL != "" {
+			add(row("Remote", mi.remoteURL))
+		}
+		if r, ok := m.smokeResults[mi.path]; ok {
+			add(row("Smoke", r.summary()+" ("+m.config.Timestamps.date(r.At)+")"))
+		}
+		if m.config.AutoPorts.enabled() {
+			add(row("Port", m.launchPort(mi)))
+		}
+		if mi.hfRepo != "" {
+			add(row("Repo", "https://huggingface.co/"+strings.SplitN(mi.hfRepo, ":", 2)[0]))
+			cache := mi.hfCache
+			if cache == "" {
+				cache = "not downloaded yet (" + llamaCacheDir() + ")"
+			}
+			add(row("Cache", cache))
+		} else {
+			add(row("Path", mi.path))
+		}
+		add(row("Blob", m.blobSummary(mi)))
+		add(row("Saved", m.modelConfigSummary(mi.name)))
+		if crashes := m.reliabilitySummary(mi.name); crashes != "" {
+			flaky := flakyModels(m.reliability, m.config.Flaky)[mi.name]
+			style := m.styles.help
+			if flaky {
+				style = m.styles.usageCritical
+			}
+			add(style.Render(fmt.Sprintf("%-9s", "Crashes")) + ellipsize(crashes, width-9))
+			if flaky {
+				add(m.styles.help.Render(ellipsize("  flaky: try re-downloading or another quant", width)))
+			}
+		}
+		if len(mi.meta) > 0 {
+			lines = append(lines, "", m.styles.help.Render("Metadata"))
+			keys := make([]string, 0, len(mi.meta))
+			for key := range mi.meta {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				add(row(ellipsize(key, 8), mi.meta[key]))
+			}
+		}
+		if p := mi.provenance; p != nil {
+			lines = append(lines, "", m.styles.help.Render("Provenance"))
+			add(row("Source", p.SourceURL))
+			add(row("Revision", p.Revision))
+			add(row("Fetched", m.config.Timestamps.dateTime(p.DownloadedAt)))
+			add(row("SHA256", p.SHA256))
+			if p.Scan != nil {
+				add(row("Scan", p.Scan.describe()+", "+m.config.Timestamps.dateTime(p.Scan.ScannedAt)))
+			}
+		}
+		var bench []string
+		for _, r := range m.benchResults {
+			if r.Model == mi.name {
+				bench = append(bench, fmt.Sprintf("%s %.1f t/s", r.column(), r.TokensSec))
+			}
+		}
+		if len(bench) > 0 {
+			lines = append(lines, "", m.styles.help.Render("Benchmarks"))
+			for _, b := range bench {
+				lines = append(lines, "  "+ellipsize(b, width-2))
+			}
+		}
+	} else {
+		add(m.styles.disabled.Render("No model selected"))
+	}
+
+	if m.server.running() {
+		lines = append(lines, "", m.styles.help.Render("Server"))
+		add(row("Model", m.currentModelName))
+		add(row("Port", m.currentPort))
+		if m.socket != nil {
+			add(row("Socket", m.socket.path))
+		}
+		if !m.serverStartedAt.IsZero() {
+			add(row("Uptime", time.Since(m.serverStartedAt).Round(time.Second).String()))
+		}
+		if m.cpuPercent > 0 || m.memRSSBytes > 0 {
+			graph := func(values []float64, floor float64) {
+				if spark := sparkline(values, width-9, floor); spark != "" {
+					add(strings.Repeat(" ", 9) + m.styles.accent.Render(spark))
+				}
+			}
+			add(row("CPU", fmt.Sprintf("%.1f%%", m.cpuPercent)))
+			graph(m.cpuHistory, 100)
+			add(m.styles.help.Render(fmt.Sprintf("%-9s", "Mem")) + m.memoryUsageStyle().Render(m.formatMemoryUsage()))
+			graph(m.memHistory, 0)
+			if m.vramBytes > 0 {
+				add(row("VRAM", formatBytes(m.vramBytes)))
+			}
+			if len(m.gpuHistory) > 0 {
+				add(row("GPU", fmt.Sprintf("%.0f%%", m.gpuPercent)))
+				graph(m.gpuHistory, 100)
+			}
+			add(row("Sampled", m.metricsCadence()))
+		}
+		if m.clientCount >= 0 {
+			add(row("Clients", fmt.Sprintf("%d", m.clientCount)))
+		}
+		if next := m.nextRestartText(); next != "" && m.server.serving() {
+			add(row("Restart", next))
+		}
+		if s := m.logStream; s != nil {
+			add(row("Stream", fmt.Sprintf("%s (%s)", s.addr, pluralize(int(s.viewers.Load()), "viewer"))))
+		}
+		if h := m.health; m.server.serving() && h.state != healthUnknown {
+			health := h.state.String()
+			if h.detail != "" {
+				health += " - " + h.detail
+			}
+			add(m.styles.help.Render(fmt.Sprintf("%-9s", "Health")) + m.healthStyle().Render(ellipsize(health, width-9)))
+			if summary := h.summary(); summary != "" {
+				add(row("", summary))
+			}
+			if m.hung != "" {
+				hint := "HUNG: " + m.hung
+				if m.attached == nil {
+					hint += " - [R] restart"
+				}
+				add(m.styles.logError.Render(ellipsize(hint, width)))
+			}
+		}
+		if m.spec.requests > 0 || m.usesDraftModel() {
+			add(row("Draft", m.specSummary()))
+			if s := m.spec; s.requests > 0 {
+				add(row("", fmt.Sprintf("%d / %d drafted tokens over %s", s.accepted, s.drafted, pluralize(s.requests, "request"))))
+			}
+		}
+		if m.process != nil {
+			// Wrapped rather than cut off, to check exactly what launched
+			wrapped := lipgloss.NewStyle().Width(max(width-9, 10)).Render(strings.Join(m.process.args, " "))
+			for i, line := range strings.Split(wrapped, "\n") {
+				label := ""
+				if i == 0 {
+					label = "Command"
+				}
+				add(m.styles.help.Render(fmt.Sprintf("%-9s", label)) + strings.TrimRight(line, " "))
+			}
+		} else {
+			add(row("Args", strings.Join(m.launchArgs, " ")))
+		}
+	}
+
+	body := strings.Join(lines, "\n")
+	// Pad to the panel height so the columns line up
+	if pad := m.contentHeight - lipgloss.Height(body); pad > 0 {
+		body += strings.Repeat("\n", pad)
+	}
+	return lipgloss.NewStyle().Width(width).MaxHeight(m.contentHeight).Render(body)
+}
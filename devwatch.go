@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// devWatchInterval is how often dev mode checks the served files.
+const devWatchInterval = 2 * time.Second
+
+// fileStamp identifies a version of a file; a missing file has ok unset.
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+	ok      bool
+}
+
+func statFile(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{size: info.Size(), modTime: info.ModTime(), ok: true}
+}
+
+// adapterFlags name the adapter files a launch loads on top of the model.
+var adapterFlags = map[string]bool{
+	"--lora":           true,
+	"--lora-scaled":    true,
+	"--control-vector": true,
+}
+
+// watchedFiles are the files the running server was launched with, as
+// its command line names them, presets and one-off flags included: the
+// model, its projector, and any LoRA adapters or control vectors.
+// statWatchedCmd adds the other shards of a split model.
+func (m appModel) watchedFiles() []string {
+	if m.process == nil {
+		return nil
+	}
+	var files []string
+	for _, f := range parseFlagArgs(m.process.args) {
+		switch {
+		case f.name == "--model" || f.name == "--mmproj" || f.name == "-mm":
+			if f.value != "" {
+				files = append(files, f.value)
+			}
+		case adapterFlags[f.name]:
+			// Newer builds take a comma-separated list
+			for _, path := range strings.Split(f.value, ",") {
+				path, err := expandHome(m.homeDir, strings.TrimSpace(path))
+				if err == nil && path != "" {
+					files = append(files, path)
+				}
+			}
+		}
+	}
+	return files
+}
+
+// shardPaths lists every part of a split GGUF given its first part, or
+// just path for a single file.
+func shardPaths(path string) []string {
+	match := sweepShardPattern.FindStringSubmatch(filepath.Base(path))
+	if match == nil {
+		return []string{path}
+	}
+	parts, _ := filepath.Glob(filepath.Join(filepath.Dir(path), match[1]+"-*-of-*.gguf"))
+	if len(parts) == 0 {
+		return []string{path}
+	}
+	return parts
+}
+
+func devWatchTickCmd(gen int) tea.Cmd {
+	return tea.Tick(devWatchInterval, func(time.Time) tea.Msg {
+		return devWatchTickMsg{gen: gen}
+	})
+}
+
+// statWatchedCmd stamps the files, every shard of a split model, off the
+// UI loop; models on a network mount can be slow to list and stat.
+func statWatchedCmd(gen int, files []string) tea.Cmd {
+	return func() tea.Msg {
+		stamps := make(map[string]fileStamp, len(files))
+		for _, f := range files {
+			for _, part := range shardPaths(f) {
+				stamps[part] = statFile(part)
+			}
+		}
+		return devWatchStatMsg{gen: gen, stamps: stamps}
+	}
+}
+
+// toggleDevWatch turns dev mode on or off.
+func (m appModel) toggleDevWatch() (appModel, tea.Cmd) {
+	if m.readOnly {
+		m.statusLineText = fmt.Sprintf("Read-only: llama-tui pid %d manages the server - [T] take over", m.lockOwner)
+		return m, nil
+	}
+	m.devWatch = !m.devWatch
+	m.devWatchGen++
+	m.watchStamps, m.watchPending, m.watchModel = nil, nil, ""
+	if !m.devWatch {
+		m.statusLineText = "Dev mode off: file changes are ignored"
+		return m, nil
+	}
+	m.statusLineText = "Dev mode on: the server restarts when its model or adapter files change"
+	return m, devWatchTickCmd(m.devWatchGen)
+}
+
+func (m appModel) handleDevWatchTick(msg devWatchTickMsg) (appModel, tea.Cmd) {
+	if msg.gen != m.devWatchGen || !m.devWatch {
+		return m, nil
+	}
+	if m.server != serverReady || m.attached != nil || m.readOnly {
+		if !m.server.busy() {
+			// Stopped; the next launch reads the files afresh
+			m.watchStamps, m.watchModel = nil, ""
+		}
+		m.watchPending = nil
+		return m, devWatchTickCmd(m.devWatchGen)
+	}
+	return m, statWatchedCmd(msg.gen, m.watchedFiles())
+}
+
+// handleDevWatchStat compares the files with the ones the server loaded.
+// A change is acted on once two checks in a row agree, so a file still
+// being written (a training run exporting an adapter) isn't loaded half
+// done.
+func (m appModel) handleDevWatchStat(msg devWatchStatMsg) (appModel, tea.Cmd) {
+	if msg.gen != m.devWatchGen || !m.devWatch {
+		return m, nil
+	}
+	tick := devWatchTickCmd(m.devWatchGen)
+	if m.server != serverReady {
+		return m, tick
+	}
+	if m.watchStamps == nil || m.watchModel != m.currentModelName {
+		m.watchStamps, m.watchModel, m.watchPending = msg.stamps, m.currentModelName, nil
+		return m, tick
+	}
+	var changed []string
+	for path, s := range msg.stamps {
+		if s != m.watchStamps[path] {
+			changed = append(changed, path)
+		}
+	}
+	if len(changed) == 0 {
+		m.watchPending = nil
+		return m, tick
+	}
+	sort.Strings(changed)
+	settled := m.watchPending != nil
+	for _, path := range changed {
+		settled = settled && msg.stamps[path] == m.watchPending[path]
+	}
+	if !settled {
+		m.watchPending = msg.stamps
+		return m, tick
+	}
+	names := make([]string, len(changed))
+	for i, path := range changed {
+		names[i] = filepath.Base(path)
+		if !msg.stamps[path].ok {
+			m.statusLineText = "Dev mode: waiting for " + names[i] + " to come back"
+			return m, tick
+		}
+	}
+	changedText := strings.Join(names, ", ")
+	if m.requestsActive() {
+		m.statusLineText = "Dev mode: " + changedText + " changed; restarting once requests finish"
+		return m, tick
+	}
+	m.watchStamps, m.watchPending = msg.stamps, nil
+	m.logEvent(fmt.Sprintf("[dev] %s changed; restarting %s", changedText, m.currentModelName))
+	m, cmd := m.restartServer()
+	m.statusLineText = "Dev mode: " + changedText + " changed - restarting"
+	return m, tea.Batch(cmd, tick)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type checkStatus int
+
+const (
+	checkPass checkStatus = iota
+	checkWarn
+	checkFail
+)
+
+func (s checkStatus) String() string {
+	switch s {
+	case checkPass:
+		return "ok"
+	case checkWarn:
+		return "warn"
+	default:
+		return "fail"
+	}
+}
+
+// diagnosticCheck is one line of the startup checklist.
+type diagnosticCheck struct {
+	name   string
+	status checkStatus
+	detail string
+	hint   string
+}
+
+// runDiagnostics checks everything a launch depends on in one pass.
+func runDiagnostics(barnDir, logsDir, port string) []diagnosticCheck {
+	return []diagnosticCheck{
+		checkServerBinary(),
+		checkBarnDir(barnDir),
+		checkLogsDir(logsDir),
+		checkPortFree(port),
+		checkGPU(),
+		checkServerBackend(),
+	}
+}
+
+func checkServerBinary() diagnosticCheck {
+	c := diagnosticCheck{name: "llama-server"}
+	bin, err := getLlamaServerBinary()
+	if err != nil {
+		c.status, c.detail = checkFail, "not found"
+		c.hint = "Install llama.cpp (e.g. brew install llama.cpp) or set LLAMA_SERVER_BIN"
+		return c
+	}
+	if info, err := os.Stat(bin); err == nil && runtime.GOOS != "windows" && info.Mode()&0o111 == 0 {
+		c.status, c.detail = checkFail, bin+" is not executable"
+		c.hint = "chmod +x " + bin
+		return c
+	}
+	c.detail = bin
+	return c
+}
+
+func checkBarnDir(barnDir string) diagnosticCheck {
+	c := diagnosticCheck{name: "Models directory", detail: barnDir}
+	entries, err := os.ReadDir(barnDir)
+	switch {
+	case os.IsNotExist(err):
+		c.status, c.hint = checkFail, "Press [c] to create it or [b] to choose another directory"
+	case err != nil:
+		c.status, c.detail, c.hint = checkFail, err.Error(), "Check the directory permissions"
+	default:
+		c.detail = fmt.Sprintf("%s (%d entries)", barnDir, len(entries))
+	}
+	return c
+}
+
+func checkLogsDir(logsDir string) diagnosticCheck {
+	c := diagnosticCheck{name: "Logs directory", detail: logsDir}
+	if logsDir == "" {
+		c.status, c.hint = checkWarn, "No home directory; set XDG_STATE_HOME to log to files"
+		return c
+	}
+	if _, err := os.Stat(logsDir); os.IsNotExist(err) {
+		c.detail = logsDir + " (created on first logged start)"
+		return c
+	}
+	f, err := os.CreateTemp(logsDir, ".write-test-*")
+	if err != nil {
+		c.status, c.detail, c.hint = checkFail, err.Error(), "File logging ([l]) will fail; check permissions"
+		return c
+	}
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+	return c
+}
+
+func checkPortFree(port string) diagnosticCheck {
+	c := diagnosticCheck{name: "Port", detail: port}
+	n, err := validatePort(port)
+	if err != nil {
+		c.status, c.detail, c.hint = checkFail, err.Error(), "Press [p] to edit the port"
+		return c
+	}
+	if !portFree(n) {
+		c.status, c.detail = checkFail, port+" is in use"
+		c.hint = "Stop whatever is listening there or press [p] to pick another port"
+	}
+	return c
+}
+
+// checkGPU looks for a driver llama-server can offload to; CPU-only
+// machines get a warning, not a failure.
+func checkGPU() diagnosticCheck {
+	c := diagnosticCheck{name: "GPU"}
+	switch runtime.GOOS {
+	case "darwin":
+		c.detail = "Metal"
+		return c
+	case "linux":
+		if _, err := exec.LookPath("nvidia-smi"); err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+			out, err := exec.CommandContext(ctx, "nvidia-smi", "-L").Output()
+			if err == nil && len(strings.TrimSpace(string(out))) > 0 {
+				c.detail = strings.SplitN(strings.TrimSpace(string(out)), "\n", 2)[0]
+				return c
+			}
+			c.status, c.detail = checkWarn, "nvidia-smi found but no GPU listed"
+			c.hint = "Check that the NVIDIA driver is loaded (nvidia-smi)"
+			return c
+		}
+		if _, err := os.Stat("/dev/kfd"); err == nil {
+			c.detail = "ROCm (/dev/kfd)"
+			return c
+		}
+		if nodes, _ := filepath.Glob("/dev/dri/renderD*"); len(nodes) > 0 {
+			c.detail = "render node " + nodes[0] + " (Vulkan/SYCL)"
+			return c
+		}
+	}
+	c.status, c.detail = checkWarn, "no GPU driver detected"
+	c.hint = "llama-server will run on the CPU; install GPU drivers for offloading"
+	return c
+}
+
+// diagnosticsCmd runs the checks off the UI goroutine.
+func diagnosticsCmd(barnDir, logsDir, port string, startup bool) tea.Cmd {
+	return func() tea.Msg {
+		return diagnosticsDoneMsg{checks: runDiagnostics(barnDir, logsDir, port), startup: startup}
+	}
+}
+
+func diagnosticsFailed(checks []diagnosticCheck) bool {
+	for _, c := range checks {
+		if c.status == checkFail {
+			return true
+		}
+	}
+	return false
+}
+
+// renderDiagnostics draws the checklist with a fix hint under each problem.
+func (m appModel) renderDiagnostics() string {
+	lines := []string{}
+	for _, c := range m.diagnostics {
+		var mark string
+		switch c.status {
+		case checkPass:
+			mark = m.styles.propsAdded.Render("✓")
+		case checkWarn:
+			mark = m.styles.logWarn.Render("!")
+		default:
+			mark = m.styles.logError.Render("✗")
+		}
+		lines = append(lines, fmt.Sprintf("%s %-17s %s", mark, c.name, c.detail))
+		if c.hint != "" && c.status != checkPass {
+			lines = append(lines, "  "+m.styles.help.Render(strings.Repeat(" ", 17)+" "+c.hint))
+		}
+	}
+	lines = append(lines, "", m.styles.help.Render("[D] run again  [e] export report  [esc] close"))
+	return strings.Join(lines, "\n")
+}
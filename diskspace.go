@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+const defaultMinFreeDiskGB = 5
+
+// diskWritingFlags are llama-server options that write files during a run,
+// mapped to whether their value is a directory (else a file).
+var diskWritingFlags = map[string]bool{
+	"--slot-save-path": true,
+	"--prompt-cache":   false,
+}
+
+// minFreeDiskBytes is the free space below which launches warn; a negative
+// min_free_disk_gb turns the check off.
+func (c appConfig) minFreeDiskBytes() uint64 {
+	switch {
+	case c.MinFreeDiskGB < 0:
+		return 0
+	case c.MinFreeDiskGB == 0:
+		return defaultMinFreeDiskGB << 30
+	}
+	return uint64(c.MinFreeDiskGB * (1 << 30))
+}
+
+// checkDiskSpace warns about each location the launch writes to (the log
+// file when file logging is on, plus the paths of diskWritingFlags) that
+// has less than minFree bytes available.
+func checkDiskSpace(argv []string, logsDir string, minFree uint64) []string {
+	if minFree == 0 {
+		return nil
+	}
+	type target struct{ what, path string }
+	var targets []target
+	if logsDir != "" {
+		targets = append(targets, target{"log files", logsDir})
+	}
+	for _, f := range parseFlagArgs(argv) {
+		isDir, ok := diskWritingFlags[f.name]
+		if !ok || f.value == "" {
+			continue
+		}
+		path := f.value
+		if !isDir {
+			path = filepath.Dir(path)
+		}
+		targets = append(targets, target{f.name, path})
+	}
+
+	var warnings []string
+	for _, t := range targets {
+		dir := existingAncestor(t.path)
+		usage, err := disk.Usage(dir)
+		if err != nil {
+			continue
+		}
+		if usage.Free < minFree {
+			warnings = append(warnings, fmt.Sprintf("only %s free for %s (%s), below the %s minimum (min_free_disk_gb)",
+				formatBytes(usage.Free), t.what, t.path, formatBytes(minFree)))
+		}
+	}
+	return warnings
+}
+
+// existingAncestor is path or its nearest parent that exists, for
+// directories the server will create on first write.
+func existingAncestor(path string) string {
+	path = filepath.Clean(path)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultDockerImage is llama.cpp's published server image; its entrypoint
+// is llama-server.
+const defaultDockerImage = "ghcr.io/ggml-org/llama.cpp:server"
+
+// containerPort is where llama-server listens inside the container.
+const containerPort = "8080"
+
+// dockerLaunch is a launch translated to a container: models are mounted
+// read-only and the host port maps to containerPort.
+type dockerLaunch struct {
+	service  string
+	image    string
+	hostPort string
+	mounts   [][2]string // host path, container path
+	args     []string
+	gpu      bool
+}
+
+// dockerLaunchFor mirrors what launching item would run: the command
+// template's llama-server arguments, launch options, metadata overrides,
+// and flag fixes, with paths rewritten to the container's mounts.
+func (m appModel) dockerLaunchFor(item modelItem, port string) (dockerLaunch, error) {
+	if item.kind == kindWhisper {
+		return dockerLaunch{}, fmt.Errorf("%s is a whisper model; only llama-server launches can be exported", item.name)
+	}
+	d := dockerLaunch{
+		service:  strings.Trim(sanitizeFileComponent(strings.ToLower(strings.TrimSuffix(filepath.Base(item.name), ".gguf"))), "._-"),
+		image:    m.config.DockerImage,
+		hostPort: port,
+	}
+	if d.image == "" {
+		d.image = defaultDockerImage
+	}
+	if d.service == "" {
+		d.service = "llama-server"
+	}
+	modelDir := filepath.Dir(item.path)
+	d.mounts = append(d.mounts, [2]string{modelDir, "/models"})
+	model := "/models/" + filepath.Base(item.path)
+	mmproj := ""
+	if item.mmproj != "" {
+		if filepath.Dir(item.mmproj) == modelDir {
+			mmproj = "/models/" + filepath.Base(item.mmproj)
+		} else {
+			d.mounts = append(d.mounts, [2]string{filepath.Dir(item.mmproj), "/mmproj"})
+			mmproj = "/mmproj/" + filepath.Base(item.mmproj)
+		}
+	}
+	argv, err := m.config.buildServerCommand("llama-server", model, mmproj, containerPort, withSamplingFlags(item.name, withKVOverrides(item.name, m.argsFor(item.name))))
+	if err != nil {
+		return d, err
+	}
+	argv = applyFlagFixes(argv, m.flagFixes)
+	// The container listens on every interface so the port mapping reaches
+	// it, whatever address the launch binds on the host
+	argv = applyFlagFixes(argv, []flagFix{{flag: "--host", withValue: true}})
+	// Wrappers in the command template (nice, srun, ...) stay on the host
+	bin := -1
+	for i, a := range argv {
+		if a == "llama-server" {
+			bin = i
+			break
+		}
+	}
+	if bin < 0 {
+		return d, fmt.Errorf("command_template does not run {{bin}}")
+	}
+	d.args = append(argv[bin+1:], "--host", "0.0.0.0")
+	for _, f := range parseFlagArgs(d.args) {
+		if f.name == "--n-gpu-layers" {
+			if n, err := strconv.Atoi(f.value); err != nil || n > 0 {
+				d.gpu = true
+			}
+		}
+	}
+	return d, nil
+}
+
+// yamlString quotes s; JSON strings are valid YAML double-quoted scalars.
+func yamlString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// shellQuote quotes s for a POSIX shell when it needs it.
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@,+%", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// dockerRunCommand is the same launch as a single docker run.
+func (d dockerLaunch) dockerRunCommand() string {
+	parts := []string{"docker", "run", "--rm", "-p", d.hostPort + ":" + containerPort}
+	for _, mnt := range d.mounts {
+		parts = append(parts, "-v", shellQuote(mnt[0]+":"+mnt[1]+":ro"))
+	}
+	if d.gpu {
+		parts = append(parts, "--gpus", "all")
+	}
+	parts = append(parts, shellQuote(d.image))
+	for _, a := range d.args {
+		parts = append(parts, shellQuote(a))
+	}
+	return strings.Join(parts, " ")
+}
+
+// composeFile renders a docker-compose.yml with one service for the launch.
+func (d dockerLaunch) composeFile() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Generated by llama-tui %s\n", version)
+	fmt.Fprintf(&b, "# Equivalent docker run:\n#   %s\n", d.dockerRunCommand())
+	if d.gpu {
+		b.WriteString("# GPU layers are offloaded: use a GPU image (e.g. llama.cpp:server-cuda) with the NVIDIA container toolkit\n")
+	}
+	b.WriteString("services:\n")
+	fmt.Fprintf(&b, "  %s:\n", d.service)
+	fmt.Fprintf(&b, "    image: %s\n", yamlString(d.image))
+	b.WriteString("    restart: unless-stopped\n")
+	b.WriteString("    ports:\n")
+	fmt.Fprintf(&b, "      - %s\n", yamlString(d.hostPort+":"+containerPort))
+	b.WriteString("    volumes:\n")
+	for _, mnt := range d.mounts {
+		fmt.Fprintf(&b, "      - %s\n", yamlString(mnt[0]+":"+mnt[1]+":ro"))
+	}
+	b.WriteString("    command:\n")
+	for _, a := range d.args {
+		fmt.Fprintf(&b, "      - %s\n", yamlString(a))
+	}
+	if d.gpu {
+		b.WriteString("    deploy:\n")
+		b.WriteString("      resources:\n")
+		b.WriteString("        reservations:\n")
+		b.WriteString("          devices:\n")
+		b.WriteString("            - driver: nvidia\n")
+		b.WriteString("              count: all\n")
+		b.WriteString("              capabilities: [gpu]\n")
+	}
+	return b.String()
+}
+
+// exportComposeCmd writes the compose file to the cache directory.
+func exportComposeCmd(d dockerLaunch) tea.Cmd {
+	return func() tea.Msg {
+		cacheDir := getCacheDir()
+		if cacheDir == "" {
+			return composeExportedMsg{err: fmt.Errorf("no cache directory available")}
+		}
+		dir := filepath.Join(cacheDir, "compose", d.service)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return composeExportedMsg{err: err}
+		}
+		path := filepath.Join(dir, "docker-compose.yml")
+		return composeExportedMsg{path: path, err: os.WriteFile(path, []byte(d.composeFile()), 0o644)}
+	}
+}
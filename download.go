@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// downloadProgress is shared between the download goroutine and the UI,
+// which samples it on a timer.
+type downloadProgress struct {
+	name  string
+	done  atomic.Int64
+	total atomic.Int64
+	// scanning is set while scan_command checks a finished file
+	scanning atomic.Bool
+}
+
+// countingWriter tracks bytes written into a downloadProgress.
+type countingWriter struct {
+	w        io.Writer
+	progress *downloadProgress
+}
+
+func (c countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.progress.done.Add(int64(n))
+	return n, err
+}
+
+// downloadFile fetches url into dest via a ".part" file that is renamed into
+// place only once complete, so interrupted downloads never look finished.
+// A ".part" file left by an earlier attempt is resumed when the server
+// supports ranges and the file hasn't changed since. The returned
+// provenance carries the checksum computed along the way, which must be
+// want unless that is "", and the verdict of scanCommand when one is
+// configured. A total already set on progress (for several files) is kept.
+func downloadFile(ctx context.Context, url, dest, want string, scanCommand []string, progress *downloadProgress) (modelProvenance, error) {
+	prov := modelProvenance{SourceURL: url}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return prov, err
+	}
+	part := dest + ".part"
+	// validator holds the ETag or Last-Modified the part was fetched with
+	validator := part + ".validator"
+	var offset int64
+	if info, err := os.Stat(part); err == nil {
+		offset = info.Size()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return prov, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		// A file changed since is sent whole rather than spliced on
+		if v, err := os.ReadFile(validator); err == nil && len(v) > 0 {
+			req.Header.Set("If-Range", string(v))
+		}
+	}
+	setHubAuth(req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return prov, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+	case resp.StatusCode == http.StatusOK:
+		// No range support: start over
+		offset = 0
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// The partial file doesn't match the remote one
+		_ = os.Remove(part)
+		_ = os.Remove(validator)
+		return prov, fmt.Errorf("download %s: stale partial file removed, try again", filepath.Base(dest))
+	default:
+		return prov, fmt.Errorf("download %s: %s", filepath.Base(dest), resp.Status)
+	}
+	if offset == 0 {
+		// If-Range takes a strong ETag or a date
+		v := resp.Header.Get("ETag")
+		if v == "" || strings.HasPrefix(v, "W/") {
+			v = resp.Header.Get("Last-Modified")
+		}
+		_ = os.WriteFile(validator, []byte(v), 0o644)
+	}
+	if resp.ContentLength > 0 {
+		progress.total.CompareAndSwap(0, offset+resp.ContentLength)
+	}
+	// Hugging Face names the commit a file was resolved from
+	prov.Revision = resp.Header.Get("X-Repo-Commit")
+	if m := hfRevision.FindStringSubmatch(url); prov.Revision == "" && m != nil {
+		prov.Revision = m[1]
+	}
+
+	hash := sha256.New()
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if offset > 0 {
+		// The checksum covers the bytes already downloaded too
+		if err := hashFile(hash, part); err != nil {
+			return prov, err
+		}
+		flags = os.O_WRONLY | os.O_APPEND
+		progress.done.Add(offset)
+	}
+	f, err := os.OpenFile(part, flags, 0o644)
+	if err != nil {
+		return prov, err
+	}
+	n, err := io.Copy(countingWriter{w: io.MultiWriter(f, hash), progress: progress}, resp.Body)
+	n += offset
+	if err != nil {
+		// Kept for the next attempt to resume
+		_ = f.Close()
+		return prov, err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(part)
+		return prov, err
+	}
+	prov.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	if want != "" && !strings.EqualFold(prov.SHA256, want) {
+		_ = os.Remove(part)
+		_ = os.Remove(validator)
+		return prov, fmt.Errorf("download %s: checksum %s doesn't match the expected %s; removed, try again", filepath.Base(dest), prov.SHA256, want)
+	}
+	prov.Size = n
+	prov.DownloadedAt = time.Now().UTC()
+	progress.scanning.Store(true)
+	err = scanDownload(ctx, scanCommand, part, dest, &prov)
+	progress.scanning.Store(false)
+	if err != nil {
+		return prov, err
+	}
+	_ = os.Remove(validator)
+	return prov, os.Rename(part, dest)
+}
+
+func hashFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// downloadModelCmd downloads a catalog entry into the barn and records its
+// provenance next to it. With a blob store, the file is then moved into it
+// and linked in place.
+func downloadModelCmd(ctx context.Context, item modelItem, blobDir string, scanCommand []string, progress *downloadProgress) tea.Cmd {
+	return func() tea.Msg {
+		prov, err := downloadFile(ctx, item.remoteURL, item.path, "", scanCommand, progress)
+		if err != nil && ctx.Err() != nil {
+			err = fmt.Errorf("cancelled")
+		}
+		if err == nil {
+			// The model is usable without it; losing provenance is not fatal
+			if saveErr := saveProvenance(item.path, prov); saveErr == nil {
+				item.provenance = &prov
+			}
+			if blobDir != "" {
+				// Left as a plain file if it can't be stored
+				_ = storeBlob(blobDir, item.path, prov.SHA256)
+			}
+		}
+		return downloadDoneMsg{item: item, err: err}
+	}
+}
+
+func downloadTickCmd() tea.Cmd {
+	return tea.Tick(500*time.Millisecond, func(_ time.Time) tea.Msg {
+		return downloadTickMsg{}
+	})
+}
+
+// bar draws the progress as a bar of width cells, empty while the size is
+// unknown.
+func (p *downloadProgress) bar(width int) string {
+	done, total := p.done.Load(), p.total.Load()
+	filled := 0
+	if total > 0 {
+		filled = int(min(done, total) * int64(width) / total)
+	}
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("░", width-filled) + "]"
+}
+
+// describe renders progress such as "1.2 GiB / 4.1 GiB (29%)", or notes
+// the scan of a finished file.
+func (p *downloadProgress) describe() string {
+	done, total := p.done.Load(), p.total.Load()
+	if p.scanning.Load() {
+		return formatBytes(uint64(done)) + " - scanning..."
+	}
+	if total <= 0 {
+		return formatBytes(uint64(done))
+	}
+	return fmt.Sprintf("%s / %s (%.0f%%)", formatBytes(uint64(done)), formatBytes(uint64(total)), float64(done)/float64(total)*100)
+}
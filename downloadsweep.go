@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// sweepShardPattern matches the files of a split model, which move
+// together.
+var sweepShardPattern = regexp.MustCompile(`(?i)^(.+)-\d+-of-\d+\.gguf$`)
+
+// sweepCandidate is a model found in the downloads folder: one GGUF, or
+// every shard of a split one.
+type sweepCandidate struct {
+	item  modelItem
+	files []string
+	size  int64
+	// dest is the directory in the barn it would move to
+	dest     string
+	conflict bool
+	selected bool
+}
+
+// downloadSweepView is the screen that moves downloaded models into the barn.
+type downloadSweepView struct {
+	dir    string
+	files  []sweepCandidate
+	cursor int
+	err    error
+	moving bool
+	// note is the outcome of the last move, shown above the keys
+	note string
+}
+
+// downloadsDir is the downloads_dir setting with ~ expanded, else
+// ~/Downloads.
+func (m appModel) downloadsDir() (string, error) {
+	dir := strings.TrimSpace(m.config.DownloadsDir)
+	if dir == "" {
+		dir = "~/Downloads"
+	}
+	return expandHome(m.homeDir, dir)
+}
+
+// sweepDestination is where a model goes in the barn: a folder for its
+// family and, below it, one for its quant.
+func sweepDestination(barnDir string, item modelItem) string {
+	dir := filepath.Join(barnDir, sanitizeFileComponent(modelFamily(item.name)))
+	if item.quant != "" {
+		dir = filepath.Join(dir, sanitizeFileComponent(item.quant))
+	}
+	return dir
+}
+
+// scanDownloadSweepCmd lists the GGUF files directly in the downloads
+// folder with what their names and headers say about them. Projectors are
+// left alone, since they belong next to a particular model.
+func scanDownloadSweepCmd(dir, barnDir string) tea.Cmd {
+	return func() tea.Msg {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return downloadSweepScannedMsg{dir: dir, err: err}
+		}
+		byModel := map[string]*sweepCandidate{}
+		for _, e := range entries {
+			name := e.Name()
+			if e.IsDir() || !strings.HasSuffix(strings.ToLower(name), ".gguf") || strings.HasPrefix(strings.ToLower(name), "mmproj") {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil {
+				continue
+			}
+			key := name
+			if match := sweepShardPattern.FindStringSubmatch(name); match != nil {
+				key = match[1] + ".gguf"
+			}
+			f := byModel[key]
+			if f == nil {
+				f = &sweepCandidate{item: modelItem{name: key, path: filepath.Join(dir, name)}}
+				byModel[key] = f
+			}
+			// The first shard carries the header
+			if name < filepath.Base(f.item.path) {
+				f.item.path = filepath.Join(dir, name)
+			}
+			f.files = append(f.files, filepath.Join(dir, name))
+			f.size += info.Size()
+		}
+		files := make([]sweepCandidate, 0, len(byModel))
+		for _, f := range byModel {
+			f.item = enrichModelItem(f.item)
+			f.dest = sweepDestination(barnDir, f.item)
+			for _, path := range f.files {
+				if _, err := os.Stat(filepath.Join(f.dest, filepath.Base(path))); err == nil {
+					f.conflict = true
+				}
+			}
+			sort.Strings(f.files)
+			files = append(files, *f)
+		}
+		sort.Slice(files, func(i, j int) bool { return files[i].item.name < files[j].item.name })
+		return downloadSweepScannedMsg{dir: dir, files: files}
+	}
+}
+
+// moveFile renames src to dst, copying when they are on different file
+// systems (the usual case for a downloads folder on another disk).
+func moveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	part := dst + ".part"
+	out, err := os.Create(part)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(part)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(part)
+		return err
+	}
+	if err := os.Rename(part, dst); err != nil {
+		os.Remove(part)
+		return err
+	}
+	return os.Remove(src)
+}
+
+// moveSweptModelsCmd moves the chosen models into the barn, stopping at the
+// first failure. With scanCommand set, each file is scanned first, like a
+// download, and one that fails is quarantined in the barn instead.
+func moveSweptModelsCmd(files []sweepCandidate, scanCommand []string) tea.Cmd {
+	return func() tea.Msg {
+		moved := 0
+		for _, f := range files {
+			if err := os.MkdirAll(f.dest, 0o755); err != nil {
+				return downloadSweepMovedMsg{moved: moved, err: err}
+			}
+			for _, path := range f.files {
+				dest := filepath.Join(f.dest, filepath.Base(path))
+				if len(scanCommand) > 0 {
+					if result := runScanCommand(context.Background(), scanCommand, path); !result.Passed {
+						quarantined := dest + quarantineExt
+						if err := moveFile(path, quarantined); err != nil {
+							return downloadSweepMovedMsg{moved: moved, err: fmt.Errorf("%s failed its scan (%s) and was left in place: %w", filepath.Base(path), result.reason(), err)}
+						}
+						_ = saveProvenance(quarantined, modelProvenance{SourceURL: path, Scan: &result})
+						return downloadSweepMovedMsg{moved: moved, err: fmt.Errorf("%s failed its scan, quarantined as %s: %s", filepath.Base(path), quarantined, result.reason())}
+					}
+				}
+				if err := moveFile(path, dest); err != nil {
+					return downloadSweepMovedMsg{moved: moved, err: fmt.Errorf("%s: %w", filepath.Base(path), err)}
+				}
+			}
+			moved++
+		}
+		return downloadSweepMovedMsg{moved: moved}
+	}
+}
+
+func (m appModel) openDownloadSweep() (appModel, tea.Cmd) {
+	if m.readOnly {
+		m.statusLineText = fmt.Sprintf("Read-only: llama-tui pid %d manages this barn - [T] take over", m.lockOwner)
+		return m, nil
+	}
+	dir, err := m.downloadsDir()
+	if err != nil {
+		m.statusLineText = fmt.Sprintf("Downloads folder: %v - set downloads_dir", err)
+		return m, nil
+	}
+	m.downloadSweep = &downloadSweepView{dir: dir}
+	return m, scanDownloadSweepCmd(dir, m.barnDir)
+}
+
+// handleDownloadSweepKey selects downloads and moves them into the barn; the
+// screen captures keys while open.
+func (m appModel) handleDownloadSweepKey(keyStr string) (appModel, tea.Cmd) {
+	v := *m.downloadSweep
+	v.files = append([]sweepCandidate(nil), v.files...)
+	switch keyStr {
+	case "up", "k":
+		v.cursor = max(v.cursor-1, 0)
+	case "down", "j":
+		v.cursor = min(v.cursor+1, max(len(v.files)-1, 0))
+	case " ":
+		if v.cursor < len(v.files) && !v.files[v.cursor].conflict {
+			v.files[v.cursor].selected = !v.files[v.cursor].selected
+		}
+	case "a":
+		all := true
+		for _, f := range v.files {
+			all = all && (f.selected || f.conflict)
+		}
+		for i := range v.files {
+			v.files[i].selected = !all && !v.files[i].conflict
+		}
+	case "r":
+		if !v.moving {
+			m.downloadSweep = &downloadSweepView{dir: v.dir}
+			return m, scanDownloadSweepCmd(v.dir, m.barnDir)
+		}
+	case "enter":
+		if v.moving || m.observerRefuses("move downloads into the barn") {
+			break
+		}
+		var chosen []sweepCandidate
+		for _, f := range v.files {
+			if f.selected {
+				chosen = append(chosen, f)
+			}
+		}
+		if len(chosen) == 0 && v.cursor < len(v.files) && !v.files[v.cursor].conflict {
+			chosen = append(chosen, v.files[v.cursor])
+		}
+		if len(chosen) == 0 {
+			v.note = "Nothing to move: select models with [space]"
+			break
+		}
+		v.moving = true
+		v.note = fmt.Sprintf("Moving %s into %s...", pluralize(len(chosen), "model"), m.barnDir)
+		if len(m.config.ScanCommand) > 0 {
+			v.note = fmt.Sprintf("Scanning and moving %s into %s...", pluralize(len(chosen), "model"), m.barnDir)
+		}
+		m.downloadSweep = &v
+		return m, moveSweptModelsCmd(chosen, m.config.ScanCommand)
+	case "esc", "m":
+		if !v.moving {
+			m.downloadSweep = nil
+			return m, nil
+		}
+		v.note = "Wait for the move to finish"
+	}
+	m.downloadSweep = &v
+	return m, nil
+}
+
+// renderDownloadSweep lists the downloads with their metadata and where
+// each would go in the barn.
+func (m appModel) renderDownloadSweep(width int) string {
+	v := m.downloadSweep
+	var b strings.Builder
+	footer := m.styles.help.Render("[↑/↓] select  [space] mark  [a] mark all  [enter] move into the barn  [r] rescan  [m] or [esc] close")
+	if v.note != "" {
+		footer = m.styles.status.Render(v.note) + "\n" + footer
+	}
+	b.WriteString(m.styles.help.Render(fmt.Sprintf("GGUF files in %s", v.dir)) + "\n\n")
+	if v.err != nil {
+		return b.String() + m.styles.logError.Render(v.err.Error()) + "\n\n" + m.styles.help.Render("Set \"downloads_dir\" in the config file to sweep another folder.") + "\n\n" + footer
+	}
+	if len(v.files) == 0 {
+		return b.String() + "No GGUF files to move.\n\n" + footer
+	}
+	const sizeWidth = 10
+	for i, f := range v.files {
+		gutter := "  "
+		nameStyle := lipgloss.NewStyle()
+		if i == v.cursor {
+			gutter = m.styles.accent.Render("│ ")
+			nameStyle = m.styles.accent.Bold(true)
+		}
+		mark := "[ ] "
+		if f.selected {
+			mark = m.styles.accent.Render("[x] ")
+		}
+		var notes []string
+		for _, s := range []string{f.item.arch, f.item.params, f.item.quant} {
+			if s != "" {
+				notes = append(notes, s)
+			}
+		}
+		if len(f.files) > 1 {
+			notes = append(notes, pluralize(len(f.files), "shard"))
+		}
+		if f.item.contextLength > 0 {
+			notes = append(notes, fmt.Sprintf("%d ctx", f.item.contextLength))
+		}
+		nameWidth := max(width-2-4-sizeWidth-1, 12)
+		name := ellipsize(f.item.name, nameWidth)
+		pad := strings.Repeat(" ", max(0, nameWidth-lipgloss.Width(name)))
+		size := fmt.Sprintf("%*s", sizeWidth, formatBytes(uint64(f.size)))
+		b.WriteString(gutter + mark + nameStyle.Render(name) + pad + " " + m.styles.status.Render(size) + "\n")
+		rel, _ := filepath.Rel(m.barnDir, f.dest)
+		dest := "→ " + rel + string(filepath.Separator)
+		if f.conflict {
+			dest = m.styles.usageWarn.Render(ellipsize(dest+" (already there)", width-6))
+		} else {
+			dest = m.styles.disabled.Render(ellipsize(dest, width-6))
+		}
+		b.WriteString("      " + m.styles.disabled.Render(ellipsize(strings.Join(notes, " · "), width-6)) + "\n")
+		b.WriteString("      " + dest + "\n")
+	}
+	b.WriteString("\n" + footer)
+	return b.String()
+}
@@ -0,0 +1,107 @@
+//go:build harness
+
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMain lets the test binary stand in for llama-server, as the
+// llama-tui binary does for --fake-server.
+func TestMain(m *testing.M) {
+	if spec, ok := os.LookupEnv(fakeServerEnv); ok {
+		os.Exit(runFakeServer(spec, os.Args[1:]))
+	}
+	os.Exit(m.Run())
+}
+
+// e2eTimeout bounds each wait on the fake server, which loads in well
+// under a second with the options these tests give it.
+const e2eTimeout = 20 * time.Second
+
+// newFakeServerHarness lists one model, launched as the fake llama-server
+// with spec's options.
+func newFakeServerHarness(t *testing.T, spec string) *harness {
+	t.Helper()
+	h := newHarness(t, 120, 30, appConfig{DisableSessionRestore: true})
+	h.FakeServer(spec)
+	h.Models("qwen-test.Q4_K_M.gguf")
+	return h
+}
+
+func serverIs(state serverState) func(appModel) bool {
+	return func(m appModel) bool { return m.server == state }
+}
+
+func logsContain(m appModel, text string) bool {
+	return strings.Contains(strings.Join(m.plainLogLines(), "\n"), text)
+}
+
+func TestFakeServerStartsAndStops(t *testing.T) {
+	h := newFakeServerHarness(t, "load=200ms")
+	port := h.Model().portInput.Value()
+	h.Keys("enter")
+	// Readiness and the log lines arrive separately
+	h.RunUntil(e2eTimeout, func(m appModel) bool {
+		return m.server == serverReady && logsContain(m, "main: model loaded")
+	})
+	m := h.Model()
+	if m.currentModelName != "qwen-test.Q4_K_M.gguf" || m.currentPort != port {
+		t.Errorf("serving %q on %q, want qwen-test.Q4_K_M.gguf on %s", m.currentModelName, m.currentPort, port)
+	}
+
+	h.Keys("s", "s")
+	h.RunUntil(e2eTimeout, serverIs(serverStopped))
+	m = h.Model()
+	if m.process != nil || m.currentPort != "" {
+		t.Errorf("process %v on port %q left after stopping", m.process, m.currentPort)
+	}
+	if m.statusLineText != "Server stopped" {
+		t.Errorf("status %q, want Server stopped", m.statusLineText)
+	}
+}
+
+func TestFakeServerCrashAfterReady(t *testing.T) {
+	h := newFakeServerHarness(t, "load=100ms,crash=2s")
+	h.Keys("enter")
+	h.RunUntil(e2eTimeout, serverIs(serverReady))
+	h.RunUntil(e2eTimeout, serverIs(serverCrashed))
+	m := h.Model()
+	if !strings.HasPrefix(m.statusLineText, "Server stopped (error:") {
+		t.Errorf("status %q, want the exit error", m.statusLineText)
+	}
+	if len(m.events) == 0 || m.events[len(m.events)-1].Kind != "crash" {
+		t.Errorf("no crash event recorded: %+v", m.events)
+	}
+}
+
+func TestFakeServerCrashOnLoad(t *testing.T) {
+	h := newFakeServerHarness(t, "load=100ms,crash-on-load")
+	h.Keys("enter")
+	h.RunUntil(e2eTimeout, serverIs(serverCrashed))
+	m := h.Model()
+	if m.oomRecovery != nil {
+		t.Errorf("a failed load offered out-of-memory recovery: %+v", m.oomRecovery)
+	}
+	// Written just before the server exits
+	if !logsContain(m, "main: exiting due to model loading error") {
+		t.Errorf("the server's last lines are missing from the logs panel:\n%s", m.logsContent())
+	}
+}
+
+func TestFakeServerOutOfMemoryOnLoad(t *testing.T) {
+	h := newFakeServerHarness(t, "load=100ms,oom-on-load")
+	h.Keys("enter")
+	h.RunUntil(e2eTimeout, serverIs(serverCrashed))
+	m := h.Model()
+	if m.oomRecovery == nil || m.oomRecovery.model != "qwen-test.Q4_K_M.gguf" {
+		t.Fatalf("no out-of-memory recovery offered for the model: %+v\n%s", m.oomRecovery, m.logsContent())
+	}
+	h.Keys("esc")
+	if h.Model().oomRecovery != nil {
+		t.Error("esc left the out-of-memory dialog open")
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// embedRecord is one line of the embed output.
+type embedRecord struct {
+	Line      int       `json:"line"`
+	Text      string    `json:"text"`
+	Embedding []float64 `json:"embedding"`
+}
+
+// embedInput is a non-empty input line and its line number.
+type embedInput struct {
+	line int
+	text string
+}
+
+// newEmbedCmd feeds a text file through the served model's embeddings
+// endpoint, one input per line.
+func newEmbedCmd() *cobra.Command {
+	var port, baseURL, output string
+	var batch int
+	cmd := &cobra.Command{
+		Use:   "embed input.txt",
+		Short: "Write embeddings for each line of a file as JSON lines or Parquet",
+		Long: "Sends every non-empty line of the input file to llama-server's /v1/embeddings and writes " +
+			`{"line", "text", "embedding"} records as JSON lines, or as Parquet when the output ends in .parquet. ` +
+			"The server must run with --embeddings. By default the server llama-tui is serving is used.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if batch < 1 {
+				return usageError{fmt.Errorf("--batch must be at least 1")}
+			}
+			if baseURL == "" {
+				if port == "" {
+					port = servedPort()
+				}
+				if _, err := validatePort(port); err != nil {
+					return usageError{fmt.Errorf("--port: %w", err)}
+				}
+				baseURL = "http://127.0.0.1:" + port
+			}
+			inputs, err := readEmbedInputs(args[0])
+			if err != nil {
+				return err
+			}
+			if output == "" {
+				output = strings.TrimSuffix(args[0], filepath.Ext(args[0])) + ".embeddings.jsonl"
+			}
+			var w io.Writer = cmd.OutOrStdout()
+			if output != "-" {
+				f, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			baseURL = strings.TrimRight(baseURL, "/")
+			if strings.EqualFold(filepath.Ext(output), ".parquet") {
+				// Parquet's footer comes last, so the records are kept until
+				// the end; those embedded before a failure are still written
+				var records []embedRecord
+				err = runEmbed(ctx, baseURL, inputs, batch, func(r embedRecord) error {
+					records = append(records, r)
+					return nil
+				}, cmd.ErrOrStderr())
+				if writeErr := writeEmbedParquet(w, records); err == nil {
+					err = writeErr
+				}
+			} else {
+				bw := bufio.NewWriter(w)
+				enc := json.NewEncoder(bw)
+				err = runEmbed(ctx, baseURL, inputs, batch, func(r embedRecord) error {
+					return enc.Encode(r)
+				}, cmd.ErrOrStderr())
+				if flushErr := bw.Flush(); err == nil {
+					err = flushErr
+				}
+			}
+			if err != nil {
+				return err
+			}
+			if output != "-" {
+				fmt.Fprintln(cmd.ErrOrStderr(), "wrote", output)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&port, "port", "", "port of the embedding server (default: the port llama-tui is serving on, else "+defaultPort+")")
+	cmd.Flags().StringVar(&baseURL, "url", "", "base URL of the server, e.g. http://gpu-box:8080 (overrides --port)")
+	cmd.Flags().StringVarP(&output, "output", "o", "", `output file, Parquet if it ends in .parquet, "-" for stdout (default: <input>.embeddings.jsonl)`)
+	cmd.Flags().IntVar(&batch, "batch", 32, "inputs per request")
+	return cmd
+}
+
+// servedPort is the port in llama-tui's status file while a server runs,
+// else the default port.
+func servedPort() string {
+	barn, _ := getDefaultBarnDir()
+	cfg, _ := loadConfig(getConfigPath(barn))
+	path := getStatusFilePath(cfg)
+	if path == "" {
+		return defaultPort
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return defaultPort
+	}
+	var st serverStatus
+	if json.Unmarshal(data, &st) != nil || st.State != "running" || st.Port == "" {
+		return defaultPort
+	}
+	return st.Port
+}
+
+func readEmbedInputs(path string) ([]embedInput, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var inputs []embedInput
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for sc.Scan() {
+		line++
+		if text := strings.TrimRight(sc.Text(), "\r"); strings.TrimSpace(text) != "" {
+			inputs = append(inputs, embedInput{line: line, text: text})
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("%s has no non-empty lines", path)
+	}
+	return inputs, nil
+}
+
+// runEmbed embeds inputs in batches, handing records to emit in input
+// order and writing progress to progress.
+func runEmbed(ctx context.Context, baseURL string, inputs []embedInput, batch int, emit func(embedRecord) error, progress io.Writer) error {
+	started := time.Now()
+	for i := 0; i < len(inputs); i += batch {
+		chunk := inputs[i:min(i+batch, len(inputs))]
+		vectors, err := requestEmbeddings(ctx, baseURL, chunk)
+		if err != nil {
+			fmt.Fprintln(progress)
+			return fmt.Errorf("lines %d-%d: %w", chunk[0].line, chunk[len(chunk)-1].line, err)
+		}
+		for j, in := range chunk {
+			if err := emit(embedRecord{Line: in.line, Text: in.text, Embedding: vectors[j]}); err != nil {
+				return err
+			}
+		}
+		done := i + len(chunk)
+		rate := float64(done) / time.Since(started).Seconds()
+		fmt.Fprintf(progress, "\rembedded %d/%d (%.0f%%) · %.1f inputs/s", done, len(inputs), float64(done)*100/float64(len(inputs)), rate)
+	}
+	fmt.Fprintln(progress)
+	return nil
+}
+
+// requestEmbeddings calls the OpenAI-compatible endpoint for one batch.
+func requestEmbeddings(ctx context.Context, baseURL string, chunk []embedInput) ([][]float64, error) {
+	texts := make([]string, len(chunk))
+	for i, in := range chunk {
+		texts[i] = in.text
+	}
+	body, err := json.Marshal(map[string]any{"input": texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/v1/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg := strings.TrimSpace(string(data))
+		if resp.StatusCode == http.StatusNotImplemented || resp.StatusCode == http.StatusNotFound {
+			msg += " (is llama-server running with --embeddings?)"
+		}
+		return nil, fmt.Errorf("%s: %s", resp.Status, ellipsize(msg, 300))
+	}
+	var parsed struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("invalid embeddings response: %w", err)
+	}
+	vectors := make([][]float64, len(chunk))
+	for _, d := range parsed.Data {
+		if d.Index >= 0 && d.Index < len(vectors) {
+			vectors[d.Index] = d.Embedding
+		}
+	}
+	for i, v := range vectors {
+		if v == nil {
+			return nil, fmt.Errorf("no embedding returned for line %d", chunk[i].line)
+		}
+	}
+	return vectors, nil
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	externalPollInterval = 15 * time.Second
+	externalPollTimeout  = 5 * time.Second
+	// externalHistory is how many polls the availability and median
+	// latency cover.
+	externalHistory = 40
+)
+
+// externalEndpoint is an OpenAI-compatible server llama-tui doesn't run,
+// such as llama-server on another machine or a hosted API, polled for the
+// endpoints dashboard only.
+type externalEndpoint struct {
+	Name string `json:"name"`
+	// URL is the server's base URL, e.g. "http://gpu-box:8080", or an API's
+	// ".../v1" URL
+	URL string `json:"url"`
+	// APIKeyEnv names the environment variable holding its API key, sent as
+	// a bearer token; keys stay out of the config file.
+	APIKeyEnv string `json:"api_key_env"`
+}
+
+// modelsURL is where the endpoint lists its models, which answers as
+// /health does on llama-server and costs no tokens on hosted APIs (most
+// still want the API key).
+func (e externalEndpoint) modelsURL() string {
+	base := strings.TrimRight(e.URL, "/")
+	if strings.HasSuffix(base, "/v1") {
+		return base + "/models"
+	}
+	return base + "/v1/models"
+}
+
+// externalPoll is one poll of an external endpoint.
+type externalPoll struct {
+	state   healthState
+	detail  string
+	latency time.Duration
+	models  []string
+	at      time.Time
+}
+
+// externalStatus is an endpoint's recent polls.
+type externalStatus struct {
+	name   string
+	recent []externalPoll
+	// since is when the endpoint's state last changed
+	since time.Time
+}
+
+func (s externalStatus) last() externalPoll {
+	if len(s.recent) == 0 {
+		return externalPoll{}
+	}
+	return s.recent[len(s.recent)-1]
+}
+
+// availability is the share of recent polls answered with a model list.
+func (s externalStatus) availability() float64 {
+	if len(s.recent) == 0 {
+		return 0
+	}
+	up := 0
+	for _, p := range s.recent {
+		if p.state == healthOK {
+			up++
+		}
+	}
+	return float64(up) / float64(len(s.recent))
+}
+
+// medianLatency is over the recent polls that were answered.
+func (s externalStatus) medianLatency() time.Duration {
+	var latencies []time.Duration
+	for _, p := range s.recent {
+		if p.state != healthError {
+			latencies = append(latencies, p.latency)
+		}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return percentile(latencies, 0.5)
+}
+
+// pollExternalCmd polls ep after delay.
+func pollExternalCmd(ep externalEndpoint, delay time.Duration) tea.Cmd {
+	return tea.Tick(delay, func(time.Time) tea.Msg {
+		return externalHealthMsg{name: ep.Name, poll: probeExternal(ep)}
+	})
+}
+
+// probeExternal lists ep's models, timing the request. A 503 is a
+// llama-server still loading its model.
+func probeExternal(ep externalEndpoint) externalPoll {
+	p := externalPoll{at: time.Now()}
+	client := http.Client{Timeout: externalPollTimeout}
+	req, err := http.NewRequest(http.MethodGet, ep.modelsURL(), nil)
+	if err != nil {
+		p.state, p.detail = healthError, err.Error()
+		return p
+	}
+	if ep.APIKeyEnv != "" {
+		key := os.Getenv(ep.APIKeyEnv)
+		if key == "" {
+			p.state, p.detail = healthError, ep.APIKeyEnv+" is not set"
+			return p
+		}
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		p.state, p.detail = healthError, err.Error()
+		return p
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	resp.Body.Close()
+	p.latency = time.Since(start)
+	switch resp.StatusCode {
+	case http.StatusOK:
+		p.state = healthOK
+	case http.StatusServiceUnavailable:
+		p.state, p.detail = healthLoading, healthErrorMessage(body)
+		return p
+	default:
+		p.state, p.detail = healthError, resp.Status
+		if msg := healthErrorMessage(body); msg != "" {
+			p.detail += ": " + msg
+		}
+		return p
+	}
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		p.state, p.detail = healthError, "not an OpenAI-compatible model list"
+		return p
+	}
+	for _, d := range list.Data {
+		p.models = append(p.models, d.ID)
+	}
+	return p
+}
+
+// checkExternalEndpoints refuses two endpoints of one name, since polls are
+// told apart by it.
+func checkExternalEndpoints(endpoints []externalEndpoint) error {
+	seen := map[string]bool{}
+	for _, ep := range endpoints {
+		if ep.Name != "" && seen[ep.Name] {
+			return fmt.Errorf("external_endpoints: %q is named twice", ep.Name)
+		}
+		seen[ep.Name] = true
+	}
+	return nil
+}
+
+// externalEndpointByName finds a configured endpoint.
+func (m appModel) externalEndpointByName(name string) (externalEndpoint, bool) {
+	for _, ep := range m.config.ExternalEndpoints {
+		if ep.Name == name {
+			return ep, true
+		}
+	}
+	return externalEndpoint{}, false
+}
+
+// externalPollCmds start a poll loop for each configured endpoint.
+func (m appModel) externalPollCmds() []tea.Cmd {
+	var cmds []tea.Cmd
+	for _, ep := range m.config.ExternalEndpoints {
+		if ep.Name != "" && ep.URL != "" {
+			cmds = append(cmds, pollExternalCmd(ep, 0))
+		}
+	}
+	return cmds
+}
+
+// handleExternalHealth records a poll, notes the endpoint going down or
+// coming back, and schedules the next poll while it is still configured.
+func (m appModel) handleExternalHealth(msg externalHealthMsg) (appModel, tea.Cmd) {
+	ep, ok := m.externalEndpointByName(msg.name)
+	if !ok {
+		return m, nil
+	}
+	statuses := append([]externalStatus(nil), m.externals...)
+	i := 0
+	for i < len(statuses) && statuses[i].name != msg.name {
+		i++
+	}
+	if i == len(statuses) {
+		statuses = append(statuses, externalStatus{name: msg.name, since: msg.poll.at})
+	}
+	s := statuses[i]
+	prev := s.last()
+	if len(s.recent) > 0 && prev.state != msg.poll.state {
+		down := msg.poll.at.Sub(s.since).Round(time.Second)
+		switch {
+		case msg.poll.state == healthError:
+			m.eventError("endpoint", ep.Name, fmt.Sprintf("%s is down: %s", ep.URL, msg.poll.detail))
+		case prev.state == healthError:
+			m.event("endpoint", ep.Name, fmt.Sprintf("%s is back after %s", ep.URL, down))
+		}
+		s.since = msg.poll.at
+	} else if len(s.recent) == 0 && msg.poll.state == healthError {
+		m.eventError("endpoint", ep.Name, fmt.Sprintf("%s is down: %s", ep.URL, msg.poll.detail))
+	}
+	s.recent = append(append([]externalPoll(nil), s.recent...), msg.poll)
+	if len(s.recent) > externalHistory {
+		s.recent = s.recent[len(s.recent)-externalHistory:]
+	}
+	statuses[i] = s
+	m.externals = statuses
+	return m, pollExternalCmd(ep, externalPollInterval)
+}
+
+// externalStatusFor is the polls of a configured endpoint so far.
+func (m appModel) externalStatusFor(name string) externalStatus {
+	for _, s := range m.externals {
+		if s.name == name {
+			return s
+		}
+	}
+	return externalStatus{name: name}
+}
+
+// renderExternalRows are the dashboard's lines for the external endpoints:
+// state, latency, availability, and models, colored by state.
+func (m appModel) renderExternalRows(width int) []string {
+	var lines []string
+	for _, ep := range m.config.ExternalEndpoints {
+		s := m.externalStatusFor(ep.Name)
+		last := s.last()
+		style := m.styles.logWarn
+		state := "polling..."
+		var detail []string
+		if len(s.recent) > 0 {
+			state = last.state.String()
+			switch last.state {
+			case healthOK:
+				style = m.styles.statusRunning.UnsetPadding()
+				detail = append(detail, formatLatency(last.latency)+" (median "+formatLatency(s.medianLatency())+")", pluralize(len(last.models), "model"))
+			case healthError:
+				style = m.styles.logError
+			}
+			detail = append(detail, fmt.Sprintf("%.0f%% up", s.availability()*100))
+			if last.detail != "" {
+				detail = append(detail, last.detail)
+			}
+			if !s.since.IsZero() && last.state != healthOK {
+				detail = append(detail, "since "+m.config.Timestamps.in(s.since).Format("15:04:05"))
+			}
+		}
+		line := fmt.Sprintf("%s  %s  %s", ep.Name, ep.URL, state)
+		if len(detail) > 0 {
+			line += "  " + strings.Join(detail, " · ")
+		}
+		lines = append(lines, "  "+style.Render("●")+" "+ellipsize(line, width-4))
+	}
+	return lines
+}
+
+// externalSegment counts the endpoints up, amber while any is not.
+func (m appModel) externalSegment() (statusSegment, bool) {
+	if len(m.externals) == 0 {
+		return statusSegment{}, false
+	}
+	up := 0
+	for _, s := range m.externals {
+		if s.last().state == healthOK {
+			up++
+		}
+	}
+	style := m.styles.accent
+	if up < len(m.externals) {
+		style = m.styles.usageWarn
+	}
+	return statusSegment{label: "Endpoints: ", value: fmt.Sprintf("%d/%d up [I]", up, len(m.externals)), style: style, priority: 5}, true
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Defaults for flagging a model as flaky: this many crashes within the
+// window.
+const (
+	defaultFlakyCrashes = 3
+	defaultFlakyDays    = 7
+)
+
+// flakyPolicy decides when a model's recent crashes earn it the flaky
+// badge in the list.
+type flakyPolicy struct {
+	// Crashes within the window that make a model flaky; default 3,
+	// negative disables the badge.
+	Crashes int `json:"crashes"`
+	// Days is the window; default 7.
+	Days int `json:"days"`
+}
+
+func (p flakyPolicy) threshold() int {
+	if p.Crashes == 0 {
+		return defaultFlakyCrashes
+	}
+	return p.Crashes
+}
+
+func (p flakyPolicy) window() time.Duration {
+	days := p.Days
+	if days <= 0 {
+		days = defaultFlakyDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// modelReliability is a model's error budget from the session history.
+type modelReliability struct {
+	runs    int
+	crashes int
+	// recentCrashes fall within the flaky window
+	recentCrashes int
+	served        time.Duration
+	lastCrash     time.Time
+}
+
+// mtbf is the mean time served between crashes, 0 without crashes.
+func (r modelReliability) mtbf() time.Duration {
+	if r.crashes == 0 {
+		return 0
+	}
+	return r.served / time.Duration(r.crashes)
+}
+
+// reliabilityByModel sums the history per model.
+func reliabilityByModel(sessions []sessionRecord, policy flakyPolicy, now time.Time) map[string]modelReliability {
+	since := now.Add(-policy.window())
+	out := map[string]modelReliability{}
+	for _, s := range sessions {
+		r := out[s.Model]
+		r.runs++
+		if s.End.After(s.Start) {
+			r.served += s.End.Sub(s.Start)
+		}
+		if s.Crashed {
+			r.crashes++
+			if s.End.After(since) {
+				r.recentCrashes++
+			}
+			if s.End.After(r.lastCrash) {
+				r.lastCrash = s.End
+			}
+		}
+		out[s.Model] = r
+	}
+	return out
+}
+
+// flakyModels are the models over the policy's crash threshold.
+func flakyModels(reliability map[string]modelReliability, policy flakyPolicy) map[string]bool {
+	threshold := policy.threshold()
+	if threshold < 0 {
+		return nil
+	}
+	flaky := map[string]bool{}
+	for model, r := range reliability {
+		if r.recentCrashes >= threshold {
+			flaky[model] = true
+		}
+	}
+	return flaky
+}
+
+// refreshReliability recomputes the error budgets after the history
+// changes and updates the list badges.
+func (m *appModel) refreshReliability() {
+	m.reliability = reliabilityByModel(m.sessions, m.config.Flaky, time.Now())
+	m.modelsList.SetDelegate(m.listDelegate())
+}
+
+// reliabilitySummary describes a model's crashes for the details pane, ""
+// when it never crashed.
+func (m appModel) reliabilitySummary(modelName string) string {
+	r, ok := m.reliability[modelName]
+	if !ok || r.crashes == 0 {
+		return ""
+	}
+	days := int(m.config.Flaky.window() / (24 * time.Hour))
+	return fmt.Sprintf("%d of %d runs (%d in %dd) · MTBF %s", r.crashes, r.runs, r.recentCrashes, days, formatSpan(r.mtbf()))
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxEvents is how many events the overlay keeps, and loads from the
+// events file at startup.
+const maxEvents = 500
+
+// eventsFileLimit is the size past which the events file is cut back to
+// its newest lines when llama-tui starts.
+const eventsFileLimit = 1 << 20
+
+// appEvent is something llama-tui did or saw happen: a scan, a start or
+// stop, an error, a changed setting. Events are kept apart from the
+// servers' logs, which they would otherwise be lost in.
+type appEvent struct {
+	At    time.Time `json:"at"`
+	Kind  string    `json:"kind"`
+	Model string    `json:"model,omitempty"`
+	Text  string    `json:"text"`
+	Error bool      `json:"error,omitempty"`
+}
+
+// The events file is shared by every workspace, like the config.
+func eventsPath() string {
+	dir := appStateDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "events.jsonl")
+}
+
+// noteTag is the "[kind]" that starts llama-tui's notes in the logs.
+var noteTag = regexp.MustCompile(`^\s*\[([a-z0-9-]+)\] `)
+
+// eventsFileMu keeps appends out of the events file while it is cut back.
+var eventsFileMu sync.Mutex
+
+// recordEvent adds an event to the overlay and queues it for the events
+// file, which Update appends it to from a command.
+func (m *appModel) recordEvent(e appEvent) {
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+	events := append(m.events[:len(m.events):len(m.events)], e)
+	if len(events) > maxEvents {
+		events = events[len(events)-maxEvents:]
+	}
+	m.events = events
+	if m.observer {
+		return
+	}
+	m.notifier.publish(e)
+	m.unsavedEvents = append(m.unsavedEvents[:len(m.unsavedEvents):len(m.unsavedEvents)], e)
+}
+
+// appendEvents adds events to the events file. Failing to write them only
+// loses them from the file.
+func appendEvents(events []appEvent) error {
+	path := eventsPath()
+	if path == "" || len(events) == 0 {
+		return nil
+	}
+	var b []byte
+	for _, e := range events {
+		data, err := json.Marshal(e)
+		if err != nil {
+			continue
+		}
+		b = append(append(b, data...), '\n')
+	}
+	eventsFileMu.Lock()
+	defer eventsFileMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func appendEventsCmd(events []appEvent) tea.Cmd {
+	return func() tea.Msg {
+		_ = appendEvents(events)
+		return nil
+	}
+}
+
+// event records what happened to model, or to llama-tui when model is "".
+func (m *appModel) event(kind, model, text string) {
+	m.recordEvent(appEvent{Kind: kind, Model: model, Text: text})
+}
+
+// eventError records a failure.
+func (m *appModel) eventError(kind, model, text string) {
+	m.recordEvent(appEvent{Kind: kind, Model: model, Text: text, Error: true})
+}
+
+// noteEvent records one of llama-tui's "[kind] ..." notes to the logs as
+// an event of that kind.
+func (m *appModel) noteEvent(line string) {
+	line = strings.TrimSpace(line)
+	e := appEvent{Kind: "ui", Text: line}
+	if tag := noteTag.FindStringSubmatch(line); tag != nil {
+		e.Kind, e.Text = tag[1], strings.TrimSpace(line[len(tag[0]):])
+		if isNumber(e.Kind) {
+			// A side server's note, tagged with its port
+			e.Kind, e.Text = "side", ":"+tag[1]+" "+e.Text
+		}
+	}
+	if strings.HasPrefix(e.Text, "ERROR") || strings.Contains(e.Text, " ERROR") {
+		e.Error = true
+	}
+	m.recordEvent(e)
+}
+
+// loadEventsCmd reads the newest events from earlier sessions, cutting an
+// overgrown file back to them.
+func loadEventsCmd(readOnly bool) tea.Cmd {
+	return func() tea.Msg {
+		path := eventsPath()
+		if path == "" {
+			return eventsLoadedMsg{}
+		}
+		eventsFileMu.Lock()
+		defer eventsFileMu.Unlock()
+		f, err := os.Open(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return eventsLoadedMsg{}
+		}
+		if err != nil {
+			return eventsLoadedMsg{err: err}
+		}
+		defer f.Close()
+		var lines []string
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 4096), 1<<20)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+			if len(lines) > 2*maxEvents {
+				lines = append(lines[:0], lines[maxEvents:]...)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return eventsLoadedMsg{err: fmt.Errorf("read %s: %w", path, err)}
+		}
+		if len(lines) > maxEvents {
+			lines = lines[len(lines)-maxEvents:]
+		}
+		var events []appEvent
+		for _, line := range lines {
+			var e appEvent
+			if json.Unmarshal([]byte(line), &e) == nil {
+				events = append(events, e)
+			}
+		}
+		if info, err := f.Stat(); err == nil && info.Size() > eventsFileLimit && !readOnly {
+			_ = writeFileAtomic(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644)
+		}
+		return eventsLoadedMsg{events: events}
+	}
+}
+
+// eventsView is the [n] overlay listing the events, newest at the bottom.
+type eventsView struct {
+	// offset counts the events scrolled back from the newest
+	offset     int
+	errorsOnly bool
+}
+
+// shownEvents are the events the overlay lists.
+func (m appModel) shownEvents() []appEvent {
+	if !m.eventsView.errorsOnly {
+		return m.events
+	}
+	var shown []appEvent
+	for _, e := range m.events {
+		if e.Error {
+			shown = append(shown, e)
+		}
+	}
+	return shown
+}
+
+func (m appModel) eventsHeight() int {
+	return max(m.height-10, 5)
+}
+
+func (m appModel) handleEventsKey(keyStr string) appModel {
+	v := *m.eventsView
+	page := m.eventsHeight()
+	last := max(len(m.shownEvents())-page, 0)
+	switch keyStr {
+	case "up", "k":
+		v.offset++
+	case "down", "j":
+		v.offset--
+	case "pgup":
+		v.offset += page
+	case "pgdown", " ":
+		v.offset -= page
+	case "home", "g":
+		v.offset = last
+	case "end", "G":
+		v.offset = 0
+	case "e":
+		v.errorsOnly = !v.errorsOnly
+		v.offset = 0
+		m.eventsView = &v
+		return m
+	case "esc", "n", "q":
+		m.eventsView = nil
+		return m
+	}
+	v.offset = min(max(v.offset, 0), last)
+	m.eventsView = &v
+	return m
+}
+
+// renderEvents lists the events with their times and kinds, errors
+// highlighted.
+func (m appModel) renderEvents(width int) string {
+	events := m.shownEvents()
+	height := m.eventsHeight()
+	end := len(events) - m.eventsView.offset
+	start := max(end-height, 0)
+	var lines []string
+	if len(events) == 0 {
+		empty := "No events yet"
+		if m.eventsView.errorsOnly {
+			empty = "No errors"
+		}
+		lines = append(lines, m.styles.disabled.Render(empty))
+	}
+	today := m.config.Timestamps.date(time.Now())
+	for _, e := range events[start:end] {
+		stamp := m.config.Timestamps.dateTime(e.At)
+		if m.config.Timestamps.date(e.At) == today {
+			stamp = m.config.Timestamps.in(e.At).Format("15:04:05")
+		}
+		text := e.Text
+		if e.Model != "" {
+			text = m.displayName(e.Model) + ": " + text
+		}
+		prefix := fmt.Sprintf("%-16s %-10s ", stamp, e.Kind)
+		text = ellipsize(text, max(width-len([]rune(prefix)), 10))
+		if e.Error {
+			text = m.styles.logError.Render(text)
+		}
+		lines = append(lines, m.styles.help.Render(prefix)+text)
+	}
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	filter := "[e] errors only"
+	if m.eventsView.errorsOnly {
+		filter = "[e] all events"
+	}
+	footer := m.styles.help.Render(fmt.Sprintf("[↑/↓] scroll  [g/G] oldest/newest  %s  [n] or [esc] close  ·  %s", filter, pluralize(len(events), "event")))
+	return strings.Join(lines, "\n") + "\n\n" + footer
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	defaultFailoverMaxRestarts = 3
+	// failoverWindow is the span max_restarts counts over.
+	failoverWindow = 10 * time.Minute
+	// failoverHold is how long proxy clients wait for the relaunched
+	// server before they are refused.
+	failoverHold = 2 * time.Minute
+)
+
+// failoverPolicy relaunches a server that crashes behind the request
+// proxy, so clients of proxy_port see a pause rather than an outage.
+type failoverPolicy struct {
+	// Enabled relaunches the crashed model with the same options; it needs
+	// proxy_port.
+	Enabled bool `json:"enabled"`
+	// Ports are alternates to relaunch on, the first one free after the
+	// port that crashed; empty relaunches on the same port.
+	Ports []string `json:"ports"`
+	// MaxRestarts within ten minutes before failover gives up; default 3.
+	MaxRestarts int `json:"max_restarts"`
+}
+
+func (p failoverPolicy) maxRestarts() int {
+	if p.MaxRestarts <= 0 {
+		return defaultFailoverMaxRestarts
+	}
+	return p.MaxRestarts
+}
+
+// failoverIncident is the latest crash failover handled, shown in the
+// status bar until the next launch from the UI.
+type failoverIncident struct {
+	model    string
+	from, to string
+	crashed  time.Time
+	// restored is when the proxy was pointed at the relaunched server
+	restored time.Time
+	// failed says why the relaunch didn't take over
+	failed string
+}
+
+// pending reports a relaunch that hasn't taken over the proxy yet.
+func (i *failoverIncident) pending() bool {
+	return i != nil && i.restored.IsZero() && i.failed == ""
+}
+
+// failoverPort picks where to relaunch after port crashed: the first
+// configured alternate after it that nothing else uses, else port itself.
+func (m appModel) failoverPort(port string) string {
+	ports := m.config.Failover.Ports
+	start := 0
+	for i, p := range ports {
+		if p == port {
+			start = i + 1
+		}
+	}
+	for i := range ports {
+		candidate := ports[(start+i)%len(ports)]
+		portNum, err := validatePort(candidate)
+		if err != nil || candidate == port {
+			continue
+		}
+		if _, used := m.ports[portNum]; !used {
+			return strconv.Itoa(portNum)
+		}
+	}
+	return port
+}
+
+// startFailover arranges the relaunch of a model that crashed while
+// serving behind the proxy, holding proxy clients until it is ready. It
+// reports false when failover is off or has given up.
+func (m appModel) startFailover(model, port string, err error) (appModel, bool) {
+	policy := m.config.Failover
+	if !policy.Enabled || m.proxy == nil || m.attached != nil || m.readOnly || model == "" {
+		return m, false
+	}
+	now := time.Now()
+	recent := m.failovers[:0:0]
+	for _, t := range m.failovers {
+		if now.Sub(t) < failoverWindow {
+			recent = append(recent, t)
+		}
+	}
+	m.failovers = recent
+	if len(recent) >= policy.maxRestarts() {
+		reason := fmt.Sprintf("gave up after %s in %s", pluralize(len(recent), "restart"), failoverWindow)
+		m.incident = &failoverIncident{model: model, from: port, crashed: now, failed: reason}
+		m.eventError("failover", model, "Not relaunching: "+reason)
+		m.failoverNote("Not relaunching " + model + ": " + reason)
+		return m, false
+	}
+	to := m.failoverPort(port)
+	m.failovers = append(m.failovers, now)
+	m.incident = &failoverIncident{model: model, from: port, to: to, crashed: now}
+	m.proxy.holdRequests(failoverHold)
+	m.eventError("failover", model, fmt.Sprintf("Crashed on port %s (%v); relaunching on port %s", port, err, to))
+	m.failoverNote(fmt.Sprintf("Relaunching %s on port %s; proxy clients on port %s wait up to %s", model, to, m.proxy.port, failoverHold))
+	m.statusLineText = fmt.Sprintf("FAILOVER: %s crashed - relaunching on port %s", model, to)
+	return m, true
+}
+
+// relaunchFailover starts the crashed model again on the incident's port
+// with the options it was serving with. It skips the launch checks, whose
+// warnings no one is there to confirm, and the scheduler, which already
+// admitted the model; the port field is left alone, and the crash's logs
+// stay above the new session's.
+func (m appModel) relaunchFailover() (appModel, tea.Cmd) {
+	i := m.incident
+	item, ok := m.findModelByName(i.model)
+	if !ok {
+		m.abandonFailover("not started: the model is no longer listed")
+		return m, nil
+	}
+	m.flagRetry = nil
+	_, _ = m.logBuffer.WriteString(m.colorLog(fmt.Sprintf("\n[failover] Starting %s on port %s...\n", item.name, i.to)))
+	m.logsViewport.SetContent(m.logsContent())
+	m.event("start", item.name, "Relaunching on port "+i.to+" after a crash")
+	m.server = serverStarting
+	return m, tea.Batch(m.startServerCmd(item, i.to), m.spinner.Tick)
+}
+
+// completeFailover points the proxy at the relaunched server once its
+// model has loaded and /health answers.
+func (m *appModel) completeFailover() {
+	if !m.incident.pending() || m.currentModelName != m.incident.model || m.currentPort != m.incident.to {
+		return
+	}
+	m.incident.restored = time.Now()
+	m.proxy.setTarget(m.currentPort)
+	down := m.incident.restored.Sub(m.incident.crashed).Round(100 * time.Millisecond)
+	m.event("failover", m.currentModelName, fmt.Sprintf("Proxy on port %s retargeted to port %s after %s down", m.proxy.port, m.currentPort, down))
+	m.failoverNote(fmt.Sprintf("Proxy on port %s now forwards to port %s", m.proxy.port, m.currentPort))
+	m.statusLineText = fmt.Sprintf("Failover complete: %s serving again on port %s after %s", m.currentModelName, m.currentPort, down)
+}
+
+// abandonFailover records a relaunch that exited before it took over.
+func (m *appModel) abandonFailover(reason string) {
+	if !m.incident.pending() {
+		return
+	}
+	m.incident.failed = reason
+	m.eventError("failover", m.incident.model, "Relaunch on port "+m.incident.to+" "+reason)
+	m.failoverNote("Relaunch on port " + m.incident.to + " " + reason + "; proxy clients are refused")
+}
+
+// failoverNote writes a failover step to the logs; the events are
+// recorded separately, for notifications.
+func (m *appModel) failoverNote(text string) {
+	_, _ = m.logBuffer.WriteString(m.colorLog("\n[failover] " + text + "\n"))
+	m.logsViewport.SetContent(m.logsContent())
+}
+
+// failoverSegment shows the latest incident: red while relaunching or
+// after failover failed, amber once the proxy was retargeted.
+func (m appModel) failoverSegment() (statusSegment, bool) {
+	i := m.incident
+	if i == nil {
+		return statusSegment{}, false
+	}
+	at := m.config.Timestamps.in(i.crashed).Format("15:04:05")
+	switch {
+	case i.failed != "":
+		return statusSegment{label: "FAILOVER: ", value: "failed at " + at + ", " + i.failed, style: m.styles.usageCritical, priority: 1, truncatable: true, minWidth: 16}, true
+	case i.restored.IsZero():
+		return statusSegment{label: "FAILOVER: ", value: "crashed at " + at + ", relaunching on " + i.to, style: m.styles.usageCritical, priority: 1, truncatable: true, minWidth: 16}, true
+	}
+	moved := i.to
+	if i.to != i.from {
+		moved = i.from + "→" + i.to
+	}
+	down := i.restored.Sub(i.crashed).Round(100 * time.Millisecond)
+	value := strings.Join([]string{"crash at " + at, moved, "down " + down.String()}, ", ")
+	return statusSegment{label: "Failover: ", value: value, style: m.styles.usageWarn, priority: 3, truncatable: true, minWidth: 16}, true
+}
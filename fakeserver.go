@@ -0,0 +1,448 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// fakeServerEnv makes the llama-tui binary act as llama-server. --fake-server
+// sets it, with its options, for the servers the session launches.
+const fakeServerEnv = "LLAMA_TUI_FAKE_SERVER"
+
+// fakeServerOptions shape the fake server's behavior, from --fake-server's
+// comma-separated value, e.g. "load=10s,crash=2m".
+type fakeServerOptions struct {
+	// load is how long the model takes to "load" before /health is ready
+	load time.Duration
+	// crash aborts the server this long after it is ready; 0 never does
+	crash time.Duration
+	// crashOnLoad fails the load instead, like a corrupt file
+	crashOnLoad bool
+	// oomOnLoad fails the load running out of GPU memory
+	oomOnLoad bool
+	// token is the delay between generated tokens
+	token time.Duration
+}
+
+func parseFakeServerOptions(spec string) (fakeServerOptions, error) {
+	o := fakeServerOptions{load: 3 * time.Second, token: 30 * time.Millisecond}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" || part == "on" {
+			continue
+		}
+		if part == "crash-on-load" {
+			o.crashOnLoad = true
+			continue
+		}
+		if part == "oom-on-load" {
+			o.oomOnLoad = true
+			continue
+		}
+		key, value, _ := strings.Cut(part, "=")
+		dst := map[string]*time.Duration{"load": &o.load, "crash": &o.crash, "token": &o.token}[key]
+		if dst == nil {
+			return o, fmt.Errorf("unknown option %q (want load=, crash=, token=, crash-on-load, or oom-on-load)", key)
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return o, fmt.Errorf("%q: want a duration such as %s=5s", part, key)
+		}
+		*dst = d
+	}
+	return o, nil
+}
+
+// useFakeServer points this session's launches at the running binary in
+// fake server mode, so features can be tried without llama.cpp or a
+// multi-GB model.
+func useFakeServer(spec string) error {
+	if _, err := parseFakeServerOptions(spec); err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if err := os.Setenv(fakeServerEnv, spec); err != nil {
+		return err
+	}
+	// Stands in for whisper-server too, for the same endpoints plus /inference
+	if err := os.Setenv("WHISPER_SERVER_BIN", exe); err != nil {
+		return err
+	}
+	return os.Setenv("LLAMA_SERVER_BIN", exe)
+}
+
+// fakeServer answers the llama-server endpoints llama-tui uses, with made-up
+// but realistic responses and log lines.
+type fakeServer struct {
+	opts     fakeServerOptions
+	model    string
+	alias    string
+	ctx      int
+	parallel int
+	loaded   atomic.Bool
+	tasks    atomic.Int64
+	busy     atomic.Int32
+
+	mu  sync.Mutex
+	out *os.File
+}
+
+func (s *fakeServer) logf(format string, args ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.out, format+"\n", args...)
+}
+
+// runFakeServer takes llama-server's arguments and serves until it is
+// signalled or its simulated crash. It returns the exit code.
+func runFakeServer(spec string, args []string) int {
+	opts, err := parseFakeServerOptions(spec)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "fake llama-server:", err)
+		return 1
+	}
+	s := &fakeServer{opts: opts, ctx: 4096, parallel: 1, out: os.Stderr}
+	host, port := "127.0.0.1", "8080"
+	for _, f := range parseFlagArgs(args) {
+		switch f.name {
+		case "--help", "-h":
+			fakeServerHelp()
+			return 0
+		case "--version":
+			fmt.Fprintf(os.Stderr, "version: 0 (fake)\nbuilt with llama-tui %s for %s\n", version, "fake-server")
+			return 0
+		case "--list-devices":
+			fmt.Println("Available devices:")
+			return 0
+		case "-m", "--model":
+			s.model = f.value
+		case "-hf", "--hf-repo":
+			if s.model == "" {
+				s.model = f.value
+			}
+		case "--alias", "-a":
+			s.alias = f.value
+		case "--port":
+			port = f.value
+		case "--host":
+			host = f.value
+		case "--ctx-size", "-c":
+			if n, err := strconv.Atoi(f.value); err == nil && n > 0 {
+				s.ctx = n
+			}
+		case "--parallel", "-np":
+			if n, err := strconv.Atoi(f.value); err == nil && n > 0 {
+				s.parallel = n
+			}
+		}
+	}
+	if s.alias == "" {
+		s.alias = filepath.Base(s.model)
+	}
+
+	s.logf("build: 0 (fake) with llama-tui %s", version)
+	s.logf("system info: n_threads = 8, n_threads_batch = 8, total_threads = 8")
+	s.logf("main: HTTP server is listening, hostname: %s, port: %s, http threads: 7", host, port)
+	s.logf("main: loading model")
+	s.logf("srv    load_model: loading model '%s'", s.model)
+	ln, err := net.Listen("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		s.logf("main: couldn't bind HTTP server socket, hostname: %s, port: %s", host, port)
+		return 1
+	}
+	srv := &http.Server{Handler: s.routes()}
+	go func() { _ = srv.Serve(ln) }()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	// Never fires unless a crash is asked for
+	var crash <-chan time.Time
+	steps := 10
+	for i := 1; i <= steps; i++ {
+		select {
+		case <-signals:
+			s.logf("srv    operator(): operator(): cleaning up before exit...")
+			return 0
+		case <-time.After(opts.load / time.Duration(steps)):
+		}
+		if opts.crashOnLoad && i == steps/2 {
+			s.logf("llama_model_load: error loading model: tensor 'blk.%d.attn_q.weight' data is not within the file bounds, model is corrupted or incomplete", i)
+			s.logf("llama_model_load_from_file_impl: failed to load model")
+			s.logf("srv    load_model: failed to load model, '%s'", s.model)
+			s.logf("main: exiting due to model loading error")
+			return 1
+		}
+		if opts.oomOnLoad && i == steps/2 {
+			s.logf("load_tensors: offloaded 33/33 layers to GPU")
+			s.logf("llama_context: n_ctx         = %d", s.ctx)
+			s.logf("ggml_backend_cuda_buffer_type_alloc_buffer: allocating %.2f MiB on device 0: cudaMalloc failed: out of memory", float64(s.ctx)/8)
+			s.logf("alloc_tensor_range: failed to allocate CUDA0 buffer of size %d", s.ctx<<17)
+			s.logf("llama_init_from_model: failed to initialize the context: failed to allocate buffer for kv cache")
+			s.logf("srv    load_model: failed to load model, '%s'", s.model)
+			s.logf("main: exiting due to model loading error")
+			return 1
+		}
+		s.logf("llama_model_loader: - tensor batch %d/%d loaded", i, steps)
+	}
+	s.logf("llama_kv_cache: size = %7.2f MiB (%6d cells,  32 layers,  %d/%d seqs)", float64(s.ctx)/8, s.ctx, s.parallel, s.parallel)
+	s.logf("srv          init: initializing slots, n_slots = %d", s.parallel)
+	s.logf("main: model loaded")
+	s.logf("main: server is listening on http://%s - starting the main loop", net.JoinHostPort(host, port))
+	s.logf("srv  update_slots: all slots are idle")
+	s.loaded.Store(true)
+	if opts.crash > 0 {
+		crash = time.After(opts.crash)
+	}
+	select {
+	case <-signals:
+		s.logf("srv    operator(): operator(): cleaning up before exit...")
+		return 0
+	case <-crash:
+		s.logf("/llama.cpp/ggml/src/ggml-backend.cpp:1853: GGML_ASSERT(n_backends > 0) failed")
+		s.logf("fake llama-server: simulated crash after %s", opts.crash)
+		// As abort() does
+		return 134
+	}
+}
+
+func (s *fakeServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		if !s.loaded.Load() {
+			writeFakeJSON(w, http.StatusServiceUnavailable, map[string]any{"error": map[string]any{"code": 503, "message": "Loading model", "type": "unavailable_error"}})
+			return
+		}
+		writeFakeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+	})
+	models := func(w http.ResponseWriter, r *http.Request) {
+		writeFakeJSON(w, http.StatusOK, map[string]any{"object": "list", "data": []any{map[string]any{"id": s.alias, "object": "model", "owned_by": "llamacpp"}}})
+	}
+	mux.HandleFunc("/v1/models", models)
+	mux.HandleFunc("/models", models)
+	mux.HandleFunc("/props", func(w http.ResponseWriter, r *http.Request) {
+		writeFakeJSON(w, http.StatusOK, map[string]any{
+			"default_generation_settings": map[string]any{"n_ctx": s.ctx, "params": map[string]any{"temperature": 0.8, "top_k": 40, "top_p": 0.95, "min_p": 0.05, "n_predict": -1, "seed": 4294967295, "samplers": []any{"top_k", "top_p", "min_p", "temperature"}}},
+			"total_slots":                 s.parallel,
+			"model_path":                  s.model,
+			"build_info":                  "0 (fake)",
+		})
+	})
+	mux.HandleFunc("/slots", func(w http.ResponseWriter, r *http.Request) {
+		slots := make([]any, s.parallel)
+		busy := int(s.busy.Load())
+		for i := range slots {
+			slots[i] = map[string]any{"id": i, "n_ctx": s.ctx / s.parallel, "is_processing": i < busy}
+		}
+		writeFakeJSON(w, http.StatusOK, slots)
+	})
+	mux.HandleFunc("/slots/", func(w http.ResponseWriter, r *http.Request) {
+		writeFakeJSON(w, http.StatusOK, map[string]any{"id_slot": 0, "n_saved": 0, "n_restored": 0, "n_erased": 0})
+	})
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprintf(w, "# HELP llamacpp:prompt_tokens_seconds Average prompt throughput in tokens/s.\nllamacpp:prompt_tokens_seconds 512\n")
+		fmt.Fprintf(w, "# HELP llamacpp:predicted_tokens_seconds Average generation throughput in tokens/s.\nllamacpp:predicted_tokens_seconds %.1f\n", float64(time.Second)/float64(max(s.opts.token, time.Millisecond)))
+		fmt.Fprintf(w, "llamacpp:requests_processing %d\nllamacpp:requests_deferred 0\n", s.busy.Load())
+	})
+	mux.HandleFunc("/apply-template", s.applyTemplate)
+	mux.HandleFunc("/tokenize", s.tokenize)
+	mux.HandleFunc("/inference", func(w http.ResponseWriter, r *http.Request) {
+		writeFakeJSON(w, http.StatusOK, map[string]any{"text": " And so my fellow Americans, ask not what your country can do for you.\n"})
+	})
+	for _, path := range []string{"/v1/chat/completions", "/chat/completions", "/v1/completions", "/completion"} {
+		mux.HandleFunc(path, s.complete)
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeFakeJSON(w, http.StatusNotFound, map[string]any{"error": map[string]any{"code": 404, "message": "File Not Found", "type": "not_found_error"}})
+	})
+	return s.accessLog(mux)
+}
+
+// accessLog writes llama-server's line for each finished request.
+func (s *fakeServer) accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		if r.URL.Path != "/health" && r.URL.Path != "/metrics" && r.URL.Path != "/slots" {
+			host, _, _ := net.SplitHostPort(r.RemoteAddr)
+			s.logf("srv  log_server_r: request: %s %s %s %d", r.Method, r.URL.Path, host, rec.status)
+		}
+	})
+}
+
+var fakeReply = strings.Fields("This reply comes from llama-tui's fake llama-server. It streams a few words at a steady pace so the logs, metrics, and proxy views have something to show.")
+
+// fakeTokenPattern splits text into ChatML control tokens, words with
+// their leading space, and runs of whitespace.
+var fakeTokenPattern = regexp.MustCompile(`<\|[^|]*\|>|\s?[^\s<]+|<|\s+`)
+
+// applyTemplate renders messages as ChatML, whatever the template flags.
+func (s *fakeServer) applyTemplate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeFakeJSON(w, http.StatusBadRequest, map[string]any{"error": map[string]any{"code": 400, "message": err.Error(), "type": "invalid_request_error"}})
+		return
+	}
+	var prompt strings.Builder
+	for _, msg := range req.Messages {
+		fmt.Fprintf(&prompt, "<|im_start|>%s\n%s<|im_end|>\n", msg.Role, msg.Content)
+	}
+	prompt.WriteString("<|im_start|>assistant\n")
+	writeFakeJSON(w, http.StatusOK, map[string]any{"prompt": prompt.String()})
+}
+
+// tokenize gives each piece a made-up but stable id.
+func (s *fakeServer) tokenize(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Content string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeFakeJSON(w, http.StatusBadRequest, map[string]any{"error": map[string]any{"code": 400, "message": err.Error(), "type": "invalid_request_error"}})
+		return
+	}
+	tokens := []any{}
+	for _, piece := range fakeTokenPattern.FindAllString(req.Content, -1) {
+		h := fnv.New32a()
+		h.Write([]byte(piece))
+		tokens = append(tokens, map[string]any{"id": h.Sum32() % 150000, "piece": piece})
+	}
+	writeFakeJSON(w, http.StatusOK, map[string]any{"tokens": tokens})
+}
+
+// complete streams a canned reply, as server-sent events when asked to.
+func (s *fakeServer) complete(w http.ResponseWriter, r *http.Request) {
+	if !s.loaded.Load() {
+		writeFakeJSON(w, http.StatusServiceUnavailable, map[string]any{"error": map[string]any{"code": 503, "message": "Loading model", "type": "unavailable_error"}})
+		return
+	}
+	var req struct {
+		Stream    bool   `json:"stream"`
+		MaxTokens int    `json:"max_tokens"`
+		NPredict  int    `json:"n_predict"`
+		Prompt    string `json:"prompt"`
+		Messages  []struct {
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeFakeJSON(w, http.StatusBadRequest, map[string]any{"error": map[string]any{"code": 400, "message": err.Error(), "type": "invalid_request_error"}})
+		return
+	}
+	limit := len(fakeReply)
+	if n := max(req.MaxTokens, req.NPredict); n > 0 {
+		limit = min(limit, n)
+	}
+	// A word a token, plus a few for each message's template markers
+	promptTokens := len(strings.Fields(req.Prompt))
+	for _, msg := range req.Messages {
+		promptTokens += len(strings.Fields(msg.Content)) + 4
+	}
+	promptTokens = max(promptTokens, 1)
+	if promptTokens > s.ctx/s.parallel {
+		writeFakeJSON(w, http.StatusBadRequest, map[string]any{"error": map[string]any{"code": 400, "message": "the request exceeds the available context size, try increasing it", "type": "exceed_context_size_error"}})
+		return
+	}
+	task := s.tasks.Add(1) - 1
+	slot := int(s.busy.Add(1)-1) % s.parallel
+	defer s.busy.Add(-1)
+	start := time.Now()
+	s.logf("slot launch_slot_: id  %d | task %d | processing task", slot, task)
+	s.logf("slot update_slots: id  %d | task %d | new prompt, n_ctx_slot = %d, n_keep = 0, n_prompt_tokens = %d", slot, task, s.ctx/s.parallel, promptTokens)
+	chat := strings.Contains(r.URL.Path, "chat")
+	flusher, _ := w.(http.Flusher)
+	if req.Stream {
+		w.Header().Set("Content-Type", "text/event-stream")
+	}
+	var text strings.Builder
+	for i, word := range fakeReply[:limit] {
+		select {
+		case <-r.Context().Done():
+			s.logf("srv  stop: cancel task, id_task = %d", task)
+			return
+		case <-time.After(s.opts.token):
+		}
+		if i > 0 {
+			word = " " + word
+		}
+		text.WriteString(word)
+		if req.Stream {
+			chunk := map[string]any{"content": word, "stop": false}
+			if chat {
+				chunk = map[string]any{"object": "chat.completion.chunk", "model": s.alias,
+					"choices": []any{map[string]any{"index": 0, "delta": map[string]any{"content": word}}}}
+			}
+			data, _ := json.Marshal(chunk)
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+	elapsed := time.Since(start)
+	s.logf("slot print_timing: id  %d | task %d | ", slot, task)
+	s.logf("prompt eval time =      12.00 ms / %5d tokens (    0.50 ms per token,  2000.00 tokens per second)", promptTokens)
+	s.logf("       eval time = %10.2f ms / %5d tokens (%8.2f ms per token, %8.2f tokens per second)",
+		float64(elapsed.Microseconds())/1000, limit, float64(elapsed.Microseconds())/1000/float64(max(limit, 1)), float64(limit)/max(elapsed.Seconds(), 0.001))
+	s.logf("slot      release: id  %d | task %d | stop processing: n_past = %d, truncated = 0", slot, task, promptTokens+limit)
+	usage := map[string]any{"prompt_tokens": promptTokens, "completion_tokens": limit, "total_tokens": promptTokens + limit}
+	switch {
+	case req.Stream && chat:
+		data, _ := json.Marshal(map[string]any{"object": "chat.completion.chunk", "model": s.alias, "usage": usage,
+			"choices": []any{map[string]any{"index": 0, "delta": map[string]any{}, "finish_reason": "stop"}}})
+		fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", data)
+	case req.Stream:
+		data, _ := json.Marshal(map[string]any{"content": "", "stop": true, "tokens_predicted": limit})
+		fmt.Fprintf(w, "data: %s\n\n", data)
+	case chat:
+		writeFakeJSON(w, http.StatusOK, map[string]any{"object": "chat.completion", "model": s.alias, "usage": usage,
+			"choices": []any{map[string]any{"index": 0, "finish_reason": "stop", "message": map[string]any{"role": "assistant", "content": text.String()}}}})
+	default:
+		writeFakeJSON(w, http.StatusOK, map[string]any{"content": text.String(), "stop": true, "tokens_predicted": limit})
+	}
+}
+
+func writeFakeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// fakeServerHelp lists the flags llama-tui knows, in llama-server's --help
+// layout, for the flag search.
+func fakeServerHelp() {
+	fmt.Println("----- common params -----")
+	fmt.Println()
+	for _, spec := range llamaFlagRegistry {
+		names := spec.name
+		if spec.short != "" {
+			names = spec.short + ", " + spec.name
+		}
+		if spec.kind != flagKindBool {
+			names += " N"
+		}
+		fmt.Printf("%-40s %s\n", names, spec.doc)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	familyOpenMark   = "▾ "
+	familyClosedMark = "▸ "
+)
+
+// familyTagPattern matches quantizer tags that say nothing about the model,
+// e.g. Unsloth's "UD-Q4_K_XL" or "imatrix"
+var familyTagPattern = regexp.MustCompile(`(?i)(?:^|[-_.])(UD|imat|imatrix)(?:[-_.]|$)`)
+
+var familySeparators = regexp.MustCompile(`[-_.]{2,}`)
+
+// modelFamily is the name a model shares with its other quants and sizes:
+// "Qwen2.5-7B-Instruct-Q4_K_M" and "Qwen2.5-14B-Instruct-Q8_0" are both
+// "Qwen2.5-Instruct".
+func modelFamily(name string) string {
+	base := filepath.Base(name)
+	if i := strings.Index(strings.ToLower(base), ".gguf"); i >= 0 {
+		base = base[:i]
+	}
+	family := base
+	for _, p := range []*regexp.Regexp{quantPattern, paramsPattern, familyTagPattern} {
+		// Matches share separators, so repeat until nothing is left
+		for p.MatchString(family) {
+			family = p.ReplaceAllString(family, "-")
+		}
+	}
+	family = strings.Trim(familySeparators.ReplaceAllString(family, "-"), "-_.")
+	if family == "" {
+		return base
+	}
+	return family
+}
+
+// familyItem heads a family of two or more models in the grouped list. Its
+// variants follow it when expanded and are kept in foldedModels otherwise.
+type familyItem struct {
+	key      string
+	name     string
+	variants []modelItem
+	expanded bool
+}
+
+// FilterValue matches the family by any of its variants, so filtering
+// finds models folded away under it.
+func (f familyItem) FilterValue() string {
+	parts := []string{strings.ToLower(f.name)}
+	for _, v := range f.variants {
+		parts = append(parts, v.FilterValue())
+	}
+	return strings.Join(parts, " ")
+}
+
+func (f familyItem) summary() string {
+	var quants []string
+	var minSize, maxSize int64
+	for i, v := range f.variants {
+		if v.quant != "" && !containsString(quants, v.quant) {
+			quants = append(quants, v.quant)
+		}
+		if i == 0 || v.size < minSize {
+			minSize = v.size
+		}
+		maxSize = max(maxSize, v.size)
+	}
+	parts := []string{pluralize(len(f.variants), "variant")}
+	switch {
+	case maxSize == 0:
+	case minSize == maxSize:
+		parts = append(parts, formatBytes(uint64(maxSize)))
+	default:
+		parts = append(parts, formatBytes(uint64(minSize))+"-"+formatBytes(uint64(maxSize)))
+	}
+	if len(quants) > 0 {
+		parts = append(parts, strings.Join(quants, ", "))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// allModelItems is every model, including variants folded into a
+// collapsed family, without the family headers.
+func (m appModel) allModelItems() []list.Item {
+	items := make([]list.Item, 0, len(m.modelsList.Items())+len(m.foldedModels))
+	for _, it := range m.modelsList.Items() {
+		if _, ok := it.(familyItem); !ok {
+			items = append(items, it)
+		}
+	}
+	return append(items, m.foldedModels...)
+}
+
+// groupFamilies puts models of the same family under a header at the
+// position of the family's first model, keeping the given order within
+// it. Pinned models stay where the pins put them. Variants of collapsed
+// families are returned separately.
+func groupFamilies(items []list.Item, expanded map[string]bool) (grouped, folded []list.Item) {
+	members := map[string][]modelItem{}
+	for _, it := range items {
+		mi, ok := it.(modelItem)
+		if !ok || mi.pinned {
+			continue
+		}
+		key := strings.ToLower(modelFamily(mi.name))
+		members[key] = append(members[key], mi)
+	}
+	placed := map[string]bool{}
+	for _, it := range items {
+		mi, ok := it.(modelItem)
+		if !ok {
+			grouped = append(grouped, it)
+			continue
+		}
+		mi.inFamily = false
+		if mi.pinned {
+			grouped = append(grouped, mi)
+			continue
+		}
+		key := strings.ToLower(modelFamily(mi.name))
+		variants := members[key]
+		if len(variants) < 2 {
+			grouped = append(grouped, mi)
+			continue
+		}
+		if placed[key] {
+			continue
+		}
+		placed[key] = true
+		f := familyItem{key: key, name: modelFamily(variants[0].name), variants: variants, expanded: expanded[key]}
+		grouped = append(grouped, f)
+		for _, v := range variants {
+			v.inFamily = f.expanded
+			if f.expanded {
+				grouped = append(grouped, v)
+			} else {
+				folded = append(folded, v)
+			}
+		}
+	}
+	return grouped, folded
+}
+
+// toggleFamily expands or collapses the selected family.
+func (m appModel) toggleFamily(f familyItem) appModel {
+	if m.expandedFamilies == nil {
+		m.expandedFamilies = map[string]bool{}
+	}
+	m.expandedFamilies[f.key] = !f.expanded
+	m.reorderModels("")
+	for i, it := range m.modelsList.Items() {
+		if g, ok := it.(familyItem); ok && g.key == f.key {
+			m.modelsList.Select(i)
+			break
+		}
+	}
+	return m
+}
+
+// renderFamily draws a family header in the models list: the family name
+// with its variant count, size range, and quants.
+func (d modelDelegate) renderFamily(w io.Writer, m list.Model, index int, f familyItem) {
+	width := m.Width()
+	selected := index == m.Index()
+	gutter := "  "
+	if selected {
+		gutter = d.styles.accent.Render("│ ")
+	}
+	avail := width - lipgloss.Width(gutter)
+	mark := familyClosedMark
+	if f.expanded {
+		mark = familyOpenMark
+	}
+	badge := ""
+	for _, v := range f.variants {
+		if d.servingPath != "" && v.path == d.servingPath {
+			badge = d.servingStyle.Render(servingBadge)
+		}
+	}
+	titleStyle := lipgloss.NewStyle().Bold(true)
+	if selected {
+		titleStyle = d.styles.accent.Bold(true)
+	}
+	name := ellipsize(f.name, max(avail-lipgloss.Width(mark)-lipgloss.Width(badge), 1))
+	title := mark + badge + titleStyle.Render(name)
+	desc := d.styles.disabled.Render(ellipsize("  "+f.summary(), avail))
+	fmt.Fprintf(w, "%s%s\n%s%s", gutter, title, gutter, desc)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// fileRow is one line of a file screen (the -hf cache, saved slots): a
+// name, its size, and notes after it.
+type fileRow struct {
+	name string
+	size int64
+	note string
+}
+
+// moveFileCursor moves a file screen's cursor over n rows for an up or
+// down key, reporting whether key was one.
+func moveFileCursor(key string, cursor, n int) (int, bool) {
+	switch key {
+	case "up", "k":
+		return max(cursor-1, 0), true
+	case "down", "j":
+		return min(cursor+1, max(n-1, 0)), true
+	}
+	return cursor, false
+}
+
+// renderFileRows lists rows with the one at cursor highlighted, each name
+// cut to fit beside its size and note.
+func (m appModel) renderFileRows(rows []fileRow, cursor, width int) string {
+	const sizeWidth = 10
+	var b strings.Builder
+	for i, r := range rows {
+		gutter := "  "
+		nameStyle := lipgloss.NewStyle()
+		if i == cursor {
+			gutter = m.styles.accent.Render("│ ")
+			nameStyle = m.styles.accent.Bold(true)
+		}
+		nameWidth := max(width-2-sizeWidth-lipgloss.Width(r.note)-2, 12)
+		name := ellipsize(r.name, nameWidth)
+		pad := strings.Repeat(" ", max(0, nameWidth-lipgloss.Width(name)))
+		size := fmt.Sprintf("%*s", sizeWidth, formatBytes(uint64(r.size)))
+		b.WriteString(gutter + nameStyle.Render(name) + pad + " " + m.styles.status.Render(size) + " " + m.styles.disabled.Render(r.note) + "\n")
+	}
+	return b.String()
+}
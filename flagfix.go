@@ -0,0 +1,113 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// rejectedFlag matches llama-server's messages for arguments a build does
+// not understand, e.g. "error: invalid argument: --jinja".
+var rejectedFlag = regexp.MustCompile(`(?:invalid|unknown) argument: (\S+)`)
+
+// flagFix rewrites one flag in the launch command for the rest of the
+// session, after llama-server rejected it.
+type flagFix struct {
+	flag string
+	// value, when set, limits the fix to this value of the flag
+	value     string
+	withValue bool
+	replace   []string
+}
+
+// describe says what the fix does, e.g. "without --jinja" or
+// "with -fa instead of -fa on".
+func (f flagFix) describe() string {
+	old := f.flag
+	if f.value != "" {
+		old += " " + f.value
+	}
+	if len(f.replace) == 0 {
+		return "without " + old
+	}
+	return "with " + strings.Join(f.replace, " ") + " instead of " + old
+}
+
+// findRejectedFlag returns the argument llama-server complained about in its
+// output, if any.
+func findRejectedFlag(output string) string {
+	m := rejectedFlag.FindAllStringSubmatch(output, -1)
+	if len(m) == 0 {
+		return ""
+	}
+	return strings.Trim(m[len(m)-1][1], `"'`)
+}
+
+// suggestFlagFix works out how to launch without the rejected argument.
+// A rejected value means the flag took no value in this build (older
+// --flash-attn is a plain switch), so the value is dropped; a rejected flag
+// is dropped together with its value.
+func suggestFlagFix(argv []string, rejected string) (flagFix, bool) {
+	idx := -1
+	for i, a := range argv {
+		if i > 0 && (a == rejected || strings.HasPrefix(a, rejected+"=")) {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return flagFix{}, false
+	}
+	if !strings.HasPrefix(rejected, "-") || isNumber(rejected) {
+		prev := argv[idx-1]
+		if !strings.HasPrefix(prev, "-") {
+			return flagFix{}, false
+		}
+		fix := flagFix{flag: prev, value: rejected, withValue: true, replace: []string{prev}}
+		if flagAliases[prev] == "--flash-attn" || prev == "--flash-attn" {
+			switch strings.ToLower(rejected) {
+			case "off", "auto", "0", "false":
+				// Off is the old default; the switch would turn it on
+				fix.replace = nil
+			}
+		}
+		return fix, true
+	}
+	name, _, inline := strings.Cut(argv[idx], "=")
+	fix := flagFix{flag: name}
+	if !inline && idx+1 < len(argv) && (!strings.HasPrefix(argv[idx+1], "-") || isNumber(argv[idx+1])) {
+		fix.withValue = true
+	}
+	return fix, true
+}
+
+// applyFlagFixes rewrites argv with every fix made this session.
+func applyFlagFixes(argv []string, fixes []flagFix) []string {
+	for _, fix := range fixes {
+		out := make([]string, 0, len(argv))
+		for i := 0; i < len(argv); i++ {
+			a := argv[i]
+			if i == 0 {
+				out = append(out, a)
+				continue
+			}
+			if name, value, inline := strings.Cut(a, "="); inline && name == fix.flag && (fix.value == "" || value == fix.value) {
+				out = append(out, fix.replace...)
+				continue
+			}
+			if a != fix.flag {
+				out = append(out, a)
+				continue
+			}
+			hasNext := i+1 < len(argv)
+			if fix.value != "" && (!hasNext || argv[i+1] != fix.value) {
+				out = append(out, a)
+				continue
+			}
+			out = append(out, fix.replace...)
+			if fix.withValue && hasNext && (!strings.HasPrefix(argv[i+1], "-") || isNumber(argv[i+1])) {
+				i++
+			}
+		}
+		argv = out
+	}
+	return argv
+}
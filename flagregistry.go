@@ -0,0 +1,63 @@
+package main
+
+// flagSpec documents a llama-server option for forms and validation.
+type flagSpec struct {
+	name    string
+	short   string
+	doc     string
+	kind    flagKind
+	choices []string
+	// section groups the flag under a heading in forms
+	section string
+	// placeholder shows llama-server's default while the field is empty
+	placeholder string
+	// min and max bound an int flag when max is set
+	min, max int
+	// explain says in plain words what the flag changes, for flags whose
+	// effect the one-line doc cannot get across
+	explain string
+}
+
+type flagKind int
+
+const (
+	flagKindInt flagKind = iota
+	flagKindString
+	flagKindChoice
+	flagKindBool
+)
+
+// llamaFlagRegistry is the embedded subset of llama-server flags the UI
+// knows how to present, with one-line documentation for each.
+var llamaFlagRegistry = []flagSpec{
+	{name: "--ctx-size", short: "-c", kind: flagKindInt, doc: "Prompt context size in tokens; 0 uses the model's trained context"},
+	{name: "--n-gpu-layers", short: "-ngl", kind: flagKindInt, doc: "Layers to offload to the GPU; 999 offloads everything that fits"},
+	{name: "--threads", short: "-t", kind: flagKindInt, doc: "CPU threads for generation; default uses all physical cores"},
+	{name: "--parallel", short: "-np", kind: flagKindInt, doc: "Number of parallel slots; the context is split between them"},
+	{name: "--batch-size", short: "-b", kind: flagKindInt, doc: "Logical maximum batch size for prompt processing"},
+	{name: "--flash-attn", short: "-fa", kind: flagKindChoice, choices: []string{"auto", "on", "off"}, doc: "Flash attention; faster and required for quantized V cache"},
+	{name: "--cache-type-k", short: "-ctk", kind: flagKindChoice, choices: []string{"f16", "q8_0", "q4_0"}, doc: "KV cache data type for K; quantizing saves memory"},
+	{name: "--cache-type-v", short: "-ctv", kind: flagKindChoice, choices: []string{"f16", "q8_0", "q4_0"}, doc: "KV cache data type for V; quantized types need flash attention"},
+	{name: "--mlock", kind: flagKindBool, doc: "Keep the model in RAM instead of letting the OS swap it out"},
+	{name: "--no-mmap", kind: flagKindBool, doc: "Load the whole model into memory instead of memory-mapping it"},
+	{name: "--context-shift", kind: flagKindBool, section: "Long chats", doc: "When a chat outgrows the context, drop old messages instead of stopping",
+		explain: "Without it, a conversation that no longer fits the context ends with an error or a cut-off reply. With it, the server discards the older half of the history (after the --keep tokens) and carries on, so long-running chats never hit a wall, but the model silently forgets what was said early on."},
+	{name: "--keep", kind: flagKindInt, section: "Long chats", placeholder: "0", min: -1, max: 1 << 20, doc: "Prompt tokens a context shift never drops; -1 keeps the whole initial prompt",
+		explain: "Set it to about the length of your system prompt so the instructions survive when old messages are dropped. Only matters with --context-shift."},
+	{name: "--cache-reuse", kind: flagKindInt, section: "Long chats", placeholder: "0", min: 0, max: 1 << 20, doc: "Smallest cached chunk, in tokens, reused past the matching start; 0 is off",
+		explain: "Normally only the unchanged beginning of a prompt is reused, so editing or trimming an earlier message makes the server reprocess everything after it. With a chunk size such as 256, matching stretches further along are shifted into place instead of recomputed, which keeps long chats quick after edits. Needs prompt caching, which is on by default."},
+	{name: "--no-cache-prompt", kind: flagKindBool, section: "Long chats", doc: "Process the whole prompt on every request instead of reusing the previous one",
+		explain: "By default each chat turn only processes the new message, because the server keeps the conversation so far in its cache. Turning that off makes every turn of a long chat as slow as the first; it is only useful for repeatable timings or to rule out a caching problem."},
+	{name: "--threads-http", kind: flagKindInt, section: "Advanced network", placeholder: "all cores", min: 1, max: 1024, doc: "Threads answering HTTP requests; raise it when many clients connect at once"},
+	{name: "--timeout", short: "-to", kind: flagKindInt, section: "Advanced network", placeholder: "600", min: 1, max: 86400, doc: "Seconds a connection may stay idle or slow before the server closes it"},
+}
+
+// lookupFlag finds a registry entry by long or short name.
+func lookupFlag(name string) (flagSpec, bool) {
+	for _, f := range llamaFlagRegistry {
+		if f.name == name || (f.short != "" && f.short == name) {
+			return f, true
+		}
+	}
+	return flagSpec{}, false
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// helpFlag is one option parsed from `llama-server --help`.
+type helpFlag struct {
+	names []string
+	arg   string
+	doc   string
+}
+
+// long is the preferred spelling to insert: the last --long name.
+func (f helpFlag) long() string {
+	for i := len(f.names) - 1; i >= 0; i-- {
+		if strings.HasPrefix(f.names[i], "--") {
+			return f.names[i]
+		}
+	}
+	return f.names[0]
+}
+
+func (f helpFlag) searchText() string {
+	return strings.ToLower(strings.Join(f.names, " ") + " " + f.doc)
+}
+
+// helpColumns splits a help line into its flag and description columns:
+// the description starts at the first wide gap not following a comma
+// ("-c,    --ctx-size N    size of...").
+var helpColumns = regexp.MustCompile(`^(\S(?:.*?[^,\s])?)(?:\s{2,}(.*))?$`)
+
+// flagName rejects section rules such as "----- common params -----".
+var flagName = regexp.MustCompile(`^--?[A-Za-z0-9]`)
+
+// parseServerHelp turns llama-server's help text into flags. Option lines
+// start with a dash; deeper-indented lines continue the description.
+func parseServerHelp(text string) []helpFlag {
+	var flags []helpFlag
+	for _, raw := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(raw)
+		if trimmed == "" || strings.HasPrefix(trimmed, "(env:") {
+			continue
+		}
+		indent := len(raw) - len(strings.TrimLeft(raw, " "))
+		if strings.HasPrefix(trimmed, "-") && indent < 8 {
+			cols := helpColumns.FindStringSubmatch(trimmed)
+			if cols == nil {
+				continue
+			}
+			var f helpFlag
+			for _, part := range strings.Split(cols[1], ",") {
+				fields := strings.Fields(part)
+				if len(fields) == 0 || !flagName.MatchString(fields[0]) {
+					continue
+				}
+				f.names = append(f.names, fields[0])
+				if len(fields) > 1 {
+					f.arg = strings.Join(fields[1:], " ")
+				}
+			}
+			if len(f.names) == 0 {
+				continue
+			}
+			f.doc = strings.TrimSpace(cols[2])
+			flags = append(flags, f)
+			continue
+		}
+		if len(flags) > 0 && indent >= 8 {
+			last := &flags[len(flags)-1]
+			last.doc = strings.TrimSpace(last.doc + " " + trimmed)
+		}
+	}
+	return flags
+}
+
+// loadServerHelpCmd runs `llama-server --help` for the installed version.
+func loadServerHelpCmd() tea.Cmd {
+	return func() tea.Msg {
+		bin, err := getLlamaServerBinary()
+		if err != nil {
+			return serverHelpLoadedMsg{err: err}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		out, err := exec.CommandContext(ctx, bin, "--help").CombinedOutput()
+		flags := parseServerHelp(string(out))
+		if len(flags) == 0 {
+			if err == nil {
+				err = fmt.Errorf("no flags found in %s --help", bin)
+			}
+			return serverHelpLoadedMsg{err: err}
+		}
+		return serverHelpLoadedMsg{flags: flags}
+	}
+}
+
+// flagSearch is a filterable list over the parsed help flags.
+type flagSearch struct {
+	input   textinput.Model
+	flags   []helpFlag
+	matches []int
+	cursor  int
+}
+
+func newFlagSearch(flags []helpFlag) flagSearch {
+	in := textinput.New()
+	in.Prompt = "Search: "
+	in.Placeholder = "e.g. rope, cache, gpu"
+	in.Focus()
+	s := flagSearch{input: in, flags: flags}
+	s.filter()
+	return s
+}
+
+func (s *flagSearch) filter() {
+	terms := strings.Fields(strings.ToLower(s.input.Value()))
+	s.matches = s.matches[:0]
+	for i, f := range s.flags {
+		text := f.searchText()
+		ok := true
+		for _, t := range terms {
+			if !strings.Contains(text, t) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			s.matches = append(s.matches, i)
+		}
+	}
+	if s.cursor >= len(s.matches) {
+		s.cursor = len(s.matches) - 1
+	}
+	if s.cursor < 0 {
+		s.cursor = 0
+	}
+}
+
+// Update handles a key; it returns the chosen flag on enter and done on
+// enter or esc.
+func (s flagSearch) Update(msg tea.KeyMsg) (flagSearch, *helpFlag, bool, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		return s, nil, true, nil
+	case "enter":
+		if len(s.matches) == 0 {
+			return s, nil, true, nil
+		}
+		f := s.flags[s.matches[s.cursor]]
+		return s, &f, true, nil
+	case "up", "ctrl+p":
+		if s.cursor > 0 {
+			s.cursor--
+		}
+		return s, nil, false, nil
+	case "down", "ctrl+n":
+		if s.cursor < len(s.matches)-1 {
+			s.cursor++
+		}
+		return s, nil, false, nil
+	}
+	var cmd tea.Cmd
+	s.input, cmd = s.input.Update(msg)
+	s.filter()
+	return s, nil, false, cmd
+}
+
+// View shows the query and a window of matches around the cursor, with the
+// selected flag's full description below.
+func (s flagSearch) View(styles uiStyles, width, rows int) string {
+	lines := []string{s.input.View(), ""}
+	start := 0
+	if s.cursor >= rows {
+		start = s.cursor - rows + 1
+	}
+	for i := start; i < len(s.matches) && i < start+rows; i++ {
+		f := s.flags[s.matches[i]]
+		label := strings.Join(f.names, ", ")
+		if f.arg != "" {
+			label += " " + f.arg
+		}
+		line := ellipsize(label, width-2)
+		if i == s.cursor {
+			lines = append(lines, styles.accent.Render("> "+line))
+		} else {
+			lines = append(lines, "  "+line)
+		}
+	}
+	if len(s.matches) == 0 {
+		lines = append(lines, styles.disabled.Render("  no matching flags"))
+	} else {
+		f := s.flags[s.matches[s.cursor]]
+		lines = append(lines, "", styles.help.Render(ellipsize(f.doc, width)))
+	}
+	lines = append(lines, "", styles.help.Render(fmt.Sprintf("%d/%d flags  [↑↓] select  [enter] insert  [esc] back", len(s.matches), len(s.flags))))
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// formField is one row of a form: a text input, a choice cycled with
+// left/right, or a boolean toggled with space. A choice of customChoice
+// switches to free text.
+type formField struct {
+	key      string
+	label    string
+	doc      string
+	kind     flagKind
+	choices  []string
+	labels   []string
+	choice   int
+	checked  bool
+	input    textinput.Model
+	validate func(string) error
+	// section starts a heading above the field when it differs from the
+	// previous field's
+	section string
+	// explain is wrapped below the doc while the field is focused
+	explain string
+}
+
+func newFieldInput() textinput.Model {
+	in := textinput.New()
+	in.Prompt = ""
+	return in
+}
+
+func newTextField(key, label, doc, value string, validate func(string) error) formField {
+	in := newFieldInput()
+	in.SetValue(value)
+	return formField{key: key, label: label, doc: doc, kind: flagKindString, input: in, validate: validate}
+}
+
+func newChoiceField(key, label, doc string, choices []string, value string) formField {
+	f := formField{key: key, label: label, doc: doc, kind: flagKindChoice, choices: choices, input: newFieldInput()}
+	for i, c := range choices {
+		if c == value {
+			f.choice = i
+		}
+	}
+	return f
+}
+
+// customChoice is a choice value that lets the user type their own.
+const customChoice = "custom"
+
+func newBoolField(key, label, doc string, checked bool) formField {
+	return formField{key: key, label: label, doc: doc, kind: flagKindBool, checked: checked, input: newFieldInput()}
+}
+
+// newFlagField builds a field for a registry flag, taking its documentation
+// and validation from the registry.
+func newFlagField(spec flagSpec, value string) formField {
+	var f formField
+	switch spec.kind {
+	case flagKindChoice:
+		// An empty first choice means "leave unset"
+		f = newChoiceField(spec.name, spec.name, spec.doc, append([]string{""}, spec.choices...), value)
+	case flagKindBool:
+		f = newBoolField(spec.name, spec.name, spec.doc, value != "")
+	case flagKindInt:
+		validate := validateOptionalInt
+		if spec.max > 0 {
+			validate = validateIntRange(spec.min, spec.max)
+		}
+		f = newTextField(spec.name, spec.name, spec.doc, value, validate)
+	default:
+		f = newTextField(spec.name, spec.name, spec.doc, value, nil)
+	}
+	f.section = spec.section
+	f.explain = spec.explain
+	f.input.Placeholder = spec.placeholder
+	return f
+}
+
+func validateOptionalInt(v string) error {
+	if strings.TrimSpace(v) == "" {
+		return nil
+	}
+	if _, err := strconv.Atoi(strings.TrimSpace(v)); err != nil {
+		return fmt.Errorf("must be a whole number")
+	}
+	return nil
+}
+
+// validateIntRange accepts blank or a whole number from lo to hi.
+func validateIntRange(lo, hi int) func(string) error {
+	return func(v string) error {
+		if strings.TrimSpace(v) == "" {
+			return nil
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < lo || n > hi {
+			return fmt.Errorf("must be a whole number from %d to %d", lo, hi)
+		}
+		return nil
+	}
+}
+
+// value returns the field's current value as text; booleans are "true" or "".
+func (f formField) value() string {
+	switch f.kind {
+	case flagKindChoice:
+		if len(f.choices) == 0 {
+			return ""
+		}
+		if f.choices[f.choice] == customChoice {
+			return strings.TrimSpace(f.input.Value())
+		}
+		return f.choices[f.choice]
+	case flagKindBool:
+		if f.checked {
+			return "true"
+		}
+		return ""
+	default:
+		return strings.TrimSpace(f.input.Value())
+	}
+}
+
+// formModel is a reusable, keyboard-navigable form. tab/shift+tab or
+// up/down move between fields, enter submits after validation, and esc
+// cancels.
+type formModel struct {
+	title  string
+	fields []formField
+	focus  int
+	err    string
+	// keysHelp lists form-specific keys handled by the owner
+	keysHelp string
+	// live, when set, is recomputed from the values on every render and
+	// shown under the fields
+	live func(values map[string]string) string
+}
+
+// formResult is what a key press did to the form.
+type formResult int
+
+const (
+	formEditing formResult = iota
+	formSubmitted
+	formCancelled
+)
+
+func newForm(title string, fields []formField) formModel {
+	f := formModel{title: title, fields: fields}
+	f.setFocus(0)
+	return f
+}
+
+func (f *formModel) setFocus(i int) {
+	if len(f.fields) == 0 {
+		return
+	}
+	i = (i + len(f.fields)) % len(f.fields)
+	for j := range f.fields {
+		if j == i {
+			f.fields[j].input.Focus()
+		} else {
+			f.fields[j].input.Blur()
+		}
+	}
+	f.focus = i
+}
+
+// appendToField adds text to a field's value and focuses it.
+func (f *formModel) appendToField(key, text string) {
+	for i := range f.fields {
+		if f.fields[i].key != key {
+			continue
+		}
+		v := strings.TrimSpace(f.fields[i].input.Value())
+		if v != "" {
+			v += " "
+		}
+		f.fields[i].input.SetValue(v + text)
+		f.fields[i].input.CursorEnd()
+		f.setFocus(i)
+		return
+	}
+}
+
+// values maps field keys to their current values.
+func (f formModel) values() map[string]string {
+	out := make(map[string]string, len(f.fields))
+	for _, field := range f.fields {
+		out[field.key] = field.value()
+	}
+	return out
+}
+
+func (f formModel) Update(msg tea.KeyMsg) (formModel, formResult, tea.Cmd) {
+	f.err = ""
+	field := &f.fields[f.focus]
+	switch msg.String() {
+	case "esc":
+		return f, formCancelled, nil
+	case "enter":
+		for i, fl := range f.fields {
+			if fl.validate == nil {
+				continue
+			}
+			if err := fl.validate(fl.value()); err != nil {
+				f.setFocus(i)
+				f.err = fmt.Sprintf("%s: %v", fl.label, err)
+				return f, formEditing, nil
+			}
+		}
+		return f, formSubmitted, nil
+	case "tab", "down":
+		f.setFocus(f.focus + 1)
+		return f, formEditing, nil
+	case "shift+tab", "up":
+		f.setFocus(f.focus - 1)
+		return f, formEditing, nil
+	}
+	switch field.kind {
+	case flagKindChoice:
+		custom := field.choices[field.choice] == customChoice
+		switch msg.String() {
+		case "left":
+			field.choice = (field.choice - 1 + len(field.choices)) % len(field.choices)
+			return f, formEditing, nil
+		case "right":
+			field.choice = (field.choice + 1) % len(field.choices)
+			return f, formEditing, nil
+		case "h":
+			if !custom {
+				field.choice = (field.choice - 1 + len(field.choices)) % len(field.choices)
+				return f, formEditing, nil
+			}
+		case "l", " ":
+			if !custom {
+				field.choice = (field.choice + 1) % len(field.choices)
+				return f, formEditing, nil
+			}
+		}
+		if !custom {
+			return f, formEditing, nil
+		}
+	case flagKindBool:
+		if msg.String() == " " || msg.String() == "x" {
+			field.checked = !field.checked
+		}
+		return f, formEditing, nil
+	}
+	var cmd tea.Cmd
+	field.input, cmd = field.input.Update(msg)
+	return f, formEditing, cmd
+}
+
+// View renders the fields with the focused field's documentation inline.
+func (f formModel) View(styles uiStyles, width int) string {
+	labelW := 0
+	for _, field := range f.fields {
+		if w := lipgloss.Width(field.label); w > labelW {
+			labelW = w
+		}
+	}
+	var b strings.Builder
+	for i, field := range f.fields {
+		if i > 0 && field.section != f.fields[i-1].section {
+			b.WriteString("\n")
+			if field.section != "" {
+				b.WriteString(styles.help.Render(field.section) + "\n")
+			}
+		}
+		marker := "  "
+		labelStyle := styles.help
+		if i == f.focus {
+			marker = styles.accent.Render("> ")
+			labelStyle = styles.accent.Bold(true)
+		}
+		var value string
+		switch field.kind {
+		case flagKindChoice:
+			v := field.choices[field.choice]
+			if field.choice < len(field.labels) {
+				v = field.labels[field.choice]
+			}
+			if v == "" {
+				v = "(default)"
+			}
+			value = "< " + v + " >"
+			if field.choices[field.choice] == customChoice {
+				value += " " + field.input.View()
+			}
+		case flagKindBool:
+			if field.checked {
+				value = "[x]"
+			} else {
+				value = "[ ]"
+			}
+		default:
+			value = field.input.View()
+		}
+		b.WriteString(marker + labelStyle.Render(fmt.Sprintf("%-*s", labelW, field.label)) + "  " + value + "\n")
+		if i == f.focus && field.doc != "" {
+			b.WriteString("  " + strings.Repeat(" ", labelW) + "  " + styles.disabled.Render(ellipsize(field.doc, width-labelW-6)) + "\n")
+		}
+		if i == f.focus && field.explain != "" {
+			indent := strings.Repeat(" ", labelW+4)
+			for _, line := range strings.Split(lipgloss.NewStyle().Width(max(width-labelW-6, 20)).Render(field.explain), "\n") {
+				b.WriteString(indent + styles.help.Render(strings.TrimRight(line, " ")) + "\n")
+			}
+		}
+	}
+	if f.live != nil {
+		b.WriteString("\n" + styles.accent.Render(ellipsize(f.live(f.values()), width)) + "\n")
+	}
+	if f.err != "" {
+		b.WriteString("\n" + styles.logError.Render(f.err) + "\n")
+	}
+	keys := "[tab/↑↓] move  [←→] choose  [space] toggle  [enter] apply  [esc] cancel"
+	if f.keysHelp != "" {
+		keys += "  " + f.keysHelp
+	}
+	b.WriteString("\n" + styles.help.Render(keys))
+	return b.String()
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/mem"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// recentLoadWindow is how long after starting another llama-server is
+// assumed to still be loading its model.
+const recentLoadWindow = 2 * time.Minute
+
+// launchGate is the checklist run before every launch. A failed check
+// doesn't refuse the launch; it turns enter into a confirmation.
+// standbyPID is the standby's process (0 for none), which is expected.
+func launchGate(item modelItem, port string, standbyLoading bool, standbyPID int32) []diagnosticCheck {
+	return []diagnosticCheck{
+		checkPortFree(port),
+		checkLaunchMemory(item),
+		checkOtherLoads(standbyLoading, standbyPID),
+	}
+}
+
+// gateSummary is the checklist on one line, e.g. for the status line.
+func gateSummary(checks []diagnosticCheck) string {
+	parts := make([]string, 0, len(checks))
+	for _, c := range checks {
+		mark := "✓"
+		if c.status == checkFail {
+			mark = "✗"
+		}
+		parts = append(parts, mark+" "+strings.ToLower(c.name)+": "+c.detail)
+	}
+	return strings.Join(parts, "  ")
+}
+
+// gateWarnings are the failed checks as launch warnings.
+func gateWarnings(checks []diagnosticCheck) []string {
+	var warnings []string
+	for _, c := range checks {
+		if c.status == checkFail {
+			warnings = append(warnings, fmt.Sprintf("%s: %s (%s)", strings.ToLower(c.name), c.detail, c.hint))
+		}
+	}
+	return warnings
+}
+
+// checkLaunchMemory compares the model's size with available RAM plus free
+// VRAM on discrete GPUs (Apple silicon shares RAM with the GPU).
+func checkLaunchMemory(item modelItem) diagnosticCheck {
+	c := diagnosticCheck{name: "Memory"}
+	need := uint64(max(item.size, 0))
+	if item.mmproj != "" {
+		if info, err := os.Stat(item.mmproj); err == nil {
+			need += uint64(info.Size())
+		}
+	}
+	vm, err := mem.VirtualMemory()
+	if err != nil {
+		c.detail = "unknown (" + err.Error() + ")"
+		return c
+	}
+	free := vm.Available
+	where := formatBytes(vm.Available) + " RAM"
+	if vram, ok := freeVRAM(); ok {
+		free += vram
+		where += " + " + formatBytes(vram) + " VRAM"
+	}
+	if need == 0 {
+		c.detail = where + " free (model size unknown)"
+		return c
+	}
+	c.detail = fmt.Sprintf("%s free for %s", where, formatBytes(need))
+	if need > free {
+		c.status = checkFail
+		c.hint = "the model may fail to load or swap; stop other servers or pick a smaller quant"
+	}
+	return c
+}
+
+// freeVRAM sums free memory across NVIDIA GPUs, when nvidia-smi is there.
+func freeVRAM() (uint64, bool) {
+	if runtime.GOOS == "darwin" {
+		return 0, false
+	}
+	if _, err := exec.LookPath("nvidia-smi"); err != nil {
+		return 0, false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "nvidia-smi", "--query-gpu=memory.free", "--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return 0, false
+	}
+	var total uint64
+	for _, line := range strings.Fields(string(out)) {
+		mib, err := strconv.ParseUint(line, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		total += mib << 20
+	}
+	return total, true
+}
+
+// checkOtherLoads looks for model loads that would compete for memory and
+// disk: the standby, or another llama-server started recently (by another
+// llama-tui or anything else).
+func checkOtherLoads(standbyLoading bool, standbyPID int32) diagnosticCheck {
+	c := diagnosticCheck{name: "Other loads", detail: "none"}
+	if standbyLoading {
+		c.status, c.detail = checkFail, "the standby is still loading"
+		c.hint = "wait for it, or press [W] to stop it"
+		return c
+	}
+	procs, err := process.Processes()
+	if err != nil {
+		return c
+	}
+	now := time.Now()
+	for _, p := range procs {
+		if p.Pid == standbyPID {
+			continue
+		}
+		name, err := p.Name()
+		if err != nil || !strings.HasPrefix(filepath.Base(name), "llama-server") {
+			continue
+		}
+		created, err := p.CreateTime()
+		if err != nil {
+			continue
+		}
+		if age := now.Sub(time.UnixMilli(created)); age < recentLoadWindow {
+			c.status = checkFail
+			c.detail = fmt.Sprintf("llama-server pid %d started %s ago", p.Pid, age.Round(time.Second))
+			c.hint = "it may still be loading; launching now competes for memory"
+			return c
+		}
+	}
+	return c
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+const ggufMagic = "GGUF"
+
+// GGUF metadata value types
+const (
+	ggufTypeUint8 uint32 = iota
+	ggufTypeInt8
+	ggufTypeUint16
+	ggufTypeInt16
+	ggufTypeUint32
+	ggufTypeInt32
+	ggufTypeFloat32
+	ggufTypeBool
+	ggufTypeString
+	ggufTypeArray
+	ggufTypeUint64
+	ggufTypeInt64
+	ggufTypeFloat64
+)
+
+// ggufTypeSizes is the encoded size of fixed-width value types.
+var ggufTypeSizes = map[uint32]int{
+	ggufTypeUint8: 1, ggufTypeInt8: 1, ggufTypeBool: 1,
+	ggufTypeUint16: 2, ggufTypeInt16: 2,
+	ggufTypeUint32: 4, ggufTypeInt32: 4, ggufTypeFloat32: 4,
+	ggufTypeUint64: 8, ggufTypeInt64: 8, ggufTypeFloat64: 8,
+}
+
+// ggufMetadata is the key/value header of a GGUF file. Scalar values are kept
+// as Go values; arrays are reduced to their element count to bound memory
+// (tokenizer vocabularies alone can hold hundreds of thousands of entries).
+type ggufMetadata struct {
+	version     uint32
+	tensorCount uint64
+	kv          map[string]any
+}
+
+// ggufArrayLen stands in for array values in ggufMetadata.kv.
+type ggufArrayLen uint64
+
+func (g *ggufMetadata) architecture() string {
+	return g.str("general.architecture")
+}
+
+func (g *ggufMetadata) str(key string) string {
+	if v, ok := g.kv[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+// uint returns an integer value regardless of its stored width.
+func (g *ggufMetadata) uint(key string) (uint64, bool) {
+	switch v := g.kv[key].(type) {
+	case uint8:
+		return uint64(v), true
+	case uint16:
+		return uint64(v), true
+	case uint32:
+		return uint64(v), true
+	case uint64:
+		return v, true
+	case int8:
+		return uint64(v), v >= 0
+	case int16:
+		return uint64(v), v >= 0
+	case int32:
+		return uint64(v), v >= 0
+	case int64:
+		return uint64(v), v >= 0
+	default:
+		return 0, false
+	}
+}
+
+// archUint reads an architecture-scoped key such as "<arch>.context_length".
+func (g *ggufMetadata) archUint(suffix string) (uint64, bool) {
+	arch := g.architecture()
+	if arch == "" {
+		return 0, false
+	}
+	return g.uint(arch + "." + suffix)
+}
+
+// contextLength is the context size the model was trained with.
+func (g *ggufMetadata) contextLength() (uint64, bool) {
+	return g.archUint("context_length")
+}
+
+// ggufFileTypes names llama.cpp's llama_ftype values (general.file_type).
+var ggufFileTypes = map[uint64]string{
+	0: "F32", 1: "F16", 2: "Q4_0", 3: "Q4_1", 7: "Q8_0", 8: "Q5_0", 9: "Q5_1",
+	10: "Q2_K", 11: "Q3_K_S", 12: "Q3_K_M", 13: "Q3_K_L", 14: "Q4_K_S", 15: "Q4_K_M",
+	16: "Q5_K_S", 17: "Q5_K_M", 18: "Q6_K", 19: "IQ2_XXS", 20: "IQ2_XS", 21: "Q2_K_S",
+	22: "IQ3_XS", 23: "IQ3_XXS", 24: "IQ1_S", 25: "IQ4_NL", 26: "IQ3_S", 27: "IQ3_M",
+	28: "IQ2_S", 29: "IQ2_M", 30: "IQ4_XS", 31: "IQ1_M", 32: "BF16", 36: "TQ1_0",
+	37: "TQ2_0", 38: "MXFP4",
+}
+
+func ggufFileTypeName(ft uint64) string {
+	if name, ok := ggufFileTypes[ft]; ok {
+		return name
+	}
+	return ""
+}
+
+// readGGUFMetadata parses the header and metadata of a GGUF file without
+// touching tensor data.
+func readGGUFMetadata(path string) (*ggufMetadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseGGUFMetadata(bufio.NewReaderSize(f, 64*1024))
+}
+
+func parseGGUFMetadata(r io.Reader) (*ggufMetadata, error) {
+	gr := ggufReader{r: r}
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, fmt.Errorf("read magic: %w", err)
+	}
+	if string(magic) != ggufMagic {
+		return nil, errors.New("not a GGUF file")
+	}
+	meta := &ggufMetadata{kv: make(map[string]any)}
+	meta.version = gr.u32()
+	if gr.err == nil && meta.version < 2 {
+		return nil, fmt.Errorf("unsupported GGUF version %d", meta.version)
+	}
+	meta.tensorCount = gr.u64()
+	kvCount := gr.u64()
+	for i := uint64(0); i < kvCount && gr.err == nil; i++ {
+		key := gr.str()
+		typ := gr.u32()
+		meta.kv[key] = gr.value(typ)
+	}
+	if gr.err != nil {
+		return nil, fmt.Errorf("read metadata: %w", gr.err)
+	}
+	return meta, nil
+}
+
+// ggufReader reads little-endian GGUF primitives, latching the first error.
+type ggufReader struct {
+	r   io.Reader
+	err error
+	buf [8]byte
+}
+
+func (gr *ggufReader) read(n int) []byte {
+	if gr.err != nil {
+		return gr.buf[:n]
+	}
+	_, gr.err = io.ReadFull(gr.r, gr.buf[:n])
+	return gr.buf[:n]
+}
+
+func (gr *ggufReader) u8() uint8   { return gr.read(1)[0] }
+func (gr *ggufReader) u16() uint16 { return binary.LittleEndian.Uint16(gr.read(2)) }
+func (gr *ggufReader) u32() uint32 { return binary.LittleEndian.Uint32(gr.read(4)) }
+func (gr *ggufReader) u64() uint64 { return binary.LittleEndian.Uint64(gr.read(8)) }
+
+func (gr *ggufReader) str() string {
+	n := gr.u64()
+	if gr.err != nil {
+		return ""
+	}
+	if n > 1<<24 {
+		gr.err = fmt.Errorf("string length %d too large", n)
+		return ""
+	}
+	b := make([]byte, n)
+	_, gr.err = io.ReadFull(gr.r, b)
+	return string(b)
+}
+
+func (gr *ggufReader) value(typ uint32) any {
+	switch typ {
+	case ggufTypeUint8:
+		return gr.u8()
+	case ggufTypeInt8:
+		return int8(gr.u8())
+	case ggufTypeUint16:
+		return gr.u16()
+	case ggufTypeInt16:
+		return int16(gr.u16())
+	case ggufTypeUint32:
+		return gr.u32()
+	case ggufTypeInt32:
+		return int32(gr.u32())
+	case ggufTypeFloat32:
+		return math.Float32frombits(gr.u32())
+	case ggufTypeBool:
+		return gr.u8() != 0
+	case ggufTypeString:
+		return gr.str()
+	case ggufTypeUint64:
+		return gr.u64()
+	case ggufTypeInt64:
+		return int64(gr.u64())
+	case ggufTypeFloat64:
+		return math.Float64frombits(gr.u64())
+	case ggufTypeArray:
+		elemType := gr.u32()
+		count := gr.u64()
+		if size, fixed := ggufTypeSizes[elemType]; fixed && gr.err == nil {
+			// Skip fixed-size elements without decoding them
+			_, gr.err = io.CopyN(io.Discard, gr.r, int64(size)*int64(count))
+			return ggufArrayLen(count)
+		}
+		for i := uint64(0); i < count && gr.err == nil; i++ {
+			gr.value(elemType)
+		}
+		return ggufArrayLen(count)
+	default:
+		if gr.err == nil {
+			gr.err = fmt.Errorf("unknown metadata type %d", typ)
+		}
+		return nil
+	}
+}
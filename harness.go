@@ -0,0 +1,374 @@
+//go:build harness
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// updateGoldenEnv rewrites golden files with the frames rendered instead of
+// comparing them.
+const updateGoldenEnv = "LLAMA_TUI_UPDATE_GOLDEN"
+
+// harness drives appModel without a terminal or a tea.Program, for UI tests
+// built with -tags harness: messages are fed to Update one at a time, the
+// frame View renders after each is kept, and the commands Update returns
+// are held rather than run, so nothing launches, ticks, or reads the
+// network unless a test runs a command itself.
+//
+//	h := newHarness(t, 120, 40, appConfig{})
+//	h.Models("qwen-test.Q4_K_M.gguf", "embed-test.Q8_0.gguf")
+//	h.Keys("down", "?")
+//	h.Golden("help")
+type harness struct {
+	tb testing.TB
+	m  appModel
+	// dir holds the session's home, config, state, and models directory
+	dir    string
+	frames []string
+	// pending are the commands Update returned, oldest first
+	pending []tea.Cmd
+	masks   []*regexp.Regexp
+	quit    bool
+	// msgs carries what commands started by RunUntil return, to it or a
+	// later call, until ended is closed with the test
+	msgs  chan tea.Msg
+	ended chan struct{}
+}
+
+// harnessHome stands for the harness's home directory in frames, which is
+// a new temporary directory on every run.
+const harnessHome = "$HOME"
+
+// newHarness starts an app on cfg in a throwaway home directory, without
+// colors and sized width by height. The environment it sets is restored
+// when the test ends, so tests using it can't run in parallel.
+func newHarness(tb testing.TB, width, height int, cfg appConfig) *harness {
+	tb.Helper()
+	dir := tb.TempDir()
+	for key, value := range map[string]string{
+		"HOME":                 dir,
+		"XDG_CONFIG_HOME":      filepath.Join(dir, "config"),
+		"XDG_STATE_HOME":       filepath.Join(dir, "state"),
+		"XDG_CACHE_HOME":       filepath.Join(dir, "cache"),
+		"LLAMA_TUI_MODELS_DIR": filepath.Join(dir, "models"),
+		"LLAMA_TUI_CONFIG":     filepath.Join(dir, "llama-tui.json"),
+		"TMUX":                 "",
+		"NO_COLOR":             "1",
+	} {
+		tb.Setenv(key, value)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "models"), 0o755); err != nil {
+		tb.Fatal(err)
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "llama-tui.json"), data, 0o644); err != nil {
+		tb.Fatal(err)
+	}
+	barnDirOverride = ""
+	useMonochrome(true)
+	h := &harness{tb: tb, m: initialModel(""), dir: dir, msgs: make(chan tea.Msg), ended: make(chan struct{})}
+	// The title is cut to the panel's width, too late to mask the path
+	h.m.modelsList.Title = strings.ReplaceAll(h.m.modelsList.Title, dir, harnessHome)
+	tb.Cleanup(func() {
+		close(h.ended)
+		releaseLock(h.m.lockPath)
+	})
+	h.Send(tea.WindowSizeMsg{Width: width, Height: height})
+	return h
+}
+
+// Model is the app's state after the last message.
+func (h *harness) Model() appModel {
+	return h.m
+}
+
+// Send feeds msgs to Update in order, keeping the frame after each and the
+// commands returned.
+func (h *harness) Send(msgs ...tea.Msg) {
+	h.tb.Helper()
+	for _, msg := range msgs {
+		next, cmd := h.m.Update(msg)
+		m, ok := next.(appModel)
+		if !ok {
+			h.tb.Fatalf("Update returned %T for %T", next, msg)
+		}
+		h.m = m
+		if cmd != nil {
+			h.pending = append(h.pending, cmd)
+		}
+		h.frames = append(h.frames, h.m.View())
+	}
+}
+
+// Keys sends key presses named as tea.KeyMsg.String() names them, e.g.
+// "enter", "ctrl+k", "alt+x", "space", or "q".
+func (h *harness) Keys(keys ...string) {
+	h.tb.Helper()
+	for _, k := range keys {
+		h.Send(harnessKey(k))
+	}
+}
+
+// Type sends text a rune at a time, as typed into a filter or form.
+func (h *harness) Type(text string) {
+	h.tb.Helper()
+	for _, r := range text {
+		if r == ' ' {
+			h.Send(harnessKey("space"))
+		} else {
+			h.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		}
+	}
+}
+
+// harnessKeyTypes are Bubble Tea's key names.
+var harnessKeyTypes = func() map[string]tea.KeyType {
+	types := map[string]tea.KeyType{}
+	for k := tea.KeyType(-128); k < 128; k++ {
+		if name := k.String(); name != "" && name != " " {
+			types[name] = k
+		}
+	}
+	return types
+}()
+
+func harnessKey(name string) tea.KeyMsg {
+	var msg tea.KeyMsg
+	if rest, ok := strings.CutPrefix(name, "alt+"); ok && rest != "" {
+		msg.Alt, name = true, rest
+	}
+	switch t, ok := harnessKeyTypes[name]; {
+	case name == "space" || name == " ":
+		msg.Type, msg.Runes = tea.KeySpace, []rune{' '}
+	case ok:
+		msg.Type = t
+	default:
+		msg.Type, msg.Runes = tea.KeyRunes, []rune(name)
+	}
+	return msg
+}
+
+// Pending hands over the commands Update returned since the last call,
+// oldest first, for a test to run or drop.
+func (h *harness) Pending() []tea.Cmd {
+	cmds := h.pending
+	h.pending = nil
+	return cmds
+}
+
+// Run runs cmd and sends what it returns, running batched and sequenced
+// commands in order. Ticks sleep for their duration; tea.Quit marks the
+// app as quit instead of being sent.
+func (h *harness) Run(cmd tea.Cmd) {
+	h.tb.Helper()
+	if cmd == nil {
+		return
+	}
+	msg := cmd()
+	if msg == nil {
+		return
+	}
+	if _, ok := msg.(tea.QuitMsg); ok {
+		h.quit = true
+		return
+	}
+	// tea.Sequence's message is unexported, but like tea.BatchMsg it is a
+	// list of commands
+	if v := reflect.ValueOf(msg); v.Kind() == reflect.Slice && v.Type().Elem() == reflect.TypeOf(tea.Cmd(nil)) {
+		for i := range v.Len() {
+			h.Run(v.Index(i).Interface().(tea.Cmd))
+		}
+		return
+	}
+	h.Send(msg)
+}
+
+// RunUntil runs the pending commands concurrently, as a tea.Program would,
+// sending what they return to Update one message at a time and running the
+// commands that returns in turn, until done reports true for the app's
+// state. It fails the test once timeout passes first. Commands still
+// running then keep going, and what they return is sent by the next call.
+func (h *harness) RunUntil(timeout time.Duration, done func(appModel) bool) {
+	h.tb.Helper()
+	var run func(cmd tea.Cmd)
+	run = func(cmd tea.Cmd) {
+		if cmd == nil {
+			return
+		}
+		msg := cmd()
+		if batch, ok := msg.(tea.BatchMsg); ok {
+			for _, c := range batch {
+				go run(c)
+			}
+			return
+		}
+		// tea.Sequence's commands run one after another
+		if v := reflect.ValueOf(msg); v.Kind() == reflect.Slice && v.Type().Elem() == reflect.TypeOf(tea.Cmd(nil)) {
+			for i := range v.Len() {
+				run(v.Index(i).Interface().(tea.Cmd))
+			}
+			return
+		}
+		if msg == nil {
+			return
+		}
+		select {
+		case h.msgs <- msg:
+		case <-h.ended:
+		}
+	}
+	deadline := time.After(timeout)
+	for !done(h.m) {
+		for _, cmd := range h.Pending() {
+			go run(cmd)
+		}
+		select {
+		case msg := <-h.msgs:
+			if _, ok := msg.(tea.QuitMsg); ok {
+				h.quit = true
+				return
+			}
+			h.Send(msg)
+		case <-deadline:
+			h.tb.Fatalf("still waiting after %s; the last frame:\n%s", timeout, h.Frame())
+		}
+	}
+}
+
+// Quit reports whether a command run by Run quit the app.
+func (h *harness) Quit() bool {
+	return h.quit
+}
+
+// Models puts empty model files in the models directory and feeds the
+// scan of it to the app, as at startup.
+func (h *harness) Models(names ...string) {
+	h.tb.Helper()
+	for _, name := range names {
+		path := filepath.Join(h.dir, "models", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			h.tb.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			h.tb.Fatal(err)
+		}
+	}
+	h.Run(h.m.scanModelsCmd())
+}
+
+// FakeServer makes launches start the fake llama-server, with the options
+// --fake-server takes in spec, on a free port. The test binary is started
+// as the server, so the test's TestMain must hand over to runFakeServer
+// when fakeServerEnv is set. A server still running when the test ends is
+// stopped.
+func (h *harness) FakeServer(spec string) {
+	h.tb.Helper()
+	if _, err := parseFakeServerOptions(spec); err != nil {
+		h.tb.Fatal(err)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		h.tb.Fatal(err)
+	}
+	h.tb.Setenv(fakeServerEnv, spec)
+	h.tb.Setenv("LLAMA_SERVER_BIN", exe)
+	h.tb.Setenv("WHISPER_SERVER_BIN", exe)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		h.tb.Fatal(err)
+	}
+	h.m.portInput.SetValue(strconv.Itoa(ln.Addr().(*net.TCPAddr).Port))
+	_ = ln.Close()
+	h.tb.Cleanup(func() { h.m.process.stopAndWait(stopGrace + time.Second) })
+}
+
+// Frame is the frame View rendered after the last message.
+func (h *harness) Frame() string {
+	if len(h.frames) == 0 {
+		return h.m.View()
+	}
+	return h.frames[len(h.frames)-1]
+}
+
+// Frames are the frames rendered after each message, oldest first.
+func (h *harness) Frames() []string {
+	return h.frames
+}
+
+// Mask hides text matching pattern from golden files, such as clock times
+// and durations, keeping its width so the layout compares.
+func (h *harness) Mask(pattern string) {
+	h.masks = append(h.masks, regexp.MustCompile(pattern))
+}
+
+func (h *harness) masked(frame string) string {
+	for _, re := range h.masks {
+		frame = re.ReplaceAllStringFunc(frame, func(s string) string {
+			return strings.Repeat("#", len([]rune(s)))
+		})
+	}
+	return frame
+}
+
+// Golden compares the last frame with testdata/<name>.golden, or writes it
+// there when LLAMA_TUI_UPDATE_GOLDEN is set.
+func (h *harness) Golden(name string) {
+	h.tb.Helper()
+	got := h.masked(h.Frame())
+	path := filepath.Join("testdata", name+".golden")
+	if os.Getenv(updateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			h.tb.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			h.tb.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		h.tb.Fatalf("%v (run with %s=1 to create it)", err, updateGoldenEnv)
+	}
+	if diff := frameDiff(string(want), got); diff != "" {
+		h.tb.Errorf("frame differs from %s (run with %s=1 to update it):\n%s", path, updateGoldenEnv, diff)
+	}
+}
+
+// frameDiff lists the lines that differ between two frames, "-" for want
+// and "+" for got.
+func frameDiff(want, got string) string {
+	if want == got {
+		return ""
+	}
+	w, g := strings.Split(want, "\n"), strings.Split(got, "\n")
+	var b strings.Builder
+	for i := range max(len(w), len(g)) {
+		var wl, gl string
+		if i < len(w) {
+			wl = w[i]
+		}
+		if i < len(g) {
+			gl = g[i]
+		}
+		if wl != gl || i >= len(w) || i >= len(g) {
+			fmt.Fprintf(&b, "%4d - %s\n%4d + %s\n", i+1, wl, i+1, gl)
+		}
+	}
+	return b.String()
+}
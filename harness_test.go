@@ -0,0 +1,35 @@
+//go:build harness
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+func TestFilterModels(t *testing.T) {
+	h := newHarness(t, 120, 30, appConfig{DisableSessionRestore: true})
+	h.Models("qwen-test.Q4_K_M.gguf", "embed-test.Q8_0.gguf")
+	h.Golden("models")
+	h.Keys("/")
+	h.Type("emb")
+	h.Keys("enter")
+	h.Golden("models-filtered")
+	if mi, ok := h.Model().modelsList.SelectedItem().(modelItem); !ok || mi.name != "embed-test.Q8_0.gguf" {
+		t.Errorf("selected %v, want embed-test.Q8_0.gguf", h.Model().modelsList.SelectedItem())
+	}
+}
+
+func TestSourceScanErrorKeepsModels(t *testing.T) {
+	h := newHarness(t, 120, 30, appConfig{DisableSessionRestore: true})
+	h.Send(scanDoneMsg{
+		items:     []list.Item{modelItem{name: "qwen-test.Q4_K_M.gguf", path: "/models/qwen-test.Q4_K_M.gguf", relPath: "qwen-test.Q4_K_M.gguf"}},
+		sourceErr: errors.New("ollama models: permission denied"),
+	})
+	h.Golden("source-scan-error")
+	if n := len(h.Model().modelsList.Items()); n != 1 {
+		t.Errorf("listed %d models, want 1", n)
+	}
+}
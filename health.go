@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const healthPollInterval = 2 * time.Second
+
+// healthState is what the server's /health endpoint last said.
+type healthState int
+
+const (
+	healthUnknown healthState = iota // not polled yet
+	healthLoading                    // 503 while the model loads
+	healthOK
+	healthError // no answer, or an unexpected status
+)
+
+func (s healthState) String() string {
+	switch s {
+	case healthLoading:
+		return "LOADING"
+	case healthOK:
+		return "HEALTHY"
+	case healthError:
+		return "ERROR"
+	}
+	return "UNKNOWN"
+}
+
+// serverHealth is one poll of the running server. Slot figures come from
+// /slots and throughput from /metrics; either is missing when the server
+// was started without it (--no-slots, or no --metrics).
+type serverHealth struct {
+	state  healthState
+	detail string
+	at     time.Time
+
+	hasSlots   bool
+	slotsBusy  int
+	slotsTotal int
+
+	hasMetrics bool
+	promptTPS  float64
+	genTPS     float64
+	inFlight   int
+	deferred   int
+}
+
+// pollHealthCmd polls the server after delay, tagged with its poll loop.
+func pollHealthCmd(port string, seq int, metrics bool, delay time.Duration) tea.Cmd {
+	return tea.Tick(delay, func(time.Time) tea.Msg {
+		return healthMsg{port: port, seq: seq, health: probeHealth(port, metrics)}
+	})
+}
+
+func probeHealth(port string, metrics bool) serverHealth {
+	h := serverHealth{at: time.Now()}
+	client := http.Client{Timeout: 2 * time.Second}
+	base := "http://127.0.0.1:" + port
+	resp, err := client.Get(base + "/health")
+	if err != nil {
+		h.state, h.detail = healthError, err.Error()
+		return h
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		h.state = healthOK
+	case http.StatusServiceUnavailable:
+		h.state, h.detail = healthLoading, healthErrorMessage(body)
+	default:
+		h.state, h.detail = healthError, resp.Status
+		if msg := healthErrorMessage(body); msg != "" {
+			h.detail += ": " + msg
+		}
+		return h
+	}
+	if h.state != healthOK {
+		return h
+	}
+	if resp, err := client.Get(base + "/slots"); err == nil {
+		var slots []struct {
+			IsProcessing bool `json:"is_processing"`
+		}
+		if resp.StatusCode == http.StatusOK && json.NewDecoder(resp.Body).Decode(&slots) == nil {
+			h.hasSlots, h.slotsTotal = true, len(slots)
+			for _, s := range slots {
+				if s.IsProcessing {
+					h.slotsBusy++
+				}
+			}
+		}
+		resp.Body.Close()
+	}
+	if metrics {
+		if resp, err := client.Get(base + "/metrics"); err == nil {
+			if resp.StatusCode == http.StatusOK {
+				h.readMetrics(resp.Body)
+			}
+			resp.Body.Close()
+		}
+	}
+	return h
+}
+
+// healthErrorMessage is the message in llama-server's error body, e.g.
+// "Loading model".
+func healthErrorMessage(body []byte) string {
+	var payload struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &payload) != nil {
+		return ""
+	}
+	return payload.Error.Message
+}
+
+// readMetrics picks llama-server's gauges out of the Prometheus text format.
+func (h *serverHealth) readMetrics(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			continue
+		}
+		switch name {
+		case "llamacpp:prompt_tokens_seconds":
+			h.promptTPS = v
+		case "llamacpp:predicted_tokens_seconds":
+			h.genTPS = v
+		case "llamacpp:requests_processing":
+			h.inFlight = int(v)
+		case "llamacpp:requests_deferred":
+			h.deferred = int(v)
+		default:
+			continue
+		}
+		h.hasMetrics = true
+	}
+}
+
+// servesMetrics reports whether the server exposes /metrics. An attached
+// server's flags are unknown, so it is tried.
+func (m appModel) servesMetrics() bool {
+	if m.process == nil {
+		return m.attached != nil
+	}
+	for _, f := range parseFlagArgs(m.process.args) {
+		if f.name == "--metrics" {
+			return true
+		}
+	}
+	return false
+}
+
+// startHealthPoll starts a new poll loop; any older loop ends when its
+// result arrives.
+func (m *appModel) startHealthPoll() tea.Cmd {
+	m.healthSeq++
+	m.health = serverHealth{}
+	m.hung = ""
+	m.lastHealthAnswer, m.lastServerLogAt = time.Now(), time.Now()
+	return pollHealthCmd(m.currentPort, m.healthSeq, m.servesMetrics(), 0)
+}
+
+// handleHealth records a poll and schedules the next one while the server
+// is up. State changes go to the log in place of a one-off "Ready" line,
+// and the watchdog looks for a hang.
+func (m appModel) handleHealth(msg healthMsg) (appModel, tea.Cmd) {
+	if msg.seq != m.healthSeq || msg.port != m.currentPort || !m.server.serving() {
+		return m, nil
+	}
+	prev := m.health.state
+	m.health = msg.health
+	if msg.health.state == healthOK {
+		m.completeFailover()
+	}
+	if next := msg.health.state; next != prev {
+		line := fmt.Sprintf("[health] %s on port %s", next, msg.port)
+		if msg.health.detail != "" {
+			line += " - " + msg.health.detail
+		}
+		m.logEvent(line)
+	}
+	if msg.health.state != healthError {
+		m.lastHealthAnswer = msg.health.at
+	}
+	m.checkWatchdog()
+	return m, pollHealthCmd(msg.port, msg.seq, m.servesMetrics(), healthPollInterval)
+}
+
+// healthStyle colors the indicator by state.
+func (m appModel) healthStyle() lipgloss.Style {
+	switch m.health.state {
+	case healthOK:
+		return m.styles.statusRunning
+	case healthError:
+		return m.styles.logError.Bold(true)
+	}
+	return m.styles.statusStopping
+}
+
+// summary is the one-line panel, e.g. "slots 1/4 · 812.3 / 41.2 t/s · 1 in flight".
+func (h serverHealth) summary() string {
+	var parts []string
+	if h.hasSlots {
+		parts = append(parts, fmt.Sprintf("slots %d/%d", h.slotsBusy, h.slotsTotal))
+	}
+	if h.hasMetrics {
+		parts = append(parts, fmt.Sprintf("%.1f / %.1f t/s", h.promptTPS, h.genTPS))
+		flight := fmt.Sprintf("%d in flight", h.inFlight)
+		if h.deferred > 0 {
+			flight += fmt.Sprintf(", %d queued", h.deferred)
+		}
+		parts = append(parts, flight)
+	}
+	return strings.Join(parts, " · ")
+}
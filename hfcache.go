@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// hfCacheFile is a model llama-server downloaded with -hf.
+type hfCacheFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+	// partial downloads were interrupted or are still running
+	partial bool
+	// live is set when a download into the file looks to be running
+	live bool
+}
+
+// hfLiveWindow is how recently a partial download must have grown, or a
+// lock file been touched, for the download to count as running.
+const hfLiveWindow = time.Minute
+
+const hfPartialSuffix = ".downloadInProgress"
+
+// hfDownloadLive reports whether llama-server is still downloading the
+// model at path, a finished or partial file: its partial file grew within
+// hfLiveWindow or a lock file is held next to it.
+func hfDownloadLive(path string) bool {
+	model := strings.TrimSuffix(path, hfPartialSuffix)
+	for _, p := range []string{model + hfPartialSuffix, model + ".lock"} {
+		if info, err := os.Stat(p); err == nil && time.Since(info.ModTime()) < hfLiveWindow {
+			return true
+		}
+	}
+	return false
+}
+
+// hfCacheView is the screen listing llama-server's download cache.
+type hfCacheView struct {
+	files  []hfCacheFile
+	cursor int
+	err    error
+	// note is the outcome of the last action, shown above the keys
+	note string
+}
+
+// scanHFCacheCmd lists the GGUF files in llama-server's cache, largest
+// first.
+func scanHFCacheCmd() tea.Cmd {
+	return func() tea.Msg {
+		dir := llamaCacheDir()
+		if dir == "" {
+			return hfCacheScannedMsg{err: fmt.Errorf("no cache directory available")}
+		}
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			return hfCacheScannedMsg{}
+		}
+		if err != nil {
+			return hfCacheScannedMsg{err: err}
+		}
+		var files []hfCacheFile
+		for _, e := range entries {
+			name := e.Name()
+			partial := strings.HasSuffix(name, hfPartialSuffix)
+			if e.IsDir() || !(strings.HasSuffix(name, ".gguf") || partial) {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil {
+				continue
+			}
+			path := filepath.Join(dir, name)
+			files = append(files, hfCacheFile{path: path, size: info.Size(), modTime: info.ModTime(), partial: partial, live: hfDownloadLive(path)})
+		}
+		sort.Slice(files, func(i, j int) bool { return files[i].size > files[j].size })
+		return hfCacheScannedMsg{files: files}
+	}
+}
+
+// deleteHFCacheFileCmd removes a cached model and the metadata llama-server
+// keeps next to it (ETag and manifest files), unless a download into it
+// started since the scan.
+func deleteHFCacheFileCmd(f hfCacheFile) tea.Cmd {
+	return func() tea.Msg {
+		if hfDownloadLive(f.path) {
+			return hfCacheDeletedMsg{file: f, err: errHFDownloading}
+		}
+		err := os.Remove(f.path)
+		if err == nil {
+			_ = os.Remove(f.path + ".json")
+			_ = os.Remove(f.path + ".etag")
+		}
+		return hfCacheDeletedMsg{file: f, err: err}
+	}
+}
+
+var errHFDownloading = errors.New("still downloading")
+
+// hfCacheRepo names the repo entry whose download this is, if any.
+func (m appModel) hfCacheRepo(path string) string {
+	for _, e := range m.hfRepos {
+		if e.CachePath == path {
+			return e.Repo
+		}
+	}
+	return ""
+}
+
+// hfCacheInUse reports whether the server is running from the file.
+func (m appModel) hfCacheInUse(path string) bool {
+	repo := m.servedHFRepo()
+	return repo != "" && repo == m.hfCacheRepo(path)
+}
+
+// handleHFCacheKey moves through and deletes from the cache screen, which
+// captures keys while open.
+func (m appModel) handleHFCacheKey(keyStr string) (appModel, tea.Cmd) {
+	v := *m.cacheView
+	if cursor, ok := moveFileCursor(keyStr, v.cursor, len(v.files)); ok {
+		v.cursor = cursor
+		m.cacheView = &v
+		return m, nil
+	}
+	switch keyStr {
+	case "r":
+		m.cacheView = &v
+		return m, scanHFCacheCmd()
+	case "d":
+		if v.cursor >= len(v.files) || m.observerRefuses("delete cached models") {
+			return m, nil
+		}
+		f := v.files[v.cursor]
+		if m.hfCacheInUse(f.path) {
+			v.note = "Stop the server before deleting the model it is running"
+			m.cacheView = &v
+			return m, nil
+		}
+		if f.live {
+			v.note = filepath.Base(f.path) + " is still downloading; [r] rescans once it is done"
+			m.cacheView = &v
+			return m, nil
+		}
+		if m.confirmAction == confirmDeleteCache {
+			m.confirmAction = confirmNone
+			return m, deleteHFCacheFileCmd(f)
+		}
+		v.note = ""
+		m.cacheView = &v
+		return m, m.askConfirm(confirmDeleteCache)
+	case "esc", "A":
+		if m.confirmAction != confirmNone {
+			m.confirmAction = confirmNone
+			v.note = "Delete cancelled"
+			m.cacheView = &v
+			return m, nil
+		}
+		m.cacheView = nil
+		return m, nil
+	}
+	m.cacheView = &v
+	return m, nil
+}
+
+// renderHFCacheView lists the cached downloads with sizes, marking the ones
+// that belong to repo entries and the one being served.
+func (m appModel) renderHFCacheView(width int) string {
+	v := m.cacheView
+	var b strings.Builder
+	footer := m.styles.help.Render("[↑/↓] select  [d] delete  [r] rescan  [A] or [esc] close")
+	if m.confirmAction == confirmDeleteCache && v.cursor < len(v.files) {
+		f := v.files[v.cursor]
+		footer = m.styles.confirmWarning.Render(fmt.Sprintf("Delete %s (%s)? Press d again to confirm, esc to cancel%s",
+			filepath.Base(f.path), formatBytes(uint64(f.size)), m.confirmCountdown()))
+	} else if v.note != "" {
+		footer = m.styles.status.Render(v.note) + "\n" + footer
+	}
+	var total int64
+	for _, f := range v.files {
+		total += f.size
+	}
+	b.WriteString(m.styles.help.Render(fmt.Sprintf("%s · %s in %s", llamaCacheDir(), formatBytes(uint64(total)), pluralize(len(v.files), "file"))) + "\n\n")
+	if v.err != nil {
+		return b.String() + m.styles.logError.Render(v.err.Error()) + "\n\n" + footer
+	}
+	if len(v.files) == 0 {
+		return b.String() + "No models downloaded with -hf yet.\n\n" + footer
+	}
+	rows := make([]fileRow, len(v.files))
+	for i, f := range v.files {
+		var notes []string
+		if repo := m.hfCacheRepo(f.path); repo != "" {
+			notes = append(notes, "hf:"+repo)
+		}
+		if m.hfCacheInUse(f.path) {
+			notes = append(notes, "serving")
+		}
+		switch {
+		case f.live:
+			notes = append(notes, "downloading")
+		case f.partial:
+			notes = append(notes, "partial")
+		}
+		notes = append(notes, m.config.Timestamps.date(f.modTime))
+		rows[i] = fileRow{name: filepath.Base(f.path), size: f.size, note: strings.Join(notes, " · ")}
+	}
+	b.WriteString(m.renderFileRows(rows, v.cursor, width))
+	b.WriteString("\n" + footer)
+	return b.String()
+}
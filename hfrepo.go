@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// hfRepoEntry is a Hugging Face repo llama-server loads itself with -hf,
+// downloading into its own cache on first launch. Entries are stored in
+// the state directory, in the order they were added.
+type hfRepoEntry struct {
+	// Repo is "user/model" with an optional ":quant" tag
+	Repo string `json:"repo"`
+	// CachePath is the GGUF llama-server downloaded, learned from its log
+	CachePath string `json:"cache_path,omitempty"`
+}
+
+// hfPathPrefix marks the path of a repo entry; the launch replaces the
+// model argument with -hf <repo>.
+const hfPathPrefix = "hf://"
+
+var (
+	hfRepoSpec = regexp.MustCompile(`^[A-Za-z0-9][\w.-]*/[\w.-]+(?::[\w.-]+)?$`)
+	// hfProgress finds a percentage in llama-server's download output
+	hfProgress = regexp.MustCompile(`(\d{1,3}(?:\.\d+)?)%`)
+	// hfCachedFile finds the GGUF llama-server downloaded or loads
+	hfCachedFile = regexp.MustCompile(`(/\S+\.gguf)\b`)
+)
+
+func validateHFRepo(repo string) error {
+	if repo == "" {
+		// Clears the entry being edited
+		return nil
+	}
+	if !hfRepoSpec.MatchString(repo) {
+		return errors.New("expected user/model or user/model:quant, e.g. unsloth/Qwen3-8B-GGUF:Q4_K_M")
+	}
+	return nil
+}
+
+func hfReposPath(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "hf-repos.json")
+}
+
+func loadHFRepos(dir string) ([]hfRepoEntry, error) {
+	path := hfReposPath(dir)
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []hfRepoEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+func saveHFReposCmd(dir string, entries []hfRepoEntry) tea.Cmd {
+	return func() tea.Msg {
+		path := hfReposPath(dir)
+		if path == "" {
+			return hfReposSavedMsg{err: fmt.Errorf("no state directory available")}
+		}
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(path), 0o755)
+		}
+		if err == nil {
+			err = os.WriteFile(path, data, 0o644)
+		}
+		return hfReposSavedMsg{err: err}
+	}
+}
+
+// hfRepoItems lists the repo entries. Once llama-server has downloaded a
+// repo, its cached file supplies the size and header details.
+func hfRepoItems(entries []hfRepoEntry) []list.Item {
+	items := make([]list.Item, 0, len(entries))
+	for _, e := range entries {
+		item := enrichModelName(modelItem{
+			name:    "hf:" + e.Repo,
+			path:    hfPathPrefix + e.Repo,
+			relPath: "Hugging Face repo, loaded with -hf",
+			hfRepo:  e.Repo,
+			hfCache: e.CachePath,
+		})
+		if info, err := os.Stat(e.CachePath); e.CachePath != "" && err == nil {
+			cached := enrichModelItem(modelItem{name: item.name, path: e.CachePath})
+			item.size = info.Size()
+			item.arch, item.contextLength = cached.arch, cached.contextLength
+			if cached.params != "" {
+				item.params = cached.params
+			}
+			if cached.quant != "" {
+				item.quant = cached.quant
+			}
+			item.relPath = "hf cache: " + e.CachePath
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// withHFRepo replaces the model argument of a repo entry's launch with
+// -hf, so llama-server resolves and downloads the repo itself.
+func withHFRepo(argv []string, item modelItem) []string {
+	if item.hfRepo == "" {
+		return argv
+	}
+	out := make([]string, 0, len(argv))
+	for i := 0; i < len(argv); i++ {
+		switch {
+		case (argv[i] == "-m" || argv[i] == "--model") && i+1 < len(argv) && argv[i+1] == item.path:
+			out = append(out, "-hf", item.hfRepo)
+			i++
+		case argv[i] == "--model="+item.path:
+			out = append(out, "-hf", item.hfRepo)
+		default:
+			out = append(out, argv[i])
+		}
+	}
+	return out
+}
+
+// llamaCacheDir is where llama-server keeps -hf downloads.
+func llamaCacheDir() string {
+	if dir := os.Getenv("LLAMA_CACHE"); dir != "" {
+		return dir
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "llama.cpp")
+}
+
+// hfDownloadTickCmd samples the partial downloads in llama-server's cache
+// while a repo entry loads.
+func hfDownloadTickCmd() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		var total int64
+		matches, _ := filepath.Glob(filepath.Join(llamaCacheDir(), "*.downloadInProgress"))
+		for _, path := range matches {
+			if info, err := os.Stat(path); err == nil {
+				total += info.Size()
+			}
+		}
+		return hfDownloadTickMsg{bytes: total}
+	})
+}
+
+// servedHFRepo returns the repo being served, if the server runs one.
+func (m appModel) servedHFRepo() string {
+	if !strings.HasPrefix(m.servingPath, hfPathPrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(m.servingPath, hfPathPrefix)
+}
+
+// trackHFLog follows a repo entry's download through the server log:
+// progress goes to the status line, and the cached file is remembered.
+func (m appModel) trackHFLog(line string) (appModel, tea.Cmd) {
+	repo := m.servedHFRepo()
+	if repo == "" {
+		return m, nil
+	}
+	lower := strings.ToLower(line)
+	if m.server == serverLoading && strings.Contains(lower, "download") {
+		if pct := hfProgress.FindStringSubmatch(line); pct != nil {
+			m.statusLineText = "Downloading " + repo + ": " + pct[1] + "%"
+		}
+	}
+	if !strings.Contains(lower, "loaded meta data") && !strings.Contains(lower, "download") && !strings.Contains(lower, "cache") {
+		return m, nil
+	}
+	path := hfCachedFile.FindString(line)
+	if path == "" {
+		return m, nil
+	}
+	for i, e := range m.hfRepos {
+		if e.Repo != repo || e.CachePath == path {
+			continue
+		}
+		entries := append([]hfRepoEntry(nil), m.hfRepos...)
+		entries[i].CachePath = path
+		m.hfRepos = entries
+		for j, it := range m.modelsList.Items() {
+			if mi, ok := it.(modelItem); ok && mi.hfRepo == repo {
+				mi.hfCache = path
+				m.modelsList.SetItem(j, mi)
+			}
+		}
+		for j, it := range m.foldedModels {
+			if mi, ok := it.(modelItem); ok && mi.hfRepo == repo {
+				mi.hfCache = path
+				m.foldedModels[j] = mi
+			}
+		}
+		m.logEvent("[hf] " + repo + " is cached at " + path)
+		return m, saveHFReposCmd(m.historyDir, entries)
+	}
+	return m, nil
+}
+
+// setHFRepo adds, renames, or (with an empty repo) removes an entry,
+// keeping entries unique.
+func setHFRepo(entries []hfRepoEntry, old, repo string) []hfRepoEntry {
+	exists := false
+	for _, e := range entries {
+		if e.Repo == repo {
+			exists = true
+		}
+	}
+	out := make([]hfRepoEntry, 0, len(entries)+1)
+	for _, e := range entries {
+		switch {
+		case e.Repo != old || old == repo:
+			out = append(out, e)
+		case repo != "" && !exists:
+			// Renamed in place; the old download does not apply
+			out = append(out, hfRepoEntry{Repo: repo})
+			exists = true
+		}
+	}
+	if !exists && repo != "" {
+		out = append(out, hfRepoEntry{Repo: repo})
+	}
+	return out
+}
@@ -0,0 +1,482 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const hubRequestTimeout = 30 * time.Second
+
+var (
+	// hubURLPattern takes the repo and file from a pasted huggingface.co
+	// link such as https://huggingface.co/user/model/blob/main/x.gguf
+	hubURLPattern = regexp.MustCompile(`^https?://[^/]+/([^/]+/[^/]+)(?:/(?:blob|resolve)/[^/]+/(.+))?/?$`)
+	// hubShardPattern matches the -NNNNN-of-NNNNN shards of a split model
+	hubShardPattern = regexp.MustCompile(`(?i)^(.+)-(\d+)-of-(\d+)\.gguf$`)
+)
+
+// hubEndpoint is the Hugging Face Hub, or the mirror set in $HF_ENDPOINT
+// as for the other Hugging Face tools.
+func hubEndpoint() string {
+	if ep := strings.TrimRight(os.Getenv("HF_ENDPOINT"), "/"); ep != "" {
+		return ep
+	}
+	return "https://huggingface.co"
+}
+
+// setHubAuth sends $HF_TOKEN to the hub, for gated and private repos.
+func setHubAuth(req *http.Request) {
+	token := os.Getenv("HF_TOKEN")
+	hub, err := url.Parse(hubEndpoint())
+	if token == "" || err != nil || req.URL.Host != hub.Host {
+		return
+	}
+	// Go drops the header when redirected to another host, such as the CDN
+	req.Header.Set("Authorization", "Bearer "+token)
+}
+
+// hubQuery is what was typed into the browser: a repo (optionally with a
+// quant or a file in it), or words to search for.
+type hubQuery struct {
+	repo   string
+	file   string
+	quant  string
+	search string
+}
+
+func parseHubQuery(q string) hubQuery {
+	q = strings.TrimSpace(q)
+	if m := hubURLPattern.FindStringSubmatch(q); m != nil {
+		return hubQuery{repo: m[1], file: m[2]}
+	}
+	if strings.Contains(q, " ") || !strings.Contains(q, "/") {
+		return hubQuery{search: q}
+	}
+	parts := strings.SplitN(q, "/", 3)
+	query := hubQuery{repo: parts[0] + "/" + parts[1]}
+	if len(parts) == 3 {
+		query.file = parts[2]
+	}
+	if repo, quant, ok := strings.Cut(query.repo, ":"); ok && query.file == "" {
+		query.repo, query.quant = repo, quant
+	}
+	return query
+}
+
+// hubRepo is a search hit.
+type hubRepo struct {
+	ID        string `json:"id"`
+	Downloads int    `json:"downloads"`
+}
+
+// hubFile is one file in a repo.
+type hubFile struct {
+	Type string `json:"type"`
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	// LFS is set for files stored in Git LFS, as GGUF files are; its oid
+	// is the SHA-256 of the content
+	LFS *struct {
+		OID string `json:"oid"`
+	} `json:"lfs"`
+}
+
+// sha256 is the checksum the hub lists for the file, "" when none.
+func (f hubFile) sha256() string {
+	if f.LFS == nil {
+		return ""
+	}
+	return f.LFS.OID
+}
+
+// hubModel is one downloadable model: a GGUF file, or every shard of a
+// split one.
+type hubModel struct {
+	name  string
+	quant string
+	files []hubFile
+	size  int64
+	// revision is the commit the files were listed at, and are
+	// downloaded from
+	revision string
+}
+
+func hubGetJSON(ctx context.Context, u string, v any) error {
+	ctx, cancel := context.WithTimeout(ctx, hubRequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	setHubAuth(req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", u, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// hubSearchCmd looks up repos with GGUF files, most downloaded first.
+func hubSearchCmd(query string) tea.Cmd {
+	return func() tea.Msg {
+		u := hubEndpoint() + "/api/models?filter=gguf&sort=downloads&direction=-1&limit=50&search=" + url.QueryEscape(query)
+		var repos []hubRepo
+		err := hubGetJSON(context.Background(), u, &repos)
+		return hubSearchMsg{query: query, repos: repos, err: err}
+	}
+}
+
+// hubFilesCmd lists a repo's GGUF models at the current commit of its
+// main branch; file or quant, when given, pick the one to select.
+func hubFilesCmd(repo, file, quant string) tea.Cmd {
+	return func() tea.Msg {
+		msg := hubFilesMsg{repo: repo, file: file, quant: quant}
+		// Pinned, so a push while downloading can't mix shards of two
+		// commits
+		var info struct {
+			SHA string `json:"sha"`
+		}
+		if msg.err = hubGetJSON(context.Background(), hubEndpoint()+"/api/models/"+repo+"/revision/main", &info); msg.err != nil {
+			return msg
+		}
+		if info.SHA == "" {
+			msg.err = fmt.Errorf("%s: no commit for main", repo)
+			return msg
+		}
+		var files []hubFile
+		msg.err = hubGetJSON(context.Background(), hubEndpoint()+"/api/models/"+repo+"/tree/"+info.SHA+"?recursive=true", &files)
+		msg.models = groupHubFiles(files)
+		for i := range msg.models {
+			msg.models[i].revision = info.SHA
+		}
+		return msg
+	}
+}
+
+// groupHubFiles collects the GGUF files of a repo into models, joining the
+// shards of split ones.
+func groupHubFiles(files []hubFile) []hubModel {
+	byName := map[string]*hubModel{}
+	var names []string
+	for _, f := range files {
+		if f.Type != "file" || !strings.HasSuffix(strings.ToLower(f.Path), ".gguf") {
+			continue
+		}
+		name := f.Path
+		if m := hubShardPattern.FindStringSubmatch(f.Path); m != nil {
+			name = m[1] + ".gguf"
+		}
+		model := byName[name]
+		if model == nil {
+			model = &hubModel{name: name}
+			// Quants are often directories, e.g. Q4_K_M/model-00001-of-00002.gguf
+			if match := quantPattern.FindStringSubmatch(strings.ReplaceAll(name, "/", "-")); match != nil {
+				model.quant = strings.ToUpper(match[1])
+			}
+			byName[name] = model
+			names = append(names, name)
+		}
+		model.files = append(model.files, f)
+		model.size += f.Size
+	}
+	sort.Strings(names)
+	models := make([]hubModel, 0, len(names))
+	for _, name := range names {
+		model := *byName[name]
+		sort.Slice(model.files, func(i, j int) bool { return model.files[i].Path < model.files[j].Path })
+		models = append(models, model)
+	}
+	return models
+}
+
+// hubModelDir is where a repo's downloads go: a directory in the barn
+// named after the repo.
+func hubModelDir(barnDir, repo string) string {
+	return filepath.Join(barnDir, sanitizeFileComponent(path.Base(repo)))
+}
+
+// hubDownloadCmd downloads every file of a model into the barn, keeping
+// the repo's directories so quants with the same file names don't collide.
+// Files already there are skipped and partial ones resumed, so an
+// interrupted download continues where it stopped; each is checked
+// against the checksum the hub lists. With a blob store, each file is
+// moved into it and linked in place.
+func hubDownloadCmd(ctx context.Context, barnDir, repo string, model hubModel, blobDir string, scanCommand []string, progress *downloadProgress) tea.Cmd {
+	return func() tea.Msg {
+		progress.total.Store(model.size)
+		dir := hubModelDir(barnDir, repo)
+		msg := hubDownloadDoneMsg{repo: repo, name: model.name, dir: dir}
+		license, gated := hubRepoLicense(ctx, repo)
+		for _, f := range model.files {
+			rel := filepath.FromSlash(f.Path)
+			if !filepath.IsLocal(rel) {
+				msg.err = fmt.Errorf("%s: not a path inside the repo", f.Path)
+				return msg
+			}
+			dest := filepath.Join(dir, rel)
+			if info, err := os.Stat(dest); err == nil && (f.Size == 0 || info.Size() == f.Size) {
+				progress.done.Add(info.Size())
+				continue
+			}
+			u := hubEndpoint() + "/" + repo + "/resolve/" + model.revision + "/" + (&url.URL{Path: f.Path}).EscapedPath()
+			prov, err := downloadFile(ctx, u, dest, f.sha256(), scanCommand, progress)
+			if err != nil {
+				if ctx.Err() != nil {
+					err = fmt.Errorf("cancelled; downloading it again resumes")
+				}
+				msg.err = fmt.Errorf("%s: %w", path.Base(f.Path), err)
+				return msg
+			}
+			prov.License, prov.Gated = license, gated
+			msg.scan = prov.Scan
+			// The model is usable without it; losing provenance is not fatal
+			_ = saveProvenance(dest, prov)
+			if blobDir != "" {
+				// Left as a plain file if it can't be stored
+				_ = storeBlob(blobDir, dest, prov.SHA256)
+			}
+		}
+		return msg
+	}
+}
+
+// hubBrowser is the download screen: a query line, then either search
+// results or the GGUF models of one repo.
+type hubBrowser struct {
+	input textinput.Model
+	// searched is the query the results are for
+	searched string
+	repos    []hubRepo
+	// repo is the repo whose models are listed; "" lists repos
+	repo    string
+	models  []hubModel
+	cursor  int
+	loading bool
+	err     error
+}
+
+func newHubBrowser() hubBrowser {
+	in := textinput.New()
+	in.Prompt = "Hugging Face: "
+	in.Placeholder = "search words, user/repo, user/repo:Q4_K_M, or a file URL"
+	in.Focus()
+	return hubBrowser{input: in}
+}
+
+// rows is the number of selectable entries shown.
+func (b hubBrowser) rows() int {
+	if b.repo != "" {
+		return len(b.models)
+	}
+	return len(b.repos)
+}
+
+// submit runs the query typed into the browser.
+func (b hubBrowser) submit() (hubBrowser, tea.Cmd) {
+	q := parseHubQuery(b.input.Value())
+	b.searched = strings.TrimSpace(b.input.Value())
+	b.cursor, b.err, b.loading = 0, nil, true
+	if q.repo != "" {
+		b.repos = nil
+		b.repo, b.models = q.repo, nil
+		return b, hubFilesCmd(q.repo, q.file, q.quant)
+	}
+	if q.search == "" {
+		b.loading = false
+		return b, nil
+	}
+	b.repo, b.models = "", nil
+	return b, hubSearchCmd(q.search)
+}
+
+func (m appModel) handleHubKey(msg tea.KeyMsg) (appModel, tea.Cmd) {
+	b := *m.hub
+	switch msg.String() {
+	case "esc":
+		if b.repo != "" && len(b.repos) > 0 {
+			// Back to the search results
+			b.repo, b.models, b.cursor, b.err = "", nil, 0, nil
+			m.hub = &b
+			return m, nil
+		}
+		m.hub = nil
+		return m, nil
+	case "up", "ctrl+p":
+		if b.cursor > 0 {
+			b.cursor--
+		}
+	case "down", "ctrl+n":
+		if b.cursor < b.rows()-1 {
+			b.cursor++
+		}
+	case "enter":
+		switch {
+		case b.loading:
+		case strings.TrimSpace(b.input.Value()) != b.searched || b.rows() == 0:
+			var cmd tea.Cmd
+			b, cmd = b.submit()
+			m.hub = &b
+			return m, cmd
+		case b.repo == "":
+			b.repo, b.models, b.loading = b.repos[b.cursor].ID, nil, true
+			b.cursor, b.err = 0, nil
+			m.hub = &b
+			return m, hubFilesCmd(b.repo, "", "")
+		default:
+			m.hub = &b
+			return m.startHubDownload(b.repo, b.models[b.cursor])
+		}
+	default:
+		var cmd tea.Cmd
+		b.input, cmd = b.input.Update(msg)
+		m.hub = &b
+		return m, cmd
+	}
+	m.hub = &b
+	return m, nil
+}
+
+// startHubDownload downloads model into the barn in the background.
+func (m appModel) startHubDownload(repo string, model hubModel) (appModel, tea.Cmd) {
+	if m.downloadCancel != nil {
+		m.statusLineText = "A download is already in progress"
+		return m, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.downloadCancel = cancel
+	m.download = &downloadProgress{name: path.Base(model.name)}
+	m.statusLineText = fmt.Sprintf("Downloading %s from %s into %s...", path.Base(model.name), repo, hubModelDir(m.barnDir, repo))
+	return m, tea.Batch(hubDownloadCmd(ctx, m.barnDir, repo, model, m.downloadBlobDir(), m.config.ScanCommand, m.download), downloadTickCmd())
+}
+
+// handleHubMsg takes in search results and repo listings.
+func (m appModel) handleHubMsg(msg tea.Msg) (appModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case hubSearchMsg:
+		if m.hub == nil || m.hub.repo != "" || m.hub.searched != msg.query {
+			return m, nil
+		}
+		b := *m.hub
+		b.loading, b.repos, b.err = false, msg.repos, msg.err
+		m.hub = &b
+		return m, nil
+
+	case hubFilesMsg:
+		if m.hub == nil || m.hub.repo != msg.repo {
+			return m, nil
+		}
+		b := *m.hub
+		b.loading, b.models, b.err = false, msg.models, msg.err
+		if msg.err == nil && len(msg.models) == 0 {
+			b.err = fmt.Errorf("no GGUF files in %s", msg.repo)
+		}
+		for i, model := range msg.models {
+			if msg.quant != "" && strings.EqualFold(model.quant, msg.quant) {
+				b.cursor = i
+			}
+			for _, f := range model.files {
+				if msg.file != "" && f.Path == msg.file {
+					b.cursor = i
+				}
+			}
+		}
+		m.hub = &b
+		if msg.file != "" && b.cursor < len(b.models) {
+			// A pasted file link starts right away
+			return m.startHubDownload(b.repo, b.models[b.cursor])
+		}
+		return m, nil
+
+	case hubDownloadDoneMsg:
+		m.downloadCancel = nil
+		m.download = nil
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Download of %s failed: %v", path.Base(msg.name), msg.err)
+			m.eventError("download", "", m.statusLineText)
+			return m, nil
+		}
+		m.statusLineText = fmt.Sprintf("Downloaded %s into %s", path.Base(msg.name), msg.dir)
+		if msg.scan != nil {
+			m.statusLineText += " - " + msg.scan.describe()
+		}
+		m.logEvent("[download] " + m.statusLineText)
+		return m, m.scanModelsCmd()
+	}
+	return m, nil
+}
+
+// renderHubView draws the download screen, with the list scrolled to keep
+// the cursor in view.
+func (m appModel) renderHubView(width int) string {
+	b := m.hub
+	head := []string{b.input.View(), ""}
+	if m.download != nil {
+		head = append(head, m.styles.accent.Render(ellipsize("Downloading "+m.download.name+": "+m.download.bar(20)+" "+m.download.describe(), width)), "")
+	}
+	var rows []string
+	footer := "[enter] search  [esc] close"
+	switch {
+	case b.loading:
+		head = append(head, m.styles.help.Render("Loading..."))
+	case b.err != nil:
+		head = append(head, m.styles.logError.Render(ellipsize(b.err.Error(), width)))
+	case b.repo != "":
+		head = append(head, m.styles.help.Render(ellipsize(b.repo+" → "+hubModelDir(m.barnDir, b.repo), width)))
+		for i, model := range b.models {
+			label := model.name
+			if len(model.files) > 1 {
+				label += fmt.Sprintf(" (%d parts)", len(model.files))
+			}
+			rows = append(rows, m.hubRow(i, label, formatBytes(uint64(model.size)), width))
+		}
+		footer = "[enter] download  [↑/↓] select  [esc] back"
+	case b.searched != "":
+		if len(b.repos) == 0 {
+			head = append(head, m.styles.disabled.Render("No GGUF repos found"))
+		}
+		for i, r := range b.repos {
+			rows = append(rows, m.hubRow(i, r.ID, fmt.Sprintf("%d ↓", r.Downloads), width))
+		}
+		footer = "[enter] list files  [↑/↓] select  [esc] close"
+	default:
+		head = append(head, m.styles.help.Render("Models are saved under "+m.barnDir+"; partial downloads resume. Set HF_TOKEN for gated repos."))
+	}
+	if visible := max(m.height-16-len(head), 5); len(rows) > visible {
+		start := min(max(b.cursor-visible+1, 0), len(rows)-visible)
+		rows = rows[start : start+visible]
+	}
+	lines := append(head, rows...)
+	if m.confirmAction == confirmStopAll {
+		return strings.Join(lines, "\n") + "\n\n" + m.styles.confirmWarning.Render("Stop everything, including this download? Press ctrl+k again to confirm"+m.confirmCountdown())
+	}
+	return strings.Join(lines, "\n") + "\n\n" + m.styles.help.Render(footer)
+}
+
+// hubRow is one list entry: the label on the left, note right-aligned.
+func (m appModel) hubRow(i int, label, note string, width int) string {
+	gutter, style := "  ", lipgloss.NewStyle()
+	if i == m.hub.cursor {
+		gutter, style = m.styles.accent.Render("│ "), m.styles.accent.Bold(true)
+	}
+	label = ellipsize(label, max(width-4-lipgloss.Width(note), 12))
+	pad := strings.Repeat(" ", max(1, width-2-lipgloss.Width(label)-lipgloss.Width(note)))
+	return gutter + style.Render(label) + pad + m.styles.status.Render(note)
+}
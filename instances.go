@@ -0,0 +1,67 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Each server instance has a color index into the theme's instance colors.
+// While more than one runs (the managed server and a standby), the color
+// tags its log lines, its status segment, and its badge in the list, so
+// interleaved output stays attributable.
+
+// multiInstance reports whether log lines need instance tags.
+func (m appModel) multiInstance() bool {
+	return m.standby != nil
+}
+
+func (m appModel) instanceStyle(color int) lipgloss.Style {
+	return m.styles.instances[color%len(m.styles.instances)]
+}
+
+// instanceTag prefixes an instance's log lines, e.g. "[8081] ".
+func (m appModel) instanceTag(color int, port string) string {
+	return m.instanceStyle(color).Render("["+port+"]") + " "
+}
+
+// nextInstanceColor picks a color for a new standby that differs from the
+// managed server's.
+func (m appModel) nextInstanceColor() int {
+	return (m.serverColor + 1) % len(m.styles.instances)
+}
+
+// listDelegate renders the list with badges for the served model and the
+// standby, colored per instance while both exist.
+func (m appModel) listDelegate() modelDelegate {
+	d := newModelDelegate(m.styles, m.servingPath)
+	d.smoke = m.smokeResults
+	d.unavailable = m.unavailable
+	d.flaky = flakyModels(m.reliability, m.config.Flaky)
+	if m.standby != nil {
+		d.standbyPath = m.standby.item.path
+		d.standbyStyle = m.instanceStyle(m.standby.color)
+		d.servingStyle = m.instanceStyle(m.serverColor)
+	}
+	return d
+}
+
+// appendServerLogLine adds a line from the managed server, tagged while a
+// standby's lines are interleaved with it.
+func (m *appModel) appendServerLogLine(text string) {
+	tag := ""
+	if m.multiInstance() && m.currentPort != "" {
+		tag = m.instanceTag(m.serverColor, m.currentPort)
+	}
+	m.appendTaggedLogLine(tag, text)
+}
+
+// waitForStandbyLog reads the standby's output into the logs panel.
+func waitForStandbyLog(ch chan string) tea.Cmd {
+	return func() tea.Msg {
+		line, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return standbyLogMsg{ch: ch, text: line}
+	}
+}
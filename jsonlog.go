@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// jsonLogView shows one JSON log line pretty-printed. line counts the
+// logs panel's lines from the start of the session, like bookmarks, so it
+// stays on its line when the buffer is trimmed; offset scrolls the
+// expansion.
+type jsonLogView struct {
+	line   int
+	offset int
+	// session is the logSession the line is in
+	session int
+	cache   *jsonLineCache
+}
+
+// jsonLineCache holds the panel's plain lines and the expanded line's rows
+// between frames, redone only when the logs change. Copies of the view
+// share it.
+type jsonLineCache struct {
+	// size is the length of the buffer lines were split from
+	size int
+	// base is the number of lines trimmed before lines[0]
+	base  int
+	lines []string
+	// gone is set once the buffer was cut without the cut being counted,
+	// which loses track of the line
+	gone bool
+	// rows and prefix are line rowsFor[0] at width rowsFor[1]
+	rowsFor [2]int
+	rows    []string
+	prefix  string
+}
+
+// logBase counts the lines trimmed before the first one in the panel,
+// which is only tracked for the main server's unfiltered logs.
+func (m appModel) logBase() int {
+	if m.logView != "" || len(m.hiddenComponents) > 0 {
+		return 0
+	}
+	return m.logLinesDropped
+}
+
+// jsonLines are the panel's plain lines for v and the number of lines
+// trimmed before them, or false once v's line can no longer be found.
+func (m appModel) jsonLines(v *jsonLogView) ([]string, int, bool) {
+	c := v.cache
+	if c.gone || v.session != m.logSession {
+		return nil, 0, false
+	}
+	size, base := len(m.rawLogsContent()), m.logBase()
+	if c.lines != nil && size == c.size && base == c.base {
+		return c.lines, c.base, true
+	}
+	if size < c.size && base == c.base {
+		// Trimmed without a count, as side servers' buffers are
+		c.gone = true
+		return nil, 0, false
+	}
+	c.size, c.base, c.lines, c.rows = size, base, m.plainLogLines(), nil
+	return c.lines, c.base, true
+}
+
+// jsonPayload returns the JSON object ending a log line, after any prefix
+// such as a timestamp or instance tag. Arrays are left alone: too many
+// plain lines end in something like "[1]".
+func jsonPayload(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasSuffix(line, "}") {
+		return "", false
+	}
+	for i := 0; i < len(line); i++ {
+		if line[i] != '{' {
+			continue
+		}
+		if payload := line[i:]; len(payload) > 2 && json.Valid([]byte(payload)) {
+			return payload, true
+		}
+	}
+	return "", false
+}
+
+// plainLogLines are the logs panel's lines without colors.
+func (m appModel) plainLogLines() []string {
+	return strings.Split(ansiEscape.ReplaceAllString(m.logsContent(), ""), "\n")
+}
+
+// findJSONLine is the first JSON line from index from, stepping by dir,
+// or -1.
+func findJSONLine(lines []string, from, dir int) int {
+	for i := from; i >= 0 && i < len(lines); i += dir {
+		if _, ok := jsonPayload(lines[i]); ok {
+			return i
+		}
+	}
+	return -1
+}
+
+// openJSONView expands the first JSON line at or below the top of the
+// logs panel, else the last one above it.
+func (m appModel) openJSONView() appModel {
+	v := &jsonLogView{session: m.logSession, cache: &jsonLineCache{}}
+	lines, base, _ := m.jsonLines(v)
+	i := findJSONLine(lines, m.logsViewport.YOffset, 1)
+	if i < 0 {
+		i = findJSONLine(lines, min(m.logsViewport.YOffset, len(lines)-1), -1)
+	}
+	if i < 0 {
+		m.statusLineText = "No JSON lines in the logs"
+		return m
+	}
+	v.line = base + i
+	m.jsonView = v
+	m.logsViewport.SetYOffset(i)
+	return m
+}
+
+func (m appModel) handleJSONViewKey(keyStr string) (appModel, tea.Cmd) {
+	v := *m.jsonView
+	switch keyStr {
+	case "up", "k":
+		v.offset = max(v.offset-1, 0)
+	case "down", "j":
+		v.offset++
+	case "pgup":
+		v.offset = max(v.offset-m.jsonViewHeight(), 0)
+	case "pgdown", " ":
+		v.offset += m.jsonViewHeight()
+	case "n", "p":
+		dir := 1
+		if keyStr == "p" {
+			dir = -1
+		}
+		lines, base, ok := m.jsonLines(&v)
+		if i := findJSONLine(lines, v.line-base+dir, dir); ok && i >= 0 {
+			v.line, v.offset = base+i, 0
+			m.logsViewport.SetYOffset(i)
+		} else {
+			m.statusLineText = "No more JSON lines"
+		}
+	case "esc", "J", "q":
+		m.jsonView = nil
+		return m, nil
+	}
+	rows, _ := m.jsonRows(&v, m.jsonViewWidth())
+	v.offset = min(v.offset, max(len(rows)-m.jsonViewHeight(), 0))
+	m.jsonView = &v
+	return m, nil
+}
+
+// jsonViewWidth is the overlay's content width.
+func (m appModel) jsonViewWidth() int {
+	return max(m.width-8, 50) - 4
+}
+
+// jsonViewHeight is how many lines of the expansion fit the overlay.
+func (m appModel) jsonViewHeight() int {
+	return max(m.height-10, 5)
+}
+
+// highlightJSON colors keys, strings, numbers, and literals in indented
+// JSON. Strings hold no raw newlines, so each token stays on its line.
+func (m appModel) highlightJSON(text string) string {
+	if m.lowMemory || m.styles.monochrome {
+		return text
+	}
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '"':
+			j := i + 1
+			for j < len(text) && text[j] != '"' {
+				if text[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			j = min(j+1, len(text))
+			style := m.styles.logInfo
+			if strings.HasPrefix(text[j:], ":") {
+				style = m.styles.accent
+			}
+			b.WriteString(style.Render(text[i:j]))
+			i = j
+		case c == '-' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(text) && strings.IndexByte("0123456789.eE+-", text[j]) >= 0 {
+				j++
+			}
+			b.WriteString(m.styles.logWarn.Render(text[i:j]))
+			i = j
+		case c >= 'a' && c <= 'z':
+			j := i + 1
+			for j < len(text) && text[j] >= 'a' && text[j] <= 'z' {
+				j++
+			}
+			b.WriteString(m.styles.help.Render(text[i:j]))
+			i = j
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// jsonRows is v's line pretty-printed and wrapped to width, with the
+// line's prefix before the JSON; none once the line has left the logs.
+func (m appModel) jsonRows(v *jsonLogView, width int) (rows []string, prefix string) {
+	lines, base, ok := m.jsonLines(v)
+	i := v.line - base
+	if !ok || i < 0 || i >= len(lines) {
+		return nil, ""
+	}
+	c := v.cache
+	if c.rows != nil && c.rowsFor == [2]int{v.line, width} {
+		return c.rows, c.prefix
+	}
+	payload, ok := jsonPayload(lines[i])
+	if !ok {
+		return nil, ""
+	}
+	var pretty bytes.Buffer
+	_ = json.Indent(&pretty, []byte(payload), "", "  ")
+	wrap := lipgloss.NewStyle().Width(width)
+	for _, l := range strings.Split(pretty.String(), "\n") {
+		rows = append(rows, strings.Split(wrap.Render(m.highlightJSON(l)), "\n")...)
+	}
+	c.rowsFor, c.rows = [2]int{v.line, width}, rows
+	c.prefix = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(lines[i]), payload))
+	return c.rows, c.prefix
+}
+
+func (m appModel) renderJSONView(width int) string {
+	v := m.jsonView
+	footer := m.styles.help.Render("[j/k] scroll  [n/p] next/previous JSON line  [J] or [esc] close")
+	rows, prefix := m.jsonRows(v, width)
+	if len(rows) == 0 {
+		return m.styles.disabled.Render("The line is no longer in the logs") + "\n\n" + footer
+	}
+	height := m.jsonViewHeight()
+	offset := min(v.offset, max(len(rows)-height, 0))
+	shown := rows[offset:min(offset+height, len(rows))]
+	header := m.styles.help.Render(fmt.Sprintf("Line %d", v.line+1))
+	if prefix != "" {
+		header += "  " + ellipsize(prefix, width-12)
+	}
+	if len(rows) > height {
+		header += m.styles.help.Render(fmt.Sprintf("  (%d-%d of %d)", offset+1, offset+len(shown), len(rows)))
+	}
+	return header + "\n\n" + strings.Join(shown, "\n") + "\n\n" + footer
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// keyBinding documents a shortcut for the help overlay.
+type keyBinding struct {
+	keys     string
+	category string
+	desc     string
+}
+
+// keyCategories orders the help overlay's sections.
+var keyCategories = []string{"Server", "Models", "Logs", "Views", "General"}
+
+// keyBindings is the registry the help overlay is generated from; add new
+// shortcuts here.
+var keyBindings = []keyBinding{
+	{"enter", "Server", "Start server with selected model (beside the running one, if any)"},
+	{"s", "Server", "Stop the running server (press twice to confirm)"},
+	{"p", "Server", "Focus/unfocus port input (↑/↓ or +/- step it, tab: next free port)"},
+	{"f", "Server", "Edit launch options (port, context, GPU layers, ...)"},
+	{"R", "Server", "Retry a launch without a flag llama-server rejected, or restart a hung server"},
+	{"W", "Server", "Preload the selected model as a paused standby (again to stop it)"},
+	{"P", "Server", "Promote the standby to the served model"},
+	{"V", "Server", "Vision test: send an image to the running multimodal model"},
+	{"C", "Server", "Export the launch as a docker-compose.yml (and docker run command)"},
+	{"S", "Server", "Save and restore slot prompt caches (needs --slot-save-path)"},
+	{"I", "Server", "Running servers: show each one's logs, stop it, or A/B chat two of them"},
+	{"F", "Server", "Dev mode: restart the server when its model or LoRA files change"},
+	{"T", "Server", "Take over server management from another instance"},
+	{"Q", "Server", "Quick share: open a temporary public HTTPS tunnel to the server (again to close it)"},
+	{"ctrl+u", "Server", "Copy the quick share URL to the clipboard"},
+	{"ctrl+p", "Server", "Prompt library: pick a warm-up or system prompt for launches, add, edit, delete"},
+	{"ctrl+k", "Server", "Stop everything: server, benchmarks, downloads (press twice)"},
+	{"r", "Models", "Refresh/rescan models list"},
+	{"b", "Models", "Change the models directory"},
+	{"c", "Models", "Create the models directory when it is missing"},
+	{"/", "Models", "Filter models by name, architecture, quant, size, or context (e.g. qwen q4 32k)"},
+	{"*", "Models", "Pin/unpin the selected model to the top of the list"},
+	{"[ / ]", "Models", "Move a pinned model up/down"},
+	{"O", "Models", "Cycle the sort order: scan order, size, then metadata_command fields"},
+	{"G", "Models", "Group the list by model family ([enter] on a family expands it)"},
+	{"K", "Models", "Edit GGUF metadata overrides for the selected model"},
+	{"e", "Models", "Edit launch options saved for the selected model"},
+	{"d", "Models", "Download GGUF models from Hugging Face into the models directory"},
+	{"a", "Models", "Add a Hugging Face repo served with -hf (edits the selected one)"},
+	{"A", "Models", "Models downloaded with -hf, with sizes and deletion"},
+	{"m", "Models", "Move GGUF files from the downloads folder into the models directory"},
+	{"B", "Models", "Benchmark the selected model with llama-bench"},
+	{"Z", "Models", "Sweep the selected model across batch sizes and context depths"},
+	{"i", "Models", "Chat with the selected model in llama-cli, without a server"},
+	{"ctrl+t", "Models", "List the selected model's tensors (shapes, types, sizes), summed by kind and by layer"},
+	{"Y", "Models", "Template sandbox: render a sample chat through the model's template"},
+	{"w", "Models", "Switch workspace (models directory, presets, and history)"},
+	{"l", "Logs", "Toggle file logging (applies on next start)"},
+	{"o", "Logs", "Open the current log file in $PAGER (default: less)"},
+	{"t", "Logs", "Tail any file into the logs panel (press again to stop)"},
+	{"E", "Logs", "Jump to the next error in the logs"},
+	{"z", "Logs", "Bookmark the current log position, with an optional label"},
+	{"'", "Logs", "List the log bookmarks and jump to one"},
+	{"{ / }", "Logs", "Jump to the previous/next log bookmark"},
+	{"x", "Logs", "Show or hide log lines by llama.cpp component (srv, slot, loader, ...)"},
+	{"J", "Logs", "Expand the next JSON log line, pretty-printed and highlighted"},
+	{"v", "Logs", "Switch the logs between running servers, then all of them interleaved"},
+	{"y", "Logs", "Copy the current log file path to the clipboard"},
+	{"ctrl+y", "Logs", "Copy the logs panel to the clipboard (asks first when it is large)"},
+	{"n", "Views", "Events: scans, starts, stops, errors, and setting changes, apart from the server logs"},
+	{"D", "Views", "Run diagnostics (server binary, directories, port, GPU)"},
+	{"X", "Views", "Show the benchmark matrix ([e] exports CSV)"},
+	{"H", "Views", "Show request latencies through the proxy"},
+	{"g", "Views", "Generation defaults the server reports, with overrides by proxy or flags"},
+	{"L", "Views", "Timeline of server sessions over the past day or week"},
+	{"N", "Views", "Usage stats: hours served, sessions, and crash rate per model and preset"},
+	{"< / >", "Views", "Narrow/widen the models panel (saved per terminal size)"},
+	{"|", "Views", "Stack the models panel above the logs, or put it beside them again"},
+	{"\\", "Views", "Cycle the panels: all, without the details pane, logs only"},
+	{"h", "Views", "Toggle this help overlay"},
+	{"u", "General", "Switch between basic mode (start, stop, port) and advanced mode"},
+	{"M", "General", "Toggle mouse capture (off allows native text selection)"},
+	{"U", "General", "Self-update when a newer release is available"},
+	{"esc", "General", "Cancel confirmation, close an overlay, or unfocus port"},
+	{"q", "General", "Quit (press twice to confirm; stops server if running)"},
+	{"ctrl+c", "General", "Quit immediately (bypasses confirmation)"},
+}
+
+// matchesHelpQuery reports whether every word of query appears in the
+// binding's keys, category, or description. A single character looks up
+// that key, case-sensitively, since it would otherwise match nearly
+// everything.
+func (b keyBinding) matchesHelpQuery(query string) bool {
+	if q := strings.TrimSpace(query); len([]rune(q)) == 1 {
+		return strings.Contains(" "+b.keys+" ", " "+q+" ")
+	}
+	haystack := strings.ToLower(b.keys + " " + b.category + " " + b.desc)
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if !strings.Contains(haystack, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// handleHelpKey types into the help search while the overlay is open. h
+// still closes it until a search is started.
+func (m appModel) handleHelpKey(msg tea.KeyMsg) (appModel, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		if m.confirmAction != confirmNone {
+			m.confirmAction = confirmNone
+			m.pendingLaunch = nil
+			m.statusLineText = "Action cancelled"
+			return m, nil
+		}
+		if m.helpQuery != "" {
+			m.helpQuery = ""
+			return m, nil
+		}
+		m.showHelp = false
+	case tea.KeyBackspace:
+		if r := []rune(m.helpQuery); len(r) > 0 {
+			m.helpQuery = string(r[:len(r)-1])
+		}
+	case tea.KeyCtrlU:
+		m.helpQuery = ""
+	case tea.KeySpace:
+		if m.helpQuery != "" {
+			m.helpQuery += " "
+		}
+	case tea.KeyRunes:
+		if m.helpQuery == "" && msg.String() == "h" {
+			m.showHelp = false
+			return m, nil
+		}
+		m.helpQuery += string(msg.Runes)
+	}
+	return m, nil
+}
+
+// renderHelpView lists the shortcuts matching the search, by category.
+func (m appModel) renderHelpView() string {
+	const keyWidth = 10
+	var lines []string
+	search := m.styles.help.Render("Search: ") + m.helpQuery + m.styles.accent.Render("█")
+	if m.helpQuery == "" {
+		search += m.styles.disabled.Render(" type to filter, e.g. log or standby")
+	}
+	lines = append(lines, search, "")
+
+	matched := 0
+	for _, category := range keyCategories {
+		var rows []string
+		for _, b := range keyBindings {
+			if b.category != category || !b.matchesHelpQuery(m.helpQuery) || (m.basicMode && !isBasicBinding(b)) {
+				continue
+			}
+			label := b.keys
+			if !strings.HasPrefix(label, "[") {
+				label = "[" + label + "]"
+			}
+			keys := lipgloss.NewStyle().Width(keyWidth).Render(label)
+			rows = append(rows, "  "+m.styles.accent.Render(keys)+" "+b.desc)
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		matched += len(rows)
+		lines = append(lines, m.styles.help.Render(category+":"))
+		lines = append(lines, rows...)
+		lines = append(lines, "")
+	}
+	if matched == 0 {
+		lines = append(lines, fmt.Sprintf("No shortcuts match %q", m.helpQuery), "")
+	}
+
+	if m.basicMode {
+		lines = append(lines, m.styles.help.Render("Basic mode shows the essentials; [u] switches to advanced mode for every shortcut"), "")
+	}
+	if m.helpQuery == "" {
+		lines = append(lines,
+			m.styles.help.Render("Status Indicators:"),
+			"  [STARTING] Launching llama-server",
+			"  [LOADING]  Server is up, model still loading",
+			"  [RUNNING]  Server is ready for requests",
+			"  [STOPPING] Server shutdown in progress",
+			"  [STOPPED]  No server running",
+			"  [CRASHED]  Server exited with an error",
+			"",
+			"Press [h] or [esc] to close this help",
+		)
+	} else {
+		lines = append(lines, "Press [esc] to clear the search")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// kvDims are the model dimensions the KV cache size depends on.
+type kvDims struct {
+	layers    uint64
+	kvHeads   uint64
+	keyLength uint64
+	valLength uint64
+	trained   uint64
+}
+
+// kvDimsFrom reads the attention shape from a GGUF header. Models whose
+// KV head count varies by layer (stored as an array) are not estimated.
+func kvDimsFrom(meta *ggufMetadata) (kvDims, bool) {
+	var d kvDims
+	var ok bool
+	if d.layers, ok = meta.archUint("block_count"); !ok || d.layers == 0 {
+		return d, false
+	}
+	heads, ok := meta.archUint("attention.head_count")
+	if !ok || heads == 0 {
+		return d, false
+	}
+	d.kvHeads = heads
+	if _, isArray := meta.kv[meta.architecture()+".attention.head_count_kv"].(ggufArrayLen); isArray {
+		return d, false
+	}
+	if n, ok := meta.archUint("attention.head_count_kv"); ok && n > 0 {
+		d.kvHeads = n
+	}
+	embd, _ := meta.archUint("embedding_length")
+	d.keyLength, d.valLength = embd/heads, embd/heads
+	if n, ok := meta.archUint("attention.key_length"); ok && n > 0 {
+		d.keyLength = n
+	}
+	if n, ok := meta.archUint("attention.value_length"); ok && n > 0 {
+		d.valLength = n
+	}
+	if d.keyLength == 0 || d.valLength == 0 {
+		return d, false
+	}
+	d.trained, _ = meta.contextLength()
+	return d, true
+}
+
+// cacheTypeBytes is the storage per element of each --cache-type-k/v;
+// quantized types pack 32 elements into a block with a scale.
+var cacheTypeBytes = map[string]float64{
+	"f32": 4, "f16": 2, "bf16": 2,
+	"q8_0": 34.0 / 32, "q5_1": 24.0 / 32, "q5_0": 22.0 / 32,
+	"q4_1": 20.0 / 32, "q4_0": 18.0 / 32, "iq4_nl": 18.0 / 32,
+}
+
+// kvCacheBytes estimates the K and V caches for ctx tokens.
+func (d kvDims) kvCacheBytes(ctx uint64, typeK, typeV string) (k, v uint64, ok bool) {
+	bk, okK := cacheTypeBytes[typeK]
+	bv, okV := cacheTypeBytes[typeV]
+	if !okK || !okV {
+		return 0, 0, false
+	}
+	elems := float64(d.layers * ctx * d.kvHeads)
+	return uint64(elems * float64(d.keyLength) * bk), uint64(elems * float64(d.valLength) * bv), true
+}
+
+// kvCacheEstimate describes the KV cache the form's context size and cache
+// types would allocate, for showing under the launch form.
+func (d kvDims) kvCacheEstimate(values map[string]string) string {
+	typeK, typeV := values["--cache-type-k"], values["--cache-type-v"]
+	if typeK == "" {
+		typeK = "f16"
+	}
+	if typeV == "" {
+		typeV = "f16"
+	}
+	ctxText := values["--ctx-size"]
+	if ctxText == "" {
+		return "KV cache: set --ctx-size to estimate it"
+	}
+	ctx, err := strconv.ParseUint(ctxText, 10, 64)
+	if err != nil {
+		return "KV cache: --ctx-size is not a number"
+	}
+	if ctx == 0 {
+		// 0 loads the trained context
+		ctx = d.trained
+	}
+	k, v, ok := d.kvCacheBytes(ctx, typeK, typeV)
+	if !ok {
+		return fmt.Sprintf("KV cache: no estimate for cache type %s/%s", typeK, typeV)
+	}
+	return fmt.Sprintf("KV cache ≈ %s at %d tokens (K %s %s + V %s %s; %d layers × %d KV heads)",
+		formatBytes(k+v), ctx, typeK, formatBytes(k), typeV, formatBytes(v), d.layers, d.kvHeads)
+}
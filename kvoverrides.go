@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// kvOverride replaces one GGUF metadata value at load time through
+// llama-server's --override-kv KEY=TYPE:VALUE.
+type kvOverride struct {
+	Key   string `json:"key"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// kvOverrideTypes are the types --override-kv accepts.
+var kvOverrideTypes = []string{"str", "int", "float", "bool"}
+
+func (o kvOverride) arg() string {
+	return o.Key + "=" + o.Type + ":" + o.Value
+}
+
+func (o kvOverride) validate() error {
+	if strings.ContainsAny(o.Key, "=, ") {
+		return fmt.Errorf("key %q must not contain '=', ',' or spaces", o.Key)
+	}
+	switch o.Type {
+	case "int":
+		if _, err := strconv.ParseInt(o.Value, 10, 64); err != nil {
+			return fmt.Errorf("%s: %q is not an int", o.Key, o.Value)
+		}
+	case "float":
+		if _, err := strconv.ParseFloat(o.Value, 64); err != nil {
+			return fmt.Errorf("%s: %q is not a float", o.Key, o.Value)
+		}
+	case "bool":
+		if o.Value != "true" && o.Value != "false" {
+			return fmt.Errorf("%s: bool must be true or false", o.Key)
+		}
+	case "str":
+		// llama.cpp keeps string overrides in a fixed 128-byte buffer
+		if len(o.Value) >= 128 {
+			return fmt.Errorf("%s: string overrides are limited to 127 bytes", o.Key)
+		}
+	default:
+		return fmt.Errorf("%s: unknown type %q", o.Key, o.Type)
+	}
+	return nil
+}
+
+// kvOverridesPath is where a model's overrides are kept.
+func kvOverridesPath(modelName string) string {
+	configDir := appConfigDir()
+	if configDir == "" {
+		return ""
+	}
+	return filepath.Join(configDir, "kv-overrides", sanitizeFileComponent(modelName)+".json")
+}
+
+func loadKVOverrides(modelName string) ([]kvOverride, error) {
+	path := kvOverridesPath(modelName)
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var overrides []kvOverride
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return overrides, nil
+}
+
+// saveKVOverrides stores a model's overrides; none removes the file.
+func saveKVOverrides(modelName string, overrides []kvOverride) error {
+	path := kvOverridesPath(modelName)
+	if path == "" {
+		return fmt.Errorf("no config directory available")
+	}
+	if len(overrides) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(overrides, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// withKVOverrides appends a model's saved overrides to the launch arguments.
+// An unreadable overrides file is skipped rather than blocking the launch.
+func withKVOverrides(modelName string, launchArgs []string) []string {
+	overrides, _ := loadKVOverrides(modelName)
+	if len(overrides) == 0 {
+		return launchArgs
+	}
+	args := append([]string(nil), launchArgs...)
+	for _, o := range overrides {
+		args = append(args, "--override-kv", o.arg())
+	}
+	return args
+}
+
+// kvOverrideRowFields builds the key, type, and value fields of one editor row.
+func kvOverrideRowFields(row int, o kvOverride) []formField {
+	suffix := strconv.Itoa(row)
+	if o.Type == "" {
+		o.Type = "str"
+	}
+	return []formField{
+		newTextField("key."+suffix, fmt.Sprintf("Key %d", row+1), "GGUF metadata key, e.g. qwen2.rope.freq_base or tokenizer.chat_template", o.Key, nil),
+		newChoiceField("type."+suffix, "  type", "Value type: str, int, float, or bool", kvOverrideTypes, o.Type),
+		newTextField("value."+suffix, "  value", "Replacement value, applied when the model loads", o.Value, nil),
+	}
+}
+
+// newKVOverridesForm edits a model's overrides as rows, with a blank row for
+// adding one.
+func newKVOverridesForm(modelName string, overrides []kvOverride) formModel {
+	var fields []formField
+	for i, o := range overrides {
+		fields = append(fields, kvOverrideRowFields(i, o)...)
+	}
+	fields = append(fields, kvOverrideRowFields(len(overrides), kvOverride{})...)
+	form := newForm("Metadata Overrides · "+modelName, fields)
+	form.keysHelp = "[ctrl+n] add row  [ctrl+d] delete row"
+	return form
+}
+
+// addKVOverrideRow appends a blank row and focuses its key.
+func (f *formModel) addKVOverrideRow() {
+	row := len(f.fields) / 3
+	f.fields = append(f.fields, kvOverrideRowFields(row, kvOverride{})...)
+	f.setFocus(row * 3)
+}
+
+// deleteKVOverrideRow removes the focused row, leaving at least one.
+func (f *formModel) deleteKVOverrideRow() {
+	overrides := kvOverridesFromForm(f.values(), len(f.fields)/3, true)
+	row := f.focus / 3
+	if row < len(overrides) {
+		overrides = append(overrides[:row], overrides[row+1:]...)
+	}
+	fields := []formField{}
+	for i, o := range overrides {
+		fields = append(fields, kvOverrideRowFields(i, o)...)
+	}
+	if len(fields) == 0 {
+		fields = kvOverrideRowFields(0, kvOverride{})
+	}
+	f.fields = fields
+	f.setFocus(min(row, len(fields)/3-1) * 3)
+}
+
+// kvOverridesFromForm reads the rows back; rows without a key are dropped
+// unless keepBlank is set.
+func kvOverridesFromForm(values map[string]string, rows int, keepBlank bool) []kvOverride {
+	var overrides []kvOverride
+	for i := 0; i < rows; i++ {
+		suffix := strconv.Itoa(i)
+		o := kvOverride{Key: values["key."+suffix], Type: values["type."+suffix], Value: values["value."+suffix]}
+		if o.Key == "" && !keepBlank {
+			continue
+		}
+		overrides = append(overrides, o)
+	}
+	return overrides
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newLaunchOptionsForm builds the launch options form from the port input
+// and the current per-launch arguments.
+func (m appModel) newLaunchOptionsForm() formModel {
+	port := newTextField("port", "Port", "Port llama-server listens on", m.portInput.Value(), func(v string) error {
+		_, err := validatePort(v)
+		return err
+	})
+	return m.launchOptionsForm("Launch Options", port, m.launchArgs)
+}
+
+// openLaunchOptions opens the launch options form, reading the selected
+// model's header for it in the background.
+func (m appModel) openLaunchOptions() (appModel, tea.Cmd) {
+	form := m.newLaunchOptionsForm()
+	m.form, m.formPurpose = &form, formLaunchOptions
+	return m, m.launchFormMetaCmd()
+}
+
+// openModelConfig opens the saved launch options of modelName, reading its
+// header for the form in the background.
+func (m appModel) openModelConfig(modelName string) (appModel, tea.Cmd) {
+	form := m.newModelConfigForm(modelName)
+	m.form, m.formPurpose, m.modelConfigName = &form, formModelConfig, modelName
+	return m, m.launchFormMetaCmd()
+}
+
+// launchFormModel is the model an open launch options form is for.
+func (m appModel) launchFormModel() (modelItem, bool) {
+	switch m.formPurpose {
+	case formLaunchOptions:
+		item, ok := m.modelsList.SelectedItem().(modelItem)
+		return item, ok
+	case formModelConfig:
+		return m.findModelByName(m.modelConfigName)
+	}
+	return modelItem{}, false
+}
+
+// launchFormMetaCmd reads the header of the launch form's model, for
+// withModelMetadata.
+func (m appModel) launchFormMetaCmd() tea.Cmd {
+	item, ok := m.launchFormModel()
+	if !ok || item.remoteURL != "" {
+		return nil
+	}
+	path := item.ggufPath()
+	return func() tea.Msg {
+		meta, err := readGGUFMetadata(path)
+		return launchFormMetaMsg{path: path, meta: meta, err: err}
+	}
+}
+
+// newModelConfigForm edits the launch options saved for one model. Its port
+// is optional: blank follows the port input and automatic ports. A model
+// with projectors in its directory can pick which one it launches with.
+func (m appModel) newModelConfigForm(modelName string) formModel {
+	c := m.modelConfigs[modelName]
+	port := newTextField("port", "Port", "Port this model always uses; blank for the usual port", c.Port, func(v string) error {
+		if strings.TrimSpace(v) == "" {
+			return nil
+		}
+		_, err := validatePort(v)
+		return err
+	})
+	form := m.launchOptionsForm("Saved Launch Options · "+modelName, port, c.Args)
+	if item, ok := m.findModelByName(modelName); ok && item.kind == kindLLM && len(item.projectors) > 0 {
+		form.fields = slices.Insert(form.fields, 1, newProjectorField(item, c.MMProj))
+	}
+	form.keysHelp += "  empty form forgets them"
+	return form
+}
+
+// launchOptionsForm lays out port, the flag registry, and extra arguments
+// for args. Flags the registry does not know are kept verbatim in the
+// extra arguments field.
+func (m appModel) launchOptionsForm(title string, port formField, args []string) formModel {
+	current := make(map[string]string)
+	var extra []string
+	for _, f := range parseFlagArgs(args) {
+		if spec, ok := lookupFlag(f.name); ok {
+			if spec.kind == flagKindBool {
+				current[spec.name] = "true"
+			} else {
+				current[spec.name] = f.value
+			}
+			continue
+		}
+		extra = append(extra, f.name)
+		if f.value != "" {
+			extra = append(extra, f.value)
+		}
+	}
+
+	fields := []formField{port}
+	for _, spec := range llamaFlagRegistry {
+		fields = append(fields, newFlagField(spec, current[spec.name]))
+	}
+	fields = append(fields, newTextField("extra", "Extra args", "Other llama-server arguments, passed through as typed", strings.Join(extra, " "), func(v string) error {
+		_, err := splitCommandLine(v)
+		return err
+	}))
+	form := newForm(title, fields)
+	form.keysHelp = "[ctrl+f] search llama-server flags"
+	return form
+}
+
+// withModelMetadata offers context sizes relative to what the form's model
+// was trained on, and estimates the KV cache they need, once its header is
+// read.
+func (f formModel) withModelMetadata(meta *ggufMetadata) formModel {
+	f.fields = append([]formField(nil), f.fields...)
+	if trained, ok := meta.contextLength(); ok && trained > 0 {
+		spec, _ := lookupFlag("--ctx-size")
+		for i, field := range f.fields {
+			if field.key == spec.name {
+				f.fields[i] = newContextField(spec, trained, field.value())
+			}
+		}
+		f.setFocus(f.focus)
+	}
+	if dims, ok := kvDimsFrom(meta); ok {
+		f.live = dims.kvCacheEstimate
+	}
+	return f
+}
+
+// newContextField offers 25%, 50%, and 100% of the trained context, or a
+// custom size, instead of a free-text number.
+func newContextField(spec flagSpec, trained uint64, value string) formField {
+	choices := []string{""}
+	labels := []string{""}
+	for _, pct := range []uint64{25, 50, 100} {
+		choices = append(choices, strconv.FormatUint(trained*pct/100, 10))
+		labels = append(labels, fmt.Sprintf("%d%% · %d", pct, trained*pct/100))
+	}
+	choices = append(choices, customChoice)
+	labels = append(labels, customChoice)
+
+	doc := fmt.Sprintf("Context size in tokens; this model was trained on %d", trained)
+	field := newChoiceField(spec.name, spec.name, doc, choices, value)
+	field.labels = labels
+	if value != "" && field.choice == 0 {
+		// Not one of the presets
+		field.choice = len(choices) - 1
+		field.input.SetValue(value)
+	}
+	field.validate = validateOptionalInt
+	return field
+}
+
+// launchArgsFromForm turns submitted launch options back into arguments.
+func launchArgsFromForm(values map[string]string) []string {
+	var args []string
+	for _, spec := range llamaFlagRegistry {
+		v := values[spec.name]
+		if v == "" {
+			continue
+		}
+		if spec.kind == flagKindBool {
+			args = append(args, spec.name)
+		} else {
+			args = append(args, spec.name, v)
+		}
+	}
+	// Validated on submit
+	extra, _ := splitCommandLine(values["extra"])
+	return append(args, extra...)
+}
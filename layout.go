@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Limits of the models panel's share of the screen, in percent.
+const (
+	minLayoutSplit  = 15
+	maxLayoutSplit  = 70
+	layoutSplitStep = 5
+)
+
+// layoutPrefs arranges the panels for one terminal size bucket.
+type layoutPrefs struct {
+	// Split is the models panel's share of the width, or of the height
+	// when stacked, in percent; 0 keeps the default.
+	Split int `json:"split,omitempty"`
+	// Stacked puts the models panel above the logs.
+	Stacked bool `json:"stacked,omitempty"`
+	// HideDetails overrides hide_details_pane when set.
+	HideDetails *bool `json:"hide_details,omitempty"`
+	// HideModels gives the logs the models panel's room.
+	HideModels bool `json:"hide_models,omitempty"`
+}
+
+// layoutBucket names the size class of a terminal, so one arrangement
+// serves every window of about that size.
+func layoutBucket(width, height int) string {
+	w := "ultrawide"
+	switch {
+	case width < 100:
+		w = "narrow"
+	case width < detailsPaneMinTerminalWidth:
+		w = "medium"
+	case width < 240:
+		w = "wide"
+	}
+	if height < 35 {
+		return w + "-short"
+	}
+	return w + "-tall"
+}
+
+// Layouts are stored per size bucket, for every workspace.
+func layoutsPath() string {
+	stateDir := appStateDir()
+	if stateDir == "" {
+		return ""
+	}
+	return filepath.Join(stateDir, "layouts.json")
+}
+
+func loadLayouts() (map[string]layoutPrefs, error) {
+	layouts := map[string]layoutPrefs{}
+	path := layoutsPath()
+	if path == "" {
+		return layouts, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return layouts, nil
+	}
+	if err != nil {
+		return layouts, err
+	}
+	if err := json.Unmarshal(data, &layouts); err != nil {
+		return map[string]layoutPrefs{}, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return layouts, nil
+}
+
+func saveLayoutsCmd(layouts map[string]layoutPrefs) tea.Cmd {
+	data, err := json.MarshalIndent(layouts, "", "  ")
+	return func() tea.Msg {
+		path := layoutsPath()
+		if path == "" {
+			return layoutsSavedMsg{err: fmt.Errorf("no state directory available")}
+		}
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(path), 0o755)
+		}
+		if err == nil {
+			err = os.WriteFile(path, data, 0o644)
+		}
+		return layoutsSavedMsg{err: err}
+	}
+}
+
+// layout is the arrangement saved for the terminal's current size.
+func (m appModel) layout() layoutPrefs {
+	return m.layouts[layoutBucket(m.width, m.height)]
+}
+
+// detailsShown reports whether the layout has room for and wants the
+// details pane.
+func (m appModel) detailsShown(prefs layoutPrefs) bool {
+	if m.width < detailsPaneMinTerminalWidth || m.basicMode || prefs.Stacked {
+		return false
+	}
+	if prefs.HideDetails != nil {
+		return !*prefs.HideDetails
+	}
+	return !m.config.HideDetailsPane
+}
+
+// changeLayout applies change to the current size bucket's arrangement,
+// re-lays the panels out, and saves it.
+func (m appModel) changeLayout(change func(*layoutPrefs) string) (appModel, tea.Cmd) {
+	bucket := layoutBucket(m.width, m.height)
+	prefs := m.layouts[bucket]
+	note := change(&prefs)
+	layouts := make(map[string]layoutPrefs, len(m.layouts)+1)
+	for k, v := range m.layouts {
+		layouts[k] = v
+	}
+	layouts[bucket] = prefs
+	m.layouts = layouts
+	next, _ := m.resizeComponents(m.width, m.height)
+	m = next.(appModel)
+	m.statusLineText = note + " (saved for " + bucket + " terminals)"
+	return m, saveLayoutsCmd(m.layouts)
+}
+
+// selectionHidden reports whether keyStr acts on the models list's
+// selection while the panel is hidden. Such keys are ignored, so nothing
+// is started or changed for a model out of sight.
+func (m appModel) selectionHidden(keyStr string) bool {
+	if !m.layout().HideModels {
+		return false
+	}
+	switch keyStr {
+	case "enter":
+		// Except confirming a launch already asked for
+		return m.confirmAction != confirmLaunch || m.pendingLaunch == nil
+	case "K", "*", "[", "]", "i", "B", "W", "e":
+		return true
+	case "a":
+		item, ok := m.modelsList.SelectedItem().(modelItem)
+		return ok && item.hfRepo != ""
+	case "C":
+		return !m.server.running()
+	}
+	return false
+}
+
+// resizeSplit widens (delta > 0) or narrows the models panel.
+func (m appModel) resizeSplit(delta int) (appModel, tea.Cmd) {
+	if m.layout().HideModels {
+		m.statusLineText = "The models panel is hidden - [\\] shows it"
+		return m, nil
+	}
+	// Start from the default arrangement's actual share
+	current := m.layout().Split
+	if current == 0 {
+		if m.layout().Stacked {
+			current = 100 * m.modelsHeight / max(m.contentHeight, 1)
+		} else {
+			current = 100 * m.leftWidth / max(m.width, 1)
+		}
+		current = (current + layoutSplitStep/2) / layoutSplitStep * layoutSplitStep
+	}
+	split := min(max(current+delta*layoutSplitStep, minLayoutSplit), maxLayoutSplit)
+	return m.changeLayout(func(p *layoutPrefs) string {
+		p.Split = split
+		return fmt.Sprintf("Models panel at %d%%", split)
+	})
+}
+
+// toggleStacked switches between side-by-side and stacked panels.
+func (m appModel) toggleStacked() (appModel, tea.Cmd) {
+	return m.changeLayout(func(p *layoutPrefs) string {
+		p.Stacked = !p.Stacked
+		// A split suits one orientation only
+		p.Split = 0
+		if p.Stacked {
+			return "Models panel above the logs"
+		}
+		return "Models panel beside the logs"
+	})
+}
+
+// cyclePanels steps through all panels, without the details pane (where
+// it fits), and the logs alone.
+func (m appModel) cyclePanels() (appModel, tea.Cmd) {
+	prefs := m.layout()
+	withDetails := m.width >= detailsPaneMinTerminalWidth && !m.basicMode && !prefs.Stacked
+	return m.changeLayout(func(p *layoutPrefs) string {
+		hidden, shown := true, false
+		switch {
+		case p.HideModels:
+			p.HideModels = false
+			if withDetails {
+				p.HideDetails = &shown
+			}
+			return "All panels shown"
+		case withDetails && m.detailsShown(*p):
+			p.HideDetails = &hidden
+			return "Details pane hidden"
+		default:
+			p.HideModels = true
+			return "Logs only - [\\] brings the panels back"
+		}
+	})
+}
+
+// noteLayoutBucket tells when a resize moved into a size bucket with a
+// saved arrangement.
+func (m appModel) noteLayoutBucket() appModel {
+	bucket := layoutBucket(m.width, m.height)
+	if bucket == m.layoutBucket {
+		return m
+	}
+	first := m.layoutBucket == ""
+	m.layoutBucket = bucket
+	if _, saved := m.layouts[bucket]; saved && !first {
+		m.statusLineText = "Restored the layout saved for " + bucket + " terminals"
+	}
+	return m
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// modelLicense is what a model says about its license: the GGUF header's
+// general.license fields, else the provenance sidecar recorded when it was
+// downloaded from Hugging Face.
+type modelLicense struct {
+	id   string
+	name string
+	link string
+	// gated repos make you accept terms before downloading
+	gated bool
+}
+
+// restrictedLicenses are license ids, by prefix, whose terms limit use
+// (acceptable use policies, user caps, non-commercial clauses) or that
+// defer to a custom agreement, so launching asks for acknowledgment.
+var restrictedLicenses = []string{
+	"llama", "gemma", "other", "cc-by-nc", "mrl", "qwen", "tongyi-qianwen",
+	"deepseek", "openrail", "bigscience", "creativeml", "health-ai", "nvidia-open-model",
+}
+
+func licenseFromModel(meta *ggufMetadata, prov *modelProvenance) modelLicense {
+	var l modelLicense
+	if meta != nil {
+		l = modelLicense{
+			id:   meta.str("general.license"),
+			name: meta.str("general.license.name"),
+			link: meta.str("general.license.link"),
+		}
+	}
+	if prov != nil {
+		if l.id == "" {
+			l.id = prov.License
+		}
+		l.gated = prov.Gated
+	}
+	return l
+}
+
+func (l modelLicense) known() bool {
+	return l.id != "" || l.name != ""
+}
+
+// label is the license's name, e.g. "llama3.1" or "apache-2.0".
+func (l modelLicense) label() string {
+	if l.name != "" && !strings.EqualFold(l.name, l.id) && l.id != "" && l.id != "other" {
+		return l.id + " (" + l.name + ")"
+	}
+	if l.name != "" {
+		return l.name
+	}
+	return l.id
+}
+
+// restricted reports whether the terms need reading before use.
+func (l modelLicense) restricted() bool {
+	if l.gated {
+		return true
+	}
+	id := strings.ToLower(l.id)
+	for _, prefix := range restrictedLicenses {
+		if strings.HasPrefix(id, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// summary describes the license for the details pane and preview.
+func (l modelLicense) summary() string {
+	s := l.label()
+	if s == "" {
+		s = "unknown"
+	}
+	if l.gated {
+		s += " · gated"
+	}
+	return s
+}
+
+// licenseAck records that the user launched a model knowing its license.
+type licenseAck struct {
+	License string    `json:"license"`
+	At      time.Time `json:"at"`
+}
+
+// Acknowledgments are kept by model name, like saved launch options.
+func licenseAcksPath(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "license-acks.json")
+}
+
+func loadLicenseAcksCmd(dir string) tea.Cmd {
+	return func() tea.Msg {
+		path := licenseAcksPath(dir)
+		if path == "" {
+			return licenseAcksLoadedMsg{}
+		}
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return licenseAcksLoadedMsg{}
+		}
+		if err != nil {
+			return licenseAcksLoadedMsg{err: err}
+		}
+		var acks map[string]licenseAck
+		if err := json.Unmarshal(data, &acks); err != nil {
+			return licenseAcksLoadedMsg{err: fmt.Errorf("invalid %s: %w", path, err)}
+		}
+		return licenseAcksLoadedMsg{acks: acks}
+	}
+}
+
+func saveLicenseAcksCmd(dir string, acks map[string]licenseAck) tea.Cmd {
+	return func() tea.Msg {
+		path := licenseAcksPath(dir)
+		if path == "" {
+			return nil
+		}
+		data, err := json.MarshalIndent(acks, "", "  ")
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(path), 0o755)
+		}
+		if err == nil {
+			err = os.WriteFile(path, append(data, '\n'), 0o644)
+		}
+		return licenseAckSavedMsg{err: err}
+	}
+}
+
+// needsLicenseAck reports whether launching item should first show its
+// license notice: restricted terms not acknowledged yet, or acknowledged
+// under a different license.
+func (m appModel) needsLicenseAck(item modelItem) bool {
+	if !item.license.restricted() {
+		return false
+	}
+	ack, ok := m.licenseAcks[item.name]
+	return !ok || ack.License != item.license.summary()
+}
+
+// licenseWarning is the pre-launch notice for a restricted model.
+func licenseWarning(item modelItem) string {
+	l := item.license
+	terms := "restricted terms"
+	if l.gated {
+		terms = "terms you accepted on Hugging Face to download it"
+	}
+	s := fmt.Sprintf("License: %s is under %s with %s", item.name, l.label(), terms)
+	if l.id == "" && l.name == "" {
+		s = fmt.Sprintf("License: %s comes from a gated repo with %s", item.name, terms)
+	}
+	if l.link != "" {
+		s += " (" + l.link + ")"
+	}
+	return s + "; check they allow your use. Launching records your acknowledgment"
+}
+
+// acknowledgeLicense records item's license as acknowledged, if it needed
+// it. The launch's warnings already carry the notice.
+func (m *appModel) acknowledgeLicense(item modelItem) tea.Cmd {
+	if !m.needsLicenseAck(item) {
+		return nil
+	}
+	acks := make(map[string]licenseAck, len(m.licenseAcks)+1)
+	for k, v := range m.licenseAcks {
+		acks[k] = v
+	}
+	acks[item.name] = licenseAck{License: item.license.summary(), At: time.Now()}
+	m.licenseAcks = acks
+	return saveLicenseAcksCmd(m.historyDir, acks)
+}
+
+// hubRepoLicense looks up a repo's license and whether it is gated, for
+// the provenance of its downloads. Failures leave them unknown.
+func hubRepoLicense(ctx context.Context, repo string) (string, bool) {
+	var info struct {
+		Gated    any `json:"gated"`
+		CardData struct {
+			License any `json:"license"`
+		} `json:"cardData"`
+	}
+	if err := hubGetJSON(ctx, hubEndpoint()+"/api/models/"+repo, &info); err != nil {
+		return "", false
+	}
+	// gated is false, or "auto"/"manual"; license a string or a list
+	gated := false
+	switch g := info.Gated.(type) {
+	case bool:
+		gated = g
+	case string:
+		gated = g != ""
+	}
+	license := ""
+	switch l := info.CardData.License.(type) {
+	case string:
+		license = l
+	case []any:
+		if len(l) > 0 {
+			license, _ = l[0].(string)
+		}
+	}
+	return license, gated
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// barnLock is the content of the advisory lockfile that marks which
+// llama-tui instance manages servers for a barn.
+type barnLock struct {
+	PID       int    `json:"pid"`
+	StartedAt string `json:"started_at"`
+}
+
+// errLockHeld is returned when a live instance already owns the lock.
+type errLockHeld struct {
+	owner barnLock
+}
+
+func (e errLockHeld) Error() string {
+	return fmt.Sprintf("another llama-tui (pid %d) manages this barn", e.owner.PID)
+}
+
+func getLockPath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), lockFileName)
+}
+
+func readLock(path string) (barnLock, error) {
+	var l barnLock
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return l, err
+	}
+	if err := json.Unmarshal(data, &l); err != nil {
+		return l, fmt.Errorf("invalid lockfile %s: %w", path, err)
+	}
+	return l, nil
+}
+
+func writeLock(path string, flags int) error {
+	data, err := json.Marshal(barnLock{PID: os.Getpid(), StartedAt: time.Now().Format(time.RFC3339)})
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// acquireLock claims the lockfile. A lock left behind by a process that no
+// longer exists is considered stale and replaced. When the directory does
+// not exist yet there is nothing to contend over, so no lock is taken.
+func acquireLock(path string) error {
+	err := writeLock(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err == nil || !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	owner, rerr := readLock(path)
+	if rerr == nil && owner.PID != os.Getpid() && pidAlive(owner.PID) {
+		return errLockHeld{owner: owner}
+	}
+	return takeOverLock(path)
+}
+
+// takeOverLock unconditionally claims the lockfile. The previous owner
+// notices on its next check and steps down.
+func takeOverLock(path string) error {
+	return writeLock(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY)
+}
+
+// releaseLock removes the lockfile if this process still owns it.
+func releaseLock(path string) {
+	if owner, err := readLock(path); err == nil && owner.PID == os.Getpid() {
+		_ = os.Remove(path)
+	}
+}
+
+// ownsLock reports whether the lockfile still names this process. A missing
+// lockfile (e.g. the barn was just created) is claimed on the spot.
+func ownsLock(path string) bool {
+	owner, err := readLock(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return acquireLock(path) == nil
+	}
+	return err == nil && owner.PID == os.Getpid()
+}
+
+func pidAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	alive, err := process.PidExists(int32(pid))
+	return err == nil && alive
+}
+
+// lockCheckCmd periodically verifies ownership so a takeover by another
+// instance is noticed.
+func lockCheckCmd(path string) tea.Cmd {
+	return tea.Tick(lockCheckInterval, func(_ time.Time) tea.Msg {
+		return lockCheckMsg{owned: ownsLock(path)}
+	})
+}
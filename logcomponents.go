@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// logComponentPattern finds the component a llama.cpp log line starts
+// with ("srv  update_slots: ...", "slot launch_slot_: ...",
+// "llama_model_loader: ..."), after the timestamp and level that
+// --log-prefix and --log-timestamps add. llama-tui's own notes
+// ("[bench] ...") count as components too. The optional [port] tag is the
+// all-servers view's.
+var logComponentPattern = regexp.MustCompile(`^(?:\[\d+\] )?(?:\d+\.\d+\.\d+\.\d+ [A-Z] )?(?:(\[[a-z][a-z-]*\])|([a-z][a-z0-9_]{1,39})(?::| {2,}\S| [a-z_]+:))`)
+
+// otherLogComponent groups the lines without a component in the filter.
+const otherLogComponent = "(other)"
+
+// logComponent returns the component line starts with and where its name
+// is, or "" for lines without one.
+func logComponent(line string) (string, int, int) {
+	loc := logComponentPattern.FindStringSubmatchIndex(line)
+	if loc == nil {
+		return "", 0, 0
+	}
+	for g := 1; g <= 2; g++ {
+		if start, end := loc[2*g], loc[2*g+1]; start >= 0 {
+			return line[start:end], start, end
+		}
+	}
+	return "", 0, 0
+}
+
+// lineComponent is the filter group of a stored, possibly styled, line.
+func lineComponent(line string) string {
+	if strings.IndexByte(line, '\x1b') >= 0 {
+		line = ansiEscape.ReplaceAllString(line, "")
+	}
+	if name, _, _ := logComponent(line); name != "" {
+		return name
+	}
+	return otherLogComponent
+}
+
+// componentStyle gives each component a color of its own that stays the
+// same across sessions.
+func (s uiStyles) componentStyle(name string) lipgloss.Style {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	return s.logComponents[h.Sum32()%uint32(len(s.logComponents))]
+}
+
+// logsContent is the selected log buffer without the lines of hidden
+// components.
+func (m appModel) logsContent() string {
+	content := m.rawLogsContent()
+	if len(m.hiddenComponents) == 0 {
+		return content
+	}
+	lines := strings.Split(content, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line == "" || !m.hiddenComponents[lineComponent(line)] {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// logFilterTitle notes a component filter in the Logs title.
+func (m appModel) logFilterTitle() string {
+	if len(m.hiddenComponents) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" • %s hidden", pluralize(len(m.hiddenComponents), "component"))
+}
+
+// logComponentMenu is the [x] overlay choosing which components' lines
+// the logs panel shows.
+type logComponentMenu struct {
+	cursor int
+	names  []string
+	counts map[string]int
+}
+
+// openComponentMenu lists the components in the shown logs, most lines
+// first, along with hidden ones that have no lines now.
+func (m appModel) openComponentMenu() appModel {
+	counts := map[string]int{}
+	for _, line := range strings.Split(m.rawLogsContent(), "\n") {
+		if line != "" {
+			counts[lineComponent(line)]++
+		}
+	}
+	for name := range m.hiddenComponents {
+		counts[name] += 0
+	}
+	if len(counts) == 0 {
+		m.statusLineText = "No logs to filter"
+		return m
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	m.componentMenu = &logComponentMenu{names: names, counts: counts}
+	return m
+}
+
+func (m appModel) handleComponentMenuKey(keyStr string) appModel {
+	v := *m.componentMenu
+	name := v.names[v.cursor]
+	hidden := make(map[string]bool, len(m.hiddenComponents)+1)
+	for k := range m.hiddenComponents {
+		hidden[k] = true
+	}
+	switch keyStr {
+	case "up", "k":
+		v.cursor = max(v.cursor-1, 0)
+	case "down", "j":
+		v.cursor = min(v.cursor+1, len(v.names)-1)
+	case " ":
+		if hidden[name] {
+			delete(hidden, name)
+		} else {
+			hidden[name] = true
+		}
+		m = m.setHiddenComponents(hidden)
+	case "o":
+		// Only this one
+		hidden = map[string]bool{}
+		for _, other := range v.names {
+			if other != name {
+				hidden[other] = true
+			}
+		}
+		m = m.setHiddenComponents(hidden)
+	case "a":
+		m = m.setHiddenComponents(nil)
+	case "esc", "enter", "x", "q":
+		m.componentMenu = nil
+		return m
+	}
+	m.componentMenu = &v
+	return m
+}
+
+// setHiddenComponents applies a new filter to the logs panel, keeping it
+// at the bottom if it was following the output.
+func (m appModel) setHiddenComponents(hidden map[string]bool) appModel {
+	if len(hidden) == 0 {
+		hidden = nil
+	}
+	follow := m.logsViewport.AtBottom()
+	m.hiddenComponents = hidden
+	m.logsViewport.SetContent(m.logsContent())
+	if follow {
+		m.logsViewport.GotoBottom()
+	}
+	return m
+}
+
+// renderComponentMenu lists the components with their line counts and
+// colors, checked when shown.
+func (m appModel) renderComponentMenu(width int) string {
+	v := m.componentMenu
+	nameWidth := 0
+	for _, name := range v.names {
+		nameWidth = max(nameWidth, len(name))
+	}
+	nameWidth = min(nameWidth, max(width-20, 10))
+	// Scroll to keep the cursor in view when there are many
+	rows := max(m.height-12, 5)
+	first := min(max(v.cursor-rows/2, 0), max(len(v.names)-rows, 0))
+	var lines []string
+	for i, name := range v.names {
+		if i < first || i >= first+rows {
+			continue
+		}
+		cursor := "  "
+		if i == v.cursor {
+			cursor = m.styles.accent.Render("▶ ")
+		}
+		check := "[✓] "
+		if m.hiddenComponents[name] {
+			check = "[ ] "
+		}
+		label := fmt.Sprintf("%-*s", nameWidth, ellipsize(name, nameWidth))
+		if name != otherLogComponent && !m.hiddenComponents[name] && !m.styles.monochrome {
+			label = m.styles.componentStyle(name).Render(label)
+		} else if m.hiddenComponents[name] {
+			label = m.styles.disabled.Render(label)
+		}
+		lines = append(lines, cursor+check+label+m.styles.help.Render(fmt.Sprintf("  %s", pluralize(v.counts[name], "line"))))
+	}
+	footer := m.styles.help.Render("[space] show/hide  [o] only this  [a] show all  [x] or [esc] close")
+	return strings.Join(lines, "\n") + "\n\n" + footer
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// logRetention bounds the logs directory. Zero values disable that limit.
+type logRetention struct {
+	MaxFiles   int     `json:"max_files"`
+	MaxTotalMB float64 `json:"max_total_mb"`
+}
+
+func (r logRetention) enabled() bool {
+	return r.MaxFiles > 0 || r.MaxTotalMB > 0
+}
+
+// pruneLogsCmd applies the retention policy to logsDir.
+func pruneLogsCmd(logsDir string, policy logRetention) tea.Cmd {
+	if !policy.enabled() {
+		return nil
+	}
+	return func() tea.Msg {
+		removed, freed, err := pruneLogs(logsDir, policy)
+		return logsPrunedMsg{removed: removed, freedBytes: freed, err: err}
+	}
+}
+
+// pruneLogs deletes the oldest .log files until both the file count and the
+// total size are within policy. It returns how many files were removed and
+// how many bytes were freed.
+func pruneLogs(logsDir string, policy logRetention) (int, uint64, error) {
+	entries, err := os.ReadDir(logsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+
+	type logFileInfo struct {
+		path    string
+		size    int64
+		modTime int64
+	}
+	var files []logFileInfo
+	var total int64
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".log") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, logFileInfo{
+			path:    filepath.Join(logsDir, e.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime().UnixNano(),
+		})
+		total += info.Size()
+	}
+
+	// Oldest first
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime < files[j].modTime })
+
+	maxBytes := int64(policy.MaxTotalMB * 1024 * 1024)
+	count := len(files)
+	removed := 0
+	var freed uint64
+	for _, f := range files {
+		overCount := policy.MaxFiles > 0 && count > policy.MaxFiles
+		overSize := maxBytes > 0 && total > maxBytes
+		if !overCount && !overSize {
+			break
+		}
+		if err := os.Remove(f.path); err != nil {
+			return removed, freed, fmt.Errorf("remove %s: %w", filepath.Base(f.path), err)
+		}
+		count--
+		total -= f.size
+		removed++
+		freed += uint64(f.size)
+	}
+	return removed, freed, nil
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// logStreamBacklog is how many recent lines a new viewer receives.
+	logStreamBacklog = 200
+	// logStreamMaxViewers caps the viewers connected at once; more are
+	// turned away.
+	logStreamMaxViewers = 32
+)
+
+// logStreamer publishes the logs panel's server output, read-only, to
+// viewers on a TCP port: plain lines for nc, a text stream for curl, or
+// WebSocket text messages for websocat. It is shared by pointer, so copies
+// of the model publish to the same viewers.
+type logStreamer struct {
+	addr    string
+	viewers atomic.Int32
+
+	mu      sync.Mutex
+	clients map[chan string]struct{}
+	backlog []string
+}
+
+// newLogStreamer resolves log_stream: a bare port listens on localhost
+// only; give a host (e.g. "0.0.0.0:7070") to reach it from other machines.
+func newLogStreamer(listen string) (*logStreamer, error) {
+	addr := strings.TrimSpace(listen)
+	if _, err := validatePort(addr); err == nil {
+		addr = net.JoinHostPort("127.0.0.1", addr)
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := validatePort(port); err != nil {
+		return nil, err
+	}
+	return &logStreamer{addr: addr, clients: map[chan string]struct{}{}}, nil
+}
+
+// publish hands a line to every viewer without waiting; a viewer too slow
+// to keep up misses lines rather than holding up the UI.
+func (s *logStreamer) publish(line string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.backlog = append(s.backlog, line)
+	if len(s.backlog) > logStreamBacklog {
+		s.backlog = s.backlog[len(s.backlog)-logStreamBacklog:]
+	}
+	for ch := range s.clients {
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+// subscribe adds a viewer, or returns nil when there are too many.
+func (s *logStreamer) subscribe() chan string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.clients) >= logStreamMaxViewers {
+		return nil
+	}
+	ch := make(chan string, 1024)
+	for _, line := range s.backlog {
+		ch <- line
+	}
+	s.clients[ch] = struct{}{}
+	s.viewers.Add(1)
+	return ch
+}
+
+func (s *logStreamer) unsubscribe(ch chan string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.clients, ch)
+	s.viewers.Add(-1)
+}
+
+// serveLogStreamCmd listens for the lifetime of the app; it only returns
+// if the listener fails.
+func serveLogStreamCmd(s *logStreamer) tea.Cmd {
+	return func() tea.Msg {
+		ln, err := net.Listen("tcp", s.addr)
+		if err != nil {
+			return logStreamStoppedMsg{err: err}
+		}
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return logStreamStoppedMsg{err: err}
+			}
+			go s.serveViewer(conn)
+		}
+	}
+}
+
+// serveViewer tells the kind of viewer by what it sends first: nc sends
+// nothing, curl and websocat send an HTTP request.
+func (s *logStreamer) serveViewer(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	_ = conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+	first, _ := r.Peek(4)
+	_ = conn.SetReadDeadline(time.Time{})
+	write := func(line string) error {
+		_, err := io.WriteString(conn, line+"\n")
+		return err
+	}
+	var req *http.Request
+	if string(first) == "GET " {
+		var err error
+		if req, err = http.ReadRequest(r); err != nil {
+			return
+		}
+		if !knownHost(req.Host) {
+			_, _ = io.WriteString(conn, "HTTP/1.1 403 Forbidden\r\nConnection: close\r\n\r\n")
+			return
+		}
+	}
+	ch := s.subscribe()
+	if ch == nil {
+		if req != nil {
+			_, _ = io.WriteString(conn, "HTTP/1.1 503 Service Unavailable\r\nConnection: close\r\n\r\n")
+		} else {
+			_ = write(fmt.Sprintf("llama-tui: too many viewers (%d)", logStreamMaxViewers))
+		}
+		return
+	}
+	defer s.unsubscribe(ch)
+	if req != nil {
+		if strings.EqualFold(req.Header.Get("Upgrade"), "websocket") {
+			if err := acceptWebSocket(conn, req); err != nil {
+				return
+			}
+			write = func(line string) error {
+				_, err := conn.Write(webSocketTextFrame(line))
+				return err
+			}
+		} else if _, err := io.WriteString(conn, "HTTP/1.1 200 OK\r\nContent-Type: text/plain; charset=utf-8\r\nCache-Control: no-cache\r\nConnection: close\r\n\r\n"); err != nil {
+			return
+		}
+	}
+	// Read-only: whatever the viewer sends is discarded, and its hanging
+	// up ends the stream
+	closed := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(io.Discard, r)
+		close(closed)
+	}()
+	for {
+		select {
+		case line := <-ch:
+			if write(line) != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// knownHost reports whether an HTTP request's Host names this machine: an
+// IP address, localhost, or the machine's hostname. A web page whose own
+// domain was rebound to this machine's address sends that domain, so it
+// can't read the logs through a viewer's browser.
+func knownHost(hostport string) bool {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	if net.ParseIP(host) != nil || strings.EqualFold(host, "localhost") {
+		return true
+	}
+	name, err := os.Hostname()
+	if err != nil {
+		return false
+	}
+	short, _, _ := strings.Cut(name, ".")
+	return strings.EqualFold(host, name) || strings.EqualFold(host, short) || strings.EqualFold(host, short+".local")
+}
+
+// webSocketGUID is the fixed key suffix from RFC 6455.
+const webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// sameOriginUpgrade reports whether a WebSocket upgrade may go ahead:
+// browsers send the page's Origin and WebSocket has no same-origin policy,
+// so any page open in the user's browser could otherwise read the logs.
+// Tools like websocat send none.
+func sameOriginUpgrade(req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, req.Host)
+}
+
+func acceptWebSocket(conn net.Conn, req *http.Request) error {
+	if !sameOriginUpgrade(req) {
+		_, _ = io.WriteString(conn, "HTTP/1.1 403 Forbidden\r\nConnection: close\r\n\r\n")
+		return fmt.Errorf("cross-origin WebSocket from %s", req.Header.Get("Origin"))
+	}
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		_, _ = io.WriteString(conn, "HTTP/1.1 400 Bad Request\r\nConnection: close\r\n\r\n")
+		return errors.New("missing Sec-WebSocket-Key")
+	}
+	sum := sha1.Sum([]byte(key + webSocketGUID))
+	_, err := fmt.Fprintf(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+		base64.StdEncoding.EncodeToString(sum[:]))
+	return err
+}
+
+// webSocketTextFrame is one unmasked server-to-client text message.
+func webSocketTextFrame(text string) []byte {
+	n := len(text)
+	frame := []byte{0x81}
+	switch {
+	case n < 126:
+		frame = append(frame, byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	return append(frame, text...)
+}
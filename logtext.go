@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// terminalEscape matches escape sequences a process may write to its
+// output: CSI (colors, cursor movement), OSC (titles, hyperlinks), and
+// two-byte escapes.
+var terminalEscape = regexp.MustCompile(`\x1b(?:\[[0-?]*[ -/]*[@-~]|\][^\x07\x1b]*(?:\x07|\x1b\\)?|[@-Z\\-_])`)
+
+// sanitizeLogLine makes an incoming log line safe to show: escape
+// sequences and control characters are removed (the panel adds its own
+// styling afterwards), invalid UTF-8 becomes U+FFFD, tabs become spaces,
+// and of a line redrawn with carriage returns only the last state is kept.
+func sanitizeLogLine(line string) string {
+	if i := strings.LastIndexByte(strings.TrimRight(line, "\r"), '\r'); i >= 0 {
+		line = line[i+1:]
+	}
+	line = terminalEscape.ReplaceAllString(line, "")
+	line = strings.ToValidUTF8(line, "�")
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t':
+			return ' '
+		case r < 0x20, r == 0x7f, r >= 0x80 && r < 0xa0:
+			return -1
+		}
+		return r
+	}, line)
+}
+
+// runeBoundary returns the largest n' <= n that does not split a UTF-8
+// sequence in b.
+func runeBoundary(b []byte, n int) int {
+	if n >= len(b) {
+		return len(b)
+	}
+	for i := n; i > 0 && n-i < utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			return i
+		}
+	}
+	return n
+}
+
+// trimLogBuffer drops roughly the oldest half of the log, cutting after a
+// newline so no line, character, or style sequence is split.
+func trimLogBuffer(data []byte) []byte {
+	start := len(data) / 2
+	if i := bytes.IndexByte(data[start:], '\n'); i >= 0 {
+		return data[start+i+1:]
+	}
+	return nil
+}
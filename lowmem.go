@@ -0,0 +1,32 @@
+package main
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Low-memory mode trims the TUI's own overhead for machines busy running a
+// huge model: log lines stay uncolored, the log buffer is smaller, and the
+// screen and logs panel are redrawn less often.
+const (
+	lowMemoryLogLimit   = 200_000
+	lowMemoryFPS        = 10
+	lowMemoryLogRefresh = time.Second
+)
+
+// logBufferLimit is the size the logs panel is trimmed at.
+func (m appModel) logBufferLimit() int {
+	if m.lowMemory {
+		return lowMemoryLogLimit
+	}
+	return logBufferSoftLimitCharacters
+}
+
+// logRefreshTickCmd redraws the logs panel with lines received since the
+// last tick, in low-memory mode.
+func logRefreshTickCmd() tea.Cmd {
+	return tea.Tick(lowMemoryLogRefresh, func(time.Time) tea.Msg {
+		return logRefreshMsg{}
+	})
+}
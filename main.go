@@ -1,17 +1,207 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+// runOptions are the root command's flags.
+type runOptions struct {
+	noMouse       bool
+	start         string
+	port          string
+	preset        string
+	autostartLast bool
+	lowMemory     bool
+	attach        string
+	attachLog     string
+	workspace     string
+	noColor       bool
+	readOnly      bool
+	fakeServer    string
+	watch         bool
+	socket        string
+	vars          []string
+	// quick runs the quick launcher on quickQuery instead of the dashboard
+	quick      bool
+	quickQuery string
+}
+
+// usageError marks invalid command-line input, which exits with status 2.
+type usageError struct{ error }
+
 func main() {
-	m := initialModel()
-	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion())
-	if _, err := p.Run(); err != nil {
-		fmt.Println("Error:", err)
+	// Launched by a --fake-server session in place of llama-server
+	if spec, ok := os.LookupEnv(fakeServerEnv); ok {
+		os.Exit(runFakeServer(spec, os.Args[1:]))
+	}
+	if err := newRootCmd().Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		var usage usageError
+		if errors.As(err, &usage) {
+			os.Exit(2)
+		}
 		os.Exit(1)
 	}
 }
+
+// promptBarnDir asks for the models directory when there is no home
+// directory to put it in, as in some containers and service accounts.
+// Without a terminal to ask on, cause is the error.
+func promptBarnDir(cause error) (string, error) {
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return "", cause
+	}
+	fmt.Fprintf(os.Stderr, "%s: %v\nModels directory for this session: ", appTitle, cause)
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	dir := strings.TrimSpace(line)
+	if dir == "" {
+		fmt.Fprintln(os.Stderr)
+		return "", cause
+	}
+	return filepath.Abs(dir)
+}
+
+// runTUI starts the interactive interface.
+func runTUI(o runOptions) error {
+	if o.port != "" {
+		if _, err := validatePort(o.port); err != nil {
+			return usageError{fmt.Errorf("--port: %w", err)}
+		}
+	}
+	var target attachTarget
+	if o.attach != "" {
+		if o.start != "" || o.preset != "" || o.autostartLast {
+			return usageError{fmt.Errorf("--attach monitors a running server; it can't be combined with --start, --preset, or --autostart-last")}
+		}
+		var err error
+		if target, err = resolveAttachTarget(o.attach); err != nil {
+			return usageError{fmt.Errorf("--attach: %w", err)}
+		}
+		if o.attachLog != "" {
+			if err := validateTailPath(o.attachLog); err != nil {
+				return usageError{fmt.Errorf("--attach-log: %w", err)}
+			}
+			target.logPath = o.attachLog
+		}
+	} else if o.attachLog != "" {
+		return usageError{fmt.Errorf("--attach-log needs --attach")}
+	}
+	if o.fakeServer != "" {
+		if err := useFakeServer(o.fakeServer); err != nil {
+			return usageError{fmt.Errorf("--fake-server: %w", err)}
+		}
+	}
+	barn, err := getDefaultBarnDir()
+	if err != nil {
+		if barn, err = promptBarnDir(err); err != nil {
+			return err
+		}
+		barnDirOverride = barn
+	}
+	if o.workspace != "" {
+		cfg, err := loadConfig(getConfigPath(barn))
+		if err != nil {
+			return err
+		}
+		if err := cfg.checkWorkspace(o.workspace); err != nil {
+			return usageError{fmt.Errorf("--workspace: %w", err)}
+		}
+	}
+	useMonochrome(o.noColor)
+	m := initialModel(o.workspace)
+	if o.attach != "" {
+		m.attach(target)
+	}
+	if o.noMouse {
+		m.mouseEnabled = false
+	}
+	if o.lowMemory {
+		m.lowMemory = true
+	}
+	m.observer = o.readOnly
+	m.devWatch = o.watch
+	m.socketFlag = o.socket
+	action, err := resolveStartupAction(m.historyDir, m.config, o.start, o.port, o.preset, o.autostartLast)
+	if err != nil {
+		releaseLock(m.lockPath)
+		return usageError{err}
+	}
+	if len(o.vars) > 0 {
+		vars, err := parseVarFlags(o.vars)
+		if err == nil && (action == nil || action.preset == "") {
+			err = fmt.Errorf("--var fills placeholders in a --preset's args")
+		}
+		if err != nil {
+			releaseLock(m.lockPath)
+			return usageError{err}
+		}
+		action.vars = vars
+	}
+	m.startup = action
+	if o.quick {
+		// Inline, so the status line is left behind in the terminal
+		m.quick = newQuickLauncher(m.historyDir, o.quickQuery)
+		m.mouseEnabled = false
+	} else if o.attach == "" && action == nil {
+		m.restoreSession()
+	}
+	opts := []tea.ProgramOption{tea.WithOutput(programOutput)}
+	if m.quick == nil {
+		opts = append(opts, tea.WithAltScreen())
+	}
+	if m.mouseEnabled {
+		opts = append(opts, tea.WithMouseCellMotion())
+	}
+	if m.lowMemory {
+		opts = append(opts, tea.WithFPS(lowMemoryFPS))
+	}
+	lastModel.Store(&m)
+	p := tea.NewProgram(m, opts...)
+	final, err := p.Run()
+	if errors.Is(err, tea.ErrProgramPanic) {
+		// Bubble Tea has restored the terminal; keep the diagnostics and
+		// don't leave the server running without a UI
+		stopServerAfterCrash()
+		if path, reportErr := writeCrashReport(err); reportErr == nil {
+			fmt.Fprintln(os.Stderr, "llama-tui crashed; report written to", path)
+		} else {
+			fmt.Fprintln(os.Stderr, "llama-tui crashed; could not write report:", reportErr)
+		}
+		if lm := lastModel.Load(); lm != nil {
+			final = *lm
+		}
+	}
+	// Leave external widgets with a clean "stopped" state on exit
+	if fm, ok := final.(appModel); ok {
+		// Recorded too late for Update to save
+		_ = appendEvents(fm.unsavedEvents)
+		if !fm.readOnly {
+			// The instance holding the lock owns the status file
+			_ = writeStatusFile(fm.statusFilePath, serverStatus{State: "stopped"})
+		}
+		if fm.clientConfigDir != "" && !fm.readOnly {
+			_ = writeClientConfigs(fm.clientConfigDir, nil)
+		}
+		fm.cancelRoot()
+		clearTmuxStatus(fm.tmuxPane)
+		clearTerminalStatus(fm.terminalStatus())
+		fm.socket.close()
+		fm.share.stopAndWait()
+		fm.sleepWatch.close()
+		fm.waitForSideServers()
+		fm.stopTemplateRender()
+		fm.waitForTemplateServers()
+		releaseLock(fm.lockPath)
+		if !fm.readOnly && !fm.config.DisableSessionRestore && fm.quick == nil {
+			_ = saveSessionSnapshot(sessionSnapshotPath(fm.historyDir), fm.sessionSnapshot())
+		}
+	}
+	return err
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	metadataTimeout     = 10 * time.Second
+	metadataConcurrency = 4
+)
+
+// sortBySize is the built-in sort key besides scan order; the others are
+// metadata fields.
+const sortBySize = "size"
+
+// metadataCommandCmd runs the configured metadata_command for each model
+// path (appended as the last argument) and collects the JSON objects it
+// prints, e.g. {"mmlu": 71.2, "notes": "good at code"}.
+func metadataCommandCmd(command []string, paths []string) tea.Cmd {
+	return func() tea.Msg {
+		msg := modelMetadataMsg{meta: make(map[string]map[string]string, len(paths))}
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, metadataConcurrency)
+		for _, path := range paths {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer func() { <-sem; wg.Done() }()
+				fields, err := runMetadataCommand(command, path)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					msg.failed++
+					if msg.err == nil {
+						msg.err = fmt.Errorf("%s: %w", path, err)
+					}
+					// Remember the failure so rescans don't retry it
+					fields = map[string]string{}
+				}
+				msg.meta[path] = fields
+			}()
+		}
+		wg.Wait()
+		return msg
+	}
+}
+
+func runMetadataCommand(command []string, path string) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), metadataTimeout)
+	defer cancel()
+	args := append(append([]string(nil), command[1:]...), path)
+	cmd := exec.CommandContext(ctx, command[0], args...)
+	cmd.Env = append(os.Environ(), "LLAMA_TUI_MODEL="+path)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return nil, fmt.Errorf("%w: %s", err, ellipsize(detail, 200))
+		}
+		return nil, err
+	}
+	return parseModelMetadata(out)
+}
+
+// parseModelMetadata flattens a JSON object to display strings; nested
+// values are kept as compact JSON.
+func parseModelMetadata(data []byte) (map[string]string, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return map[string]string{}, nil
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("expected a JSON object: %w", err)
+	}
+	fields := make(map[string]string, len(raw))
+	for key, value := range raw {
+		var s string
+		var f float64
+		switch {
+		case string(value) == "null":
+		case json.Unmarshal(value, &s) == nil:
+			fields[key] = s
+		case json.Unmarshal(value, &f) == nil:
+			fields[key] = strconv.FormatFloat(f, 'f', -1, 64)
+		default:
+			var compact bytes.Buffer
+			if json.Compact(&compact, value) == nil {
+				fields[key] = compact.String()
+			}
+		}
+	}
+	return fields, nil
+}
+
+// applyModelMetadata attaches fetched metadata to the items and lists the
+// local models that haven't been run through the command yet.
+func applyModelMetadata(items []list.Item, meta map[string]map[string]string) ([]list.Item, []string) {
+	var missing []string
+	for i, it := range items {
+		mi, ok := it.(modelItem)
+		if !ok || mi.remoteURL != "" || mi.hfRepo != "" {
+			continue
+		}
+		fields, ok := meta[mi.path]
+		if !ok {
+			missing = append(missing, mi.path)
+			continue
+		}
+		mi.meta = fields
+		items[i] = mi
+	}
+	return items, missing
+}
+
+// sortKeys are the orders [O] cycles through: scan order, size, then each
+// metadata field seen.
+func sortKeys(items []list.Item) []string {
+	seen := map[string]bool{}
+	var fields []string
+	for _, it := range items {
+		mi, ok := it.(modelItem)
+		if !ok {
+			continue
+		}
+		for key := range mi.meta {
+			if !seen[key] {
+				seen[key] = true
+				fields = append(fields, key)
+			}
+		}
+	}
+	sort.Strings(fields)
+	return append([]string{"", sortBySize}, fields...)
+}
+
+// sortModels orders items by key: scan order for "", largest first for
+// size, and for metadata fields highest number first (text A-Z), with
+// models lacking the field last.
+func sortModels(items []list.Item, key string) []list.Item {
+	out := append([]list.Item(nil), items...)
+	sort.SliceStable(out, func(i, j int) bool {
+		a, aok := out[i].(modelItem)
+		b, bok := out[j].(modelItem)
+		if !aok || !bok {
+			return aok
+		}
+		switch key {
+		case "":
+			return a.order < b.order
+		case sortBySize:
+			return a.size > b.size
+		}
+		av, aHas := a.meta[key]
+		bv, bHas := b.meta[key]
+		if aHas != bHas {
+			return aHas
+		}
+		af, aErr := strconv.ParseFloat(av, 64)
+		bf, bErr := strconv.ParseFloat(bv, 64)
+		if aErr == nil && bErr == nil {
+			return af > bf
+		}
+		if (aErr == nil) != (bErr == nil) {
+			return aErr == nil
+		}
+		return strings.ToLower(av) < strings.ToLower(bv)
+	})
+	return out
+}
+
+// cycleSort switches the list to the next sort key, keeping the selection.
+func (m appModel) cycleSort() appModel {
+	keys := sortKeys(m.allModelItems())
+	next := keys[0]
+	for i, k := range keys {
+		if k == m.sortKey {
+			next = keys[(i+1)%len(keys)]
+		}
+	}
+	m.sortKey = next
+	selected := ""
+	if mi, ok := m.modelsList.SelectedItem().(modelItem); ok {
+		selected = mi.path
+	}
+	m.reorderModels(selected)
+	m.statusLineText = "Sorted by " + sortLabel(next)
+	if len(m.pins) > 0 {
+		m.statusLineText += " (pinned models stay first)"
+	}
+	return m
+}
+
+func sortLabel(key string) string {
+	if key == "" {
+		return "name (scan order)"
+	}
+	return key
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shirou/gopsutil/v4/cpu"
+)
+
+// Resource sampling follows the server's activity: fast while it
+// generates, slower once it has been quiet for a while.
+const (
+	metricsBusyInterval   = 500 * time.Millisecond
+	metricsActiveInterval = time.Second
+	metricsIdleInterval   = 5 * time.Second
+	// metricsBusyWindow is how long after a generation log line the server
+	// counts as generating
+	metricsBusyWindow = 5 * time.Second
+	// metricsIdleAfter is how long without generation before backing off
+	metricsIdleAfter     = time.Minute
+	metricsHistoryLength = 120
+	// headerSparkWidth is how many recent samples the header graphs show
+	headerSparkWidth = 10
+)
+
+// generationLogMarkers appear in llama-server's log while it processes a
+// request: slot assignment, batch updates, and the timings at the end.
+var generationLogMarkers = []string{"launch_slot_", "update_slots", "print_timing", "processing task", "n_decoded"}
+
+func isGenerationLogLine(line string) bool {
+	for _, marker := range generationLogMarkers {
+		if strings.Contains(line, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// generating reports requests in progress, seen in the log or the proxy.
+func (m appModel) generating() bool {
+	return m.proxyInFlight > 0 || time.Since(m.lastGenerationAt) < metricsBusyWindow
+}
+
+// metricsInterval is the delay before the next resource sample.
+func (m appModel) metricsInterval() time.Duration {
+	interval := metricsIdleInterval
+	switch {
+	case m.server != serverReady:
+		// Loading shows steady progress at the normal rate
+		interval = metricsActiveInterval
+	case m.generating():
+		interval = metricsBusyInterval
+	case time.Since(m.lastGenerationAt) < metricsIdleAfter:
+		interval = metricsActiveInterval
+	}
+	if m.lowMemory {
+		interval = max(interval, metricsActiveInterval)
+	}
+	return interval
+}
+
+// metricsCadence describes the sampling rate for the details pane.
+func (m appModel) metricsCadence() string {
+	interval := m.metricsInterval()
+	state := "idle"
+	switch interval {
+	case metricsBusyInterval:
+		state = "generating"
+	case metricsActiveInterval:
+		state = "active"
+	}
+	return fmt.Sprintf("every %s (%s)", interval, state)
+}
+
+// noteGenerationLine records activity from a server log line. If the poll
+// loop was waiting at a slower rate, a new loop starts now so the graphs
+// pick up the load at once; the old loop ends when its sample arrives.
+func (m *appModel) noteGenerationLine(line string) tea.Cmd {
+	if !isGenerationLogLine(line) {
+		return nil
+	}
+	wasSlower := m.metricsInterval() > metricsBusyInterval
+	m.lastGenerationAt = time.Now()
+	if !wasSlower || !m.server.serving() || m.lowMemory {
+		return nil
+	}
+	return m.pollResourceUsageCmd()
+}
+
+// sampleUsageCmd samples the server after delay, tagged with its poll loop.
+func sampleUsageCmd(server *serverProcess, pid int32, seq int, delay time.Duration) tea.Cmd {
+	sample := func() tea.Msg {
+		msg := sampleProcessUsage(server, pid)
+		if usage, ok := msg.(resourceUsageMsg); ok {
+			usage.seq = seq
+			usage.systemCPU = systemCPUPercent()
+			usage.gpuPercent, usage.gpuOK = gpuUtilization()
+			usage.vramBytes = processVRAM(pid)
+			return usage
+		}
+		return msg
+	}
+	if delay <= 0 {
+		return sample
+	}
+	return tea.Tick(delay, func(time.Time) tea.Msg { return sample() })
+}
+
+// systemCPUPercent is the whole machine's CPU use since the last call, so
+// a server that spills onto the CPU shows up even when other processes
+// share the load.
+func systemCPUPercent() float64 {
+	percents, err := cpu.Percent(0, false)
+	if err != nil || len(percents) == 0 {
+		return 0
+	}
+	return percents[0]
+}
+
+// gpuUtilization averages the NVIDIA GPUs' utilization, when nvidia-smi is
+// there. macOS only reports it to root, so it is not sampled.
+func gpuUtilization() (float64, bool) {
+	if runtime.GOOS == "darwin" {
+		return 0, false
+	}
+	if _, err := exec.LookPath("nvidia-smi"); err != nil {
+		return 0, false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "nvidia-smi", "--query-gpu=utilization.gpu", "--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return 0, false
+	}
+	var total float64
+	fields := strings.Fields(string(out))
+	for _, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return 0, false
+		}
+		total += v
+	}
+	if len(fields) == 0 {
+		return 0, false
+	}
+	return total / float64(len(fields)), true
+}
+
+// processVRAM is the GPU memory pid holds across NVIDIA GPUs, when
+// nvidia-smi is there; 0 when it holds none or it can't be read.
+func processVRAM(pid int32) uint64 {
+	if runtime.GOOS == "darwin" {
+		return 0
+	}
+	if _, err := exec.LookPath("nvidia-smi"); err != nil {
+		return 0
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "nvidia-smi", "--query-compute-apps=pid,used_memory", "--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return 0
+	}
+	var mib uint64
+	for _, line := range strings.Split(string(out), "\n") {
+		p, used, ok := strings.Cut(line, ",")
+		if !ok || strings.TrimSpace(p) != strconv.Itoa(int(pid)) {
+			continue
+		}
+		if v, err := strconv.ParseUint(strings.TrimSpace(used), 10, 64); err == nil {
+			mib += v
+		}
+	}
+	return mib << 20
+}
+
+// recordUsage updates the current CPU and memory figures and their
+// history. CPU is measured between consecutive samples; the first sample
+// of a process only has its lifetime average.
+func (m *appModel) recordUsage(msg resourceUsageMsg) {
+	cpu := msg.cpuPercent
+	if prev := m.lastUsage; prev.pid == msg.pid && !prev.at.IsZero() {
+		if elapsed := msg.at.Sub(prev.at).Seconds(); elapsed > 0 && msg.cpuSeconds >= prev.cpuSeconds {
+			cpu = (msg.cpuSeconds - prev.cpuSeconds) / elapsed * 100
+		}
+	}
+	m.lastUsage = msg
+	m.cpuPercent = cpu
+	m.memRSSBytes = msg.memRSSBytes
+	m.vramBytes = msg.vramBytes
+	if msg.memTotalBytes > 0 {
+		m.memTotalBytes = msg.memTotalBytes
+	}
+	m.cpuHistory = appendHistory(m.cpuHistory, cpu)
+	m.memHistory = appendHistory(m.memHistory, float64(msg.memRSSBytes))
+	m.systemCPUHistory = appendHistory(m.systemCPUHistory, msg.systemCPU)
+	if msg.gpuOK {
+		m.gpuPercent = msg.gpuPercent
+		m.gpuHistory = appendHistory(m.gpuHistory, msg.gpuPercent)
+	}
+}
+
+// resetUsage clears the figures when the server goes away.
+func (m *appModel) resetUsage() {
+	m.cpuPercent, m.memRSSBytes, m.vramBytes = 0, 0, 0
+	m.cpuHistory, m.memHistory = nil, nil
+	m.systemCPUHistory, m.gpuHistory, m.gpuPercent = nil, nil, 0
+	m.lastUsage = resourceUsageMsg{}
+}
+
+func appendHistory(history []float64, v float64) []float64 {
+	history = append(history, v)
+	if len(history) > metricsHistoryLength {
+		history = history[len(history)-metricsHistoryLength:]
+	}
+	return history
+}
+
+// sparkline draws the last width values scaled to at least floor.
+func sparkline(values []float64, width int, floor float64) string {
+	if len(values) > width {
+		values = values[len(values)-width:]
+	}
+	top := floor
+	for _, v := range values {
+		top = max(top, v)
+	}
+	if top <= 0 {
+		return ""
+	}
+	var b strings.Builder
+	for _, v := range values {
+		level := int(v / top * float64(len(heatGlyphs)-1))
+		b.WriteRune(heatGlyphs[min(max(level, 0), len(heatGlyphs)-1)])
+	}
+	return b.String()
+}
+
+// headerSparklines graphs recent system CPU and GPU utilization for the
+// header, so GPU-bound generation and work spilling to the CPU are told
+// apart at a glance.
+func (m appModel) headerSparklines() string {
+	var parts []string
+	if spark := sparkline(m.systemCPUHistory, headerSparkWidth, 100); spark != "" {
+		parts = append(parts, m.styles.help.Render("CPU ")+m.styles.accent.Render(spark))
+	}
+	if spark := sparkline(m.gpuHistory, headerSparkWidth, 100); spark != "" {
+		parts = append(parts, m.styles.help.Render("GPU ")+m.styles.servingBadge.Render(spark))
+	}
+	return strings.Join(parts, " ")
+}
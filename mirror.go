@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// mirrorBodyLimit caps each recorded response body.
+	mirrorBodyLimit = 1 << 20
+	// mirrorRequestLimit caps the body of a mirrored request, which is held
+	// in memory to send twice; larger requests are refused with 413.
+	mirrorRequestLimit = 32 << 20
+	// mirrorLogLimit is the size at which the mirror log is rotated to
+	// mirror.jsonl.1, replacing the previous one.
+	mirrorLogLimit = 64 << 20
+	mirrorTimeout  = 10 * time.Minute
+)
+
+// mirrorLogMu serializes appends to the mirror log, which come from each
+// mirrored request's goroutine, so rotation doesn't interleave with them.
+var mirrorLogMu sync.Mutex
+
+// mirrorSample compares one request as served by the primary and the
+// mirror.
+type mirrorSample struct {
+	at            time.Time
+	primary       time.Duration
+	mirror        time.Duration
+	primaryStatus int
+	mirrorStatus  int
+	err           string
+}
+
+// mirrorResponse is one side of a recorded comparison.
+type mirrorResponse struct {
+	Port      string `json:"port"`
+	Status    int    `json:"status,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+	Body      string `json:"body,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// mirrorRecord is a line of the mirror log: the request and both responses,
+// for comparing a candidate model (say, another quant) offline.
+type mirrorRecord struct {
+	At      time.Time       `json:"at"`
+	Path    string          `json:"path"`
+	Request json.RawMessage `json:"request"`
+	Primary mirrorResponse  `json:"primary"`
+	Mirror  mirrorResponse  `json:"mirror"`
+}
+
+func mirrorLogPath() string {
+	cacheDir := getCacheDir()
+	if cacheDir == "" {
+		return ""
+	}
+	return filepath.Join(cacheDir, "mirror.jsonl")
+}
+
+// cappedBuffer keeps the first mirrorBodyLimit bytes written to it.
+type cappedBuffer struct {
+	bytes.Buffer
+	truncated bool
+}
+
+// Write always reports the whole of p written, so copying continues past
+// the limit.
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if room := mirrorBodyLimit - b.Len(); n > room {
+		p, b.truncated = p[:max(room, 0)], true
+	}
+	b.Buffer.Write(p)
+	return n, nil
+}
+
+// shouldMirror reports whether a request to the primary on port is also
+// sent to the mirror: generation requests only, and never to the server
+// being mirrored.
+func (p *requestProxy) shouldMirror(r *http.Request, port string) bool {
+	return p.mirror != "" && p.mirror != port && r.Method == http.MethodPost
+}
+
+// mirrorRequest copies a request for the mirror port. The copy doesn't
+// share the client's cancellation, so neither the client disconnecting nor
+// the primary finishing first cuts it short.
+func (p *requestProxy) mirrorRequest(r *http.Request, body []byte) (*http.Request, context.CancelFunc, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), mirrorTimeout)
+	target := "http://" + net.JoinHostPort("127.0.0.1", p.mirror) + r.URL.RequestURI()
+	req, err := http.NewRequestWithContext(ctx, r.Method, target, bytes.NewReader(body))
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	req.Header = r.Header.Clone()
+	return req, cancel, nil
+}
+
+// sendMirror sends a request made by mirrorRequest and captures the reply.
+func (p *requestProxy) sendMirror(req *http.Request, cancel context.CancelFunc) mirrorResponse {
+	defer cancel()
+	res := mirrorResponse{Port: p.mirror}
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		res.Error = err.Error()
+		res.LatencyMS = time.Since(start).Milliseconds()
+		return res
+	}
+	defer resp.Body.Close()
+	var buf cappedBuffer
+	_, err = io.Copy(&buf, resp.Body)
+	res.LatencyMS = time.Since(start).Milliseconds()
+	res.Status = resp.StatusCode
+	res.Body, res.Truncated = buf.String(), buf.truncated
+	if err != nil {
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// recordMirror waits for the mirror's response, then remembers the
+// comparison and appends it to the mirror log.
+func (p *requestProxy) recordMirror(at time.Time, path string, body []byte, primary mirrorResponse, mirrored <-chan mirrorResponse) {
+	mirror := <-mirrored
+	sample := mirrorSample{
+		at:            at,
+		primary:       time.Duration(primary.LatencyMS) * time.Millisecond,
+		mirror:        time.Duration(mirror.LatencyMS) * time.Millisecond,
+		primaryStatus: primary.Status,
+		mirrorStatus:  mirror.Status,
+		err:           mirror.Error,
+	}
+	request := json.RawMessage(body)
+	if !json.Valid(body) {
+		request, _ = json.Marshal(string(body))
+	}
+	line, err := json.Marshal(mirrorRecord{At: at, Path: path, Request: request, Primary: primary, Mirror: mirror})
+	if err == nil && p.mirrorLog != "" {
+		err = appendMirrorLine(p.mirrorLog, line)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mirrorSamples = append(p.mirrorSamples, sample)
+	if len(p.mirrorSamples) > latencySampleLimit {
+		p.mirrorSamples = p.mirrorSamples[1:]
+	}
+	p.mirrorErr = err
+}
+
+// appendMirrorLine appends line to the mirror log, first rotating a log
+// that has reached mirrorLogLimit. The log holds full prompts, so it is
+// readable only by the user.
+func appendMirrorLine(path string, line []byte) error {
+	mirrorLogMu.Lock()
+	defer mirrorLogMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if info, err := os.Stat(path); err == nil && info.Size()+int64(len(line)) > mirrorLogLimit {
+		if err := os.Rename(path, path+".1"); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// mirrorSnapshot returns the remembered comparisons, oldest first, and the
+// last error writing the log.
+func (p *requestProxy) mirrorSnapshot() ([]mirrorSample, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]mirrorSample(nil), p.mirrorSamples...), p.mirrorErr
+}
+
+// renderMirrorSummary compares primary and mirror latencies for the
+// latency view.
+func (m appModel) renderMirrorSummary() string {
+	line := fmt.Sprintf("mirror :%s", m.proxy.mirror)
+	var primary, mirror []time.Duration
+	failed := 0
+	for _, s := range m.mirrorSamples {
+		if s.err != "" || s.mirrorStatus >= 500 {
+			failed++
+			continue
+		}
+		primary = append(primary, s.primary)
+		mirror = append(mirror, s.mirror)
+	}
+	if len(m.mirrorSamples) == 0 {
+		line += " · no requests mirrored yet"
+	} else {
+		line += " · " + pluralize(len(primary), "comparison")
+	}
+	if len(primary) > 0 {
+		sort.Slice(primary, func(i, j int) bool { return primary[i] < primary[j] })
+		sort.Slice(mirror, func(i, j int) bool { return mirror[i] < mirror[j] })
+		p50, m50 := percentile(primary, 0.5), percentile(mirror, 0.5)
+		line += fmt.Sprintf(" · p50 %s vs %s", formatLatency(p50), formatLatency(m50))
+		if p50 > 0 {
+			line += fmt.Sprintf(" (%+.0f%%)", (m50.Seconds()/p50.Seconds()-1)*100)
+		}
+	}
+	var parts []string
+	parts = append(parts, m.styles.help.Render(line))
+	if failed > 0 {
+		parts = append(parts, m.styles.logError.Render(pluralize(failed, "mirror failure")))
+	}
+	if m.mirrorErr != nil {
+		parts = append(parts, m.styles.logError.Render("log: "+m.mirrorErr.Error()))
+	} else if path := m.proxy.mirrorLog; path != "" {
+		parts = append(parts, m.styles.help.Render("recorded in "+path))
+	}
+	return strings.Join(parts, m.styles.help.Render(" · "))
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// modelLaunchConfig is a model's saved launch options, applied whenever it
+// starts so each GGUF remembers its own context size, offload, and so on.
+type modelLaunchConfig struct {
+	Model string   `json:"model"`
+	Port  string   `json:"port,omitempty"`
+	Args  []string `json:"args,omitempty"`
+	// MMProj picks the model's projector over the one paired by name: a
+	// projector's path, or noProjector to launch without one
+	MMProj string `json:"mmproj,omitempty"`
+}
+
+// noProjector is the MMProj of a model launched without a projector even
+// though one pairs with it.
+const noProjector = "none"
+
+func (c modelLaunchConfig) empty() bool {
+	return c.Port == "" && len(c.Args) == 0 && c.MMProj == ""
+}
+
+func modelConfigsDir() string {
+	configDir := appConfigDir()
+	if configDir == "" {
+		return ""
+	}
+	return filepath.Join(configDir, "launch-configs")
+}
+
+// modelConfigPath is where a model's launch options are kept.
+func modelConfigPath(modelName string) string {
+	dir := modelConfigsDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, sanitizeFileComponent(modelName)+".json")
+}
+
+func loadModelConfigsCmd() tea.Cmd {
+	return func() tea.Msg {
+		configs, err := loadModelConfigs()
+		return modelConfigsLoadedMsg{configs: configs, err: err}
+	}
+}
+
+// loadModelConfigs reads every saved model's launch options, keyed by model
+// name. Unreadable files are reported but don't hide the others.
+func loadModelConfigs() (map[string]modelLaunchConfig, error) {
+	configs := map[string]modelLaunchConfig{}
+	dir := modelConfigsDir()
+	if dir == "" {
+		return configs, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return configs, nil
+	}
+	if err != nil {
+		return configs, err
+	}
+	var firstErr error
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		data, err := os.ReadFile(path)
+		var c modelLaunchConfig
+		if err == nil {
+			err = json.Unmarshal(data, &c)
+		}
+		if err == nil && c.Model == "" {
+			err = fmt.Errorf("missing model name")
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("invalid %s: %w", path, err)
+			}
+			continue
+		}
+		configs[c.Model] = c
+	}
+	return configs, firstErr
+}
+
+// saveModelConfigCmd stores a model's launch options; empty ones remove the
+// file so the model goes back to the session's options.
+func saveModelConfigCmd(c modelLaunchConfig) tea.Cmd {
+	return func() tea.Msg {
+		return modelConfigSavedMsg{config: c, err: saveModelConfig(c)}
+	}
+}
+
+func saveModelConfig(c modelLaunchConfig) error {
+	path := modelConfigPath(c.Model)
+	if path == "" {
+		return fmt.Errorf("no config directory available")
+	}
+	if c.empty() {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// argsFor is what a launch of modelName passes on top of the configured
+// extra_args: the model's saved options, then the session's [f] options,
+// which win where both set a flag since llama-server takes the last one.
+func (m appModel) argsFor(modelName string) []string {
+	saved := m.modelConfigs[modelName].Args
+	args := m.launchArgs
+	if len(saved) > 0 {
+		args = append(append([]string(nil), saved...), m.launchArgs...)
+	}
+	if r := m.retryArgs; r != nil && r.model == modelName {
+		args = withLaunchFlags(args, r.args)
+	}
+	return args
+}
+
+// modelConfigSummary describes a model's saved options for the details pane.
+func (m appModel) modelConfigSummary(modelName string) string {
+	c, ok := m.modelConfigs[modelName]
+	if !ok {
+		return ""
+	}
+	parts := append([]string(nil), c.Args...)
+	switch c.MMProj {
+	case "":
+	case noProjector:
+		parts = append([]string{"no projector"}, parts...)
+	default:
+		parts = append([]string{"projector " + filepath.Base(c.MMProj)}, parts...)
+	}
+	if c.Port != "" {
+		parts = append([]string{"port " + c.Port}, parts...)
+	}
+	return strings.Join(parts, " ")
+}
+
+// applyProjectors sets the projector each model launches with from its
+// saved options. A chosen projector that is no longer in the model's
+// directory falls back to the paired one.
+func applyProjectors(items []list.Item, configs map[string]modelLaunchConfig) []list.Item {
+	for i, it := range items {
+		mi, ok := it.(modelItem)
+		if !ok || mi.kind != kindLLM {
+			continue
+		}
+		switch choice := configs[mi.name].MMProj; {
+		case choice == noProjector:
+			mi.mmproj = ""
+		case choice != "" && slices.Contains(mi.projectors, choice):
+			mi.mmproj = choice
+		default:
+			mi.mmproj = mi.pairedMMProj
+		}
+		items[i] = mi
+	}
+	return items
+}
+
+// refreshProjectors re-applies the saved projector choices to the models
+// list, after they load or change, keeping its order and filter.
+func (m *appModel) refreshProjectors() tea.Cmd {
+	m.foldedModels = applyProjectors(m.foldedModels, m.modelConfigs)
+	return m.modelsList.SetItems(applyProjectors(m.modelsList.Items(), m.modelConfigs))
+}
+
+// newProjectorField picks the projector for item among those in its
+// directory; the first choice keeps the one paired by name.
+func newProjectorField(item modelItem, value string) formField {
+	paired := noProjector
+	if item.pairedMMProj != "" {
+		paired = filepath.Base(item.pairedMMProj)
+	}
+	choices := []string{"", noProjector}
+	labels := []string{"paired · " + paired, "none"}
+	for _, p := range item.projectors {
+		choices = append(choices, p)
+		labels = append(labels, filepath.Base(p))
+	}
+	field := newChoiceField("mmproj", "Projector", "Multimodal projector passed with --mmproj", choices, value)
+	field.labels = labels
+	return field
+}
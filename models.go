@@ -15,19 +15,305 @@ import (
 
 // list item for models
 type modelItem struct {
-	name string
-	path string
+	name    string
+	path    string
+	relPath string
+	size    int64
+	quant   string
+	params  string
+	// mmproj is the multimodal projector launched with this model, if any:
+	// pairedMMProj, the one its name pairs it with, unless its saved launch
+	// options pick another of projectors, those in its directory
+	mmproj       string
+	pairedMMProj string
+	projectors   []string
+	// remoteURL is set for catalog entries not yet downloaded to path
+	remoteURL string
+	// pinned models are listed first, in the user's order
+	pinned bool
+	// arch and contextLength come from the GGUF header when readable
+	arch          string
+	contextLength uint64
+	// provenance is set for models downloaded through llama-tui
+	provenance *modelProvenance
+	// hfRepo is set for Hugging Face repos llama-server loads with -hf;
+	// hfCache is its downloaded file once known
+	hfRepo  string
+	hfCache string
+	// meta are the fields metadata_command printed for this model
+	meta map[string]string
+	// order is the position in the scan, the default sort
+	order int
+	// inFamily is set while listed under an expanded family
+	inFamily bool
+	// kind picks the server binary; whisper.cpp models use whisper-server
+	kind modelKind
+	// license is from the GGUF header or the provenance sidecar
+	license modelLicense
+	// blob is the checksum of the stored blob its file links to, if any
+	blob string
 }
 
-func (m modelItem) Title() string       { return m.name }
-func (m modelItem) Description() string { return m.path }
-func (m modelItem) FilterValue() string { return m.name }
+func (m modelItem) Title() string { return m.name }
+
+// ggufPath is the file to read header details from.
+func (m modelItem) ggufPath() string {
+	if m.hfRepo != "" {
+		return m.hfCache
+	}
+	return m.path
+}
+func (m modelItem) Description() string {
+	if m.relPath != "" {
+		return m.relPath
+	}
+	return m.path
+}
+
+// FilterValue includes header metadata so searches like "qwen q4 32k" work
+// even for badly named files.
+func (m modelItem) FilterValue() string {
+	parts := []string{m.name, m.arch, m.quant, m.params}
+	if m.contextLength > 0 {
+		parts = append(parts, formatContextLength(m.contextLength))
+	}
+	// Metadata matches by value or as key:value, e.g. "license:apache"
+	for key, value := range m.meta {
+		parts = append(parts, key+":"+value)
+	}
+	return strings.ToLower(strings.Join(parts, " "))
+}
+
+// formatContextLength renders token counts the way people write them: 32k.
+func formatContextLength(n uint64) string {
+	if n >= 1024 && n%1024 == 0 {
+		return fmt.Sprintf("%dk", n/1024)
+	}
+	return strconv.FormatUint(n, 10)
+}
+
+// filterModels matches items containing every whitespace-separated term of
+// the query, in any order.
+func filterModels(term string, targets []string) []list.Rank {
+	terms := strings.Fields(strings.ToLower(term))
+	var ranks []list.Rank
+	for i, target := range targets {
+		ok := true
+		for _, t := range terms {
+			if !strings.Contains(target, t) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			ranks = append(ranks, list.Rank{Index: i})
+		}
+	}
+	return ranks
+}
+
+var (
+	// Quantization tags such as Q4_K_M, IQ3_XXS, Q8_0, F16, BF16, MXFP4
+	quantPattern = regexp.MustCompile(`(?i)(?:^|[-_.])(I?Q\d(?:_[A-Z0-9]+)*|BF16|F16|F32|MXFP4)(?:[-_.]|$)`)
+	// Parameter counts such as 7B, 0.5B, 8x7B, 350M
+	paramsPattern = regexp.MustCompile(`(?i)(?:^|[-_.])((?:\d+x)?\d+(?:\.\d+)?[BM])(?:[-_.]|$)`)
+)
+
+// enrichModelName fills in quantization and parameter count from the file
+// name alone, for models whose header cannot be read.
+func enrichModelName(item modelItem) modelItem {
+	base := filepath.Base(item.name)
+	if match := quantPattern.FindStringSubmatch(base); match != nil {
+		item.quant = strings.ToUpper(match[1])
+	}
+	if match := paramsPattern.FindStringSubmatch(base); match != nil {
+		// Keep the expert multiplier lowercase: 8x7B
+		item.params = strings.Replace(strings.ToUpper(match[1]), "X", "x", 1)
+	}
+	return item
+}
+
+// shortNames gives each model name the fewest trailing path components
+// that no other name ends with, ignoring case: "Qwen3-8B-Q4_K_M.gguf"
+// alone, but "unsloth/Qwen3-8B-Q4_K_M.gguf" and "bartowski/..." when two
+// directories hold the same file.
+func shortNames(names []string) map[string]string {
+	parts := make([][]string, len(names))
+	for i, n := range names {
+		parts[i] = strings.Split(filepath.ToSlash(n), "/")
+	}
+	suffix := func(p []string, k int) string {
+		return strings.Join(p[max(len(p)-k, 0):], "/")
+	}
+	short := make(map[string]string, len(names))
+	for i, p := range parts {
+		k := 1
+		for ; k < len(p); k++ {
+			mine, clash := strings.ToLower(suffix(p, k)), false
+			for j, q := range parts {
+				if j != i && names[j] != names[i] && strings.ToLower(suffix(q, k)) == mine {
+					clash = true
+					break
+				}
+			}
+			if !clash {
+				break
+			}
+		}
+		short[names[i]] = suffix(p, k)
+	}
+	return short
+}
+
+// displayName is how the header, status bar, and history show a model:
+// its short name among the barn's models, or the name as given for models
+// outside the barn.
+func (m appModel) displayName(name string) string {
+	if short, ok := m.shortNames[name]; ok {
+		return short
+	}
+	return name
+}
+
+// enrichModelItem fills in quantization and parameter count, preferring the
+// file name and falling back to the GGUF header (needed for ollama blobs),
+// plus architecture and trained context for searching.
+func enrichModelItem(item modelItem) modelItem {
+	item = enrichModelName(item)
+	item.provenance, _ = loadProvenance(item.path)
+	item.license = licenseFromModel(nil, item.provenance)
+	if item.kind == kindWhisper {
+		return enrichWhisperItem(item)
+	}
+	meta, err := readGGUFMetadata(item.path)
+	if err != nil {
+		return item
+	}
+	item.license = licenseFromModel(meta, item.provenance)
+	item.arch = meta.architecture()
+	item.contextLength, _ = meta.contextLength()
+	if item.params == "" {
+		item.params = strings.ToUpper(meta.str("general.size_label"))
+	}
+	if item.quant == "" {
+		if ft, ok := meta.uint("general.file_type"); ok {
+			item.quant = ggufFileTypeName(ft)
+		}
+	}
+	return item
+}
 
 func (m appModel) scanModelsCmd() tea.Cmd {
 	return func() tea.Msg {
+		// Report a missing barn explicitly so the UI can offer to create it
+		if _, statErr := os.Stat(m.barnDir); os.IsNotExist(statErr) {
+			sourceItems, err := scanModelSources(m.modelSources, nil)
+			return scanDoneMsg{items: append(sourceItems, hfRepoItems(m.hfRepos)...), sourceErr: err, barnMissing: true}
+		}
 		items, err := scanModels(m.barnDir)
-		return scanDoneMsg{items: items, err: err}
+		if err != nil {
+			return scanDoneMsg{items: items, err: err}
+		}
+		// Models from ollama, LM Studio, and GPT4All are served in place,
+		// without copying. One that can't be read (say, ollama's manifests
+		// aren't readable) doesn't hide the rest.
+		sourceItems, sourceErr := scanModelSources(m.modelSources, items)
+		items = append(items, sourceItems...)
+		for i, it := range items {
+			items[i] = enrichModelItem(it.(modelItem))
+		}
+		items = append(items, hfRepoItems(m.hfRepos)...)
+		// Remote entries are listed even if a catalog is unreachable
+		remoteItems, err := scanCatalogs(m.config.Catalogs, m.barnDir)
+		for _, it := range remoteItems {
+			items = append(items, enrichModelName(it.(modelItem)))
+		}
+		if sourceErr == nil {
+			sourceErr = err
+		}
+		return scanDoneMsg{items: items, sourceErr: sourceErr}
+	}
+}
+
+// splitNameSuffix matches shard numbering left on a split file's stem,
+// e.g. "-split-2", ".part3", "_00002"
+var splitNameSuffix = regexp.MustCompile(`(?i)[-_.]?(?:split|part|shard)?[-_.]?\d+(?:-?of-?\d+)?$`)
+
+// isGGUFFileName accepts "*.gguf" plus ".gguf.split*" naming variants.
+func isGGUFFileName(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.HasSuffix(lower, ".gguf") || strings.Contains(lower, ".gguf.split")
+}
+
+// splitBaseName derives the shared model name for a split file that does not
+// follow the -NNNNN-of-NNNNN convention.
+func splitBaseName(fileName string) string {
+	stem := fileName
+	if i := strings.Index(strings.ToLower(stem), ".gguf"); i >= 0 {
+		stem = stem[:i]
+	}
+	if trimmed := splitNameSuffix.ReplaceAllString(stem, ""); trimmed != "" {
+		stem = trimmed
+	}
+	return stem
+}
+
+// ggufSplitIndex reports the shard number for files whose header carries
+// split.* metadata with more than one part.
+func ggufSplitIndex(path string) (int, bool) {
+	meta, err := readGGUFMetadata(path)
+	if err != nil {
+		return 0, false
 	}
+	count, ok := meta.uint("split.count")
+	if !ok || count <= 1 {
+		return 0, false
+	}
+	no, _ := meta.uint("split.no")
+	return int(no), true
+}
+
+// createBarnDirCmd creates the barn directory (and parents).
+func createBarnDirCmd(dir string) tea.Cmd {
+	return func() tea.Msg {
+		return barnDirCreatedMsg{dir: dir, err: os.MkdirAll(dir, 0o755)}
+	}
+}
+
+// projectorPrecision is the weight type a projector's name ends with, e.g.
+// the "-f16" of "mmproj-gemma-3-4b-it-f16.gguf".
+var projectorPrecision = regexp.MustCompile(`(?i)[-_.](f16|bf16|f32|q8_0)$`)
+
+// projectorStem is the model a projector names, lowercased: "gemma-3-4b-it"
+// for "mmproj-gemma-3-4b-it-f16.gguf"; "" for a generic name like
+// "mmproj-f16.gguf" or "mmproj-model-f16.gguf".
+func projectorStem(path string) string {
+	stem := strings.TrimSuffix(strings.ToLower(filepath.Base(path)), ".gguf")
+	stem = strings.TrimLeft(strings.TrimPrefix(stem, "mmproj"), "-_.")
+	stem = projectorPrecision.ReplaceAllString(stem, "")
+	if stem == "model" || projectorPrecision.MatchString("-"+stem) {
+		return ""
+	}
+	return stem
+}
+
+// pairProjector picks the projector for model among those in its
+// directory: the one whose name starts the model's, the longest such, or
+// else the only projector when model is the directory's only model. A
+// projector isn't attached to models it doesn't name, since a text model
+// launched with --mmproj fails to load.
+func pairProjector(model string, projectors []string, onlyModel bool) string {
+	name := strings.ToLower(model)
+	best := ""
+	for _, p := range projectors {
+		if stem := projectorStem(p); stem != "" && strings.HasPrefix(name, stem) && len(stem) > len(projectorStem(best)) {
+			best = p
+		}
+	}
+	if best == "" && onlyModel && len(projectors) == 1 {
+		best = projectors[0]
+	}
+	return best
 }
 
 func scanModels(barnDir string) ([]list.Item, error) {
@@ -48,62 +334,93 @@ func scanModels(barnDir string) ([]list.Item, error) {
 	type groupedModel struct {
 		item       modelItem
 		shardIndex int
+		totalSize  int64
 	}
 	modelMap := make(map[string]groupedModel)
+	// Multimodal projectors are paired with models in the same directory
+	// instead of being listed as models themselves
+	mmprojByDir := make(map[string][]string)
 
-	err = filepath.WalkDir(barnDir, func(path string, d os.DirEntry, walkErr error) error {
-		if walkErr != nil {
-			return walkErr
-		}
-		if d.IsDir() {
-			return nil
-		}
-		if !strings.HasSuffix(strings.ToLower(d.Name()), ".gguf") {
+	err = walkBarnFiles(barnDir, func(path string, d os.DirEntry) error {
+		if !isGGUFFileName(d.Name()) {
+			// whisper.cpp models are listed too, and served by whisper-server
+			if isWhisperModelFile(path) {
+				rel, _ := filepath.Rel(barnDir, path)
+				var fileSize int64
+				if info, err := os.Stat(path); err == nil {
+					fileSize = info.Size()
+				}
+				modelMap[rel] = groupedModel{
+					item:      modelItem{name: rel, path: path, relPath: rel, kind: kindWhisper},
+					totalSize: fileSize,
+				}
+			}
 			return nil
 		}
 
 		rel, _ := filepath.Rel(barnDir, path)
 		fileName := d.Name()
+		if strings.HasPrefix(strings.ToLower(fileName), "mmproj") {
+			mmprojByDir[filepath.Dir(path)] = append(mmprojByDir[filepath.Dir(path)], path)
+			return nil
+		}
+		// Stat through symlinks: names in the blob store are links
+		var fileSize int64
+		if info, err := os.Stat(path); err == nil {
+			fileSize = info.Size()
+		}
 
-		// Check if this is a multipart file
-		matches := multipartPattern.FindStringSubmatch(fileName)
-		if matches != nil {
+		// Check if this is a multipart file, by name or by split.* metadata
+		baseName, shardNum, isSplit := "", 0, false
+		if matches := multipartPattern.FindStringSubmatch(fileName); matches != nil {
+			baseName, isSplit = matches[1], true
+			shardNum, err = strconv.Atoi(matches[2])
+			if err != nil {
+				shardNum = 0
+			}
+		} else if no, ok := ggufSplitIndex(path); ok {
+			baseName, shardNum, isSplit = splitBaseName(fileName), no, true
+		}
+		if isSplit {
 			dir := filepath.Dir(rel)
 			var groupKey string
 			if dir == "." {
-				groupKey = strings.ToLower(matches[1])
+				groupKey = strings.ToLower(baseName)
 			} else {
-				groupKey = strings.ToLower(filepath.Join(dir, matches[1]))
-			}
-
-			shardNum, err := strconv.Atoi(matches[2])
-			if err != nil {
-				shardNum = 0
+				groupKey = strings.ToLower(filepath.Join(dir, baseName))
 			}
 
 			existing, exists := modelMap[groupKey]
+			// Sum all shard sizes so the entry reflects the whole model
+			totalSize := existing.totalSize + fileSize
 			if !exists || shardNum < existing.shardIndex {
 				var displayName string
 				if dir == "." {
-					displayName = matches[1] + ".gguf"
+					displayName = baseName + ".gguf"
 				} else {
-					displayName = filepath.Join(dir, matches[1]+".gguf")
+					displayName = filepath.Join(dir, baseName+".gguf")
 				}
 				modelMap[groupKey] = groupedModel{
 					item: modelItem{
-						name: displayName,
-						path: path,
+						name:    displayName,
+						path:    path,
+						relPath: rel,
 					},
 					shardIndex: shardNum,
 				}
 			}
+			grouped := modelMap[groupKey]
+			grouped.totalSize = totalSize
+			modelMap[groupKey] = grouped
 		} else {
 			modelMap[rel] = groupedModel{
 				item: modelItem{
-					name: rel,
-					path: path,
+					name:    rel,
+					path:    path,
+					relPath: rel,
 				},
 				shardIndex: 0,
+				totalSize:  fileSize,
 			}
 		}
 		return nil
@@ -113,8 +430,23 @@ func scanModels(barnDir string) ([]list.Item, error) {
 	}
 
 	// Convert map values to slice and sort by name
+	store := resolvedBlobStore(barnDir)
+	modelsByDir := make(map[string]int)
+	for _, grouped := range modelMap {
+		if grouped.item.kind == kindLLM {
+			modelsByDir[filepath.Dir(grouped.item.path)]++
+		}
+	}
 	items := make([]list.Item, 0, len(modelMap))
 	for _, grouped := range modelMap {
+		grouped.item.size = grouped.totalSize
+		grouped.item.blob = blobOf(store, grouped.item.path)
+		if grouped.item.kind == kindLLM {
+			dir := filepath.Dir(grouped.item.path)
+			grouped.item.mmproj = pairProjector(filepath.Base(grouped.item.name), mmprojByDir[dir], modelsByDir[dir] == 1)
+			grouped.item.pairedMMProj = grouped.item.mmproj
+			grouped.item.projectors = mmprojByDir[dir]
+		}
 		items = append(items, grouped.item)
 	}
 
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/spf13/cobra"
+)
+
+// modelSource is another local tool's model store, listed beside the barn
+// so its models are served in place instead of downloaded again.
+type modelSource struct {
+	// name prefixes the source's models in the list, e.g. "lmstudio:"
+	name  string
+	label string
+	dir   string
+}
+
+// modelSources are the stores of other tools under home. Without a home
+// directory, only an ollama store set by OLLAMA_MODELS is known.
+func modelSources(home string) []modelSource {
+	if home == "" {
+		if dir := getOllamaModelsDir(""); filepath.IsAbs(dir) {
+			return []modelSource{{name: "ollama", label: "ollama", dir: dir}}
+		}
+		return nil
+	}
+	return []modelSource{
+		{name: "ollama", label: "ollama", dir: getOllamaModelsDir(home)},
+		{name: "lmstudio", label: "LM Studio", dir: getLMStudioModelsDir(home)},
+		{name: "gpt4all", label: "GPT4All", dir: getGPT4AllModelsDir(home)},
+	}
+}
+
+// getLMStudioModelsDir resolves LM Studio's models folder.
+// Priority:
+// 1) downloadsFolder in $HOME/.lmstudio/settings.json, if it was moved
+// 2) $HOME/.lmstudio/models
+// 3) $HOME/.cache/lm-studio/models, used by older versions
+func getLMStudioModelsDir(home string) string {
+	if data, err := os.ReadFile(filepath.Join(home, ".lmstudio", "settings.json")); err == nil {
+		var settings struct {
+			DownloadsFolder string `json:"downloadsFolder"`
+		}
+		if json.Unmarshal(data, &settings) == nil && strings.TrimSpace(settings.DownloadsFolder) != "" {
+			return strings.TrimSpace(settings.DownloadsFolder)
+		}
+	}
+	legacy := filepath.Join(home, ".cache", "lm-studio", "models")
+	if info, err := os.Stat(legacy); err == nil && info.IsDir() {
+		if _, err := os.Stat(filepath.Join(home, ".lmstudio", "models")); os.IsNotExist(err) {
+			return legacy
+		}
+	}
+	return filepath.Join(home, ".lmstudio", "models")
+}
+
+// getGPT4AllModelsDir resolves GPT4All's download path: modelPath in its
+// settings file if set, else its per-platform default.
+func getGPT4AllModelsDir(home string) string {
+	if path := iniValue(filepath.Join(home, ".config", "nomic.ai", "GPT4All.ini"), "modelPath"); path != "" {
+		return path
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		return filepath.Join(home, "Library", "Application Support", "nomic.ai", "GPT4All")
+	case "windows":
+		if dir := os.Getenv("LOCALAPPDATA"); dir != "" {
+			return filepath.Join(dir, "nomic.ai", "GPT4All")
+		}
+	}
+	return filepath.Join(home, ".local", "share", "nomic.ai", "GPT4All")
+}
+
+// iniValue reads key from any section of an INI file, or "".
+func iniValue(path, key string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), "=")
+		if ok && strings.TrimSpace(k) == key {
+			return strings.Trim(strings.TrimSpace(v), `"`)
+		}
+	}
+	return ""
+}
+
+// scan lists the source's models as "<name>:<model>" entries. A missing
+// store yields no items.
+func (s modelSource) scan() ([]list.Item, error) {
+	if s.name == "ollama" {
+		return scanOllamaModels(s.dir)
+	}
+	// LM Studio and GPT4All keep plain GGUF files, laid out much like the
+	// barn, so split models and projectors pair up the same way
+	found, err := scanModels(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	items := []list.Item{}
+	for _, it := range found {
+		item := it.(modelItem)
+		if item.kind != kindLLM {
+			continue
+		}
+		item.name = s.name + ":" + strings.TrimSuffix(filepath.ToSlash(item.name), ".gguf")
+		item.relPath, item.blob = "", ""
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// scanModelSources lists the models of every source, leaving out those
+// already linked into the barn by import-models. A source that can't be
+// read is reported after the others are listed.
+func scanModelSources(sources []modelSource, barnItems []list.Item) ([]list.Item, error) {
+	inBarn := map[string]bool{}
+	for _, it := range barnItems {
+		if resolved, err := filepath.EvalSymlinks(it.(modelItem).path); err == nil {
+			inBarn[resolved] = true
+		}
+	}
+	items := []list.Item{}
+	var firstErr error
+	for _, s := range sources {
+		found, err := s.scan()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s models: %w", s.label, err)
+			}
+			continue
+		}
+		for _, it := range found {
+			if resolved, err := filepath.EvalSymlinks(it.(modelItem).path); err == nil && inBarn[resolved] {
+				continue
+			}
+			items = append(items, it)
+		}
+	}
+	return items, firstErr
+}
+
+// sourceLinks are the symlinks import-models makes for a source: barn
+// path to the file it points at. Ollama blobs are named after their model;
+// other sources keep their folder layout so shards and projectors stay
+// together.
+func sourceLinks(s modelSource, barnDir string) (map[string]string, error) {
+	dest := filepath.Join(barnDir, s.name)
+	links := map[string]string{}
+	if s.name == "ollama" {
+		items, err := scanOllamaModels(s.dir)
+		if err != nil {
+			return nil, err
+		}
+		flat := strings.NewReplacer(":", "-", "/", "-")
+		for _, it := range items {
+			item := it.(modelItem)
+			links[filepath.Join(dest, flat.Replace(strings.TrimPrefix(item.name, "ollama:"))+".gguf")] = item.path
+		}
+		return links, nil
+	}
+	if info, err := os.Stat(s.dir); err != nil || !info.IsDir() {
+		return links, nil
+	}
+	err := filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !isGGUFFileName(d.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return err
+		}
+		links[filepath.Join(dest, rel)] = path
+		return nil
+	})
+	return links, err
+}
+
+// importModelSources links the models of the named sources (all when
+// none are named) into the barn, under a folder per source, so they are
+// listed, pinned, and launched like the barn's own. Files already linked
+// from the barn and names already taken are skipped, and so are files
+// that fail scanCommand, when one is set.
+func importModelSources(barnDir string, sources []modelSource, names []string, scanCommand []string, dryRun bool, out io.Writer) error {
+	inBarn := map[string]bool{}
+	if _, err := os.Stat(barnDir); err == nil {
+		err := walkBarnFiles(barnDir, func(path string, d fs.DirEntry) error {
+			if resolved, err := filepath.EvalSymlinks(path); err == nil {
+				inBarn[resolved] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	linked, skipped, failed := 0, 0, 0
+	for _, s := range sources {
+		if len(names) > 0 && !containsString(names, s.name) {
+			continue
+		}
+		links, err := sourceLinks(s, barnDir)
+		if err != nil {
+			return fmt.Errorf("%s models: %w", s.label, err)
+		}
+		if len(links) == 0 {
+			fmt.Fprintf(out, "%s: no models in %s\n", s.label, s.dir)
+			continue
+		}
+		order := make([]string, 0, len(links))
+		for link := range links {
+			order = append(order, link)
+		}
+		sort.Strings(order)
+		for _, link := range order {
+			target := links[link]
+			rel, _ := filepath.Rel(barnDir, link)
+			if resolved, err := filepath.EvalSymlinks(target); err == nil && inBarn[resolved] {
+				skipped++
+				continue
+			}
+			if _, err := os.Lstat(link); err == nil {
+				fmt.Fprintf(out, "skip %s: exists\n", rel)
+				skipped++
+				continue
+			}
+			if len(scanCommand) > 0 && !dryRun {
+				if result := runScanCommand(context.Background(), scanCommand, target); !result.Passed {
+					fmt.Fprintf(out, "skip %s: failed its scan: %s\n", rel, result.reason())
+					failed++
+					continue
+				}
+			}
+			fmt.Fprintf(out, "link %s -> %s\n", rel, target)
+			linked++
+			if dryRun {
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(link), 0o755); err != nil {
+				return err
+			}
+			if err := os.Symlink(target, link); err != nil {
+				return err
+			}
+		}
+	}
+	verb := "Linked"
+	if dryRun {
+		verb = "Would link"
+	}
+	fmt.Fprintf(out, "%s %s; %d already in %s\n", verb, pluralize(linked, "file"), skipped, barnDir)
+	if failed > 0 {
+		return fmt.Errorf("%s failed scan_command and were not linked", pluralize(failed, "file"))
+	}
+	return nil
+}
+
+// newImportModelsCmd links other tools' models into the barn.
+func newImportModelsCmd() *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "import-models [ollama|lmstudio|gpt4all]...",
+		Short: "Symlink models from ollama, LM Studio, and GPT4All into the models directory",
+		Long: "Models in ollama's, LM Studio's, and GPT4All's stores are listed as ollama:, lmstudio:, and gpt4all: entries " +
+			"and served in place. import-models links them into <models dir>/<source>/ instead, so they sort, group, and " +
+			"take presets like any other model, without copying them. The links break if the other tool deletes the model.",
+		Args:      cobra.OnlyValidArgs,
+		ValidArgs: []string{"ollama", "lmstudio", "gpt4all"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			home, err := userHomeDir()
+			if err != nil {
+				return err
+			}
+			barn, err := getDefaultBarnDir()
+			if err != nil {
+				return err
+			}
+			cfg, err := loadConfig(getConfigPath(barn))
+			if err != nil {
+				return err
+			}
+			return importModelSources(barn, modelSources(home), args, cfg.ScanCommand, dryRun, cmd.OutOrStdout())
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only list what would be linked")
+	return cmd
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+)
+
+// notifyTimeout bounds one notification request.
+const notifyTimeout = 15 * time.Second
+
+// notifyKinds are the events sinks get when they don't list their own.
+var notifyKinds = []string{"ready", "crash", "download", "bench"}
+
+// notificationSink is where lifecycle events are pushed: a generic
+// webhook, a Slack incoming webhook, or an ntfy topic.
+type notificationSink struct {
+	// Type is "webhook", "slack", or "ntfy"
+	Type string `json:"type"`
+	// URL is the webhook URL, or the ntfy topic URL, e.g.
+	// https://ntfy.sh/my-llama-tui
+	URL string `json:"url"`
+	// Events limits the sink to these of "ready", "crash", "download",
+	// "bench", and "error"; all but "error" when empty
+	Events []string `json:"events"`
+	// Headers are added to each request, e.g. an Authorization token for
+	// a private ntfy server
+	Headers map[string]string `json:"headers"`
+}
+
+func (s notificationSink) validate() error {
+	switch s.Type {
+	case "webhook", "slack", "ntfy":
+	default:
+		return fmt.Errorf("type %q: want webhook, slack, or ntfy", s.Type)
+	}
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s url %q is not an http(s) URL", s.Type, s.URL)
+	}
+	for _, kind := range s.Events {
+		if kind != "error" && !containsString(notifyKinds, kind) {
+			return fmt.Errorf("%s event %q: want %s, or error", s.Type, kind, strings.Join(notifyKinds, ", "))
+		}
+	}
+	return nil
+}
+
+// wants reports whether the sink takes notifications of kind.
+func (s notificationSink) wants(kind string) bool {
+	if len(s.Events) == 0 {
+		return kind != "error"
+	}
+	return containsString(s.Events, kind)
+}
+
+// name identifies the sink in errors without its secret path.
+func (s notificationSink) name() string {
+	if u, err := url.Parse(s.URL); err == nil {
+		return s.Type + " (" + u.Host + ")"
+	}
+	return s.Type
+}
+
+// notifyKind is the notification an event makes, or "" for none. Failed
+// launches count as crashes, and a failed sweep as a benchmark. Failed
+// notifications aren't notified, which could go round forever.
+func notifyKind(e appEvent) string {
+	switch {
+	case e.Kind == "notify":
+		return ""
+	case e.Kind == "ready", e.Kind == "crash", e.Kind == "download", e.Kind == "bench":
+		return e.Kind
+	case e.Kind == "start" && e.Error:
+		return "crash"
+	case e.Kind == "sweep" && e.Error:
+		return "bench"
+	case e.Error:
+		return "error"
+	}
+	return ""
+}
+
+// notification is what a sink is sent.
+type notification struct {
+	Kind  string    `json:"event"`
+	Model string    `json:"model,omitempty"`
+	Text  string    `json:"text"`
+	Error bool      `json:"error"`
+	At    time.Time `json:"at"`
+	Host  string    `json:"host"`
+}
+
+// notifyTitles head notifications of each kind, failed or not.
+var notifyTitles = map[string][2]string{
+	"ready":    {"Model ready", "Model ready"},
+	"crash":    {"Server crashed", "Server crashed"},
+	"download": {"Download done", "Download failed"},
+	"bench":    {"Benchmark done", "Benchmark failed"},
+	"error":    {appTitle + " error", appTitle + " error"},
+}
+
+// title is the one-line summary, e.g. "Server crashed: qwen3-8b on myhost".
+func (n notification) title() string {
+	title := notifyTitles[n.Kind][0]
+	if n.Error {
+		title = notifyTitles[n.Kind][1]
+	}
+	if n.Model != "" {
+		title += ": " + n.Model
+	}
+	if n.Host != "" {
+		title += " on " + n.Host
+	}
+	return title
+}
+
+// notificationRequest builds the request for the sink's service.
+func notificationRequest(ctx context.Context, s notificationSink, n notification) (*http.Request, error) {
+	var body []byte
+	contentType := "application/json"
+	switch s.Type {
+	case "slack":
+		icon := ":white_check_mark:"
+		if n.Error {
+			icon = ":warning:"
+		}
+		body, _ = json.Marshal(map[string]string{"text": fmt.Sprintf("%s *%s*\n%s", icon, n.title(), n.Text)})
+	case "ntfy":
+		body, contentType = []byte(n.Text), "text/plain; charset=utf-8"
+	default:
+		body, _ = json.Marshal(n)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if s.Type == "ntfy" {
+		req.Header.Set("Title", n.title())
+		if n.Error {
+			req.Header.Set("Tags", "warning")
+			req.Header.Set("Priority", "high")
+		} else {
+			req.Header.Set("Tags", "llama")
+		}
+	}
+	for k, v := range s.Headers {
+		req.Header.Set(k, v)
+	}
+	return req, nil
+}
+
+// sendNotification delivers n to one sink.
+func sendNotification(s notificationSink, n notification) error {
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	req, err := notificationRequest(ctx, s, n)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
+
+// notifier pushes events to the configured sinks in the background. It is
+// shared by pointer, so copies of the model notify through the same queue.
+type notifier struct {
+	sinks    []notificationSink
+	host     string
+	queue    chan notification
+	failures chan error
+}
+
+func newNotifier(sinks []notificationSink) (*notifier, error) {
+	for _, s := range sinks {
+		if err := s.validate(); err != nil {
+			return nil, err
+		}
+	}
+	host, _ := os.Hostname()
+	return &notifier{sinks: sinks, host: host, queue: make(chan notification, 64), failures: make(chan error, 8)}, nil
+}
+
+// publish queues a notification of e for the sinks that want it, without
+// waiting; a backed-up queue drops it rather than hold up the UI.
+func (n *notifier) publish(e appEvent) {
+	if n == nil {
+		return
+	}
+	kind := notifyKind(e)
+	if kind == "" {
+		return
+	}
+	select {
+	case n.queue <- notification{Kind: kind, Model: e.Model, Text: e.Text, Error: e.Error, At: e.At, Host: n.host}:
+	default:
+	}
+}
+
+// runNotifierCmd delivers queued notifications for the rest of the
+// session; failures come back one at a time through waitForNotifyFailure.
+func runNotifierCmd(n *notifier) tea.Cmd {
+	return func() tea.Msg {
+		for note := range n.queue {
+			for _, s := range n.sinks {
+				if !s.wants(note.Kind) {
+					continue
+				}
+				if err := sendNotification(s, note); err != nil {
+					select {
+					case n.failures <- fmt.Errorf("%s: %w", s.name(), err):
+					default:
+					}
+				}
+			}
+		}
+		return nil
+	}
+}
+
+func waitForNotifyFailure(n *notifier) tea.Cmd {
+	return func() tea.Msg {
+		return notifyFailedMsg{err: <-n.failures}
+	}
+}
+
+// newNotifyTestCmd sends a test notification to every configured sink.
+func newNotifyTestCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "notify-test",
+		Short: "Send a test notification to each sink in the config's notifications",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			barn, err := getDefaultBarnDir()
+			if err != nil {
+				return err
+			}
+			cfg, err := loadConfig(getConfigPath(barn))
+			if err != nil {
+				return err
+			}
+			if len(cfg.Notifications) == 0 {
+				return fmt.Errorf("no notifications in %s", getConfigPath(barn))
+			}
+			n, err := newNotifier(cfg.Notifications)
+			if err != nil {
+				return fmt.Errorf("notifications: %w", err)
+			}
+			note := notification{Kind: "ready", Model: "test-model", Text: "Test notification from llama-tui notify-test", At: time.Now(), Host: n.host}
+			failed := 0
+			for _, s := range n.sinks {
+				if err := sendNotification(s, note); err != nil {
+					failed++
+					fmt.Fprintf(cmd.OutOrStdout(), "FAIL %s: %v\n", s.name(), err)
+					continue
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "ok   %s\n", s.name())
+			}
+			if failed > 0 {
+				return fmt.Errorf("%s failed", pluralize(failed, "sink"))
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+)
+
+// observerBlockedKeys are the shortcuts that start, stop, or delete
+// something, or change what the next launch does. --read-only turns them
+// off, for a monitoring instance on a shared screen.
+var observerBlockedKeys = map[string]string{
+	"enter":  "start servers",
+	"s":      "stop the server",
+	"ctrl+k": "stop everything",
+	"q":      "quit (it would stop the server; ctrl+c still quits)",
+	"W":      "load a standby",
+	"P":      "promote the standby",
+	"R":      "restart the server",
+	"T":      "take over the barn",
+	"S":      "save or restore slots",
+	"d":      "download models",
+	"a":      "add Hugging Face repos",
+	"A":      "delete cached models",
+	"m":      "move downloads into the barn",
+	"B":      "run benchmarks",
+	"Z":      "run benchmarks",
+	"i":      "start llama-cli",
+	"e":      "edit launch options",
+	"f":      "edit launch options",
+	"K":      "edit launch options",
+	"p":      "change the port",
+	"b":      "change the models directory",
+	"c":      "create the models directory",
+	"w":      "switch workspaces",
+	"l":      "change file logging",
+	"U":      "update llama-tui",
+	"F":      "change dev mode",
+	"Q":      "share the server publicly",
+	"ctrl+p": "edit prompts or pick one for the next launch",
+}
+
+// keyOverlayOpen reports whether an overlay that handles its own keys is
+// open.
+func (m appModel) keyOverlayOpen() bool {
+	return m.cacheView != nil || m.slotView != nil || m.serversView != nil || m.jsonView != nil || m.bookmarkList != nil || m.promptLibrary != nil || m.eventsView != nil || m.componentMenu != nil || m.requestLogs != nil || m.templateSandbox != nil || m.abChat != nil || m.showHelp || m.hub != nil ||
+		m.samplingView != nil || m.tensorView != nil || m.downloadSweep != nil
+}
+
+// observerRefuses reports whether --read-only refuses action, and explains
+// why in the status line. Actions that change something check it
+// themselves, wherever their key is pressed.
+func (m *appModel) observerRefuses(action string) bool {
+	if !m.observer {
+		return false
+	}
+	m.statusLineText = fmt.Sprintf("Read-only observer: cannot %s", action)
+	return true
+}
+
+// observerBlocks reports whether --read-only refuses keyStr where it was
+// pressed, and explains why in the status line.
+func (m *appModel) observerBlocks(keyStr string) bool {
+	if !m.observer {
+		return false
+	}
+	action := ""
+	switch {
+	case m.keyOverlayOpen():
+		// Overlays refuse their own mutating keys, with observerRefuses
+	case keyStr == "enter" && m.showLatency:
+		// Opens a request's server lines
+	case keyStr == "enter":
+		if _, ok := m.modelsList.SelectedItem().(familyItem); !ok {
+			action = observerBlockedKeys[keyStr]
+		}
+	case keyStr == "e" && (m.showDiagnostics || m.showBenchMatrix):
+		// Exports a report instead
+	default:
+		action = observerBlockedKeys[keyStr]
+	}
+	return action != "" && m.observerRefuses(action)
+}
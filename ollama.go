@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+const ollamaModelMediaType = "application/vnd.ollama.image.model"
+
+// ollamaManifest is the subset of an ollama image manifest needed to locate
+// the GGUF weights blob.
+type ollamaManifest struct {
+	Layers []struct {
+		MediaType string `json:"mediaType"`
+		Digest    string `json:"digest"`
+	} `json:"layers"`
+}
+
+// getOllamaModelsDir resolves ollama's model store.
+// Priority:
+// 1) OLLAMA_MODELS environment variable
+// 2) $HOME/.ollama/models
+func getOllamaModelsDir(home string) string {
+	if envDir := strings.TrimSpace(os.Getenv("OLLAMA_MODELS")); envDir != "" {
+		return envDir
+	}
+	return filepath.Join(home, ".ollama", "models")
+}
+
+// scanOllamaModels reads ollama manifests and maps each model layer blob to a
+// human-readable "ollama:<name>:<tag>" entry. A missing store yields no items.
+func scanOllamaModels(modelsDir string) ([]list.Item, error) {
+	manifestsDir := filepath.Join(modelsDir, "manifests")
+	blobsDir := filepath.Join(modelsDir, "blobs")
+	if info, err := os.Stat(manifestsDir); err != nil || !info.IsDir() {
+		return []list.Item{}, nil
+	}
+
+	items := []list.Item{}
+	err := filepath.WalkDir(manifestsDir, func(path string, d os.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if d.IsDir() {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		var manifest ollamaManifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			// Not a manifest we understand; skip it
+			return nil
+		}
+		for _, layer := range manifest.Layers {
+			if layer.MediaType != ollamaModelMediaType {
+				continue
+			}
+			// Blobs are stored as "sha256-<hex>" for digest "sha256:<hex>"
+			blobPath := filepath.Join(blobsDir, strings.Replace(layer.Digest, ":", "-", 1))
+			info, err := os.Stat(blobPath)
+			if err != nil {
+				continue
+			}
+			items = append(items, modelItem{
+				name: "ollama:" + ollamaModelName(manifestsDir, path),
+				path: blobPath,
+				size: info.Size(),
+			})
+			break
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// ollamaModelName turns a manifest path like
+// registry.ollama.ai/library/llama3/8b into "llama3:8b", keeping the
+// namespace for non-library models (e.g. "user/model:tag").
+func ollamaModelName(manifestsDir, path string) string {
+	rel, err := filepath.Rel(manifestsDir, path)
+	if err != nil {
+		return filepath.Base(path)
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) < 2 {
+		return rel
+	}
+	tag := parts[len(parts)-1]
+	nameParts := parts[:len(parts)-1]
+	// Drop the registry host, and the implicit "library" namespace
+	if len(nameParts) > 1 {
+		nameParts = nameParts[1:]
+	}
+	if len(nameParts) > 1 && nameParts[0] == "library" {
+		nameParts = nameParts[1:]
+	}
+	return strings.Join(nameParts, "/") + ":" + tag
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// oomPattern matches the messages of a server that ran out of memory:
+// llama.cpp's buffer allocations on CUDA, Metal, Vulkan, or the host, and
+// the C++ runtime's.
+var oomPattern = regexp.MustCompile(`(?i)out of memory|cudaMalloc failed|failed to allocate|unable to allocate|ErrorOutOfDeviceMemory|insufficient memory|not enough memory|cannot allocate memory|std::bad_alloc`)
+
+// gpuMemoryPattern tells a device allocation from a host one.
+var gpuMemoryPattern = regexp.MustCompile(`(?i)cuda|metal|vulkan|rocm|hip|sycl|device|gpu|vram`)
+
+// Where the load log says what was actually allocated, for flags left to
+// their defaults.
+var (
+	loadedContextPattern = regexp.MustCompile(`\bn_ctx\s*=\s*(\d+)`)
+	offloadedPattern     = regexp.MustCompile(`offloaded (\d+)/(\d+) layers to GPU`)
+)
+
+// minOOMContext is as small as the suggested context gets.
+const minOOMContext = 2048
+
+// detectOOM reports whether a crash was the server running out of memory,
+// with the first line that says so; the ones after it are its callers
+// giving up, and may not say which memory it was. A server killed outright
+// most likely met the kernel's OOM killer, which doesn't get a line in its
+// output.
+func detectOOM(output string, exitErr error) (reason string, gpu bool, ok bool) {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !oomPattern.MatchString(line) {
+			continue
+		}
+		if reason == "" {
+			reason = line
+		}
+		gpu = gpu || gpuMemoryPattern.MatchString(line)
+	}
+	if reason != "" {
+		return reason, gpu, true
+	}
+	if exitErr != nil && strings.Contains(exitErr.Error(), "signal: killed") {
+		return "Killed by the system, most likely its OOM killer (out of host memory)", false, true
+	}
+	return "", false, false
+}
+
+// oomOption is one way to relaunch in less memory.
+type oomOption struct {
+	label string
+	args  []string
+	note  string
+}
+
+// oomRecovery is the dialog shown after an out-of-memory crash, offering
+// smaller settings worked out from the launch that failed.
+type oomRecovery struct {
+	model   string
+	reason  string
+	current string
+	options []oomOption
+}
+
+// oomLaunch is the memory-related part of a failed launch.
+type oomLaunch struct {
+	ctx          uint64
+	typeK, typeV string
+	// layers offloaded to the GPU, out of total; 0 total when unknown
+	gpuLayers, totalLayers uint64
+}
+
+// readOOMLaunch takes the settings from the command line, falling back to
+// what the load log reports for the ones left to llama-server's defaults.
+func readOOMLaunch(argv []string, output string, dims kvDims, haveDims bool) oomLaunch {
+	last := map[string]string{}
+	for _, f := range parseFlagArgs(argv) {
+		last[f.name] = f.value
+	}
+	l := oomLaunch{typeK: "f16", typeV: "f16"}
+	if v := last["--cache-type-k"]; v != "" {
+		l.typeK = strings.ToLower(v)
+	}
+	if v := last["--cache-type-v"]; v != "" {
+		l.typeV = strings.ToLower(v)
+	}
+	if n, err := strconv.ParseUint(last["--ctx-size"], 10, 64); err == nil && n > 0 {
+		l.ctx = n
+	} else if m := loadedContextPattern.FindStringSubmatch(output); m != nil {
+		l.ctx, _ = strconv.ParseUint(m[1], 10, 64)
+	} else if haveDims {
+		l.ctx = dims.trained
+	}
+	if haveDims {
+		l.totalLayers = dims.layers
+	}
+	if m := offloadedPattern.FindStringSubmatch(output); m != nil {
+		l.gpuLayers, _ = strconv.ParseUint(m[1], 10, 64)
+		if l.totalLayers == 0 {
+			l.totalLayers, _ = strconv.ParseUint(m[2], 10, 64)
+		}
+	}
+	if n, err := strconv.ParseInt(last["--n-gpu-layers"], 10, 64); err == nil {
+		// 999 and -1 ask for everything that fits
+		switch {
+		case l.totalLayers == 0:
+			if n > 0 && n < 999 {
+				l.gpuLayers = uint64(n)
+			}
+		case n < 0 || uint64(n) >= l.totalLayers:
+			l.gpuLayers = l.totalLayers
+		default:
+			l.gpuLayers = uint64(n)
+		}
+	}
+	// The log counts the output layer too
+	if l.totalLayers > 0 {
+		l.gpuLayers = min(l.gpuLayers, l.totalLayers)
+	}
+	return l
+}
+
+// describe sums up the settings for the dialog.
+func (l oomLaunch) describe() string {
+	var parts []string
+	if l.ctx > 0 {
+		parts = append(parts, fmt.Sprintf("context %d", l.ctx))
+	}
+	parts = append(parts, "KV cache "+l.typeK+"/"+l.typeV)
+	if l.totalLayers > 0 {
+		parts = append(parts, fmt.Sprintf("%d/%d layers on the GPU", l.gpuLayers, l.totalLayers))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// smallerCacheType is the next quantization down for the KV cache, or ""
+// when it is as small as suggestions go.
+func smallerCacheType(t string) string {
+	switch t {
+	case "f32", "f16", "bf16":
+		return "q8_0"
+	case "q8_0", "q5_1", "q5_0":
+		return "q4_0"
+	}
+	return ""
+}
+
+// oomOptions works out the reduced settings to offer: half the context, a
+// quantized KV cache, and, when the GPU ran out, fewer offloaded layers,
+// then all of them at once.
+func oomOptions(l oomLaunch, dims kvDims, haveDims, gpu bool) []oomOption {
+	kvNote := func(ctx uint64, typeK, typeV string) string {
+		if !haveDims || ctx == 0 {
+			return ""
+		}
+		before, beforeV, ok1 := dims.kvCacheBytes(l.ctx, l.typeK, l.typeV)
+		after, afterV, ok2 := dims.kvCacheBytes(ctx, typeK, typeV)
+		if !ok1 || !ok2 {
+			return ""
+		}
+		return fmt.Sprintf("KV cache %s → %s", formatBytes(before+beforeV), formatBytes(after+afterV))
+	}
+	var options []oomOption
+	var combined []string
+	ctx, typeK, typeV := l.ctx, l.typeK, l.typeV
+	if l.ctx/2 >= minOOMContext {
+		ctx = l.ctx / 2
+		args := []string{"--ctx-size", strconv.FormatUint(ctx, 10)}
+		options = append(options, oomOption{label: fmt.Sprintf("Halve the context to %d tokens", ctx), args: args, note: kvNote(ctx, l.typeK, l.typeV)})
+		combined = append(combined, args...)
+	}
+	if k, v := smallerCacheType(l.typeK), smallerCacheType(l.typeV); k != "" || v != "" {
+		if k != "" {
+			typeK = k
+		}
+		if v != "" {
+			typeV = v
+		}
+		// A quantized V cache needs flash attention
+		args := []string{"--cache-type-k", typeK, "--cache-type-v", typeV, "--flash-attn", "on"}
+		options = append(options, oomOption{label: fmt.Sprintf("Quantize the KV cache to %s/%s", typeK, typeV), args: args, note: kvNote(l.ctx, typeK, typeV)})
+		combined = append(combined, args...)
+	}
+	if gpu && l.gpuLayers > 1 {
+		layers := l.gpuLayers * 3 / 4
+		args := []string{"--n-gpu-layers", strconv.FormatUint(layers, 10)}
+		note := fmt.Sprintf("%d of %d layers stay on the GPU, the rest run slower on the CPU", layers, l.totalLayers)
+		if l.totalLayers == 0 {
+			note = "the rest run slower on the CPU"
+		}
+		options = append(options, oomOption{label: fmt.Sprintf("Offload fewer layers (%d)", layers), args: args, note: note})
+		combined = append(combined, args...)
+	}
+	if len(options) > 1 {
+		options = append(options, oomOption{label: "All of the above", args: combined, note: kvNote(ctx, typeK, typeV)})
+	}
+	return options
+}
+
+// offerOOMRecovery opens the recovery dialog when a crash of modelName's
+// server was it running out of memory.
+func (m *appModel) offerOOMRecovery(modelName string, argv []string, exitErr error) bool {
+	output := ansiEscape.ReplaceAllString(m.logBuffer.String(), "")
+	reason, gpu, ok := detectOOM(output, exitErr)
+	if !ok {
+		return false
+	}
+	item, ok := m.findModelByName(modelName)
+	if !ok || item.kind == kindWhisper {
+		return false
+	}
+	var dims kvDims
+	haveDims := false
+	if meta, err := readGGUFMetadata(item.ggufPath()); err == nil {
+		dims, haveDims = kvDimsFrom(meta)
+	}
+	launch := readOOMLaunch(argv, output, dims, haveDims)
+	options := oomOptions(launch, dims, haveDims, gpu)
+	if len(options) == 0 {
+		return false
+	}
+	m.oomRecovery = &oomRecovery{model: modelName, reason: reason, current: launch.describe(), options: options}
+	return true
+}
+
+// withLaunchFlags replaces the flags in args, under either spelling, with
+// the new ones.
+func withLaunchFlags(args, flags []string) []string {
+	set := map[string]bool{}
+	for _, f := range parseFlagArgs(flags) {
+		set[f.name] = true
+	}
+	var out []string
+	for i := 0; i < len(args); i++ {
+		name, _, inline := strings.Cut(args[i], "=")
+		if canonical, ok := flagAliases[name]; ok {
+			name = canonical
+		}
+		if !set[name] {
+			out = append(out, args[i])
+			continue
+		}
+		if !inline && i+1 < len(args) && (!strings.HasPrefix(args[i+1], "-") || isNumber(args[i+1])) {
+			i++
+		}
+	}
+	return append(out, flags...)
+}
+
+// launchRetry overrides flags for the next launch of model only; starting
+// it, or launching another model, drops the override.
+type launchRetry struct {
+	model string
+	args  []string
+}
+
+func (m appModel) handleOOMRecoveryKey(keyStr string) (appModel, tea.Cmd) {
+	r := m.oomRecovery
+	if keyStr == "enter" {
+		// The first suggestion is the least costly
+		keyStr = "1"
+	}
+	if n, err := strconv.Atoi(keyStr); err == nil && n >= 1 && n <= len(r.options) {
+		m.oomRecovery = nil
+		item, ok := m.findModelByName(r.model)
+		if !ok {
+			m.statusLineText = r.model + " is no longer in the models list"
+			return m, nil
+		}
+		option := r.options[n-1]
+		// For this retry only; [e] saves them with the model's options
+		m.retryArgs = &launchRetry{model: item.name, args: option.args}
+		m, cmd := m.requestStart(item)
+		if cmd != nil {
+			m.statusLineText = fmt.Sprintf("Retrying %s with %s for this launch ([e] saves options for the model)", item.name, strings.Join(option.args, " "))
+		}
+		return m, cmd
+	}
+	switch keyStr {
+	case "f":
+		m.oomRecovery = nil
+		m.portInput.Blur()
+		return m.openLaunchOptions()
+	case "e":
+		if _, ok := m.findModelByName(r.model); !ok {
+			break
+		}
+		m.oomRecovery = nil
+		m.portInput.Blur()
+		return m.openModelConfig(r.model)
+	case "o":
+		// The allocation failure is at the bottom of the logs panel
+		m.oomRecovery = nil
+		m.logsViewport.GotoBottom()
+	case "esc", "q":
+		m.oomRecovery = nil
+	}
+	return m, nil
+}
+
+func (m appModel) renderOOMRecovery(width int) string {
+	r := m.oomRecovery
+	var b strings.Builder
+	b.WriteString(m.styles.help.Render(r.model+" ran out of memory") + "\n\n")
+	b.WriteString(m.styles.logError.Width(width).Render(r.reason) + "\n\n")
+	b.WriteString(m.styles.accent.Render("Failed with") + "\n")
+	b.WriteString(m.styles.help.Width(width).Render(r.current) + "\n\n")
+	b.WriteString(m.styles.accent.Render("Retry with") + "\n")
+	for i, o := range r.options {
+		b.WriteString(fmt.Sprintf("[%d] %s  ", i+1, o.label) + m.styles.help.Render(strings.Join(o.args, " ")) + "\n")
+		if o.note != "" {
+			b.WriteString(m.styles.help.Render("    "+ellipsize(o.note, width-4)) + "\n")
+		}
+	}
+	options := []string{"[enter] retry with [1]", "[f] edit launch options"}
+	if _, ok := m.findModelByName(r.model); ok {
+		options = append(options, "[e] edit this model's args")
+	}
+	options = append(options, "[o] show logs", "[esc] close")
+	b.WriteString("\n" + m.styles.help.Width(width).Render(strings.Join(options, "  ")))
+	return b.String()
+}
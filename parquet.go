@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// writeEmbedParquet writes embed records as a Parquet file with columns
+// line (int32), text (string), and embedding (list of float), in a single
+// row group of uncompressed PLAIN-encoded pages: what DuckDB, pandas, and
+// Polars read, without a Parquet library.
+func writeEmbedParquet(w io.Writer, records []embedRecord) error {
+	var lines, texts, floats []byte
+	var rep, def rleLevels
+	embedded := 0
+	for _, r := range records {
+		lines = binary.LittleEndian.AppendUint32(lines, uint32(int32(r.Line)))
+		texts = binary.LittleEndian.AppendUint32(texts, uint32(len(r.Text)))
+		texts = append(texts, r.Text...)
+		if len(r.Embedding) == 0 {
+			// An empty list is one level with nothing defined
+			rep.add(0, 1)
+			def.add(0, 1)
+			embedded++
+			continue
+		}
+		rep.add(0, 1)
+		rep.add(1, len(r.Embedding)-1)
+		def.add(1, len(r.Embedding))
+		for _, v := range r.Embedding {
+			floats = binary.LittleEndian.AppendUint32(floats, math.Float32bits(float32(v)))
+		}
+		embedded += len(r.Embedding)
+	}
+	columns := []parquetColumn{
+		{path: []string{"line"}, typ: parquetInt32, values: len(records), data: lines},
+		{path: []string{"text"}, typ: parquetByteArray, values: len(records), data: texts},
+		{path: []string{"embedding", "list", "element"}, typ: parquetFloat, values: embedded, data: append(append(rep.encode(), def.encode()...), floats...)},
+	}
+
+	out := &parquetOutput{w: bufio.NewWriter(w)}
+	out.write([]byte("PAR1"))
+	for i := range columns {
+		c := &columns[i]
+		var page thriftWriter
+		page.begin()
+		page.i32(1, 0) // DATA_PAGE
+		page.i32(2, int32(len(c.data)))
+		page.i32(3, int32(len(c.data)))
+		page.structField(5)
+		page.i32(1, int32(c.values))
+		page.i32(2, parquetPlain)
+		page.i32(3, parquetRLE)
+		page.i32(4, parquetRLE)
+		page.end()
+		page.end()
+		c.offset = out.n
+		c.size = int64(page.Len() + len(c.data))
+		out.write(page.Bytes())
+		out.write(c.data)
+	}
+
+	var meta thriftWriter
+	meta.begin()
+	meta.i32(1, 1)
+	meta.list(2, thriftStruct, 6)
+	for _, e := range []struct {
+		name                            string
+		typ, repetition, children, kind int32
+	}{
+		{"schema", -1, -1, 3, -1},
+		{"line", parquetInt32, parquetRequired, 0, -1},
+		{"text", parquetByteArray, parquetRequired, 0, parquetUTF8},
+		{"embedding", -1, parquetRequired, 1, parquetList},
+		{"list", -1, parquetRepeated, 1, -1},
+		{"element", parquetFloat, parquetRequired, 0, -1},
+	} {
+		meta.begin()
+		if e.typ >= 0 {
+			meta.i32(1, e.typ)
+		}
+		if e.repetition >= 0 {
+			meta.i32(3, e.repetition)
+		}
+		meta.str(4, e.name)
+		if e.children > 0 {
+			meta.i32(5, e.children)
+		}
+		if e.kind >= 0 {
+			meta.i32(6, e.kind)
+		}
+		meta.end()
+	}
+	meta.i64(3, int64(len(records)))
+	meta.list(4, thriftStruct, 1)
+	meta.begin()
+	meta.list(1, thriftStruct, len(columns))
+	var total int64
+	for _, c := range columns {
+		meta.begin()
+		meta.i64(2, c.offset)
+		meta.structField(3)
+		meta.i32(1, c.typ)
+		meta.list(2, thriftI32, 2)
+		meta.varint(parquetPlain)
+		meta.varint(parquetRLE)
+		meta.list(3, thriftBinary, len(c.path))
+		for _, p := range c.path {
+			meta.uvarint(uint64(len(p)))
+			meta.WriteString(p)
+		}
+		meta.i32(4, 0) // UNCOMPRESSED
+		meta.i64(5, int64(c.values))
+		meta.i64(6, c.size)
+		meta.i64(7, c.size)
+		meta.i64(9, c.offset)
+		meta.end()
+		meta.end()
+		total += c.size
+	}
+	meta.i64(2, total)
+	meta.i64(3, int64(len(records)))
+	meta.end()
+	meta.str(6, "llama-tui")
+	meta.end()
+	out.write(meta.Bytes())
+	out.write(binary.LittleEndian.AppendUint32(nil, uint32(meta.Len())))
+	out.write([]byte("PAR1"))
+	return out.flush()
+}
+
+// Parquet's enum values, from parquet.thrift.
+const (
+	parquetInt32     = 1
+	parquetFloat     = 4
+	parquetByteArray = 6
+	parquetRequired  = 0
+	parquetRepeated  = 2
+	parquetUTF8      = 0
+	parquetList      = 3
+	parquetPlain     = 0
+	parquetRLE       = 3
+)
+
+// parquetColumn is one column chunk: a single data page whose data holds
+// the repetition and definition levels, if any, then the values.
+type parquetColumn struct {
+	path   []string
+	typ    int32
+	values int
+	data   []byte
+	offset int64
+	size   int64
+}
+
+// rleLevels encodes levels of bit width 1 as runs of the RLE/bit-packing
+// hybrid encoding.
+type rleLevels struct {
+	out   []byte
+	value byte
+	run   int
+}
+
+func (r *rleLevels) add(value byte, n int) {
+	if n == 0 {
+		return
+	}
+	if r.run > 0 && value == r.value {
+		r.run += n
+		return
+	}
+	r.flush()
+	r.value, r.run = value, n
+}
+
+func (r *rleLevels) flush() {
+	if r.run > 0 {
+		r.out = binary.AppendUvarint(r.out, uint64(r.run)<<1)
+		r.out = append(r.out, r.value)
+		r.run = 0
+	}
+}
+
+// encode is the levels as a data page holds them, after their length.
+func (r *rleLevels) encode() []byte {
+	r.flush()
+	return append(binary.LittleEndian.AppendUint32(nil, uint32(len(r.out))), r.out...)
+}
+
+// Thrift compact protocol types used by Parquet's metadata.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs in Thrift's compact protocol. begin and end
+// open and close a struct; fields are written in increasing id order.
+type thriftWriter struct {
+	bytes.Buffer
+	lastID []int16
+}
+
+func (t *thriftWriter) begin() {
+	t.lastID = append(t.lastID, 0)
+}
+
+func (t *thriftWriter) end() {
+	t.WriteByte(0)
+	t.lastID = t.lastID[:len(t.lastID)-1]
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	last := &t.lastID[len(t.lastID)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.WriteByte(typ)
+		t.varint(int64(id))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) uvarint(v uint64) {
+	t.Write(binary.AppendUvarint(nil, v))
+}
+
+// varint is a zigzag-encoded signed integer.
+func (t *thriftWriter) varint(v int64) {
+	t.uvarint(uint64(v<<1 ^ v>>63))
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thriftWriter) str(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.uvarint(uint64(len(s)))
+	t.WriteString(s)
+}
+
+// list starts a list field of n elements, written next without headers;
+// struct elements each need begin and end.
+func (t *thriftWriter) list(id int16, elem byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.WriteByte(byte(n)<<4 | elem)
+	} else {
+		t.WriteByte(0xf0 | elem)
+		t.uvarint(uint64(n))
+	}
+}
+
+func (t *thriftWriter) structField(id int16) {
+	t.field(id, thriftStruct)
+	t.begin()
+}
+
+// parquetOutput tracks the offset column chunks start at and keeps the
+// first write error.
+type parquetOutput struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *parquetOutput) write(p []byte) {
+	if c.err != nil {
+		return
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+}
+
+func (c *parquetOutput) flush() error {
+	if c.err != nil {
+		return c.err
+	}
+	return c.w.Flush()
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+)
+
+// llama-tui keeps its files where the platform expects them:
+//
+//	config   presets and the optional config file   $XDG_CONFIG_HOME/llama-tui
+//	state    history, pins, sessions, crash reports $XDG_STATE_HOME/llama-tui
+//	logs     llama-server output                    <state>/logs
+//	cache    exports and anything safe to delete    $XDG_CACHE_HOME/llama-tui
+//
+// On macOS these are ~/Library/Application Support/llama-tui (config and
+// state), ~/Library/Logs/llama-tui, and ~/Library/Caches/llama-tui. Each
+// returns "" when the platform has no such directory (no home directory and
+// no XDG variable), and callers skip the file.
+
+// userHomeDir is the home directory. An empty or relative $HOME is an error
+// too, rather than putting files under the working directory.
+func userHomeDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(home) {
+		return "", fmt.Errorf("$HOME is not an absolute path: %q", home)
+	}
+	return home, nil
+}
+
+// expandHome resolves a leading ~/ in a path the user gave against home,
+// which is "" when there is no home directory.
+func expandHome(home, path string) (string, error) {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path, nil
+	}
+	if home == "" {
+		return "", fmt.Errorf("no home directory for %s", path)
+	}
+	return filepath.Join(home, rest), nil
+}
+
+// getCacheDir is llama-tui's directory under the user cache dir.
+func getCacheDir() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil || !filepath.IsAbs(cacheDir) {
+		return ""
+	}
+	return filepath.Join(cacheDir, appTitle)
+}
+
+// appConfigDir is llama-tui's directory under the user config dir.
+func appConfigDir() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil || !filepath.IsAbs(configDir) {
+		return ""
+	}
+	return filepath.Join(configDir, appTitle)
+}
+
+// appStateDir holds data worth keeping that isn't configuration.
+func appStateDir() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, appTitle)
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		return appConfigDir()
+	case "windows":
+		if dir := os.Getenv("LocalAppData"); dir != "" {
+			return filepath.Join(dir, appTitle, "state")
+		}
+		return ""
+	}
+	home, err := userHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".local", "state", appTitle)
+}
+
+// appLogsDir is where llama-server output is written when logging to file
+// is on.
+func appLogsDir() string {
+	if runtime.GOOS == "darwin" && os.Getenv("XDG_STATE_HOME") == "" {
+		if home, err := userHomeDir(); err == nil {
+			return filepath.Join(home, "Library", "Logs", appTitle)
+		}
+	}
+	stateDir := appStateDir()
+	if stateDir == "" {
+		return ""
+	}
+	return filepath.Join(stateDir, "logs")
+}
+
+// historyFiles are the state files that used to live in the cache
+// directory, including each workspace's copies under workspaces/.
+var historyFiles = []string{
+	"bench-results.json",
+	"bench-sweeps.json",
+	"hf-repos.json",
+	"last-launch.json",
+	"pins.json",
+	"session.json",
+	"sessions.jsonl",
+	"smoke.json",
+	"workspaces",
+}
+
+// presetDirs are the per-model presets that used to live in the cache
+// directory.
+var presetDirs = []string{"launch-configs", "kv-overrides"}
+
+// migrateLegacyPaths moves history and presets from the cache directory,
+// where earlier versions kept them. Anything already at the new location
+// is left alone. It reports how many files moved. Server logs in the
+// models directory, which may be many, move in the background with
+// barnLogsMigration.
+func migrateLegacyPaths() (int, error) {
+	var moved int
+	var errs []error
+	move := func(src, dst string) {
+		if dst == "" {
+			return
+		}
+		n, err := migratePath(src, dst, nil)
+		moved += n
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if cacheDir := getCacheDir(); cacheDir != "" {
+		stateDir, configDir := appStateDir(), appConfigDir()
+		for _, name := range historyFiles {
+			if stateDir != "" && stateDir != cacheDir {
+				move(filepath.Join(cacheDir, name), filepath.Join(stateDir, name))
+			}
+		}
+		for _, name := range presetDirs {
+			if configDir != "" && configDir != cacheDir {
+				move(filepath.Join(cacheDir, name), filepath.Join(configDir, name))
+			}
+		}
+	}
+	return moved, errors.Join(errs...)
+}
+
+// barnLogsMigration moves a models directory's llama-server-logs folder,
+// used by earlier versions, into the logs directory from a command, as
+// the files may be many or on another disk.
+type barnLogsMigration struct {
+	dir string
+	// moved counts the files moved so far, for the status line
+	moved atomic.Int64
+}
+
+// newBarnLogsMigration is the migration barnDir needs, nil for none.
+func newBarnLogsMigration(barnDir string) *barnLogsMigration {
+	if barnDir == "" || appLogsDir() == "" {
+		return nil
+	}
+	if _, err := os.Lstat(filepath.Join(barnDir, legacyLogsRelativeDir)); err != nil {
+		return nil
+	}
+	return &barnLogsMigration{dir: barnDir}
+}
+
+// cmd runs the migration, reporting progress until it is done.
+func (g *barnLogsMigration) cmd() tea.Cmd {
+	run := func() tea.Msg {
+		n, err := migratePath(filepath.Join(g.dir, legacyLogsRelativeDir), appLogsDir(), &g.moved)
+		return barnLogsMigratedMsg{moved: n, err: err}
+	}
+	return tea.Batch(run, migrationTickCmd())
+}
+
+func migrationTickCmd() tea.Cmd {
+	return tea.Tick(500*time.Millisecond, func(_ time.Time) tea.Msg {
+		return migrationTickMsg{}
+	})
+}
+
+// migratePath moves src to dst, merging directories file by file and
+// removing source directories left empty. A missing src is not an error,
+// and files already present at dst are kept in place at src. Each file
+// moved is counted in progress, when given.
+func migratePath(src, dst string, progress *atomic.Int64) (int, error) {
+	info, err := os.Lstat(src)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if !info.IsDir() {
+		if _, err := os.Lstat(dst); err == nil {
+			return 0, nil
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return 0, err
+		}
+		if err := moveFile(src, dst); err != nil {
+			return 0, fmt.Errorf("moving %s: %w", src, err)
+		}
+		if progress != nil {
+			progress.Add(1)
+		}
+		return 1, nil
+	}
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return 0, err
+	}
+	var moved int
+	var errs []error
+	for _, e := range entries {
+		n, err := migratePath(filepath.Join(src, e.Name()), filepath.Join(dst, e.Name()), progress)
+		moved += n
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	// Only succeeds once everything has moved
+	_ = os.Remove(src)
+	return moved, errors.Join(errs...)
+}
+
+// migrationNote describes a migration's outcome for the status line, or ""
+// when there was nothing to do.
+func migrationNote(moved int, err error) string {
+	switch {
+	case err != nil:
+		return fmt.Sprintf("Moving files to their new locations: %v", err)
+	case moved > 0:
+		return fmt.Sprintf("Moved %s to their new locations (see llama-tui paths)", pluralize(moved, "file"))
+	}
+	return ""
+}
+
+// newPathsCmd prints where llama-tui reads and writes its files.
+func newPathsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "paths",
+		Short: "Print the config, state, logs, and cache locations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			barn, err := getDefaultBarnDir()
+			if err != nil {
+				return err
+			}
+			orNone := func(p string) string {
+				if p == "" {
+					return "(none)"
+				}
+				return p
+			}
+			w := cmd.OutOrStdout()
+			fmt.Fprintf(w, "models  %s\n", barn)
+			fmt.Fprintf(w, "config  %s\n", getConfigPath(barn))
+			fmt.Fprintf(w, "presets %s\n", orNone(appConfigDir()))
+			fmt.Fprintf(w, "state   %s\n", orNone(historyDir("")))
+			fmt.Fprintf(w, "logs    %s\n", orNone(appLogsDir()))
+			fmt.Fprintf(w, "cache   %s\n", orNone(getCacheDir()))
+			return nil
+		},
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Pinned models are stored by path, in their display order.
+func pinsPath(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "pins.json")
+}
+
+func loadPins(dir string) ([]string, error) {
+	path := pinsPath(dir)
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var pins []string
+	if err := json.Unmarshal(data, &pins); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return pins, nil
+}
+
+func savePinsCmd(dir string, pins []string) tea.Cmd {
+	return func() tea.Msg {
+		path := pinsPath(dir)
+		if path == "" {
+			return pinsSavedMsg{err: fmt.Errorf("no state directory available")}
+		}
+		data, err := json.MarshalIndent(pins, "", "  ")
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(path), 0o755)
+		}
+		if err == nil {
+			err = os.WriteFile(path, data, 0o644)
+		}
+		return pinsSavedMsg{err: err}
+	}
+}
+
+// orderWithPins moves pinned models to the top in pin order, leaving the
+// rest in their scanned (alphabetical) order.
+func orderWithPins(items []list.Item, pins []string) []list.Item {
+	rank := make(map[string]int, len(pins))
+	for i, p := range pins {
+		rank[p] = i
+	}
+	pinned := make([]list.Item, len(pins))
+	rest := make([]list.Item, 0, len(items))
+	for _, it := range items {
+		mi, ok := it.(modelItem)
+		if !ok {
+			rest = append(rest, it)
+			continue
+		}
+		if r, ok := rank[mi.path]; ok {
+			mi.pinned = true
+			pinned[r] = mi
+			continue
+		}
+		mi.pinned = false
+		rest = append(rest, mi)
+	}
+	out := make([]list.Item, 0, len(items))
+	for _, it := range pinned {
+		// Pins for models that are gone keep their slot for when they return
+		if it != nil {
+			out = append(out, it)
+		}
+	}
+	return append(out, rest...)
+}
+
+// togglePin pins path at the bottom of the pinned section or unpins it.
+func togglePin(pins []string, path string) ([]string, bool) {
+	for i, p := range pins {
+		if p == path {
+			return append(pins[:i:i], pins[i+1:]...), false
+		}
+	}
+	return append(pins[:len(pins):len(pins)], path), true
+}
+
+// movePin shifts a pinned path by delta positions, reporting whether it moved.
+func movePin(pins []string, path string, delta int) ([]string, bool) {
+	for i, p := range pins {
+		if p != path {
+			continue
+		}
+		j := i + delta
+		if j < 0 || j >= len(pins) {
+			return pins, false
+		}
+		out := append([]string(nil), pins...)
+		out[i], out[j] = out[j], out[i]
+		return out, true
+	}
+	return pins, false
+}
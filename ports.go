@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// portTable is the central record of ports held by servers this app manages,
+// mapping port to the model served on it.
+type portTable map[int]string
+
+// with returns a copy of the table with port assigned to model. Copies keep
+// value-receiver Update semantics intact.
+func (t portTable) with(port int, model string) portTable {
+	next := make(portTable, len(t)+1)
+	for p, m := range t {
+		next[p] = m
+	}
+	next[port] = model
+	return next
+}
+
+// without returns a copy of the table with port released.
+func (t portTable) without(port int) portTable {
+	next := make(portTable, len(t))
+	for p, m := range t {
+		if p != port {
+			next[p] = m
+		}
+	}
+	return next
+}
+
+// portFree reports whether nothing (managed or external) is listening on port.
+func portFree(port int) bool {
+	ln, err := net.Listen("tcp", "127.0.0.1:"+strconv.Itoa(port))
+	if err != nil {
+		return false
+	}
+	_ = ln.Close()
+	return true
+}
+
+// checkPort explains why port cannot be used, or returns nil.
+func (t portTable) checkPort(port int) error {
+	if model, ok := t[port]; ok {
+		return fmt.Errorf("port %d is already used by %s", port, model)
+	}
+	if !portFree(port) {
+		return fmt.Errorf("port %d is already in use by another process", port)
+	}
+	return nil
+}
+
+// nextFreePort finds the first port after start that is neither managed nor
+// in use, or 0 if none is found nearby.
+func (t portTable) nextFreePort(start int) int {
+	for p := start + 1; p <= 65535 && p < start+100; p++ {
+		if _, taken := t[p]; taken {
+			continue
+		}
+		if portFree(p) {
+			return p
+		}
+	}
+	return 0
+}
+
+// portInputBase is the port the focused input steps from: its value, else
+// the port a launch of the selected model would use.
+func (m appModel) portInputBase() int {
+	portStr := strings.TrimSpace(m.portInput.Value())
+	if portStr == "" {
+		portStr = defaultPort
+		if item, ok := m.modelsList.SelectedItem().(modelItem); ok {
+			portStr = m.launchPort(item)
+		}
+	}
+	port, err := validatePort(portStr)
+	if err != nil {
+		port, _ = strconv.Atoi(defaultPort)
+	}
+	return port
+}
+
+// stepPortInput moves the port input's port up or down by delta, saying
+// whether the new port is taken.
+func (m appModel) stepPortInput(delta int) appModel {
+	port := min(max(m.portInputBase()+delta, 1), 65535)
+	m.setPortInput(port)
+	if err := m.ports.checkPort(port); err != nil {
+		m.statusLineText = fmt.Sprintf("Port %d: %v - [tab] next free port", port, err)
+	} else {
+		m.statusLineText = fmt.Sprintf("Port %d is free", port)
+	}
+	return m
+}
+
+// nextFreePortInput moves the port input on to the next port that is
+// neither managed nor in use.
+func (m appModel) nextFreePortInput() appModel {
+	base := m.portInputBase()
+	next := m.ports.nextFreePort(base)
+	if next == 0 {
+		m.statusLineText = fmt.Sprintf("No free port found after %d", base)
+		return m
+	}
+	m.setPortInput(next)
+	m.statusLineText = fmt.Sprintf("Port %d is free", next)
+	return m
+}
+
+func (m *appModel) setPortInput(port int) {
+	m.portInput.SetValue(strconv.Itoa(port))
+	m.portInput.CursorEnd()
+}
+
+// portRange is where automatic per-model ports are assigned.
+type portRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+func (r portRange) enabled() bool {
+	return r.Start > 0 && r.End >= r.Start && r.End <= 65535
+}
+
+// portFor derives a stable port for model from a hash of its name, so the
+// same model always comes back on the same port.
+func (r portRange) portFor(model string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(strings.ToLower(model)))
+	return r.Start + int(h.Sum32()%uint32(r.End-r.Start+1))
+}
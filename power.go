@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+const powerCheckInterval = 30 * time.Second
+
+// powerPolicy pauses or stops the server on low battery. Zero threshold
+// disables it.
+type powerPolicy struct {
+	// BatteryThreshold is the charge percent below which, on battery, the
+	// server is paused or stopped.
+	BatteryThreshold int `json:"battery_threshold"`
+	// Action is "pause" (suspend the process until AC power returns, the
+	// default) or "stop".
+	Action string `json:"action"`
+	// ResumeOnAC restarts a server stopped by this policy once mains power
+	// returns.
+	ResumeOnAC bool `json:"resume_on_ac"`
+	// BeforeSleep applies Action when the machine is about to sleep too,
+	// whatever the battery (Linux with systemd-logind only).
+	BeforeSleep bool `json:"before_sleep"`
+}
+
+func (p powerPolicy) enabled() bool {
+	return p.BatteryThreshold > 0
+}
+
+// watchesSleep reports whether the policy needs logind's sleep signals.
+func (p powerPolicy) watchesSleep() bool {
+	return p.BeforeSleep && runtime.GOOS == "linux"
+}
+
+func (p powerPolicy) stops() bool {
+	return strings.EqualFold(p.Action, "stop")
+}
+
+// powerState is a battery reading; known is false on machines without one.
+type powerState struct {
+	known     bool
+	onBattery bool
+	percent   int
+}
+
+// readPowerState reads the battery from sysfs on Linux and pmset on macOS.
+func readPowerState() (powerState, error) {
+	switch runtime.GOOS {
+	case "linux":
+		return readSysfsPowerState("/sys/class/power_supply")
+	case "darwin":
+		out, err := exec.Command("pmset", "-g", "batt").Output()
+		if err != nil {
+			return powerState{}, err
+		}
+		return parsePmsetBatt(string(out)), nil
+	}
+	return powerState{}, nil
+}
+
+func readSysfsPowerState(root string) (powerState, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return powerState{}, err
+	}
+	read := func(dir, name string) string {
+		data, _ := os.ReadFile(filepath.Join(root, dir, name))
+		return strings.TrimSpace(string(data))
+	}
+	var st powerState
+	onAC := false
+	for _, e := range entries {
+		switch read(e.Name(), "type") {
+		case "Battery":
+			if pct, err := strconv.Atoi(read(e.Name(), "capacity")); err == nil {
+				st.known = true
+				st.percent = pct
+			}
+			if read(e.Name(), "status") == "Discharging" {
+				st.onBattery = true
+			}
+		case "Mains":
+			if read(e.Name(), "online") == "1" {
+				onAC = true
+			}
+		}
+	}
+	if onAC {
+		st.onBattery = false
+	}
+	return st, nil
+}
+
+var pmsetPercent = regexp.MustCompile(`(\d+)%`)
+
+func parsePmsetBatt(out string) powerState {
+	var st powerState
+	if m := pmsetPercent.FindStringSubmatch(out); m != nil {
+		st.percent, _ = strconv.Atoi(m[1])
+		st.known = true
+	}
+	st.onBattery = strings.Contains(out, "'Battery Power'")
+	return st
+}
+
+// powerCheckCmd reads the power state after delay.
+func powerCheckCmd(delay time.Duration) tea.Cmd {
+	return tea.Tick(delay, func(t time.Time) tea.Msg {
+		st, err := readPowerState()
+		return powerStateMsg{state: st, at: t, err: err}
+	})
+}
+
+// setServerSuspended pauses or resumes the server process.
+func setServerSuspended(cmd *exec.Cmd, suspend bool) error {
+	if cmd == nil || cmd.Process == nil {
+		return fmt.Errorf("server is not running")
+	}
+	proc, err := process.NewProcess(int32(cmd.Process.Pid))
+	if err != nil {
+		return err
+	}
+	if suspend {
+		return proc.Suspend()
+	}
+	return proc.Resume()
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// flagAliases maps short llama-server flags to their long form so checks
+// can treat both spellings as the same option.
+var flagAliases = map[string]string{
+	"-m":           "--model",
+	"-c":           "--ctx-size",
+	"-fa":          "--flash-attn",
+	"-ctk":         "--cache-type-k",
+	"-ctv":         "--cache-type-v",
+	"-ngl":         "--n-gpu-layers",
+	"--gpu-layers": "--n-gpu-layers",
+	"-t":           "--threads",
+	"-np":          "--parallel",
+}
+
+// repeatableFlags may legitimately appear more than once.
+var repeatableFlags = map[string]bool{
+	"--lora":           true,
+	"--lora-scaled":    true,
+	"--override-kv":    true,
+	"--control-vector": true,
+}
+
+// ropeFlags extend a model's usable context beyond its training length.
+var ropeFlags = []string{"--rope-scaling", "--rope-scale", "--rope-freq-scale", "--rope-freq-base", "--yarn-orig-ctx"}
+
+type flagArg struct {
+	name  string
+	value string
+}
+
+// parseFlagArgs extracts flags and their values from argv, normalizing
+// aliases and "--flag=value" syntax. Non-flag tokens are attached as the
+// value of the preceding flag.
+func parseFlagArgs(argv []string) []flagArg {
+	var flags []flagArg
+	for i := 0; i < len(argv); i++ {
+		tok := argv[i]
+		if !strings.HasPrefix(tok, "-") || isNumber(tok) {
+			continue
+		}
+		name, value, hasValue := strings.Cut(tok, "=")
+		if canonical, ok := flagAliases[name]; ok {
+			name = canonical
+		}
+		if !hasValue && i+1 < len(argv) && (!strings.HasPrefix(argv[i+1], "-") || isNumber(argv[i+1])) {
+			value = argv[i+1]
+			i++
+		}
+		flags = append(flags, flagArg{name: name, value: value})
+	}
+	return flags
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// checkLaunchFlags reports incompatible or redundant flag combinations.
+// meta may be nil when the model header could not be read.
+func checkLaunchFlags(argv []string, meta *ggufMetadata) []string {
+	flags := parseFlagArgs(argv)
+	counts := map[string]int{}
+	last := map[string]string{}
+	for _, f := range flags {
+		counts[f.name]++
+		last[f.name] = f.value
+	}
+	has := func(name string) bool { return counts[name] > 0 }
+
+	var warnings []string
+	for _, f := range flags {
+		if counts[f.name] > 1 && !repeatableFlags[f.name] {
+			warnings = append(warnings, fmt.Sprintf("%s is given %d times; only the last value (%q) takes effect", f.name, counts[f.name], last[f.name]))
+			// Report each duplicate once
+			counts[f.name] = 1
+		}
+	}
+
+	if has("--keep") && !has("--context-shift") {
+		warnings = append(warnings, "--keep only matters with --context-shift, which is off")
+	}
+
+	if v := last["--cache-reuse"]; has("--no-cache-prompt") && v != "" && v != "0" {
+		warnings = append(warnings, "--cache-reuse needs prompt caching, but --no-cache-prompt turns it off")
+	}
+
+	if has("--mlock") && has("--no-mmap") {
+		warnings = append(warnings, "--mlock with --no-mmap: the model is already fully loaded into RAM, so --mlock only pins it (and may fail on low ulimit -l)")
+	}
+
+	if v := strings.ToLower(last["--cache-type-v"]); v != "" && v != "f16" && v != "f32" && v != "bf16" {
+		switch strings.ToLower(last["--flash-attn"]) {
+		case "off", "0", "false":
+			warnings = append(warnings, fmt.Sprintf("--cache-type-v %s requires flash attention, but --flash-attn is off", v))
+		}
+	}
+
+	if meta != nil && has("--ctx-size") {
+		trained, ok := meta.contextLength()
+		requested, err := strconv.ParseUint(last["--ctx-size"], 10, 64)
+		if ok && err == nil && requested > trained {
+			ropeSet := false
+			for _, rf := range ropeFlags {
+				if has(rf) {
+					ropeSet = true
+					break
+				}
+			}
+			if !ropeSet {
+				warnings = append(warnings, fmt.Sprintf("--ctx-size %d exceeds the model's trained context (%d) and no RoPE scaling flags are set", requested, trained))
+			}
+		}
+	}
+	return warnings
+}
+
+// preflightCmd expands the launch command and checks it for flag conflicts
+// before anything is started.
+func (m appModel) preflightCmd(selected modelItem, port string) tea.Cmd {
+	cfg := m.config
+	launchArgs := m.argsFor(selected.name)
+	fixes := m.flagFixes
+	logsDir := ""
+	if m.logToFileEnabled {
+		logsDir = m.logsDir
+	}
+	var notices []string
+	if m.needsLicenseAck(selected) {
+		notices = append(notices, licenseWarning(selected))
+	}
+	standbyLoading, standbyPID := false, int32(0)
+	if s := m.standby; s != nil {
+		standbyLoading = !s.ready
+		if s.started != nil && s.started.process != nil {
+			standbyPID = int32(s.started.process.pid)
+		}
+	}
+	return func() tea.Msg {
+		// Checked here rather than left to the server, whose file-not-found
+		// wouldn't mention an unmounted volume
+		if reason := checkModelPath(selected); reason != "" {
+			return preflightDoneMsg{item: selected, port: port, err: fmt.Errorf("%s is unavailable: %s - [r] rescan once it is back", selected.name, reason), unavailable: reason}
+		}
+		gate := launchGate(selected, port, standbyLoading, standbyPID)
+		if selected.kind == kindWhisper {
+			// llama-server's flag checks don't apply to whisper-server
+			argv, err := cfg.buildWhisperCommand("whisper-server", selected.path, port, launchArgs)
+			if err != nil {
+				return preflightDoneMsg{item: selected, port: port, err: err}
+			}
+			warnings := append(notices, checkDiskSpace(argv, logsDir, cfg.minFreeDiskBytes())...)
+			return preflightDoneMsg{item: selected, port: port, warnings: append(gateWarnings(gate), warnings...), gate: gate}
+		}
+		launchArgs := withSamplingFlags(selected.name, withKVOverrides(selected.name, launchArgs))
+		argv, err := cfg.buildServerCommand("llama-server", selected.path, selected.mmproj, port, launchArgs)
+		if err != nil {
+			return preflightDoneMsg{item: selected, port: port, err: err}
+		}
+		argv = applyFlagFixes(withHFRepo(argv, selected), fixes)
+		// Header read failures only disable the model-aware checks
+		meta, _ := readGGUFMetadata(selected.ggufPath())
+		warnings := append(notices, checkLaunchFlags(argv, meta)...)
+		warnings = append(warnings, checkDiskSpace(argv, logsDir, cfg.minFreeDiskBytes())...)
+		if bin, err := getLlamaServerBinary(); err == nil {
+			warnings = append(warnings, checkBackendFlags(argv, detectServerBackends(bin))...)
+		}
+		return preflightDoneMsg{item: selected, port: port, warnings: append(gateWarnings(gate), warnings...), gate: gate}
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// presetVariable is a placeholder in a preset's args, {{name}} or
+// {{name=default}}, filled in at launch.
+var presetVariable = regexp.MustCompile(`\{\{\s*([A-Za-z_][\w-]*)\s*(?:=([^}]*))?\}\}`)
+
+// templateVar is one placeholder of a preset, with its default if any.
+type templateVar struct {
+	name string
+	def  string
+}
+
+// templateVariables lists the placeholders in args in order of first use.
+func templateVariables(args []string) []templateVar {
+	var vars []templateVar
+	seen := map[string]bool{}
+	for _, arg := range args {
+		for _, match := range presetVariable.FindAllStringSubmatch(arg, -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				vars = append(vars, templateVar{name: match[1], def: strings.TrimSpace(match[2])})
+			}
+		}
+	}
+	return vars
+}
+
+// expandTemplate fills the placeholders in args from values.
+func expandTemplate(args []string, values map[string]string) []string {
+	out := make([]string, len(args))
+	for i, arg := range args {
+		out[i] = presetVariable.ReplaceAllStringFunc(arg, func(s string) string {
+			return values[presetVariable.FindStringSubmatch(s)[1]]
+		})
+	}
+	return out
+}
+
+// parseVarFlags reads --var name=value flags.
+func parseVarFlags(flags []string) (map[string]string, error) {
+	vars := map[string]string{}
+	for _, f := range flags {
+		name, value, ok := strings.Cut(f, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("--var %q: want name=value", f)
+		}
+		vars[strings.TrimSpace(name)] = value
+	}
+	return vars, nil
+}
+
+// The values last entered for each preset's placeholders are offered again.
+func presetVarsPath(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "preset-vars.json")
+}
+
+func loadPresetVars(dir string) map[string]map[string]string {
+	vars := map[string]map[string]string{}
+	path := presetVarsPath(dir)
+	if path == "" {
+		return vars
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &vars)
+	}
+	return vars
+}
+
+// savePresetVarsCmd remembers the values entered for preset; the memory
+// is a convenience, so failures are ignored.
+func savePresetVarsCmd(dir, preset string, values map[string]string) tea.Cmd {
+	return func() tea.Msg {
+		path := presetVarsPath(dir)
+		if path == "" {
+			return nil
+		}
+		vars := loadPresetVars(dir)
+		vars[preset] = values
+		data, err := json.MarshalIndent(vars, "", "  ")
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(path), 0o755)
+		}
+		if err == nil {
+			_ = os.WriteFile(path, data, 0o644)
+		}
+		return nil
+	}
+}
+
+// promptPresetVars asks for the placeholders of action's args that --var
+// didn't fill, prefilled with the last values or the defaults. It reports
+// false when nothing is left to ask.
+func (m appModel) promptPresetVars(action startupAction) (appModel, bool) {
+	vars := templateVariables(action.args)
+	var missing []templateVar
+	for _, v := range vars {
+		if _, ok := action.vars[v.name]; !ok {
+			missing = append(missing, v)
+		}
+	}
+	if len(missing) == 0 {
+		return m, false
+	}
+	last := loadPresetVars(m.historyDir)[action.preset]
+	fields := make([]formField, 0, len(missing))
+	for _, v := range missing {
+		value := v.def
+		if prev, ok := last[v.name]; ok {
+			value = prev
+		}
+		doc := "Used in " + strings.Join(argsUsing(action.args, v.name), ", ")
+		fields = append(fields, newTextField(v.name, v.name, doc, value, func(s string) error {
+			if strings.TrimSpace(s) == "" {
+				return fmt.Errorf("needs a value")
+			}
+			return nil
+		}))
+	}
+	title := "Launch " + action.preset
+	if action.preset == "" {
+		title = "Launch " + action.model
+	}
+	form := newForm(title, fields)
+	m.form, m.formPurpose = &form, formPresetVars
+	m.presetLaunch = &action
+	m.statusLineText = "Fill in " + pluralize(len(missing), "value") + " for this launch - [enter] starts it, [esc] cancels"
+	return m, true
+}
+
+// argsUsing lists where placeholder name is used: the flag it is the
+// value of, else the arg that contains it.
+func argsUsing(args []string, name string) []string {
+	var using []string
+	for i, arg := range args {
+		for _, match := range presetVariable.FindAllStringSubmatch(arg, -1) {
+			if match[1] != name {
+				continue
+			}
+			if match[0] == arg && i > 0 && strings.HasPrefix(args[i-1], "-") {
+				arg = args[i-1]
+			}
+			using = append(using, arg)
+			break
+		}
+	}
+	return using
+}
+
+// launchWithPresetVars fills in the placeholders and runs the launch.
+func (m appModel) launchWithPresetVars(values map[string]string) (appModel, tea.Cmd) {
+	if m.presetLaunch == nil {
+		return m, nil
+	}
+	action := *m.presetLaunch
+	m.presetLaunch = nil
+	entered := make(map[string]string, len(values))
+	filled := make(map[string]string, len(action.vars)+len(values))
+	for k, v := range action.vars {
+		filled[k] = v
+	}
+	for k, v := range values {
+		entered[k] = strings.TrimSpace(v)
+		filled[k] = strings.TrimSpace(v)
+	}
+	action.vars = filled
+	m.startup = &action
+	next, cmd := m.runStartupAction()
+	if action.preset == "" {
+		return next, cmd
+	}
+	return next, tea.Batch(cmd, savePresetVarsCmd(m.historyDir, action.preset, entered))
+}
+
+// describeVars is "name=value ..." in name order, for the event log.
+func describeVars(vars map[string]string) string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + "=" + vars[name]
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// previewDelay is how long the highlight has to rest on a model before the
+// logs panel previews it, so scrolling through the list doesn't flicker.
+const previewDelay = 600 * time.Millisecond
+
+// previewDwellCmd waits out previewDelay for the selected model; the
+// preview only opens if it is still selected then.
+func (m appModel) previewDwellCmd() tea.Cmd {
+	path := m.selectedPath()
+	if path == "" {
+		return nil
+	}
+	return tea.Tick(previewDelay, func(time.Time) tea.Msg {
+		return previewDwellMsg{path: path}
+	})
+}
+
+// selectionMoved runs the checks for a new selection: the file's
+// availability and, after a moment, the preview.
+func (m appModel) selectionMoved(prev string) tea.Cmd {
+	if m.selectedPath() == prev {
+		return nil
+	}
+	return tea.Batch(m.checkSelectedPathCmd(prev), m.previewDwellCmd())
+}
+
+// previewing reports whether the logs panel shows the selected model's
+// preview instead of server output: only while nothing is running.
+func (m appModel) previewing() bool {
+	if m.previewPath == "" || m.server.running() || m.attached != nil {
+		return false
+	}
+	return m.previewPath == m.selectedPath()
+}
+
+// lastSession is the model's most recent recorded session.
+func (m appModel) lastSession(modelName string) (sessionRecord, bool) {
+	var last sessionRecord
+	found := false
+	for _, s := range m.sessions {
+		if s.Model == modelName && (!found || s.Start.After(last.Start)) {
+			last, found = s, true
+		}
+	}
+	return last, found
+}
+
+// renderPreview summarizes the selected model without starting it: what
+// it is, how it was last launched, and how that run ended.
+func (m appModel) renderPreview(width, height int) string {
+	mi, ok := m.modelsList.SelectedItem().(modelItem)
+	if !ok {
+		return ""
+	}
+	row := func(label, value string) string {
+		if value == "" {
+			return ""
+		}
+		return m.styles.help.Render(fmt.Sprintf("%-10s", label)) + ellipsize(value, width-10)
+	}
+	var lines []string
+	add := func(s string) {
+		if s != "" {
+			lines = append(lines, s)
+		}
+	}
+	section := func(title string) {
+		lines = append(lines, "", m.styles.help.Render(title))
+	}
+
+	add(m.styles.accent.Bold(true).Render(ellipsize(m.displayName(mi.name), width)))
+	if reason, ok := m.unavailable[mi.path]; ok {
+		add(m.styles.usageCritical.Render(ellipsize("Unavailable: "+reason, width)))
+	}
+	var summary []string
+	for _, s := range []string{mi.arch, mi.params, mi.quant} {
+		if s != "" {
+			summary = append(summary, s)
+		}
+	}
+	if mi.size > 0 {
+		summary = append(summary, formatBytes(uint64(mi.size)))
+	}
+	add(row("Model", strings.Join(summary, " · ")))
+	if mi.contextLength > 0 {
+		add(row("Context", formatContextLength(mi.contextLength)+" trained"))
+	}
+	if mi.mmproj != "" {
+		add(row("Vision", filepath.Base(mi.mmproj)))
+	}
+	if mi.kind == kindWhisper {
+		add(row("Server", mi.kind.serverName()))
+	}
+	if l := mi.license; l.known() || l.gated {
+		style := m.styles.help
+		if m.needsLicenseAck(mi) {
+			style = m.styles.usageWarn
+		}
+		add(m.styles.help.Render(fmt.Sprintf("%-10s", "License")) + style.Render(ellipsize(l.summary(), width-10)))
+	}
+
+	section("Last used settings")
+	settings := m.modelConfigSummary(mi.name)
+	if settings == "" {
+		settings = "defaults"
+	}
+	add(row("Saved", settings))
+	add(row("Port", m.launchPort(mi)))
+
+	section("Last run")
+	if s, ok := m.lastSession(mi.name); ok {
+		outcome := "stopped"
+		style := m.styles.help
+		if s.Crashed {
+			outcome, style = "crashed", m.styles.usageCritical
+		}
+		add(row("Ended", style.Render(outcome)+" after "+formatSpan(s.End.Sub(s.Start))+" on port "+s.Port))
+		add(row("When", m.config.Timestamps.dateTime(s.End)))
+		if s.Preset != "" {
+			add(row("Preset", s.Preset))
+		}
+		if s.Prompt != "" {
+			add(row("Prompt", promptPick{name: s.Prompt, system: s.PromptUse == "system"}.describe()))
+		}
+	} else {
+		add(m.styles.disabled.Render("Never run"))
+	}
+	add(row("Crashes", m.reliabilitySummary(mi.name)))
+	if r, ok := m.smokeResults[mi.path]; ok {
+		add(row("Smoke", r.summary()+" ("+m.config.Timestamps.date(r.At)+")"))
+	}
+
+	lines = append(lines, "", m.styles.help.Render(ellipsize("[enter] start  [e] saved options  · logs return when a server starts", width)))
+	// Same height as the logs it stands in for
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return strings.Join(lines, "\n")
+}
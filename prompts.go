@@ -0,0 +1,352 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// The prompt library is a directory of text files in the config directory,
+// one per named prompt, so they can be edited outside llama-tui too.
+const promptsDirName = "prompts"
+
+const promptExt = ".txt"
+
+// promptName is what a library prompt may be called: it becomes a file name.
+var promptName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// promptPick is the library prompt chosen for launches, with its text as
+// last read.
+type promptPick struct {
+	name string
+	text string
+	// system adds it to chat requests through the proxy as their system
+	// message instead of sending it as the warm-up request
+	system bool
+}
+
+// hash identifies the text a session was launched with, since the file
+// may be edited later.
+func (p promptPick) hash() string {
+	sum := sha256.Sum256([]byte(p.text))
+	return hex.EncodeToString(sum[:])
+}
+
+// systemText is the system message for chat requests, "" for none.
+func (p promptPick) systemText() string {
+	if !p.system {
+		return ""
+	}
+	return strings.TrimSpace(p.text)
+}
+
+func (p promptPick) use() string {
+	if p.system {
+		return "system"
+	}
+	return "warmup"
+}
+
+func (p promptPick) describe() string {
+	if p.system {
+		return p.name + " (system prompt)"
+	}
+	return p.name + " (warm-up)"
+}
+
+// libraryPrompt is one prompt of the library.
+type libraryPrompt struct {
+	name string
+	text string
+}
+
+// promptLibraryView is the [ctrl+p] overlay managing the library.
+type promptLibraryView struct {
+	prompts []libraryPrompt
+	cursor  int
+	// deleting is the prompt [d] asked about; [d] again deletes it
+	deleting string
+	loading  bool
+	err      error
+}
+
+func promptsDir() string {
+	configDir := appConfigDir()
+	if configDir == "" {
+		return ""
+	}
+	return filepath.Join(configDir, promptsDirName)
+}
+
+func promptPath(name string) string {
+	return filepath.Join(promptsDir(), name+promptExt)
+}
+
+// loadPrompts reads the library in name order.
+func loadPrompts() ([]libraryPrompt, error) {
+	dir := promptsDir()
+	if dir == "" {
+		return nil, fmt.Errorf("no config directory available")
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var prompts []libraryPrompt
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), promptExt)
+		if !ok || e.IsDir() || !promptName.MatchString(name) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		prompts = append(prompts, libraryPrompt{name: name, text: string(data)})
+	}
+	sort.Slice(prompts, func(i, j int) bool { return prompts[i].name < prompts[j].name })
+	return prompts, nil
+}
+
+// loadPromptsCmd reads the library for the overlay, which then puts its
+// cursor on keep.
+func loadPromptsCmd(keep string) tea.Cmd {
+	return func() tea.Msg {
+		prompts, err := loadPrompts()
+		return promptsLoadedMsg{prompts: prompts, err: err, keep: keep}
+	}
+}
+
+// savePromptCmd writes a new prompt to the library.
+func savePromptCmd(name, text string) tea.Cmd {
+	return func() tea.Msg {
+		err := os.MkdirAll(promptsDir(), 0o755)
+		if err == nil {
+			err = os.WriteFile(promptPath(name), []byte(text+"\n"), 0o644)
+		}
+		return promptSavedMsg{name: name, err: err}
+	}
+}
+
+// deletePromptCmd removes a prompt from the library.
+func deletePromptCmd(name string) tea.Cmd {
+	return func() tea.Msg {
+		return promptSavedMsg{name: name, deleted: true, err: os.Remove(promptPath(name))}
+	}
+}
+
+func (m appModel) openPromptLibrary() (appModel, tea.Cmd) {
+	m.promptLibrary = &promptLibraryView{loading: true}
+	return m, loadPromptsCmd(m.promptPick.name)
+}
+
+// showPrompts puts a read of the library in the overlay, if it is still
+// open, and refreshes the pick's text from it. A pick whose prompt was
+// deleted or renamed outside is dropped.
+func (m appModel) showPrompts(msg promptsLoadedMsg) appModel {
+	if msg.err == nil && m.promptPick.name != "" {
+		i := slices.IndexFunc(msg.prompts, func(p libraryPrompt) bool { return p.name == m.promptPick.name })
+		if i < 0 {
+			m.promptPick = promptPick{}
+		} else {
+			m.promptPick.text = msg.prompts[i].text
+		}
+	}
+	if m.promptLibrary == nil {
+		return m
+	}
+	v := &promptLibraryView{prompts: msg.prompts, err: msg.err}
+	for i, p := range msg.prompts {
+		if p.name == msg.keep {
+			v.cursor = i
+		}
+	}
+	m.promptLibrary = v
+	return m
+}
+
+// editPromptCmd suspends the TUI to edit a library prompt.
+func editPromptCmd(name string) tea.Cmd {
+	editor := templateEditor()
+	c := exec.Command(editor[0], append(editor[1:], promptPath(name))...)
+	return tea.ExecProcess(c, func(err error) tea.Msg {
+		return promptEditedMsg{name: name, err: err}
+	})
+}
+
+// newPromptForm names a new prompt, other than those in prompts, and gives
+// its first line; [e] edits the rest.
+func newPromptForm(prompts []libraryPrompt) formModel {
+	name := newTextField("name", "Name", "Letters, digits, '.', '-', and '_'; stored as "+promptsDirName+"/<name>"+promptExt+" in the config directory", "", func(s string) error {
+		s = strings.TrimSpace(s)
+		if !promptName.MatchString(s) {
+			return fmt.Errorf("letters, digits, '.', '-', and '_' only")
+		}
+		if slices.ContainsFunc(prompts, func(p libraryPrompt) bool { return p.name == s }) {
+			return fmt.Errorf("%s already exists", s)
+		}
+		return nil
+	})
+	text := newTextField("text", "Prompt", "The prompt text; [e] in the library opens it in $EDITOR for more lines", "", func(s string) error {
+		if strings.TrimSpace(s) == "" {
+			return fmt.Errorf("needs some text")
+		}
+		return nil
+	})
+	return newForm("New Prompt", []formField{name, text})
+}
+
+// addPrompt writes a prompt from the new prompt form.
+func (m appModel) addPrompt(values map[string]string) (appModel, tea.Cmd) {
+	name := strings.TrimSpace(values["name"])
+	m.statusLineText = "Saving prompt " + name + "..."
+	return m, savePromptCmd(name, strings.TrimSpace(values["text"]))
+}
+
+// promptSaved reports a prompt added or deleted and rereads the library.
+func (m appModel) promptSaved(msg promptSavedMsg) (appModel, tea.Cmd) {
+	switch {
+	case msg.err != nil && msg.deleted:
+		m.statusLineText = fmt.Sprintf("Prompt not deleted: %v", msg.err)
+	case msg.err != nil:
+		m.statusLineText = fmt.Sprintf("Prompt not saved: %v", msg.err)
+	case msg.deleted:
+		m.statusLineText = "Deleted prompt " + msg.name
+	default:
+		m.statusLineText = "Added prompt " + msg.name + " - [enter] uses it to warm up launches, [s] as the system prompt"
+	}
+	keep := msg.name
+	if msg.deleted {
+		keep = ""
+	}
+	return m, loadPromptsCmd(keep)
+}
+
+func (m appModel) handlePromptLibraryKey(keyStr string) (appModel, tea.Cmd) {
+	v := *m.promptLibrary
+	deleting := v.deleting
+	v.deleting = ""
+	var selected libraryPrompt
+	if len(v.prompts) > 0 {
+		selected = v.prompts[v.cursor]
+	}
+	switch keyStr {
+	case "up", "k":
+		v.cursor = max(v.cursor-1, 0)
+	case "down", "j":
+		v.cursor = min(v.cursor+1, max(len(v.prompts)-1, 0))
+	case "n":
+		form := newPromptForm(v.prompts)
+		m.form, m.formPurpose = &form, formPrompt
+	case "enter", "s":
+		if selected.name == "" {
+			m.statusLineText = "No prompts - [n] adds one"
+			break
+		}
+		pick := promptPick{name: selected.name, text: selected.text, system: keyStr == "s"}
+		if pick.name == m.promptPick.name && pick.system == m.promptPick.system {
+			m.promptPick = promptPick{}
+			m.statusLineText = "Launches use no library prompt"
+			break
+		}
+		if pick.system && m.proxy == nil {
+			m.statusLineText = "A system prompt needs proxy_port: it is added to chat requests through the proxy"
+			break
+		}
+		m.promptPick = pick
+		if pick.system {
+			m.statusLineText = "Chat requests through the proxy get " + pick.name + " as their system prompt"
+		} else {
+			m.statusLineText = "Launches warm up with " + pick.name + " once ready"
+		}
+	case "e":
+		if selected.name != "" {
+			m.promptLibrary = &v
+			return m, editPromptCmd(selected.name)
+		}
+	case "d":
+		if selected.name == "" {
+			break
+		}
+		if deleting != selected.name {
+			v.deleting = selected.name
+			m.statusLineText = "Press [d] again to delete " + selected.name
+			break
+		}
+		m.promptLibrary = &v
+		return m, deletePromptCmd(selected.name)
+	case "esc", "q", "ctrl+p":
+		m.promptLibrary = nil
+		return m, nil
+	}
+	m.promptLibrary = &v
+	return m, nil
+}
+
+// renderPromptLibrary lists the prompts with their first lines, marking
+// the one launches use.
+func (m appModel) renderPromptLibrary(width int) string {
+	v := m.promptLibrary
+	var lines []string
+	switch {
+	case v.loading:
+		lines = append(lines, m.styles.help.Render("Reading prompts..."))
+	case v.err != nil:
+		lines = append(lines, m.styles.logError.Render(v.err.Error()))
+	case len(v.prompts) == 0:
+		lines = append(lines, m.styles.help.Render("No prompts yet. [n] adds one; they are kept in "+promptsDir()))
+	}
+	nameWidth := 0
+	for _, p := range v.prompts {
+		nameWidth = max(nameWidth, len(p.name))
+	}
+	nameWidth = min(nameWidth, width/3)
+	for i, p := range v.prompts {
+		cursor := "  "
+		if i == v.cursor {
+			cursor = m.styles.accent.Render("▶ ")
+		}
+		use := "          "
+		if p.name == m.promptPick.name {
+			use = fmt.Sprintf("%-10s", "["+m.promptPick.use()+"]")
+		}
+		first, _, _ := strings.Cut(strings.TrimSpace(p.text), "\n")
+		row := fmt.Sprintf("%-*s  %s", nameWidth, ellipsize(p.name, nameWidth), use)
+		if i == v.cursor {
+			row = m.styles.accent.Render(row)
+		}
+		text := fmt.Sprintf("%s (%s)", first, pluralize(strings.Count(strings.TrimSpace(p.text), "\n")+1, "line"))
+		lines = append(lines, cursor+row+"  "+m.styles.help.Render(ellipsize(text, max(width-nameWidth-16, 10))))
+	}
+	footer := "[enter] warm up launches with it  [s] pass it as the system prompt  [n] new  [e] edit  [d] delete  [esc] close"
+	return strings.Join(lines, "\n") + "\n\n" + m.styles.help.Render(ellipsize(footer, width))
+}
+
+// warmupPrompt is what is sent once the server is ready: the picked
+// library prompt, else warmup_prompt.
+func (m appModel) warmupPrompt() string {
+	if m.servedPrompt.name != "" && !m.servedPrompt.system && strings.TrimSpace(m.servedPrompt.text) != "" {
+		return m.servedPrompt.text
+	}
+	return m.config.WarmupPrompt
+}
+
+// promptSegment notes the prompt launches use.
+func (m appModel) promptSegment() (statusSegment, bool) {
+	if m.promptPick.name == "" {
+		return statusSegment{}, false
+	}
+	return statusSegment{label: "Prompt: ", value: m.promptPick.describe(), style: m.styles.accent, priority: 5, truncatable: true, minWidth: 10}, true
+}
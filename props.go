@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// propsValueLimit caps how much of a changed value is shown; long values
+// such as chat templates are summarized instead.
+const propsValueLimit = 80
+
+type propsChange struct {
+	key      string
+	oldValue string
+	newValue string
+}
+
+// propsPath is where the last /props snapshot of a model is kept.
+func propsPath(modelName string) string {
+	cacheDir := getCacheDir()
+	if cacheDir == "" {
+		return ""
+	}
+	return filepath.Join(cacheDir, "props", sanitizeFileComponent(modelName)+".json")
+}
+
+// sanitizeFileComponent reduces s to characters safe in a file name.
+func sanitizeFileComponent(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+// fetchProps reads /props, raw and decoded.
+func fetchProps(ctx context.Context, base string) ([]byte, any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/props", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("/props: %s", resp.Status)
+	}
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	var props any
+	if err := json.Unmarshal(raw, &props); err != nil {
+		return nil, nil, fmt.Errorf("/props: %w", err)
+	}
+	return raw, props, nil
+}
+
+// captureProps fetches /props once the server is healthy, diffs it against
+// the previous run of the same model, and stores it for next time. The
+// served generation defaults are returned for the sampling panel.
+func captureProps(ctx context.Context, port, modelName string) ([]propsChange, bool, map[string]string, error) {
+	base := "http://127.0.0.1:" + port
+	if err := waitForHealthy(ctx, base); err != nil {
+		return nil, false, nil, err
+	}
+	raw, current, err := fetchProps(ctx, base)
+	if err != nil {
+		return nil, false, nil, err
+	}
+	defaults := generationDefaults(current)
+
+	path := propsPath(modelName)
+	if path == "" {
+		return nil, false, defaults, nil
+	}
+	var changes []propsChange
+	hadPrevious := false
+	if prevRaw, err := os.ReadFile(path); err == nil {
+		var previous any
+		if json.Unmarshal(prevRaw, &previous) == nil {
+			hadPrevious = true
+			changes = diffProps(flattenJSON(previous), flattenJSON(current))
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return changes, hadPrevious, defaults, err
+	}
+	return changes, hadPrevious, defaults, os.WriteFile(path, raw, 0o644)
+}
+
+// flattenJSON maps dotted key paths to scalar values rendered as JSON.
+func flattenJSON(v any) map[string]string {
+	out := make(map[string]string)
+	var walk func(prefix string, v any)
+	walk = func(prefix string, v any) {
+		switch t := v.(type) {
+		case map[string]any:
+			for k, child := range t {
+				key := k
+				if prefix != "" {
+					key = prefix + "." + k
+				}
+				walk(key, child)
+			}
+		case []any:
+			for i, child := range t {
+				walk(fmt.Sprintf("%s[%d]", prefix, i), child)
+			}
+		default:
+			b, _ := json.Marshal(t)
+			out[prefix] = string(b)
+		}
+	}
+	walk("", v)
+	return out
+}
+
+func diffProps(previous, current map[string]string) []propsChange {
+	var changes []propsChange
+	for k, nv := range current {
+		if ov, ok := previous[k]; !ok {
+			changes = append(changes, propsChange{key: k, newValue: nv})
+		} else if ov != nv {
+			changes = append(changes, propsChange{key: k, oldValue: ov, newValue: nv})
+		}
+	}
+	for k, ov := range previous {
+		if _, ok := current[k]; !ok {
+			changes = append(changes, propsChange{key: k, oldValue: ov})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].key < changes[j].key })
+	return changes
+}
+
+func summarizePropsValue(v string) string {
+	if len(v) > propsValueLimit {
+		return fmt.Sprintf("<%d chars>", len(v))
+	}
+	return v
+}
+
+func capturePropsCmd(root context.Context, port, modelName string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(root, 10*time.Minute)
+		defer cancel()
+		changes, hadPrevious, defaults, err := captureProps(ctx, port, modelName)
+		return propsDiffMsg{modelName: modelName, changes: changes, hadPrevious: hadPrevious, defaults: defaults, err: err}
+	}
+}
+
+// renderPropsDiff formats changes as colored log lines.
+func (m appModel) renderPropsDiff(msg propsDiffMsg) []string {
+	if !msg.hadPrevious {
+		return []string{m.styles.logInfo.Render("[props] Saved /props snapshot for future comparisons")}
+	}
+	if len(msg.changes) == 0 {
+		return []string{m.styles.logInfo.Render("[props] No changes in /props since the previous run")}
+	}
+	lines := []string{m.styles.logWarn.Render(fmt.Sprintf("[props] %d change(s) since the previous run:", len(msg.changes)))}
+	for _, c := range msg.changes {
+		switch {
+		case c.oldValue == "":
+			lines = append(lines, m.styles.propsAdded.Render("  + "+c.key+": "+summarizePropsValue(c.newValue)))
+		case c.newValue == "":
+			lines = append(lines, m.styles.propsRemoved.Render("  - "+c.key+": "+summarizePropsValue(c.oldValue)))
+		default:
+			lines = append(lines, m.styles.propsChanged.Render("  ~ "+c.key+": "+summarizePropsValue(c.oldValue)+" -> "+summarizePropsValue(c.newValue)))
+		}
+	}
+	return lines
+}
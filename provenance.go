@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// modelProvenance records where a downloaded model came from, kept in a
+// sidecar manifest next to the model so it travels with the file.
+type modelProvenance struct {
+	SourceURL    string    `json:"source_url"`
+	Revision     string    `json:"revision,omitempty"`
+	DownloadedAt time.Time `json:"downloaded_at"`
+	SHA256       string    `json:"sha256"`
+	Size         int64     `json:"size"`
+	// License and Gated are the Hugging Face repo's, when it said
+	License string `json:"license,omitempty"`
+	Gated   bool   `json:"gated,omitempty"`
+	// Scan is scan_command's verdict, when one was configured
+	Scan *scanResult `json:"scan,omitempty"`
+}
+
+// hfRevision finds the revision in a Hugging Face ".../resolve/<rev>/..." URL.
+var hfRevision = regexp.MustCompile(`/resolve/([^/]+)/`)
+
+func provenancePath(modelPath string) string {
+	return modelPath + ".provenance.json"
+}
+
+func loadProvenance(modelPath string) (*modelProvenance, error) {
+	data, err := os.ReadFile(provenancePath(modelPath))
+	if err != nil {
+		return nil, err
+	}
+	var p modelProvenance
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", provenancePath(modelPath), err)
+	}
+	return &p, nil
+}
+
+func saveProvenance(modelPath string, p modelProvenance) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(provenancePath(modelPath), append(data, '\n'), 0o644)
+}
+
+// provenanceLines describes a model's origin for reports.
+func provenanceLines(item modelItem) []string {
+	p := item.provenance
+	if p == nil {
+		return []string{"source: unknown (not downloaded through llama-tui)"}
+	}
+	lines := []string{"source: " + p.SourceURL}
+	if p.Revision != "" {
+		lines = append(lines, "revision: "+p.Revision)
+	}
+	lines = append(lines,
+		"downloaded: "+p.DownloadedAt.Format(time.RFC3339),
+		"sha256: "+p.SHA256,
+		fmt.Sprintf("size: %d", p.Size))
+	if p.Scan != nil {
+		lines = append(lines, "scan: "+p.Scan.describe()+" at "+p.Scan.ScannedAt.Format(time.RFC3339))
+	}
+	return lines
+}
+
+// findModelByName returns the listed model with exactly this name.
+func (m appModel) findModelByName(name string) (modelItem, bool) {
+	for _, it := range m.allModelItems() {
+		if mi, ok := it.(modelItem); ok && mi.name == name {
+			return mi, true
+		}
+	}
+	return modelItem{}, false
+}
+
+// exportDiagnosticsCmd writes the checklist and the provenance of the
+// selected and served models to a report in the cache directory, for
+// attaching to bug reports or eval notes.
+func (m appModel) exportDiagnosticsCmd() tea.Cmd {
+	var b strings.Builder
+	fmt.Fprintf(&b, "llama-tui %s diagnostics\n", version)
+	fmt.Fprintf(&b, "time: %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&b, "go: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&b, "barn: %s\n", m.barnDir)
+	b.WriteString("\n== checks ==\n")
+	for _, c := range m.diagnostics {
+		fmt.Fprintf(&b, "[%s] %s: %s\n", c.status, c.name, c.detail)
+		if c.hint != "" && c.status != checkPass {
+			fmt.Fprintf(&b, "    hint: %s\n", c.hint)
+		}
+	}
+	models := map[string]string{}
+	if mi, ok := m.modelsList.SelectedItem().(modelItem); ok {
+		models["selected"] = mi.name
+	}
+	if m.currentModelName != "" {
+		models["served"] = m.currentModelName
+	}
+	for _, role := range []string{"selected", "served"} {
+		name, ok := models[role]
+		if !ok || (role == "served" && name == models["selected"]) {
+			continue
+		}
+		fmt.Fprintf(&b, "\n== %s model: %s ==\n", role, name)
+		if mi, ok := m.findModelByName(name); ok {
+			fmt.Fprintf(&b, "path: %s\n", mi.path)
+			for _, line := range provenanceLines(mi) {
+				b.WriteString(line + "\n")
+			}
+		}
+	}
+	report := b.String()
+	return func() tea.Msg {
+		cacheDir := getCacheDir()
+		if cacheDir == "" {
+			return diagnosticsExportedMsg{err: fmt.Errorf("no cache directory available")}
+		}
+		if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+			return diagnosticsExportedMsg{err: err}
+		}
+		path := filepath.Join(cacheDir, "diagnostics-"+time.Now().Format("20060102-150405")+".txt")
+		return diagnosticsExportedMsg{path: path, err: os.WriteFile(path, []byte(report), 0o644)}
+	}
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// latencySampleLimit is how many recent requests the proxy remembers.
+const latencySampleLimit = 300
+
+// latencySample is one proxied request.
+type latencySample struct {
+	id       string // X-Request-Id sent to the server
+	method   string
+	path     string
+	at       time.Time
+	elapsed  time.Duration
+	status   int
+	inFlight int   // requests in flight when this one arrived, itself included
+	bytes    int64 // request body size, a rough measure of prompt length
+}
+
+// requestProxy gives clients one stable address that forwards to whichever
+// port the managed server is on, and records recent request latencies.
+// It is shared by pointer, so copies of the model see the same proxy.
+type requestProxy struct {
+	host     string
+	port     string
+	rp       *httputil.ReverseProxy
+	target   atomic.Value // string port; "" while nothing is served
+	inFlight atomic.Int32
+	seq      atomic.Uint64
+	// mirror is a second server's port that also receives each request,
+	// with both responses written to mirrorLog; "" disables mirroring
+	mirror    string
+	mirrorLog string
+	// limiter refuses requests over proxy_rate_limit; nil when unlimited
+	limiter *rateLimiter
+	// sampling holds the generation defaults filled into requests that
+	// leave them out, a map[string]any; nil for none
+	sampling atomic.Value
+	// systemPrompt is the library prompt added to chat requests without a
+	// system message of their own, a string; "" for none
+	systemPrompt atomic.Value
+
+	mu sync.Mutex
+	// held is closed when the next target is set; until heldUntil,
+	// requests arriving with no target wait on it instead of being refused
+	held          chan struct{}
+	heldUntil     time.Time
+	samples       []latencySample
+	next          int
+	mirrorSamples []mirrorSample
+	mirrorErr     error
+}
+
+// proxyTargetKey carries the port a request is forwarded to, chosen when
+// it arrives, through to the reverse proxy's Rewrite.
+type proxyTargetKey struct{}
+
+// newRequestProxy makes a proxy listening on host:port; host "" is
+// 127.0.0.1.
+func newRequestProxy(host, port string) *requestProxy {
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	p := &requestProxy{host: host, port: port}
+	p.target.Store("")
+	p.rp = &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			port, _ := pr.In.Context().Value(proxyTargetKey{}).(string)
+			pr.SetURL(&url.URL{Scheme: "http", Host: net.JoinHostPort("127.0.0.1", port)})
+		},
+		// Stream tokens as they are generated
+		FlushInterval: -1,
+	}
+	return p
+}
+
+// setTarget points the proxy at a server port, or "" to refuse requests,
+// releasing any held requests.
+func (p *requestProxy) setTarget(port string) {
+	if p == nil {
+		return
+	}
+	p.target.Store(port)
+	p.mu.Lock()
+	if p.held != nil {
+		close(p.held)
+		p.held = nil
+	}
+	p.mu.Unlock()
+}
+
+// holdRequests has requests wait up to d for the next target, while a
+// crashed server is relaunched, instead of refusing them.
+func (p *requestProxy) holdRequests(d time.Duration) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	if p.held == nil {
+		p.held = make(chan struct{})
+	}
+	p.heldUntil = time.Now().Add(d)
+	p.mu.Unlock()
+}
+
+// waitForTarget waits out a hold for the next target; "" when there is no
+// hold, it expires, or the client gives up.
+func (p *requestProxy) waitForTarget(r *http.Request) string {
+	p.mu.Lock()
+	held, until := p.held, p.heldUntil
+	p.mu.Unlock()
+	if held == nil {
+		return ""
+	}
+	timer := time.NewTimer(time.Until(until))
+	defer timer.Stop()
+	select {
+	case <-held:
+	case <-timer.C:
+	case <-r.Context().Done():
+	}
+	port, _ := p.target.Load().(string)
+	return port
+}
+
+// setSampling replaces the generation defaults filled into requests.
+func (p *requestProxy) setSampling(values map[string]any) {
+	if p != nil {
+		p.sampling.Store(values)
+	}
+}
+
+// setSystemPrompt replaces the system prompt added to chat requests.
+func (p *requestProxy) setSystemPrompt(text string) {
+	if p != nil {
+		p.systemPrompt.Store(text)
+	}
+}
+
+func (p *requestProxy) record(s latencySample) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.samples) < latencySampleLimit {
+		p.samples = append(p.samples, s)
+		return
+	}
+	p.samples[p.next] = s
+	p.next = (p.next + 1) % latencySampleLimit
+}
+
+// snapshot returns the remembered samples, oldest first.
+func (p *requestProxy) snapshot() []latencySample {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]latencySample, 0, len(p.samples))
+	out = append(out, p.samples[p.next:]...)
+	return append(out, p.samples[:p.next]...)
+}
+
+// statusRecorder captures the response status while keeping streaming
+// (server-sent events) flushing through to the client.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	// body keeps a copy of the response when the request is mirrored
+	body *cappedBuffer
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	if r.body != nil {
+		_, _ = r.body.Write(p)
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (p *requestProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	port, _ := p.target.Load().(string)
+	if port == "" {
+		port = p.waitForTarget(r)
+	}
+	if port == "" {
+		http.Error(w, "llama-tui: no model is being served", http.StatusServiceUnavailable)
+		return
+	}
+	if p.limiter != nil {
+		release, ok := p.limiter.limit(w, r)
+		if !ok {
+			return
+		}
+		defer release()
+	}
+	r = r.WithContext(context.WithValue(r.Context(), proxyTargetKey{}, port))
+	values, _ := p.sampling.Load().(map[string]any)
+	system, _ := p.systemPrompt.Load().(string)
+	if values != nil || system != "" {
+		fillRequestDefaults(r, values, system)
+	}
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	var body []byte
+	var mirrored chan mirrorResponse
+	if p.shouldMirror(r, port) {
+		var err error
+		if body, err = io.ReadAll(http.MaxBytesReader(w, r.Body, mirrorRequestLimit)); err != nil {
+			status := http.StatusBadRequest
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			http.Error(w, "llama-tui: reading request: "+err.Error(), status)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if req, cancel, err := p.mirrorRequest(r, body); err == nil {
+			rec.body = &cappedBuffer{}
+			mirrored = make(chan mirrorResponse, 1)
+			go func() { mirrored <- p.sendMirror(req, cancel) }()
+		}
+	}
+	id := r.Header.Get(requestIDHeader)
+	if id == "" {
+		id = fmt.Sprintf("ltui-%d", p.seq.Add(1))
+		r.Header.Set(requestIDHeader, id)
+	}
+	w.Header().Set(requestIDHeader, id)
+	inFlight := int(p.inFlight.Add(1))
+	defer p.inFlight.Add(-1)
+	start := time.Now()
+	p.rp.ServeHTTP(rec, r)
+	elapsed := time.Since(start)
+	p.record(latencySample{id: id, method: r.Method, path: r.URL.Path, at: start, elapsed: elapsed, status: rec.status, inFlight: inFlight, bytes: r.ContentLength})
+	if mirrored != nil {
+		primary := mirrorResponse{Port: port, Status: rec.status, LatencyMS: elapsed.Milliseconds(),
+			Body: rec.body.String(), Truncated: rec.body.truncated}
+		go p.recordMirror(start, r.URL.Path, body, primary, mirrored)
+	}
+}
+
+// serveProxyCmd listens for the lifetime of the app; it only returns if the
+// listener fails.
+func serveProxyCmd(p *requestProxy) tea.Cmd {
+	return func() tea.Msg {
+		srv := &http.Server{Addr: net.JoinHostPort(p.host, p.port), Handler: p}
+		err := srv.ListenAndServe()
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+		return proxyStoppedMsg{err: err}
+	}
+}
+
+// proxyStatsCmd samples the proxy once a second for the latency view.
+func proxyStatsCmd(p *requestProxy) tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		mirrorSamples, mirrorErr := p.mirrorSnapshot()
+		var rates []clientRate
+		var rejected int
+		if p.limiter != nil {
+			rates, rejected = p.limiter.snapshot(time.Now())
+		}
+		return proxyStatsMsg{samples: p.snapshot(), inFlight: int(p.inFlight.Load()), mirrorSamples: mirrorSamples, mirrorErr: mirrorErr, rates: rates, rejected: rejected}
+	})
+}
+
+// latencyBuckets are the histogram's upper bounds; the last is open-ended.
+var latencyBuckets = []struct {
+	limit time.Duration
+	label string
+}{
+	{100 * time.Millisecond, "<100ms"},
+	{250 * time.Millisecond, "<250ms"},
+	{500 * time.Millisecond, "<500ms"},
+	{time.Second, "<1s"},
+	{2 * time.Second, "<2s"},
+	{5 * time.Second, "<5s"},
+	{10 * time.Second, "<10s"},
+	{30 * time.Second, "<30s"},
+	{0, "30s+"},
+}
+
+func latencyBucket(d time.Duration) int {
+	for i, b := range latencyBuckets {
+		if b.limit == 0 || d < b.limit {
+			return i
+		}
+	}
+	return len(latencyBuckets) - 1
+}
+
+var heatGlyphs = []rune("▁▂▃▄▅▆▇█")
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(float64(len(sorted)-1)*p)]
+}
+
+func formatLatency(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	return fmt.Sprintf("%.1fs", d.Seconds())
+}
+
+// renderLatencyView shows a histogram of recent request latencies and a
+// strip of requests in arrival order, where height is latency, color is how
+// many requests were in flight, and the row below is request size. Below
+// them is the access log, from which a request's server lines are opened.
+func (m appModel) renderLatencyView(width int) string {
+	if m.proxy == nil {
+		return "The request proxy is off.\n\nSet \"proxy_port\" in the config file and point clients at it to record latencies.\n\nPress [H] or [esc] to close"
+	}
+	var b strings.Builder
+	target := m.currentPort
+	if target == "" {
+		target = "no server"
+	} else {
+		target = ":" + target
+	}
+	b.WriteString(m.styles.help.Render(fmt.Sprintf("proxy :%s → %s", m.proxy.port, target)) + "\n")
+	if m.proxy.mirror != "" {
+		b.WriteString(m.renderMirrorSummary() + "\n")
+	}
+	if m.proxy.limiter != nil {
+		b.WriteString("\n" + m.renderRateLimits(5))
+	}
+	b.WriteString("\n")
+	samples := m.latencySamples
+	if len(samples) == 0 {
+		b.WriteString("No requests yet.\n\n" + m.styles.help.Render("[H] or [esc] close"))
+		return b.String()
+	}
+
+	durations := make([]time.Duration, len(samples))
+	counts := make([]int, len(latencyBuckets))
+	var maxBytes int64
+	for i, s := range samples {
+		durations[i] = s.elapsed
+		counts[latencyBucket(s.elapsed)]++
+		if s.bytes > maxBytes {
+			maxBytes = s.bytes
+		}
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	b.WriteString(fmt.Sprintf("%s · p50 %s · p95 %s · max %s · %d in flight\n\n",
+		pluralize(len(samples), "request"), formatLatency(percentile(durations, 0.5)),
+		formatLatency(percentile(durations, 0.95)), formatLatency(durations[len(durations)-1]), m.proxyInFlight))
+
+	maxCount := 0
+	for _, c := range counts {
+		maxCount = max(maxCount, c)
+	}
+	barW := max(width-20, 10)
+	for i, bucket := range latencyBuckets {
+		bar := strings.Repeat("█", counts[i]*barW/maxCount)
+		if counts[i] > 0 && bar == "" {
+			bar = "▏"
+		}
+		b.WriteString(fmt.Sprintf("%7s ", bucket.label) + m.styles.accent.Render(bar) + m.styles.help.Render(fmt.Sprintf(" %d", counts[i])) + "\n")
+	}
+
+	b.WriteString("\n")
+	stripW := max(width-10, 10)
+	for start := 0; start < len(samples); start += stripW {
+		chunk := samples[start:min(start+stripW, len(samples))]
+		var lat, size strings.Builder
+		for _, s := range chunk {
+			glyph := string(heatGlyphs[min(latencyBucket(s.elapsed), len(heatGlyphs)-1)])
+			style := m.styles.servingBadge
+			switch {
+			case s.status >= 500:
+				glyph, style = "✕", m.styles.logError
+			case s.inFlight >= 3:
+				style = m.styles.usageCritical
+			case s.inFlight == 2:
+				style = m.styles.usageWarn
+			}
+			lat.WriteString(style.Render(glyph))
+			level := 0
+			if maxBytes > 0 && s.bytes > 0 {
+				level = int(s.bytes * int64(len(heatGlyphs)-1) / maxBytes)
+			}
+			size.WriteRune(heatGlyphs[level])
+		}
+		b.WriteString(m.styles.help.Render("latency ") + lat.String() + "\n")
+		b.WriteString(m.styles.help.Render("size    ") + m.styles.disabled.Render(size.String()) + "\n")
+	}
+	b.WriteString("\n" + m.styles.help.Render("oldest → newest; color = concurrent requests: ") +
+		m.styles.servingBadge.Render("1") + " " + m.styles.usageWarn.Render("2") + " " +
+		m.styles.usageCritical.Render("3+") + "; " + m.styles.logError.Render("✕") + m.styles.help.Render(" = server error"))
+	b.WriteString("\n\n" + m.styles.help.Render("Requests") + "\n")
+	b.WriteString(m.renderRequestList(width, 8))
+	b.WriteString("\n" + m.styles.help.Render("[↑/↓] select request  [enter] backend logs  [H] or [esc] close"))
+	return b.String()
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+// quickRows is how many matches the quick picker shows.
+const quickRows = 8
+
+// quickLauncher is `llama-tui quick`: a fuzzy model picker that launches
+// the pick and then shrinks to a one-line status instead of the dashboard.
+type quickLauncher struct {
+	input  textinput.Model
+	cursor int
+	// launched switches to the one-line status
+	launched bool
+	// autostart launches the query's model after the first scan when it
+	// names exactly one, as --start would
+	autostart bool
+	// last is the most recent launch, listed first and relaunched with its
+	// arguments
+	last lastLaunch
+}
+
+func newQuickLauncher(dir, query string) *quickLauncher {
+	in := textinput.New()
+	in.Prompt = "Model: "
+	in.Placeholder = "type to filter"
+	in.SetValue(query)
+	in.Focus()
+	last, _ := loadLastLaunch(dir)
+	return &quickLauncher{input: in, autostart: query != "", last: last}
+}
+
+// newQuickCmd is the quick launcher's entry point.
+func newQuickCmd() *cobra.Command {
+	o := runOptions{quick: true}
+	cmd := &cobra.Command{
+		Use:   "quick [model]",
+		Short: "Pick a model and serve it with a one-line status instead of the dashboard",
+		Long: "Shows a fuzzy model picker and starts serving the pick on the default port with its saved launch options " +
+			"(and the last launch's arguments when it is the same model). A model naming exactly one, as --start would, starts right away.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				o.quickQuery = args[0]
+			}
+			return runTUI(o)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&o.port, "port", "", "port to serve on (default "+defaultPort+")")
+	flags.BoolVar(&o.noColor, "no-color", false, "render without colors (also when NO_COLOR is set)")
+	flags.StringVar(&o.fakeServer, "fake-server", "", "launch a built-in fake llama-server instead of the real one, for development")
+	flags.Lookup("fake-server").NoOptDefVal = "on"
+	return cmd
+}
+
+// quickMatches are the local models matching the query, best first. With
+// no query, the last launched model leads.
+func (m appModel) quickMatches() []modelItem {
+	var models []modelItem
+	for _, it := range m.allModelItems() {
+		if mi, ok := it.(modelItem); ok && mi.remoteURL == "" {
+			models = append(models, mi)
+		}
+	}
+	query := strings.TrimSpace(m.quick.input.Value())
+	if query == "" {
+		for i, mi := range models {
+			if mi.name == m.quick.last.Model {
+				models = append(append([]modelItem{mi}, models[:i]...), models[i+1:]...)
+				break
+			}
+		}
+		return models
+	}
+	names := make([]string, len(models))
+	for i, mi := range models {
+		names[i] = mi.name
+	}
+	ranks := list.DefaultFilter(query, names)
+	matches := make([]modelItem, len(ranks))
+	for i, r := range ranks {
+		matches[i] = models[r.Index]
+	}
+	return matches
+}
+
+// quickAutostart launches the model named on the command line once the
+// models are listed, leaving the picker up when it is ambiguous.
+func (m appModel) quickAutostart() (appModel, tea.Cmd) {
+	q := *m.quick
+	q.autostart = false
+	m.quick = &q
+	item, err := findModelItem(m.allModelItems(), strings.TrimSpace(q.input.Value()))
+	if err != nil {
+		m.statusLineText = err.Error()
+		return m, nil
+	}
+	return m.quickLaunch(item)
+}
+
+// quickLaunch starts item as [enter] in the dashboard would.
+func (m appModel) quickLaunch(item modelItem) (appModel, tea.Cmd) {
+	q := *m.quick
+	q.launched = true
+	m.quick = &q
+	m.startFailure, m.oomRecovery = nil, nil
+	m.launchArgs, m.startupLaunch = nil, ""
+	if item.name == q.last.Model {
+		m.launchArgs = q.last.Args
+	}
+	m.reorderModels(item.path)
+	return m.requestStart(item)
+}
+
+// handleQuickKey handles the picker, and in the one-line status the keys
+// it offers; handled is false for those left to the dashboard's handling.
+func (m appModel) handleQuickKey(msg tea.KeyMsg) (next appModel, cmd tea.Cmd, handled bool) {
+	keyStr := msg.String()
+	if keyStr == "ctrl+c" {
+		return m, nil, false
+	}
+	q := *m.quick
+	if q.launched || m.server.busy() {
+		switch keyStr {
+		case "q":
+			if !m.server.serving() {
+				next, cmd := m.handleQuit()
+				return next, cmd, true
+			}
+			return m, nil, false
+		case "esc":
+			return m, nil, false
+		case "enter":
+			if m.confirmAction == confirmLaunch {
+				return m, nil, false
+			}
+			if !m.server.busy() {
+				// Back to the picker after the server stopped or failed
+				q.launched = false
+				m.quick = &q
+				m.statusLineText = ""
+			}
+		}
+		return m, nil, true
+	}
+	matches := m.quickMatches()
+	switch keyStr {
+	case "up", "ctrl+p":
+		q.cursor = max(q.cursor-1, 0)
+	case "down", "ctrl+n":
+		q.cursor = min(q.cursor+1, max(len(matches)-1, 0))
+	case "esc":
+		if q.input.Value() == "" {
+			next, cmd := m.handleQuit()
+			return next, cmd, true
+		}
+		q.input.SetValue("")
+		q.cursor = 0
+	case "enter":
+		if len(matches) == 0 {
+			m.statusLineText = "No model matches"
+			return m, nil, true
+		}
+		next, cmd := m.quickLaunch(matches[min(q.cursor, len(matches)-1)])
+		return next, cmd, true
+	default:
+		prev := q.input.Value()
+		q.input, cmd = q.input.Update(msg)
+		if q.input.Value() != prev {
+			q.cursor = 0
+		}
+	}
+	m.quick = &q
+	return m, cmd, true
+}
+
+// renderQuick draws the picker, or the one-line status once a model is
+// launched.
+func (m appModel) renderQuick() string {
+	width := m.width
+	if width <= 0 {
+		width = 80
+	}
+	q := m.quick
+	if q.launched || m.server.busy() {
+		chip, style := m.statusChip()
+		parts := []string{style.Render(chip)}
+		if m.currentModelName != "" && m.currentPort != "" {
+			parts = append(parts, m.styles.accent.Render(m.currentModelName+":"+m.currentPort))
+		}
+		keys := "[q] quit"
+		switch {
+		case m.server.serving():
+			keys = "[q] stop and quit"
+		case !m.server.busy():
+			keys = "[enter] pick another  [q] quit"
+		}
+		line := strings.Join(parts, " ")
+		status := ellipsize(m.statusLineText, max(width-lipgloss.Width(line)-lipgloss.Width(keys)-4, 10))
+		// Bubble Tea erases the last line on exit; this keeps the status
+		return line + " " + m.styles.status.Render(status) + "  " + m.styles.help.Render(keys) + "\n"
+	}
+	matches := m.quickMatches()
+	input := q.input
+	input.Width = max(width-len(input.Prompt)-2, 10)
+	lines := []string{input.View()}
+	start := max(q.cursor-quickRows+1, 0)
+	for i := start; i < len(matches) && i < start+quickRows; i++ {
+		mi := matches[i]
+		label := mi.name
+		if mi.name == q.last.Model {
+			label += " (last used)"
+		}
+		row := fmt.Sprintf("%s  %s", ellipsize(label, width-14), m.styles.help.Render(formatBytes(uint64(mi.size))))
+		if i == q.cursor {
+			lines = append(lines, m.styles.accent.Render("▶ ")+row)
+		} else {
+			lines = append(lines, "  "+row)
+		}
+	}
+	footer := "[esc] quit"
+	if len(matches) == 0 {
+		lines = append(lines, m.styles.disabled.Render("  no matching models"))
+	} else {
+		footer = fmt.Sprintf("[enter] serve on port %s  [↑/↓] select  [esc] quit", m.launchPort(matches[min(q.cursor, len(matches)-1)]))
+	}
+	lines = append(lines, m.styles.help.Render(ellipsize(footer, width)))
+	if m.statusLineText != "" {
+		lines = append(lines, m.styles.status.Render(ellipsize(m.statusLineText, width)))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// proxyRateLimit caps what clients may send through the request proxy.
+// Zero limits are off.
+type proxyRateLimit struct {
+	RequestsPerMinute int `json:"requests_per_minute"`
+	MaxConcurrent     int `json:"max_concurrent"`
+	// PerClient applies the limits to each client address separately
+	// instead of to all clients together
+	PerClient bool `json:"per_client"`
+}
+
+func (l proxyRateLimit) enabled() bool {
+	return l.RequestsPerMinute > 0 || l.MaxConcurrent > 0
+}
+
+func (l proxyRateLimit) validate() error {
+	if l.RequestsPerMinute < 0 || l.MaxConcurrent < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	return nil
+}
+
+// describe summarizes the limits, e.g. "30/min, 2 at once per client".
+func (l proxyRateLimit) describe() string {
+	var parts []string
+	if l.RequestsPerMinute > 0 {
+		parts = append(parts, fmt.Sprintf("%d/min", l.RequestsPerMinute))
+	}
+	if l.MaxConcurrent > 0 {
+		parts = append(parts, fmt.Sprintf("%d at once", l.MaxConcurrent))
+	}
+	s := strings.Join(parts, ", ")
+	if l.PerClient {
+		return s + " per client"
+	}
+	return s + " for all clients"
+}
+
+// rateIdleTimeout is how long a client's counters are kept after its last
+// request, so every address ever seen isn't held for good.
+const rateIdleTimeout = 10 * time.Minute
+
+// rateWindow is the recent traffic of one client, or of all of them.
+type rateWindow struct {
+	recent   []time.Time // admitted in the last minute, oldest first
+	inFlight int
+	allowed  int
+	rejected int
+	last     time.Time
+}
+
+// rateLimiter admits proxy requests within a proxyRateLimit. It is shared
+// by the proxy's handlers and sampled for the TUI.
+type rateLimiter struct {
+	limits proxyRateLimit
+
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+	// rejected counts refusals since the proxy started, including those
+	// of pruned windows
+	rejected int
+	pruned   time.Time
+}
+
+func newRateLimiter(limits proxyRateLimit) *rateLimiter {
+	return &rateLimiter{limits: limits, windows: make(map[string]*rateWindow)}
+}
+
+// acquire admits a request from client, returning the func that ends it,
+// or nil with why it was refused and when to retry.
+func (l *rateLimiter) acquire(client string, now time.Time) (release func(), reason string, retryAfter time.Duration) {
+	key := ""
+	if l.limits.PerClient {
+		key = client
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune(now)
+	w := l.windows[key]
+	if w == nil {
+		w = &rateWindow{}
+		l.windows[key] = w
+	}
+	w.last = now
+	cutoff := now.Add(-time.Minute)
+	drop := 0
+	for drop < len(w.recent) && !w.recent[drop].After(cutoff) {
+		drop++
+	}
+	w.recent = w.recent[drop:]
+	switch {
+	case l.limits.MaxConcurrent > 0 && w.inFlight >= l.limits.MaxConcurrent:
+		w.rejected++
+		l.rejected++
+		return nil, fmt.Sprintf("%d requests already in progress", w.inFlight), time.Second
+	case l.limits.RequestsPerMinute > 0 && len(w.recent) >= l.limits.RequestsPerMinute:
+		w.rejected++
+		l.rejected++
+		return nil, fmt.Sprintf("%d requests in the last minute", len(w.recent)), w.recent[0].Add(time.Minute).Sub(now)
+	}
+	w.recent = append(w.recent, now)
+	w.inFlight++
+	w.allowed++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			w.inFlight--
+			l.mu.Unlock()
+		})
+	}, "", 0
+}
+
+// prune drops the windows of clients idle for rateIdleTimeout, at most
+// once a minute. l.mu is held.
+func (l *rateLimiter) prune(now time.Time) {
+	if now.Sub(l.pruned) < time.Minute {
+		return
+	}
+	l.pruned = now
+	for client, w := range l.windows {
+		if w.inFlight == 0 && now.Sub(w.last) > rateIdleTimeout {
+			delete(l.windows, client)
+		}
+	}
+}
+
+// limit writes a 429 for r when it is over the limits; otherwise the
+// returned func must be called when the request ends.
+func (l *rateLimiter) limit(w http.ResponseWriter, r *http.Request) (func(), bool) {
+	release, reason, retry := l.acquire(clientAddress(r), time.Now())
+	if release == nil {
+		w.Header().Set("Retry-After", strconv.Itoa(max(int(math.Ceil(retry.Seconds())), 1)))
+		http.Error(w, "llama-tui: rate limit exceeded: "+reason, http.StatusTooManyRequests)
+		return nil, false
+	}
+	return release, true
+}
+
+// clientAddress is the host a request came from.
+func clientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// clientRate is one row of the rate limit table.
+type clientRate struct {
+	client     string // "" for all clients together
+	lastMinute int
+	inFlight   int
+	allowed    int
+	rejected   int
+	last       time.Time
+}
+
+// snapshot returns each window's counters, busiest first, and the
+// refusals since the proxy started.
+func (l *rateLimiter) snapshot(now time.Time) ([]clientRate, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune(now)
+	cutoff := now.Add(-time.Minute)
+	out := make([]clientRate, 0, len(l.windows))
+	for client, w := range l.windows {
+		n := 0
+		for _, t := range w.recent {
+			if t.After(cutoff) {
+				n++
+			}
+		}
+		out = append(out, clientRate{client: client, lastMinute: n, inFlight: w.inFlight, allowed: w.allowed, rejected: w.rejected, last: w.last})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].lastMinute != out[j].lastMinute {
+			return out[i].lastMinute > out[j].lastMinute
+		}
+		return out[i].client < out[j].client
+	})
+	return out, l.rejected
+}
+
+// renderRateLimits is the latency view's table of limited clients.
+func (m appModel) renderRateLimits(limit int) string {
+	limits := m.proxy.limiter.limits
+	var b strings.Builder
+	b.WriteString(m.styles.help.Render("rate limit "+limits.describe()) + "\n")
+	if len(m.proxyRates) == 0 {
+		b.WriteString(m.styles.help.Render("no requests yet") + "\n")
+		return b.String()
+	}
+	for i, r := range m.proxyRates {
+		if i == limit {
+			b.WriteString(m.styles.help.Render(fmt.Sprintf("… %s more", pluralize(len(m.proxyRates)-limit, "client"))) + "\n")
+			break
+		}
+		client := r.client
+		if client == "" {
+			client = "all clients"
+		}
+		line := fmt.Sprintf("%-20s %3d/min  %2d in flight  %5d allowed  ", ellipsize(client, 20), r.lastMinute, r.inFlight, r.allowed)
+		rejected := fmt.Sprintf("%d rejected", r.rejected)
+		style := m.styles.help
+		if r.rejected > 0 {
+			style = m.styles.usageWarn
+		}
+		b.WriteString(line + style.Render(rejected) + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+const defaultReadyTimeout = 90 * time.Second
+
+// readinessProbe decides when a launched server counts as ready. The config
+// sets the default; a preset can override any field for its launches.
+type readinessProbe struct {
+	// Method is "tcp" (default), ready once the port accepts connections,
+	// or "http", ready once GET /health answers 200 (the model has loaded;
+	// whisper-server is asked for / instead)
+	Method string `json:"method,omitempty"`
+	// Addresses are the hosts (or host:port) probed. By default the --host
+	// the server binds to, or 127.0.0.1 and ::1 when it binds to loopback
+	// or all interfaces.
+	Addresses      []string `json:"addresses,omitempty"`
+	IntervalMS     int      `json:"interval_ms,omitempty"`
+	TimeoutSeconds int      `json:"timeout_seconds,omitempty"`
+	// healthPath is the endpoint "http" asks, set by the kind of server
+	healthPath string
+}
+
+// with overlays the fields set in o.
+func (p readinessProbe) with(o *readinessProbe) readinessProbe {
+	if o == nil {
+		return p
+	}
+	if o.Method != "" {
+		p.Method = o.Method
+	}
+	if len(o.Addresses) > 0 {
+		p.Addresses = o.Addresses
+	}
+	if o.IntervalMS > 0 {
+		p.IntervalMS = o.IntervalMS
+	}
+	if o.TimeoutSeconds > 0 {
+		p.TimeoutSeconds = o.TimeoutSeconds
+	}
+	return p
+}
+
+func (p readinessProbe) validate() error {
+	switch p.Method {
+	case "", "tcp", "http":
+		return nil
+	}
+	return fmt.Errorf("readiness method must be \"tcp\" or \"http\", not %q", p.Method)
+}
+
+func (p readinessProbe) interval() time.Duration {
+	if p.IntervalMS > 0 {
+		return time.Duration(p.IntervalMS) * time.Millisecond
+	}
+	return 500 * time.Millisecond
+}
+
+// timeout is how long to wait, with fallback used when none is configured.
+func (p readinessProbe) timeout(fallback time.Duration) time.Duration {
+	if p.TimeoutSeconds > 0 {
+		return time.Duration(p.TimeoutSeconds) * time.Second
+	}
+	return fallback
+}
+
+// targets resolves the addresses to probe for a server launched as argv.
+func (p readinessProbe) targets(argv []string, port string) []string {
+	hosts := p.Addresses
+	if len(hosts) == 0 {
+		hosts = []string{"127.0.0.1", "::1"}
+		for _, f := range parseFlagArgs(argv) {
+			if f.name != "--host" {
+				continue
+			}
+			switch f.value {
+			case "", "0.0.0.0", "::", "localhost", "127.0.0.1":
+			default:
+				// Bound to one interface; loopback would never answer
+				hosts = []string{f.value}
+			}
+		}
+	}
+	out := make([]string, 0, len(hosts))
+	for _, h := range hosts {
+		if _, _, err := net.SplitHostPort(h); err == nil {
+			out = append(out, h)
+			continue
+		}
+		out = append(out, net.JoinHostPort(h, port))
+	}
+	return out
+}
+
+// check makes one attempt against addr.
+func (p readinessProbe) check(addr string) bool {
+	timeout := 500 * time.Millisecond
+	if p.Method == "http" {
+		client := http.Client{Timeout: 2 * time.Second}
+		path := p.healthPath
+		if path == "" {
+			path = "/health"
+		}
+		resp, err := client.Get("http://" + addr + path)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
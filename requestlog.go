@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// requestIDHeader carries the proxy's ID for each request to the server
+// and back to the client. A client's own ID is kept.
+const requestIDHeader = "X-Request-Id"
+
+// serverLineLimit is how many timed server log lines are kept for
+// correlating with proxied requests.
+const serverLineLimit = 5000
+
+// Server lines arrive a moment after the work they describe, and the
+// access line comes after the response is sent.
+const (
+	requestLogLead  = 100 * time.Millisecond
+	requestLogTrail = 500 * time.Millisecond
+)
+
+// timedLogLine is a server log line with the time it was read.
+type timedLogLine struct {
+	at   time.Time
+	text string
+}
+
+// requestLogView is the backend log lines matched to one proxied request.
+type requestLogView struct {
+	sample     latencySample
+	lines      []timedLogLine
+	overlapped int  // other requests in flight at the same time
+	partial    bool // the request began before the oldest kept line
+	offset     int
+}
+
+// llama-server does not log request headers, so lines are matched by time
+// and by the task number a slot gives each request.
+var (
+	taskIDPattern     = regexp.MustCompile(`\btask (\d+)\b`)
+	taskLaunchPattern = regexp.MustCompile(`launch_slot_.*\btask (\d+)\b`)
+	accessLinePattern = regexp.MustCompile(`log_server_r.*request: `)
+)
+
+// noteServerLine keeps the managed server's line with its arrival time.
+func (m *appModel) noteServerLine(text string) {
+	m.serverLines = append(m.serverLines, timedLogLine{at: time.Now(), text: ansiEscape.ReplaceAllString(sanitizeLogLine(text), "")})
+	if len(m.serverLines) > serverLineLimit {
+		m.serverLines = append([]timedLogLine(nil), m.serverLines[len(m.serverLines)-serverLineLimit/2:]...)
+	}
+}
+
+func (s latencySample) window() (time.Time, time.Time) {
+	return s.at.Add(-requestLogLead), s.at.Add(s.elapsed + requestLogTrail)
+}
+
+// requestTasks hands each slot task launched to the earliest request that
+// was in flight at the time and has no task yet.
+func requestTasks(samples []latencySample, lines []timedLogLine) map[string]string {
+	byStart := append([]latencySample(nil), samples...)
+	sort.SliceStable(byStart, func(i, j int) bool { return byStart[i].at.Before(byStart[j].at) })
+	tasks := map[string]string{}
+	for _, l := range lines {
+		match := taskLaunchPattern.FindStringSubmatch(l.text)
+		if match == nil {
+			continue
+		}
+		for _, s := range byStart {
+			from, _ := s.window()
+			if _, taken := tasks[s.id]; taken || l.at.Before(from) || l.at.After(s.at.Add(s.elapsed)) {
+				continue
+			}
+			tasks[s.id] = match[1]
+			break
+		}
+	}
+	return tasks
+}
+
+// correlateRequest picks the server lines that belong to sample. Lines
+// naming a task go to the request that task was handed to. Other lines
+// are only attributed when no other request overlapped, except the
+// server's access line for the same path.
+func correlateRequest(sample latencySample, samples []latencySample, lines []timedLogLine) requestLogView {
+	v := requestLogView{sample: sample}
+	from, to := sample.window()
+	for _, s := range samples {
+		if s.id == sample.id {
+			continue
+		}
+		if f, t := s.window(); f.Before(to) && t.After(from) {
+			v.overlapped++
+		}
+	}
+	v.partial = len(lines) == 0 || lines[0].at.After(from)
+	task := requestTasks(samples, lines)[sample.id]
+	for _, l := range lines {
+		if l.at.Before(from) || l.at.After(to) {
+			continue
+		}
+		if match := taskIDPattern.FindStringSubmatch(l.text); match != nil {
+			if match[1] == task {
+				v.lines = append(v.lines, l)
+			}
+			continue
+		}
+		if v.overlapped == 0 || (accessLinePattern.MatchString(l.text) && strings.Contains(l.text, " "+sample.path+" ")) {
+			v.lines = append(v.lines, l)
+		}
+	}
+	return v
+}
+
+// selectedRequest is the request highlighted in the latency view, the
+// newest when none has been picked or it has aged out.
+func (m appModel) selectedRequest() int {
+	for i := len(m.latencySamples) - 1; i >= 0; i-- {
+		if m.latencySamples[i].id == m.latencySelected {
+			return i
+		}
+	}
+	return len(m.latencySamples) - 1
+}
+
+// handleLatencyKey moves through the latency view's request list and
+// opens a request's backend lines. ok is false for keys it leaves to the
+// main handler.
+func (m appModel) handleLatencyKey(keyStr string) (next appModel, cmd tea.Cmd, ok bool) {
+	if len(m.latencySamples) == 0 {
+		return m, nil, false
+	}
+	i := m.selectedRequest()
+	switch keyStr {
+	case "up", "k":
+		i = max(i-1, 0)
+	case "down", "j":
+		i = min(i+1, len(m.latencySamples)-1)
+	case "home", "g":
+		i = 0
+	case "end":
+		i = len(m.latencySamples) - 1
+	case "enter":
+		v := correlateRequest(m.latencySamples[i], m.latencySamples, m.serverLines)
+		m.requestLogs = &v
+		return m, nil, true
+	default:
+		return m, nil, false
+	}
+	m.latencySelected = m.latencySamples[i].id
+	return m, nil, true
+}
+
+func (m appModel) handleRequestLogsKey(keyStr string) (appModel, tea.Cmd) {
+	v := *m.requestLogs
+	height := m.requestLogsHeight()
+	switch keyStr {
+	case "up", "k":
+		v.offset = max(v.offset-1, 0)
+	case "down", "j":
+		v.offset++
+	case "pgup":
+		v.offset = max(v.offset-height, 0)
+	case "pgdown", " ":
+		v.offset += height
+	case "esc", "enter", "q":
+		m.requestLogs = nil
+		return m, nil
+	}
+	v.offset = min(v.offset, max(len(v.lines)-height, 0))
+	m.requestLogs = &v
+	return m, nil
+}
+
+// requestLogsHeight is how many log lines fit the drill-down overlay.
+func (m appModel) requestLogsHeight() int {
+	return max(m.height-12, 5)
+}
+
+// requestLine is one access log entry in the latency view.
+func (m appModel) requestLine(s latencySample) string {
+	return fmt.Sprintf("%s  %s  %s %s  %d  %s", m.config.Timestamps.in(s.at).Format("15:04:05"),
+		s.id, s.method, s.path, s.status, formatLatency(s.elapsed))
+}
+
+// renderRequestList is the tail of the access log around the selected
+// request.
+func (m appModel) renderRequestList(width, rows int) string {
+	samples := m.latencySamples
+	sel := m.selectedRequest()
+	start := max(min(sel-rows/2, len(samples)-rows), 0)
+	var b strings.Builder
+	for i := start; i < min(start+rows, len(samples)); i++ {
+		line := ellipsize(m.requestLine(samples[i]), width-2)
+		switch {
+		case i == sel:
+			line = m.styles.accent.Render("› " + line)
+		case samples[i].status >= 500:
+			line = "  " + m.styles.logError.Render(line)
+		default:
+			line = "  " + line
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+func (m appModel) renderRequestLogs(width int) string {
+	v := m.requestLogs
+	footer := m.styles.help.Render("[j/k] scroll  [enter] or [esc] back to requests")
+	header := ellipsize(m.requestLine(v.sample), width)
+	var notes []string
+	if v.overlapped > 0 {
+		notes = append(notes, fmt.Sprintf("%s overlapped; lines not tied to this request's slot task are left out", pluralize(v.overlapped, "other request")))
+	}
+	if v.partial {
+		notes = append(notes, "the request is older than the server output still kept; some lines may be missing")
+	}
+	for _, n := range notes {
+		header += "\n" + m.styles.help.Render(ellipsize(n, width))
+	}
+	if len(v.lines) == 0 {
+		return header + "\n\n" + m.styles.disabled.Render("No server lines matched this request") + "\n\n" + footer
+	}
+	height := m.requestLogsHeight()
+	offset := min(v.offset, max(len(v.lines)-height, 0))
+	shown := v.lines[offset:min(offset+height, len(v.lines))]
+	rows := make([]string, len(shown))
+	for i, l := range shown {
+		// Offset from the request's arrival
+		stamp := fmt.Sprintf("+%-7s ", formatLatency(max(l.at.Sub(v.sample.at), 0)))
+		rows[i] = m.styles.help.Render(stamp) + m.colorLog(ellipsize(l.text, width-len(stamp)))
+	}
+	if len(v.lines) > height {
+		header += "\n" + m.styles.help.Render(fmt.Sprintf("(%d-%d of %d lines)", offset+1, offset+len(shown), len(v.lines)))
+	}
+	return header + "\n\n" + strings.Join(rows, "\n") + "\n\n" + footer
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shirou/gopsutil/v4/mem"
+)
+
+// resourceReservation is the memory a server is expected to hold, as a
+// preset declares it, or the machine's capacity.
+type resourceReservation struct {
+	VRAMGB float64 `json:"vram_gb,omitempty"`
+	RAMGB  float64 `json:"ram_gb,omitempty"`
+}
+
+func (r resourceReservation) zero() bool {
+	return r.VRAMGB <= 0 && r.RAMGB <= 0
+}
+
+func (r resourceReservation) plus(o resourceReservation) resourceReservation {
+	return resourceReservation{VRAMGB: r.VRAMGB + max(o.VRAMGB, 0), RAMGB: r.RAMGB + max(o.RAMGB, 0)}
+}
+
+func formatGB(gb float64) string {
+	return strconv.FormatFloat(gb, 'f', -1, 64) + " GB"
+}
+
+// hasReservations reports whether any preset reserves resources, so the
+// capacity is worth detecting.
+func (c appConfig) hasReservations() bool {
+	for _, p := range c.Presets {
+		if p.Reserve != nil && !p.Reserve.zero() {
+			return true
+		}
+	}
+	return false
+}
+
+// detectCapacityCmd reads the machine's total RAM and NVIDIA VRAM; the
+// configured capacity replaces either. Zero leaves that resource unchecked.
+func detectCapacityCmd(override resourceReservation) tea.Cmd {
+	return func() tea.Msg {
+		c := override
+		if c.RAMGB <= 0 {
+			if vm, err := mem.VirtualMemory(); err == nil {
+				c.RAMGB = float64(vm.Total) / (1 << 30)
+			}
+		}
+		if c.VRAMGB <= 0 {
+			if vram, ok := totalVRAM(); ok {
+				c.VRAMGB = float64(vram) / (1 << 30)
+			}
+		}
+		return capacityDetectedMsg{capacity: c}
+	}
+}
+
+// totalVRAM sums the memory of NVIDIA GPUs, like freeVRAM.
+func totalVRAM() (uint64, bool) {
+	if runtime.GOOS == "darwin" {
+		return 0, false
+	}
+	if _, err := exec.LookPath("nvidia-smi"); err != nil {
+		return 0, false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "nvidia-smi", "--query-gpu=memory.total", "--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return 0, false
+	}
+	var total uint64
+	for _, line := range strings.Fields(string(out)) {
+		mib, err := strconv.ParseUint(line, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		total += mib << 20
+	}
+	return total, total > 0
+}
+
+// reservationFor is what serving item holds: the reservation of the preset
+// it was launched from, else of the first preset by name that launches it.
+func (m appModel) reservationFor(item modelItem, preset string) resourceReservation {
+	if p, ok := m.config.Presets[preset]; ok && p.Reserve != nil {
+		return *p.Reserve
+	}
+	names := make([]string, 0, len(m.config.Presets))
+	for name, p := range m.config.Presets {
+		if p.Reserve != nil && !p.Reserve.zero() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	items := m.allModelItems()
+	for _, name := range names {
+		p := m.config.Presets[name]
+		if found, err := findModelItem(items, p.Model); err == nil && found.path == item.path {
+			return *p.Reserve
+		}
+	}
+	return resourceReservation{}
+}
+
+// reservedResources sums the reservations of the servers llama-tui runs,
+// with the names of those holding any.
+func (m appModel) reservedResources() (resourceReservation, []string) {
+	var total resourceReservation
+	var holders []string
+	add := func(item modelItem, preset string) {
+		if r := m.reservationFor(item, preset); !r.zero() {
+			total = total.plus(r)
+			holders = append(holders, item.name)
+		}
+	}
+	if m.server.busy() && m.attached == nil {
+		if item, ok := m.findModelByName(m.currentModelName); ok {
+			add(item, m.launchPreset)
+		}
+	}
+	for _, s := range m.sideServers {
+		if s.state != serverStopped && s.state != serverCrashed {
+			add(s.item, "")
+		}
+	}
+	if m.standby != nil {
+		add(m.standby.item, "")
+	}
+	return total, holders
+}
+
+// admission is the scheduler's answer for a launch.
+type admission int
+
+const (
+	admitLaunch admission = iota
+	// admitQueue waits for running servers to free their reservations
+	admitQueue
+	// admitRefuse can never fit
+	admitRefuse
+)
+
+// admit decides whether a launch of item fits beside the servers already
+// reserving resources, explaining why not.
+func (m appModel) admit(item modelItem, preset string) (admission, string) {
+	need := m.reservationFor(item, preset)
+	if need.zero() {
+		return admitLaunch, ""
+	}
+	capacity := m.capacity
+	if need.VRAMGB > 0 && capacity.VRAMGB > 0 && need.VRAMGB > capacity.VRAMGB {
+		return admitRefuse, fmt.Sprintf("%s reserves %s VRAM, more than the %s here", item.name, formatGB(need.VRAMGB), formatGB(roundGB(capacity.VRAMGB)))
+	}
+	if need.RAMGB > 0 && capacity.RAMGB > 0 && need.RAMGB > capacity.RAMGB {
+		return admitRefuse, fmt.Sprintf("%s reserves %s RAM, more than the %s here", item.name, formatGB(need.RAMGB), formatGB(roundGB(capacity.RAMGB)))
+	}
+	used, holders := m.reservedResources()
+	short := func(name string, used, need, capacity float64) string {
+		if need <= 0 || capacity <= 0 || used+need <= capacity {
+			return ""
+		}
+		return fmt.Sprintf("%s needs %s %s, %s of %s reserved by %s", item.name, formatGB(need), name, formatGB(roundGB(used)), formatGB(roundGB(capacity)), strings.Join(holders, ", "))
+	}
+	if reason := short("VRAM", used.VRAMGB, need.VRAMGB, capacity.VRAMGB); reason != "" {
+		return admitQueue, reason
+	}
+	if reason := short("RAM", used.RAMGB, need.RAMGB, capacity.RAMGB); reason != "" {
+		return admitQueue, reason
+	}
+	return admitLaunch, ""
+}
+
+func roundGB(gb float64) float64 {
+	return float64(int(gb*10+0.5)) / 10
+}
+
+// schedule admits a launch of item, else refuses or queues it; a local
+// model launched now leaves the queue.
+func (m appModel) schedule(item modelItem, preset string) (appModel, bool) {
+	if item.remoteURL != "" {
+		// Checked once downloaded
+		return m, true
+	}
+	switch verdict, reason := m.admit(item, preset); verdict {
+	case admitRefuse:
+		m.statusLineText = "Not launching: " + reason
+		m.eventError("queue", item.name, "Refused: "+reason)
+		return m, false
+	case admitQueue:
+		return m.queueLaunch(item, preset, reason), false
+	}
+	for i, q := range m.launchQueue {
+		if q.item.path == item.path {
+			m.launchQueue = append(m.launchQueue[:i:i], m.launchQueue[i+1:]...)
+			break
+		}
+	}
+	return m, true
+}
+
+// queuedLaunch is a launch waiting on the queue. A preset launch keeps the
+// preset's options, which later launches may have replaced by the time it
+// starts.
+type queuedLaunch struct {
+	item      modelItem
+	preset    string
+	args      []string
+	readiness *readinessProbe
+}
+
+// queued reports whether item is waiting on the launch queue.
+func (m appModel) queued(item modelItem) bool {
+	for _, q := range m.launchQueue {
+		if q.item.path == item.path {
+			return true
+		}
+	}
+	return false
+}
+
+// queueLaunch holds item until the servers reserving resources free
+// enough; the same model again takes it off the queue.
+func (m appModel) queueLaunch(item modelItem, preset, reason string) appModel {
+	for i, q := range m.launchQueue {
+		if q.item.path == item.path {
+			m.launchQueue = append(m.launchQueue[:i:i], m.launchQueue[i+1:]...)
+			m.statusLineText = "Took " + item.name + " off the launch queue"
+			return m
+		}
+	}
+	q := queuedLaunch{item: item, preset: preset}
+	if preset != "" {
+		q.args, q.readiness = m.launchArgs, m.launchReadiness
+	}
+	m.launchQueue = append(m.launchQueue[:len(m.launchQueue):len(m.launchQueue)], q)
+	m.statusLineText = fmt.Sprintf("Queued: %s - starts when a server stops (enter on it again to unqueue)", reason)
+	m.event("queue", item.name, "Queued: "+reason)
+	return m
+}
+
+// runLaunchQueue starts the first queued launch that now fits, once the
+// servers holding reservations have changed.
+func (m appModel) runLaunchQueue() (appModel, tea.Cmd) {
+	if m.server == serverStarting || m.server.stopping() {
+		return m, nil
+	}
+	for _, q := range m.launchQueue {
+		if verdict, _ := m.admit(q.item, q.preset); verdict != admitLaunch {
+			continue
+		}
+		m.logEvent(fmt.Sprintf("[queue] Starting %s now that it fits", q.item.name))
+		if q.preset != "" {
+			// Kept for this model only, as a startup preset's are
+			m.launchArgs, m.launchReadiness, m.launchPreset = q.args, q.readiness, q.preset
+			m.startupLaunch = q.item.name
+		}
+		return m.requestStart(q.item)
+	}
+	return m, nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// cronSchedule is a five-field cron expression: minute, hour, day of
+// month, month, and day of week (0 or 7 is Sunday). Fields take "*",
+// numbers, ranges (1-5), lists (1,15), and steps (*/6, 0-30/10).
+type cronSchedule struct {
+	spec    string
+	minute  []bool
+	hour    []bool
+	dom     []bool
+	month   []bool
+	dow     []bool
+	anyDOM  bool
+	anyDOW  bool
+	enabled bool
+}
+
+// cronNicknames are the usual shorthands.
+var cronNicknames = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@nightly": "0 4 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+func parseCronSchedule(spec string) (cronSchedule, error) {
+	s := cronSchedule{spec: strings.TrimSpace(spec)}
+	if s.spec == "" {
+		return s, nil
+	}
+	expr := s.spec
+	if full, ok := cronNicknames[expr]; ok {
+		expr = full
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return s, fmt.Errorf("%q: want five fields (minute hour day month weekday), e.g. \"0 4 * * *\"", spec)
+	}
+	var err error
+	for i, f := range []struct {
+		dst    *[]bool
+		lo, hi int
+		name   string
+	}{
+		{&s.minute, 0, 59, "minute"},
+		{&s.hour, 0, 23, "hour"},
+		{&s.dom, 1, 31, "day of month"},
+		{&s.month, 1, 12, "month"},
+		{&s.dow, 0, 7, "day of week"},
+	} {
+		if *f.dst, err = parseCronField(fields[i], f.lo, f.hi); err != nil {
+			return s, fmt.Errorf("%q: %s: %w", spec, f.name, err)
+		}
+	}
+	// Sunday is both 0 and 7
+	s.dow[0] = s.dow[0] || s.dow[7]
+	s.anyDOM, s.anyDOW = fields[2] == "*", fields[4] == "*"
+	s.enabled = true
+	if s.next(time.Now()).IsZero() {
+		return cronSchedule{spec: s.spec}, fmt.Errorf("%q never comes around", spec)
+	}
+	return s, nil
+}
+
+func parseCronField(field string, lo, hi int) ([]bool, error) {
+	set := make([]bool, hi+1)
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("bad step in %q", part)
+			}
+			rng, step = part[:i], n
+		}
+		from, to := lo, hi
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if from, err = strconv.Atoi(a); err != nil {
+				return nil, fmt.Errorf("bad value %q", part)
+			}
+			to = from
+			if isRange {
+				if to, err = strconv.Atoi(b); err != nil {
+					return nil, fmt.Errorf("bad range %q", part)
+				}
+			} else if step > 1 {
+				// "5/15" counts from 5 to the end
+				to = hi
+			}
+		}
+		if from < lo || to > hi || from > to {
+			return nil, fmt.Errorf("%q is outside %d-%d", part, lo, hi)
+		}
+		for v := from; v <= to; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+// next is the first matching minute after t, or zero when none comes
+// within a year (e.g. February 30th).
+func (s cronSchedule) next(t time.Time) time.Time {
+	if !s.enabled {
+		return time.Time{}
+	}
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(1, 0, 1)
+	for t.Before(limit) {
+		switch {
+		case !s.month[int(t.Month())]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !s.hour[t.Hour()]:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !s.minute[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches follows cron: when both day fields are restricted, either one
+// matching is enough.
+func (s cronSchedule) dayMatches(t time.Time) bool {
+	dom, dow := s.dom[t.Day()], s.dow[int(t.Weekday())]
+	switch {
+	case s.anyDOM && s.anyDOW:
+		return true
+	case s.anyDOM:
+		return dow
+	case s.anyDOW:
+		return dom
+	}
+	return dom || dow
+}
+
+// restartTickCmd checks the restart schedule every half minute.
+func restartTickCmd() tea.Cmd {
+	return tea.Tick(30*time.Second, func(time.Time) tea.Msg {
+		return restartTickMsg{}
+	})
+}
+
+// handleRestartTick restarts the managed server when its scheduled time
+// has come, unless it is answering requests right then; that run is
+// skipped rather than delayed.
+func (m appModel) handleRestartTick() (appModel, tea.Cmd) {
+	tick := restartTickCmd()
+	if m.server != serverReady || m.attached != nil || m.readOnly || m.powerPaused {
+		m.nextRestart = time.Time{}
+		return m, tick
+	}
+	now := time.Now()
+	if m.nextRestart.IsZero() {
+		m.nextRestart = m.restartSchedule.next(now)
+		return m, tick
+	}
+	if now.Before(m.nextRestart) {
+		return m, tick
+	}
+	m.nextRestart = m.restartSchedule.next(now)
+	if m.requestsActive() {
+		m.logEvent("[schedule] Skipped the scheduled restart: requests are in flight")
+		m.statusLineText = "Scheduled restart skipped: requests in flight"
+		return m, tick
+	}
+	m.logEvent("[schedule] Restarting " + m.currentModelName + " (restart_schedule " + m.restartSchedule.spec + ")")
+	m, cmd := m.restartServer()
+	return m, tea.Batch(cmd, tick)
+}
+
+// restartServer stops the managed server and launches the same model,
+// port, and options again once it has exited.
+func (m appModel) restartServer() (appModel, tea.Cmd) {
+	m.restartAfterStop = &startupAction{model: m.currentModelName, port: m.currentPort, args: m.launchArgs, readiness: m.launchReadiness, preset: m.launchPreset}
+	return m.handleStop()
+}
+
+// nextRestartText is the next scheduled restart for the details pane.
+func (m appModel) nextRestartText() string {
+	if m.nextRestart.IsZero() {
+		return ""
+	}
+	in := strings.TrimSuffix(max(time.Until(m.nextRestart), 0).Round(time.Minute).String(), "0s")
+	if in == "" {
+		in = "<1m"
+	}
+	return fmt.Sprintf("%s (in %s)", m.config.Timestamps.dateTime(m.nextRestart), in)
+}
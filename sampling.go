@@ -0,0 +1,481 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// samplingFlags are the generation defaults llama-server takes as flags,
+// by their /props name, in the order the panel lists them.
+var samplingFlags = []struct {
+	key, flag string
+	integer   bool
+}{
+	{"temperature", "--temp", false},
+	{"top_k", "--top-k", true},
+	{"top_p", "--top-p", false},
+	{"min_p", "--min-p", false},
+	{"typical_p", "--typical", false},
+	{"top_n_sigma", "--top-nsigma", false},
+	{"repeat_penalty", "--repeat-penalty", false},
+	{"repeat_last_n", "--repeat-last-n", true},
+	{"presence_penalty", "--presence-penalty", false},
+	{"frequency_penalty", "--frequency-penalty", false},
+	{"dry_multiplier", "--dry-multiplier", false},
+	{"xtc_probability", "--xtc-probability", false},
+	{"xtc_threshold", "--xtc-threshold", false},
+	{"mirostat", "--mirostat", true},
+	{"mirostat_tau", "--mirostat-ent", false},
+	{"mirostat_eta", "--mirostat-lr", false},
+	{"n_predict", "--n-predict", true},
+	{"seed", "--seed", true},
+}
+
+// samplingAliases are request fields that already set a default under
+// another name, so the proxy leaves it alone.
+var samplingAliases = map[string][]string{
+	"n_predict": {"max_tokens", "max_completion_tokens"},
+}
+
+// samplingPaths are the generation endpoints the proxy fills defaults into.
+var samplingPaths = []string{"/completion", "/completions", "/v1/completions", "/chat/completions", "/v1/chat/completions"}
+
+// samplingBodyLimit is the largest request body defaults are filled into;
+// bigger ones, long prompts with images perhaps, are passed on untouched
+// rather than held in memory.
+const samplingBodyLimit = 8 << 20
+
+// samplingOverrides replace a model's served generation defaults, either
+// as launch flags, which take a restart, or by the proxy filling them into
+// requests that don't set them.
+type samplingOverrides struct {
+	// Apply is "flags" or "proxy"
+	Apply  string            `json:"apply"`
+	Values map[string]string `json:"values"`
+}
+
+func (o samplingOverrides) equal(p samplingOverrides) bool {
+	return (o.Apply == p.Apply || len(o.Values) == 0) && maps.Equal(o.Values, p.Values)
+}
+
+// samplingPath is where a model's overrides are kept.
+func samplingPath(modelName string) string {
+	configDir := appConfigDir()
+	if configDir == "" {
+		return ""
+	}
+	return filepath.Join(configDir, "sampling", sanitizeFileComponent(modelName)+".json")
+}
+
+func loadSamplingOverrides(modelName string) (samplingOverrides, error) {
+	path := samplingPath(modelName)
+	if path == "" {
+		return samplingOverrides{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return samplingOverrides{}, nil
+	}
+	if err != nil {
+		return samplingOverrides{}, err
+	}
+	var o samplingOverrides
+	if err := json.Unmarshal(data, &o); err != nil {
+		return samplingOverrides{}, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return o, nil
+}
+
+// saveSamplingOverrides stores a model's overrides; none removes the file.
+func saveSamplingOverrides(modelName string, o samplingOverrides) error {
+	path := samplingPath(modelName)
+	if path == "" {
+		return fmt.Errorf("no config directory available")
+	}
+	if len(o.Values) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// withSamplingFlags appends a model's overrides kept as flags to the launch
+// arguments. An unreadable file is skipped rather than blocking the launch.
+func withSamplingFlags(modelName string, launchArgs []string) []string {
+	o, _ := loadSamplingOverrides(modelName)
+	if o.Apply != "flags" || len(o.Values) == 0 {
+		return launchArgs
+	}
+	args := append([]string(nil), launchArgs...)
+	for _, f := range samplingFlags {
+		if v, ok := o.Values[f.key]; ok {
+			args = append(args, f.flag, v)
+		}
+	}
+	return args
+}
+
+// requestValues are the overrides the proxy fills into requests, or nil
+// when they are kept as flags.
+func (o samplingOverrides) requestValues() map[string]any {
+	if o.Apply != "proxy" || len(o.Values) == 0 {
+		return nil
+	}
+	values := make(map[string]any, len(o.Values))
+	for k, v := range o.Values {
+		var parsed any
+		if json.Unmarshal([]byte(v), &parsed) != nil {
+			parsed = v
+		}
+		values[k] = parsed
+	}
+	return values
+}
+
+// chatPaths are the generation endpoints taking a list of messages.
+var chatPaths = []string{"/chat/completions", "/v1/chat/completions"}
+
+// fillRequestDefaults adds values to a generation request's JSON body for
+// the fields it leaves out, and system as the first message of a chat
+// request that has no system message. Anything it can't parse, or too big
+// to, is passed on as is.
+func fillRequestDefaults(r *http.Request, values map[string]any, system string) {
+	if system != "" && !containsString(chatPaths, r.URL.Path) {
+		system = ""
+	}
+	if (len(values) == 0 && system == "") || r.Method != http.MethodPost || !containsString(samplingPaths, r.URL.Path) {
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, samplingBodyLimit+1))
+	if err != nil || len(body) > samplingBodyLimit {
+		// Put back what was read ahead of the rest
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+		return
+	}
+	r.Body.Close()
+	setBody := func(b []byte) {
+		r.Body = io.NopCloser(bytes.NewReader(b))
+		r.ContentLength = int64(len(b))
+	}
+	setBody(body)
+	var req map[string]any
+	if json.Unmarshal(body, &req) != nil {
+		return
+	}
+	changed := false
+	for k, v := range values {
+		if _, ok := req[k]; ok {
+			continue
+		}
+		set := false
+		for _, alias := range samplingAliases[k] {
+			_, set = req[alias]
+			if set {
+				break
+			}
+		}
+		if !set {
+			req[k], changed = v, true
+		}
+	}
+	if messages, ok := req["messages"].([]any); ok && system != "" && !hasSystemMessage(messages) {
+		req["messages"] = append([]any{map[string]any{"role": "system", "content": system}}, messages...)
+		changed = true
+	}
+	if !changed {
+		return
+	}
+	if filled, err := json.Marshal(req); err == nil {
+		setBody(filled)
+	}
+}
+
+func hasSystemMessage(messages []any) bool {
+	for _, msg := range messages {
+		if msg, ok := msg.(map[string]any); ok && msg["role"] == "system" {
+			return true
+		}
+	}
+	return false
+}
+
+// generationDefaults picks the scalar sampling parameters out of a /props
+// response. Older servers keep them in default_generation_settings itself
+// rather than under its params.
+func generationDefaults(props any) map[string]string {
+	root, _ := props.(map[string]any)
+	settings, _ := root["default_generation_settings"].(map[string]any)
+	if params, ok := settings["params"].(map[string]any); ok {
+		settings = params
+	}
+	defaults := map[string]string{}
+	for k, v := range settings {
+		switch v.(type) {
+		case map[string]any, []any:
+			continue
+		}
+		b, _ := json.Marshal(v)
+		defaults[k] = string(b)
+	}
+	return defaults
+}
+
+// fetchGenerationDefaultsCmd reads the served defaults again for the panel.
+func fetchGenerationDefaultsCmd(port, modelName string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_, props, err := fetchProps(ctx, "http://127.0.0.1:"+port)
+		if err != nil {
+			return generationDefaultsMsg{modelName: modelName, err: err}
+		}
+		return generationDefaultsMsg{modelName: modelName, defaults: generationDefaults(props)}
+	}
+}
+
+// samplingView is the panel showing the served generation defaults and
+// the overrides saved for the model.
+type samplingView struct {
+	modelName string
+	saved     samplingOverrides
+	err       error
+	// note is the outcome of the last action, shown above the keys
+	note string
+}
+
+// openSamplingView shows the running model's generation defaults.
+func (m appModel) openSamplingView() (appModel, tea.Cmd) {
+	if m.server != serverReady || m.currentModelName == "" {
+		m.statusLineText = "No model is being served - start one to see its generation defaults"
+		return m, nil
+	}
+	if m.servingWhisper() {
+		m.statusLineText = "whisper-server has no generation defaults"
+		return m, nil
+	}
+	saved, err := loadSamplingOverrides(m.currentModelName)
+	m.samplingView = &samplingView{modelName: m.currentModelName, saved: saved, err: err}
+	return m, fetchGenerationDefaultsCmd(m.currentPort, m.currentModelName)
+}
+
+// newSamplingForm edits the overrides, one field per flag, with the
+// served value as each one's doc.
+func (m appModel) newSamplingForm(saved samplingOverrides) formModel {
+	apply := saved.Apply
+	if apply == "" {
+		apply = "proxy"
+	}
+	fields := []formField{newChoiceField("apply", "Apply", "proxy: fill into requests through the proxy, now; flags: pass to llama-server, on restart", []string{"proxy", "flags"}, apply)}
+	for _, f := range samplingFlags {
+		served, ok := m.servedDefaults[f.key]
+		doc := "Served: " + served + " · flag " + f.flag + " · blank keeps the server's"
+		if !ok {
+			doc = "Not in /props · flag " + f.flag + " · blank keeps the server's"
+		}
+		integer := f.integer
+		field := newTextField(f.key, f.key, doc, saved.Values[f.key], func(v string) error {
+			if v == "" {
+				return nil
+			}
+			if integer {
+				_, err := strconv.ParseInt(v, 10, 64)
+				return err
+			}
+			_, err := strconv.ParseFloat(v, 64)
+			return err
+		})
+		field.input.Placeholder = served
+		fields = append(fields, field)
+	}
+	return newForm("Generation Defaults · "+m.samplingView.modelName, fields)
+}
+
+// samplingFromForm reads the overrides back, dropping blank fields.
+func samplingFromForm(values map[string]string) samplingOverrides {
+	o := samplingOverrides{Apply: values["apply"], Values: map[string]string{}}
+	for _, f := range samplingFlags {
+		if v := strings.TrimSpace(values[f.key]); v != "" {
+			o.Values[f.key] = v
+		}
+	}
+	return o
+}
+
+// applySampling saves the overrides and, for the proxy, applies them at
+// once; flags wait for a restart.
+func (m appModel) applySampling(o samplingOverrides) (appModel, tea.Cmd) {
+	v := *m.samplingView
+	if err := saveSamplingOverrides(v.modelName, o); err != nil {
+		v.note = fmt.Sprintf("Could not save overrides: %v", err)
+		m.samplingView = &v
+		return m, nil
+	}
+	v.saved, v.err = o, nil
+	if v.modelName == m.currentModelName && (o.Apply == "proxy" || m.sampling.Apply == "proxy") {
+		m.sampling = o
+		if o.Apply != "proxy" {
+			m.sampling = samplingOverrides{}
+		}
+		m.proxy.setSampling(m.sampling.requestValues())
+	}
+	switch {
+	case len(o.Values) == 0:
+		v.note = "Cleared the overrides"
+	case o.Apply == "proxy" && m.proxy == nil:
+		v.note = "Saved, but no proxy is running - set proxy_port, or apply them as flags"
+	case o.Apply == "proxy":
+		v.note = "Requests through the proxy now get these where they don't set them"
+	}
+	if !o.equal(m.sampling) {
+		v.note = "Saved - [R] restarts the server with them"
+	}
+	m.samplingView = &v
+	m.logEvent(fmt.Sprintf("[sampling] %s overrides for %s: %s", pluralize(len(o.Values), "generation default"), v.modelName, samplingSummary(o)))
+	return m, nil
+}
+
+func samplingSummary(o samplingOverrides) string {
+	if len(o.Values) == 0 {
+		return "none"
+	}
+	var parts []string
+	for _, f := range samplingFlags {
+		if v, ok := o.Values[f.key]; ok {
+			parts = append(parts, f.key+"="+v)
+		}
+	}
+	return strings.Join(parts, " ") + " (" + o.Apply + ")"
+}
+
+// handleSamplingKey edits, clears, and restarts from the panel, which
+// captures keys while open.
+func (m appModel) handleSamplingKey(keyStr string) (appModel, tea.Cmd) {
+	v := *m.samplingView
+	switch keyStr {
+	case "e", "enter", "x", "R":
+		if m.observerRefuses("change generation defaults") {
+			return m, nil
+		}
+	}
+	switch keyStr {
+	case "e", "enter":
+		if m.readOnly {
+			v.note = "Read-only: another instance manages this server"
+			break
+		}
+		form := m.newSamplingForm(v.saved)
+		m.form, m.formPurpose = &form, formSampling
+	case "x":
+		if m.readOnly || len(v.saved.Values) == 0 {
+			break
+		}
+		return m.applySampling(samplingOverrides{})
+	case "R":
+		if m.readOnly || v.saved.equal(m.sampling) || v.modelName != m.currentModelName {
+			break
+		}
+		m.samplingView = nil
+		m.statusLineText = "Restarting " + m.displayName(v.modelName) + " with its generation defaults"
+		return m.restartServer()
+	case "r":
+		m.samplingView = &v
+		return m, fetchGenerationDefaultsCmd(m.currentPort, v.modelName)
+	case "esc", "g":
+		m.samplingView = nil
+		return m, nil
+	}
+	m.samplingView = &v
+	return m, nil
+}
+
+// renderSamplingView lists the served defaults beside the overrides,
+// followed by the other parameters /props reports.
+func (m appModel) renderSamplingView(width int) string {
+	v := m.samplingView
+	var b strings.Builder
+	keys := "[e] edit overrides  [x] clear  [r] refresh  [g] or [esc] close"
+	if !v.saved.equal(m.sampling) && v.modelName == m.currentModelName {
+		keys = "[e] edit overrides  [x] clear  [R] restart to apply  [r] refresh  [g] or [esc] close"
+	}
+	footer := m.styles.help.Render(keys)
+	if v.note != "" {
+		footer = m.styles.status.Render(v.note) + "\n" + footer
+	}
+	how := "none saved"
+	if len(v.saved.Values) > 0 {
+		how = "applied by the proxy"
+		if v.saved.Apply == "flags" {
+			how = "passed as flags"
+		}
+	}
+	b.WriteString(m.styles.help.Render(fmt.Sprintf("/props of %s · overrides %s", m.displayName(v.modelName), how)) + "\n\n")
+	if v.err != nil {
+		b.WriteString(m.styles.logError.Render(v.err.Error()) + "\n\n")
+	}
+	if len(m.servedDefaults) == 0 {
+		return b.String() + "Reading /props...\n\n" + footer
+	}
+	const keyWidth, valueWidth = 20, 14
+	row := func(key, served, override, flag string) string {
+		return fmt.Sprintf("  %-*s %*s  %-*s %s", keyWidth, key, valueWidth, served, valueWidth, override, flag)
+	}
+	b.WriteString(m.styles.help.Render(row("parameter", "served", "override", "flag")) + "\n")
+	for _, f := range samplingFlags {
+		served, ok := m.servedDefaults[f.key]
+		override, overridden := v.saved.Values[f.key]
+		if !ok && !overridden {
+			continue
+		}
+		line := row(f.key, ellipsize(served, valueWidth), ellipsize(override, valueWidth), m.styles.disabled.Render(f.flag))
+		if overridden {
+			line = m.styles.accent.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+	var others []string
+	for k, served := range m.servedDefaults {
+		if !containsString(samplingKeys(), k) {
+			others = append(others, k+"="+summarizePropsValue(served))
+		}
+	}
+	if len(others) > 0 {
+		sort.Strings(others)
+		b.WriteString("\n" + m.styles.disabled.Render(lipgloss.NewStyle().Width(width).Render("Also served: "+strings.Join(others, "  "))) + "\n")
+	}
+	b.WriteString("\n" + footer)
+	return b.String()
+}
+
+func samplingKeys() []string {
+	keys := make([]string, len(samplingFlags))
+	for i, f := range samplingFlags {
+		keys[i] = f.key
+	}
+	return keys
+}
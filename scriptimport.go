@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	shellAssignment = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
+	shardFileName   = regexp.MustCompile(`(?i)^(.+)-\d+-of-\d+\.gguf$`)
+)
+
+// scriptImport is a preset recovered from a launch script, with anything
+// that could not be carried over.
+type scriptImport struct {
+	name     string
+	preset   launchPreset
+	warnings []string
+}
+
+// presetNameForScript names a preset after its script: run-qwen.sh → run-qwen.
+func presetNameForScript(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return strings.Join(strings.Fields(name), "-")
+}
+
+// parseLaunchScript finds the llama-server invocation in a shell script and
+// converts it into a preset. Line continuations and simple variable
+// assignments are understood; the model becomes its file name so it matches
+// the listing, and --port becomes the preset port.
+func parseLaunchScript(name string, data []byte) (scriptImport, error) {
+	imp := scriptImport{name: name}
+	vars := map[string]string{}
+	src := strings.ReplaceAll(string(data), "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\\\n", " ")
+	var words []string
+	bin := -1
+	for _, line := range strings.Split(src, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lineWords, err := splitCommandLine(expandScriptVars(line, vars))
+		if err != nil || len(lineWords) == 0 {
+			continue
+		}
+		if isAssignmentLine(lineWords) {
+			for _, w := range lineWords {
+				if m := shellAssignment.FindStringSubmatch(w); m != nil {
+					vars[m[1]] = m[2]
+				}
+			}
+			continue
+		}
+		for i, w := range lineWords {
+			if strings.HasPrefix(filepath.Base(w), "llama-server") {
+				words, bin = lineWords, i
+				break
+			}
+		}
+		if bin >= 0 {
+			break
+		}
+	}
+	if bin < 0 {
+		return imp, fmt.Errorf("no llama-server command found")
+	}
+	for _, w := range words[:bin] {
+		if shellAssignment.MatchString(w) {
+			imp.warnings = append(imp.warnings, "environment "+w+" not imported; set it in command_template")
+		}
+	}
+
+	args := words[bin+1:]
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if stop, rest := shellOperator(a); stop {
+			if rest != "" {
+				imp.preset.Args = append(imp.preset.Args, rest)
+			}
+			break
+		}
+		if a == "" {
+			// "$@" and friends expand to nothing here
+			continue
+		}
+		flag, value, hasValue := strings.Cut(a, "=")
+		switch flag {
+		case "-m", "--model", "--port":
+			if !hasValue {
+				if i+1 >= len(args) {
+					return imp, fmt.Errorf("%s needs a value", flag)
+				}
+				i++
+				value = args[i]
+			}
+			if flag == "--port" {
+				imp.preset.Port = value
+			} else {
+				imp.preset.Model = modelNameForPath(value)
+			}
+		default:
+			imp.preset.Args = append(imp.preset.Args, a)
+		}
+	}
+	if imp.preset.Model == "" {
+		imp.warnings = append(imp.warnings, "no -m/--model; the preset only prefills port and arguments")
+	}
+	return imp, nil
+}
+
+// isAssignmentLine reports whether a line only sets variables.
+func isAssignmentLine(words []string) bool {
+	for i, w := range words {
+		if i == 0 && (w == "export" || w == "local" || w == "readonly") {
+			continue
+		}
+		if !shellAssignment.MatchString(w) {
+			return false
+		}
+	}
+	return true
+}
+
+// shellOperator reports whether a word ends the command (a pipe, redirect, or
+// separator), returning any argument glued to a trailing separator.
+func shellOperator(w string) (bool, string) {
+	switch {
+	case w == "|" || w == "||" || w == "&&" || w == "&" || w == ";":
+		return true, ""
+	case strings.HasPrefix(w, ">") || strings.HasPrefix(w, "<") || strings.HasPrefix(w, "2>") || strings.HasPrefix(w, "&>"):
+		return true, ""
+	case strings.HasSuffix(w, ";"):
+		return true, strings.TrimSuffix(w, ";")
+	}
+	return false, ""
+}
+
+// expandScriptVars substitutes $VAR, ${VAR}, and ${VAR:-default} from the
+// script's own assignments, then the environment. Positional parameters
+// expand to nothing.
+func expandScriptVars(s string, vars map[string]string) string {
+	return os.Expand(s, func(key string) string {
+		name, def, hasDefault := strings.Cut(key, ":-")
+		if v, ok := vars[name]; ok && v != "" {
+			return v
+		}
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+		if hasDefault {
+			return def
+		}
+		return ""
+	})
+}
+
+// modelNameForPath maps a model path to the name the listing shows, which
+// --start and presets match by substring.
+func modelNameForPath(path string) string {
+	base := filepath.Base(path)
+	if m := shardFileName.FindStringSubmatch(base); m != nil {
+		return m[1] + ".gguf"
+	}
+	return base
+}
+
+// savePresets merges presets into the config file, keeping every other
+// setting as written. Existing presets are replaced only when overwrite is
+// set; the names that were skipped are returned.
+func savePresets(configPath string, presets map[string]launchPreset, overwrite bool) ([]string, error) {
+	doc := map[string]json.RawMessage{}
+	data, err := os.ReadFile(configPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("invalid config %s: %w", configPath, err)
+		}
+	}
+	existing := map[string]json.RawMessage{}
+	if raw, ok := doc["presets"]; ok {
+		if err := json.Unmarshal(raw, &existing); err != nil {
+			return nil, fmt.Errorf("invalid presets in %s: %w", configPath, err)
+		}
+	}
+	var skipped []string
+	for name, p := range presets {
+		if _, ok := existing[name]; ok && !overwrite {
+			skipped = append(skipped, name)
+			continue
+		}
+		raw, err := json.Marshal(p)
+		if err != nil {
+			return nil, err
+		}
+		existing[name] = raw
+	}
+	sort.Strings(skipped)
+	raw, err := json.Marshal(existing)
+	if err != nil {
+		return nil, err
+	}
+	doc["presets"] = raw
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(configPath), 0o755); err != nil {
+		return nil, err
+	}
+	tmp := configPath + ".tmp"
+	if err := os.WriteFile(tmp, append(out, '\n'), 0o644); err != nil {
+		return nil, err
+	}
+	return skipped, os.Rename(tmp, configPath)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// renderScrollbar draws a one-column vertical scrollbar of the given height
+// for content of total lines, of which visible lines starting at offset are
+// shown. It returns one cell per line.
+func (m appModel) renderScrollbar(height, total, offset, visible int) []string {
+	cells := make([]string, height)
+	if height <= 0 {
+		return cells
+	}
+	if total <= visible || total == 0 {
+		// Everything fits; draw an empty track
+		for i := range cells {
+			cells[i] = " "
+		}
+		return cells
+	}
+	thumb := height * visible / total
+	if thumb < 1 {
+		thumb = 1
+	}
+	maxOffset := total - visible
+	top := 0
+	if maxOffset > 0 {
+		top = (height - thumb) * offset / maxOffset
+	}
+	for i := range cells {
+		if i >= top && i < top+thumb {
+			cells[i] = m.styles.accent.Render("┃")
+		} else {
+			cells[i] = m.styles.panelBorder.Render("│")
+		}
+	}
+	return cells
+}
+
+// withScrollbar appends a scrollbar column to each line of body.
+func withScrollbar(body string, bar []string) string {
+	lines := strings.Split(body, "\n")
+	for i := range lines {
+		if i < len(bar) {
+			lines[i] += bar[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// logsPosition describes the logs viewport position, e.g. "123/4096 lines, 42%".
+func (m appModel) logsPosition() string {
+	total := m.logsViewport.TotalLineCount()
+	if total == 0 {
+		return ""
+	}
+	bottom := m.logsViewport.YOffset + m.logsViewport.Height
+	if bottom > total {
+		bottom = total
+	}
+	return fmt.Sprintf("%d/%d lines, %.0f%%", bottom, total, m.logsViewport.ScrollPercent()*100)
+}
+
+// modelsPosition describes the selected model's position, e.g. "3/27".
+func (m appModel) modelsPosition() string {
+	n := len(m.modelsList.VisibleItems())
+	if n == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d/%d", m.modelsList.Index()+1, n)
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// version is set at build time via -ldflags "-X main.version=...".
+var version = "dev"
+
+// githubRelease is the subset of the GitHub releases API response we use.
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	HTMLURL string `json:"html_url"`
+	Assets  []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+func fetchLatestRelease(ctx context.Context) (githubRelease, error) {
+	var rel githubRelease
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, releasesAPIURL, nil)
+	if err != nil {
+		return rel, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return rel, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return rel, fmt.Errorf("release check failed: %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		return rel, fmt.Errorf("invalid release response: %w", err)
+	}
+	return rel, nil
+}
+
+// checkForUpdateCmd queries the latest release and reports it when newer than
+// the running build. Errors are reported quietly; the check is best-effort.
+func checkForUpdateCmd() tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		rel, err := fetchLatestRelease(ctx)
+		if err != nil {
+			return updateAvailableMsg{err: err}
+		}
+		if !isNewerVersion(rel.TagName, version) {
+			return updateAvailableMsg{}
+		}
+		return updateAvailableMsg{release: &rel}
+	}
+}
+
+// releaseAssetName is this platform's raw binary in a release, e.g.
+// "llama-tui_linux_arm64"; archives and other platforms' builds never
+// match it.
+func releaseAssetName() string {
+	name := "llama-tui_" + runtime.GOOS + "_" + runtime.GOARCH
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// releaseAssetURL finds an asset of rel by its exact name.
+func releaseAssetURL(rel githubRelease, name string) string {
+	for _, a := range rel.Assets {
+		if a.Name == name {
+			return a.BrowserDownloadURL
+		}
+	}
+	return ""
+}
+
+// downloadRelease fetches url into w.
+func downloadRelease(ctx context.Context, url string, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download failed: %s", resp.Status)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// selfUpdateCmd downloads this platform's binary from the release, checks
+// it against the release's .sha256 file and that it runs, and only then
+// moves it over the running executable. The new binary takes effect on
+// restart; the old one is kept beside it with a ".old" suffix.
+func selfUpdateCmd(rel githubRelease) tea.Cmd {
+	return func() tea.Msg {
+		asset := releaseAssetName()
+		url := releaseAssetURL(rel, asset)
+		if url == "" {
+			return selfUpdateDoneMsg{err: fmt.Errorf("no %s in release %s", asset, rel.TagName)}
+		}
+		sumURL := releaseAssetURL(rel, asset+".sha256")
+		if sumURL == "" {
+			return selfUpdateDoneMsg{err: fmt.Errorf("no %s.sha256 in release %s to verify the download with", asset, rel.TagName)}
+		}
+
+		exe, err := os.Executable()
+		if err != nil {
+			return selfUpdateDoneMsg{err: err}
+		}
+		exe, err = filepath.EvalSymlinks(exe)
+		if err != nil {
+			return selfUpdateDoneMsg{err: err}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		var sumFile strings.Builder
+		if err := downloadRelease(ctx, sumURL, &sumFile); err != nil {
+			return selfUpdateDoneMsg{err: fmt.Errorf("checksum: %w", err)}
+		}
+		fields := strings.Fields(sumFile.String())
+		if len(fields) == 0 {
+			return selfUpdateDoneMsg{err: fmt.Errorf("%s.sha256 is empty", asset)}
+		}
+		want := strings.ToLower(fields[0])
+
+		// Write next to the executable so the final rename stays on one filesystem
+		tmp, err := os.CreateTemp(filepath.Dir(exe), ".llama-tui-update-*")
+		if err != nil {
+			return selfUpdateDoneMsg{err: err}
+		}
+		tmpPath := tmp.Name()
+		fail := func(err error) tea.Msg {
+			_ = os.Remove(tmpPath)
+			return selfUpdateDoneMsg{err: err}
+		}
+		hash := sha256.New()
+		err = downloadRelease(ctx, url, io.MultiWriter(tmp, hash))
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fail(err)
+		}
+		if got := hex.EncodeToString(hash.Sum(nil)); got != want {
+			return fail(fmt.Errorf("%s does not match its checksum (got %s, want %s)", asset, got, want))
+		}
+		if err := os.Chmod(tmpPath, 0o755); err != nil {
+			return fail(err)
+		}
+		// A binary for another platform or a truncated one won't get this far
+		checkCtx, checkCancel := context.WithTimeout(ctx, 10*time.Second)
+		defer checkCancel()
+		if out, err := exec.CommandContext(checkCtx, tmpPath, "--version").CombinedOutput(); err != nil {
+			return fail(fmt.Errorf("the downloaded binary does not run: %v %s", err, strings.TrimSpace(string(out))))
+		}
+
+		old := exe + ".old"
+		_ = os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return fail(err)
+		}
+		if err := os.Rename(tmpPath, exe); err != nil {
+			// Put the old binary back
+			_ = os.Rename(old, exe)
+			return fail(err)
+		}
+		return selfUpdateDoneMsg{version: rel.TagName}
+	}
+}
+
+// isNewerVersion reports whether latest is a higher dotted version than
+// current. Development builds never report updates.
+func isNewerVersion(latest, current string) bool {
+	lv, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	cv, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	for i := 0; i < len(lv) || i < len(cv); i++ {
+		var l, c int
+		if i < len(lv) {
+			l = lv[i]
+		}
+		if i < len(cv) {
+			c = cv[i]
+		}
+		if l != c {
+			return l > c
+		}
+	}
+	return false
+}
+
+func parseVersion(v string) ([]int, bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	// Ignore pre-release/build suffixes such as "-rc1" or "+dirty"
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	if v == "" {
+		return nil, false
+	}
+	parts := strings.Split(v, ".")
+	nums := make([]int, 0, len(parts))
+	for _, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, false
+		}
+		nums = append(nums, n)
+	}
+	return nums, true
+}
@@ -3,9 +3,9 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -15,7 +15,9 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/atotto/clipboard"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shirou/gopsutil/v4/mem"
 	"github.com/shirou/gopsutil/v4/process"
 )
 
@@ -52,168 +54,314 @@ func getLlamaServerBinary() (string, error) {
 	return bin, nil
 }
 
-func (m *appModel) startServerCmd(selected modelItem, port string) tea.Cmd {
+// launchSpec is everything a launch needs from the model, taken in the
+// Update loop so the process starts without touching the model.
+type launchSpec struct {
+	item modelItem
+	port string
+	bin  string
+	argv []string
+	// err fails the launch before anything is started
+	err        error
+	probe      readinessProbe
+	launchArgs []string
+	// logFilePath is where the output is also written, "" for nowhere
+	logFilePath string
+	stamps      timestampConfig
+}
+
+func (m appModel) launchSpec(selected modelItem, port string) launchSpec {
+	spec := launchSpec{item: selected, port: port, launchArgs: m.launchArgs, stamps: m.config.Timestamps}
+	// Resolve llama-server, or whisper-server for speech models
+	spec.bin, spec.err = selected.kind.serverBinary()
+	if spec.err != nil {
+		return spec
+	}
+	spec.argv, spec.err = m.launchCommand(selected, spec.bin, port)
+	if spec.err != nil {
+		return spec
+	}
+	spec.probe = m.config.Readiness.with(m.launchReadiness)
+	spec.probe.healthPath = selected.kind.healthPath()
+	if spec.err = spec.probe.validate(); spec.err != nil {
+		return spec
+	}
+	if m.logToFileEnabled && m.logsDir != "" {
+		spec.logFilePath = filepath.Join(m.logsDir, logFileName(m.config.Timestamps.fileStamp(time.Now()), selected.name, port))
+	}
+	return spec
+}
+
+// exitOutputWait is how long a server's exit waits to be reported for the
+// output it wrote to be read.
+const exitOutputWait = time.Second
+
+// startServerCmd launches selected on port as the model stands now.
+func (m appModel) startServerCmd(selected modelItem, port string) tea.Cmd {
+	spec := m.launchSpec(selected, port)
 	return func() tea.Msg {
-		// Do not mutate model state here; return it via a message and let Update handle it.
-		// This avoids pointer-to-model mutations outside of the Update loop.
-
-		ctx, cancel := context.WithCancel(context.Background())
-		// Resolve llama-server binary
-		bin, binErr := getLlamaServerBinary()
-		if binErr != nil {
-			cancel()
-			return startErrorMsg{err: binErr}
-		}
-		cmd := exec.CommandContext(ctx, bin, "-m", selected.path, "--port", port, "--jinja")
-		cmdEnv := os.Environ()
-		cmd.Env = cmdEnv
+		return launchServer(spec)
+	}
+}
 
-		stdout, err := cmd.StdoutPipe()
-		if err != nil {
-			cancel()
-			return startErrorMsg{err: fmt.Errorf("failed to create stdout pipe: %w", err)}
-		}
-		stderr, err := cmd.StderrPipe()
-		if err != nil {
-			cancel()
-			return startErrorMsg{err: fmt.Errorf("failed to create stderr pipe: %w", err)}
-		}
+// launchServer starts the server and hands it to a supervisor, returning
+// its channels via a message for Update to attach.
+func launchServer(spec launchSpec) tea.Msg {
+	selected, port, argv := spec.item, spec.port, spec.argv
+	if spec.err != nil {
+		return startErrorMsg{model: selected.name, port: port, err: spec.err}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	// Own process group, so stopping also reaches any helpers it spawns;
+	// cancellation asks politely and the supervisor escalates
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return signalProcessGroup(cmd, syscall.SIGTERM)
+	}
+	cmdEnv := os.Environ()
+	cmd.Env = cmdEnv
 
-		// Prepare file logging if enabled
-		var fileWriter io.WriteCloser
-		var logFilePath string
-		if m.logToFileEnabled {
-			_ = os.MkdirAll(m.logsDir, 0o755)
-			filename := time.Now().Format("20060102_150405") + ".log"
-			filePath := filepath.Join(m.logsDir, filename)
-			f, ferr := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
-			if ferr != nil {
-				// If file cannot be opened, continue without file
-			} else {
-				logFilePath = filePath
-				fileWriter = f
-			}
+	// Pipes of our own rather than StdoutPipe's, which Wait closes as soon
+	// as the process exits, losing the last lines it wrote: often the ones
+	// saying why
+	stdout, stdoutW, err := os.Pipe()
+	if err != nil {
+		cancel()
+		return startErrorMsg{model: selected.name, port: port, err: fmt.Errorf("failed to create stdout pipe: %w", err)}
+	}
+	stderr, stderrW, err := os.Pipe()
+	if err != nil {
+		cancel()
+		_, _ = stdout.Close(), stdoutW.Close()
+		return startErrorMsg{model: selected.name, port: port, err: fmt.Errorf("failed to create stderr pipe: %w", err)}
+	}
+	cmd.Stdout, cmd.Stderr = stdoutW, stderrW
+
+	// Prepare file logging if enabled
+	var fileWriter io.WriteCloser
+	stamps := spec.stamps
+	var logFilePath string
+	if spec.logFilePath != "" {
+		_ = os.MkdirAll(filepath.Dir(spec.logFilePath), 0o755)
+		f, ferr := os.OpenFile(spec.logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if ferr != nil {
+			// If file cannot be opened, continue without file
+		} else {
+			logFilePath = spec.logFilePath
+			fileWriter = f
 		}
+	}
 
-		logChan := make(chan string, 1024)
-		exitChan := make(chan error, 1)
+	logChan := make(chan string, 1024)
+	exitChan := make(chan error, 1)
+	readyChan := make(chan bool, 1)
 
-		// Start the command synchronously to catch immediate errors
-		err = cmd.Start()
-		if err != nil {
-			cancel()
-			return startErrorMsg{err: fmt.Errorf("failed to start llama-server: %w", err)}
+	// Start the command synchronously to catch immediate errors
+	err = cmd.Start()
+	// The server has its own copies of the write ends
+	_, _ = stdoutW.Close(), stderrW.Close()
+	if err != nil {
+		cancel()
+		_, _ = stdout.Close(), stderr.Close()
+		if fileWriter != nil {
+			_ = fileWriter.Close()
 		}
+		return startErrorMsg{model: selected.name, port: port, err: fmt.Errorf("failed to start %s: %w", selected.kind.serverName(), err)}
+	}
+	exited := make(chan error, 1)
+	server := superviseProcess(ctx, cancel, cmd, exited)
 
-		// Emit quick diagnostics to the log channel for visibility
-		select {
-		case logChan <- fmt.Sprintf("Resolved llama-server binary: %s", bin):
-		default:
-		}
-		select {
-		case logChan <- fmt.Sprintf("Exec: %s -m %s --port %s --jinja", bin, selected.path, port):
-		default:
-		}
-		select {
-		case logChan <- "Waiting for server to become ready...":
-		default:
-		}
+	// Emit quick diagnostics to the log channel for visibility
+	select {
+	case logChan <- fmt.Sprintf("Resolved %s binary: %s", selected.kind.serverName(), spec.bin):
+	default:
+	}
+	select {
+	case logChan <- "Exec: " + strings.Join(argv, " "):
+	default:
+	}
+	select {
+	case logChan <- "Waiting for server to become ready...":
+	default:
+	}
 
-		// Reader goroutine - always streams logs to TUI regardless of file logging
-		go func() {
-			defer func() {
-				if fileWriter != nil {
-					_ = fileWriter.Close()
-				}
-			}()
-
-			stdoutScanner := bufio.NewScanner(stdout)
-			stderrScanner := bufio.NewScanner(stderr)
-			stdoutScanner.Buffer(make([]byte, 1024), 1024*1024)
-			stderrScanner.Buffer(make([]byte, 1024), 1024*1024)
-
-			var wg sync.WaitGroup
-			wg.Add(2)
-			copyFn := func(scanner *bufio.Scanner) {
-				defer wg.Done()
-				for scanner.Scan() {
-					line := scanner.Text()
-					// Always write to file if enabled
-					if fileWriter != nil {
-						_, _ = io.WriteString(fileWriter, line+"\n")
-					}
-					// Always send to log channel for TUI display
-					select {
-					case logChan <- line:
-					default:
-						// In case UI is slow, drop oldest by non-blocking send
-						// to prevent deadlocks; best-effort logging in UI.
-					}
-				}
+	// Reader goroutine - always streams logs to TUI regardless of file logging
+	outputDone := make(chan struct{})
+	go func() {
+		defer func() {
+			_, _ = stdout.Close(), stderr.Close()
+			if fileWriter != nil {
+				_ = fileWriter.Close()
 			}
-			go copyFn(stdoutScanner)
-			go copyFn(stderrScanner)
-			wg.Wait()
-			// Close the log channel only after both stdout and stderr are fully read
-			close(logChan)
 		}()
 
-		// Readiness probe goroutine - check when port starts accepting connections
-		go func() {
-			addresses := []string{"127.0.0.1:" + port, "[::1]:" + port}
-			deadline := time.Now().Add(90 * time.Second)
-			dialTimeout := 500 * time.Millisecond
-			for {
-				// Stop probing if process has exited (exitChan would close soon after)
+		var wg sync.WaitGroup
+		wg.Add(2)
+		copyFn := func(name string, r io.Reader) {
+			defer wg.Done()
+			emit := func(line string) {
+				// Always write to file if enabled
+				if fileWriter != nil {
+					_, _ = io.WriteString(fileWriter, stamps.linePrefix()+line+"\n")
+				}
+				// Always send to log channel for TUI display
 				select {
-				case <-exitChan:
-					return
+				case logChan <- line:
 				default:
+					// In case UI is slow, drop oldest by non-blocking send
+					// to prevent deadlocks; best-effort logging in UI.
 				}
-				ready := false
-				for _, addr := range addresses {
-					conn, cerr := net.DialTimeout("tcp", addr, dialTimeout)
-					if cerr == nil {
-						_ = conn.Close()
-						ready = true
-						break
-					}
-				}
-				if ready {
-					select {
-					case logChan <- fmt.Sprintf("Ready: listening on port %s", port):
-					default:
-					}
-					return
+			}
+			if err := readLogLines(r, maxLogLineBytes, emit); err != nil {
+				emit(fmt.Sprintf("[ui] Error reading %s: %v", name, err))
+				// Keep draining so the server never blocks on a full pipe
+				_, _ = io.Copy(io.Discard, r)
+			}
+		}
+		go copyFn("stdout", stdout)
+		go copyFn("stderr", stderr)
+		wg.Wait()
+		// Close the log channel only after both stdout and stderr are fully read
+		close(logChan)
+		close(outputDone)
+	}()
+
+	// The exit is reported once the output is read, so Update has the last
+	// lines when it handles it; helpers still holding the pipes open only
+	// hold it up for exitOutputWait
+	go func() {
+		if err, ok := <-exited; ok {
+			select {
+			case <-outputDone:
+			case <-time.After(exitOutputWait):
+			}
+			exitChan <- err
+		}
+		close(exitChan)
+	}()
+
+	// Readiness probe goroutine - check when port starts accepting connections
+	probe := spec.probe
+	readyTimeout := probe.timeout(defaultReadyTimeout)
+	if selected.hfRepo != "" {
+		// The first launch downloads the repo before listening
+		readyTimeout = probe.timeout(6 * time.Hour)
+	}
+	addresses := probe.targets(argv, port)
+	go func() {
+		deadline := time.Now().Add(readyTimeout)
+		for {
+			// Stop probing once the process is stopping or has exited
+			if server.ctx.Err() != nil {
+				readyChan <- false
+				return
+			}
+			ready := ""
+			for _, addr := range addresses {
+				if probe.check(addr) {
+					ready = addr
+					break
 				}
-				if time.Now().After(deadline) {
-					select {
-					case logChan <- fmt.Sprintf("Warning: no readiness detected on port %s after 90s. It may still be loading the model (20B models can take a while).", port):
-					default:
-					}
-					return
+			}
+			if ready != "" {
+				readyChan <- true
+				return
+			}
+			if time.Now().After(deadline) {
+				select {
+				case logChan <- fmt.Sprintf("Warning: no readiness detected on %s after %s. It may still be loading the model (20B models can take a while).", strings.Join(addresses, ", "), readyTimeout):
+				default:
 				}
-				time.Sleep(500 * time.Millisecond)
+				readyChan <- false
+				return
 			}
-		}()
+			time.Sleep(probe.interval())
+		}
+	}()
 
-		// Wait goroutine - monitors process exit
-		go func() {
-			waitErr := cmd.Wait()
-			exitChan <- waitErr
-			close(exitChan)
-		}()
+	// Return process state via message; Update will attach it to the model.
+	return startedWithStateMsg{
+		logChan:     logChan,
+		exitChan:    exitChan,
+		readyChan:   readyChan,
+		process:     server,
+		modelName:   selected.name,
+		modelPath:   selected.path,
+		launchArgs:  spec.launchArgs,
+		mmprojPath:  selected.mmproj,
+		port:        port,
+		logFilePath: logFilePath,
+	}
+}
 
-		// Return process state via message; Update will attach it to the model.
-		return startedWithStateMsg{
-			logChan:     logChan,
-			exitChan:    exitChan,
-			ctx:         ctx,
-			cancel:      cancel,
-			cmd:         cmd,
-			modelName:   selected.name,
-			port:        port,
-			logFilePath: logFilePath,
+// openLogInPagerCmd suspends the TUI and opens the log file in $PAGER
+// (falling back to less), resuming once the pager exits.
+func openLogInPagerCmd(path string) tea.Cmd {
+	pager := strings.Fields(os.Getenv("PAGER"))
+	if len(pager) == 0 {
+		pager = []string{"less"}
+	}
+	args := append(pager[1:], path)
+	c := exec.Command(pager[0], args...)
+	return tea.ExecProcess(c, func(err error) tea.Msg {
+		return pagerClosedMsg{err: err}
+	})
+}
+
+// logFileName builds "<timestamp>_<model>_<port>.log" with the model name
+// reduced to filesystem-safe characters.
+func logFileName(stamp, modelName, port string) string {
+	base := filepath.Base(modelName)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	return fmt.Sprintf("%s_%s_%s.log", stamp, sanitizeFileComponent(base), port)
+}
+
+// copyToClipboardCmd writes text to the system clipboard.
+func copyToClipboardCmd(text string) tea.Cmd {
+	return func() tea.Msg {
+		return clipboardCopiedMsg{text: text, err: clipboard.WriteAll(text)}
+	}
+}
+
+// readLogLines reads newline-delimited output and calls emit per line.
+// Lines longer than maxLine are split into chunks annotated as continued,
+// rather than aborting the stream like bufio.Scanner does. io.EOF and
+// closed-pipe errors at process exit are not reported.
+func readLogLines(r io.Reader, maxLine int, emit func(string)) error {
+	br := bufio.NewReaderSize(r, 64*1024)
+	var line []byte
+	part := 0
+	for {
+		frag, isPrefix, err := br.ReadLine()
+		line = append(line, frag...)
+		for len(line) > maxLine {
+			cut := runeBoundary(line, maxLine)
+			chunk := string(line[:cut])
+			if part > 0 {
+				chunk = "[...] " + chunk
+			}
+			emit(chunk + " [...] (line exceeds " + formatBytes(uint64(maxLine)) + ", continued)")
+			line = line[cut:]
+			part++
+		}
+		if err == nil && !isPrefix {
+			out := string(line)
+			if part > 0 {
+				out = "[...] " + out
+			}
+			emit(out)
+			line = line[:0]
+			part = 0
+		}
+		if err != nil {
+			if len(line) > 0 {
+				emit(string(line))
+			}
+			if err == io.EOF || errors.Is(err, os.ErrClosed) {
+				return nil
+			}
+			return err
 		}
 	}
 }
@@ -222,12 +370,23 @@ func (m appModel) waitForLogLine() tea.Cmd {
 	if m.logChan == nil {
 		return nil
 	}
+	ch := m.logChan
 	return func() tea.Msg {
-		line, ok := <-m.logChan
+		line, ok := <-ch
 		if !ok {
 			return nil
 		}
-		return logLineMsg{text: line}
+		return logLineMsg{ch: ch, text: line}
+	}
+}
+
+func (m appModel) waitForReady() tea.Cmd {
+	ch := m.readyChan
+	if ch == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		return serverReadyMsg{readyChan: ch, ready: <-ch}
 	}
 }
 
@@ -235,74 +394,100 @@ func (m appModel) waitForExit() tea.Cmd {
 	if m.exitChan == nil {
 		return nil
 	}
+	ch := m.exitChan
 	return func() tea.Msg {
-		err, ok := <-m.exitChan
+		err, ok := <-ch
 		if !ok {
-			return serverExitedMsg{err: nil}
+			return serverExitedMsg{exitChan: ch, err: nil}
 		}
-		return serverExitedMsg{err: err}
+		return serverExitedMsg{exitChan: ch, err: err}
 	}
 }
 
-func (m *appModel) stopServerCmd() tea.Cmd {
+func (m appModel) stopServerCmd() tea.Cmd {
+	server := m.process
 	return func() tea.Msg {
-		if m.serverCmd == nil {
-			return nil
-		}
 		// Attempt graceful stop - don't return stoppedMsg here
 		// Wait for serverExitedMsg to confirm actual exit
-		if m.serverCancel != nil {
-			m.serverCancel()
-		}
-		if m.serverCmd.Process != nil {
-			// Best-effort graceful signals
-			_ = m.serverCmd.Process.Signal(os.Interrupt)
-			_ = m.serverCmd.Process.Signal(syscall.SIGTERM)
-			// Escalate to SIGKILL after a short grace period, without blocking UI
-			go func(cmd *exec.Cmd) {
-				timer := time.NewTimer(2 * time.Second)
-				defer timer.Stop()
-				<-timer.C
-				if cmd != nil && cmd.Process != nil {
-					_ = cmd.Process.Kill()
-				}
-			}(m.serverCmd)
-		}
+		server.stop()
 		return nil
 	}
 }
 
+// pollResourceUsageCmd starts a resource poll loop for the managed or
+// attached server, ending any earlier loop.
 func (m *appModel) pollResourceUsageCmd() tea.Cmd {
-	return func() tea.Msg {
-		if m.serverCmd == nil || m.serverCmd.Process == nil {
-			return nil
-		}
-		
-		pid := int32(m.serverCmd.Process.Pid)
-		proc, err := process.NewProcess(pid)
-		if err != nil {
-			// Process not found or error accessing it - return nil to skip update
-			return nil
-		}
-		
-		cpuPercent, err := proc.CPUPercent()
-		if err != nil {
-			// Skip CPU update on error
-			cpuPercent = 0
-		}
-		
-		memInfo, err := proc.MemoryInfo()
-		if err != nil {
-			// Skip memory update on error
-			return resourceUsageMsg{
-				cpuPercent: cpuPercent,
-				memRSSBytes: 0,
-			}
-		}
-		
+	var pid int32
+	switch {
+	case m.process != nil:
+		pid = int32(m.process.pid)
+	case m.attached != nil:
+		pid = m.attached.pid
+	}
+	if pid <= 0 {
+		return nil
+	}
+	m.metricsSeq++
+	return sampleUsageCmd(m.process, pid, m.metricsSeq, 0)
+}
+
+// sampleProcessUsage reads CPU and RSS for the server process along with
+// the total system memory used to compute warning thresholds; server is
+// nil for a server llama-tui is only attached to.
+func sampleProcessUsage(server *serverProcess, pid int32) tea.Msg {
+	proc, err := process.NewProcess(pid)
+	if err != nil {
+		// Process not found or error accessing it - return nil to skip update
+		return nil
+	}
+
+	cpuPercent, err := proc.CPUPercent()
+	if err != nil {
+		// Skip CPU update on error
+		cpuPercent = 0
+	}
+	// Total CPU time, for the rate between samples
+	var cpuSeconds float64
+	if times, err := proc.Times(); err == nil {
+		cpuSeconds = times.User + times.System
+	}
+	at := time.Now()
+
+	var memTotal uint64
+	if vm, err := mem.VirtualMemory(); err == nil {
+		memTotal = vm.Total
+	}
+
+	memInfo, err := proc.MemoryInfo()
+	if err != nil {
+		// Skip memory update on error
 		return resourceUsageMsg{
-			cpuPercent: cpuPercent,
-			memRSSBytes: memInfo.RSS,
+			process:       server,
+			pid:           pid,
+			cpuPercent:    cpuPercent,
+			cpuSeconds:    cpuSeconds,
+			at:            at,
+			memRSSBytes:   0,
+			memTotalBytes: memTotal,
 		}
 	}
+
+	return resourceUsageMsg{
+		process:       server,
+		pid:           pid,
+		cpuPercent:    cpuPercent,
+		cpuSeconds:    cpuSeconds,
+		at:            at,
+		memRSSBytes:   memInfo.RSS,
+		memTotalBytes: memTotal,
+	}
+}
+
+// memoryThresholdPercent is a configured percentage threshold, def when
+// unset or out of range.
+func memoryThresholdPercent(v, def float64) float64 {
+	if v <= 0 || v > 100 {
+		return def
+	}
+	return v
 }
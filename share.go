@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultShareCommand opens a Cloudflare quick tunnel, which needs no
+// account and lasts as long as the process.
+var defaultShareCommand = []string{"cloudflared", "tunnel", "--no-autoupdate", "--url", "http://127.0.0.1:{port}"}
+
+const defaultShareURLPattern = `https://[-a-z0-9]+\.trycloudflare\.com`
+
+// quickShareConfig is the tunnel [Q] runs to share the served model.
+type quickShareConfig struct {
+	// Command runs the tunnel in the foreground; {port} is replaced with
+	// the port to expose. Defaults to a cloudflared quick tunnel
+	Command []string `json:"command"`
+	// URLPattern finds the public URL in the command's output
+	URLPattern string `json:"url_pattern"`
+}
+
+// quickShare is a running tunnel to the served model.
+type quickShare struct {
+	process *serverProcess
+	port    string
+	// found gets the public URL once the tunnel prints it
+	found chan string
+	exit  chan error
+	// last is the last line of output, a string, which explains most
+	// failures
+	last atomic.Value
+	// stopped is set once the tunnel is asked to close
+	stopped atomic.Bool
+}
+
+func (s *quickShare) lastLine() string {
+	line, _ := s.last.Load().(string)
+	return line
+}
+
+// stop tears the tunnel down; its exit is reported as usual.
+func (s *quickShare) stop() {
+	if s != nil {
+		s.stopped.Store(true)
+		s.process.stop()
+	}
+}
+
+// stopAndWait tears the tunnel down before llama-tui exits, so it
+// doesn't outlive it.
+func (s *quickShare) stopAndWait() {
+	if s != nil {
+		s.stopped.Store(true)
+		s.process.stopAndWait(stopGrace + time.Second)
+	}
+}
+
+// shareCommand is the tunnel's command line for port.
+func (c quickShareConfig) shareCommand(port string) []string {
+	command := c.Command
+	if len(command) == 0 {
+		command = defaultShareCommand
+	}
+	argv := make([]string, len(command))
+	for i, arg := range command {
+		argv[i] = strings.ReplaceAll(arg, "{port}", port)
+	}
+	return argv
+}
+
+func (c quickShareConfig) urlPattern() (*regexp.Regexp, error) {
+	if c.URLPattern == "" {
+		return regexp.MustCompile(defaultShareURLPattern), nil
+	}
+	return regexp.Compile(c.URLPattern)
+}
+
+// startShareCmd runs the tunnel to port, watching its output for the URL.
+func startShareCmd(c quickShareConfig, port string) tea.Cmd {
+	return func() tea.Msg {
+		argv := c.shareCommand(port)
+		pattern, err := c.urlPattern()
+		if err != nil {
+			return shareStartedMsg{err: fmt.Errorf("quick_share url_pattern: %w", err)}
+		}
+		if _, err := exec.LookPath(argv[0]); err != nil {
+			return shareStartedMsg{err: fmt.Errorf("%s not found - install it or set quick_share.command", argv[0])}
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+		setProcessGroup(cmd)
+		cmd.Cancel = func() error {
+			return signalProcessGroup(cmd, syscall.SIGTERM)
+		}
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			cancel()
+			return shareStartedMsg{err: err}
+		}
+		stderr, err := cmd.StderrPipe()
+		if err != nil {
+			cancel()
+			return shareStartedMsg{err: err}
+		}
+		if err := cmd.Start(); err != nil {
+			cancel()
+			return shareStartedMsg{err: fmt.Errorf("failed to start %s: %w", argv[0], err)}
+		}
+		s := &quickShare{port: port, found: make(chan string, 1), exit: make(chan error, 1)}
+		s.process = superviseProcess(ctx, cancel, cmd, s.exit)
+		// cloudflared prints the URL to stderr; other tunnels may use stdout
+		var reported atomic.Bool
+		watch := func(r io.Reader) {
+			scanner := bufio.NewScanner(r)
+			for scanner.Scan() {
+				line := scanner.Text()
+				if strings.TrimSpace(line) != "" {
+					s.last.Store(strings.TrimSpace(line))
+				}
+				if url := pattern.FindString(line); url != "" && reported.CompareAndSwap(false, true) {
+					s.found <- url
+				}
+			}
+		}
+		go watch(stdout)
+		go watch(stderr)
+		return shareStartedMsg{share: s}
+	}
+}
+
+// waitForShareURL reports the tunnel's URL, or nothing if it ends first.
+func waitForShareURL(s *quickShare) tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case url := <-s.found:
+			return shareURLMsg{share: s, url: url}
+		case <-s.process.ctx.Done():
+			return nil
+		}
+	}
+}
+
+func waitForShareExit(s *quickShare) tea.Cmd {
+	return func() tea.Msg {
+		err := <-s.exit
+		return shareExitedMsg{share: s, err: err}
+	}
+}
+
+// toggleShare opens a tunnel to the served model, through the proxy when
+// there is one so its rate limits apply, or tears the open one down.
+func (m appModel) toggleShare() (appModel, tea.Cmd) {
+	switch {
+	case m.readOnly:
+		m.statusLineText = fmt.Sprintf("Read-only: llama-tui pid %d manages this barn - [T] take over", m.lockOwner)
+		return m, nil
+	case m.sharePending:
+		m.statusLineText = "Still opening the quick share tunnel..."
+		return m, nil
+	case m.share != nil:
+		m.share.stop()
+		m.statusLineText = "Closing the quick share tunnel..."
+		return m, nil
+	case m.server != serverReady:
+		m.statusLineText = "Nothing to share - start a server first"
+		return m, nil
+	}
+	port := m.currentPort
+	if m.proxy != nil {
+		port = m.proxy.port
+	}
+	m.sharePending = true
+	m.statusLineText = "Opening a quick share tunnel to port " + port + "..."
+	return m, startShareCmd(m.config.QuickShare, port)
+}
+
+// handleShareStarted follows a tunnel that started, unless the server
+// stopped meanwhile.
+func (m appModel) handleShareStarted(msg shareStartedMsg) (appModel, tea.Cmd) {
+	m.sharePending = false
+	if msg.err != nil {
+		m.statusLineText = "Quick share: " + msg.err.Error()
+		m.eventError("share", m.currentModelName, m.statusLineText)
+		return m, nil
+	}
+	m.share = msg.share
+	if m.server != serverReady {
+		m.share.stop()
+	}
+	return m, tea.Batch(waitForShareURL(msg.share), waitForShareExit(msg.share))
+}
+
+// handleShareURL shows the tunnel's public URL.
+func (m appModel) handleShareURL(msg shareURLMsg) (appModel, tea.Cmd) {
+	if msg.share != m.share {
+		return m, nil
+	}
+	m.shareURL = msg.url
+	m.statusLineText = "Sharing " + m.displayName(m.currentModelName) + " at " + msg.url + " - anyone with the URL can use it; [Q] closes it"
+	m.event("share", m.currentModelName, "Shared port "+msg.share.port+" at "+msg.url)
+	return m, nil
+}
+
+// shareLabel is the header's note of the tunnel.
+func (m appModel) shareLabel() string {
+	switch {
+	case m.shareURL != "":
+		return "⇡ " + m.shareURL + " [ctrl+u] copy"
+	case m.share != nil || m.sharePending:
+		return "⇡ opening tunnel..."
+	}
+	return ""
+}
+
+// handleShareExited notes a tunnel that closed, whether asked to or not.
+func (m appModel) handleShareExited(msg shareExitedMsg) (appModel, tea.Cmd) {
+	if msg.share != m.share {
+		return m, nil
+	}
+	m.share, m.shareURL = nil, ""
+	switch {
+	case msg.share.stopped.Load() || msg.err == nil:
+		m.statusLineText = "Quick share closed"
+		m.event("share", m.currentModelName, "Closed the quick share tunnel")
+	default:
+		reason := msg.err.Error()
+		if last := msg.share.lastLine(); last != "" {
+			reason += ": " + last
+		}
+		m.statusLineText = "Quick share tunnel failed: " + reason
+		m.eventError("share", m.currentModelName, m.statusLineText)
+	}
+	return m, nil
+}
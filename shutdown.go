@@ -0,0 +1,488 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// shutdownSequence stops several servers in dependency order, a step at a
+// time: a step starts once every server of the one before has exited.
+type shutdownSequence struct {
+	// steps hold ports; "" is the served model
+	steps [][]string
+	// names are the models on those ports, kept for the display after
+	// they exit
+	names map[string]string
+	step  int
+	// quit exits llama-tui after the last step
+	quit bool
+}
+
+// modelMatches reports whether a depends_on entry names model: its exact
+// name or a case-insensitive part of it.
+func modelMatches(pattern, model string) bool {
+	return pattern == model || (pattern != "" && strings.Contains(strings.ToLower(model), strings.ToLower(pattern)))
+}
+
+// needs reports whether model depends on other by depends_on.
+func (c appConfig) needs(model, other string) bool {
+	for key, deps := range c.DependsOn {
+		if !modelMatches(key, model) {
+			continue
+		}
+		for _, dep := range deps {
+			if modelMatches(dep, other) && !modelMatches(dep, model) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// instanceName is the model served on port; "" is the served model.
+func (m appModel) instanceName(port string) string {
+	if port == "" {
+		return m.currentModelName
+	}
+	if i := m.sideServerIndex(port); i >= 0 {
+		return m.sideServers[i].item.name
+	}
+	return ""
+}
+
+// liveInstances lists the servers a shutdown can stop: the served model
+// unless attached, then the side servers not already exiting.
+func (m appModel) liveInstances() []string {
+	var ports []string
+	if m.server.serving() && m.attached == nil {
+		ports = append(ports, "")
+	}
+	for _, s := range m.sideServers {
+		switch s.state {
+		case serverStarting, serverLoading, serverReady:
+			ports = append(ports, s.port)
+		}
+	}
+	return ports
+}
+
+// liveDependents lists the servers that need port's model, directly or
+// through another server.
+func (m appModel) liveDependents(port string) []string {
+	live := m.liveInstances()
+	needed := []string{port}
+	var dependents []string
+	for len(needed) > 0 {
+		target := m.instanceName(needed[0])
+		needed = needed[1:]
+		for _, p := range live {
+			if p == port || slices.Contains(dependents, p) {
+				continue
+			}
+			if m.config.needs(m.instanceName(p), target) {
+				dependents = append(dependents, p)
+				needed = append(needed, p)
+			}
+		}
+	}
+	return dependents
+}
+
+// planShutdown orders ports into steps: a server stops only after every
+// server needing it, and the served model, the one behind the proxy,
+// stops last unless depends_on says otherwise. Servers caught in a
+// dependency cycle stop together.
+func (m appModel) planShutdown(ports []string) [][]string {
+	remaining := append([]string(nil), ports...)
+	var steps [][]string
+	for len(remaining) > 0 {
+		var step, rest []string
+		for _, p := range remaining {
+			needed := false
+			for _, q := range remaining {
+				if q != p && m.config.needs(m.instanceName(q), m.instanceName(p)) {
+					needed = true
+					break
+				}
+			}
+			if needed {
+				rest = append(rest, p)
+			} else {
+				step = append(step, p)
+			}
+		}
+		if len(step) == 0 {
+			step, rest = remaining, nil
+		}
+		if i := slices.Index(step, ""); i >= 0 && len(step) > 1 {
+			step = slices.Delete(step, i, i+1)
+			rest = append(rest, "")
+		}
+		steps = append(steps, step)
+		remaining = rest
+	}
+	return steps
+}
+
+// shutdownExited reports whether the server on port has stopped.
+func (m appModel) shutdownExited(port string) bool {
+	if port == "" {
+		return !m.server.running()
+	}
+	i := m.sideServerIndex(port)
+	return i < 0 || m.sideServers[i].state == serverStopped || m.sideServers[i].state == serverCrashed
+}
+
+// describe draws the sequence, e.g. "reranker:8082 ✓ → embedder:8081 ◐
+// → chat:8080 ○", marking servers stopped, stopping, and waiting.
+func (s shutdownSequence) describe(m appModel, marks bool) string {
+	steps := make([]string, len(s.steps))
+	for i, step := range s.steps {
+		servers := make([]string, len(step))
+		for j, port := range step {
+			label := s.names[port] + ":" + port
+			if port == "" {
+				label = s.names[port] + " (served)"
+			}
+			if marks {
+				switch {
+				case m.shutdownExited(port):
+					label += " ✓"
+				case i == s.step:
+					label += " ◐"
+				default:
+					label += " ○"
+				}
+			}
+			servers[j] = label
+		}
+		steps[i] = strings.Join(servers, " + ")
+	}
+	return strings.Join(steps, " → ")
+}
+
+// beginShutdown stops ports in dependency order, then quits when asked.
+func (m appModel) beginShutdown(ports []string, quit bool) (appModel, tea.Cmd) {
+	names := make(map[string]string, len(ports))
+	for _, p := range ports {
+		names[p] = m.instanceName(p)
+	}
+	if m.currentPort != "" {
+		names[""] += ":" + m.currentPort
+	}
+	m.shutdown = &shutdownSequence{steps: m.planShutdown(ports), names: names, quit: quit}
+	m.logEvent("[shutdown] Stopping in order: " + m.shutdown.describe(m, false))
+	return m.runShutdownStep()
+}
+
+// runShutdownStep stops the servers of the current step, moving on at
+// once when they have all exited already.
+func (m appModel) runShutdownStep() (appModel, tea.Cmd) {
+	seq := m.shutdown
+	var cmds []tea.Cmd
+	for _, port := range seq.steps[seq.step] {
+		if port == "" {
+			var cmd tea.Cmd
+			m, cmd = m.handleStop()
+			cmds = append(cmds, cmd)
+		} else {
+			m = m.stopSideServer(port)
+		}
+	}
+	m.statusLineText = "Stopping in order: " + seq.describe(m, true)
+	next, cmd := m.advanceShutdown()
+	return next, tea.Batch(append(cmds, cmd)...)
+}
+
+// advanceShutdown starts the next step once the current one's servers
+// have exited, and finishes the sequence after the last.
+func (m appModel) advanceShutdown() (appModel, tea.Cmd) {
+	seq := *m.shutdown
+	for _, port := range seq.steps[seq.step] {
+		if !m.shutdownExited(port) {
+			m.statusLineText = "Stopping in order: " + seq.describe(m, true)
+			return m, nil
+		}
+	}
+	seq.step++
+	if seq.step < len(seq.steps) {
+		m.shutdown = &seq
+		return m.runShutdownStep()
+	}
+	m.shutdown = nil
+	m.logEvent("[shutdown] Stopped " + seq.describe(m, false))
+	if seq.quit {
+		return m.handleQuit()
+	}
+	m.statusLineText = "Stopped in order: " + seq.describe(m, false)
+	return m, nil
+}
+
+// stopWithDependents stops the server on port ("" for the served model),
+// stopping the servers that need it first.
+func (m appModel) stopWithDependents(port string) (appModel, tea.Cmd) {
+	dependents := m.liveDependents(port)
+	if len(dependents) == 0 || m.shutdown != nil {
+		if port == "" {
+			return m.handleStop()
+		}
+		return m.stopSideServer(port), nil
+	}
+	return m.beginShutdown(append(dependents, port), false)
+}
+
+// dependencyNote names the live servers the model on port needs, for the
+// servers panel.
+func (m appModel) dependencyNote(port string) string {
+	model := m.instanceName(port)
+	var needs []string
+	for _, p := range m.liveInstances() {
+		if p != port && m.config.needs(model, m.instanceName(p)) {
+			needs = append(needs, m.instanceName(p))
+		}
+	}
+	if len(needs) == 0 {
+		return ""
+	}
+	return "needs " + strings.Join(needs, ", ")
+}
+
+// startSequence starts the models a launch depends on before it, a step
+// at a time: a step starts once every server of the one before is ready.
+// The dependencies run as side servers; the model launched goes last, the
+// way [enter] would start it.
+type startSequence struct {
+	target modelItem
+	// steps hold model names; the last is the target's alone
+	steps [][]string
+	// ports are where the dependencies started, by name
+	ports map[string]string
+	step  int
+}
+
+// dependencyPatterns lists the depends_on entries naming what model needs.
+func (c appConfig) dependencyPatterns(model string) []string {
+	var patterns []string
+	for key, deps := range c.DependsOn {
+		if !modelMatches(key, model) {
+			continue
+		}
+		for _, dep := range deps {
+			if !modelMatches(dep, model) && !slices.Contains(patterns, dep) {
+				patterns = append(patterns, dep)
+			}
+		}
+	}
+	slices.Sort(patterns)
+	return patterns
+}
+
+// liveModel reports whether a server llama-tui runs serves a model that
+// pattern names.
+func (m appModel) liveModel(pattern string) bool {
+	for _, p := range m.liveInstances() {
+		if modelMatches(pattern, m.instanceName(p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// missingDependencies lists the models item needs, directly or through
+// another dependency, that no server runs yet. A dependency must name one
+// listed model unless one of its matches is already running.
+func (m appModel) missingDependencies(item modelItem) ([]modelItem, error) {
+	var missing []modelItem
+	queue := []string{item.name}
+	for len(queue) > 0 {
+		model := queue[0]
+		queue = queue[1:]
+		for _, pattern := range m.config.dependencyPatterns(model) {
+			if m.liveModel(pattern) || modelMatches(pattern, item.name) {
+				continue
+			}
+			var matches []modelItem
+			for _, it := range m.allModelItems() {
+				if mi, ok := it.(modelItem); ok && mi.remoteURL == "" && modelMatches(pattern, mi.name) {
+					matches = append(matches, mi)
+				}
+			}
+			switch {
+			case len(matches) == 0:
+				return nil, fmt.Errorf("%s needs %q (depends_on), which matches no listed model", model, pattern)
+			case len(matches) > 1:
+				names := make([]string, len(matches))
+				for i, mi := range matches {
+					names[i] = mi.name
+				}
+				return nil, fmt.Errorf("%s needs %q (depends_on), which matches %s - start one first", model, pattern, strings.Join(names, ", "))
+			}
+			dep := matches[0]
+			if !slices.ContainsFunc(missing, func(mi modelItem) bool { return mi.name == dep.name }) {
+				missing = append(missing, dep)
+				queue = append(queue, dep.name)
+			}
+		}
+	}
+	return missing, nil
+}
+
+// planStart orders the dependencies into steps, the reverse of a
+// shutdown: a model starts only after every model it needs. Models caught
+// in a dependency cycle start together.
+func (m appModel) planStart(deps []modelItem) [][]string {
+	var remaining []string
+	for _, d := range deps {
+		remaining = append(remaining, d.name)
+	}
+	var steps [][]string
+	for len(remaining) > 0 {
+		var step, rest []string
+		for _, name := range remaining {
+			waits := false
+			for _, other := range remaining {
+				if other != name && m.config.needs(name, other) {
+					waits = true
+					break
+				}
+			}
+			if waits {
+				rest = append(rest, name)
+			} else {
+				step = append(step, name)
+			}
+		}
+		if len(step) == 0 {
+			step, rest = remaining, nil
+		}
+		steps = append(steps, step)
+		remaining = rest
+	}
+	return steps
+}
+
+// startState is how far the dependency name has come: "ready", "starting",
+// "failed", or "" before its step.
+func (s startSequence) startState(m appModel, name string) string {
+	port, ok := s.ports[name]
+	if !ok {
+		return ""
+	}
+	i := m.sideServerIndex(port)
+	if i < 0 {
+		return "failed"
+	}
+	switch m.sideServers[i].state {
+	case serverReady:
+		return "ready"
+	case serverStopped, serverCrashed, serverDraining:
+		return "failed"
+	}
+	return "starting"
+}
+
+// describe draws the sequence, e.g. "embed ✓ → rerank ◐ → qwen ○",
+// marking models ready, starting, failed, and waiting.
+func (s startSequence) describe(m appModel, marks bool) string {
+	steps := make([]string, len(s.steps))
+	for i, step := range s.steps {
+		models := make([]string, len(step))
+		for j, name := range step {
+			label := name
+			if port, ok := s.ports[name]; ok {
+				label += ":" + port
+			}
+			if marks {
+				switch s.startState(m, name) {
+				case "ready":
+					label += " ✓"
+				case "starting":
+					label += " ◐"
+				case "failed":
+					label += " ✕"
+				default:
+					label += " ○"
+				}
+			}
+			models[j] = label
+		}
+		steps[i] = strings.Join(models, " + ")
+	}
+	return strings.Join(steps, " → ")
+}
+
+// beginStartSequence starts deps in dependency order, then item.
+func (m appModel) beginStartSequence(item modelItem, deps []modelItem) (appModel, tea.Cmd) {
+	steps := append(m.planStart(deps), []string{item.name})
+	m.starting = &startSequence{target: item, steps: steps, ports: map[string]string{}}
+	m.logEvent("[startup] Starting in order: " + m.starting.describe(m, false))
+	return m.runStartStep()
+}
+
+// runStartStep starts the current step's dependencies as side servers, or
+// the target once they are all up.
+func (m appModel) runStartStep() (appModel, tea.Cmd) {
+	seq := *m.starting
+	if seq.step == len(seq.steps)-1 {
+		m.starting = nil
+		m.logEvent("[startup] Dependencies ready: " + seq.describe(m, false))
+		return m.requestStart(seq.target)
+	}
+	var cmds []tea.Cmd
+	ports := maps.Clone(seq.ports)
+	for _, name := range seq.steps[seq.step] {
+		item, ok := m.findModelByName(name)
+		if !ok {
+			return m.abandonStartSequence(name + " is no longer listed")
+		}
+		if m, ok = m.schedule(item, ""); !ok {
+			return m.abandonStartSequence(name + ": " + m.statusLineText)
+		}
+		var cmd tea.Cmd
+		m, cmd = m.startSideServer(item)
+		i := slices.IndexFunc(m.sideServers, func(s sideServer) bool {
+			return s.item.name == name && s.state == serverStarting
+		})
+		if i < 0 {
+			return m.abandonStartSequence(name + ": " + m.statusLineText)
+		}
+		ports[name] = m.sideServers[i].port
+		cmds = append(cmds, cmd)
+	}
+	seq.ports = ports
+	m.starting = &seq
+	m.statusLineText = "Starting in order: " + seq.describe(m, true)
+	return m, tea.Batch(cmds...)
+}
+
+// advanceStartSequence starts the next step once the current one's
+// servers are ready, and gives up when one of them exits.
+func (m appModel) advanceStartSequence() (appModel, tea.Cmd) {
+	seq := *m.starting
+	for _, name := range seq.steps[seq.step] {
+		switch seq.startState(m, name) {
+		case "failed":
+			return m.abandonStartSequence(name + " exited before it was ready")
+		case "starting":
+			m.statusLineText = "Starting in order: " + seq.describe(m, true)
+			return m, nil
+		}
+	}
+	seq.step++
+	m.starting = &seq
+	return m.runStartStep()
+}
+
+// abandonStartSequence stops ordering the launch, leaving the
+// dependencies already started running.
+func (m appModel) abandonStartSequence(reason string) (appModel, tea.Cmd) {
+	seq := m.starting
+	m.starting = nil
+	m.statusLineText = "Not starting " + seq.target.name + ": " + reason
+	m.eventError("start", seq.target.name, "Dependencies not started: "+reason)
+	return m, nil
+}
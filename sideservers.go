@@ -0,0 +1,507 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// sideServer is a server started while the managed one is running, e.g. a
+// small draft model beside a large one. The managed server keeps the proxy,
+// metrics, and session history; side servers run on their own port, with
+// their own log buffer, until stopped.
+type sideServer struct {
+	item    modelItem
+	port    string
+	started *startedWithStateMsg
+	state   serverState
+	err     error
+	// color is the instance color of its tag and panel row
+	color int
+	// logs is shared by copies of the model, like the main log buffer
+	logs *bytes.Buffer
+}
+
+// describe summarizes the side server for the status bar and panel.
+func (s sideServer) describe() string {
+	state := "starting"
+	switch s.state {
+	case serverLoading:
+		state = "loading"
+	case serverReady:
+		state = "ready"
+	case serverDraining:
+		state = "stopping"
+	case serverStopped:
+		state = "stopped"
+	case serverCrashed:
+		state = "crashed"
+	}
+	return fmt.Sprintf("%s:%s (%s)", s.item.name, s.port, state)
+}
+
+// sideServerIndex finds the side server on port, or -1.
+func (m appModel) sideServerIndex(port string) int {
+	for i, s := range m.sideServers {
+		if s.port == port {
+			return i
+		}
+	}
+	return -1
+}
+
+// withSideServer returns the model with the side server on port changed
+// by update, copying the slice so earlier models are left alone.
+func (m appModel) withSideServer(port string, update func(*sideServer)) appModel {
+	i := m.sideServerIndex(port)
+	if i < 0 {
+		return m
+	}
+	servers := append([]sideServer(nil), m.sideServers...)
+	update(&servers[i])
+	m.sideServers = servers
+	return m
+}
+
+// sideServerStates summarizes the side servers' states, to notice any
+// of them changing.
+func sideServerStates(m appModel) string {
+	var b strings.Builder
+	for _, s := range m.sideServers {
+		fmt.Fprintf(&b, "%s=%d ", s.port, s.state)
+	}
+	return b.String()
+}
+
+// liveSideServers counts side servers whose process is still up.
+func (m appModel) liveSideServers() int {
+	n := 0
+	for _, s := range m.sideServers {
+		if s.state != serverStopped && s.state != serverCrashed {
+			n++
+		}
+	}
+	return n
+}
+
+// startSideServer launches item next to the managed server, on its saved
+// or automatic port when free, else the next free one.
+func (m appModel) startSideServer(item modelItem) (appModel, tea.Cmd) {
+	switch {
+	case item.remoteURL != "":
+		m.statusLineText = "Download " + item.name + " first; side servers start local models only"
+		return m, nil
+	case item.name == m.currentModelName:
+		m.statusLineText = item.name + " is already being served"
+		return m, nil
+	case m.unavailable[item.path] != "":
+		m.statusLineText = fmt.Sprintf("%s is unavailable: %s", item.name, m.unavailable[item.path])
+		return m, checkPathsCmd([]list.Item{item})
+	}
+	for _, s := range m.sideServers {
+		if s.item.name == item.name && s.state != serverStopped && s.state != serverCrashed {
+			m.statusLineText = fmt.Sprintf("%s is already running on port %s", item.name, s.port)
+			return m, nil
+		}
+	}
+	port, err := m.freeLaunchPort(item)
+	if err != nil {
+		m.statusLineText = fmt.Sprintf("Side server: %v", err)
+		return m, nil
+	}
+	portStr := strconv.Itoa(port)
+	// An exited entry on the same port is replaced
+	if i := m.sideServerIndex(portStr); i >= 0 {
+		m.sideServers = append(append([]sideServer(nil), m.sideServers[:i]...), m.sideServers[i+1:]...)
+	}
+	color := (m.serverColor + len(m.sideServers) + 1) % len(m.styles.instances)
+	if m.standby != nil && color == m.standby.color {
+		color = (color + 1) % len(m.styles.instances)
+	}
+	m.sideServers = append(append([]sideServer(nil), m.sideServers...), sideServer{
+		item: item, port: portStr, state: serverStarting, color: color, logs: &bytes.Buffer{},
+	})
+	m.ports = m.ports.with(port, item.name)
+	m.startCombinedLogs()
+	m.statusLineText = fmt.Sprintf("Starting %s beside %s on port %s - [v] shows its logs", item.name, m.currentModelName, portStr)
+	m.logEvent(fmt.Sprintf("[%s] Starting %s", portStr, item.name))
+	start := m.startServerCmd(item, portStr)
+	return m, func() tea.Msg {
+		switch msg := start().(type) {
+		case startedWithStateMsg:
+			return sideStartedMsg{port: portStr, started: msg}
+		case startErrorMsg:
+			return sideStartedMsg{port: portStr, err: msg.err}
+		}
+		return nil
+	}
+}
+
+// stopSideServer stops the side server on port; one that already exited is
+// removed from the list instead.
+func (m appModel) stopSideServer(port string) appModel {
+	i := m.sideServerIndex(port)
+	if i < 0 {
+		return m
+	}
+	s := m.sideServers[i]
+	switch s.state {
+	case serverStopped, serverCrashed:
+		m.sideServers = append(append([]sideServer(nil), m.sideServers[:i]...), m.sideServers[i+1:]...)
+		if m.logView == port {
+			m.logView = ""
+			m.logsViewport.SetContent(m.logsContent())
+		}
+		return m
+	case serverDraining:
+		return m
+	}
+	next := serverDraining
+	if s.started != nil {
+		s.started.process.stop()
+	} else {
+		// Still launching: the process is stopped once it reports in
+		m.releaseSidePort(port)
+		next = serverStopped
+	}
+	m = m.withSideServer(port, func(s *sideServer) { s.state = next })
+	m.statusLineText = fmt.Sprintf("Stopping %s on port %s...", s.item.name, port)
+	return m
+}
+
+// stopSideServers stops every side server, for quit and stop-all.
+func (m appModel) stopSideServers() (appModel, bool) {
+	stopped := false
+	for _, s := range m.sideServers {
+		if s.state == serverStopped || s.state == serverCrashed || s.state == serverDraining {
+			continue
+		}
+		m = m.stopSideServer(s.port)
+		stopped = true
+	}
+	return m, stopped
+}
+
+// waitForSideServers stops the side servers that have started and waits
+// for them together, for exits where nothing else will: after the UI has
+// quit, or after a crash.
+func (m appModel) waitForSideServers() {
+	var wg sync.WaitGroup
+	for _, s := range m.sideServers {
+		if s.started == nil || s.started.process == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(p *serverProcess) {
+			defer wg.Done()
+			p.stopAndWait(stopGrace + time.Second)
+		}(s.started.process)
+	}
+	wg.Wait()
+}
+
+func (m *appModel) releaseSidePort(port string) {
+	if portNum, err := strconv.Atoi(port); err == nil {
+		m.ports = m.ports.without(portNum)
+	}
+}
+
+// appendSideLogLine adds a line to a side server's own log buffer, shown
+// when its logs are selected.
+func (m *appModel) appendSideLogLine(port, text string) {
+	i := m.sideServerIndex(port)
+	if i < 0 {
+		return
+	}
+	buf := m.sideServers[i].logs
+	line := m.colorLog(sanitizeLogLine(text))
+	_, _ = buf.WriteString(line)
+	_, _ = buf.WriteString("\n")
+	m.appendCombinedLine(m.instanceTag(m.sideServers[i].color, port), line)
+	if buf.Len() > m.logBufferLimit() {
+		trimmed := trimLogBuffer(buf.Bytes())
+		buf.Reset()
+		_, _ = buf.Write(trimmed)
+	}
+	if m.logView != port {
+		return
+	}
+	if m.lowMemory {
+		m.logsDirty = true
+		return
+	}
+	m.logsViewport.SetContent(m.logsContent())
+	m.logsViewport.GotoBottom()
+}
+
+// rawLogsContent is the log buffer selected with [v]: the managed
+// server's, a side server's, or every server's interleaved.
+func (m appModel) rawLogsContent() string {
+	if m.logView == allLogsView && m.combinedLogs != nil {
+		return m.combinedLogs.String()
+	}
+	if m.logView != "" {
+		if i := m.sideServerIndex(m.logView); i >= 0 {
+			return m.sideServers[i].logs.String()
+		}
+	}
+	return m.logBuffer.String()
+}
+
+// logViewTitle names the selected log buffer for the Logs title.
+func (m appModel) logViewTitle() string {
+	if m.logView == "" {
+		if len(m.sideServers) == 0 {
+			return ""
+		}
+		return " · main"
+	}
+	if m.logView == allLogsView {
+		return " · all servers"
+	}
+	if i := m.sideServerIndex(m.logView); i >= 0 {
+		return " · " + m.sideServers[i].describe()
+	}
+	return ""
+}
+
+// cycleLogView switches the logs panel to the next server's buffer.
+func (m appModel) cycleLogView() appModel {
+	if len(m.sideServers) == 0 && m.logView == allLogsView {
+		return m.showLogView("")
+	}
+	if len(m.sideServers) == 0 {
+		m.statusLineText = "Only one server's logs to show ([enter] on another model runs it beside this one)"
+		return m
+	}
+	views := []string{""}
+	for _, s := range m.sideServers {
+		views = append(views, s.port)
+	}
+	views = append(views, allLogsView)
+	next := ""
+	for i, v := range views {
+		if v == m.logView {
+			next = views[(i+1)%len(views)]
+		}
+	}
+	return m.showLogView(next)
+}
+
+func (m appModel) showLogView(port string) appModel {
+	m.logView = port
+	m.logsViewport.SetContent(m.logsContent())
+	m.logsViewport.GotoBottom()
+	if port == "" {
+		m.statusLineText = "Showing the main server's logs"
+	} else if port == allLogsView {
+		m.statusLineText = "Showing every server's logs, tagged by port ([v] shows one at a time)"
+	} else if i := m.sideServerIndex(port); i >= 0 {
+		m.statusLineText = "Showing logs of " + m.sideServers[i].describe()
+	}
+	return m
+}
+
+func waitForSideLog(port string, ch chan string) tea.Cmd {
+	return func() tea.Msg {
+		line, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return sideLogMsg{port: port, ch: ch, text: line}
+	}
+}
+
+func waitForSideReady(port string, ch chan bool) tea.Cmd {
+	return func() tea.Msg {
+		return sideReadyMsg{port: port, readyChan: ch, ready: <-ch}
+	}
+}
+
+func waitForSideExit(port string, ch chan error) tea.Cmd {
+	return func() tea.Msg {
+		err := <-ch
+		return sideExitedMsg{port: port, exitChan: ch, err: err}
+	}
+}
+
+// handleSideServerMsg follows side servers through their lifecycle.
+func (m appModel) handleSideServerMsg(msg tea.Msg) (appModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case sideStartedMsg:
+		i := m.sideServerIndex(msg.port)
+		if i < 0 || m.sideServers[i].state != serverStarting {
+			// Stopped while launching
+			if msg.err == nil {
+				msg.started.process.stop()
+			}
+			return m, nil
+		}
+		if msg.err != nil {
+			m.releaseSidePort(msg.port)
+			m = m.withSideServer(msg.port, func(s *sideServer) { s.state, s.err = serverCrashed, msg.err })
+			m.statusLineText = fmt.Sprintf("%s failed to start: %v", m.sideServers[i].item.name, msg.err)
+			m.logEvent(fmt.Sprintf("[%s] %s", msg.port, m.statusLineText))
+			return m, nil
+		}
+		started := msg.started
+		m = m.withSideServer(msg.port, func(s *sideServer) { s.started, s.state = &started, serverLoading })
+		return m, tea.Batch(
+			waitForSideLog(msg.port, started.logChan),
+			waitForSideReady(msg.port, started.readyChan),
+			waitForSideExit(msg.port, started.exitChan),
+		)
+
+	case sideLogMsg:
+		i := m.sideServerIndex(msg.port)
+		if i < 0 || m.sideServers[i].started == nil || m.sideServers[i].started.logChan != msg.ch {
+			return m, nil
+		}
+		m.appendSideLogLine(msg.port, msg.text)
+		return m, waitForSideLog(msg.port, msg.ch)
+
+	case sideReadyMsg:
+		i := m.sideServerIndex(msg.port)
+		if i < 0 || m.sideServers[i].started == nil || m.sideServers[i].started.readyChan != msg.readyChan {
+			return m, nil
+		}
+		if msg.ready && m.sideServers[i].state == serverLoading {
+			m = m.withSideServer(msg.port, func(s *sideServer) { s.state = serverReady })
+			m.statusLineText = fmt.Sprintf("%s ready on port %s", m.sideServers[i].item.name, msg.port)
+			m.logEvent(fmt.Sprintf("[%s] %s is ready", msg.port, m.sideServers[i].item.name))
+		}
+		return m, nil
+
+	case sideExitedMsg:
+		i := m.sideServerIndex(msg.port)
+		if i < 0 || m.sideServers[i].started == nil || m.sideServers[i].started.exitChan != msg.exitChan {
+			return m, nil
+		}
+		s := m.sideServers[i]
+		m.releaseSidePort(msg.port)
+		state, event := serverStopped, "stopped"
+		if s.state != serverDraining && msg.err != nil && !errors.Is(msg.err, context.Canceled) {
+			state, event = serverCrashed, fmt.Sprintf("exited: %v", msg.err)
+		}
+		m = m.withSideServer(msg.port, func(s *sideServer) { s.state, s.err = state, msg.err })
+		m.appendSideLogLine(msg.port, "[ui] Server "+event)
+		m.logEvent(fmt.Sprintf("[%s] %s %s", msg.port, s.item.name, event))
+		if state == serverCrashed {
+			m.statusLineText = fmt.Sprintf("%s on port %s %s - [v] shows its logs", s.item.name, msg.port, event)
+		}
+		return m, nil
+	}
+	return m, nil
+}
+
+// serversView is the Running Servers panel: the managed server and every
+// side server, with keys to show their logs or stop them.
+type serversView struct {
+	cursor int
+}
+
+// serverRows lists the panel's rows; "" is the managed server.
+func (m appModel) serverRows() []string {
+	var rows []string
+	if m.server.running() || m.server == serverStarting {
+		rows = append(rows, "")
+	}
+	for _, s := range m.sideServers {
+		rows = append(rows, s.port)
+	}
+	return rows
+}
+
+func (m appModel) handleServersKey(keyStr string) (appModel, tea.Cmd) {
+	v := *m.serversView
+	rows := m.serverRows()
+	v.cursor = min(v.cursor, max(len(rows)-1, 0))
+	switch keyStr {
+	case "up", "k":
+		if v.cursor > 0 {
+			v.cursor--
+		}
+	case "down", "j":
+		if v.cursor < len(rows)-1 {
+			v.cursor++
+		}
+	case "enter", "v":
+		if v.cursor < len(rows) {
+			m.serversView = nil
+			return m.showLogView(rows[v.cursor]), nil
+		}
+	case "c":
+		if v.cursor >= len(rows) {
+			break
+		}
+		m.serversView = nil
+		return m.openABChat(rows[v.cursor])
+	case "x":
+		if v.cursor >= len(rows) || m.observerRefuses("stop servers") {
+			break
+		}
+		m.serversView = &v
+		return m.stopWithDependents(rows[v.cursor])
+	case "esc", "I", "q":
+		m.serversView = nil
+		return m, nil
+	}
+	m.serversView = &v
+	return m, nil
+}
+
+// renderServersView draws the Running Servers panel, followed by the
+// external endpoints being monitored.
+func (m appModel) renderServersView(width int) string {
+	rows := m.serverRows()
+	footer := m.styles.help.Render("[enter] show logs  [c] A/B chat with another  [x] stop (or clear an exited one)  [I] or [esc] close")
+	var lines []string
+	if len(rows) == 0 {
+		lines = append(lines, m.styles.disabled.Render("No servers running - [enter] on a model starts one"))
+	}
+	if m.shutdown != nil {
+		lines = append(lines, m.styles.usageWarn.Render(ellipsize("Stopping in order: "+m.shutdown.describe(m, true), width)), "")
+	}
+	if m.starting != nil {
+		lines = append(lines, m.styles.usageWarn.Render(ellipsize("Starting in order: "+m.starting.describe(m, true), width)), "")
+	}
+	for i, port := range rows {
+		var line string
+		style := m.instanceStyle(m.serverColor)
+		if port == "" {
+			label, _ := m.statusChip()
+			line = fmt.Sprintf("%s:%s  %s  (main: proxy, metrics)", m.currentModelName, m.currentPort, label)
+		} else {
+			s := m.sideServers[m.sideServerIndex(port)]
+			style = m.instanceStyle(s.color)
+			line = s.describe()
+			if s.state == serverCrashed && s.err != nil {
+				line += "  " + s.err.Error()
+			}
+		}
+		if note := m.dependencyNote(port); note != "" {
+			line += "  · " + note
+		}
+		if port == m.logView {
+			line += "  · logs shown"
+		}
+		cursor := "  "
+		if i == m.serversView.cursor {
+			cursor = "> "
+		}
+		lines = append(lines, cursor+style.Render("●")+" "+ellipsize(line, width-4))
+	}
+	if len(m.config.ExternalEndpoints) > 0 {
+		lines = append(lines, "", m.styles.help.Render("External endpoints (monitored only)"))
+		lines = append(lines, m.renderExternalRows(width)...)
+	}
+	return strings.Join(lines, "\n") + "\n\n" + footer
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// sleepSignalRule picks logind's PrepareForSleep signal, sent with true
+// before the machine sleeps and false after it wakes.
+const sleepSignalRule = "type='signal',interface='org.freedesktop.login1.Manager',member='PrepareForSleep'"
+
+// sleepWatch follows logind through dbus-monitor so the power policy can
+// act before the machine sleeps. While it holds a delay inhibitor, logind
+// waits (up to its InhibitDelayMaxSec, 5 seconds by default) for it to be
+// released before sleeping.
+type sleepWatch struct {
+	ctx     context.Context
+	monitor *exec.Cmd
+	signals chan bool
+
+	mu      sync.Mutex
+	inhibit *exec.Cmd
+}
+
+// startSleepWatchCmd starts following logind, until ctx ends.
+func startSleepWatchCmd(ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		if _, err := exec.LookPath("dbus-monitor"); err != nil {
+			return sleepWatchStartedMsg{err: fmt.Errorf("dbus-monitor not found")}
+		}
+		cmd := exec.CommandContext(ctx, "dbus-monitor", "--system", sleepSignalRule)
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return sleepWatchStartedMsg{err: err}
+		}
+		if err := cmd.Start(); err != nil {
+			return sleepWatchStartedMsg{err: fmt.Errorf("failed to start dbus-monitor: %w", err)}
+		}
+		w := &sleepWatch{ctx: ctx, monitor: cmd, signals: make(chan bool)}
+		go func() {
+			defer close(w.signals)
+			// The signal's header line names the member; its argument
+			// follows as "boolean true" or "boolean false"
+			scanner := bufio.NewScanner(stdout)
+			signal := false
+			for scanner.Scan() {
+				line := strings.TrimSpace(scanner.Text())
+				switch {
+				case strings.Contains(line, "member=PrepareForSleep"):
+					signal = true
+				case signal && strings.HasPrefix(line, "boolean "):
+					signal = false
+					select {
+					case w.signals <- line == "boolean true":
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+			_ = cmd.Wait()
+		}()
+		return sleepWatchStartedMsg{watch: w, err: w.hold()}
+	}
+}
+
+// waitForSleepSignal reports the next PrepareForSleep, or nothing once
+// the watch has ended.
+func waitForSleepSignal(w *sleepWatch) tea.Cmd {
+	return func() tea.Msg {
+		sleeping, ok := <-w.signals
+		if !ok {
+			return nil
+		}
+		return sleepSignalMsg{watch: w, sleeping: sleeping}
+	}
+}
+
+// hold takes a delay inhibitor, unless one is held already.
+func (w *sleepWatch) hold() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.inhibit != nil {
+		return nil
+	}
+	cmd := exec.CommandContext(w.ctx, "systemd-inhibit", "--what=sleep", "--mode=delay",
+		"--who="+appTitle, "--why=Stopping the server before sleep", "sleep", "infinity")
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("could not delay sleep: %w", err)
+	}
+	go func() { _ = cmd.Wait() }()
+	w.inhibit = cmd
+	return nil
+}
+
+// release lets the machine sleep.
+func (w *sleepWatch) release() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.inhibit != nil {
+		_ = w.inhibit.Process.Kill()
+		w.inhibit = nil
+	}
+}
+
+// close ends the watch and lets the machine sleep, before llama-tui exits.
+// The context ending does the same, but not before the exit.
+func (w *sleepWatch) close() {
+	if w == nil {
+		return
+	}
+	w.release()
+	_ = w.monitor.Process.Kill()
+}
+
+// releaseSleepCmd lets the machine sleep once p has exited, or at once
+// when there is no process to wait for.
+func releaseSleepCmd(w *sleepWatch, p *serverProcess) tea.Cmd {
+	return func() tea.Msg {
+		if p != nil {
+			select {
+			case <-p.done:
+			case <-time.After(stopGrace + time.Second):
+			}
+		}
+		w.release()
+		return nil
+	}
+}
+
+// holdSleepCmd takes the inhibitor again after a wake; a failure is
+// reported as the watch's, without starting it again.
+func holdSleepCmd(w *sleepWatch) tea.Cmd {
+	return func() tea.Msg {
+		if err := w.hold(); err != nil {
+			return sleepWatchStartedMsg{err: err}
+		}
+		return nil
+	}
+}
+
+// handleSleepSignal applies the power policy before the machine sleeps
+// and undoes a pause after it wakes. A stopped server is restarted by the
+// battery check, with resume_on_ac, once on mains power.
+func (m appModel) handleSleepSignal(msg sleepSignalMsg) (appModel, tea.Cmd) {
+	next := waitForSleepSignal(msg.watch)
+	if !msg.sleeping {
+		if m.powerPaused {
+			if err := m.process.setSuspended(false); err != nil {
+				m.logEvent(fmt.Sprintf("[power] Could not resume server: %v", err))
+			} else {
+				m.powerPaused = false
+				m.logEvent("[power] Awake - server resumed")
+			}
+		}
+		return m, tea.Batch(next, holdSleepCmd(msg.watch))
+	}
+	if !m.server.serving() || m.powerPaused {
+		return m, tea.Batch(next, releaseSleepCmd(msg.watch, nil))
+	}
+	if m.config.Power.stops() {
+		p := m.process
+		m.powerStopped = &startupAction{model: m.currentModelName, port: m.currentPort, args: m.launchArgs}
+		m.logEvent("[power] Going to sleep - stopping server")
+		m, cmd := m.handleStop()
+		return m, tea.Batch(cmd, next, releaseSleepCmd(msg.watch, p))
+	}
+	if err := m.process.setSuspended(true); err != nil {
+		m.logEvent(fmt.Sprintf("[power] Could not pause server: %v", err))
+	} else {
+		m.powerPaused = true
+		m.logEvent("[power] Going to sleep - server paused")
+	}
+	return m, tea.Batch(next, releaseSleepCmd(msg.watch, nil))
+}
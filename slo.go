@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Defaults for the latency objective.
+const (
+	defaultSLOPercentile = 95
+	defaultSLOWindow     = 50
+	// sloMinRequests is how many requests are needed before the objective
+	// is judged, so one slow first request doesn't page anyone.
+	sloMinRequests = 5
+)
+
+// promptTiming is the prompt processing line of llama-server's per-request
+// timings, e.g. "prompt eval time = 12.00 ms / 24 tokens ( 0.50 ms per token, ...)".
+var promptTiming = regexp.MustCompile(`prompt eval time =\s*([\d.]+) ms`)
+
+// tokenTiming is the generation line, for its milliseconds per token.
+var tokenTiming = regexp.MustCompile(`\beval time =\s*[\d.]+ ms /\s*\d+ (?:tokens|runs)\s*\(\s*([\d.]+) ms per token`)
+
+// latencySLO is an objective on the served model's latency, checked
+// against the timings the server logs for each request.
+type latencySLO struct {
+	// Metric is "token", milliseconds per generated token, or
+	// "first_token", the prompt processing before the first token;
+	// default "token".
+	Metric string `json:"metric"`
+	// Percentile of recent requests held to the target; default 95.
+	Percentile float64 `json:"percentile"`
+	// TargetMS is the most that percentile may take; 0 disables the
+	// objective.
+	TargetMS float64 `json:"target_ms"`
+	// Window is how many recent requests are judged; default 50.
+	Window int `json:"window"`
+}
+
+func (s latencySLO) enabled() bool {
+	return s.TargetMS > 0
+}
+
+func (s latencySLO) metric() string {
+	if s.Metric == "first_token" {
+		return s.Metric
+	}
+	return "token"
+}
+
+func (s latencySLO) percentile() float64 {
+	if s.Percentile <= 0 || s.Percentile > 100 {
+		return defaultSLOPercentile
+	}
+	return s.Percentile
+}
+
+func (s latencySLO) window() int {
+	if s.Window <= 0 {
+		return defaultSLOWindow
+	}
+	return s.Window
+}
+
+// describe names what is measured, e.g. "p95 token latency".
+func (s latencySLO) describe() string {
+	name := "token latency"
+	if s.metric() == "first_token" {
+		name = "time to first token"
+	}
+	return "p" + strconv.FormatFloat(s.percentile(), 'f', -1, 64) + " " + name
+}
+
+// sloState follows the objective over the running server's requests.
+type sloState struct {
+	// samples are the latest requests' latencies in ms, oldest first
+	samples  []float64
+	value    float64
+	breached bool
+}
+
+// sample reads the objective's metric from a server log line.
+func (s latencySLO) sample(line string) (float64, bool) {
+	var match []string
+	if s.metric() == "first_token" {
+		match = promptTiming.FindStringSubmatch(line)
+	} else if !strings.Contains(line, "prompt eval") {
+		match = tokenTiming.FindStringSubmatch(line)
+	}
+	if match == nil {
+		return 0, false
+	}
+	ms, err := strconv.ParseFloat(match[1], 64)
+	return ms, err == nil
+}
+
+// noteSLOLine adds a request's timing to the objective and judges it,
+// recording an event when it is breached or met again.
+func (m *appModel) noteSLOLine(line string) {
+	slo := m.config.SLO
+	if !slo.enabled() {
+		return
+	}
+	ms, ok := slo.sample(line)
+	if !ok {
+		return
+	}
+	m.slo.samples = append(m.slo.samples, ms)
+	if over := len(m.slo.samples) - slo.window(); over > 0 {
+		m.slo.samples = append(m.slo.samples[:0], m.slo.samples[over:]...)
+	}
+	if len(m.slo.samples) < min(sloMinRequests, slo.window()) {
+		return
+	}
+	sorted := append([]float64(nil), m.slo.samples...)
+	sort.Float64s(sorted)
+	m.slo.value = sorted[int(float64(len(sorted)-1)*slo.percentile()/100)]
+	breached := m.slo.value > slo.TargetMS
+	if breached == m.slo.breached {
+		return
+	}
+	m.slo.breached = breached
+	detail := fmt.Sprintf("%s %s, target %s, over the last %s", slo.describe(), formatSLOValue(m.slo.value), formatSLOValue(slo.TargetMS), pluralize(len(m.slo.samples), "request"))
+	if breached {
+		m.statusLineText = "SLO breached: " + detail
+		m.eventError("slo", m.currentModelName, "Breached: "+detail)
+		return
+	}
+	m.event("slo", m.currentModelName, "Met again: "+detail)
+}
+
+func formatSLOValue(ms float64) string {
+	return strconv.FormatFloat(ms, 'f', 1, 64) + " ms"
+}
+
+// sloSegment is the status bar's note of the objective, red while it is
+// breached; nothing until enough requests are in.
+func (m appModel) sloSegment() (statusSegment, bool) {
+	slo := m.config.SLO
+	if !slo.enabled() || !m.server.running() || m.slo.value == 0 {
+		return statusSegment{}, false
+	}
+	p := "p" + strconv.FormatFloat(slo.percentile(), 'f', -1, 64)
+	value := fmt.Sprintf("%s %s ≤ %s", p, formatSLOValue(m.slo.value), formatSLOValue(slo.TargetMS))
+	if m.slo.breached {
+		value = fmt.Sprintf("%s %s > %s", p, formatSLOValue(m.slo.value), formatSLOValue(slo.TargetMS))
+		return statusSegment{label: "SLO: ", value: value, style: m.styles.usageCritical, priority: 2}, true
+	}
+	return statusSegment{label: "SLO: ", value: value, style: m.styles.accent, priority: 5}, true
+}
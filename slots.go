@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// slotFile is a KV cache llama-server saved under --slot-save-path.
+type slotFile struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+// slotView is the screen for saving and restoring llama-server's slots
+// (their prompt caches), so a long system prompt survives a restart.
+type slotView struct {
+	dir    string
+	files  []slotFile
+	cursor int
+	// slot is the server slot saves and restores apply to
+	slot int
+	err  error
+	note string
+}
+
+// slotSaveDir is the --slot-save-path of the running server, else of the
+// next launch.
+func (m appModel) slotSaveDir() string {
+	launchArgs := m.launchArgs
+	if item, ok := m.modelsList.SelectedItem().(modelItem); ok {
+		launchArgs = m.argsFor(item.name)
+	}
+	argv := append(append([]string(nil), m.config.ExtraArgs...), launchArgs...)
+	if m.process != nil {
+		argv = m.process.args
+	}
+	dir := ""
+	for _, f := range parseFlagArgs(argv) {
+		if f.name == "--slot-save-path" {
+			dir = f.value
+		}
+	}
+	return dir
+}
+
+// slotCount is the number of server slots (--parallel, default 1).
+func (m appModel) slotCount() int {
+	if m.process == nil {
+		return 1
+	}
+	n := 1
+	for _, f := range parseFlagArgs(m.process.args) {
+		if f.name == "--parallel" {
+			if v, err := strconv.Atoi(f.value); err == nil && v > 0 {
+				n = v
+			}
+		}
+	}
+	return n
+}
+
+// scanSlotFilesCmd lists saved slots, newest first.
+func scanSlotFilesCmd(dir string) tea.Cmd {
+	return func() tea.Msg {
+		if dir == "" {
+			return slotFilesMsg{}
+		}
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			return slotFilesMsg{}
+		}
+		if err != nil {
+			return slotFilesMsg{err: err}
+		}
+		var files []slotFile
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil {
+				continue
+			}
+			files = append(files, slotFile{name: e.Name(), size: info.Size(), modTime: info.ModTime()})
+		}
+		sort.Slice(files, func(i, j int) bool { return files[i].modTime.After(files[j].modTime) })
+		return slotFilesMsg{files: files}
+	}
+}
+
+// deleteSlotFileCmd removes a saved slot off the UI loop; the save
+// directory may be on a slow disk.
+func deleteSlotFileCmd(dir, name string) tea.Cmd {
+	return func() tea.Msg {
+		return slotDeletedMsg{name: name, err: os.Remove(filepath.Join(dir, name))}
+	}
+}
+
+// validateSlotFileName keeps names inside the save directory, which
+// llama-server requires as well.
+func validateSlotFileName(name string) error {
+	switch {
+	case strings.TrimSpace(name) == "":
+		return errors.New("enter a name")
+	case strings.ContainsAny(name, `/\`) || name == "." || name == "..":
+		return errors.New("use a plain file name, without directories")
+	}
+	return nil
+}
+
+// slotActionCmd asks the server on port to save or restore slot using
+// filename in its --slot-save-path.
+func slotActionCmd(port string, slot int, action, filename string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		body, _ := json.Marshal(map[string]string{"filename": filename})
+		url := fmt.Sprintf("http://127.0.0.1:%s/slots/%d?action=%s", port, slot, action)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return slotActionMsg{action: action, filename: filename, err: err}
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return slotActionMsg{action: action, filename: filename, err: err}
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			msg := strings.TrimSpace(string(data))
+			var parsed struct {
+				Error struct {
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			if json.Unmarshal(data, &parsed) == nil && parsed.Error.Message != "" {
+				msg = parsed.Error.Message
+			}
+			return slotActionMsg{action: action, filename: filename, err: fmt.Errorf("%s: %s", resp.Status, ellipsize(msg, 200))}
+		}
+		var result struct {
+			Saved    int `json:"n_saved"`
+			Restored int `json:"n_restored"`
+		}
+		_ = json.Unmarshal(data, &result)
+		return slotActionMsg{action: action, filename: filename, tokens: max(result.Saved, result.Restored)}
+	}
+}
+
+// handleSlotKey moves through the saved slots and runs actions on them;
+// the screen captures keys while open.
+func (m appModel) handleSlotKey(keyStr string) (appModel, tea.Cmd) {
+	v := *m.slotView
+	serving := m.server == serverReady && m.attached == nil
+	switch keyStr {
+	case "n", "enter", "d":
+		if m.observerRefuses("save, restore, or delete slots") {
+			return m, nil
+		}
+	}
+	if cursor, ok := moveFileCursor(keyStr, v.cursor, len(v.files)); ok {
+		v.cursor = cursor
+		m.slotView = &v
+		return m, nil
+	}
+	switch keyStr {
+	case "+", "=":
+		v.slot = (v.slot + 1) % m.slotCount()
+	case "-":
+		v.slot = (v.slot + m.slotCount() - 1) % m.slotCount()
+	case "n":
+		switch {
+		case v.dir == "":
+			v.note = "Launch with --slot-save-path <dir> to save slots"
+		case !serving:
+			v.note = "Start a server (and send it a prompt) before saving its slot"
+		default:
+			m.slotView = &v
+			name := m.currentModelName
+			name = strings.TrimSuffix(name, filepath.Ext(name)) + ".bin"
+			form := newForm("Save Slot", []formField{
+				newTextField("name", "Name", fmt.Sprintf("File in %s for slot %d's prompt cache", v.dir, v.slot), name, validateSlotFileName),
+			})
+			m.form, m.formPurpose = &form, formSlotSave
+			return m, nil
+		}
+	case "enter":
+		switch {
+		case v.cursor >= len(v.files):
+			return m, nil
+		case !serving:
+			v.note = "Start the server before restoring a slot"
+		default:
+			name := v.files[v.cursor].name
+			v.note = fmt.Sprintf("Restoring %s into slot %d...", name, v.slot)
+			m.slotView = &v
+			return m, slotActionCmd(m.currentPort, v.slot, "restore", name)
+		}
+	case "d":
+		if v.cursor >= len(v.files) {
+			return m, nil
+		}
+		if m.confirmAction == confirmDeleteSlot {
+			m.confirmAction = confirmNone
+			return m, deleteSlotFileCmd(v.dir, v.files[v.cursor].name)
+		}
+		v.note = ""
+		m.slotView = &v
+		return m, m.askConfirm(confirmDeleteSlot)
+	case "esc", "S":
+		if m.confirmAction != confirmNone {
+			m.confirmAction = confirmNone
+			v.note = "Delete cancelled"
+			break
+		}
+		m.slotView = nil
+		return m, nil
+	}
+	m.slotView = &v
+	return m, nil
+}
+
+// renderSlotView lists the saved slot files and the slot actions apply to.
+func (m appModel) renderSlotView(width int) string {
+	v := m.slotView
+	var b strings.Builder
+	footer := m.styles.help.Render("[n] save slot  [enter] restore  [d] delete  [+/-] slot  [S] or [esc] close")
+	if m.confirmAction == confirmDeleteSlot && v.cursor < len(v.files) {
+		footer = m.styles.confirmWarning.Render(fmt.Sprintf("Delete %s? Press d again to confirm, esc to cancel%s", v.files[v.cursor].name, m.confirmCountdown()))
+	} else if v.note != "" {
+		footer = m.styles.status.Render(v.note) + "\n" + footer
+	}
+	if v.dir == "" {
+		b.WriteString("Saving slots needs llama-server's --slot-save-path.\n")
+		b.WriteString(m.styles.help.Render("Add e.g. --slot-save-path /var/tmp/llama-slots to the launch options [f] or extra_args.") + "\n\n")
+		return b.String() + footer
+	}
+	target := fmt.Sprintf("slot %d of %d", v.slot, m.slotCount())
+	b.WriteString(m.styles.help.Render(v.dir+" · saves and restores apply to "+target) + "\n\n")
+	if v.err != nil {
+		return b.String() + m.styles.logError.Render(v.err.Error()) + "\n\n" + footer
+	}
+	if len(v.files) == 0 {
+		return b.String() + "No saved slots yet.\n\n" + footer
+	}
+	rows := make([]fileRow, len(v.files))
+	for i, f := range v.files {
+		rows[i] = fileRow{name: f.name, size: f.size, note: m.config.Timestamps.dateTime(f.modTime)}
+	}
+	b.WriteString(m.renderFileRows(rows, v.cursor, width))
+	b.WriteString("\n" + footer)
+	return b.String()
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	smokePrompt    = "Reply with the single word OK."
+	smokeMaxTokens = 16
+	smokeMark      = "✓ "
+	smokeFailMark  = "✗ "
+)
+
+// smokeResult is the quick check run the first time a model serves: one
+// short completion, recording whether it answered and how fast.
+type smokeResult struct {
+	Passed          bool      `json:"passed"`
+	TokensPerSecond float64   `json:"tokens_per_second,omitempty"`
+	Error           string    `json:"error,omitempty"`
+	At              time.Time `json:"at"`
+}
+
+// summary is the result in a few words, e.g. "passed, 41.8 t/s".
+func (r smokeResult) summary() string {
+	if !r.Passed {
+		return "failed: " + r.Error
+	}
+	if r.TokensPerSecond > 0 {
+		return fmt.Sprintf("passed, %.1f t/s", r.TokensPerSecond)
+	}
+	return "passed"
+}
+
+// Results are stored by model path.
+func smokeResultsPath(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "smoke.json")
+}
+
+func loadSmokeResults(dir string) (map[string]smokeResult, error) {
+	path := smokeResultsPath(dir)
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var results map[string]smokeResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return results, nil
+}
+
+func saveSmokeResultsCmd(dir string, results map[string]smokeResult) tea.Cmd {
+	return func() tea.Msg {
+		path := smokeResultsPath(dir)
+		if path == "" {
+			return smokeSavedMsg{err: fmt.Errorf("no state directory available")}
+		}
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(path), 0o755)
+		}
+		if err == nil {
+			err = os.WriteFile(path, data, 0o644)
+		}
+		return smokeSavedMsg{err: err}
+	}
+}
+
+// needsSmokeTest reports whether the model just served has no passing
+// result yet. Embedding and reranking servers have no chat endpoint to try.
+func (m appModel) needsSmokeTest() bool {
+	if m.servingPath == "" {
+		return false
+	}
+	if r, ok := m.smokeResults[m.servingPath]; ok && r.Passed {
+		return false
+	}
+	if m.process != nil {
+		for _, f := range parseFlagArgs(m.process.args) {
+			switch f.name {
+			case "--embedding", "--embeddings", "--reranking", "--rerank":
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// smokeTestCmd runs the check against the server on port once /health
+// turns 200.
+func smokeTestCmd(root context.Context, port, modelPath string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(root, warmupTimeout)
+		defer cancel()
+		result := smokeTest(ctx, "http://127.0.0.1:"+port)
+		result.At = time.Now()
+		return smokeDoneMsg{path: modelPath, result: result}
+	}
+}
+
+func smokeTest(ctx context.Context, base string) smokeResult {
+	fail := func(err error) smokeResult { return smokeResult{Error: err.Error()} }
+	if err := waitForHealthy(ctx, base); err != nil {
+		return fail(err)
+	}
+	body, err := json.Marshal(map[string]any{
+		"messages":   []map[string]string{{"role": "user", "content": smokePrompt}},
+		"max_tokens": smokeMaxTokens,
+	})
+	if err != nil {
+		return fail(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/v1/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return fail(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	started := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fail(err)
+	}
+	elapsed := time.Since(started)
+	if resp.StatusCode != http.StatusOK {
+		return fail(fmt.Errorf("%s: %s", resp.Status, ellipsize(strings.TrimSpace(string(data)), 200)))
+	}
+	var parsed struct {
+		Choices []struct {
+			Message struct {
+				Content          string `json:"content"`
+				ReasoningContent string `json:"reasoning_content"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage struct {
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+		// llama-server's own generation speed, excluding prompt processing
+		Timings struct {
+			PredictedPerSecond float64 `json:"predicted_per_second"`
+		} `json:"timings"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return fail(fmt.Errorf("invalid completion response: %w", err))
+	}
+	answered := parsed.Usage.CompletionTokens > 0
+	for _, c := range parsed.Choices {
+		if strings.TrimSpace(c.Message.Content+c.Message.ReasoningContent) != "" {
+			answered = true
+		}
+	}
+	if !answered {
+		return fail(fmt.Errorf("the completion was empty"))
+	}
+	result := smokeResult{Passed: true, TokensPerSecond: parsed.Timings.PredictedPerSecond}
+	if result.TokensPerSecond == 0 && parsed.Usage.CompletionTokens > 0 && elapsed > 0 {
+		result.TokensPerSecond = float64(parsed.Usage.CompletionTokens) / elapsed.Seconds()
+	}
+	return result
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// snapshotInterval is how often the session is saved while llama-tui runs.
+const snapshotInterval = 5 * time.Second
+
+// sessionSnapshot is what a restart restores: the view, and the servers
+// that were running so a still-running one can be reattached.
+type sessionSnapshot struct {
+	SavedAt string `json:"saved_at,omitempty"`
+	// Selected is the path of the selected model
+	Selected string `json:"selected,omitempty"`
+	SortKey  string `json:"sort_key,omitempty"`
+	// View is the open full-screen view: "latency", "timeline",
+	// "timeline-week", "bench", or "stats"
+	View string `json:"view,omitempty"`
+	// Mode is "basic" or "advanced"
+	Mode string `json:"mode,omitempty"`
+	// LogsTop is the line at the top of the logs panel while it is
+	// scrolled back from the newest output
+	LogsTop string           `json:"logs_top,omitempty"`
+	Servers []snapshotServer `json:"servers,omitempty"`
+}
+
+// snapshotServer is a running server; Role is "main", "side", or
+// "standby".
+type snapshotServer struct {
+	Role    string `json:"role"`
+	Model   string `json:"model"`
+	Port    string `json:"port"`
+	PID     int    `json:"pid"`
+	LogFile string `json:"log_file,omitempty"`
+}
+
+func sessionSnapshotPath(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "session.json")
+}
+
+func processPID(started *startedWithStateMsg) int {
+	if started == nil || started.process == nil {
+		return 0
+	}
+	return started.process.pid
+}
+
+// sessionSnapshot captures the current view and servers, without SavedAt.
+func (m appModel) sessionSnapshot() sessionSnapshot {
+	snap := sessionSnapshot{SortKey: m.sortKey}
+	if mi, ok := m.modelsList.SelectedItem().(modelItem); ok {
+		snap.Selected = mi.path
+	}
+	snap.Mode = m.uiModeName()
+	if m.logView == "" && !m.logsViewport.AtBottom() {
+		if lines := m.plainLogLines(); m.logsViewport.YOffset < len(lines) {
+			snap.LogsTop = strings.TrimSpace(lines[m.logsViewport.YOffset])
+		}
+	}
+	switch {
+	case m.showLatency:
+		snap.View = "latency"
+	case m.showTimeline && m.timelineWeek:
+		snap.View = "timeline-week"
+	case m.showTimeline:
+		snap.View = "timeline"
+	case m.showBenchMatrix:
+		snap.View = "bench"
+	case m.showStats:
+		snap.View = "stats"
+	}
+	if m.server.serving() {
+		main := snapshotServer{Role: "main", Model: m.currentModelName, Port: m.currentPort, LogFile: m.logFilePath}
+		switch {
+		case m.process != nil:
+			main.PID = m.process.pid
+		case m.attached != nil:
+			main.PID = int(m.attached.pid)
+			main.LogFile = m.attached.logPath
+		}
+		if main.PID > 0 {
+			snap.Servers = append(snap.Servers, main)
+		}
+	}
+	for _, s := range m.sideServers {
+		if pid := processPID(s.started); pid > 0 && s.state.serving() {
+			snap.Servers = append(snap.Servers, snapshotServer{Role: "side", Model: s.item.name, Port: s.port, PID: pid})
+		}
+	}
+	if m.standby != nil {
+		if pid := processPID(m.standby.started); pid > 0 {
+			snap.Servers = append(snap.Servers, snapshotServer{Role: "standby", Model: m.standby.item.name, Port: m.standby.port, PID: pid})
+		}
+	}
+	return snap
+}
+
+// key identifies a snapshot's content, so an unchanged session
+// isn't rewritten every tick.
+func (s sessionSnapshot) key() string {
+	s.SavedAt = ""
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+func loadSessionSnapshot(path string) (sessionSnapshot, error) {
+	var snap sessionSnapshot
+	if path == "" {
+		return snap, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return snap, nil
+	}
+	if err != nil {
+		return snap, err
+	}
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return snap, fmt.Errorf("%s: %w", path, err)
+	}
+	return snap, nil
+}
+
+// saveSessionSnapshot replaces the snapshot atomically, so a crash while
+// writing leaves the previous one.
+func saveSessionSnapshot(path string, snap sessionSnapshot) error {
+	if path == "" {
+		return nil
+	}
+	snap.SavedAt = time.Now().Format(time.RFC3339)
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'), 0o644)
+}
+
+// restoreLogsScroll scrolls the logs panel back to the line that was at its
+// top, once the reattached server's log has been read up to its end. The
+// line's latest copy is taken, as the same line may be logged many times.
+func (m *appModel) restoreLogsScroll() {
+	top := m.restoreLogsTop
+	m.restoreLogsTop = ""
+	if top == "" || m.logView != "" {
+		return
+	}
+	lines := m.plainLogLines()
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.TrimSpace(lines[i]) == top {
+			m.logsViewport.SetYOffset(i)
+			return
+		}
+	}
+}
+
+func snapshotTickCmd() tea.Cmd {
+	return tea.Tick(snapshotInterval, func(time.Time) tea.Msg { return snapshotTickMsg{} })
+}
+
+// saveSnapshotCmd writes the session when it changed since the last write.
+// Read-only instances leave the snapshot to the one managing servers.
+func (m *appModel) saveSnapshotCmd() tea.Cmd {
+	if m.readOnly || m.config.DisableSessionRestore {
+		return nil
+	}
+	snap := m.sessionSnapshot()
+	key := snap.key()
+	if key == m.snapshotKey {
+		return nil
+	}
+	m.snapshotKey = key
+	path := sessionSnapshotPath(m.historyDir)
+	return func() tea.Msg {
+		// Best-effort: a lost snapshot only costs the restored view
+		_ = saveSessionSnapshot(path, snap)
+		return nil
+	}
+}
+
+// restoreSession applies the previous session's snapshot at startup: the
+// sort order and open view now, the selection once models are scanned. A
+// main server still listening where it was is reattached as with --attach,
+// and the logs panel scrolled back to where it was once its log is read;
+// other servers still running are named in the status line.
+func (m *appModel) restoreSession() {
+	if m.config.DisableSessionRestore {
+		return
+	}
+	snap, err := loadSessionSnapshot(sessionSnapshotPath(m.historyDir))
+	if err != nil {
+		m.statusLineText = fmt.Sprintf("Previous session not restored: %v", err)
+		return
+	}
+	m.snapshotKey = snap.key()
+	m.sortKey = snap.SortKey
+	m.restoreSelected = snap.Selected
+	if snap.Mode != "" {
+		m.basicMode = snap.Mode == "basic"
+	}
+	switch snap.View {
+	case "latency":
+		m.showLatency = true
+	case "timeline", "timeline-week":
+		m.showTimeline, m.timelineWeek = true, snap.View == "timeline-week"
+	case "bench":
+		m.showBenchMatrix = true
+	case "stats":
+		m.showStats = true
+	}
+	var others []string
+	for _, s := range snap.Servers {
+		portNum, err := strconv.Atoi(s.Port)
+		if err != nil || !pidAlive(s.PID) || listenerPID(portNum) != int32(s.PID) {
+			continue
+		}
+		if s.Role == "main" && !m.readOnly && m.attached == nil {
+			target := attachTarget{pid: int32(s.PID), port: s.Port, model: s.Model}
+			if s.LogFile != "" && validateTailPath(s.LogFile) == nil {
+				target.logPath = s.LogFile
+			}
+			m.attach(target)
+			m.statusLineText = fmt.Sprintf("Reattached to %s on port %s (pid %d), left running by the previous session", s.Model, s.Port, s.PID)
+			continue
+		}
+		others = append(others, fmt.Sprintf("%s on %s (pid %d)", s.Model, s.Port, s.PID))
+	}
+	if m.attached != nil && m.attached.logPath != "" {
+		m.restoreLogsTop = snap.LogsTop
+	}
+	switch {
+	case len(others) == 0:
+	case m.attached != nil:
+		m.statusLineText += "; also still running: " + strings.Join(others, ", ")
+	default:
+		m.statusLineText = "Still running from the previous session: " + strings.Join(others, ", ")
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// serverSocket serves the managed server on a unix domain socket for
+// local clients that prefer one. It exists while a model is served and is
+// removed when the server stops. Requests go through the request proxy
+// when there is one, so they are timed and rate limited like the rest.
+type serverSocket struct {
+	path   string
+	srv    *http.Server
+	target atomic.Value // port of the served model
+}
+
+// socketPath is the socket_path setting (or --socket) with ~ expanded
+// and made absolute, or "" when off. A ~ path without a home directory is
+// an error.
+func (m appModel) socketPath() (string, error) {
+	path := strings.TrimSpace(m.socketFlag)
+	if path == "" {
+		path = strings.TrimSpace(m.config.SocketPath)
+	}
+	if path == "" {
+		return "", nil
+	}
+	path, err := expandHome(m.homeDir, path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(path)
+}
+
+// openServerSocketCmd opens the socket off the UI loop; checking for a
+// stale one can wait on a dead process's socket.
+func openServerSocketCmd(path, port string, proxy *requestProxy) tea.Cmd {
+	return func() tea.Msg {
+		s, err := openServerSocket(path, port, proxy)
+		return socketOpenedMsg{path: path, socket: s, err: err}
+	}
+}
+
+// openServerSocket listens on path, replacing a socket left behind by a
+// process that is gone but refusing to take over one still answering.
+func openServerSocket(path, port string, proxy *requestProxy) (*serverSocket, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("%s is in use by another process", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	// Local clients of this user only, like a loopback port but stricter.
+	// The socket is bound in a directory only this user can enter and
+	// moved into place once private, so it is never open to others even
+	// briefly, and the process-wide umask is left alone.
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".llama-tui-socket-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	bound := filepath.Join(dir, "sock")
+	ln, err := net.Listen("unix", bound)
+	if err != nil {
+		return nil, err
+	}
+	// close removes the socket at its final path
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := os.Chmod(bound, 0o600); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+	if err := os.Rename(bound, path); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+	s := &serverSocket{path: path}
+	s.target.Store(port)
+	var handler http.Handler = s
+	if proxy != nil {
+		handler = proxy
+	}
+	s.srv = &http.Server{Handler: handler}
+	go func() { _ = s.srv.Serve(ln) }()
+	return s, nil
+}
+
+// setTarget follows the served model to a new port, as after promoting
+// a standby.
+func (s *serverSocket) setTarget(port string) {
+	if s != nil {
+		s.target.Store(port)
+	}
+}
+
+// ServeHTTP forwards to the served model when there is no request proxy.
+func (s *serverSocket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	port, _ := s.target.Load().(string)
+	rp := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(&url.URL{Scheme: "http", Host: net.JoinHostPort("127.0.0.1", port)})
+		},
+		FlushInterval: -1,
+	}
+	rp.ServeHTTP(w, r)
+}
+
+// close stops serving and removes the socket file.
+func (s *serverSocket) close() {
+	if s == nil {
+		return
+	}
+	_ = s.srv.Close()
+	// Closing the listener normally unlinks it already
+	_ = os.Remove(s.path)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// specAcceptance is llama-server's per-request speculative decoding
+	// summary, e.g. "draft acceptance rate = 0.57143 (   12 accepted /    21 generated)"
+	specAcceptance = regexp.MustCompile(`draft acceptance rate = ([\d.]+) \(\s*(\d+) accepted /\s*(\d+) generated\)`)
+	// evalTiming is the generation timing printed just before it, e.g.
+	// "eval time = 1234.56 ms / 100 tokens ( 12.35 ms per token, 81.00 tokens per second)"
+	evalTiming = regexp.MustCompile(`\beval time =\s*[\d.]+ ms /\s*(\d+) (?:tokens|runs).*?([\d.]+) tokens per second`)
+)
+
+// specStats sums speculative decoding over the requests the server has
+// finished since it started.
+type specStats struct {
+	requests int
+	accepted int
+	drafted  int
+	// tokens generated by requests that used the draft model
+	tokens int
+	// last request's generation speed
+	lastTokensPerSec float64
+	// pending holds the eval timing until its acceptance line arrives
+	pendingTokens int
+	pendingRate   float64
+}
+
+// acceptanceRate is the share of drafted tokens the target model kept.
+func (s specStats) acceptanceRate() float64 {
+	if s.drafted == 0 {
+		return 0
+	}
+	return float64(s.accepted) / float64(s.drafted)
+}
+
+// speedup estimates tokens generated per pass of the target model: without
+// a draft every token is a pass; each accepted token saves one. The cost
+// of running the draft model is not included, so it is an upper bound.
+func (s specStats) speedup() float64 {
+	passes := s.tokens - s.accepted
+	if passes <= 0 {
+		return 0
+	}
+	return float64(s.tokens) / float64(passes)
+}
+
+// noteSpeculativeLine picks speculative decoding figures out of a server
+// log line.
+func (m *appModel) noteSpeculativeLine(line string) {
+	if match := evalTiming.FindStringSubmatch(line); match != nil && !strings.Contains(line, "prompt eval") {
+		m.spec.pendingTokens, _ = strconv.Atoi(match[1])
+		m.spec.pendingRate, _ = strconv.ParseFloat(match[2], 64)
+		return
+	}
+	match := specAcceptance.FindStringSubmatch(line)
+	if match == nil {
+		return
+	}
+	accepted, _ := strconv.Atoi(match[2])
+	drafted, _ := strconv.Atoi(match[3])
+	m.spec.requests++
+	m.spec.accepted += accepted
+	m.spec.drafted += drafted
+	m.spec.tokens += max(m.spec.pendingTokens, accepted)
+	m.spec.lastTokensPerSec = m.spec.pendingRate
+	m.spec.pendingTokens, m.spec.pendingRate = 0, 0
+}
+
+// usesDraftModel reports whether the running server was launched with a
+// draft model for speculative decoding.
+func (m appModel) usesDraftModel() bool {
+	if m.process == nil {
+		return false
+	}
+	for _, f := range parseFlagArgs(m.process.args) {
+		switch f.name {
+		case "-md", "--model-draft", "-hfd", "-hfrd", "--hf-repo-draft":
+			return true
+		}
+	}
+	return false
+}
+
+// specSummary is the one-line widget, e.g. "57% accepted · ≤1.6x · 81.0 t/s".
+func (m appModel) specSummary() string {
+	s := m.spec
+	if s.requests == 0 {
+		return "waiting for a request"
+	}
+	summary := fmt.Sprintf("%.0f%% accepted · ≤%.1fx", s.acceptanceRate()*100, s.speedup())
+	if s.lastTokensPerSec > 0 {
+		summary += fmt.Sprintf(" · %.1f t/s", s.lastTokensPerSec)
+	}
+	return summary
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// standbyServer is a second model preloaded on its own port and suspended
+// once ready, so switching to it is a resume instead of a load.
+type standbyServer struct {
+	item    modelItem
+	port    string
+	started *startedWithStateMsg
+	ready   bool
+	// color is the instance color of its log lines and badges
+	color int
+	// suspended standbys hold their memory but use no CPU
+	suspended bool
+}
+
+// describe summarizes the standby for the status bar.
+func (s *standbyServer) describe() string {
+	state := "loading"
+	switch {
+	case s.suspended:
+		state = "paused"
+	case s.ready:
+		state = "ready"
+	}
+	return fmt.Sprintf("%s:%s (%s)", s.item.name, s.port, state)
+}
+
+// stop ends the standby process, if it got as far as starting.
+func (s *standbyServer) stop() {
+	if s.started != nil {
+		s.started.process.stop()
+	}
+}
+
+// freeLaunchPort picks a port for a standby or side server that clashes
+// with nothing else:
+// the model's automatic port when configured and free, else the next free
+// port after the launch port.
+func (m appModel) freeLaunchPort(item modelItem) (int, error) {
+	if m.config.AutoPorts.enabled() {
+		if p := m.config.AutoPorts.portFor(item.name); m.ports.checkPort(p) == nil {
+			return p, nil
+		}
+	}
+	base, err := validatePort(m.launchPort(item))
+	if err != nil {
+		return 0, err
+	}
+	if p := m.ports.nextFreePort(base); p > 0 {
+		return p, nil
+	}
+	return 0, fmt.Errorf("no free port near %d", base)
+}
+
+// startStandby launches the selected model as the standby.
+func (m appModel) startStandby(item modelItem) (appModel, tea.Cmd) {
+	switch {
+	case m.attached != nil:
+		m.statusLineText = "Standbys need a server llama-tui manages, not an attached one"
+		return m, nil
+	case m.readOnly:
+		m.statusLineText = fmt.Sprintf("Read-only: llama-tui pid %d manages this barn - [T] take over", m.lockOwner)
+		return m, nil
+	case item.remoteURL != "":
+		m.statusLineText = "Download " + item.name + " first; standbys start local models only"
+		return m, nil
+	case m.server.running() && item.name == m.currentModelName:
+		m.statusLineText = item.name + " is already being served"
+		return m, nil
+	}
+	if verdict, reason := m.admit(item, ""); verdict != admitLaunch {
+		// A standby is loaded ahead of time, so it isn't queued
+		m.statusLineText = "No standby: " + reason
+		return m, nil
+	}
+	port, err := m.freeLaunchPort(item)
+	if err != nil {
+		m.statusLineText = fmt.Sprintf("Standby: %v", err)
+		return m, nil
+	}
+	portStr := strconv.Itoa(port)
+	m.standby = &standbyServer{item: item, port: portStr, color: m.nextInstanceColor()}
+	m.ports = m.ports.with(port, "the standby "+item.name)
+	m.modelsList.SetDelegate(m.listDelegate())
+	m.statusLineText = fmt.Sprintf("Loading %s as standby on port %s...", item.name, portStr)
+	m.logEvent(fmt.Sprintf("[standby] Loading %s on port %s", item.name, portStr))
+	start := m.startServerCmd(item, portStr)
+	return m, func() tea.Msg {
+		switch msg := start().(type) {
+		case startedWithStateMsg:
+			return standbyStartedMsg{port: portStr, started: msg}
+		case startErrorMsg:
+			return standbyStartedMsg{port: portStr, err: msg.err}
+		}
+		return nil
+	}
+}
+
+// discardStandby stops the standby and releases its port.
+func (m *appModel) discardStandby() {
+	if m.standby == nil {
+		return
+	}
+	m.standby.stop()
+	if port, err := strconv.Atoi(m.standby.port); err == nil {
+		m.ports = m.ports.without(port)
+	}
+	m.standby = nil
+	m.modelsList.SetDelegate(m.listDelegate())
+}
+
+// waitForStandbyHealthy polls /health until the model has loaded, or
+// reports failure once the process is gone.
+func waitForStandbyHealthy(started startedWithStateMsg) tea.Cmd {
+	return func() tea.Msg {
+		url := "http://" + net.JoinHostPort("127.0.0.1", started.port) + "/health"
+		for {
+			req, err := http.NewRequestWithContext(started.process.ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return standbyReadyMsg{process: started.process}
+			}
+			if resp, err := http.DefaultClient.Do(req); err == nil {
+				resp.Body.Close()
+				if resp.StatusCode == http.StatusOK {
+					return standbyReadyMsg{process: started.process, ready: true}
+				}
+			}
+			// The process's context ends when it is stopped or exits
+			if started.process.ctx.Err() != nil {
+				return standbyReadyMsg{process: started.process}
+			}
+			time.Sleep(time.Second)
+		}
+	}
+}
+
+// handleStandbyMsg follows the standby through loading.
+func (m appModel) handleStandbyMsg(msg tea.Msg) (appModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case standbyStartedMsg:
+		if m.standby == nil || m.standby.port != msg.port {
+			// Discarded while launching
+			if msg.err == nil {
+				msg.started.process.stop()
+			}
+			return m, nil
+		}
+		if msg.err != nil {
+			name := m.standby.item.name
+			m.discardStandby()
+			m.statusLineText = fmt.Sprintf("Standby %s failed to start: %v", name, msg.err)
+			return m, nil
+		}
+		s := *m.standby
+		s.started = &msg.started
+		m.standby = &s
+		return m, tea.Batch(waitForStandbyHealthy(msg.started), waitForStandbyLog(msg.started.logChan))
+
+	case standbyLogMsg:
+		if msg.ch == m.logChan {
+			// Promoted; the managed server's reader takes over
+			m.appendServerLogLine(msg.text)
+			return m, nil
+		}
+		if m.standby == nil || m.standby.started == nil || m.standby.started.logChan != msg.ch {
+			return m, nil
+		}
+		m.appendTaggedLogLine(m.instanceTag(m.standby.color, m.standby.port), msg.text)
+		return m, waitForStandbyLog(msg.ch)
+
+	case standbyReadyMsg:
+		if m.standby == nil || m.standby.started == nil || m.standby.started.process != msg.process {
+			return m, nil
+		}
+		name := m.standby.item.name
+		if !msg.ready {
+			m.discardStandby()
+			m.statusLineText = "Standby " + name + " exited or did not become ready"
+			m.logEvent(m.statusLineText)
+			return m, nil
+		}
+		s := *m.standby
+		s.ready = true
+		if err := s.started.process.setSuspended(true); err == nil {
+			s.suspended = true
+		}
+		m.standby = &s
+		m.statusLineText = fmt.Sprintf("Standby %s ready on port %s - [P] promote", name, m.standby.port)
+		m.logEvent("[standby] " + name + " is ready")
+		return m, nil
+	}
+	return m, nil
+}
+
+// promoteStandby makes the standby the managed server: it is resumed, the
+// proxy is pointed at it, and the previous server stops in the background.
+func (m appModel) promoteStandby() (appModel, tea.Cmd) {
+	s := m.standby
+	switch {
+	case s == nil:
+		m.statusLineText = "No standby ([W] loads the selected model as one)"
+		return m, nil
+	case !s.ready:
+		m.statusLineText = "Standby " + s.item.name + " is still loading"
+		return m, nil
+	case m.server == serverStarting || m.server.stopping():
+		m.statusLineText = "Wait for the current server to finish starting or stopping"
+		return m, nil
+	}
+	m.standby = nil
+	m.serverColor = s.color
+	if s.suspended {
+		if err := s.started.process.setSuspended(false); err != nil {
+			m.statusLineText = fmt.Sprintf("Could not resume standby: %v", err)
+			s.started.process.stop()
+			if port, err := strconv.Atoi(s.port); err == nil {
+				m.ports = m.ports.without(port)
+			}
+			return m, nil
+		}
+	}
+	var previous string
+	var sessionCmd tea.Cmd
+	if m.server.serving() {
+		previous = m.currentModelName
+		sessionCmd = m.endSession(false)
+		m.process.stop()
+		if port, err := strconv.Atoi(m.currentPort); err == nil {
+			m.ports = m.ports.without(port)
+		}
+		m.powerPaused = false
+	}
+	// The launch probe may have given up on a slow load; the standby is
+	// known healthy, so the attached server goes straight to ready. A probe
+	// still running reports the open port itself.
+	select {
+	case <-s.started.readyChan:
+		s.started.readyChan <- true
+	default:
+	}
+	m.logBuffer.Reset()
+	m.clearBookmarks()
+	m.logErrorCount, m.logWarnCount = 0, 0
+	m.flagRetry = nil
+	m.logEvent(fmt.Sprintf("[standby] Promoted %s on port %s", s.item.name, s.port))
+	if previous != "" {
+		m.logEvent("[standby] Stopping " + previous + " in the background")
+	}
+	m, attachCmd := m.attachServer(*s.started)
+	return m, tea.Batch(sessionCmd, attachCmd)
+}
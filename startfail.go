@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// startFailure is a launch that failed before llama-server ran, shown as
+// a modal with the ways out. binInput is focused while choosing another
+// llama-server binary.
+type startFailure struct {
+	err      error
+	model    string
+	port     string
+	binInput textinput.Model
+}
+
+// startFailureCause guesses why a launch failed and what fixes it.
+func startFailureCause(err error) string {
+	text := err.Error()
+	switch {
+	case strings.Contains(text, "LLAMA_SERVER_BIN points to"):
+		return "LLAMA_SERVER_BIN names a file that doesn't exist. Choose the llama-server binary with [b]."
+	case strings.Contains(text, "not found in PATH"), errors.Is(err, exec.ErrNotFound), errors.Is(err, os.ErrNotExist):
+		return "llama-server isn't installed, or isn't on PATH. Install llama.cpp or choose the binary with [b]."
+	case errors.Is(err, os.ErrPermission):
+		return "The binary isn't executable. Run chmod +x on it, or choose another with [b]."
+	case errors.Is(err, syscall.ENOEXEC):
+		return "The binary was built for another OS or CPU. Choose a build for this machine with [b]."
+	case strings.Contains(text, "command_template"):
+		return "command_template in the config doesn't form a command line. Fix it in the config file."
+	case strings.Contains(text, "readiness"):
+		return "The readiness check is misconfigured. Fix it in the launch options with [f]."
+	case strings.Contains(text, "pipe"):
+		return "The system ran out of file descriptors or processes. Close something and retry."
+	}
+	return "llama-server could not be launched with these settings. Check the launch options and the logs."
+}
+
+func newStartFailure(msg startErrorMsg) *startFailure {
+	bin := textinput.New()
+	bin.Placeholder = "path to llama-server"
+	bin.Prompt = "Binary: "
+	if path, err := getLlamaServerBinary(); err == nil {
+		bin.SetValue(path)
+	} else {
+		bin.SetValue(strings.TrimSpace(os.Getenv("LLAMA_SERVER_BIN")))
+	}
+	return &startFailure{err: msg.err, model: msg.model, port: msg.port, binInput: bin}
+}
+
+func (m appModel) handleStartFailureKey(msg tea.KeyMsg) (appModel, tea.Cmd) {
+	f := *m.startFailure
+	keyStr := msg.String()
+	if f.binInput.Focused() {
+		switch keyStr {
+		case "enter":
+			return m.useServerBinary(f)
+		case "esc":
+			f.binInput.Blur()
+			m.startFailure = &f
+			return m, nil
+		}
+		var cmd tea.Cmd
+		f.binInput, cmd = f.binInput.Update(msg)
+		m.startFailure = &f
+		return m, cmd
+	}
+	switch keyStr {
+	case "enter":
+		m.startFailure = nil
+		item, ok := m.findModelByName(f.model)
+		if !ok {
+			m.statusLineText = f.model + " is no longer in the models list"
+			return m, nil
+		}
+		return m.requestStart(item)
+	case "f":
+		m.startFailure = nil
+		m.portInput.Blur()
+		return m.openLaunchOptions()
+	case "e":
+		if _, ok := m.findModelByName(f.model); !ok {
+			break
+		}
+		m.startFailure = nil
+		m.portInput.Blur()
+		return m.openModelConfig(f.model)
+	case "p":
+		m.startFailure = nil
+		m.statusLineText = "Port input focused - type port number"
+		if n, err := strconv.Atoi(f.port); err == nil {
+			if next := m.ports.nextFreePort(n); next > 0 {
+				m.portInput.SetValue(strconv.Itoa(next))
+				m.statusLineText = fmt.Sprintf("Port input focused - %d is free", next)
+			}
+		}
+		m.portInput.CursorEnd()
+		m.portInput.Focus()
+	case "b":
+		f.binInput.CursorEnd()
+		f.binInput.Focus()
+		m.startFailure = &f
+	case "o":
+		m.startFailure = nil
+		if m.lastLogFilePath == "" {
+			// The error is at the bottom of the logs panel
+			m.logsViewport.GotoBottom()
+			return m, nil
+		}
+		m.statusLineText = "Opening " + filepath.Base(m.lastLogFilePath) + " in pager..."
+		return m, openLogInPagerCmd(m.lastLogFilePath)
+	case "esc", "q":
+		m.startFailure = nil
+	}
+	return m, nil
+}
+
+// useServerBinary points LLAMA_SERVER_BIN at the chosen file for the rest
+// of the session and retries the launch.
+func (m appModel) useServerBinary(f startFailure) (appModel, tea.Cmd) {
+	given := strings.TrimSpace(f.binInput.Value())
+	path, err := expandHome(m.homeDir, given)
+	var info os.FileInfo
+	if err == nil {
+		info, err = os.Stat(path)
+	}
+	switch {
+	case given == "":
+		m.statusLineText = "Enter the path to llama-server"
+	case err != nil:
+		m.statusLineText = fmt.Sprintf("Cannot use %s: %v", given, err)
+	case info.IsDir():
+		m.statusLineText = path + " is a directory"
+	case info.Mode()&0o111 == 0:
+		m.statusLineText = path + " is not executable (chmod +x it)"
+	default:
+		_ = os.Setenv("LLAMA_SERVER_BIN", path)
+		m.startFailure = nil
+		item, ok := m.findModelByName(f.model)
+		if !ok {
+			m.statusLineText = "Using " + path + " for this session"
+			return m, nil
+		}
+		m, cmd := m.requestStart(item)
+		m.logEvent("[ui] Using llama-server " + path + " for this session (set LLAMA_SERVER_BIN to keep it)")
+		return m, cmd
+	}
+	m.startFailure = &f
+	return m, nil
+}
+
+func (m appModel) renderStartFailure(width int) string {
+	f := m.startFailure
+	var b strings.Builder
+	if f.model != "" {
+		b.WriteString(m.styles.help.Render(fmt.Sprintf("%s on port %s", f.model, f.port)) + "\n\n")
+	}
+	b.WriteString(m.styles.logError.Width(width).Render(f.err.Error()) + "\n\n")
+	b.WriteString(m.styles.accent.Render("Likely cause") + "\n")
+	b.WriteString(m.styles.help.Width(width).Render(startFailureCause(f.err)) + "\n\n")
+	if f.binInput.Focused() {
+		f.binInput.Width = max(width-len(f.binInput.Prompt)-2, 10)
+		b.WriteString(f.binInput.View() + "\n\n")
+		b.WriteString(m.styles.help.Render("[enter] use it and retry  [esc] back"))
+		return b.String()
+	}
+	options := []string{"[enter] retry", "[f] edit launch options"}
+	if _, ok := m.findModelByName(f.model); ok {
+		options = append(options, "[e] edit this model's args")
+	}
+	options = append(options, "[p] change port", "[b] choose llama-server binary", "[o] open logs", "[esc] close")
+	b.WriteString(m.styles.help.Width(width).Render(strings.Join(options, "  ")))
+	return b.String()
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+// launchPreset is a named model/port/arguments combination from the config.
+type launchPreset struct {
+	Model string   `json:"model"`
+	Port  string   `json:"port,omitempty"`
+	Args  []string `json:"args,omitempty"`
+	// Readiness overrides the configured readiness probe for this preset
+	Readiness *readinessProbe `json:"readiness,omitempty"`
+	// Reserve is the memory the model holds while served; launches that
+	// would reserve more than the machine has wait or are refused
+	Reserve *resourceReservation `json:"reserve,omitempty"`
+}
+
+// startupAction is a launch requested on the command line, performed once
+// the first model scan completes.
+type startupAction struct {
+	model     string
+	port      string
+	args      []string
+	readiness *readinessProbe
+	// preset names the preset the action came from, for the history
+	preset string
+	// vars fill the {{placeholders}} in args
+	vars map[string]string
+}
+
+// lastLaunch records the most recent successful start for --autostart-last.
+type lastLaunch struct {
+	Model string   `json:"model"`
+	Port  string   `json:"port"`
+	Args  []string `json:"args,omitempty"`
+}
+
+func lastLaunchPath(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "last-launch.json")
+}
+
+func loadLastLaunch(dir string) (lastLaunch, error) {
+	var l lastLaunch
+	path := lastLaunchPath(dir)
+	if path == "" {
+		return l, fmt.Errorf("no state directory available")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return l, err
+	}
+	if err := json.Unmarshal(data, &l); err != nil {
+		return l, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return l, nil
+}
+
+func saveLastLaunch(dir string, l lastLaunch) error {
+	path := lastLaunchPath(dir)
+	if path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// resolveStartupAction turns the command-line flags into a startup action.
+// An explicit --start or --port overrides what a preset or the last launch
+// would use.
+func resolveStartupAction(dir string, cfg appConfig, start, port, preset string, autostartLast bool) (*startupAction, error) {
+	var action startupAction
+	switch {
+	case preset != "":
+		p, ok := cfg.Presets[preset]
+		if !ok {
+			return nil, fmt.Errorf("unknown preset %q", preset)
+		}
+		action = startupAction{model: p.Model, port: p.Port, args: p.Args, readiness: p.Readiness, preset: preset}
+	case autostartLast:
+		l, err := loadLastLaunch(dir)
+		if err != nil {
+			return nil, fmt.Errorf("no previous launch to restore: %w", err)
+		}
+		action = startupAction{model: l.Model, port: l.Port, args: l.Args}
+	}
+	if start != "" {
+		action.model = start
+	}
+	if port != "" {
+		action.port = port
+	}
+	if action.model == "" {
+		if action.port != "" || len(action.args) > 0 {
+			// Only prefill; nothing to start
+			return &action, nil
+		}
+		return nil, nil
+	}
+	return &action, nil
+}
+
+// findModelItem finds a model by exact name, then by a unique
+// case-insensitive substring match.
+func findModelItem(items []list.Item, name string) (modelItem, error) {
+	var matches []modelItem
+	lower := strings.ToLower(name)
+	for _, it := range items {
+		mi, ok := it.(modelItem)
+		if !ok {
+			continue
+		}
+		if mi.name == name {
+			return mi, nil
+		}
+		if strings.Contains(strings.ToLower(mi.name), lower) {
+			matches = append(matches, mi)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return modelItem{}, fmt.Errorf("no model matches %q", name)
+	case 1:
+		return matches[0], nil
+	default:
+		return modelItem{}, fmt.Errorf("%q matches %d models; be more specific", name, len(matches))
+	}
+}
@@ -3,11 +3,15 @@ package main
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
@@ -16,36 +20,478 @@ import (
 // tea messages
 type (
 	scanDoneMsg struct {
-		items []list.Item
-		err   error
+		items       []list.Item
+		err         error
+		barnMissing bool
+		// sourceErr is a model source or catalog that couldn't be read;
+		// the other models are still listed
+		sourceErr error
+	}
+	modelMetadataMsg struct {
+		meta   map[string]map[string]string
+		failed int
+		err    error
+	}
+	barnDirCreatedMsg struct {
+		dir string
+		err error
 	}
 	logLineMsg struct {
+		ch   chan string
 		text string
 	}
+	serverHelpLoadedMsg struct {
+		flags []helpFlag
+		err   error
+	}
+	diagnosticsExportedMsg struct {
+		path string
+		err  error
+	}
+	diagnosticsDoneMsg struct {
+		checks  []diagnosticCheck
+		startup bool
+	}
+	tailLineMsg struct {
+		ch   chan tailLine
+		line tailLine
+		done bool
+	}
+	sleepWatchStartedMsg struct {
+		watch *sleepWatch
+		err   error
+	}
+	sleepSignalMsg struct {
+		watch    *sleepWatch
+		sleeping bool
+	}
+	powerStateMsg struct {
+		state powerState
+		at    time.Time
+		err   error
+	}
+	proxyStatsMsg struct {
+		samples       []latencySample
+		inFlight      int
+		mirrorSamples []mirrorSample
+		mirrorErr     error
+		rates         []clientRate
+		rejected      int
+	}
+	proxyStoppedMsg struct {
+		err error
+	}
+	logStreamStoppedMsg struct {
+		err error
+	}
+	notifyFailedMsg struct {
+		err error
+	}
+	capacityDetectedMsg struct {
+		capacity resourceReservation
+	}
+	controlStoppedMsg struct {
+		err error
+	}
+	controlRequestMsg struct {
+		req controlRequest
+	}
+	standbyStartedMsg struct {
+		port    string
+		started startedWithStateMsg
+		err     error
+	}
+	standbyLogMsg struct {
+		ch   chan string
+		text string
+	}
+	standbyReadyMsg struct {
+		process *serverProcess
+		ready   bool
+	}
+	sideStartedMsg struct {
+		port    string
+		started startedWithStateMsg
+		err     error
+	}
+	sideLogMsg struct {
+		port string
+		ch   chan string
+		text string
+	}
+	sideReadyMsg struct {
+		port      string
+		readyChan chan bool
+		ready     bool
+	}
+	sideExitedMsg struct {
+		port     string
+		exitChan chan error
+		err      error
+	}
+	logRefreshMsg  struct{}
+	confirmTickMsg struct {
+		seq int
+	}
+	sessionsLoadedMsg struct {
+		sessions []sessionRecord
+		err      error
+	}
+	slotFilesMsg struct {
+		files []slotFile
+		err   error
+	}
+	slotDeletedMsg struct {
+		name string
+		err  error
+	}
+	slotActionMsg struct {
+		action   string
+		filename string
+		tokens   int
+		err      error
+	}
+	smokeDoneMsg struct {
+		path   string
+		result smokeResult
+	}
+	smokeSavedMsg struct {
+		err error
+	}
+	hfReposSavedMsg struct {
+		err error
+	}
+	hfDownloadTickMsg struct {
+		bytes int64
+	}
+	attachHealthMsg struct {
+		status int
+		err    error
+		// pid and model are looked up again after the server was down
+		pid   int32
+		model string
+	}
+	hfCacheScannedMsg struct {
+		files []hfCacheFile
+		err   error
+	}
+	hfCacheDeletedMsg struct {
+		file hfCacheFile
+		err  error
+	}
+	downloadSweepScannedMsg struct {
+		dir   string
+		files []sweepCandidate
+		err   error
+	}
+	templateRenderedMsg struct {
+		model  string
+		via    string
+		prompt string
+		tokens []templateToken
+		err    error
+	}
+	templateSampleEditedMsg struct {
+		err error
+	}
+	promptEditedMsg struct {
+		name string
+		err  error
+	}
+	// launchFormMetaMsg is the header of a launch options form's model
+	launchFormMetaMsg struct {
+		path string
+		meta *ggufMetadata
+		err  error
+	}
+	promptsLoadedMsg struct {
+		prompts []libraryPrompt
+		err     error
+		keep    string
+	}
+	promptSavedMsg struct {
+		name    string
+		deleted bool
+		err     error
+	}
+	downloadSweepMovedMsg struct {
+		moved int
+		err   error
+	}
+	composeExportedMsg struct {
+		path string
+		err  error
+	}
+	tensorsLoadedMsg struct {
+		path    string
+		tensors []ggufTensor
+		err     error
+	}
+	sessionRecordedMsg struct {
+		model string
+		err   error
+	}
+	layoutsSavedMsg struct {
+		err error
+	}
+	pinsSavedMsg struct {
+		err error
+	}
+	kvOverridesSavedMsg struct {
+		modelName string
+		count     int
+		err       error
+	}
+	modelConfigsLoadedMsg struct {
+		configs map[string]modelLaunchConfig
+		err     error
+	}
+	licenseAcksLoadedMsg struct {
+		acks map[string]licenseAck
+		err  error
+	}
+	eventsLoadedMsg struct {
+		events []appEvent
+		err    error
+	}
+	licenseAckSavedMsg struct {
+		err error
+	}
+	modelConfigSavedMsg struct {
+		config modelLaunchConfig
+		err    error
+	}
+	// clientsMsg reports established connections to the served port
+	clientsMsg struct {
+		port  string
+		seq   int
+		count int
+		err   error
+	}
+	healthMsg struct {
+		port   string
+		seq    int
+		health serverHealth
+	}
 	resourceUsageMsg struct {
-		cpuPercent float64
-		memRSSBytes uint64
+		process *serverProcess
+		pid     int32
+		// seq is the poll loop the sample belongs to
+		seq           int
+		cpuPercent    float64
+		cpuSeconds    float64
+		at            time.Time
+		memRSSBytes   uint64
+		memTotalBytes uint64
+		// systemCPU and gpuPercent are the whole machine's utilization
+		systemCPU  float64
+		gpuPercent float64
+		gpuOK      bool
+		// vramBytes is the GPU memory the server process holds
+		vramBytes uint64
 	}
 	serverExitedMsg struct {
-		err error
+		exitChan chan error
+		err      error
 	}
 	startedMsg          struct{}
 	startedWithStateMsg struct {
 		logChan     chan string
 		exitChan    chan error
-		ctx         context.Context
-		cancel      context.CancelFunc
-		cmd         *exec.Cmd
+		readyChan   chan bool
+		process     *serverProcess
 		modelName   string
+		modelPath   string
+		mmprojPath  string
+		launchArgs  []string
 		port        string
 		logFilePath string
 	}
 	startErrorMsg struct {
-		err error
+		model string
+		port  string
+		err   error
 	}
 	stoppedMsg struct {
 		err error
 	}
+	pagerClosedMsg struct {
+		err error
+	}
+	snapshotTickMsg struct{}
+	restartTickMsg  struct{}
+	devWatchTickMsg struct {
+		gen int
+	}
+	devWatchStatMsg struct {
+		gen    int
+		stamps map[string]fileStamp
+	}
+	chatClosedMsg struct {
+		model string
+		err   error
+	}
+	clipboardCopiedMsg struct {
+		text string
+		err  error
+	}
+	preflightDoneMsg struct {
+		item     modelItem
+		port     string
+		warnings []string
+		gate     []diagnosticCheck
+		err      error
+		// unavailable is why the model's files can't be read, if so
+		unavailable string
+	}
+	pathsCheckedMsg struct {
+		results map[string]string
+	}
+	previewDwellMsg struct {
+		path string
+	}
+	lockCheckMsg struct {
+		owned bool
+	}
+	lockTakenOverMsg struct {
+		err error
+	}
+	visionTestDoneMsg struct {
+		reply   string
+		elapsed time.Duration
+		err     error
+	}
+	serverReadyMsg struct {
+		readyChan chan bool
+		ready     bool
+	}
+	warmupChunkMsg struct {
+		ch    chan warmupChunk
+		chunk warmupChunk
+	}
+	warmupDoneMsg struct {
+		ch chan warmupChunk
+	}
+	abChunkMsg struct {
+		side  int
+		ch    chan abChunk
+		chunk abChunk
+	}
+	abDoneMsg struct {
+		side int
+		ch   chan abChunk
+	}
+	abContextMsg struct {
+		port string
+		nCtx int
+		err  error
+	}
+	propsDiffMsg struct {
+		modelName   string
+		changes     []propsChange
+		hadPrevious bool
+		defaults    map[string]string
+		err         error
+	}
+	socketOpenedMsg struct {
+		path   string
+		socket *serverSocket
+		err    error
+	}
+	shareStartedMsg struct {
+		share *quickShare
+		err   error
+	}
+	shareURLMsg struct {
+		share *quickShare
+		url   string
+	}
+	shareExitedMsg struct {
+		share *quickShare
+		err   error
+	}
+	generationDefaultsMsg struct {
+		modelName string
+		defaults  map[string]string
+		err       error
+	}
+	benchDoneMsg struct {
+		model   string
+		results []benchResult
+		history []benchResult
+		err     error
+	}
+	benchResultsLoadedMsg struct {
+		results []benchResult
+		err     error
+	}
+	benchExportedMsg struct {
+		path string
+		err  error
+	}
+	benchSweepStepMsg struct {
+		batch  int
+		points []sweepPoint
+		build  string
+		err    error
+	}
+	benchSweepsLoadedMsg struct {
+		sweeps []benchSweep
+		err    error
+	}
+	benchSweepSavedMsg struct {
+		sweeps []benchSweep
+		err    error
+	}
+	downloadDoneMsg struct {
+		item modelItem
+		err  error
+	}
+	downloadTickMsg struct{}
+	// barnLogsMigratedMsg ends a barnLogsMigration
+	barnLogsMigratedMsg struct {
+		moved int
+		err   error
+	}
+	migrationTickMsg struct{}
+	hubSearchMsg     struct {
+		query string
+		repos []hubRepo
+		err   error
+	}
+	hubFilesMsg struct {
+		repo   string
+		models []hubModel
+		// file and quant select the model a pasted link or tag names
+		file  string
+		quant string
+		err   error
+	}
+	externalHealthMsg struct {
+		name string
+		poll externalPoll
+	}
+	hubDownloadDoneMsg struct {
+		repo string
+		name string
+		dir  string
+		// scan is the verdict on the last file downloaded, when scanned
+		scan *scanResult
+		err  error
+	}
+	logsPrunedMsg struct {
+		removed    int
+		freedBytes uint64
+		err        error
+	}
+	updateAvailableMsg struct {
+		release *githubRelease
+		err     error
+	}
+	selfUpdateDoneMsg struct {
+		version string
+		err     error
+	}
 )
 
 // confirmation action type
@@ -55,6 +501,76 @@ const (
 	confirmNone confirmAction = iota
 	confirmQuit
 	confirmStop
+	confirmLaunch
+	confirmStopAll
+	confirmDeleteCache
+	confirmDeleteSlot
+	confirmCopyLogs
+)
+
+// serverState is the managed server's lifecycle:
+// idle → starting → loading → ready → draining → stopped/crashed.
+type serverState int
+
+const (
+	serverIdle     serverState = iota // nothing launched this session
+	serverStarting                    // process being launched
+	serverLoading                     // process up, model still loading
+	serverReady                       // answering requests
+	serverDraining                    // stop requested, waiting for exit
+	serverQuitting                    // draining before the app exits
+	serverStopped
+	serverCrashed // exited on its own with an error
+)
+
+// running reports whether a server process is attached.
+func (s serverState) running() bool {
+	return s == serverLoading || s == serverReady || s == serverDraining || s == serverQuitting
+}
+
+// stopping reports whether the attached process is being shut down.
+func (s serverState) stopping() bool {
+	return s == serverDraining || s == serverQuitting
+}
+
+// serving reports whether a process is up and not shutting down.
+func (s serverState) serving() bool {
+	return s == serverLoading || s == serverReady
+}
+
+// busy reports whether a launch or a running server rules out starting
+// another.
+func (s serverState) busy() bool {
+	return s == serverStarting || s.running()
+}
+
+// transitional states animate the status chip.
+func (s serverState) transitional() bool {
+	return s == serverStarting || s == serverLoading || s.stopping()
+}
+
+// flagRetryOffer is a one-key relaunch after llama-server rejected a flag.
+type flagRetryOffer struct {
+	item     modelItem
+	rejected string
+	fix      flagFix
+}
+
+// which form is open, deciding what a submit applies to
+type formPurpose int
+
+const (
+	formNone formPurpose = iota
+	formLaunchOptions
+	formTailFile
+	formKVOverrides
+	formHFRepo
+	formSlotSave
+	formWorkspace
+	formModelConfig
+	formSampling
+	formPresetVars
+	formPrompt
 )
 
 // model state
@@ -65,86 +581,538 @@ type appModel struct {
 	styles         uiStyles
 	modelsList     list.Model
 	portInput      textinput.Model
+	barnInput      textinput.Model
+	bookmarkInput  textinput.Model
 	logsViewport   viewport.Model
 	statusLineText string
 
 	leftWidth     int
 	rightWidth    int
+	detailsWidth  int
 	contentHeight int
+	modelsHeight  int
 
-	homeDir          string
+	config     appConfig
+	configPath string
+	homeDir    string
+	// workspace is the active workspace's name, "" for the default, and
+	// historyDir holds its history files; commands are handed historyDir
+	// rather than reading it themselves
+	workspace        string
+	historyDir       string
 	barnDir          string
+	barnMissing      bool
 	logsDir          string
+	modelSources     []modelSource
 	logToFileEnabled bool
 	logFile          *os.File
 	logFilePath      string
+	lastLogFilePath  string
 	logChan          chan string
 	exitChan         chan error
-	serverCmd        *exec.Cmd
-	serverCtx        context.Context
-	serverCancel     context.CancelFunc
-	serverRunning    bool
-	serverStopping   bool
-	pendingQuit      bool
+	readyChan        chan bool
+	warmupChan       chan warmupChunk
+	warmupText       string
+	warmupActive     bool
+	process          *serverProcess
+	server           serverState
+	spinner          spinner.Model
+	serverStartedAt  time.Time
+	statusFilePath   string
+	clientConfigDir  string
+	tmuxPane         string
+	lockPath         string
+	readOnly         bool
+	observer         bool
+	lockOwner        int
 	showHelp         bool
+	mouseEnabled     bool
+	lowMemory        bool
+	logsDirty        bool
 	currentModelName string
 	currentPort      string
+	currentMMProj    string
+	visionTesting    bool
 	logBuffer        bytes.Buffer
+	logErrorCount    int
+	logWarnCount     int
 	confirmAction    confirmAction
-	cpuPercent       float64
+	confirmDeadline  time.Time
+	confirmSeq       int
+	pendingLaunch    *preflightDoneMsg
+	// unattendedLaunch is a model started over the control API, whose
+	// launch warnings are accepted since no one is there to confirm them
+	unattendedLaunch string
+	launchArgs       []string
+	launchReadiness  *readinessProbe
+	launchPreset     string
+	// startupLaunch is the model the launch options above came from a
+	// startup action or restart for
+	startupLaunch   string
+	flagFixes       []flagFix
+	flagRetry       *flagRetryOffer
+	form            *formModel
+	flagSearch      *flagSearch
+	serverFlags     []helpFlag
+	pins            []string
+	layouts         map[string]layoutPrefs
+	layoutBucket    string
+	powerPaused     bool
+	powerStopped    *startupAction
+	sleepWatch      *sleepWatch
+	lastPowerCheck  time.Time
+	diagnostics     []diagnosticCheck
+	showDiagnostics bool
+	tailPath        string
+	tailChan        chan tailLine
+	tailCancel      context.CancelFunc
+	formPurpose     formPurpose
+	kvModelName     string
+	modelConfigs    map[string]modelLaunchConfig
+	modelConfigName string
+	ports           portTable
+	startup         *startupAction
+	benchCancel     context.CancelFunc
+	benchModel      string
+	benchResults    []benchResult
+	sweep           *sweepRun
+	benchSweeps     []benchSweep
+	showSweep       bool
+	showBenchMatrix bool
+	downloadCancel  context.CancelFunc
+	download        *downloadProgress
+	hub             *hubBrowser
+	cpuPercent      float64
+	clientCount     int
+	standby         *standbyServer
+	sideServers     []sideServer
+	combinedLogs    *bytes.Buffer
+	logView         string
+	serversView     *serversView
+	servingPath     string
+	hfRepos         []hfRepoEntry
+	hfEditRepo      string
+	sessions        []sessionRecord
+	reliability     map[string]modelReliability
+	showTimeline    bool
+	showStats       bool
+	statsAllTime    bool
+	timelineWeek    bool
+	cacheView       *hfCacheView
+	tensorView      *tensorView
+	promptLibrary   *promptLibraryView
+	// shutdown is a stop of several servers in dependency order
+	shutdown *shutdownSequence
+	// starting is a launch waiting on the models it depends on
+	starting *startSequence
+	// externals are the polls of external_endpoints, in the order answered
+	externals []externalStatus
+	// promptPick is the library prompt launches use, servedPrompt the one
+	// the running server was launched with
+	promptPick      promptPick
+	servedPrompt    promptPick
+	presetLaunch    *startupAction
+	samplingView    *samplingView
+	sampling        samplingOverrides
+	servedDefaults  map[string]string
+	share           *quickShare
+	shareURL        string
+	sharePending    bool
+	downloadSweep   *downloadSweepView
+	migration       *barnLogsMigration
+	templateSandbox *templateSandbox
+	// templateRuns counts renders still running, each of which may own a
+	// throwaway server; shared by copies of the model
+	templateRuns *sync.WaitGroup
+	// root ends when llama-tui quits, cancelling the requests started
+	// under it
+	root             context.Context
+	cancelRoot       context.CancelFunc
+	abChat           *abChat
+	attached         *attachTarget
+	smokeResults     map[string]smokeResult
+	unavailable      map[string]string
+	licenseAcks      map[string]licenseAck
+	previewPath      string
+	helpQuery        string
+	slotView         *slotView
+	serverColor      int
+	proxy            *requestProxy
+	logStream        *logStreamer
+	notifier         *notifier
+	terminalProgress bool
+	capacity         resourceReservation
+	launchQueue      []queuedLaunch
+	control          *controlAPI
+	latencySamples   []latencySample
+	latencySelected  string
+	shortNames       map[string]string
+	serverLines      []timedLogLine
+	requestLogs      *requestLogView
+	proxyInFlight    int
+	proxyRates       []clientRate
+	proxyRejected    int
+	socketFlag       string
+	basicMode        bool
+	socket           *serverSocket
+	socketOpening    bool
+	mirrorSamples    []mirrorSample
+	mirrorErr        error
+	modelMeta        map[string]map[string]string
+	sortKey          string
+	groupByFamily    bool
+	expandedFamilies map[string]bool
+	foldedModels     []list.Item
+	spec             specStats
+	slo              sloState
+	lastGenerationAt time.Time
+	metricsSeq       int
+	clientsSeq       int
+	health           serverHealth
+	healthSeq        int
+	lastHealthAnswer time.Time
+	lastServerLogAt  time.Time
+	hung             string
+	restartAfterStop *startupAction
+	// failovers are when crashed servers were relaunched, for max_restarts
+	failovers        []time.Time
+	incident         *failoverIncident
+	restartSchedule  cronSchedule
+	nextRestart      time.Time
+	devWatch         bool
+	devWatchGen      int
+	watchModel       string
+	watchStamps      map[string]fileStamp
+	watchPending     map[string]fileStamp
+	snapshotKey      string
+	restoreSelected  string
+	restoreLogsTop   string
+	jsonView         *jsonLogView
+	bookmarks        []logBookmark
+	bookmarkList     *bookmarkListView
+	events           []appEvent
+	unsavedEvents    []appEvent
+	eventsView       *eventsView
+	componentMenu    *logComponentMenu
+	hiddenComponents map[string]bool
+	bookmarkJump     *bookmarkJump
+	bookmarkLine     int
+	logLinesDropped  int
+	logSession       int
+	startFailure     *startFailure
+	// quick replaces the dashboard with the quick launcher
+	quick       *quickLauncher
+	oomRecovery *oomRecovery
+	// retryArgs are flags for the next launch of one model only, such as
+	// an out-of-memory retry's smaller context
+	retryArgs        *launchRetry
+	lastUsage        resourceUsageMsg
+	cpuHistory       []float64
+	systemCPUHistory []float64
+	gpuHistory       []float64
+	gpuPercent       float64
+	memHistory       []float64
+	showLatency      bool
 	memRSSBytes      uint64
+	memTotalBytes    uint64
+	vramBytes        uint64
+	memWarnPercent   float64
+	memCritPercent   float64
+	availableUpdate  *githubRelease
+	updating         bool
 }
 
-func initialModel() appModel {
-	styles := newStyles()
-
-	home, _ := os.UserHomeDir()
-	barnDir := filepath.Join(home, llamaBarnRelativeDir)
-	logsDir := filepath.Join(barnDir, logsRelativeDir)
+// initialModel sets up the app in workspace ("" for the default).
+func initialModel(workspace string) appModel {
+	home, homeErr := userHomeDir()
+	// runTUI has made sure there is one
+	barnDir, _ := getDefaultBarnDir()
+	configPath := getConfigPath(barnDir)
+	cfg, cfgErr := loadConfig(configPath)
+	cfg, barnDir, wsErr := cfg.inWorkspace(workspace, home, barnDir)
+	logsDir := appLogsDir()
+	dir := historyDir(workspace)
+	styles, themeErr := newStyles(cfg.Theme)
+	lockPath := getLockPath(configPath)
+	lockErr := acquireLock(lockPath)
 
 	items := []list.Item{}
-	mdlList := list.New(items, list.NewDefaultDelegate(), 0, 0)
+	mdlList := list.New(items, newModelDelegate(styles, ""), 0, 0)
 	mdlList.Title = "Models in " + barnDir
+	if workspace != "" {
+		mdlList.Title += " (" + workspace + ")"
+	}
 	mdlList.DisableQuitKeybindings()
 	mdlList.SetShowHelp(false)
 	mdlList.SetFilteringEnabled(true)
+	mdlList.Filter = filterModels
 
 	port := textinput.New()
 	port.Placeholder = "port"
-	port.SetValue(defaultPort)
+	if cfg.AutoPorts.enabled() {
+		// Empty means "derive from the model name"
+		port.Placeholder = "auto"
+	} else {
+		port.SetValue(defaultPort)
+	}
 	port.CharLimit = 5
 	port.Prompt = "Port: "
 
+	barn := textinput.New()
+	barn.Placeholder = "models directory"
+	barn.Prompt = "Models dir: "
+
+	bookmark := textinput.New()
+	bookmark.Placeholder = "label (optional)"
+	bookmark.Prompt = "Bookmark: "
+	bookmark.CharLimit = 60
+
 	vp := viewport.New(0, 0)
 	vp.SetContent("")
 
 	m := appModel{
 		styles:           styles,
+		config:           cfg,
+		configPath:       configPath,
+		statusFilePath:   getStatusFilePath(cfg),
+		clientConfigDir:  getClientConfigDir(cfg),
+		lockPath:         lockPath,
 		modelsList:       mdlList,
 		portInput:        port,
+		barnInput:        barn,
+		bookmarkInput:    bookmark,
+		groupByFamily:    cfg.GroupModelFamilies,
 		logsViewport:     vp,
 		statusLineText:   "Ready",
 		homeDir:          home,
+		workspace:        workspace,
+		historyDir:       dir,
 		barnDir:          barnDir,
 		logsDir:          logsDir,
+		modelSources:     modelSources(home),
 		logToFileEnabled: false,
 		logChan:          nil,
 		exitChan:         nil,
-		serverCmd:        nil,
-		serverRunning:    false,
-		serverStopping:   false,
-		pendingQuit:      false,
+		server:           serverIdle,
+		spinner:          spinner.New(spinner.WithSpinner(spinner.MiniDot), spinner.WithStyle(styles.accent)),
 		showHelp:         false,
+		mouseEnabled:     !cfg.DisableMouse,
+		lowMemory:        cfg.LowMemory,
+		basicMode:        cfg.BasicMode,
 		currentModelName: "",
 		currentPort:      "",
 		confirmAction:    confirmNone,
+		ports:            portTable{},
 		cpuPercent:       0,
 		memRSSBytes:      0,
+		memWarnPercent:   memoryThresholdPercent(cfg.MemoryWarnPercent, defaultMemWarnPercent),
+		memCritPercent:   memoryThresholdPercent(cfg.MemoryCritPercent, defaultMemCritPercent),
+		templateRuns:     &sync.WaitGroup{},
+	}
+	m.root, m.cancelRoot = context.WithCancel(context.Background())
+	var held errLockHeld
+	if errors.As(lockErr, &held) {
+		// Another instance manages servers; watch without interfering
+		m.readOnly = true
+		m.lockOwner = held.owner.PID
+		m.statusLineText = fmt.Sprintf("Read-only: %v - [T] take over", held)
+	} else if lockErr != nil {
+		m.statusLineText = fmt.Sprintf("Lock error (continuing unlocked): %v", lockErr)
+	}
+	if !m.readOnly {
+		// The instance holding the lock moves files an earlier version left
+		// behind, before any history is read; old server logs follow from
+		// Init
+		if note := migrationNote(migrateLegacyPaths()); note != "" {
+			m.statusLineText = note
+		}
+		m.migration = newBarnLogsMigration(barnDir)
+	}
+	if !cfg.DisableTmuxStatus && !m.readOnly {
+		// The instance managing servers owns the tmux options
+		m.tmuxPane = tmuxPaneFromEnv()
+	}
+	layouts, layoutsErr := loadLayouts()
+	m.layouts = layouts
+	if layoutsErr != nil {
+		m.statusLineText = fmt.Sprintf("Saved layouts unavailable: %v", layoutsErr)
+	}
+	pins, pinsErr := loadPins(dir)
+	m.pins = pins
+	if pinsErr != nil {
+		m.statusLineText = fmt.Sprintf("Pinned models unavailable: %v", pinsErr)
+	}
+	smoke, smokeErr := loadSmokeResults(dir)
+	m.smokeResults = smoke
+	if smokeErr != nil {
+		m.statusLineText = fmt.Sprintf("Smoke test results unavailable: %v", smokeErr)
+	}
+	hfRepos, hfErr := loadHFRepos(dir)
+	m.hfRepos = hfRepos
+	if hfErr != nil {
+		m.statusLineText = fmt.Sprintf("Hugging Face repos unavailable: %v", hfErr)
+	}
+	if cfg.ProxyPort != "" {
+		if portNum, err := validatePort(cfg.ProxyPort); err == nil {
+			m.proxy = newRequestProxy(cfg.ProxyHost, cfg.ProxyPort)
+			m.ports = m.ports.with(portNum, "the request proxy")
+		} else {
+			m.statusLineText = fmt.Sprintf("Request proxy off: proxy_port: %v", err)
+		}
+	}
+	if m.proxy != nil && cfg.ProxyMirrorPort != "" {
+		if _, err := validatePort(cfg.ProxyMirrorPort); err == nil {
+			m.proxy.mirror = cfg.ProxyMirrorPort
+			if cfg.ProxyMirrorRecord {
+				m.proxy.mirrorLog = mirrorLogPath()
+			}
+		} else {
+			m.statusLineText = fmt.Sprintf("Request mirroring off: proxy_mirror_port: %v", err)
+		}
+	}
+	if m.proxy != nil && cfg.ProxyRateLimit != (proxyRateLimit{}) {
+		if err := cfg.ProxyRateLimit.validate(); err != nil {
+			m.statusLineText = fmt.Sprintf("Rate limiting off: proxy_rate_limit: %v", err)
+		} else if cfg.ProxyRateLimit.enabled() {
+			m.proxy.limiter = newRateLimiter(cfg.ProxyRateLimit)
+		}
+	}
+	if s, err := parseCronSchedule(cfg.RestartSchedule); err == nil {
+		m.restartSchedule = s
+	} else {
+		m.statusLineText = fmt.Sprintf("Scheduled restarts off: restart_schedule %v", err)
+	}
+	if cfg.LogStream != "" {
+		if s, err := newLogStreamer(cfg.LogStream); err == nil {
+			m.logStream = s
+		} else {
+			m.statusLineText = fmt.Sprintf("Log stream off: log_stream: %v", err)
+		}
+	}
+	if on, err := terminalProgressEnabled(cfg.TerminalProgress); err == nil {
+		m.terminalProgress = on
+	} else {
+		m.statusLineText = fmt.Sprintf("Terminal progress off: terminal_progress %v", err)
+	}
+	if len(cfg.Notifications) > 0 && !m.readOnly {
+		// Only the instance managing servers notifies, once per event
+		if n, err := newNotifier(cfg.Notifications); err == nil {
+			m.notifier = n
+		} else {
+			m.statusLineText = fmt.Sprintf("Notifications off: notifications: %v", err)
+		}
+	}
+	if cfg.ControlAPI != "" {
+		if c, err := newControlAPI(cfg.ControlAPI); err == nil {
+			m.control = c
+		} else {
+			m.statusLineText = fmt.Sprintf("Control API off: control_api: %v", err)
+		}
+	}
+	if themeErr != nil {
+		m.statusLineText = fmt.Sprintf("Theme: %v", themeErr)
+	}
+	if homeErr != nil {
+		m.statusLineText = fmt.Sprintf("No home directory (%v): ollama, LM Studio, and GPT4All models and ~/ paths are unavailable", homeErr)
+		m.eventError("config", "", m.statusLineText)
+	}
+	if wsErr != nil {
+		m.statusLineText = wsErr.Error()
+	}
+	if cfgErr != nil {
+		m.statusLineText = fmt.Sprintf("Config error (using defaults): %v", cfgErr)
 	}
 
 	return m
 }
 
+// setBarnDir points the app at a new models directory, returning the
+// command that brings over any logs an earlier version kept in it.
+func (m *appModel) setBarnDir(dir string) tea.Cmd {
+	// Model paths derive from it and are handed to servers started in
+	// other directories, so keep it absolute
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	m.barnDir = dir
+	m.modelsList.Title = "Models in " + dir
+	if m.workspace != "" {
+		m.modelsList.Title += " (" + m.workspace + ")"
+	}
+	if m.readOnly || m.migration != nil {
+		// One at a time; a directory skipped now is migrated next start
+		return nil
+	}
+	if m.migration = newBarnLogsMigration(dir); m.migration != nil {
+		return m.migration.cmd()
+	}
+	return nil
+}
+
 func (m appModel) Init() tea.Cmd {
-	return m.scanModelsCmd()
+	cmds := []tea.Cmd{
+		m.scanModelsCmd(),
+		pruneLogsCmd(m.logsDir, m.config.LogRetention),
+		tmuxUpdateCmd(m.tmuxPane, m.tmuxStatus()),
+		terminalUpdateCmd(terminalStatus{}, m.terminalStatus()),
+		loadBenchResultsCmd(m.historyDir),
+		loadBenchSweepsCmd(m.historyDir),
+		loadModelConfigsCmd(),
+		loadSessionsCmd(m.historyDir),
+		loadLicenseAcksCmd(m.historyDir),
+		loadEventsCmd(m.readOnly),
+		snapshotTickCmd(),
+	}
+	if m.migration != nil {
+		cmds = append(cmds, m.migration.cmd())
+	}
+	cmds = append(cmds, m.externalPollCmds()...)
+	if !m.readOnly {
+		// A read-only instance would overwrite the lock holder's status
+		cmds = append(cmds, writeStatusFileCmd(m.statusFilePath, m.serverStatus()))
+	}
+	if m.attached != nil {
+		cmds = append(cmds, attachHealthCmd(m.attached.port, false, 0))
+	} else if !m.readOnly {
+		cmds = append(cmds, lockCheckCmd(m.lockPath))
+		// Clears endpoints left by a previous run that didn't exit cleanly
+		cmds = append(cmds, writeClientConfigsCmd(m.clientConfigDir, m.clientEndpoints()))
+	}
+	if m.config.StartupChecks != "off" {
+		cmds = append(cmds, diagnosticsCmd(m.barnDir, m.logsDir, m.portInput.Value(), true))
+	}
+	if m.config.Power.enabled() || m.config.Power.watchesSleep() {
+		cmds = append(cmds, powerCheckCmd(0))
+	}
+	if m.config.Power.watchesSleep() && !m.readOnly && m.attached == nil {
+		cmds = append(cmds, startSleepWatchCmd(m.root))
+	}
+	if m.proxy != nil {
+		cmds = append(cmds, serveProxyCmd(m.proxy), proxyStatsCmd(m.proxy))
+	}
+	if m.logStream != nil {
+		cmds = append(cmds, serveLogStreamCmd(m.logStream))
+	}
+	if m.config.hasReservations() {
+		cmds = append(cmds, detectCapacityCmd(m.config.ResourceCapacity))
+	}
+	if m.notifier != nil {
+		cmds = append(cmds, runNotifierCmd(m.notifier), waitForNotifyFailure(m.notifier))
+	}
+	if m.control != nil {
+		cmds = append(cmds, serveControlCmd(m.control), waitControlCmd(m.control))
+	}
+	if m.restartSchedule.enabled {
+		cmds = append(cmds, restartTickCmd())
+	}
+	if m.devWatch {
+		cmds = append(cmds, devWatchTickCmd(m.devWatchGen))
+	}
+	if m.config.CheckForUpdates {
+		cmds = append(cmds, checkForUpdateCmd())
+	}
+	if m.lowMemory {
+		cmds = append(cmds, logRefreshTickCmd())
+	}
+	return tea.Batch(cmds...)
 }
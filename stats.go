@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// usageStats totals the session history for one model or preset.
+type usageStats struct {
+	name     string
+	served   time.Duration
+	sessions int
+	crashes  int
+	last     time.Time
+}
+
+func (u usageStats) crashRate() float64 {
+	if u.sessions == 0 {
+		return 0
+	}
+	return float64(u.crashes) / float64(u.sessions)
+}
+
+// usageSummary is everything the stats screen shows, computed from the
+// session history alone.
+type usageSummary struct {
+	total   usageStats
+	first   time.Time
+	models  []usageStats
+	presets []usageStats
+}
+
+// summarizeUsage totals sessions ending after from (all of them when from
+// is zero), busiest first.
+func summarizeUsage(sessions []sessionRecord, from time.Time) usageSummary {
+	var sum usageSummary
+	models := map[string]*usageStats{}
+	presets := map[string]*usageStats{}
+	add := func(into map[string]*usageStats, name string, s sessionRecord, served time.Duration) {
+		u := into[name]
+		if u == nil {
+			u = &usageStats{name: name}
+			into[name] = u
+		}
+		u.served += served
+		u.sessions++
+		if s.Crashed {
+			u.crashes++
+		}
+		if s.End.After(u.last) {
+			u.last = s.End
+		}
+	}
+	for _, s := range sessions {
+		if !from.IsZero() && !s.End.After(from) {
+			continue
+		}
+		start := s.Start
+		if !from.IsZero() && start.Before(from) {
+			start = from
+		}
+		served := max(s.End.Sub(start), 0)
+		sum.total.served += served
+		sum.total.sessions++
+		if s.Crashed {
+			sum.total.crashes++
+		}
+		if sum.first.IsZero() || s.Start.Before(sum.first) {
+			sum.first = s.Start
+		}
+		add(models, s.Model, s, served)
+		if s.Preset != "" {
+			add(presets, s.Preset, s, served)
+		}
+	}
+	sorted := func(in map[string]*usageStats) []usageStats {
+		out := make([]usageStats, 0, len(in))
+		for _, u := range in {
+			out = append(out, *u)
+		}
+		sort.Slice(out, func(i, j int) bool {
+			if out[i].served != out[j].served {
+				return out[i].served > out[j].served
+			}
+			return out[i].name < out[j].name
+		})
+		return out
+	}
+	sum.models, sum.presets = sorted(models), sorted(presets)
+	return sum
+}
+
+// formatHours renders served time in hours, the unit people budget in.
+func formatHours(d time.Duration) string {
+	if d < time.Hour {
+		return formatSpan(d)
+	}
+	return fmt.Sprintf("%.1fh", d.Hours())
+}
+
+// renderStatsView summarizes the session history: hours served and crash
+// rate per model and per preset, over the past 30 days or all time. Nothing
+// leaves the machine; it reads the same history as the timeline.
+func (m appModel) renderStatsView(width int) string {
+	now := m.config.Timestamps.now()
+	from, period := now.AddDate(0, 0, -30), "the past 30 days"
+	if m.statsAllTime {
+		from, period = time.Time{}, "all recorded time"
+	}
+	footer := m.styles.help.Render("Computed locally from the session history  ·  [tab] 30 days/all time  [N] or [esc] close")
+
+	sessions := append([]sessionRecord(nil), m.sessions...)
+	if m.server.serving() && !m.serverStartedAt.IsZero() {
+		sessions = append(sessions, sessionRecord{Model: m.currentModelName, Preset: m.launchPreset, Start: m.serverStartedAt, End: now})
+	}
+	sum := summarizeUsage(sessions, from)
+	var b strings.Builder
+	b.WriteString(m.styles.help.Render("Usage over "+period) + "\n\n")
+	if sum.total.sessions == 0 {
+		return b.String() + "No sessions recorded in this period.\n\n" + footer
+	}
+	overview := fmt.Sprintf("%s served in %s across %s", formatHours(sum.total.served), pluralize(sum.total.sessions, "session"), pluralize(len(sum.models), "model"))
+	if m.statsAllTime {
+		overview += " since " + m.config.Timestamps.date(sum.first)
+	}
+	b.WriteString(overview + "\n")
+	crashes := fmt.Sprintf("%d crashes", sum.total.crashes)
+	if sum.total.crashes == 1 {
+		crashes = "1 crash"
+	}
+	crashLine := fmt.Sprintf("Crash rate %.0f%% (%s)", sum.total.crashRate()*100, crashes)
+	if sum.total.crashes > 0 {
+		crashLine = m.styles.usageWarn.Render(crashLine)
+	}
+	b.WriteString(crashLine + "\n\n")
+
+	onDisk := map[string]bool{}
+	for _, it := range m.allModelItems() {
+		if mi, ok := it.(modelItem); ok {
+			onDisk[mi.name] = true
+		}
+	}
+	nameWidth := min(max(width-46, 16), 40)
+	table := func(title string, rows []usageStats, limit int, markRemoved bool) {
+		b.WriteString(m.styles.help.Render(fmt.Sprintf("%-*s %8s %9s %7s  %s", nameWidth, title, "served", "sessions", "crashes", "last used")) + "\n")
+		for i, u := range rows {
+			if i == limit {
+				b.WriteString(m.styles.help.Render(fmt.Sprintf("… %d more", len(rows)-limit)) + "\n")
+				break
+			}
+			name := u.name
+			if markRemoved {
+				name = m.displayName(name)
+				if !onDisk[u.name] {
+					name += " (removed)"
+				}
+			}
+			crashes := fmt.Sprintf("%7s", "-")
+			if u.crashes > 0 {
+				crashes = m.styles.usageWarn.Render(fmt.Sprintf("%6.0f%%", u.crashRate()*100))
+			}
+			label := lipgloss.NewStyle().Width(nameWidth).Render(ellipsize(name, nameWidth))
+			b.WriteString(fmt.Sprintf("%s %8s %9d %s  %s\n", label, formatHours(u.served), u.sessions, crashes, m.config.Timestamps.date(u.last)))
+		}
+	}
+	table("Model", sum.models, 12, true)
+	if len(sum.presets) > 0 {
+		b.WriteString("\n")
+		table("Preset", sum.presets, 6, false)
+	} else {
+		b.WriteString("\n" + m.styles.help.Render("No preset launches in this period (start one with --preset)") + "\n")
+	}
+	b.WriteString("\n" + footer)
+	return b.String()
+}
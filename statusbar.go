@@ -0,0 +1,116 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const statusSeparator = " • "
+
+// statusSegment is one labelled piece of the status bar.
+// Segments with a lower priority value are kept longest when space runs out;
+// truncatable segments are ellipsized down to minWidth before anything is dropped.
+type statusSegment struct {
+	label       string
+	value       string
+	style       lipgloss.Style
+	priority    int
+	truncatable bool
+	minWidth    int
+}
+
+func (s statusSegment) width() int {
+	// Measure the styled value so padding on chip styles is accounted for
+	return lipgloss.Width(s.label) + lipgloss.Width(s.style.Render(s.value))
+}
+
+func (s statusSegment) render(labelStyle lipgloss.Style) string {
+	return labelStyle.Render(s.label) + s.style.Render(s.value)
+}
+
+// renderStatusBar lays out segments on a single line no wider than width.
+// A width of zero or less disables truncation.
+func renderStatusBar(segments []statusSegment, labelStyle lipgloss.Style, width int) string {
+	segs := make([]statusSegment, len(segments))
+	copy(segs, segments)
+
+	total := func() int {
+		w := 0
+		for i, s := range segs {
+			if i > 0 {
+				w += lipgloss.Width(statusSeparator)
+			}
+			w += s.width()
+		}
+		return w
+	}
+
+	if width > 0 {
+		// First shrink truncatable values, so e.g. a long model name gets
+		// ellipsized before the port disappears
+		for i := range segs {
+			over := total() - width
+			if over <= 0 {
+				break
+			}
+			if !segs[i].truncatable {
+				continue
+			}
+			valueW := lipgloss.Width(segs[i].value)
+			target := valueW - over
+			if target < segs[i].minWidth {
+				target = segs[i].minWidth
+			}
+			if target < valueW {
+				segs[i].value = ellipsize(segs[i].value, target)
+			}
+		}
+		// Then drop the least important segments until the line fits
+		for total() > width && len(segs) > 1 {
+			drop := -1
+			for i, s := range segs {
+				if drop < 0 || s.priority > segs[drop].priority {
+					drop = i
+				}
+			}
+			segs = append(segs[:drop], segs[drop+1:]...)
+		}
+	}
+
+	parts := make([]string, 0, len(segs))
+	for _, s := range segs {
+		parts = append(parts, s.render(labelStyle))
+	}
+	line := strings.Join(parts, labelStyle.Render(statusSeparator))
+	if width > 0 && lipgloss.Width(line) > width {
+		// Even the most important segment is too wide; hard-clip it
+		line = lipgloss.NewStyle().MaxWidth(width).Render(line)
+	}
+	return line
+}
+
+// ellipsize shortens plain (unstyled) text to at most width cells, marking the
+// cut with an ellipsis.
+func ellipsize(s string, width int) string {
+	if lipgloss.Width(s) <= width {
+		return s
+	}
+	if width <= 0 {
+		return ""
+	}
+	if width == 1 {
+		return "…"
+	}
+	var b strings.Builder
+	w := 0
+	for _, r := range s {
+		rw := lipgloss.Width(string(r))
+		if w+rw > width-1 {
+			break
+		}
+		b.WriteRune(r)
+		w += rw
+	}
+	return b.String() + "…"
+}
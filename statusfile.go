@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// serverStatus is the machine-readable snapshot written for external
+// widgets (tmux status line, polybar, SketchyBar, ...).
+type serverStatus struct {
+	State     string `json:"state"`
+	Model     string `json:"model,omitempty"`
+	Port      string `json:"port,omitempty"`
+	PID       int    `json:"pid,omitempty"`
+	StartedAt string `json:"started_at,omitempty"`
+}
+
+// getStatusFilePath resolves where the status file is written.
+// Priority:
+// 1) status_file in the config ("off" disables it)
+// 2) <user cache dir>/llama-tui/status.json
+func getStatusFilePath(cfg appConfig) string {
+	if p := strings.TrimSpace(cfg.StatusFile); p != "" {
+		if p == "off" {
+			return ""
+		}
+		return p
+	}
+	cacheDir := getCacheDir()
+	if cacheDir == "" {
+		return ""
+	}
+	return filepath.Join(cacheDir, "status.json")
+}
+
+func (m appModel) serverStatus() serverStatus {
+	st := serverStatus{State: "stopped"}
+	switch {
+	case m.server.stopping():
+		st.State = "stopping"
+	case m.server.running():
+		st.State = "running"
+	default:
+		return st
+	}
+	st.Model = m.currentModelName
+	st.Port = m.currentPort
+	if m.process != nil {
+		st.PID = m.process.pid
+	}
+	if !m.serverStartedAt.IsZero() {
+		st.StartedAt = m.serverStartedAt.Format(time.RFC3339)
+	}
+	return st
+}
+
+// writeStatusFile replaces the status file atomically so pollers never see
+// a partially written document.
+func writeStatusFile(path string, st serverStatus) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'), 0o644)
+}
+
+// writeFileAtomic replaces path with data through a temporary file of its
+// own beside it, so readers see the old or the new file and concurrent
+// writers, another instance perhaps, never share a half-written one.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(perm)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+	}
+	return err
+}
+
+func writeStatusFileCmd(path string, st serverStatus) tea.Cmd {
+	if path == "" {
+		return nil
+	}
+	return func() tea.Msg {
+		// Best-effort: a stale widget is not worth interrupting the UI for
+		_ = writeStatusFile(path, st)
+		return nil
+	}
+}
@@ -1,6 +1,13 @@
 package main
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
 
 type uiStyles struct {
 	title          lipgloss.Style
@@ -19,26 +26,197 @@ type uiStyles struct {
 	logInfo        lipgloss.Style
 	disabled       lipgloss.Style
 	confirmWarning lipgloss.Style
+	usageWarn      lipgloss.Style
+	usageCritical  lipgloss.Style
+	servingBadge   lipgloss.Style
+	propsAdded     lipgloss.Style
+	propsRemoved   lipgloss.Style
+	propsChanged   lipgloss.Style
+	// instances tell concurrent servers apart
+	instances []lipgloss.Style
+	// logComponents color llama.cpp log components (srv, slot, ...)
+	logComponents []lipgloss.Style
+	// statusSymbols marks server states with ●/◐/○ so they don't rely on
+	// color alone
+	statusSymbols bool
+	// monochrome styles use no color at all, only bold and reverse video
+	monochrome bool
+}
+
+// uiTheme is a palette the styles are built from.
+type uiTheme struct {
+	title   lipgloss.Color
+	subtext lipgloss.Color
+	accent  lipgloss.Color
+	surface lipgloss.Color
+	dim     lipgloss.Color
+	ok      lipgloss.Color
+	warn    lipgloss.Color
+	err     lipgloss.Color
+	alert   lipgloss.Color
+	// instances are distinct colors for concurrent servers
+	instances []lipgloss.Color
+	symbols   bool
 }
 
-func newStyles() uiStyles {
+const defaultTheme = "mocha"
+
+var uiThemes = map[string]uiTheme{
 	// Catppuccin Mocha color palette
+	"mocha": {
+		title:   "#b4befe", // lavender
+		subtext: "#a6adc8", // subtext0
+		accent:  "#89b4fa", // blue
+		surface: "#313244", // surface0
+		dim:     "#6c7086", // overlay1
+		ok:      "#a6e3a1", // green
+		warn:    "#f9e2af", // yellow
+		err:     "#f38ba8", // red
+		alert:   "#fab387", // peach
+		// blue, mauve, teal, pink
+		instances: []lipgloss.Color{"#89b4fa", "#cba6f7", "#94e2d5", "#f5c2e7"},
+	},
+	// Bright, saturated colors on black; dimmed text stays readable
+	"high-contrast": {
+		title:     "#ffffff",
+		subtext:   "#e0e0e0",
+		accent:    "#00ffff",
+		surface:   "#000000",
+		dim:       "#b0b0b0",
+		ok:        "#00ff00",
+		warn:      "#ffff00",
+		err:       "#ff4040",
+		alert:     "#ff9900",
+		instances: []lipgloss.Color{"#00ffff", "#ff00ff", "#ffff00", "#00ff00"},
+		symbols:   true,
+	},
+	// Okabe-Ito colors, distinguishable with red-green color blindness;
+	// good states are blue rather than green
+	"colorblind": {
+		title:   "#cc79a7", // reddish purple
+		subtext: "#bbbbbb",
+		accent:  "#56b4e9", // sky blue
+		surface: "#303030",
+		dim:     "#888888",
+		ok:      "#56b4e9", // sky blue
+		warn:    "#f0e442", // yellow
+		err:     "#d55e00", // vermillion
+		alert:   "#e69f00", // orange
+		// sky blue, orange, bluish green, reddish purple
+		instances: []lipgloss.Color{"#56b4e9", "#e69f00", "#009e73", "#cc79a7"},
+		symbols:   true,
+	},
+}
+
+// themeNames lists the available themes for messages and docs.
+func themeNames() []string {
+	names := make([]string, 0, len(uiThemes))
+	for name := range uiThemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// monochrome is set by --no-color or a non-empty NO_COLOR (see
+// https://no-color.org) before the model is built; themes are then ignored.
+var monochrome bool
+
+// useMonochrome turns color off for lipgloss and everything rendered
+// through it, including the bubbles components.
+func useMonochrome(flag bool) {
+	if !flag && os.Getenv("NO_COLOR") == "" {
+		return
+	}
+	monochrome = true
+	lipgloss.SetColorProfile(termenv.Ascii)
+}
+
+// newStyles builds the styles for a theme; an empty name is the default.
+func newStyles(name string) (uiStyles, error) {
+	if monochrome {
+		return monochromeStyles(), nil
+	}
+	if name == "" {
+		name = defaultTheme
+	}
+	t, ok := uiThemes[name]
+	if !ok {
+		s, _ := newStyles(defaultTheme)
+		return s, fmt.Errorf("unknown theme %q (available: %v)", name, themeNames())
+	}
+	instances := make([]lipgloss.Style, len(t.instances))
+	for i, c := range t.instances {
+		instances[i] = lipgloss.NewStyle().Bold(true).Foreground(c)
+	}
+	// Component colors stay clear of the error and warning colors
+	var components []lipgloss.Style
+	for _, c := range append([]lipgloss.Color{t.ok, t.alert, t.title}, t.instances...) {
+		components = append(components, lipgloss.NewStyle().Foreground(c))
+	}
+	chip := lipgloss.NewStyle().Bold(true).Background(t.surface).Padding(0, 1)
 	return uiStyles{
-		title:          lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#b4befe")), // lavender
-		status:         lipgloss.NewStyle().Foreground(lipgloss.Color("#a6adc8")),            // subtext0
-		sectionTitle:   lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#b4befe")), // lavender
-		help:           lipgloss.NewStyle().Foreground(lipgloss.Color("#a6adc8")),            // subtext0
-		accent:         lipgloss.NewStyle().Foreground(lipgloss.Color("#89b4fa")),            // blue
+		title:          lipgloss.NewStyle().Bold(true).Foreground(t.title),
+		status:         lipgloss.NewStyle().Foreground(t.subtext),
+		sectionTitle:   lipgloss.NewStyle().Bold(true).Foreground(t.title),
+		help:           lipgloss.NewStyle().Foreground(t.subtext),
+		accent:         lipgloss.NewStyle().Foreground(t.accent),
 		border:         lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1),
-		statusRunning:  lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#a6e3a1")).Background(lipgloss.Color("#313244")).Padding(0, 1), // green on surface0
-		statusStopping: lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#f9e2af")).Background(lipgloss.Color("#313244")).Padding(0, 1), // yellow on surface0
-		statusStopped:  lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#6c7086")).Background(lipgloss.Color("#313244")).Padding(0, 1), // overlay1 on surface0
-		panelBorder:    lipgloss.NewStyle().Foreground(lipgloss.Color("#6c7086")),                                                                // overlay1
-		panelTitle:     lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#b4befe")),                                                     // lavender
-		logError:       lipgloss.NewStyle().Foreground(lipgloss.Color("#f38ba8")),                                                                // red
-		logWarn:        lipgloss.NewStyle().Foreground(lipgloss.Color("#f9e2af")),                                                                // yellow
-		logInfo:        lipgloss.NewStyle().Foreground(lipgloss.Color("#89b4fa")),                                                                // blue
-		disabled:       lipgloss.NewStyle().Foreground(lipgloss.Color("#6c7086")),                                                                // overlay1 (dimmed)
-		confirmWarning: lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#fab387")).Background(lipgloss.Color("#313244")), // orange/peach on surface0, bold
+		statusRunning:  chip.Foreground(t.ok),
+		statusStopping: chip.Foreground(t.warn),
+		statusStopped:  chip.Foreground(t.dim),
+		panelBorder:    lipgloss.NewStyle().Foreground(t.dim),
+		panelTitle:     lipgloss.NewStyle().Bold(true).Foreground(t.title),
+		logError:       lipgloss.NewStyle().Foreground(t.err),
+		logWarn:        lipgloss.NewStyle().Foreground(t.warn),
+		logInfo:        lipgloss.NewStyle().Foreground(t.accent),
+		disabled:       lipgloss.NewStyle().Foreground(t.dim),
+		confirmWarning: lipgloss.NewStyle().Bold(true).Foreground(t.alert).Background(t.surface),
+		usageWarn:      lipgloss.NewStyle().Bold(true).Foreground(t.warn),
+		usageCritical:  lipgloss.NewStyle().Bold(true).Foreground(t.err),
+		servingBadge:   lipgloss.NewStyle().Bold(true).Foreground(t.ok),
+		propsAdded:     lipgloss.NewStyle().Foreground(t.ok),
+		propsRemoved:   lipgloss.NewStyle().Foreground(t.err),
+		propsChanged:   lipgloss.NewStyle().Foreground(t.warn),
+		instances:      instances,
+		logComponents:  components,
+		statusSymbols:  t.symbols,
+	}, nil
+}
+
+// monochromeStyles keep what color would convey legible without it: bold
+// for headings and problems, reverse video for prompts that need attention,
+// and state symbols on the status chip.
+func monochromeStyles() uiStyles {
+	plain := lipgloss.NewStyle()
+	bold := plain.Bold(true)
+	chip := bold.Padding(0, 1)
+	return uiStyles{
+		title:          bold,
+		status:         plain,
+		sectionTitle:   bold,
+		help:           plain,
+		accent:         plain,
+		border:         plain.Border(lipgloss.RoundedBorder()).Padding(0, 1),
+		statusRunning:  chip,
+		statusStopping: chip,
+		statusStopped:  chip,
+		panelBorder:    plain,
+		panelTitle:     bold,
+		logError:       bold,
+		logWarn:        plain,
+		logInfo:        plain,
+		disabled:       plain.Faint(true),
+		confirmWarning: bold.Reverse(true),
+		usageWarn:      bold,
+		usageCritical:  bold.Reverse(true),
+		servingBadge:   bold,
+		propsAdded:     plain,
+		propsRemoved:   plain,
+		propsChanged:   plain,
+		instances:      []lipgloss.Style{bold},
+		logComponents:  []lipgloss.Style{plain},
+		statusSymbols:  true,
+		monochrome:     true,
 	}
 }
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// stopGrace is how long a stopped server's process group has to exit
+// before it is killed.
+const stopGrace = 2 * time.Second
+
+var errProcessExited = errors.New("server is not running")
+
+// serverProcess is a launched server. Its supervisor goroutine is the only
+// code that touches the exec.Cmd once it has started: the UI asks it to
+// stop, suspend, or resume over a channel and hears of the exit over
+// another, so nothing reads or writes the process from outside its own
+// goroutine, nor the model from outside the Update loop.
+type serverProcess struct {
+	pid  int
+	args []string
+	// ctx ends once the process is asked to stop or exits
+	ctx      context.Context
+	requests chan processRequest
+	// done is closed when the supervisor has finished with the process
+	done chan struct{}
+}
+
+type processOp int
+
+const (
+	processStop processOp = iota
+	processSuspend
+	processResume
+)
+
+type processRequest struct {
+	op    processOp
+	reply chan error
+}
+
+// superviseProcess takes over cmd, started under ctx, and sends its exit
+// error to exit once.
+func superviseProcess(ctx context.Context, cancel context.CancelFunc, cmd *exec.Cmd, exit chan<- error) *serverProcess {
+	p := &serverProcess{
+		pid:      cmd.Process.Pid,
+		args:     cmd.Args,
+		ctx:      ctx,
+		requests: make(chan processRequest),
+		done:     make(chan struct{}),
+	}
+	go p.supervise(cmd, cancel, exit)
+	return p
+}
+
+// supervise waits for the process and answers requests until it has
+// exited and, if it was stopped, its group has been killed.
+func (p *serverProcess) supervise(cmd *exec.Cmd, cancel context.CancelFunc, exit chan<- error) {
+	defer close(p.done)
+	defer cancel()
+	waited := make(chan error, 1)
+	go func() { waited <- cmd.Wait() }()
+	suspended, exited, stopping := false, false, false
+	var kill <-chan time.Time
+	for {
+		select {
+		case req := <-p.requests:
+			var err error
+			switch {
+			case req.op == processStop && !stopping:
+				stopping = true
+				cancel()
+				// A suspended process cannot act on signals until resumed
+				if suspended {
+					_ = setServerSuspended(cmd, false)
+				}
+				// Best-effort graceful signals to the whole process group
+				_ = signalProcessGroup(cmd, syscall.SIGINT)
+				_ = signalProcessGroup(cmd, syscall.SIGTERM)
+				kill = time.After(stopGrace)
+			case req.op == processSuspend || req.op == processResume:
+				if exited || stopping {
+					err = errProcessExited
+					break
+				}
+				if err = setServerSuspended(cmd, req.op == processSuspend); err == nil {
+					suspended = req.op == processSuspend
+				}
+			}
+			if req.reply != nil {
+				req.reply <- err
+			}
+		case err := <-waited:
+			exited, waited = true, nil
+			exit <- err
+			close(exit)
+			// Done unless a stop is still waiting to kill the group
+			if !stopping || kill == nil {
+				return
+			}
+		case <-kill:
+			// The group is killed even if the server already exited, so
+			// no orphaned workers are left behind
+			_ = signalProcessGroup(cmd, syscall.SIGKILL)
+			kill = nil
+			if exited {
+				return
+			}
+		}
+	}
+}
+
+// request asks the supervisor for op and waits for the answer.
+func (p *serverProcess) request(op processOp) error {
+	if p == nil {
+		return errProcessExited
+	}
+	reply := make(chan error, 1)
+	select {
+	case p.requests <- processRequest{op: op, reply: reply}:
+		return <-reply
+	case <-p.done:
+		return errProcessExited
+	}
+}
+
+// stop asks the process group to exit, killing it after a grace period.
+// The exit is reported as usual.
+func (p *serverProcess) stop() {
+	_ = p.request(processStop)
+}
+
+// setSuspended pauses or resumes the process.
+func (p *serverProcess) setSuspended(suspend bool) error {
+	if suspend {
+		return p.request(processSuspend)
+	}
+	return p.request(processResume)
+}
+
+// stopAndWait stops the process and waits up to timeout for its
+// supervisor to finish, for exits where nothing else will.
+func (p *serverProcess) stopAndWait(timeout time.Duration) {
+	if p == nil {
+		return
+	}
+	p.stop()
+	select {
+	case <-p.done:
+	case <-time.After(timeout):
+	}
+}
@@ -0,0 +1,155 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// launchShell launches script under sh as a server would be, on a port
+// nothing listens on.
+func launchShell(t *testing.T, script string) startedWithStateMsg {
+	t.Helper()
+	msg := launchServer(launchSpec{
+		item: modelItem{name: "test-model"},
+		port: "1",
+		bin:  "sh",
+		argv: []string{"sh", "-c", script},
+	})
+	started, ok := msg.(startedWithStateMsg)
+	if !ok {
+		t.Fatalf("launch returned %#v", msg)
+	}
+	t.Cleanup(func() { started.process.stopAndWait(stopGrace + time.Second) })
+	return started
+}
+
+// waitExit returns the process's exit error, failing after timeout.
+func waitExit(t *testing.T, s startedWithStateMsg, timeout time.Duration) error {
+	t.Helper()
+	select {
+	case err := <-s.exitChan:
+		return err
+	case <-time.After(timeout):
+		t.Fatalf("no exit after %s", timeout)
+		return nil
+	}
+}
+
+// waitDone fails unless the supervisor finishes within timeout.
+func waitDone(t *testing.T, p *serverProcess, timeout time.Duration) {
+	t.Helper()
+	select {
+	case <-p.done:
+	case <-time.After(timeout):
+		t.Fatalf("supervisor still running after %s", timeout)
+	}
+}
+
+func TestSupervisorStartReportsOutputAndExit(t *testing.T) {
+	t.Parallel()
+	s := launchShell(t, "echo hello; exit 3")
+	var lines []string
+	for line := range s.logChan {
+		lines = append(lines, line)
+	}
+	if !strings.Contains(strings.Join(lines, "\n"), "hello") {
+		t.Errorf("logs %q don't include the server's output", lines)
+	}
+	var exit *exec.ExitError
+	if err := waitExit(t, s, 5*time.Second); !errors.As(err, &exit) || exit.ExitCode() != 3 {
+		t.Errorf("exit error %v, want status 3", err)
+	}
+	if ready := <-s.readyChan; ready {
+		t.Error("reported ready though nothing listened")
+	}
+	waitDone(t, s.process, time.Second)
+	if _, open := <-s.exitChan; open {
+		t.Error("exit channel left open")
+	}
+	if err := s.process.setSuspended(true); !errors.Is(err, errProcessExited) {
+		t.Errorf("suspending an exited server: %v, want errProcessExited", err)
+	}
+}
+
+func TestSupervisorStopEndsProcessGroup(t *testing.T) {
+	t.Parallel()
+	// The shell ignores SIGINT and SIGTERM, so only the kill ends it
+	s := launchShell(t, `trap "" INT TERM; sleep 30 & wait; sleep 30`)
+	time.Sleep(100 * time.Millisecond)
+	began := time.Now()
+	s.process.stop()
+	if s.process.ctx.Err() == nil {
+		t.Error("context still live after stop")
+	}
+	waitExit(t, s, stopGrace+2*time.Second)
+	if waited := time.Since(began); waited < stopGrace {
+		t.Errorf("exited after %s, before the %s grace period", waited, stopGrace)
+	}
+	waitDone(t, s.process, time.Second)
+	if err := s.process.setSuspended(false); !errors.Is(err, errProcessExited) {
+		t.Errorf("resuming a stopped server: %v, want errProcessExited", err)
+	}
+	// Stopping again is harmless
+	s.process.stop()
+}
+
+func TestSupervisorStopsSuspendedProcess(t *testing.T) {
+	t.Parallel()
+	s := launchShell(t, "sleep 30")
+	if err := s.process.setSuspended(true); err != nil {
+		t.Fatalf("suspend: %v", err)
+	}
+	s.process.stop()
+	waitExit(t, s, stopGrace+2*time.Second)
+	waitDone(t, s.process, stopGrace+2*time.Second)
+}
+
+func TestSupervisorRestartWithConcurrentRequests(t *testing.T) {
+	t.Parallel()
+	first := launchShell(t, "sleep 30")
+	// Requests from several goroutines while the process is stopped, as
+	// the UI, the power watcher, and crash handling may race to do
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			switch i % 3 {
+			case 0:
+				_ = first.process.setSuspended(true)
+			case 1:
+				_ = first.process.setSuspended(false)
+			default:
+				first.process.stop()
+			}
+		}()
+	}
+	first.process.stopAndWait(stopGrace + 2*time.Second)
+	wg.Wait()
+	waitDone(t, first.process, time.Second)
+	waitExit(t, first, time.Second)
+
+	// The relaunch is supervised on its own; the old one's stop doesn't
+	// reach it
+	second := launchShell(t, "sleep 30")
+	if second.process.pid == first.process.pid {
+		t.Fatal("relaunch reused the stopped process")
+	}
+	if second.process.ctx.Err() != nil {
+		t.Fatal("relaunched server's context already ended")
+	}
+	if err := second.process.setSuspended(true); err != nil {
+		t.Errorf("suspending the relaunched server: %v", err)
+	}
+	if err := second.process.setSuspended(false); err != nil {
+		t.Errorf("resuming the relaunched server: %v", err)
+	}
+	second.process.stop()
+	waitExit(t, second, stopGrace+2*time.Second)
+}
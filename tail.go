@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	tailPollInterval = 250 * time.Millisecond
+	// tailBacklogBytes of existing content are shown when tailing starts
+	tailBacklogBytes = 16 * 1024
+)
+
+// tailLine is one line from a tailed file; err is set on the final event
+// when tailing fails. caughtUp is set on an event without text once the
+// content the file had when tailing began has been sent.
+type tailLine struct {
+	text     string
+	err      error
+	caughtUp bool
+}
+
+// tailFile streams lines appended to path until ctx is cancelled, starting
+// with the end of the existing content. A truncated or replaced file
+// (log rotation) is followed from its beginning.
+func tailFile(ctx context.Context, path string, out chan<- tailLine) {
+	defer close(out)
+	send := func(l tailLine) bool {
+		select {
+		case out <- l:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		send(tailLine{err: err})
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	var offset int64
+	skipPartial := false
+	if info, err := f.Stat(); err == nil && info.Size() > tailBacklogBytes {
+		offset = info.Size() - tailBacklogBytes
+		skipPartial = true
+	}
+	var pending []byte
+	buf := make([]byte, 64*1024)
+	caughtUp := false
+	for {
+		n, readErr := f.ReadAt(buf, offset)
+		if n > 0 {
+			offset += int64(n)
+			pending = append(pending, buf[:n]...)
+			for {
+				i := bytes.IndexByte(pending, '\n')
+				if i < 0 {
+					break
+				}
+				line := string(bytes.TrimRight(pending[:i], "\r"))
+				pending = pending[i+1:]
+				if skipPartial {
+					// The backlog starts mid-line
+					skipPartial = false
+					continue
+				}
+				if !send(tailLine{text: line}) {
+					return
+				}
+			}
+			if len(pending) > maxLogLineBytes {
+				if !send(tailLine{text: string(pending[:runeBoundary(pending, maxLogLineBytes)]) + " [...]"}) {
+					return
+				}
+				pending = pending[:0]
+			}
+		}
+		if readErr != nil && readErr != io.EOF {
+			send(tailLine{err: readErr})
+			return
+		}
+		if n == len(buf) {
+			continue
+		}
+		if !caughtUp {
+			caughtUp = true
+			if !send(tailLine{caughtUp: true}) {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(tailPollInterval):
+		}
+
+		// Follow rotation: the path now names a different or shorter file
+		pathInfo, statErr := os.Stat(path)
+		fileInfo, fstatErr := f.Stat()
+		if statErr != nil || fstatErr != nil {
+			continue
+		}
+		if !os.SameFile(pathInfo, fileInfo) {
+			if nf, err := os.Open(path); err == nil {
+				_ = f.Close()
+				f, offset, pending = nf, 0, pending[:0]
+			}
+		} else if fileInfo.Size() < offset {
+			offset, pending = 0, pending[:0]
+		}
+	}
+}
+
+// startTailCmd begins tailing path into ch.
+func startTailCmd(ctx context.Context, path string, ch chan tailLine) tea.Cmd {
+	return func() tea.Msg {
+		go tailFile(ctx, path, ch)
+		return nil
+	}
+}
+
+func waitForTailLine(ch chan tailLine) tea.Cmd {
+	if ch == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		line, ok := <-ch
+		return tailLineMsg{ch: ch, line: line, done: !ok}
+	}
+}
+
+func validateTailPath(path string) error {
+	if path == "" {
+		return errors.New("enter a file path")
+	}
+	home, _ := userHomeDir()
+	path, err := expandHome(home, path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return errors.New("is a directory")
+	}
+	return nil
+}
@@ -0,0 +1,487 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// templateSampleFile is the sandbox's sample conversation in the config
+// directory: an array of chat messages, or a request body with "messages"
+// and any extra fields (tools, chat_template_kwargs, ...).
+const templateSampleFile = "template-sample.json"
+
+// templateRenderTimeout bounds loading a throwaway server and rendering.
+const templateRenderTimeout = 3 * time.Minute
+
+const defaultTemplateSample = `[
+  {"role": "system", "content": "You are a helpful assistant."},
+  {"role": "user", "content": "What is the capital of France?"},
+  {"role": "assistant", "content": "Paris."},
+  {"role": "user", "content": "And of Italy?"}
+]
+`
+
+// templateFlags are the launch options that change how a conversation is
+// rendered; a throwaway render server gets only these.
+var templateFlags = map[string]bool{
+	"--jinja":                true,
+	"--no-jinja":             true,
+	"--chat-template":        true,
+	"--chat-template-file":   true,
+	"--chat-template-kwargs": true,
+	"--override-kv":          true,
+	"--reasoning-format":     true,
+	"--reasoning-budget":     true,
+}
+
+// specialTokenPattern spots the control tokens of common chat formats so
+// they stand out in the rendered prompt.
+var specialTokenPattern = regexp.MustCompile(`<[|｜][^<>\s]{1,40}?[|｜]>|</?s>|\[/?INST\]|<(?:start|end)_of_turn>|<bos>|<eos>`)
+
+type templateToken struct {
+	id    int
+	piece string
+}
+
+// templateSandbox renders a sample conversation through a model's chat
+// template, as the server would, to debug templates before serving.
+type templateSandbox struct {
+	item         modelItem
+	templateFile string
+	pathInput    textinput.Model
+	editingPath  bool
+	rendering    bool
+	cancel       context.CancelFunc
+	// via says what rendered the prompt
+	via        string
+	prompt     string
+	tokens     []templateToken
+	err        error
+	showTokens bool
+	offset     int
+}
+
+func templateSamplePath() string {
+	configDir := appConfigDir()
+	if configDir == "" {
+		return ""
+	}
+	return filepath.Join(configDir, templateSampleFile)
+}
+
+// loadTemplateSample reads the sample conversation as a request body,
+// writing the default sample on first use.
+func loadTemplateSample() ([]byte, error) {
+	path := templateSamplePath()
+	if path == "" {
+		return nil, errors.New("no config directory available")
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, err
+		}
+		data = []byte(defaultTemplateSample)
+		err = os.WriteFile(path, data, 0o644)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var body map[string]json.RawMessage
+	var messages []json.RawMessage
+	switch {
+	case json.Unmarshal(data, &messages) == nil:
+		return json.Marshal(map[string]any{"messages": messages})
+	case json.Unmarshal(data, &body) == nil && body["messages"] != nil:
+		return data, nil
+	}
+	return nil, fmt.Errorf("%s: expected an array of messages or an object with \"messages\"", path)
+}
+
+// templateArgs keeps the launch options that affect rendering.
+func templateArgs(launchArgs []string, templateFile string) []string {
+	var args []string
+	for _, f := range parseFlagArgs(launchArgs) {
+		if !templateFlags[f.name] || (templateFile != "" && strings.HasPrefix(f.name, "--chat-template") && f.name != "--chat-template-kwargs") {
+			continue
+		}
+		args = append(args, f.name)
+		if f.value != "" {
+			args = append(args, f.value)
+		}
+	}
+	if templateFile != "" {
+		args = append(args, "--chat-template-file", templateFile)
+	}
+	return args
+}
+
+// renderTemplateCmd renders the sample through the server on port, or
+// through a throwaway CPU-only server when port is "". runs is done once
+// any throwaway server has exited.
+func renderTemplateCmd(ctx context.Context, runs *sync.WaitGroup, item modelItem, port string, args []string) tea.Cmd {
+	return func() tea.Msg {
+		defer runs.Done()
+		msg := templateRenderedMsg{model: item.name, via: "the running server on port " + port}
+		body, err := loadTemplateSample()
+		if err != nil {
+			msg.err = err
+			return msg
+		}
+		if port == "" {
+			var stop func()
+			port, stop, err = startTemplateServer(ctx, item, args)
+			if err != nil {
+				msg.err = err
+				return msg
+			}
+			defer stop()
+			msg.via = "a throwaway CPU-only llama-server"
+		}
+		base := "http://127.0.0.1:" + port
+		var rendered struct {
+			Prompt string `json:"prompt"`
+		}
+		if err := postTemplateJSON(ctx, base+"/apply-template", body, &rendered); err != nil {
+			msg.err = err
+			return msg
+		}
+		msg.prompt = rendered.Prompt
+		req, _ := json.Marshal(map[string]any{"content": rendered.Prompt, "add_special": true, "parse_special": true, "with_pieces": true})
+		var tokenized struct {
+			Tokens []struct {
+				ID    int             `json:"id"`
+				Piece json.RawMessage `json:"piece"`
+			} `json:"tokens"`
+		}
+		if err := postTemplateJSON(ctx, base+"/tokenize", req, &tokenized); err != nil {
+			msg.err = err
+			return msg
+		}
+		for _, t := range tokenized.Tokens {
+			var piece string
+			if json.Unmarshal(t.Piece, &piece) != nil {
+				// Pieces that aren't valid UTF-8 come back as byte arrays
+				piece = "<bytes " + string(t.Piece) + ">"
+			}
+			msg.tokens = append(msg.tokens, templateToken{id: t.ID, piece: piece})
+		}
+		return msg
+	}
+}
+
+func postTemplateJSON(ctx context.Context, url string, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(data, &e) == nil && e.Error.Message != "" {
+			return fmt.Errorf("%s: %s", filepath.Base(url), e.Error.Message)
+		}
+		return fmt.Errorf("%s: %s", filepath.Base(url), resp.Status)
+	}
+	return json.Unmarshal(data, out)
+}
+
+// startTemplateServer loads item in a llama-server that only renders: no
+// GPU offload, a small context, no warm-up. stop ends it.
+func startTemplateServer(ctx context.Context, item modelItem, args []string) (string, func(), error) {
+	bin, err := getLlamaServerBinary()
+	if err != nil {
+		return "", nil, err
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, err
+	}
+	port := strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+	l.Close()
+	argv := append([]string{bin, "-m", item.path, "--host", "127.0.0.1", "--port", port,
+		"--jinja", "-ngl", "0", "-c", "1024", "-np", "1", "--no-warmup"}, args...)
+	argv = withHFRepo(argv, item)
+	ctx, cancel := context.WithCancel(ctx)
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return signalProcessGroup(cmd, syscall.SIGTERM)
+	}
+	var output bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &output, &output
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return "", nil, err
+	}
+	exited := make(chan error, 1)
+	server := superviseProcess(ctx, cancel, cmd, exited)
+	stop := func() {
+		server.stop()
+		<-exited
+	}
+	if err := waitForHealthy(server.ctx, "http://127.0.0.1:"+port); err != nil {
+		stop()
+		lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+		if last := lines[len(lines)-1]; ctx.Err() == nil && last != "" {
+			return "", nil, fmt.Errorf("llama-server exited: %s", last)
+		}
+		return "", nil, err
+	}
+	return port, stop, nil
+}
+
+// templateEditor is the command line of the user's editor.
+func templateEditor() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(env)); len(fields) > 0 {
+			return fields
+		}
+	}
+	return []string{"vi"}
+}
+
+// editTemplateSampleCmd suspends the TUI to edit the sample conversation.
+func editTemplateSampleCmd() tea.Cmd {
+	if _, err := loadTemplateSample(); err != nil && templateSamplePath() == "" {
+		return func() tea.Msg { return templateSampleEditedMsg{err: err} }
+	}
+	editor := templateEditor()
+	c := exec.Command(editor[0], append(editor[1:], templateSamplePath())...)
+	return tea.ExecProcess(c, func(err error) tea.Msg {
+		return templateSampleEditedMsg{err: err}
+	})
+}
+
+func (m appModel) openTemplateSandbox() (appModel, tea.Cmd) {
+	item, ok := m.modelsList.SelectedItem().(modelItem)
+	switch {
+	case m.observerRefuses("start a template server"):
+		return m, nil
+	case !ok:
+		m.statusLineText = "No model selected"
+		return m, nil
+	case item.remoteURL != "":
+		m.statusLineText = "Download " + item.name + " first; the template comes from the model file"
+		return m, nil
+	case item.kind == kindWhisper:
+		m.statusLineText = item.name + " is a whisper model; it has no chat template"
+		return m, nil
+	}
+	in := textinput.New()
+	in.Prompt = "Template file: "
+	in.Placeholder = "path to a .jinja file (empty: the model's own template)"
+	m.templateSandbox = &templateSandbox{item: item, pathInput: in}
+	return m.renderTemplate()
+}
+
+// renderTemplate starts rendering the sample, through the running server
+// when it serves the model with its own template.
+func (m appModel) renderTemplate() (appModel, tea.Cmd) {
+	v := *m.templateSandbox
+	if v.cancel != nil {
+		v.cancel()
+	}
+	port := ""
+	if v.templateFile == "" && m.server.running() && m.currentModelName == v.item.name {
+		port = m.currentPort
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), templateRenderTimeout)
+	v.cancel, v.rendering, v.err = cancel, true, nil
+	m.templateSandbox = &v
+	args := templateArgs(withKVOverrides(v.item.name, m.argsFor(v.item.name)), v.templateFile)
+	m.templateRuns.Add(1)
+	return m, renderTemplateCmd(ctx, m.templateRuns, v.item, port, args)
+}
+
+// stopTemplateRender cancels the sandbox's render, stopping its throwaway
+// server.
+func (m appModel) stopTemplateRender() {
+	if m.templateSandbox != nil && m.templateSandbox.cancel != nil {
+		m.templateSandbox.cancel()
+	}
+}
+
+// waitForTemplateServers waits, up to the grace a server gets to stop, for
+// cancelled renders to stop their throwaway servers, for exits where
+// nothing else will.
+func (m appModel) waitForTemplateServers() {
+	done := make(chan struct{})
+	go func() {
+		m.templateRuns.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(stopGrace + time.Second):
+	}
+}
+
+func (m appModel) handleTemplateSandboxKey(msg tea.KeyMsg) (appModel, tea.Cmd) {
+	v := *m.templateSandbox
+	if v.editingPath {
+		switch msg.String() {
+		case "enter":
+			path, err := expandHome(m.homeDir, strings.TrimSpace(v.pathInput.Value()))
+			if err != nil {
+				v.err = err
+				m.templateSandbox = &v
+				return m, nil
+			}
+			if path != "" {
+				if _, err := os.Stat(path); err != nil {
+					v.err = err
+					m.templateSandbox = &v
+					return m, nil
+				}
+			}
+			v.templateFile, v.editingPath = path, false
+			v.pathInput.Blur()
+			m.templateSandbox = &v
+			return m.renderTemplate()
+		case "esc":
+			v.editingPath = false
+			v.pathInput.Blur()
+			m.templateSandbox = &v
+			return m, nil
+		}
+		var cmd tea.Cmd
+		v.pathInput, cmd = v.pathInput.Update(msg)
+		m.templateSandbox = &v
+		return m, cmd
+	}
+	height := m.templateSandboxHeight()
+	switch msg.String() {
+	case "up", "k":
+		v.offset = max(v.offset-1, 0)
+	case "down", "j":
+		v.offset++
+	case "pgup":
+		v.offset = max(v.offset-height, 0)
+	case "pgdown", " ":
+		v.offset += height
+	case "tab":
+		v.showTokens = !v.showTokens
+		v.offset = 0
+	case "e":
+		m.templateSandbox = &v
+		return m, editTemplateSampleCmd()
+	case "t":
+		v.editingPath = true
+		v.pathInput.SetValue(v.templateFile)
+		v.pathInput.CursorEnd()
+		m.templateSandbox = &v
+		return m, v.pathInput.Focus()
+	case "r":
+		m.templateSandbox = &v
+		return m.renderTemplate()
+	case "esc", "q", "Y":
+		if v.cancel != nil {
+			v.cancel()
+		}
+		m.templateSandbox = nil
+		return m, nil
+	}
+	v.offset = min(v.offset, max(len(v.rows())-height, 0))
+	m.templateSandbox = &v
+	return m, nil
+}
+
+// templateSandboxHeight is how many rows of output fit the overlay.
+func (m appModel) templateSandboxHeight() int {
+	return max(m.height-14, 5)
+}
+
+// rows are the lines of the prompt, or one token per line.
+func (v templateSandbox) rows() []string {
+	if v.showTokens {
+		rows := make([]string, len(v.tokens))
+		for i, t := range v.tokens {
+			rows[i] = fmt.Sprintf("%5d  %7d  %s", i, t.id, strconv.Quote(t.piece))
+		}
+		return rows
+	}
+	if v.prompt == "" {
+		return nil
+	}
+	return strings.Split(v.prompt, "\n")
+}
+
+func (m appModel) renderTemplateSandbox(width int) string {
+	v := m.templateSandbox
+	var b strings.Builder
+	source := "the model's chat template"
+	if v.templateFile != "" {
+		source = v.templateFile
+	}
+	b.WriteString(m.styles.help.Render(ellipsize("Sample: "+templateSamplePath(), width)) + "\n")
+	b.WriteString(m.styles.help.Render(ellipsize("Template: "+source, width)) + "\n")
+	if v.editingPath {
+		b.WriteString(v.pathInput.View() + "\n")
+	}
+	b.WriteString("\n")
+	footer := m.styles.help.Render("[tab] prompt/tokens  [e] edit sample  [t] template file  [r] render again  [j/k] scroll  [esc] close")
+	switch {
+	case v.rendering:
+		return b.String() + m.styles.status.Render("Rendering...") + "\n\n" + footer
+	case v.err != nil:
+		return b.String() + m.styles.logError.Render(ellipsize(v.err.Error(), width)) + "\n\n" + footer
+	}
+	rows := v.rows()
+	height := m.templateSandboxHeight()
+	offset := min(v.offset, max(len(rows)-height, 0))
+	shown := rows[offset:min(offset+height, len(rows))]
+	title := fmt.Sprintf("Prompt (%s, %s) via %s", pluralize(len(v.prompt), "byte"), pluralize(len(v.tokens), "token"), v.via)
+	if v.showTokens {
+		title = fmt.Sprintf("Tokens (%d) - index, id, piece", len(v.tokens))
+	}
+	if len(rows) > height {
+		title += fmt.Sprintf("  (%d-%d of %d)", offset+1, offset+len(shown), len(rows))
+	}
+	b.WriteString(m.styles.accent.Render(ellipsize(title, width)) + "\n")
+	for i, row := range shown {
+		if v.showTokens {
+			b.WriteString(ellipsize(row, width) + "\n")
+			continue
+		}
+		// Line ends are marked so trailing spaces and blank lines show
+		line := ellipsize(row, width-1)
+		line = specialTokenPattern.ReplaceAllStringFunc(line, func(s string) string { return m.styles.accent.Render(s) })
+		if offset+i < len(rows)-1 {
+			line += m.styles.disabled.Render("↵")
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String() + "\n" + footer
+}
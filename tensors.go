@@ -0,0 +1,449 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ggmlType is a tensor data type: its name and how many bytes a block of
+// elements takes.
+type ggmlType struct {
+	name       string
+	blockSize  uint64
+	blockBytes uint64
+}
+
+// ggmlTypes are ggml's tensor types by id.
+var ggmlTypes = map[uint32]ggmlType{
+	0: {"F32", 1, 4}, 1: {"F16", 1, 2}, 2: {"Q4_0", 32, 18}, 3: {"Q4_1", 32, 20},
+	6: {"Q5_0", 32, 22}, 7: {"Q5_1", 32, 24}, 8: {"Q8_0", 32, 34}, 9: {"Q8_1", 32, 36},
+	10: {"Q2_K", 256, 84}, 11: {"Q3_K", 256, 110}, 12: {"Q4_K", 256, 144}, 13: {"Q5_K", 256, 176},
+	14: {"Q6_K", 256, 210}, 15: {"Q8_K", 256, 292}, 16: {"IQ2_XXS", 256, 66}, 17: {"IQ2_XS", 256, 74},
+	18: {"IQ3_XXS", 256, 98}, 19: {"IQ1_S", 256, 50}, 20: {"IQ4_NL", 32, 18}, 21: {"IQ3_S", 256, 110},
+	22: {"IQ2_S", 256, 82}, 23: {"IQ4_XS", 256, 136}, 24: {"I8", 1, 1}, 25: {"I16", 1, 2},
+	26: {"I32", 1, 4}, 27: {"I64", 1, 8}, 28: {"F64", 1, 8}, 29: {"IQ1_M", 256, 56},
+	30: {"BF16", 1, 2}, 34: {"TQ1_0", 256, 54}, 35: {"TQ2_0", 256, 66}, 39: {"MXFP4", 32, 17},
+}
+
+// ggufTensor is one tensor's entry in a GGUF file's tensor index.
+type ggufTensor struct {
+	name   string
+	shape  []uint64
+	typ    uint32
+	offset uint64
+	// bytes is the tensor's data size
+	bytes uint64
+}
+
+func (t ggufTensor) elements() uint64 {
+	n := uint64(1)
+	for _, d := range t.shape {
+		n *= d
+	}
+	return n
+}
+
+func (t ggufTensor) typeName() string {
+	if gt, ok := ggmlTypes[t.typ]; ok {
+		return gt.name
+	}
+	return "type " + strconv.FormatUint(uint64(t.typ), 10)
+}
+
+func (t ggufTensor) shapeText() string {
+	dims := make([]string, len(t.shape))
+	for i, d := range t.shape {
+		dims[i] = strconv.FormatUint(d, 10)
+	}
+	return strings.Join(dims, "×")
+}
+
+// tensorBlock splits "blk.12.attn_q.weight" into its layer and kind.
+var tensorBlock = regexp.MustCompile(`^blk\.(\d+)\.(.+)$`)
+
+// layer is the block a tensor belongs to, or -1 for the embeddings,
+// output, and other tensors outside the blocks.
+func (t ggufTensor) layer() int {
+	if match := tensorBlock.FindStringSubmatch(t.name); match != nil {
+		n, _ := strconv.Atoi(match[1])
+		return n
+	}
+	return -1
+}
+
+// kind is the tensor's name without its block, e.g. "attn_q.weight".
+func (t ggufTensor) kind() string {
+	if match := tensorBlock.FindStringSubmatch(t.name); match != nil {
+		return match[2]
+	}
+	return t.name
+}
+
+// countingReader tracks how far into the file the header reads reach, to
+// find where tensor data starts.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// readGGUFTensors reads the tensor index of a GGUF file, after its
+// metadata, without touching tensor data.
+func readGGUFTensors(path string) ([]ggufTensor, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	cr := &countingReader{r: bufio.NewReaderSize(f, 64*1024)}
+	meta, err := parseGGUFMetadata(cr)
+	if err != nil {
+		return nil, err
+	}
+	gr := ggufReader{r: cr}
+	tensors := make([]ggufTensor, 0, min(meta.tensorCount, 1<<16))
+	for i := uint64(0); i < meta.tensorCount && gr.err == nil; i++ {
+		t := ggufTensor{name: gr.str()}
+		dims := gr.u32()
+		if dims > 8 {
+			return nil, fmt.Errorf("tensor %s: %d dimensions", t.name, dims)
+		}
+		for d := uint32(0); d < dims; d++ {
+			t.shape = append(t.shape, gr.u64())
+		}
+		t.typ = gr.u32()
+		t.offset = gr.u64()
+		tensors = append(tensors, t)
+	}
+	if gr.err != nil {
+		return nil, fmt.Errorf("read tensor index: %w", gr.err)
+	}
+	// Unknown types are sized by the gap to the next tensor's data
+	alignment, ok := meta.uint("general.alignment")
+	if !ok || alignment == 0 {
+		alignment = 32
+	}
+	dataStart := (uint64(cr.n) + alignment - 1) / alignment * alignment
+	byOffset := make([]int, len(tensors))
+	for i := range byOffset {
+		byOffset[i] = i
+	}
+	sort.Slice(byOffset, func(a, b int) bool { return tensors[byOffset[a]].offset < tensors[byOffset[b]].offset })
+	for n, i := range byOffset {
+		t := &tensors[i]
+		if gt, ok := ggmlTypes[t.typ]; ok {
+			t.bytes = t.elements() / gt.blockSize * gt.blockBytes
+			continue
+		}
+		end := uint64(info.Size()) - dataStart
+		if n+1 < len(byOffset) {
+			end = tensors[byOffset[n+1]].offset
+		}
+		if end > t.offset {
+			t.bytes = end - t.offset
+		}
+	}
+	return tensors, nil
+}
+
+// loadTensorsCmd reads the tensor index of every part of a model.
+func loadTensorsCmd(path string) tea.Cmd {
+	return func() tea.Msg {
+		var all []ggufTensor
+		for _, part := range shardPaths(path) {
+			tensors, err := readGGUFTensors(part)
+			if err != nil {
+				return tensorsLoadedMsg{path: path, err: err}
+			}
+			all = append(all, tensors...)
+		}
+		return tensorsLoadedMsg{path: path, tensors: all}
+	}
+}
+
+// tensorGroup sums the tensors of one kind or one layer.
+type tensorGroup struct {
+	name     string
+	count    int
+	elements uint64
+	bytes    uint64
+	// types counts tensors per data type
+	types map[string]int
+}
+
+func (g *tensorGroup) add(t ggufTensor) {
+	g.count++
+	g.elements += t.elements()
+	g.bytes += t.bytes
+	if g.types == nil {
+		g.types = map[string]int{}
+	}
+	g.types[t.typeName()]++
+}
+
+// typesText lists the group's data types, most used first, e.g.
+// "Q4_K×24 Q6_K×4".
+func (g tensorGroup) typesText() string {
+	names := make([]string, 0, len(g.types))
+	for name := range g.types {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(a, b int) bool {
+		if g.types[names[a]] != g.types[names[b]] {
+			return g.types[names[a]] > g.types[names[b]]
+		}
+		return names[a] < names[b]
+	})
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name
+		if len(names) > 1 || g.count > 1 {
+			parts[i] += "×" + strconv.Itoa(g.types[name])
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// tensorsByKind groups tensors by kind, largest first.
+func tensorsByKind(tensors []ggufTensor) []tensorGroup {
+	index := map[string]int{}
+	var groups []tensorGroup
+	for _, t := range tensors {
+		i, ok := index[t.kind()]
+		if !ok {
+			i = len(groups)
+			index[t.kind()] = i
+			groups = append(groups, tensorGroup{name: t.kind()})
+		}
+		groups[i].add(t)
+	}
+	sort.SliceStable(groups, func(a, b int) bool { return groups[a].bytes > groups[b].bytes })
+	return groups
+}
+
+// tensorsByLayer groups tensors by block in order, the tensors outside
+// the blocks first.
+func tensorsByLayer(tensors []ggufTensor) []tensorGroup {
+	byLayer := map[int]*tensorGroup{}
+	var layers []int
+	for _, t := range tensors {
+		l := t.layer()
+		g, ok := byLayer[l]
+		if !ok {
+			name := "blk." + strconv.Itoa(l)
+			if l < 0 {
+				name = "outside blocks"
+			}
+			g = &tensorGroup{name: name}
+			byLayer[l] = g
+			layers = append(layers, l)
+		}
+		g.add(t)
+	}
+	sort.Ints(layers)
+	groups := make([]tensorGroup, len(layers))
+	for i, l := range layers {
+		groups[i] = *byLayer[l]
+	}
+	return groups
+}
+
+// Tensor browser tabs.
+const (
+	tensorTabAll = iota
+	tensorTabKinds
+	tensorTabLayers
+	tensorTabCount
+)
+
+var tensorTabNames = []string{"tensors", "by kind", "by layer"}
+
+// tensorView is the [ctrl+t] browser of a model's tensors.
+type tensorView struct {
+	model   string
+	path    string
+	loading bool
+	err     error
+	tensors []ggufTensor
+	tab     int
+	offset  int
+	// bySize lists tensors largest first instead of in file order
+	bySize bool
+}
+
+// openTensorView starts reading the selected model's tensor index.
+func (m appModel) openTensorView() (appModel, tea.Cmd) {
+	item, ok := m.modelsList.SelectedItem().(modelItem)
+	if !ok {
+		m.statusLineText = "No model selected"
+		return m, nil
+	}
+	path := item.path
+	if item.hfRepo != "" {
+		path = item.hfCache
+	}
+	if item.remoteURL != "" || path == "" {
+		m.statusLineText = "Download " + item.name + " first to list its tensors"
+		return m, nil
+	}
+	m.tensorView = &tensorView{model: item.name, path: path, loading: true}
+	return m, loadTensorsCmd(path)
+}
+
+func (m appModel) tensorsHeight() int {
+	return max(m.height-12, 5)
+}
+
+// rows is the number of rows the current tab lists.
+func (v tensorView) rows() int {
+	switch v.tab {
+	case tensorTabKinds:
+		return len(tensorsByKind(v.tensors))
+	case tensorTabLayers:
+		return len(tensorsByLayer(v.tensors))
+	}
+	return len(v.tensors)
+}
+
+func (m appModel) handleTensorKey(keyStr string) (appModel, tea.Cmd) {
+	v := *m.tensorView
+	page := m.tensorsHeight()
+	last := max(v.rows()-page, 0)
+	switch keyStr {
+	case "up", "k":
+		v.offset--
+	case "down", "j":
+		v.offset++
+	case "pgup":
+		v.offset -= page
+	case "pgdown", " ":
+		v.offset += page
+	case "home", "g":
+		v.offset = 0
+	case "end", "G":
+		v.offset = last
+	case "tab":
+		v.tab = (v.tab + 1) % tensorTabCount
+		v.offset = 0
+	case "shift+tab":
+		v.tab = (v.tab + tensorTabCount - 1) % tensorTabCount
+		v.offset = 0
+	case "s":
+		v.bySize = !v.bySize
+		v.offset = 0
+	case "esc", "ctrl+t", "q":
+		m.tensorView = nil
+		return m, nil
+	}
+	v.offset = min(max(v.offset, 0), last)
+	m.tensorView = &v
+	return m, nil
+}
+
+// renderTensorView draws the current tab as a table under the model's
+// totals.
+func (m appModel) renderTensorView(width int) string {
+	v := m.tensorView
+	switch {
+	case v.loading:
+		return m.styles.help.Render("Reading the tensor index of " + v.path + "...")
+	case v.err != nil:
+		return m.styles.logError.Render("Could not read tensors: "+v.err.Error()) + "\n\n" + m.styles.help.Render("[esc] close")
+	}
+	var total tensorGroup
+	for _, t := range v.tensors {
+		total.add(t)
+	}
+	tabs := make([]string, len(tensorTabNames))
+	for i, name := range tensorTabNames {
+		if i == v.tab {
+			tabs[i] = m.styles.accent.Render("[" + name + "]")
+		} else {
+			tabs[i] = m.styles.help.Render(" " + name + " ")
+		}
+	}
+	lines := []string{
+		fmt.Sprintf("%s · %s elements · %s · %s", pluralize(total.count, "tensor"), formatElements(total.elements), formatBytes(total.bytes), total.typesText()),
+		strings.Join(tabs, " "),
+		"",
+	}
+	var header string
+	var rows []string
+	switch v.tab {
+	case tensorTabAll:
+		nameW := max(width-39, 16)
+		header = fmt.Sprintf("%-*s %-18s %-8s %10s", nameW, "name", "shape", "type", "size")
+		tensors := v.tensors
+		if v.bySize {
+			tensors = append([]ggufTensor(nil), tensors...)
+			sort.SliceStable(tensors, func(a, b int) bool { return tensors[a].bytes > tensors[b].bytes })
+		}
+		for _, t := range tensors {
+			rows = append(rows, fmt.Sprintf("%-*s %-18s %-8s %10s", nameW, ellipsize(t.name, nameW), ellipsize(t.shapeText(), 18), t.typeName(), formatBytes(t.bytes)))
+		}
+	default:
+		groups := tensorsByKind(v.tensors)
+		label := "kind"
+		if v.tab == tensorTabLayers {
+			groups, label = tensorsByLayer(v.tensors), "layer"
+		}
+		nameW := min(max(width/3, 16), 28)
+		typesW := max(width-nameW-38, 8)
+		header = fmt.Sprintf("%-*s %7s %10s %10s %6s  %s", nameW, label, "tensors", "elements", "size", "share", "types")
+		for _, g := range groups {
+			share := 0.0
+			if total.bytes > 0 {
+				share = float64(g.bytes) * 100 / float64(total.bytes)
+			}
+			rows = append(rows, fmt.Sprintf("%-*s %7d %10s %10s %5.1f%%  %s", nameW, ellipsize(g.name, nameW), g.count, formatElements(g.elements), formatBytes(g.bytes), share, ellipsize(g.typesText(), typesW)))
+		}
+	}
+	lines = append(lines, m.styles.sectionTitle.Render(ellipsize(header, width)))
+	height := m.tensorsHeight()
+	end := min(v.offset+height, len(rows))
+	for _, row := range rows[v.offset:end] {
+		lines = append(lines, ellipsize(row, width))
+	}
+	for i := end - v.offset; i < height; i++ {
+		lines = append(lines, "")
+	}
+	order := ""
+	if v.tab == tensorTabAll {
+		order = "[s] largest first  "
+		if v.bySize {
+			order = "[s] file order  "
+		}
+	}
+	footer := fmt.Sprintf("[tab] switch view  [↑/↓] scroll  %s[esc] close  ·  %d-%d of %d", order, min(v.offset+1, len(rows)), end, len(rows))
+	return strings.Join(lines, "\n") + "\n\n" + m.styles.help.Render(footer)
+}
+
+// formatElements shortens an element count, e.g. 7.24B.
+func formatElements(n uint64) string {
+	switch {
+	case n >= 1e9:
+		return fmt.Sprintf("%.2fB", float64(n)/1e9)
+	case n >= 1e6:
+		return fmt.Sprintf("%.1fM", float64(n)/1e6)
+	case n >= 1e3:
+		return fmt.Sprintf("%.1fK", float64(n)/1e3)
+	}
+	return strconv.FormatUint(n, 10)
+}
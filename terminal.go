@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// terminalStatus is what llama-tui shows outside its own screen: the
+// window title, and a progress indicator in the tab or taskbar on
+// terminals that understand OSC 9;4.
+type terminalStatus struct {
+	title string
+	// progress is the OSC 9;4 state and value, e.g. "1;42" for 42% done,
+	// "3;0" for busy without a known end, "0;0" for none
+	progress string
+}
+
+// progressTerminal reports whether the terminal is known to draw OSC 9;4
+// progress. Others may show the sequence as a notification or text, so
+// it is only sent when recognized or asked for.
+func progressTerminal() bool {
+	if os.Getenv("WT_SESSION") != "" || os.Getenv("ConEmuANSI") == "ON" {
+		return true
+	}
+	switch os.Getenv("TERM_PROGRAM") {
+	case "ghostty", "WezTerm":
+		return true
+	}
+	return false
+}
+
+// terminalProgressEnabled applies the terminal_progress setting.
+func terminalProgressEnabled(setting string) (bool, error) {
+	switch setting {
+	case "", "auto":
+		return progressTerminal(), nil
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("%q: want auto, on, or off", setting)
+}
+
+func (m appModel) terminalStatus() terminalStatus {
+	if m.config.DisableTerminalTitle {
+		return terminalStatus{}
+	}
+	st := terminalStatus{progress: "0;0"}
+	// Inside tmux the pane title is already set with the tmux status
+	if m.tmuxPane == "" {
+		st.title = appTitle + " " + m.tmuxStatus().summary()
+	}
+	switch {
+	case m.download != nil:
+		done, total := m.download.done.Load(), m.download.total.Load()
+		if total > 0 {
+			pct := min(done*100/total, 100)
+			st.progress = fmt.Sprintf("1;%d", pct)
+			if st.title != "" {
+				st.title += fmt.Sprintf(" · ↓ %d%%", pct)
+			}
+		} else {
+			st.progress = "3;0"
+		}
+	case m.server == serverStarting || m.server == serverLoading:
+		st.progress = "3;0"
+	case m.server == serverCrashed:
+		st.progress = "2;100"
+	}
+	if !m.terminalProgress {
+		st.progress = ""
+	}
+	return st
+}
+
+// terminalOutput is the program's output, the terminal. Writes are taken
+// one at a time, and the renderer writes each frame at once, so sequences
+// sent from commands land between frames rather than inside one.
+type terminalOutput struct {
+	*os.File
+	mu sync.Mutex
+}
+
+func (o *terminalOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.File.Write(p)
+}
+
+func (o *terminalOutput) WriteString(s string) (int, error) {
+	return o.Write([]byte(s))
+}
+
+// programOutput is where the program draws and the progress sequences go.
+var programOutput = &terminalOutput{File: os.Stdout}
+
+// osc9Progress is the escape sequence setting progress, wrapped for tmux
+// to pass through to the outer terminal.
+func osc9Progress(progress string) string {
+	seq := "\x1b]9;4;" + progress + "\x07"
+	if os.Getenv("TMUX") != "" {
+		return "\x1bPtmux;\x1b" + seq + "\x1b\\"
+	}
+	return seq
+}
+
+// terminalUpdateCmd shows st in place of prev.
+func terminalUpdateCmd(prev, st terminalStatus) tea.Cmd {
+	var cmds []tea.Cmd
+	if st.title != prev.title && st.title != "" {
+		cmds = append(cmds, tea.SetWindowTitle(st.title))
+	}
+	if st.progress != prev.progress && st.progress != "" {
+		cmds = append(cmds, func() tea.Msg {
+			_, _ = programOutput.WriteString(osc9Progress(st.progress))
+			return nil
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+// clearTerminalStatus removes the progress indicator and the title on
+// exit, so neither outlives llama-tui.
+func clearTerminalStatus(st terminalStatus) {
+	if st.progress != "" {
+		_, _ = programOutput.WriteString(osc9Progress("0;0"))
+	}
+	if st.title != "" {
+		_, _ = programOutput.WriteString("\x1b]2;\x07")
+	}
+}
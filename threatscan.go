@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// scanTimeout bounds one scan_command run; scanners read the whole
+	// file, and models are large.
+	scanTimeout = 30 * time.Minute
+	// quarantineExt is added to a download that failed its scan, which
+	// keeps it out of the models list.
+	quarantineExt = ".quarantined"
+)
+
+// scanResult is scan_command's verdict on a downloaded file, recorded in
+// its provenance.
+type scanResult struct {
+	Command   string    `json:"command"`
+	Passed    bool      `json:"passed"`
+	ScannedAt time.Time `json:"scanned_at"`
+	// Output is the end of what the scanner printed, for failures
+	Output string `json:"output,omitempty"`
+}
+
+// describe is the verdict for reports, e.g. "passed clamscan".
+func (s scanResult) describe() string {
+	name := s.Command
+	if fields := strings.Fields(s.Command); len(fields) > 0 {
+		name = fields[0]
+	}
+	if s.Passed {
+		return "passed " + name
+	}
+	return "failed " + name
+}
+
+// runScanCommand runs command with path as its last argument. Exit status
+// 0 passes the file; anything else, including the scanner not starting
+// or timing out, fails it.
+func runScanCommand(ctx context.Context, command []string, path string) scanResult {
+	ctx, cancel := context.WithTimeout(ctx, scanTimeout)
+	defer cancel()
+	args := append(append([]string(nil), command[1:]...), path)
+	cmd := exec.CommandContext(ctx, command[0], args...)
+	cmd.Env = append(os.Environ(), "LLAMA_TUI_MODEL="+path)
+	out, err := cmd.CombinedOutput()
+	result := scanResult{Command: strings.Join(command, " "), Passed: err == nil, ScannedAt: time.Now().UTC()}
+	if err == nil {
+		return result
+	}
+	detail := strings.TrimSpace(logTail(string(out), 500))
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		err = fmt.Errorf("scan timed out after %s", scanTimeout)
+	case !errors.As(err, &exitErr):
+		err = fmt.Errorf("scanner did not run: %w", err)
+	}
+	result.Output = strings.TrimSpace(err.Error() + "\n" + detail)
+	return result
+}
+
+// scanDownload runs scan_command on a finished download at part before it
+// is moved to dest, so the models list never shows an unscanned file. A
+// file that fails is moved aside to dest plus
+// quarantineExt with its provenance, so the failure is on record, and an
+// error says where it went.
+func scanDownload(ctx context.Context, command []string, part, dest string, prov *modelProvenance) error {
+	if len(command) == 0 {
+		return nil
+	}
+	result := runScanCommand(ctx, command, part)
+	if ctx.Err() != nil {
+		// Cancelled with the download: the file stays a ".part" file
+		return ctx.Err()
+	}
+	prov.Scan = &result
+	if result.Passed {
+		return nil
+	}
+	quarantined := dest + quarantineExt
+	if err := os.Rename(part, quarantined); err != nil {
+		_ = os.Remove(part)
+		return fmt.Errorf("failed its scan and was removed: %s", result.reason())
+	}
+	_ = saveProvenance(quarantined, *prov)
+	return fmt.Errorf("failed its scan, quarantined as %s: %s", quarantined, result.reason())
+}
+
+// reason is why a scan failed in a line: the exit status and the first
+// line the scanner printed, which names the file and finding for clamscan.
+func (s scanResult) reason() string {
+	lines := strings.SplitN(s.Output, "\n", 3)
+	return strings.Join(lines[:min(len(lines), 2)], ": ")
+}
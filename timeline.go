@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// sessionRecord is one server session in the launch history, appended
+// when the server exits.
+type sessionRecord struct {
+	Model   string    `json:"model"`
+	Port    string    `json:"port"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Crashed bool      `json:"crashed,omitempty"`
+	// Preset is the --preset the session was launched from
+	Preset string `json:"preset,omitempty"`
+	// Path, SHA256 (when known), and Args record what was served
+	Path   string   `json:"path,omitempty"`
+	SHA256 string   `json:"sha256,omitempty"`
+	Args   []string `json:"args,omitempty"`
+	// Size and Fingerprint are read from the file as the session ends
+	Size        int64  `json:"size,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Prompt is the library prompt the session was launched with,
+	// PromptUse "warmup" or "system", and PromptSHA256 its text's hash
+	Prompt       string `json:"prompt,omitempty"`
+	PromptUse    string `json:"prompt_use,omitempty"`
+	PromptSHA256 string `json:"prompt_sha256,omitempty"`
+	// Prev and Hash chain the history; see audit.go
+	Prev string `json:"prev,omitempty"`
+	Hash string `json:"hash,omitempty"`
+}
+
+// The history is kept as JSON lines, so recording a session is an append.
+// Lines are never rewritten; see audit.go.
+func sessionsPath(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "sessions.jsonl")
+}
+
+// recordSessionCmd appends a finished session, fingerprinting its model
+// file, and reports a history that couldn't be written.
+func recordSessionCmd(dir string, rec sessionRecord) tea.Cmd {
+	return func() tea.Msg {
+		path := sessionsPath(dir)
+		if path == "" || rec.Start.IsZero() {
+			return nil
+		}
+		if rec.Path != "" {
+			rec.Size, rec.Fingerprint, _ = fingerprintFile(rec.Path)
+		}
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		if err == nil {
+			err = appendSession(path, rec)
+		}
+		return sessionRecordedMsg{model: rec.Model, err: err}
+	}
+}
+
+// endSession records the managed server's session ending now, and adds it
+// to the history in memory for the timeline and error budgets.
+func (m *appModel) endSession(crashed bool) tea.Cmd {
+	rec := sessionRecord{
+		Model:   m.currentModelName,
+		Port:    m.currentPort,
+		Start:   m.serverStartedAt,
+		End:     time.Now(),
+		Crashed: crashed,
+		Preset:  m.launchPreset,
+	}
+	if item, ok := m.findModelByName(m.currentModelName); ok {
+		rec.Path, rec.SHA256 = item.path, modelChecksum(item)
+	}
+	if m.process != nil {
+		rec.Args = m.process.args
+	}
+	if m.servedPrompt.name != "" {
+		rec.Prompt, rec.PromptUse, rec.PromptSHA256 = m.servedPrompt.name, m.servedPrompt.use(), m.servedPrompt.hash()
+	}
+	if !rec.Start.IsZero() {
+		m.sessions = append(append([]sessionRecord(nil), m.sessions...), rec)
+		m.refreshReliability()
+	}
+	return recordSessionCmd(m.historyDir, rec)
+}
+
+// loadSessionsCmd reads the history for the timeline, skipping lines that
+// don't parse (e.g. one cut short by a crash).
+func loadSessionsCmd(dir string) tea.Cmd {
+	return func() tea.Msg {
+		path := sessionsPath(dir)
+		if path == "" {
+			return sessionsLoadedMsg{err: fmt.Errorf("no state directory available")}
+		}
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			return sessionsLoadedMsg{}
+		}
+		if err != nil {
+			return sessionsLoadedMsg{err: err}
+		}
+		defer f.Close()
+		var sessions []sessionRecord
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			var rec sessionRecord
+			if json.Unmarshal(sc.Bytes(), &rec) == nil && !rec.Start.IsZero() {
+				sessions = append(sessions, rec)
+			}
+		}
+		return sessionsLoadedMsg{sessions: sessions, err: sc.Err()}
+	}
+}
+
+// formatSpan renders a duration in its two largest units, e.g. 3h12m.
+func formatSpan(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd%dh", int(d.Hours())/24, int(d.Hours())%24)
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+}
+
+// renderTimelineView draws a Gantt chart of sessions over the past day or
+// week: a row per model with its sessions as bars, crashes marked ✕.
+func (m appModel) renderTimelineView(width int) string {
+	span := 24 * time.Hour
+	spanName := "day"
+	if m.timelineWeek {
+		span, spanName = 7*24*time.Hour, "week"
+	}
+	now := m.config.Timestamps.now()
+	from := now.Add(-span)
+	footer := m.styles.help.Render("█ served  ✕ crashed  ▶ running  ·  [tab] day/week  [L] or [esc] close")
+
+	sessions := append([]sessionRecord(nil), m.sessions...)
+	running := m.server.serving() && !m.serverStartedAt.IsZero()
+	if running {
+		sessions = append(sessions, sessionRecord{Model: m.currentModelName, Start: m.serverStartedAt, End: now})
+	}
+	type modelRow struct {
+		name     string
+		sessions []sessionRecord
+		last     time.Time
+		total    time.Duration
+		crashes  int
+	}
+	rows := map[string]*modelRow{}
+	for _, s := range sessions {
+		if !s.End.After(from) {
+			continue
+		}
+		r := rows[s.Model]
+		if r == nil {
+			r = &modelRow{name: s.Model}
+			rows[s.Model] = r
+		}
+		r.sessions = append(r.sessions, s)
+		if s.End.After(r.last) {
+			r.last = s.End
+		}
+		start := s.Start
+		if start.Before(from) {
+			start = from
+		}
+		r.total += s.End.Sub(start)
+		if s.Crashed {
+			r.crashes++
+		}
+	}
+	header := m.styles.help.Render(fmt.Sprintf("Server sessions over the past %s", spanName)) + "\n\n"
+	if len(rows) == 0 {
+		return header + "No sessions recorded in this period.\n\n" + footer
+	}
+	ordered := make([]*modelRow, 0, len(rows))
+	for _, r := range rows {
+		ordered = append(ordered, r)
+	}
+	// Most recently used first
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].last.After(ordered[j].last) })
+	// Sessions of models since removed count when telling names apart
+	names := make([]string, 0, len(m.shortNames)+len(rows))
+	for name := range m.shortNames {
+		names = append(names, name)
+	}
+	for name := range rows {
+		if _, ok := m.shortNames[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	short := shortNames(names)
+
+	labelWidth := width / 4
+	if labelWidth > 28 {
+		labelWidth = 28
+	}
+	const statsWidth = 24
+	barWidth := width - labelWidth - statsWidth - 2
+	if barWidth < 12 {
+		barWidth = 12
+	}
+	cell := span / time.Duration(barWidth)
+	column := func(t time.Time) int {
+		c := int(t.Sub(from) / cell)
+		if c < 0 {
+			return 0
+		}
+		if c >= barWidth {
+			return barWidth - 1
+		}
+		return c
+	}
+
+	var b strings.Builder
+	b.WriteString(header)
+	for _, r := range ordered {
+		cells := make([]string, barWidth)
+		for i := range cells {
+			cells[i] = m.styles.disabled.Render("·")
+		}
+		for _, s := range r.sessions {
+			first, last := column(s.Start), column(s.End)
+			for c := first; c <= last; c++ {
+				cells[c] = m.styles.accent.Render("█")
+			}
+			switch {
+			case s.Crashed:
+				cells[last] = m.styles.usageCritical.Render("✕")
+			case running && s.End.Equal(now):
+				cells[last] = m.styles.servingBadge.Render("▶")
+			}
+		}
+		label := lipgloss.NewStyle().Width(labelWidth).Render(ellipsize(short[r.name], labelWidth))
+		stats := fmt.Sprintf("%s · %s", formatSpan(r.total), pluralize(len(r.sessions), "run"))
+		statsStyle := m.styles.status
+		if r.crashes > 0 {
+			crashes := "1 crash"
+			if r.crashes > 1 {
+				crashes = fmt.Sprintf("%d crashes", r.crashes)
+			}
+			stats += " · " + crashes
+			statsStyle = m.styles.usageWarn
+		}
+		b.WriteString(label + " " + strings.Join(cells, "") + " " + statsStyle.Render(ellipsize(stats, statsWidth)) + "\n")
+	}
+
+	// Time axis: every 6 hours for a day, every midnight for a week
+	axis := []rune(strings.Repeat(" ", barWidth))
+	y, mo, d := from.Date()
+	tick := time.Date(y, mo, d+1, 0, 0, 0, 0, from.Location())
+	next := func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+	layout := "Mon"
+	if !m.timelineWeek {
+		tick = from.Truncate(time.Hour).Add(time.Hour)
+		for tick.Hour()%6 != 0 {
+			tick = tick.Add(time.Hour)
+		}
+		next = func(t time.Time) time.Time { return t.Add(6 * time.Hour) }
+		layout = "15:04"
+	}
+	for ; tick.Before(now); tick = next(tick) {
+		label := []rune("╵" + tick.Format(layout))
+		c := column(tick)
+		if c+len(label) > barWidth {
+			break
+		}
+		copy(axis[c:], label)
+	}
+	b.WriteString(strings.Repeat(" ", labelWidth+1) + m.styles.help.Render(string(axis)) + "\n\n")
+	b.WriteString(footer)
+	return b.String()
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Default layouts, in Go's reference-time notation.
+const (
+	defaultLogFileLayout  = "20060102_150405"
+	defaultDateLayout     = "2006-01-02"
+	defaultDateTimeLayout = "2006-01-02 15:04"
+)
+
+// timestampConfig controls how times are written into file names and log
+// files and shown in the UI, e.g. UTC for a logs directory synced between
+// machines in different zones.
+type timestampConfig struct {
+	// Zone is "local" (default), "utc", or an IANA name such as
+	// "Europe/Berlin".
+	Zone string `json:"zone"`
+	// LogFile starts log file names; characters unsafe in file names are
+	// replaced.
+	LogFile string `json:"log_file"`
+	// LogLines prefixes each line written to log files; empty writes the
+	// server's lines as they are.
+	LogLines string `json:"log_lines"`
+	// Date and DateTime format history: smoke tests, downloads, saved slots,
+	// and cached files.
+	Date     string `json:"date"`
+	DateTime string `json:"date_time"`
+
+	loc *time.Location
+}
+
+// resolve loads the zone; loadConfig calls it so a typo is reported with
+// the rest of the config.
+func (t *timestampConfig) resolve() error {
+	switch zone := strings.TrimSpace(t.Zone); strings.ToLower(zone) {
+	case "", "local":
+		t.loc = time.Local
+	case "utc":
+		t.loc = time.UTC
+	default:
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			return fmt.Errorf("timestamps.zone: %w", err)
+		}
+		t.loc = loc
+	}
+	return nil
+}
+
+// in converts a time to the configured zone.
+func (t timestampConfig) in(at time.Time) time.Time {
+	if t.loc == nil {
+		return at.Local()
+	}
+	return at.In(t.loc)
+}
+
+func (t timestampConfig) now() time.Time {
+	return t.in(time.Now())
+}
+
+func layoutOr(layout, fallback string) string {
+	if strings.TrimSpace(layout) == "" {
+		return fallback
+	}
+	return layout
+}
+
+// fileStamp is the time at the start of a log file name.
+func (t timestampConfig) fileStamp(at time.Time) string {
+	return sanitizeFileComponent(t.in(at).Format(layoutOr(t.LogFile, defaultLogFileLayout)))
+}
+
+// linePrefix is the stamp written before a log file line, "" when off.
+func (t timestampConfig) linePrefix() string {
+	if strings.TrimSpace(t.LogLines) == "" {
+		return ""
+	}
+	return t.now().Format(t.LogLines) + " "
+}
+
+func (t timestampConfig) date(at time.Time) string {
+	return t.in(at).Format(layoutOr(t.Date, defaultDateLayout))
+}
+
+func (t timestampConfig) dateTime(at time.Time) string {
+	return t.in(at).Format(layoutOr(t.DateTime, defaultDateTimeLayout))
+}
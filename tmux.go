@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// tmuxStatus is what llama-tui publishes to tmux: user options a status
+// line can show with e.g. #{@llama_tui}, and the pane title.
+type tmuxStatus struct {
+	state string
+	model string
+	port  string
+}
+
+// tmuxPaneFromEnv is the pane llama-tui runs in, or "" outside tmux.
+func tmuxPaneFromEnv() string {
+	if os.Getenv("TMUX") == "" {
+		return ""
+	}
+	return os.Getenv("TMUX_PANE")
+}
+
+func (m appModel) tmuxStatus() tmuxStatus {
+	st := tmuxStatus{state: "stopped"}
+	switch m.server {
+	case serverStarting:
+		st.state = "starting"
+	case serverLoading:
+		st.state = "loading"
+	case serverDraining, serverQuitting:
+		st.state = "stopping"
+	case serverReady:
+		st.state = "running"
+		if m.powerPaused {
+			st.state = "paused"
+		}
+	case serverCrashed:
+		st.state = "crashed"
+	}
+	if m.server.busy() {
+		st.model, st.port = m.currentModelName, m.currentPort
+	}
+	return st
+}
+
+// summary is the one-option form, e.g. "● qwen3-8b:8080".
+func (st tmuxStatus) summary() string {
+	symbol := map[string]string{"running": "●", "crashed": "✕", "stopped": "○"}[st.state]
+	if symbol == "" {
+		symbol = "◐"
+	}
+	if st.model == "" {
+		return symbol + " " + st.state
+	}
+	return symbol + " " + strings.TrimSuffix(st.model, ".gguf") + ":" + st.port
+}
+
+// tmuxArgs sets the session's user options and the pane title in one tmux
+// invocation; a zero status unsets the options.
+func tmuxArgs(pane string, st *tmuxStatus) []string {
+	options := []string{"@llama_tui", "@llama_tui_state", "@llama_tui_model", "@llama_tui_port"}
+	var args []string
+	for i, name := range options {
+		if i > 0 {
+			args = append(args, ";")
+		}
+		if st == nil {
+			args = append(args, "set-option", "-q", "-u", "-t", pane, name)
+			continue
+		}
+		value := []string{st.summary(), st.state, st.model, st.port}[i]
+		args = append(args, "set-option", "-q", "-t", pane, name, value)
+	}
+	title := appTitle
+	if st != nil {
+		title += " " + st.summary()
+	}
+	return append(args, ";", "select-pane", "-t", pane, "-T", title)
+}
+
+// tmuxUpdateCmd publishes st; tmux errors are ignored like status file
+// errors.
+func tmuxUpdateCmd(pane string, st tmuxStatus) tea.Cmd {
+	if pane == "" {
+		return nil
+	}
+	return func() tea.Msg {
+		_ = exec.Command("tmux", tmuxArgs(pane, &st)...).Run()
+		return nil
+	}
+}
+
+// clearTmuxStatus removes the options on exit so the status line doesn't
+// show a server that is gone.
+func clearTmuxStatus(pane string) {
+	if pane == "" {
+		return
+	}
+	_ = exec.Command("tmux", tmuxArgs(pane, nil)...).Run()
+}
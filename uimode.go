@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// basicKeys are the shortcuts basic mode keeps: starting and stopping,
+// the port, finding models, help, and quitting. Everything else in
+// keyBindings waits for advanced mode.
+var basicKeys = map[string]bool{
+	"enter":  true,
+	"s":      true,
+	"p":      true,
+	"r":      true,
+	"b":      true,
+	"c":      true,
+	"/":      true,
+	"u":      true,
+	"h":      true,
+	"esc":    true,
+	"q":      true,
+	"ctrl+c": true,
+}
+
+// bindingKeys splits a binding's keys, "[ / ]" into "[" and "]".
+func bindingKeys(b keyBinding) []string {
+	parts := strings.Split(b.keys, " / ")
+	for i, p := range parts {
+		parts[i] = strings.TrimSpace(p)
+	}
+	return parts
+}
+
+// isBasicBinding reports whether basic mode keeps every key of b.
+func isBasicBinding(b keyBinding) bool {
+	for _, k := range bindingKeys(b) {
+		if !basicKeys[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// registeredKey reports whether keyStr is one of keyBindings' shortcuts,
+// as opposed to list navigation.
+func registeredKey(keyStr string) bool {
+	for _, b := range keyBindings {
+		for _, k := range bindingKeys(b) {
+			if k == keyStr {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// basicBlocks reports whether basic mode refuses keyStr, and points at
+// advanced mode in the status line.
+func (m *appModel) basicBlocks(keyStr string) bool {
+	if !m.basicMode || basicKeys[keyStr] || m.keyOverlayOpen() || !registeredKey(keyStr) {
+		return false
+	}
+	m.statusLineText = fmt.Sprintf("[%s] is an advanced feature - [u] switches to advanced mode", keyStr)
+	return true
+}
+
+// toggleUIMode switches between basic and advanced mode. Basic mode drops
+// the details pane, header graphs, and all but the essential status bar
+// entries along with the advanced shortcuts.
+func (m appModel) toggleUIMode() (tea.Model, tea.Cmd) {
+	m.basicMode = !m.basicMode
+	if m.basicMode {
+		m.statusLineText = "Basic mode: start, stop, port, and models only - [u] for advanced mode"
+	} else {
+		m.statusLineText = "Advanced mode: every feature is available - [u] for basic mode"
+	}
+	return m.resizeComponents(m.width, m.height)
+}
+
+// uiModeName is the mode as stored in the session snapshot.
+func (m appModel) uiModeName() string {
+	if m.basicMode {
+		return "basic"
+	}
+	return "advanced"
+}
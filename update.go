@@ -5,30 +5,59 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/shirou/gopsutil/v4/process"
 )
 
 // handleQuit performs the actual quit action without confirmation concerns.
-// If server is running, it sets pendingQuit and stops the server first.
+// If server is running, it moves to serverQuitting and stops the server first.
 func (m appModel) handleQuit() (appModel, tea.Cmd) {
+	m.cancelRoot()
+	m.starting = nil
+	m.share.stop()
+	m.stopTemplateRender()
+	if m.attached != nil {
+		// Not ours to stop
+		return m, tea.Quit
+	}
+	m.discardStandby()
+	switch {
+	case m.shutdown == nil:
+		if ports := m.liveInstances(); len(ports) > 1 {
+			return m.beginShutdown(ports, true)
+		}
+	case !m.shutdown.quit:
+		seq := *m.shutdown
+		seq.quit = true
+		m.shutdown = &seq
+		m.statusLineText = "Quitting once the servers have stopped in order - ctrl+c stops the rest at once"
+		return m, nil
+	default:
+		// Asked again: the rest stop at once
+		m.shutdown = nil
+	}
+	m, _ = m.stopSideServers()
 	// Ensure server is stopped before quitting
-	if m.serverRunning && !m.serverStopping {
-		m.pendingQuit = true
-		m.serverStopping = true
+	if m.server.serving() {
+		m.server = serverQuitting
 		m.statusLineText = "Stopping server before quit..."
 		stopMsg := "\n[ui] Stopping server before quit...\n"
 		coloredStopMsg := m.colorLog(stopMsg)
 		_, _ = m.logBuffer.WriteString(coloredStopMsg)
-		m.logsViewport.SetContent(m.logBuffer.String())
-		return m, m.stopServerCmd()
+		m.logsViewport.SetContent(m.logsContent())
+		return m, tea.Batch(m.stopServerCmd(), m.spinner.Tick)
 	}
-	// If already stopping, just quit (will happen after serverExitedMsg)
-	if m.serverStopping {
+	// Already stopping or still launching: quit once the server has exited
+	if m.server.stopping() || m.server == serverStarting {
+		m.server = serverQuitting
+		m.statusLineText = "Quitting once the server has stopped..."
 		return m, nil
 	}
 	return m, tea.Quit
@@ -36,27 +65,635 @@ func (m appModel) handleQuit() (appModel, tea.Cmd) {
 
 // handleStop performs the actual stop action without confirmation concerns.
 func (m appModel) handleStop() (appModel, tea.Cmd) {
-	if m.serverRunning && !m.serverStopping {
-		m.serverStopping = true
+	if m.attached != nil {
+		m.statusLineText = fmt.Sprintf("Attached to port %s - stop the server where it was started", m.attached.port)
+		return m, nil
+	}
+	if m.server.serving() {
+		m.server = serverDraining
 		m.statusLineText = "Stopping server..."
 		stopMsg := "\n[ui] Stopping server...\n"
 		coloredStopMsg := m.colorLog(stopMsg)
 		_, _ = m.logBuffer.WriteString(coloredStopMsg)
-		m.logsViewport.SetContent(m.logBuffer.String())
-		return m, m.stopServerCmd()
+		m.logsViewport.SetContent(m.logsContent())
+		return m, tea.Batch(m.stopServerCmd(), m.spinner.Tick)
+	}
+	if m.server == serverStarting {
+		m.statusLineText = "Server is still starting..."
+		return m, nil
 	}
-	if m.serverStopping {
+	if m.server.stopping() {
 		m.statusLineText = "Server is already stopping..."
 		return m, nil
 	}
-	if !m.serverRunning {
+	if !m.server.running() {
 		m.statusLineText = "No server is running"
 		return m, nil
 	}
 	return m, nil
 }
 
+// handleStopAll terminates everything llama-tui manages: the server and any
+// running benchmark.
+func (m appModel) handleStopAll() (appModel, tea.Cmd) {
+	stopped := []string{}
+	if m.benchCancel != nil {
+		m.benchCancel()
+		stopped = append(stopped, "benchmark")
+	}
+	if m.downloadCancel != nil {
+		m.downloadCancel()
+		stopped = append(stopped, "download")
+	}
+	if m.standby != nil {
+		m.discardStandby()
+		stopped = append(stopped, "standby")
+	}
+	if m.starting != nil {
+		m.starting = nil
+		stopped = append(stopped, "ordered start")
+	}
+	var cmd tea.Cmd
+	if ports := m.liveInstances(); len(ports) > 1 && m.shutdown == nil {
+		m, cmd = m.beginShutdown(ports, false)
+		stopped = append(stopped, pluralize(len(ports), "server")+" in order")
+	} else {
+		if live := m.liveSideServers(); live > 0 {
+			m, _ = m.stopSideServers()
+			stopped = append(stopped, pluralize(live, "side server"))
+		}
+		if m.server.serving() && m.attached == nil {
+			m, cmd = m.handleStop()
+			stopped = append(stopped, "server")
+		}
+	}
+	if len(stopped) == 0 {
+		m.statusLineText = "Nothing to stop"
+		return m, nil
+	}
+	m.statusLineText = "Stopping everything: " + strings.Join(stopped, ", ")
+	return m, cmd
+}
+
+// launchPort is the port a launch of item would use: the port input, else
+// the model's saved port, else its automatic port when configured, else the
+// default.
+func (m appModel) launchPort(item modelItem) string {
+	if portStr := strings.TrimSpace(m.portInput.Value()); portStr != "" {
+		return portStr
+	}
+	if port := m.modelConfigs[item.name].Port; port != "" {
+		return port
+	}
+	if m.config.AutoPorts.enabled() {
+		return strconv.Itoa(m.config.AutoPorts.portFor(item.name))
+	}
+	return defaultPort
+}
+
+// reorderModels re-applies pin ordering to the list, keeping the model at
+// selectPath selected.
+func (m *appModel) reorderModels(selectPath string) {
+	items := orderWithPins(sortModels(m.allModelItems(), m.sortKey), m.pins)
+	m.foldedModels = nil
+	if m.groupByFamily {
+		for _, it := range items {
+			// A folded model to select opens its family
+			if mi, ok := it.(modelItem); ok && mi.path == selectPath && !mi.pinned {
+				if m.expandedFamilies == nil {
+					m.expandedFamilies = map[string]bool{}
+				}
+				m.expandedFamilies[strings.ToLower(modelFamily(mi.name))] = true
+			}
+		}
+		items, m.foldedModels = groupFamilies(items, m.expandedFamilies)
+	}
+	names := make([]string, 0, len(items)+len(m.foldedModels))
+	for _, it := range append(items, m.foldedModels...) {
+		if mi, ok := it.(modelItem); ok {
+			names = append(names, mi.name)
+		}
+	}
+	m.shortNames = shortNames(names)
+	m.modelsList.SetItems(items)
+	for i, it := range items {
+		if mi, ok := it.(modelItem); ok && mi.path == selectPath {
+			m.modelsList.Select(i)
+			break
+		}
+	}
+}
+
+// appendLogLine adds a sanitized, colored line to the logs panel, trimming
+// the buffer to its soft limit.
+func (m *appModel) appendLogLine(text string) {
+	m.appendTaggedLogLine("", text)
+}
+
+// appendTaggedLogLine is appendLogLine with a styled prefix naming the
+// instance the line came from.
+func (m *appModel) appendTaggedLogLine(tag, text string) {
+	text = sanitizeLogLine(text)
+	switch classifyLogLine(text) {
+	case logLevelError:
+		m.logErrorCount++
+	case logLevelWarn:
+		m.logWarnCount++
+	}
+	m.logStream.publish(ansiEscape.ReplaceAllString(tag, "") + text)
+	coloredLine := tag + m.colorLog(text)
+	_, _ = m.logBuffer.WriteString(coloredLine)
+	_, _ = m.logBuffer.WriteString("\n")
+	if m.logBuffer.Len() > m.logBufferLimit() {
+		// Trim oldest half to keep memory bounded
+		var newBuf bytes.Buffer
+		kept := trimLogBuffer(m.logBuffer.Bytes())
+		m.logLinesDropped += bytes.Count(m.logBuffer.Bytes()[:m.logBuffer.Len()-len(kept)], []byte("\n"))
+		_, _ = newBuf.Write(kept)
+		m.logBuffer = newBuf
+		m.dropTrimmedBookmarks()
+	}
+	if m.combinedLogs != nil {
+		if tag == "" {
+			tag = m.mainInstanceTag()
+		}
+		m.appendCombinedLine(tag, m.colorLog(text))
+	}
+
+	if m.logView != "" {
+		// A side server's logs, or every server's, are shown
+		return
+	}
+	if m.lowMemory {
+		// Shown on the next refresh tick
+		m.logsDirty = true
+		return
+	}
+	m.logsViewport.SetContent(m.logsContent())
+	m.logsViewport.GotoBottom()
+}
+
+// jumpToNextError scrolls the logs to the first error line below the top
+// of the view, wrapping around to the first one.
+func (m *appModel) jumpToNextError() bool {
+	lines := strings.Split(ansiEscape.ReplaceAllString(m.logsContent(), ""), "\n")
+	first := -1
+	for i, line := range lines {
+		if classifyLogLine(line) != logLevelError {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		if i > m.logsViewport.YOffset {
+			m.logsViewport.SetYOffset(i)
+			return true
+		}
+	}
+	if first < 0 {
+		return false
+	}
+	m.logsViewport.SetYOffset(first)
+	return true
+}
+
+// askConfirm makes action pending until its key is pressed again, another
+// key is pressed, or confirmTimeout passes. confirmSeq tells the countdown
+// ticks of this prompt from an earlier one's.
+func (m *appModel) askConfirm(action confirmAction) tea.Cmd {
+	m.confirmAction = action
+	m.confirmDeadline = time.Now().Add(confirmTimeout)
+	m.confirmSeq++
+	return confirmTickCmd(m.confirmSeq)
+}
+
+// confirmTickCmd refreshes the countdown once a second.
+func confirmTickCmd(seq int) tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return confirmTickMsg{seq: seq}
+	})
+}
+
+// confirmCountdown says when a pending confirmation cancels itself.
+func (m appModel) confirmCountdown() string {
+	left := time.Until(m.confirmDeadline)
+	if left < 0 {
+		left = 0
+	}
+	return fmt.Sprintf(" (cancels in %ds)", int((left+time.Second-1)/time.Second))
+}
+
+// logEvent appends a UI event line to the logs panel.
+func (m *appModel) logEvent(line string) {
+	m.noteEvent(line)
+	_, _ = m.logBuffer.WriteString(m.colorLog(line) + "\n")
+	if m.combinedLogs != nil {
+		// Untagged: llama-tui's own note rather than a server's line
+		_, _ = m.combinedLogs.WriteString(m.colorLog(line) + "\n")
+	}
+	m.logsViewport.SetContent(m.logsContent())
+	m.logsViewport.GotoBottom()
+}
+
+// applyForm applies a submitted form according to what it was opened for.
+func (m appModel) applyForm() (appModel, tea.Cmd) {
+	form := *m.form
+	values := form.values()
+	purpose := m.formPurpose
+	m.form, m.formPurpose = nil, formNone
+	switch purpose {
+	case formTailFile:
+		path, err := expandHome(m.homeDir, values["file"])
+		if err != nil {
+			m.statusLineText = fmt.Sprintf("Not tailing: %v", err)
+			return m, nil
+		}
+		m.statusLineText = "Tailing " + path + " ([t] to stop)"
+		return m, m.beginTail(path)
+	case formWorkspace:
+		name := values["workspace"]
+		if name == defaultWorkspace {
+			name = ""
+		}
+		if name == m.workspace {
+			m.statusLineText = "Already in workspace " + m.workspaceLabel()
+			return m, nil
+		}
+		return m.switchWorkspace(name)
+	case formSlotSave:
+		if m.slotView == nil || m.server != serverReady {
+			m.statusLineText = "The server stopped before the slot was saved"
+			return m, nil
+		}
+		v := *m.slotView
+		v.note = fmt.Sprintf("Saving slot %d to %s...", v.slot, values["name"])
+		m.slotView = &v
+		return m, slotActionCmd(m.currentPort, v.slot, "save", values["name"])
+	case formKVOverrides:
+		overrides := kvOverridesFromForm(values, len(form.fields)/3, false)
+		for _, o := range overrides {
+			if err := o.validate(); err != nil {
+				// Keep editing until every row is valid
+				form.err = err.Error()
+				m.form, m.formPurpose = &form, purpose
+				return m, nil
+			}
+		}
+		name := m.kvModelName
+		return m, func() tea.Msg {
+			return kvOverridesSavedMsg{modelName: name, count: len(overrides), err: saveKVOverrides(name, overrides)}
+		}
+	case formHFRepo:
+		repo := values["repo"]
+		m.hfRepos = setHFRepo(m.hfRepos, m.hfEditRepo, repo)
+		switch {
+		case repo == "" && m.hfEditRepo == "":
+			m.statusLineText = "No repo entered"
+			return m, nil
+		case repo == "":
+			m.statusLineText = "Removed hf:" + m.hfEditRepo
+		case m.hfEditRepo == "":
+			m.statusLineText = "Added hf:" + repo + " - [enter] downloads and serves it"
+		default:
+			m.statusLineText = "Changed hf:" + m.hfEditRepo + " to hf:" + repo
+		}
+		return m, tea.Batch(saveHFReposCmd(m.historyDir, m.hfRepos), m.scanModelsCmd())
+	case formLaunchOptions:
+		m.portInput.SetValue(values["port"])
+		m.launchArgs = launchArgsFromForm(values)
+		// Edited options are no longer the preset's
+		m.launchPreset, m.startupLaunch = "", ""
+		if len(m.launchArgs) == 0 {
+			m.statusLineText = "Launch options cleared"
+		} else {
+			m.statusLineText = "Launch options: " + strings.Join(m.launchArgs, " ")
+		}
+		m.event("config", "", m.statusLineText+" (port "+m.portInput.Value()+")")
+	case formPresetVars:
+		return m.launchWithPresetVars(values)
+	case formPrompt:
+		return m.addPrompt(values)
+	case formSampling:
+		if m.samplingView == nil {
+			return m, nil
+		}
+		return m.applySampling(samplingFromForm(values))
+	case formModelConfig:
+		c := modelLaunchConfig{Model: m.modelConfigName, Port: strings.TrimSpace(values["port"]), Args: launchArgsFromForm(values), MMProj: values["mmproj"]}
+		return m, saveModelConfigCmd(c)
+	}
+	return m, nil
+}
+
+// attachServer adopts a started server process as the managed server and
+// begins receiving its events.
+func (m appModel) attachServer(msg startedWithStateMsg) (appModel, tea.Cmd) {
+	m.process = msg.process
+	m.logChan = msg.logChan
+	m.exitChan = msg.exitChan
+	m.readyChan = msg.readyChan
+	m.warmupChan = nil
+	m.warmupText = ""
+	m.warmupActive = false
+	m.spec = specStats{}
+	m.slo = sloState{}
+	quitting := m.server == serverQuitting
+	m.server = serverLoading
+	m.serverStartedAt = time.Now()
+	m.currentModelName = msg.modelName
+	m.currentPort = msg.port
+	// A failover relaunch takes over the proxy once it is ready
+	if !m.incident.pending() || msg.modelName != m.incident.model || msg.port != m.incident.to {
+		m.incident = nil
+		m.proxy.setTarget(msg.port)
+	}
+	m.servedPrompt = m.promptPick
+	if m.servingWhisper() {
+		m.servedPrompt = promptPick{}
+	} else if m.servedPrompt.name != "" {
+		m.event("config", msg.modelName, "Prompt "+m.servedPrompt.describe())
+	}
+	m.sampling, _ = loadSamplingOverrides(msg.modelName)
+	m.proxy.setSampling(m.sampling.requestValues())
+	m.proxy.setSystemPrompt(m.servedPrompt.systemText())
+	m.servedDefaults = nil
+	m.powerStopped = nil
+	if portNum, err := strconv.Atoi(msg.port); err == nil {
+		m.ports = m.ports.with(portNum, msg.modelName)
+	}
+	m.currentMMProj = msg.mmprojPath
+	m.servingPath = msg.modelPath
+	var socketCmd tea.Cmd
+	if m.socket != nil {
+		m.socket.setTarget(msg.port)
+	} else if path, err := m.socketPath(); err != nil {
+		m.logEvent(fmt.Sprintf("[socket] Not serving a socket: %v", err))
+	} else if path != "" && !m.readOnly && !m.socketOpening {
+		m.socketOpening = true
+		socketCmd = openServerSocketCmd(path, msg.port, m.proxy)
+	}
+	m.modelsList.SetDelegate(m.listDelegate())
+	m.logFilePath = msg.logFilePath
+	if msg.logFilePath != "" {
+		m.lastLogFilePath = msg.logFilePath
+	}
+	last := lastLaunch{Model: msg.modelName, Port: msg.port, Args: msg.launchArgs}
+	dir := m.historyDir
+	m.statusLineText = fmt.Sprintf("Serving %s on port %s", msg.modelName, msg.port)
+	if msg.logFilePath != "" {
+		m.statusLineText += fmt.Sprintf(" - log: %s ([y] copy path)", msg.logFilePath)
+	}
+	// Blur port input when server starts
+	if m.portInput.Focused() {
+		m.portInput.Blur()
+	}
+	var quitCmd tea.Cmd
+	if quitting {
+		// Quit was requested while launching
+		m, quitCmd = m.handleQuit()
+	}
+	var hfCmd tea.Cmd
+	if m.servedHFRepo() != "" {
+		hfCmd = hfDownloadTickCmd()
+	}
+	return m, tea.Batch(
+		quitCmd,
+		hfCmd,
+		socketCmd,
+		m.waitForLogLine(),
+		m.waitForExit(),
+		m.waitForReady(),
+		m.pollResourceUsageCmd(),
+		m.startClientsPoll(msg.port),
+		func() tea.Msg {
+			// Best-effort; only --autostart-last depends on it
+			_ = saveLastLaunch(dir, last)
+			return nil
+		},
+	)
+}
+
+// offerFlagRetry looks for a flag the server rejected at startup (older
+// builds lack newer flags) and offers to relaunch without it.
+func (m *appModel) offerFlagRetry(modelName string, argv []string) {
+	rejected := findRejectedFlag(ansiEscape.ReplaceAllString(m.logBuffer.String(), ""))
+	if rejected == "" {
+		return
+	}
+	fix, ok := suggestFlagFix(argv, rejected)
+	if !ok {
+		return
+	}
+	item, ok := m.findModelByName(modelName)
+	if !ok {
+		return
+	}
+	m.flagRetry = &flagRetryOffer{item: item, rejected: rejected, fix: fix}
+	m.statusLineText = fmt.Sprintf("llama-server rejected %s - press R to retry %s", rejected, fix.describe())
+}
+
+// beginStart clears the logs for a new session and launches the server.
+// Any preflight warnings the user accepted are kept at the top of the log.
+func (m appModel) beginStart(item modelItem, portStr string, warnings []string) (appModel, tea.Cmd) {
+	// Blur port input before starting server
+	if m.portInput.Focused() {
+		m.portInput.Blur()
+	}
+	// Clear logs for a new session and set initial message
+	m.logBuffer.Reset()
+	m.clearBookmarks()
+	m.logErrorCount, m.logWarnCount = 0, 0
+	m.flagRetry = nil
+	for _, w := range warnings {
+		_, _ = m.logBuffer.WriteString(m.colorLog("Warning: "+w) + "\n")
+	}
+	if flakyModels(m.reliability, m.config.Flaky)[item.name] {
+		note := fmt.Sprintf("%s is flaky: crashed %s - consider re-downloading it or trying another quant", item.name, m.reliabilitySummary(item.name))
+		_, _ = m.logBuffer.WriteString(m.colorLog("Warning: "+note) + "\n")
+	}
+	initialMsg := fmt.Sprintf("Starting %s with model: %s on port: %s...", item.kind.serverName(), item.name, portStr)
+	coloredMsg := m.colorLog(initialMsg)
+	_, _ = m.logBuffer.WriteString(coloredMsg)
+	m.logsViewport.SetContent(m.logsContent())
+	m.statusLineText = fmt.Sprintf("Starting %s on port %s...", item.name, portStr)
+	m.event("start", item.name, "Starting on port "+portStr)
+	m.server = serverStarting
+	start := m.startServerCmd(item, portStr)
+	// A retry's flags were for this launch only
+	m.retryArgs = nil
+	return m, tea.Batch(start, m.spinner.Tick)
+}
+
+// runStartupAction performs the launch requested on the command line, once.
+func (m appModel) runStartupAction() (appModel, tea.Cmd) {
+	action := *m.startup
+	m.startup = nil
+	if next, asked := m.promptPresetVars(action); asked {
+		return next, nil
+	}
+	if len(action.vars) > 0 {
+		action.args = expandTemplate(action.args, action.vars)
+		m.event("config", action.model, "Preset "+action.preset+" with "+describeVars(action.vars))
+	}
+	if action.port != "" {
+		m.portInput.SetValue(action.port)
+	}
+	if action.model == "" {
+		m.launchArgs = action.args
+		m.launchReadiness = action.readiness
+		m.launchPreset = action.preset
+		return m, nil
+	}
+	item, err := findModelItem(m.allModelItems(), action.model)
+	if err != nil {
+		m.statusLineText = fmt.Sprintf("Startup: %v", err)
+		return m, nil
+	}
+	// The preset's options are for its model; requestStart drops them when
+	// another model is launched
+	m.launchArgs = action.args
+	m.launchReadiness = action.readiness
+	m.launchPreset = action.preset
+	m.startupLaunch = item.name
+	m.reorderModels(item.path)
+	return m.requestStart(item)
+}
+
+// requestStart validates the port and runs the launch preflight for item.
+func (m appModel) requestStart(item modelItem) (appModel, tea.Cmd) {
+	if m.attached != nil {
+		m.statusLineText = fmt.Sprintf("Attached to port %s - launching is off while monitoring", m.attached.port)
+		return m, nil
+	}
+	if m.readOnly {
+		m.statusLineText = fmt.Sprintf("Read-only: llama-tui pid %d manages this barn - [T] take over", m.lockOwner)
+		return m, nil
+	}
+	if m.retryArgs != nil && item.name != m.retryArgs.model {
+		m.retryArgs = nil
+	}
+	if m.starting != nil && m.starting.target.name != item.name {
+		m.statusLineText = "Starting " + m.starting.target.name + " after its dependencies - [ctrl+k] cancels"
+		return m, nil
+	}
+	if m.starting == nil && item.remoteURL == "" && !(m.server.busy() && item.name == m.currentModelName) {
+		deps, err := m.missingDependencies(item)
+		if err != nil {
+			m.statusLineText = "Not starting: " + err.Error()
+			return m, nil
+		}
+		if len(deps) > 0 {
+			return m.beginStartSequence(item, deps)
+		}
+	}
+	if m.startupLaunch != "" && item.name != m.startupLaunch {
+		// A startup preset's options don't carry over to other models
+		m.launchArgs, m.launchReadiness, m.launchPreset = nil, nil, ""
+		m.startupLaunch = ""
+	}
+	if m.server.serving() && item.name != m.currentModelName {
+		// Runs beside the managed server instead of replacing it
+		next, ok := m.schedule(item, "")
+		if !ok {
+			return next, nil
+		}
+		return next.startSideServer(item)
+	}
+	if m.server.busy() {
+		m.statusLineText = "Server is already running or stopping"
+		return m, nil
+	}
+	m, ok := m.schedule(item, m.launchPreset)
+	if !ok {
+		return m, nil
+	}
+	portStr := m.launchPort(item)
+	// Validate port before starting server
+	portNum, err := validatePort(portStr)
+	if err != nil {
+		m.statusLineText = fmt.Sprintf("Invalid port: %v", err)
+		return m, nil
+	}
+	portStr = strconv.Itoa(portNum)
+	// Ports of servers llama-tui runs can't be shared; other listeners are
+	// a pre-flight check
+	if model, ok := m.ports[portNum]; ok {
+		err := fmt.Errorf("port %d is already used by %s", portNum, model)
+		if next := m.ports.nextFreePort(portNum); next > 0 {
+			m.statusLineText = fmt.Sprintf("%v - try %d ([p] to edit port)", err, next)
+		} else {
+			m.statusLineText = err.Error()
+		}
+		return m, nil
+	}
+	// Catalog entries are downloaded into the barn first, then launched
+	if item.remoteURL != "" {
+		if m.downloadCancel != nil {
+			m.statusLineText = "A download is already in progress"
+			return m, nil
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		m.downloadCancel = cancel
+		m.download = &downloadProgress{name: item.name}
+		m.statusLineText = "Downloading " + item.name + "..."
+		return m, tea.Batch(downloadModelCmd(ctx, item, m.downloadBlobDir(), m.config.ScanCommand, m.download), downloadTickCmd())
+	}
+	m.statusLineText = fmt.Sprintf("Checking launch flags for %s...", item.name)
+	return m, m.preflightCmd(item, portStr)
+}
+
+// Update handles a message and, whenever the server state changes, refreshes
+// the status file read by external widgets.
 func (m appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	defer func() {
+		if r := recover(); r != nil {
+			recordPanic(r, &m)
+			panic(r)
+		}
+	}()
+	next, cmd := m.update(msg)
+	if nm, ok := next.(appModel); ok {
+		if st := nm.serverStatus(); !nm.readOnly && st != m.serverStatus() {
+			cmd = tea.Batch(cmd, writeStatusFileCmd(nm.statusFilePath, st))
+		}
+		if e := nm.clientEndpoints(); !nm.readOnly && !slices.Equal(e, m.clientEndpoints()) {
+			cmd = tea.Batch(cmd, writeClientConfigsCmd(nm.clientConfigDir, e))
+		}
+		if st := nm.tmuxStatus(); st != m.tmuxStatus() {
+			cmd = tea.Batch(cmd, tmuxUpdateCmd(nm.tmuxPane, st))
+		}
+		if prev, st := m.terminalStatus(), nm.terminalStatus(); st != prev {
+			cmd = tea.Batch(cmd, terminalUpdateCmd(prev, st))
+		}
+		if nm.starting != nil && sideServerStates(nm) != sideServerStates(m) {
+			// A dependency came up or exited; the next step may start
+			var step tea.Cmd
+			nm, step = nm.advanceStartSequence()
+			next, cmd = nm, tea.Batch(cmd, step)
+		}
+		if nm.shutdown != nil && (nm.server != m.server || nm.liveSideServers() != m.liveSideServers()) {
+			// A server exited; the next step of the shutdown may start
+			var step tea.Cmd
+			nm, step = nm.advanceShutdown()
+			next, cmd = nm, tea.Batch(cmd, step)
+		}
+		if len(nm.launchQueue) > 0 && (nm.server != m.server || nm.liveSideServers() != m.liveSideServers() || nm.standby != m.standby) {
+			// Servers came or went; a queued launch may fit now
+			var queued tea.Cmd
+			nm, queued = nm.runLaunchQueue()
+			next, cmd = nm, tea.Batch(cmd, queued)
+		}
+		if len(nm.unsavedEvents) > 0 {
+			cmd = tea.Batch(cmd, appendEventsCmd(nm.unsavedEvents))
+			nm.unsavedEvents = nil
+			next = nm
+		}
+		lastModel.Store(&nm)
+	}
+	return next, cmd
+}
+
+func (m appModel) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -76,14 +713,71 @@ func (m appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case scanDoneMsg:
+		m.barnMissing = msg.barnMissing
+		for i, it := range msg.items {
+			if mi, ok := it.(modelItem); ok {
+				mi.order = i
+				msg.items[i] = mi
+			}
+		}
+		items, missing := applyModelMetadata(applyProjectors(msg.items, m.modelConfigs), m.modelMeta)
+		// Files behind symlinks or mounts can be listed yet unreadable
+		metaCmd := checkPathsCmd(items)
+		if len(m.config.MetadataCommand) > 0 && len(missing) > 0 {
+			metaCmd = tea.Batch(metaCmd, metadataCommandCmd(m.config.MetadataCommand, missing))
+		}
 		if msg.err != nil {
 			m.statusLineText = fmt.Sprintf("Scan error: %v", msg.err)
+			m.eventError("scan", "", m.statusLineText)
+		} else if msg.barnMissing {
+			m.foldedModels = nil
+			m.modelsList.SetItems(items)
+			m.statusLineText = fmt.Sprintf("Models directory %s does not exist - [c] create it, [b] change path", m.barnDir)
 		} else {
-			m.modelsList.SetItems(msg.items)
-			m.statusLineText = fmt.Sprintf("Found %d model(s)", len(msg.items))
-			if len(msg.items) > 0 && m.modelsList.Index() < 0 {
+			m.foldedModels = nil
+			m.modelsList.SetItems(items)
+			m.reorderModels("")
+			m.statusLineText = fmt.Sprintf("Found %d model(s)", len(items))
+			m.event("scan", "", fmt.Sprintf("Found %s in %s", pluralize(len(items), "model"), m.barnDir))
+			if len(items) > 0 && m.modelsList.Index() < 0 {
 				m.modelsList.Select(0)
 			}
+			if m.restoreSelected != "" {
+				m.reorderModels(m.restoreSelected)
+				m.restoreSelected = ""
+			}
+		}
+		if msg.err == nil && msg.sourceErr != nil {
+			m.eventError("scan", "", fmt.Sprintf("Skipped: %v", msg.sourceErr))
+			m.statusLineText += fmt.Sprintf(" (skipped: %v)", msg.sourceErr)
+		}
+		if m.startup != nil {
+			next, cmd := m.runStartupAction()
+			return next, tea.Batch(cmd, metaCmd)
+		}
+		if m.quick != nil && m.quick.autostart {
+			next, cmd := m.quickAutostart()
+			return next, tea.Batch(cmd, metaCmd)
+		}
+		return m, tea.Batch(metaCmd, m.previewDwellCmd())
+
+	case modelMetadataMsg:
+		if m.modelMeta == nil {
+			m.modelMeta = make(map[string]map[string]string, len(msg.meta))
+		}
+		for path, fields := range msg.meta {
+			m.modelMeta[path] = fields
+		}
+		items, _ := applyModelMetadata(m.allModelItems(), m.modelMeta)
+		selected := ""
+		if mi, ok := m.modelsList.SelectedItem().(modelItem); ok {
+			selected = mi.path
+		}
+		m.foldedModels = nil
+		m.modelsList.SetItems(items)
+		m.reorderModels(selected)
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("metadata_command failed for %s: %v", pluralize(msg.failed, "model"), msg.err)
 		}
 		return m, nil
 
@@ -92,145 +786,1334 @@ func (m appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, tea.Batch(m.waitForLogLine(), m.waitForExit())
 
 	case startedWithStateMsg:
-		// Attach process state to the model and begin receiving events
-		m.serverCtx = msg.ctx
-		m.serverCancel = msg.cancel
-		m.serverCmd = msg.cmd
-		m.logChan = msg.logChan
-		m.exitChan = msg.exitChan
-		m.serverRunning = true
-		m.serverStopping = false
-		m.currentModelName = msg.modelName
-		m.currentPort = msg.port
-		m.logFilePath = msg.logFilePath
-		m.statusLineText = fmt.Sprintf("Serving %s on port %s", msg.modelName, msg.port)
-		// Blur port input when server starts
-		if m.portInput.Focused() {
-			m.portInput.Blur()
-		}
-		return m, tea.Batch(m.waitForLogLine(), m.waitForExit(), m.pollResourceUsageCmd())
+		return m.attachServer(msg)
 
 	case startErrorMsg:
 		// Handle start errors - don't mark as running
+		if m.server == serverQuitting {
+			return m, tea.Quit
+		}
+		m.server = serverCrashed
 		m.statusLineText = fmt.Sprintf("Failed to start server: %v", msg.err)
+		m.eventError("start", msg.model, fmt.Sprintf("Failed to start on port %s: %v", msg.port, msg.err))
+		m.abandonFailover("failed to start")
+		m.startFailure = newStartFailure(msg)
 		// Also surface error in logs panel so it's visible without scanning the status line
 		errorMsg := "\nERROR: " + msg.err.Error() + "\n"
 		coloredError := m.colorLog(errorMsg)
 		_, _ = m.logBuffer.WriteString(coloredError)
-		m.logsViewport.SetContent(m.logBuffer.String())
+		m.logsViewport.SetContent(m.logsContent())
 		return m, nil
 
-	case stoppedMsg:
-		// This message is no longer used - cleanup happens in serverExitedMsg
+	case pagerClosedMsg:
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Pager error: %v", msg.err)
+		} else {
+			m.statusLineText = "Returned from pager"
+		}
 		return m, nil
 
-	case resourceUsageMsg:
-		// Update resource metrics
-		m.cpuPercent = msg.cpuPercent
-		m.memRSSBytes = msg.memRSSBytes
-		// Schedule next poll if server is still running
-		if m.serverRunning && !m.serverStopping {
-			// Capture serverCmd pointer to avoid stale closure
-			serverCmd := m.serverCmd
-			return m, tea.Tick(time.Second, func(_ time.Time) tea.Msg {
-				if serverCmd == nil || serverCmd.Process == nil {
-					return nil
+	case restartTickMsg:
+		return m.handleRestartTick()
+
+	case devWatchTickMsg:
+		return m.handleDevWatchTick(msg)
+
+	case devWatchStatMsg:
+		return m.handleDevWatchStat(msg)
+
+	case snapshotTickMsg:
+		return m, tea.Batch(m.saveSnapshotCmd(), snapshotTickCmd())
+
+	case chatClosedMsg:
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("llama-cli exited: %v", msg.err)
+		} else {
+			m.statusLineText = "Returned from chat with " + msg.model
+		}
+		return m, nil
+
+	case barnDirCreatedMsg:
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Could not create %s: %v", msg.dir, msg.err)
+			return m, nil
+		}
+		m.statusLineText = "Created " + msg.dir + " - scanning for models..."
+		return m, m.scanModelsCmd()
+
+	case clipboardCopiedMsg:
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Copy failed: %v", msg.err)
+		} else {
+			m.statusLineText = "Copied: " + msg.text
+		}
+		return m, nil
+
+	case preflightDoneMsg:
+		m = m.withAvailability(map[string]string{msg.item.path: msg.unavailable})
+		if m.server.busy() {
+			return m, nil
+		}
+		unattended := m.unattendedLaunch == msg.item.name
+		if unattended {
+			m.unattendedLaunch = ""
+		}
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Failed to start server: %v", msg.err)
+			m.eventError("start", msg.item.name, msg.err.Error())
+			return m, nil
+		}
+		if unattended && len(msg.warnings) > 0 {
+			m.event("start", msg.item.name, "Launching over the control API despite "+pluralize(len(msg.warnings), "launch warning"))
+		}
+		if len(msg.warnings) == 0 || unattended {
+			next, cmd := m.beginStart(msg.item, msg.port, msg.warnings)
+			// Flash the passed checklist until the server reports progress
+			next.statusLineText = gateSummary(msg.gate)
+			return next, cmd
+		}
+		// Show what is wrong and ask before launching
+		m.logBuffer.Reset()
+		m.clearBookmarks()
+		if diagnosticsFailed(msg.gate) {
+			_, _ = m.logBuffer.WriteString("Pre-flight: " + gateSummary(msg.gate) + "\n")
+		}
+		for _, w := range msg.warnings {
+			_, _ = m.logBuffer.WriteString(m.colorLog("Warning: "+w) + "\n")
+		}
+		m.logsViewport.SetContent(m.logsContent())
+		m.logsViewport.GotoTop()
+		pending := msg
+		m.pendingLaunch = &pending
+		m.statusLineText = fmt.Sprintf("%d launch warning(s): press enter again to launch anyway, esc to cancel", len(msg.warnings))
+		return m, m.askConfirm(confirmLaunch)
+
+	case lockCheckMsg:
+		if m.readOnly {
+			return m, nil
+		}
+		if msg.owned {
+			return m, lockCheckCmd(m.lockPath)
+		}
+		// Another instance took over: step down and free the port for it
+		m.readOnly = true
+		if owner, err := readLock(m.lockPath); err == nil {
+			m.lockOwner = owner.PID
+		}
+		m.statusLineText = fmt.Sprintf("Another llama-tui (pid %d) took over - now read-only", m.lockOwner)
+		if m.server.serving() {
+			return m.handleStop()
+		}
+		return m, nil
+
+	case lockTakenOverMsg:
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Take over failed: %v", msg.err)
+			return m, nil
+		}
+		m.readOnly = false
+		m.lockOwner = 0
+		m.statusLineText = "Took over server management for this barn"
+		if !m.config.DisableTmuxStatus {
+			m.tmuxPane = tmuxPaneFromEnv()
+		}
+		return m, tea.Batch(lockCheckCmd(m.lockPath), tmuxUpdateCmd(m.tmuxPane, m.tmuxStatus()))
+
+	case visionTestDoneMsg:
+		m.visionTesting = false
+		var line string
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Vision test failed: %v", msg.err)
+			line = fmt.Sprintf("[vision] ERROR: %v", msg.err)
+		} else {
+			m.statusLineText = fmt.Sprintf("Vision test OK (%.1fs)", msg.elapsed.Seconds())
+			line = fmt.Sprintf("[vision] Response (%.1fs): %s", msg.elapsed.Seconds(), msg.reply)
+		}
+		_, _ = m.logBuffer.WriteString("\n" + m.colorLog(line) + "\n")
+		m.logsViewport.SetContent(m.logsContent())
+		m.logsViewport.GotoBottom()
+		return m, nil
+
+	case serverReadyMsg:
+		// Ignore readiness from a previous session
+		if msg.readyChan != m.readyChan || !m.server.serving() {
+			return m, nil
+		}
+		if !msg.ready {
+			return m, nil
+		}
+		m.server = serverReady
+		if m.attached == nil {
+			m.nextRestart = m.restartSchedule.next(time.Now())
+			m.event("ready", m.currentModelName, fmt.Sprintf("Ready on port %s after %s", m.currentPort, time.Since(m.serverStartedAt).Round(100*time.Millisecond)))
+		}
+		if m.servingWhisper() {
+			m.completeFailover()
+			// No props, health, or completions to ask whisper-server for
+			return m, nil
+		}
+		propsCmd := tea.Batch(capturePropsCmd(m.root, m.currentPort, m.currentModelName), m.startHealthPoll())
+		if m.needsSmokeTest() {
+			propsCmd = tea.Batch(propsCmd, smokeTestCmd(m.root, m.currentPort, m.servingPath))
+		}
+		warmup := m.warmupPrompt()
+		if strings.TrimSpace(warmup) == "" {
+			return m, propsCmd
+		}
+		maxTokens := m.config.WarmupMaxTokens
+		if maxTokens <= 0 {
+			maxTokens = defaultWarmupMaxTokens
+		}
+		m.warmupChan = make(chan warmupChunk, 64)
+		m.warmupText = ""
+		m.warmupActive = true
+		session := context.Background()
+		if m.process != nil {
+			session = m.process.ctx
+		}
+		return m, tea.Batch(
+			propsCmd,
+			startWarmupCmd(session, m.currentPort, m.servedPrompt.systemText(), warmup, maxTokens, m.warmupChan),
+			waitForWarmupChunk(m.warmupChan),
+		)
+
+	case warmupChunkMsg:
+		if msg.ch != m.warmupChan {
+			return m, nil
+		}
+		if msg.chunk.err != nil {
+			m.warmupActive = false
+			m.warmupText = ""
+			line := fmt.Sprintf("[warmup] ERROR: %v", msg.chunk.err)
+			_, _ = m.logBuffer.WriteString(m.colorLog(line) + "\n")
+			m.logsViewport.SetContent(m.logsContent())
+			return m, waitForWarmupChunk(m.warmupChan)
+		}
+		m.warmupText += msg.chunk.text
+		return m, waitForWarmupChunk(m.warmupChan)
+
+	case warmupDoneMsg:
+		if msg.ch != m.warmupChan {
+			return m, nil
+		}
+		m.warmupActive = false
+		m.warmupChan = nil
+		if m.warmupText != "" {
+			line := "[warmup] " + strings.Join(strings.Fields(m.warmupText), " ")
+			_, _ = m.logBuffer.WriteString(m.colorLog(line) + "\n")
+			m.logsViewport.SetContent(m.logsContent())
+			m.logsViewport.GotoBottom()
+		}
+		return m, nil
+
+	case abChunkMsg:
+		return m.handleABChunk(msg)
+
+	case abDoneMsg:
+		return m.handleABDone(msg), nil
+
+	case abContextMsg:
+		return m.handleABContext(msg), nil
+
+	case previewDwellMsg:
+		if msg.path == m.selectedPath() {
+			m.previewPath = msg.path
+		}
+		return m, nil
+	case pathsCheckedMsg:
+		return m.withAvailability(msg.results), nil
+
+	case smokeDoneMsg:
+		results := make(map[string]smokeResult, len(m.smokeResults)+1)
+		for k, v := range m.smokeResults {
+			results[k] = v
+		}
+		results[msg.path] = msg.result
+		m.smokeResults = results
+		if msg.result.Passed {
+			m.logEvent("[smoke] Test " + msg.result.summary())
+		} else {
+			m.logEvent("[smoke] ERROR: test failed: " + msg.result.Error)
+		}
+		m.modelsList.SetDelegate(m.listDelegate())
+		return m, saveSmokeResultsCmd(m.historyDir, results)
+
+	case smokeSavedMsg:
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Could not save smoke test results: %v", msg.err)
+		}
+		return m, nil
+
+	case propsDiffMsg:
+		if msg.modelName != m.currentModelName {
+			return m, nil
+		}
+		if msg.defaults != nil {
+			m.servedDefaults = msg.defaults
+		}
+		var lines []string
+		if msg.err != nil {
+			lines = []string{m.colorLog(fmt.Sprintf("[props] Could not capture /props: %v", msg.err))}
+		} else {
+			lines = m.renderPropsDiff(msg)
+		}
+		_, _ = m.logBuffer.WriteString(strings.Join(lines, "\n") + "\n")
+		m.logsViewport.SetContent(m.logsContent())
+		m.logsViewport.GotoBottom()
+		return m, nil
+
+	case shareStartedMsg:
+		return m.handleShareStarted(msg)
+
+	case shareURLMsg:
+		return m.handleShareURL(msg)
+
+	case shareExitedMsg:
+		return m.handleShareExited(msg)
+
+	case generationDefaultsMsg:
+		if m.samplingView == nil || msg.modelName != m.samplingView.modelName {
+			return m, nil
+		}
+		v := *m.samplingView
+		v.err = msg.err
+		if msg.err == nil && msg.modelName == m.currentModelName {
+			m.servedDefaults = msg.defaults
+		}
+		m.samplingView = &v
+		return m, nil
+
+	case benchResultsLoadedMsg:
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Benchmark history error: %v", msg.err)
+			return m, nil
+		}
+		m.benchResults = msg.results
+		return m, nil
+
+	case benchDoneMsg:
+		m.benchCancel = nil
+		m.benchModel = ""
+		if msg.history != nil {
+			m.benchResults = msg.history
+		}
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Benchmark of %s: %v", msg.model, msg.err)
+			_, _ = m.logBuffer.WriteString(m.colorLog(fmt.Sprintf("[bench] ERROR (%s): %v", msg.model, msg.err)) + "\n")
+			m.eventError("bench", msg.model, fmt.Sprintf("Benchmark failed: %v", msg.err))
+		} else {
+			m.statusLineText = fmt.Sprintf("Benchmark of %s done - [X] to view matrix", msg.model)
+			m.event("bench", msg.model, "Benchmark done: "+pluralize(len(msg.results), "result"))
+			for _, r := range msg.results {
+				_, _ = m.logBuffer.WriteString(fmt.Sprintf("[bench] %s %s: %.1f t/s\n", msg.model, r.column(), r.TokensSec))
+			}
+		}
+		m.logsViewport.SetContent(m.logsContent())
+		m.logsViewport.GotoBottom()
+		return m, nil
+
+	case benchSweepStepMsg:
+		return m.handleSweepStep(msg)
+
+	case benchSweepsLoadedMsg:
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Sweep history error: %v", msg.err)
+			return m, nil
+		}
+		m.benchSweeps = msg.sweeps
+		return m, nil
+
+	case benchSweepSavedMsg:
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Saving the sweep failed: %v", msg.err)
+		}
+		if msg.sweeps != nil {
+			m.benchSweeps = msg.sweeps
+		}
+		return m, nil
+
+	case benchExportedMsg:
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("CSV export failed: %v", msg.err)
+		} else {
+			m.statusLineText = "Exported benchmark matrix to " + msg.path
+		}
+		return m, nil
+
+	case externalHealthMsg:
+		return m.handleExternalHealth(msg)
+
+	case hubSearchMsg, hubFilesMsg, hubDownloadDoneMsg:
+		return m.handleHubMsg(msg)
+
+	case migrationTickMsg:
+		if m.migration == nil {
+			return m, nil
+		}
+		m.statusLineText = fmt.Sprintf("Moving old server logs out of %s: %s so far...", m.migration.dir, pluralize(int(m.migration.moved.Load()), "file"))
+		return m, migrationTickCmd()
+
+	case barnLogsMigratedMsg:
+		m.migration = nil
+		if note := migrationNote(msg.moved, msg.err); note != "" {
+			m.statusLineText = note
+			m.logEvent("[paths] " + note)
+		}
+		return m, nil
+
+	case downloadTickMsg:
+		if m.download == nil {
+			return m, nil
+		}
+		m.statusLineText = "Downloading " + m.download.name + ": " + m.download.bar(20) + " " + m.download.describe()
+		return m, downloadTickCmd()
+
+	case downloadDoneMsg:
+		m.downloadCancel = nil
+		m.download = nil
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Download of %s failed: %v", msg.item.name, msg.err)
+			m.eventError("download", msg.item.name, fmt.Sprintf("Download failed: %v", msg.err))
+			return m, nil
+		}
+		// Launch the now-local copy; the next scan lists it as a regular model
+		local := msg.item
+		local.remoteURL = ""
+		m.statusLineText = "Downloaded " + local.name
+		detail := "Downloaded"
+		if p := local.provenance; p != nil && p.Scan != nil {
+			m.statusLineText += " - " + p.Scan.describe()
+			detail += ", " + p.Scan.describe()
+		}
+		m.event("download", local.name, detail)
+		next, cmd := m.requestStart(local)
+		return next, tea.Batch(cmd, next.scanModelsCmd())
+
+	case logsPrunedMsg:
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Log cleanup error: %v", msg.err)
+		} else if msg.removed > 0 {
+			m.statusLineText = fmt.Sprintf("Log cleanup: removed %d old log file(s), freed %s", msg.removed, formatBytes(msg.freedBytes))
+		}
+		return m, nil
+
+	case updateAvailableMsg:
+		// Update checks are best-effort; failures are not worth interrupting for
+		if msg.err == nil && msg.release != nil {
+			m.availableUpdate = msg.release
+		}
+		return m, nil
+
+	case selfUpdateDoneMsg:
+		m.updating = false
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Self-update failed: %v", msg.err)
+			return m, nil
+		}
+		m.availableUpdate = nil
+		m.statusLineText = fmt.Sprintf("Updated to %s - restart llama-tui to use it", msg.version)
+		return m, nil
+
+	case stoppedMsg:
+		// This message is no longer used - cleanup happens in serverExitedMsg
+		return m, nil
+
+	case resourceUsageMsg:
+		// A previous server's poll loop, or one replaced by a faster one,
+		// ends here
+		if msg.process != m.process || (m.attached != nil && msg.pid != m.attached.pid) || msg.seq != m.metricsSeq {
+			return m, nil
+		}
+		m.recordUsage(msg)
+		// Schedule next poll if server is still running
+		if m.server.serving() {
+			return m, sampleUsageCmd(msg.process, msg.pid, msg.seq, m.metricsInterval())
+		}
+		return m, nil
+
+	case serverHelpLoadedMsg:
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Flag search unavailable: %v", msg.err)
+			return m, nil
+		}
+		m.serverFlags = msg.flags
+		m.statusLineText = fmt.Sprintf("Loaded %d llama-server flags", len(msg.flags))
+		if m.form != nil && (m.formPurpose == formLaunchOptions || m.formPurpose == formModelConfig) {
+			search := newFlagSearch(m.serverFlags)
+			m.flagSearch = &search
+		}
+		return m, nil
+
+	case diagnosticsDoneMsg:
+		m.diagnostics = msg.checks
+		if !msg.startup {
+			m.statusLineText = "Diagnostics complete"
+		}
+		if !msg.startup || m.config.StartupChecks == "always" || diagnosticsFailed(msg.checks) {
+			m.showDiagnostics = true
+		}
+		return m, nil
+
+	case tailLineMsg:
+		// Ignore lines from a tail that has since been stopped or replaced
+		if msg.ch != m.tailChan {
+			return m, nil
+		}
+		if msg.done || msg.line.err != nil {
+			if msg.line.err != nil {
+				m.logEvent(fmt.Sprintf("[tail] ERROR: %v", msg.line.err))
+				m.statusLineText = fmt.Sprintf("Tail stopped: %v", msg.line.err)
+			}
+			m.tailCancel()
+			m.tailPath, m.tailChan, m.tailCancel = "", nil, nil
+			return m, nil
+		}
+		if msg.line.caughtUp {
+			m.restoreLogsScroll()
+			return m, waitForTailLine(m.tailChan)
+		}
+		text := msg.line.text
+		if m.server.running() && m.attached == nil {
+			// Keep tailed lines distinguishable from the server's own
+			text = "[" + filepath.Base(m.tailPath) + "] " + text
+		}
+		m.appendLogLine(text)
+		var metricsCmd tea.Cmd
+		if m.attached != nil {
+			// The attached server's log is the only sign of its activity
+			metricsCmd = m.noteGenerationLine(text)
+		}
+		return m, tea.Batch(metricsCmd, waitForTailLine(m.tailChan))
+
+	case sleepWatchStartedMsg:
+		if msg.err != nil {
+			m.logEvent(fmt.Sprintf("[power] Can't act before sleep: %v", msg.err))
+		}
+		if msg.watch == nil {
+			return m, nil
+		}
+		m.sleepWatch = msg.watch
+		return m, waitForSleepSignal(msg.watch)
+
+	case sleepSignalMsg:
+		return m.handleSleepSignal(msg)
+
+	case powerStateMsg:
+		// Compare wall clocks: the monotonic clock stops while asleep
+		at := msg.at.Round(0)
+		if !m.lastPowerCheck.IsZero() {
+			if gap := at.Sub(m.lastPowerCheck); gap > 3*powerCheckInterval {
+				m.logEvent(fmt.Sprintf("[power] System resumed from sleep (about %s)", gap.Round(time.Minute)))
+			}
+		}
+		m.lastPowerCheck = at
+		next := powerCheckCmd(powerCheckInterval)
+		if msg.err != nil || !msg.state.known {
+			return m, next
+		}
+		policy := m.config.Power
+		low := msg.state.onBattery && msg.state.percent < policy.BatteryThreshold
+		switch {
+		case low && m.server.serving() && !m.powerPaused:
+			if policy.stops() {
+				m.powerStopped = &startupAction{model: m.currentModelName, port: m.currentPort, args: m.launchArgs}
+				m.logEvent(fmt.Sprintf("[power] Battery at %d%% - stopping server", msg.state.percent))
+				var cmd tea.Cmd
+				m, cmd = m.handleStop()
+				return m, tea.Batch(cmd, next)
+			}
+			if err := m.process.setSuspended(true); err != nil {
+				m.logEvent(fmt.Sprintf("[power] Could not pause server: %v", err))
+				return m, next
+			}
+			m.powerPaused = true
+			m.statusLineText = "Server paused on low battery"
+			m.logEvent(fmt.Sprintf("[power] Battery at %d%% - server paused", msg.state.percent))
+		case !msg.state.onBattery && m.powerPaused:
+			if err := m.process.setSuspended(false); err != nil {
+				m.logEvent(fmt.Sprintf("[power] Could not resume server: %v", err))
+				return m, next
+			}
+			m.powerPaused = false
+			m.statusLineText = "Server resumed on AC power"
+			m.logEvent("[power] On AC power - server resumed")
+		case !msg.state.onBattery && policy.ResumeOnAC && m.powerStopped != nil && !m.server.serving():
+			m.startup, m.powerStopped = m.powerStopped, nil
+			m.logEvent("[power] On AC power - restarting " + m.startup.model)
+			var cmd tea.Cmd
+			m, cmd = m.runStartupAction()
+			return m, tea.Batch(cmd, next)
+		}
+		return m, next
+
+	case spinner.TickMsg:
+		// Animate only while in a transitional state; dropping the tick
+		// stops the animation
+		if !m.server.transitional() {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
+	case tensorsLoadedMsg:
+		if m.tensorView == nil || m.tensorView.path != msg.path {
+			return m, nil
+		}
+		v := *m.tensorView
+		v.loading, v.tensors, v.err = false, msg.tensors, msg.err
+		m.tensorView = &v
+		return m, nil
+
+	case sessionRecordedMsg:
+		if msg.err != nil {
+			m.eventError("history", msg.model, "Could not record the session: "+msg.err.Error())
+		}
+		return m, nil
+
+	case layoutsSavedMsg:
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Could not save the layout: %v", msg.err)
+		}
+		return m, nil
+
+	case pinsSavedMsg:
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Could not save pinned models: %v", msg.err)
+		}
+		return m, nil
+
+	case sideStartedMsg, sideLogMsg, sideReadyMsg, sideExitedMsg:
+		return m.handleSideServerMsg(msg)
+
+	case standbyStartedMsg, standbyReadyMsg, standbyLogMsg:
+		return m.handleStandbyMsg(msg)
+
+	case socketOpenedMsg:
+		m.socketOpening = false
+		switch {
+		case msg.err != nil:
+			m.logEvent(fmt.Sprintf("[socket] ERROR: %v", msg.err))
+		case !m.server.serving() || m.socket != nil:
+			// The server stopped while the socket was opened
+			msg.socket.close()
+		default:
+			m.socket = msg.socket
+			m.socket.setTarget(m.currentPort)
+			m.logEvent("[socket] Serving on unix:" + msg.path)
+		}
+		return m, nil
+
+	case proxyStatsMsg:
+		m.latencySamples = msg.samples
+		m.proxyInFlight = msg.inFlight
+		m.mirrorSamples, m.mirrorErr = msg.mirrorSamples, msg.mirrorErr
+		m.proxyRates, m.proxyRejected = msg.rates, msg.rejected
+		return m, proxyStatsCmd(m.proxy)
+
+	case proxyStoppedMsg:
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Request proxy stopped: %v", msg.err)
+		}
+		return m, nil
+
+	case logStreamStoppedMsg:
+		m.statusLineText = fmt.Sprintf("Log stream stopped: %v", msg.err)
+		m.logStream = nil
+		return m, nil
+
+	case capacityDetectedMsg:
+		m.capacity = msg.capacity
+		return m, nil
+
+	case notifyFailedMsg:
+		m.statusLineText = fmt.Sprintf("Notification failed: %v", msg.err)
+		m.eventError("notify", "", msg.err.Error())
+		return m, waitForNotifyFailure(m.notifier)
+
+	case controlStoppedMsg:
+		m.statusLineText = fmt.Sprintf("Control API stopped: %v", msg.err)
+		return m, nil
+
+	case controlRequestMsg:
+		return m.handleControlRequest(msg.req)
+
+	case composeExportedMsg:
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Could not export compose file: %v", msg.err)
+		} else {
+			m.statusLineText = "Compose file written to " + msg.path + " (docker run equivalent inside)"
+		}
+		return m, nil
+
+	case diagnosticsExportedMsg:
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Could not export diagnostics: %v", msg.err)
+		} else {
+			m.statusLineText = "Diagnostics exported to " + msg.path
+		}
+		return m, nil
+
+	case modelConfigsLoadedMsg:
+		m.modelConfigs = msg.configs
+		cmd := m.refreshProjectors()
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Saved launch options error: %v", msg.err)
+		}
+		return m, cmd
+
+	case eventsLoadedMsg:
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Event history unavailable: %v", msg.err)
+			return m, nil
+		}
+		// Events of this session so far come after the earlier ones
+		events := append(msg.events, m.events...)
+		if len(events) > maxEvents {
+			events = events[len(events)-maxEvents:]
+		}
+		m.events = events
+		return m, nil
+
+	case licenseAcksLoadedMsg:
+		m.licenseAcks = msg.acks
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("License acknowledgments unavailable: %v", msg.err)
+		}
+		return m, nil
+
+	case licenseAckSavedMsg:
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Could not record the license acknowledgment: %v", msg.err)
+		}
+		return m, nil
+
+	case modelConfigSavedMsg:
+		c := msg.config
+		switch {
+		case msg.err != nil:
+			m.statusLineText = fmt.Sprintf("Could not save launch options: %v", msg.err)
+			m.eventError("config", c.Model, m.statusLineText)
+			return m, nil
+		case c.empty():
+			delete(m.modelConfigs, c.Model)
+			m.statusLineText = "Forgot launch options for " + c.Model
+			m.event("config", c.Model, "Forgot its saved launch options")
+		default:
+			if m.modelConfigs == nil {
+				m.modelConfigs = map[string]modelLaunchConfig{}
+			}
+			m.modelConfigs[c.Model] = c
+			m.statusLineText = fmt.Sprintf("Saved launch options for %s: %s (applies on next start)", c.Model, m.modelConfigSummary(c.Model))
+			m.event("config", c.Model, "Saved launch options: "+m.modelConfigSummary(c.Model))
+		}
+		return m, m.refreshProjectors()
+
+	case kvOverridesSavedMsg:
+		switch {
+		case msg.err != nil:
+			m.statusLineText = fmt.Sprintf("Could not save metadata overrides: %v", msg.err)
+		case msg.count == 0:
+			m.statusLineText = "Cleared metadata overrides for " + msg.modelName
+		default:
+			m.statusLineText = fmt.Sprintf("Saved %s for %s (applies on next start)", pluralize(msg.count, "metadata override"), msg.modelName)
+		}
+		return m, nil
+
+	case healthMsg:
+		return m.handleHealth(msg)
+
+	case clientsMsg:
+		// Drop samples for a server that has since stopped or moved, and
+		// those of a loop started before the latest
+		if msg.seq != m.clientsSeq || !m.server.running() || msg.port != m.currentPort {
+			return m, nil
+		}
+		if msg.err != nil {
+			// Leave the count unknown rather than showing a misleading zero
+			m.clientCount = -1
+		} else {
+			m.clientCount = msg.count
+		}
+		return m, pollClientsCmd(msg.port, msg.seq, max(m.metricsInterval(), clientPollInterval))
+
+	case serverExitedMsg:
+		// A server replaced by a promoted standby exits in the background
+		if msg.exitChan != m.exitChan {
+			return m, nil
+		}
+		// Cleanup state - this is where we actually confirm the server has stopped
+		quitting := m.server == serverQuitting
+		neverReady := m.server == serverLoading
+		exitedModel, exitedPort := m.currentModelName, m.currentPort
+		var argv []string
+		if m.process != nil {
+			argv = m.process.args
+		}
+		// Show output still buffered, which often explains a quick exit
+	drain:
+		for m.logChan != nil {
+			select {
+			case line, ok := <-m.logChan:
+				if !ok {
+					break drain
+				}
+				m.appendLogLine(line)
+			default:
+				break drain
+			}
+		}
+		if !m.server.stopping() && msg.err != nil && !errors.Is(msg.err, context.Canceled) {
+			m.server = serverCrashed
+		} else {
+			m.server = serverStopped
+		}
+		sessionCmd := m.endSession(m.server == serverCrashed)
+		// The exit output stays in view until the selection moves
+		m.previewPath = ""
+		m.serverStartedAt = time.Time{}
+		if portNum, err := strconv.Atoi(m.currentPort); err == nil {
+			m.ports = m.ports.without(portNum)
+		}
+		m.currentModelName = ""
+		m.currentPort = ""
+		m.proxy.setTarget("")
+		m.sampling, m.servedDefaults, m.samplingView = samplingOverrides{}, nil, nil
+		m.proxy.setSampling(nil)
+		m.proxy.setSystemPrompt("")
+		if m.share != nil {
+			m.share.stop()
+			m.logEvent("[share] Closing the quick share tunnel with the server")
+		}
+		m.socket.close()
+		m.socket = nil
+		m.currentMMProj = ""
+		m.servingPath = ""
+		m.modelsList.SetDelegate(m.listDelegate())
+		m.process = nil
+		m.logChan = nil
+		m.exitChan = nil
+		m.readyChan = nil
+		m.warmupChan = nil
+		m.warmupText = ""
+		m.warmupActive = false
+		m.resetUsage()
+		m.clientCount = 0
+		m.powerPaused = false
+		if m.logFile != nil {
+			_ = m.logFile.Close()
+			m.logFile = nil
+		}
+		m.logFilePath = ""
+		if m.server == serverCrashed {
+			m.eventError("crash", exitedModel, fmt.Sprintf("Exited on port %s: %v", exitedPort, msg.err))
+		} else {
+			m.event("stop", exitedModel, "Stopped on port "+exitedPort)
+		}
+		m.abandonFailover("exited before it was ready")
+		failedOver := false
+		if m.server == serverCrashed && !neverReady && m.restartAfterStop == nil {
+			m, failedOver = m.startFailover(exitedModel, exitedPort, msg.err)
+		}
+		if msg.err != nil && !errors.Is(msg.err, context.Canceled) {
+			if !failedOver {
+				m.statusLineText = fmt.Sprintf("Server stopped (error: %v)", msg.err)
+			}
+			stopMsg := fmt.Sprintf("\n[ui] Server stopped with error: %v\n", msg.err)
+			coloredStopMsg := m.colorLog(stopMsg)
+			_, _ = m.logBuffer.WriteString(coloredStopMsg)
+			m.logsViewport.SetContent(m.logsContent())
+			if m.server == serverCrashed && !failedOver && !m.offerOOMRecovery(exitedModel, argv, msg.err) && neverReady {
+				m.offerFlagRetry(exitedModel, argv)
+			}
+		} else {
+			m.statusLineText = "Server stopped"
+			stopMsg := "\n[ui] Server stopped successfully\n"
+			coloredStopMsg := m.colorLog(stopMsg)
+			_, _ = m.logBuffer.WriteString(coloredStopMsg)
+			m.logsViewport.SetContent(m.logsContent())
+		}
+		m.hung = ""
+		// If quit was pending, now quit
+		if quitting {
+			return m, tea.Sequence(sessionCmd, tea.Quit)
+		}
+		if failedOver {
+			next, cmd := m.relaunchFailover()
+			return next, tea.Batch(sessionCmd, pruneLogsCmd(m.logsDir, m.config.LogRetention), cmd)
+		}
+		if m.restartAfterStop != nil {
+			m.startup, m.restartAfterStop = m.restartAfterStop, nil
+			next, cmd := m.runStartupAction()
+			return next, tea.Batch(sessionCmd, pruneLogsCmd(m.logsDir, m.config.LogRetention), cmd)
+		}
+		return m, tea.Batch(sessionCmd, pruneLogsCmd(m.logsDir, m.config.LogRetention))
+
+	case logLineMsg:
+		// Output of a server replaced by a promoted standby
+		if m.logChan != nil && msg.ch != m.logChan {
+			return m, nil
+		}
+		m.appendServerLogLine(msg.text)
+		m.noteServerLine(msg.text)
+		m.lastServerLogAt = time.Now()
+		m.noteSpeculativeLine(msg.text)
+		m.noteSLOLine(msg.text)
+		metricsCmd := m.noteGenerationLine(msg.text)
+		m, hfCmd := m.trackHFLog(msg.text)
+		if m.server.running() {
+			return m, tea.Batch(hfCmd, metricsCmd, m.waitForLogLine())
+		}
+		return m, hfCmd
+
+	case hfDownloadTickMsg:
+		repo := m.servedHFRepo()
+		if repo == "" || m.server != serverLoading {
+			return m, nil
+		}
+		if msg.bytes > 0 {
+			m.statusLineText = fmt.Sprintf("Downloading %s into %s: %s", repo, llamaCacheDir(), formatBytes(uint64(msg.bytes)))
+		}
+		return m, hfDownloadTickCmd()
+
+	case logRefreshMsg:
+		if m.logsDirty {
+			m.logsDirty = false
+			m.logsViewport.SetContent(m.logsContent())
+			m.logsViewport.GotoBottom()
+		}
+		return m, logRefreshTickCmd()
+
+	case confirmTickMsg:
+		if msg.seq != m.confirmSeq || m.confirmAction == confirmNone {
+			return m, nil
+		}
+		if time.Now().Before(m.confirmDeadline) {
+			return m, confirmTickCmd(msg.seq)
+		}
+		m.confirmAction = confirmNone
+		m.pendingLaunch = nil
+		m.statusLineText = "Confirmation timed out - nothing was done"
+		return m, nil
+
+	case sessionsLoadedMsg:
+		m.sessions = msg.sessions
+		m.refreshReliability()
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Session history error: %v", msg.err)
+		}
+		return m, nil
+
+	case attachHealthMsg:
+		return m.handleAttachHealth(msg)
+
+	case slotFilesMsg:
+		if m.slotView == nil {
+			return m, nil
+		}
+		v := *m.slotView
+		v.files, v.err = msg.files, msg.err
+		if v.cursor >= len(v.files) {
+			v.cursor = max(0, len(v.files)-1)
+		}
+		m.slotView = &v
+		return m, nil
+
+	case slotDeletedMsg:
+		note := "Deleted " + msg.name
+		if msg.err != nil {
+			note = fmt.Sprintf("Could not delete %s: %v", msg.name, msg.err)
+		}
+		if m.slotView == nil {
+			m.statusLineText = note
+			return m, nil
+		}
+		v := *m.slotView
+		v.note = note
+		m.slotView = &v
+		return m, scanSlotFilesCmd(v.dir)
+
+	case slotActionMsg:
+		var note string
+		switch {
+		case msg.err != nil:
+			note = fmt.Sprintf("Could not %s %s: %v", msg.action, msg.filename, msg.err)
+		case msg.action == "save":
+			note = fmt.Sprintf("Saved %s to %s", pluralize(msg.tokens, "token"), msg.filename)
+		default:
+			note = fmt.Sprintf("Restored %s from %s", pluralize(msg.tokens, "token"), msg.filename)
+		}
+		m.logEvent("[slots] " + note)
+		if m.slotView == nil {
+			m.statusLineText = note
+			return m, nil
+		}
+		v := *m.slotView
+		v.note = note
+		m.slotView = &v
+		return m, scanSlotFilesCmd(v.dir)
+
+	case hfCacheScannedMsg:
+		if m.cacheView == nil {
+			return m, nil
+		}
+		v := *m.cacheView
+		v.files, v.err = msg.files, msg.err
+		if v.cursor >= len(v.files) {
+			v.cursor = max(0, len(v.files)-1)
+		}
+		m.cacheView = &v
+		return m, nil
+
+	case templateRenderedMsg:
+		if m.templateSandbox == nil || m.templateSandbox.item.name != msg.model {
+			return m, nil
+		}
+		v := *m.templateSandbox
+		v.rendering, v.err = false, msg.err
+		if msg.err == nil {
+			v.prompt, v.tokens, v.via, v.offset = msg.prompt, msg.tokens, msg.via, 0
+		}
+		m.templateSandbox = &v
+		return m, nil
+
+	case promptEditedMsg:
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Editor: %v", msg.err)
+		} else {
+			m.statusLineText = "Saved prompt " + msg.name
+		}
+		return m, loadPromptsCmd(msg.name)
+
+	case launchFormMetaMsg:
+		// Dropped if the form was closed or is for another model by now
+		item, ok := m.launchFormModel()
+		if msg.err != nil || m.form == nil || !ok || item.ggufPath() != msg.path {
+			return m, nil
+		}
+		form := m.form.withModelMetadata(msg.meta)
+		m.form = &form
+		return m, nil
+
+	case promptsLoadedMsg:
+		return m.showPrompts(msg), nil
+
+	case promptSavedMsg:
+		return m.promptSaved(msg)
+
+	case templateSampleEditedMsg:
+		if m.templateSandbox == nil {
+			return m, nil
+		}
+		if msg.err != nil {
+			v := *m.templateSandbox
+			v.err = fmt.Errorf("editor: %w", msg.err)
+			m.templateSandbox = &v
+			return m, nil
+		}
+		return m.renderTemplate()
+
+	case downloadSweepScannedMsg:
+		if m.downloadSweep == nil || m.downloadSweep.dir != msg.dir {
+			return m, nil
+		}
+		v := *m.downloadSweep
+		v.files, v.err = msg.files, msg.err
+		v.cursor = min(v.cursor, max(len(v.files)-1, 0))
+		m.downloadSweep = &v
+		return m, nil
+
+	case downloadSweepMovedMsg:
+		if msg.moved > 0 {
+			m.logEvent(fmt.Sprintf("[sweep] moved %s into %s", pluralize(msg.moved, "model"), m.barnDir))
+		}
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Moved %s, then failed: %v", pluralize(msg.moved, "model"), msg.err)
+		} else {
+			m.statusLineText = fmt.Sprintf("Moved %s into %s", pluralize(msg.moved, "model"), m.barnDir)
+		}
+		cmds := []tea.Cmd{m.scanModelsCmd()}
+		if m.downloadSweep != nil {
+			v := *m.downloadSweep
+			v.moving = false
+			v.note = m.statusLineText
+			m.downloadSweep = &v
+			cmds = append(cmds, scanDownloadSweepCmd(v.dir, m.barnDir))
+		}
+		return m, tea.Batch(cmds...)
+
+	case hfCacheDeletedMsg:
+		name := filepath.Base(msg.file.path)
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Could not delete %s: %v", name, msg.err)
+		} else {
+			m.statusLineText = fmt.Sprintf("Deleted %s, freeing %s", name, formatBytes(uint64(msg.file.size)))
+			m.logEvent("[hf] deleted " + msg.file.path)
+		}
+		if m.cacheView != nil {
+			v := *m.cacheView
+			v.note = m.statusLineText
+			m.cacheView = &v
+		}
+		cmds := []tea.Cmd{scanHFCacheCmd()}
+		if msg.err == nil {
+			entries := append([]hfRepoEntry(nil), m.hfRepos...)
+			changed := false
+			for i, e := range entries {
+				if e.CachePath == msg.file.path {
+					entries[i].CachePath = ""
+					changed = true
+				}
+			}
+			if changed {
+				// The repo stays listed and downloads again on its next launch
+				m.hfRepos = entries
+				cmds = append(cmds, saveHFReposCmd(m.historyDir, entries), m.scanModelsCmd())
+			}
+		}
+		return m, tea.Batch(cmds...)
+
+	case hfReposSavedMsg:
+		if msg.err != nil {
+			m.statusLineText = fmt.Sprintf("Could not save Hugging Face repos: %v", msg.err)
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		keyStr := msg.String()
+
+		// The models directory input captures all keys while editing
+		if m.barnInput.Focused() {
+			switch keyStr {
+			case "enter":
+				dir := strings.TrimSpace(m.barnInput.Value())
+				m.barnInput.Blur()
+				if dir == "" {
+					m.statusLineText = "Models directory unchanged"
+					return m, nil
+				}
+				dir, err := expandHome(m.homeDir, dir)
+				if err != nil {
+					m.statusLineText = fmt.Sprintf("Models directory unchanged: %v", err)
+					return m, nil
+				}
+				migrate := m.setBarnDir(filepath.Clean(dir))
+				m.statusLineText = "Scanning for models in " + m.barnDir + "..."
+				return m, tea.Batch(m.scanModelsCmd(), migrate)
+			case "esc":
+				m.barnInput.Blur()
+				m.statusLineText = "Models directory unchanged"
+				return m, nil
+			case "ctrl+c":
+				return m.handleQuit()
+			}
+			var cmd tea.Cmd
+			m.barnInput, cmd = m.barnInput.Update(msg)
+			return m, cmd
+		}
+		// So does the bookmark label
+		if m.bookmarkInput.Focused() {
+			switch keyStr {
+			case "enter":
+				m.bookmarkInput.Blur()
+				return m.saveBookmark(strings.TrimSpace(m.bookmarkInput.Value())), nil
+			case "esc":
+				m.bookmarkInput.Blur()
+				m.statusLineText = "No bookmark added"
+				return m, nil
+			case "ctrl+c":
+				return m.handleQuit()
+			}
+			var cmd tea.Cmd
+			m.bookmarkInput, cmd = m.bookmarkInput.Update(msg)
+			return m, cmd
+		}
+		// An open form captures all keys until applied or cancelled
+		if m.form != nil {
+			if keyStr == "ctrl+c" {
+				return m.handleQuit()
+			}
+			if m.flagSearch != nil {
+				search, chosen, done, cmd := m.flagSearch.Update(msg)
+				m.flagSearch = &search
+				if done {
+					m.flagSearch = nil
 				}
-				pid := int32(serverCmd.Process.Pid)
-				proc, err := process.NewProcess(pid)
-				if err != nil {
-					return nil
+				if chosen != nil {
+					m.form.appendToField("extra", chosen.long())
 				}
-				cpuPercent, err := proc.CPUPercent()
-				if err != nil {
-					cpuPercent = 0
+				return m, cmd
+			}
+			if m.formPurpose == formKVOverrides && (keyStr == "ctrl+n" || keyStr == "ctrl+d") {
+				if keyStr == "ctrl+n" {
+					m.form.addKVOverrideRow()
+				} else {
+					m.form.deleteKVOverrideRow()
 				}
-				memInfo, err := proc.MemoryInfo()
-				if err != nil {
-					return resourceUsageMsg{
-						cpuPercent: cpuPercent,
-						memRSSBytes: 0,
-					}
+				return m, nil
+			}
+			if keyStr == "ctrl+f" && (m.formPurpose == formLaunchOptions || m.formPurpose == formModelConfig) {
+				if m.serverFlags == nil {
+					m.statusLineText = "Reading llama-server --help..."
+					return m, loadServerHelpCmd()
 				}
-				return resourceUsageMsg{
-					cpuPercent: cpuPercent,
-					memRSSBytes: memInfo.RSS,
+				search := newFlagSearch(m.serverFlags)
+				m.flagSearch = &search
+				return m, nil
+			}
+			form, result, cmd := m.form.Update(msg)
+			m.form = &form
+			switch result {
+			case formSubmitted:
+				return m.applyForm()
+			case formCancelled:
+				if m.formPurpose == formPresetVars {
+					m.statusLineText = "Launch cancelled"
+					m.presetLaunch = nil
+				} else if m.formPurpose == formPrompt {
+					m.statusLineText = "No prompt added"
+				} else {
+					m.statusLineText = "Options unchanged"
 				}
-			})
+				m.form, m.formPurpose = nil, formNone
+			}
+			return m, cmd
 		}
-		return m, nil
-
-	case serverExitedMsg:
-		// Cleanup state - this is where we actually confirm the server has stopped
-		m.serverRunning = false
-		m.serverStopping = false
-		m.currentModelName = ""
-		m.currentPort = ""
-		m.serverCmd = nil
-		m.serverCancel = nil
-		m.logChan = nil
-		m.exitChan = nil
-		m.cpuPercent = 0
-		m.memRSSBytes = 0
-		if m.logFile != nil {
-			_ = m.logFile.Close()
-			m.logFile = nil
+		if m.quick != nil {
+			if next, cmd, ok := m.handleQuickKey(msg); ok {
+				return next, cmd
+			}
 		}
-		m.logFilePath = ""
-		if msg.err != nil && !errors.Is(msg.err, context.Canceled) {
-			m.statusLineText = fmt.Sprintf("Server stopped (error: %v)", msg.err)
-			stopMsg := fmt.Sprintf("\n[ui] Server stopped with error: %v\n", msg.err)
-			coloredStopMsg := m.colorLog(stopMsg)
-			_, _ = m.logBuffer.WriteString(coloredStopMsg)
-			m.logsViewport.SetContent(m.logBuffer.String())
-		} else {
-			m.statusLineText = "Server stopped"
-			stopMsg := "\n[ui] Server stopped successfully\n"
-			coloredStopMsg := m.colorLog(stopMsg)
-			_, _ = m.logBuffer.WriteString(coloredStopMsg)
-			m.logsViewport.SetContent(m.logBuffer.String())
+		// Typing a filter query goes to the list, not the shortcuts
+		if m.modelsList.FilterState() == list.Filtering && keyStr != "ctrl+c" {
+			var cmd tea.Cmd
+			prev := m.selectedPath()
+			m.modelsList, cmd = m.modelsList.Update(msg)
+			return m, tea.Batch(cmd, m.selectionMoved(prev))
 		}
-		// If quit was pending, now quit
-		if m.pendingQuit {
-			return m, tea.Quit
+		// The focused port input steps its port instead of moving the list
+		if m.portInput.Focused() && !m.keyOverlayOpen() {
+			switch keyStr {
+			case "up", "+":
+				return m.stepPortInput(1), nil
+			case "down", "-":
+				return m.stepPortInput(-1), nil
+			case "tab":
+				// Left to the timeline, stats, and sweep toggles on their
+				// screens
+				if !m.showBenchMatrix && !m.showStats && !m.showTimeline {
+					return m.nextFreePortInput(), nil
+				}
+			}
 		}
-		return m, nil
-
-	case logLineMsg:
-		// Append to buffer (with trimming to soft limit)
-		coloredLine := m.colorLog(msg.text)
-		_, _ = m.logBuffer.WriteString(coloredLine)
-		_, _ = m.logBuffer.WriteString("\n")
-		if m.logBuffer.Len() > logBufferSoftLimitCharacters {
-			// Trim oldest half to keep memory bounded
-			data := m.logBuffer.Bytes()
-			start := len(data) / 2
-			var newBuf bytes.Buffer
-			_, _ = newBuf.Write(data[start:])
-			m.logBuffer = newBuf
-		}
-
-		m.logsViewport.SetContent(m.logBuffer.String())
-		m.logsViewport.GotoBottom()
-		if m.serverRunning {
-			return m, m.waitForLogLine()
+		// A confirmation that timed out (its tick may still be on the way)
+		// no longer counts
+		if m.confirmAction != confirmNone && time.Now().After(m.confirmDeadline) {
+			m.confirmAction = confirmNone
+			m.pendingLaunch = nil
 		}
-		return m, nil
-
-	case tea.KeyMsg:
 		// Cancel any pending confirmation if a non-confirm key is pressed
 		// (except esc which is handled separately, and the matching confirm key)
-		keyStr := msg.String()
 		if m.confirmAction != confirmNone && keyStr != "esc" &&
 			!(m.confirmAction == confirmQuit && keyStr == "q") &&
-			!(m.confirmAction == confirmStop && keyStr == "s") {
+			!(m.confirmAction == confirmStop && keyStr == "s") &&
+			!(m.confirmAction == confirmLaunch && keyStr == "enter") &&
+			!(m.confirmAction == confirmStopAll && keyStr == "ctrl+k") &&
+			!(m.confirmAction == confirmDeleteCache && keyStr == "d") &&
+			!(m.confirmAction == confirmDeleteSlot && keyStr == "d") &&
+			!(m.confirmAction == confirmCopyLogs && (keyStr == "1" || keyStr == "2")) {
 			m.confirmAction = confirmNone
+			m.pendingLaunch = nil
+		}
+		if m.confirmAction == confirmCopyLogs && keyStr != "esc" {
+			return m.handleCopyLogsChoice(keyStr)
+		}
+		if m.observerBlocks(keyStr) || m.basicBlocks(keyStr) {
+			return m, nil
+		}
+		// The sampling panel captures keys while open
+		if m.samplingView != nil && keyStr != "ctrl+c" {
+			return m.handleSamplingKey(keyStr)
+		}
+		// The cache screen captures keys while open
+		if m.cacheView != nil && keyStr != "ctrl+c" {
+			return m.handleHFCacheKey(keyStr)
+		}
+		if m.downloadSweep != nil && keyStr != "ctrl+c" {
+			return m.handleDownloadSweepKey(keyStr)
+		}
+		if m.templateSandbox != nil && keyStr != "ctrl+c" {
+			return m.handleTemplateSandboxKey(msg)
+		}
+		if m.abChat != nil && keyStr != "ctrl+c" {
+			return m.handleABChatKey(msg)
+		}
+		if m.slotView != nil && keyStr != "ctrl+c" {
+			return m.handleSlotKey(keyStr)
+		}
+		if m.hub != nil && keyStr != "ctrl+c" && keyStr != "ctrl+k" {
+			return m.handleHubKey(msg)
+		}
+		if m.serversView != nil && keyStr != "ctrl+c" {
+			return m.handleServersKey(keyStr)
+		}
+		if m.jsonView != nil && keyStr != "ctrl+c" {
+			return m.handleJSONViewKey(keyStr)
+		}
+		if m.bookmarkList != nil && keyStr != "ctrl+c" {
+			return m.handleBookmarkListKey(keyStr)
+		}
+		if m.componentMenu != nil && keyStr != "ctrl+c" {
+			return m.handleComponentMenuKey(keyStr), nil
+		}
+		if m.eventsView != nil && keyStr != "ctrl+c" {
+			return m.handleEventsKey(keyStr), nil
+		}
+		if m.tensorView != nil && keyStr != "ctrl+c" {
+			return m.handleTensorKey(keyStr)
+		}
+		if m.promptLibrary != nil && keyStr != "ctrl+c" {
+			return m.handlePromptLibraryKey(keyStr)
+		}
+		if m.requestLogs != nil && keyStr != "ctrl+c" {
+			return m.handleRequestLogsKey(keyStr)
+		}
+		if m.showLatency {
+			if next, cmd, ok := m.handleLatencyKey(keyStr); ok {
+				return next, cmd
+			}
+		}
+		if m.startFailure != nil && keyStr != "ctrl+c" {
+			return m.handleStartFailureKey(msg)
+		}
+		if m.oomRecovery != nil && keyStr != "ctrl+c" {
+			return m.handleOOMRecoveryKey(keyStr)
+		}
+		// So does the help overlay, for its search
+		if m.showHelp && keyStr != "ctrl+c" {
+			return m.handleHelpKey(msg)
+		}
+		if m.selectionHidden(keyStr) {
+			m.statusLineText = "The models panel is hidden - [\\] shows it"
+			return m, nil
 		}
 
 		switch keyStr {
 		case "ctrl+c":
 			// ctrl+c bypasses confirmation - immediate quit
 			return m.handleQuit()
+		case "ctrl+k":
+			// Panic key: stop everything, with a single confirmation
+			if m.confirmAction == confirmStopAll {
+				m.confirmAction = confirmNone
+				return m.handleStopAll()
+			}
+			if !(m.server.serving() && m.attached == nil) && m.benchCancel == nil && m.downloadCancel == nil && m.liveSideServers() == 0 {
+				m.statusLineText = "Nothing to stop"
+				return m, nil
+			}
+			m.statusLineText = "Stop everything? Press ctrl+k again to confirm, esc to cancel"
+			return m, m.askConfirm(confirmStopAll)
 		case "q":
 			// Quit with confirmation
 			if m.confirmAction == confirmQuit {
@@ -239,18 +2122,19 @@ func (m appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m.handleQuit()
 			}
 			// First press - request confirmation
-			m.confirmAction = confirmQuit
 			m.statusLineText = "Quit requested: press q again to confirm, esc to cancel"
-			return m, nil
+			return m, m.askConfirm(confirmQuit)
 		case "r":
-			if m.serverRunning || m.serverStopping {
+			if m.server.busy() {
 				m.statusLineText = "Cannot refresh while server is running"
 				return m, nil
 			}
 			m.statusLineText = "Scanning for models..."
+			// Refreshing also reruns metadata_command
+			m.modelMeta = nil
 			return m, m.scanModelsCmd()
 		case "l":
-			if m.serverRunning || m.serverStopping {
+			if m.server.busy() {
 				m.statusLineText = "Cannot toggle logging while server is running"
 				return m, nil
 			}
@@ -263,7 +2147,7 @@ func (m appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return m, nil
 		case "p":
-			if m.serverRunning || m.serverStopping {
+			if m.server.busy() {
 				m.statusLineText = "Cannot edit port while server is running"
 				return m, nil
 			}
@@ -272,37 +2156,470 @@ func (m appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.statusLineText = "Port input unfocused"
 			} else {
 				m.portInput.Focus()
-				m.statusLineText = "Port input focused - type port number"
+				m.statusLineText = "Port input focused - type port number, ↑/↓ to step, tab for the next free port"
 			}
 			return m, nil
 		case "s":
 			// Stop with confirmation (only if server is running and not stopping)
-			if m.serverRunning && !m.serverStopping {
+			if m.server.serving() && m.attached == nil {
 				if m.confirmAction == confirmStop {
 					// Second press - actually stop
 					m.confirmAction = confirmNone
-					return m.handleStop()
+					return m.stopWithDependents("")
 				}
 				// First press - request confirmation
-				m.confirmAction = confirmStop
 				m.statusLineText = "Stop server? Press s again to confirm, esc to cancel"
-				return m, nil
+				return m, m.askConfirm(confirmStop)
 			}
 			// No confirmation needed if server is not running or already stopping
 			return m.handleStop()
+		case "o":
+			if m.lastLogFilePath == "" {
+				m.statusLineText = "No log file to open (enable file logging with l)"
+				return m, nil
+			}
+			m.statusLineText = "Opening " + filepath.Base(m.lastLogFilePath) + " in pager..."
+			return m, openLogInPagerCmd(m.lastLogFilePath)
+		case "c":
+			if !m.barnMissing {
+				break
+			}
+			m.statusLineText = "Creating " + m.barnDir + "..."
+			return m, createBarnDirCmd(m.barnDir)
+		case "b":
+			if m.server.busy() {
+				m.statusLineText = "Cannot change models directory while server is running"
+				return m, nil
+			}
+			if m.portInput.Focused() {
+				m.portInput.Blur()
+			}
+			m.barnInput.SetValue(m.barnDir)
+			m.barnInput.CursorEnd()
+			m.barnInput.Focus()
+			m.statusLineText = "Edit models directory - enter to apply, esc to cancel"
+			return m, nil
+		case "f":
+			if m.server.busy() {
+				m.statusLineText = "Launch options apply on the next start; stop the server first"
+				return m, nil
+			}
+			m.portInput.Blur()
+			return m.openLaunchOptions()
+		case "K":
+			item, ok := m.modelsList.SelectedItem().(modelItem)
+			if !ok {
+				m.statusLineText = "No model selected"
+				return m, nil
+			}
+			overrides, err := loadKVOverrides(item.name)
+			if err != nil {
+				m.statusLineText = fmt.Sprintf("Metadata overrides unreadable, starting fresh: %v", err)
+			}
+			m.portInput.Blur()
+			form := newKVOverridesForm(item.name, overrides)
+			m.form, m.formPurpose, m.kvModelName = &form, formKVOverrides, item.name
+			return m, nil
+		case "*":
+			if m.observerRefuses("pin models") {
+				return m, nil
+			}
+			item, ok := m.modelsList.SelectedItem().(modelItem)
+			if !ok {
+				m.statusLineText = "No model selected"
+				return m, nil
+			}
+			var pinned bool
+			m.pins, pinned = togglePin(m.pins, item.path)
+			m.reorderModels(item.path)
+			if pinned {
+				m.statusLineText = "Pinned " + item.name + " ([ and ] reorder pins)"
+			} else {
+				m.statusLineText = "Unpinned " + item.name
+			}
+			return m, savePinsCmd(m.historyDir, m.pins)
+		case "[", "]":
+			if m.observerRefuses("reorder pins") {
+				return m, nil
+			}
+			item, ok := m.modelsList.SelectedItem().(modelItem)
+			if !ok || !item.pinned {
+				m.statusLineText = "Only pinned models can be reordered ([*] pins)"
+				return m, nil
+			}
+			delta := 1
+			if keyStr == "[" {
+				delta = -1
+			}
+			var moved bool
+			m.pins, moved = movePin(m.pins, item.path, delta)
+			if !moved {
+				return m, nil
+			}
+			m.reorderModels(item.path)
+			return m, savePinsCmd(m.historyDir, m.pins)
+		case "a":
+			// Edits the selected repo entry, else adds one
+			m.portInput.Blur()
+			m.hfEditRepo = ""
+			if item, ok := m.modelsList.SelectedItem().(modelItem); ok && item.hfRepo != "" {
+				m.hfEditRepo = item.hfRepo
+			}
+			title := "Add Hugging Face Repo"
+			doc := "Loaded by llama-server with -hf, downloading into its cache on first launch, e.g. unsloth/Qwen3-8B-GGUF:Q4_K_M"
+			if m.hfEditRepo != "" {
+				title = "Edit Hugging Face Repo"
+				doc += ". Clear to remove the entry"
+			}
+			form := newForm(title, []formField{
+				newTextField("repo", "Repo", doc, m.hfEditRepo, validateHFRepo),
+			})
+			m.form, m.formPurpose = &form, formHFRepo
+			return m, nil
+		case "t":
+			if m.tailCancel != nil {
+				m.tailCancel()
+				m.logEvent("[tail] Stopped following " + m.tailPath)
+				m.statusLineText = "Stopped tailing " + m.tailPath
+				m.tailPath, m.tailChan, m.tailCancel = "", nil, nil
+				return m, nil
+			}
+			m.portInput.Blur()
+			form := newForm("Tail File", []formField{
+				newTextField("file", "File", "Any log file to stream into the logs panel, e.g. an external server's log", "", validateTailPath),
+			})
+			m.form, m.formPurpose = &form, formTailFile
+			return m, nil
+		case "D":
+			m.statusLineText = "Running diagnostics..."
+			return m, diagnosticsCmd(m.barnDir, m.logsDir, m.portInput.Value(), false)
+		case "J":
+			return m.openJSONView(), nil
+		case "z":
+			return m.beginBookmark()
+		case "'":
+			return m.openBookmarkList(), nil
+		case "x":
+			return m.openComponentMenu(), nil
+		case "n":
+			m.eventsView = &eventsView{}
+			return m, nil
+		case "{", "}":
+			dir := 1
+			if keyStr == "{" {
+				dir = -1
+			}
+			return m.stepBookmark(dir), nil
+		case "E":
+			if !m.jumpToNextError() {
+				m.statusLineText = "No errors in the logs"
+			}
+			return m, nil
+		case "y":
+			if m.lastLogFilePath == "" {
+				m.statusLineText = "No log file to copy (enable file logging with l)"
+				return m, nil
+			}
+			return m, copyToClipboardCmd(m.lastLogFilePath)
+		case "ctrl+y":
+			return m.copyLogs()
+		case "Q":
+			return m.toggleShare()
+		case "ctrl+u":
+			if m.shareURL == "" {
+				m.statusLineText = "Not shared - [Q] opens a quick share tunnel"
+				return m, nil
+			}
+			return m, copyTextCmd(m.shareURL, m.shareURL)
+		case "i":
+			item, ok := m.modelsList.SelectedItem().(modelItem)
+			if !ok {
+				m.statusLineText = "No model selected"
+				return m, nil
+			}
+			cmd, err := m.openChatCmd(item)
+			if err != nil {
+				m.statusLineText = "Cannot chat: " + err.Error()
+				return m, nil
+			}
+			m.statusLineText = "Chatting with " + item.name + " in llama-cli..."
+			return m, cmd
+		case "B":
+			if m.readOnly {
+				m.statusLineText = "Read-only: cannot run benchmarks"
+				return m, nil
+			}
+			if m.server.busy() {
+				m.statusLineText = "Stop the server before benchmarking (results would be skewed)"
+				return m, nil
+			}
+			if m.benchCancel != nil {
+				m.statusLineText = "Benchmark of " + m.benchModel + " already running..."
+				return m, nil
+			}
+			item, ok := m.modelsList.SelectedItem().(modelItem)
+			if !ok {
+				m.statusLineText = "No model selected"
+				return m, nil
+			}
+			if item.kind == kindWhisper {
+				m.statusLineText = "llama-bench cannot run whisper models"
+				return m, nil
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			m.benchCancel = cancel
+			m.benchModel = item.name
+			m.statusLineText = "Running llama-bench on " + item.name + "..."
+			_, _ = m.logBuffer.WriteString(m.colorLog("[bench] Running llama-bench on "+item.name+" (this can take several minutes)") + "\n")
+			m.logsViewport.SetContent(m.logsContent())
+			m.logsViewport.GotoBottom()
+			return m, runBenchCmd(ctx, m.historyDir, item, m.config)
+		case "X":
+			m.showBenchMatrix = !m.showBenchMatrix
+			return m, nil
+		case "Z":
+			return m.startSweep()
+		case "C":
+			if m.observerRefuses("export a compose file") {
+				return m, nil
+			}
+			// The running launch, else what launching the selection would run
+			item, ok := m.findModelByName(m.currentModelName)
+			port := m.currentPort
+			if !m.server.running() || !ok {
+				item, ok = m.modelsList.SelectedItem().(modelItem)
+				if !ok {
+					m.statusLineText = "No model selected"
+					return m, nil
+				}
+				port = m.launchPort(item)
+			}
+			if item.remoteURL != "" || item.hfRepo != "" {
+				m.statusLineText = "Only local models can be exported"
+				return m, nil
+			}
+			d, err := m.dockerLaunchFor(item, port)
+			if err != nil {
+				m.statusLineText = fmt.Sprintf("Compose export: %v", err)
+				return m, nil
+			}
+			return m, exportComposeCmd(d)
+		case "W":
+			if m.standby != nil {
+				name := m.standby.item.name
+				m.discardStandby()
+				m.statusLineText = "Stopped standby " + name
+				return m, nil
+			}
+			item, ok := m.modelsList.SelectedItem().(modelItem)
+			if !ok {
+				m.statusLineText = "No model selected"
+				return m, nil
+			}
+			return m.startStandby(item)
+		case "P":
+			return m.promoteStandby()
+		case "R":
+			if m.hung != "" && m.attached == nil && m.server == serverReady {
+				return m.restartHung()
+			}
+			if m.flagRetry == nil {
+				break
+			}
+			offer := *m.flagRetry
+			m.flagRetry = nil
+			// Kept for the session, so later launches on this build work too
+			m.flagFixes = append(m.flagFixes, offer.fix)
+			return m.requestStart(offer.item)
+		case "H":
+			m.showLatency = !m.showLatency
+			return m, nil
+		case "g":
+			return m.openSamplingView()
+		case "<", ">":
+			delta := 1
+			if keyStr == "<" {
+				delta = -1
+			}
+			return m.resizeSplit(delta)
+		case "|":
+			return m.toggleStacked()
+		case "ctrl+t":
+			return m.openTensorView()
+		case "ctrl+p":
+			return m.openPromptLibrary()
+		case "\\":
+			return m.cyclePanels()
+		case "d":
+			if m.readOnly {
+				m.statusLineText = fmt.Sprintf("Read-only: llama-tui pid %d manages this barn - [T] take over", m.lockOwner)
+				return m, nil
+			}
+			browser := newHubBrowser()
+			m.hub = &browser
+			return m, nil
+		case "I":
+			m.serversView = &serversView{}
+			return m, nil
+		case "v":
+			return m.cycleLogView(), nil
+		case "L":
+			m.showTimeline = !m.showTimeline
+			if m.showTimeline {
+				return m, loadSessionsCmd(m.historyDir)
+			}
+			return m, nil
+		case "N":
+			m.showStats = !m.showStats
+			if m.showStats {
+				return m, loadSessionsCmd(m.historyDir)
+			}
+			return m, nil
+		case "A":
+			m.cacheView = &hfCacheView{}
+			return m, scanHFCacheCmd()
+		case "m":
+			return m.openDownloadSweep()
+		case "Y":
+			return m.openTemplateSandbox()
+		case "F":
+			return m.toggleDevWatch()
+		case "w":
+			return m.openWorkspacePicker()
+		case "O":
+			return m.cycleSort(), nil
+		case "G":
+			m.groupByFamily = !m.groupByFamily
+			selected := ""
+			if mi, ok := m.modelsList.SelectedItem().(modelItem); ok {
+				selected = mi.path
+			}
+			m.reorderModels(selected)
+			if m.groupByFamily {
+				m.statusLineText = "Models grouped by family - [enter] on a family expands it"
+			} else {
+				m.statusLineText = "Models listed flat"
+			}
+			return m, nil
+		case "S":
+			dir := m.slotSaveDir()
+			m.slotView = &slotView{dir: dir}
+			return m, scanSlotFilesCmd(dir)
+		case "tab":
+			if m.showBenchMatrix {
+				m.showSweep = !m.showSweep
+				return m, nil
+			}
+			if m.showStats {
+				m.statsAllTime = !m.statsAllTime
+				return m, nil
+			}
+			if !m.showTimeline {
+				break
+			}
+			m.timelineWeek = !m.timelineWeek
+			return m, nil
+		case "e":
+			if m.showDiagnostics {
+				return m, m.exportDiagnosticsCmd()
+			}
+			if m.showBenchMatrix {
+				return m, exportBenchCSVCmd(m.benchResults)
+			}
+			item, ok := m.modelsList.SelectedItem().(modelItem)
+			if !ok {
+				m.statusLineText = "No model selected"
+				return m, nil
+			}
+			m.portInput.Blur()
+			return m.openModelConfig(item.name)
+		case "u":
+			return m.toggleUIMode()
+		case "M":
+			m.mouseEnabled = !m.mouseEnabled
+			if m.mouseEnabled {
+				m.statusLineText = "Mouse capture on (wheel scrolls logs)"
+				return m, tea.EnableMouseCellMotion
+			}
+			m.statusLineText = "Mouse capture off (native text selection)"
+			return m, tea.DisableMouse
+		case "V":
+			if m.observerRefuses("send a vision test") {
+				return m, nil
+			}
+			if !m.server.serving() {
+				m.statusLineText = "Start a vision model first"
+				return m, nil
+			}
+			if m.currentMMProj == "" {
+				m.statusLineText = "No mmproj paired with this model - not a vision model?"
+				return m, nil
+			}
+			if m.visionTesting {
+				m.statusLineText = "Vision test already running..."
+				return m, nil
+			}
+			m.visionTesting = true
+			m.statusLineText = "Running vision test..."
+			_, _ = m.logBuffer.WriteString("\n" + m.colorLog("[vision] Sending test image: "+visionTestPrompt) + "\n")
+			m.logsViewport.SetContent(m.logsContent())
+			m.logsViewport.GotoBottom()
+			return m, visionTestCmd(m.currentPort, m.config.VisionTestImage)
+		case "T":
+			if !m.readOnly {
+				m.statusLineText = "This instance already manages the barn"
+				return m, nil
+			}
+			lockPath := m.lockPath
+			return m, func() tea.Msg {
+				return lockTakenOverMsg{err: takeOverLock(lockPath)}
+			}
+		case "U":
+			if m.availableUpdate == nil {
+				m.statusLineText = "No update available"
+				return m, nil
+			}
+			if m.server.busy() {
+				m.statusLineText = "Stop the server before updating"
+				return m, nil
+			}
+			if m.updating {
+				m.statusLineText = "Update already in progress..."
+				return m, nil
+			}
+			m.updating = true
+			m.statusLineText = fmt.Sprintf("Downloading llama-tui %s...", m.availableUpdate.TagName)
+			return m, selfUpdateCmd(*m.availableUpdate)
 		case "h":
-			m.showHelp = !m.showHelp
+			m.showHelp, m.helpQuery = true, ""
 			return m, nil
 		case "esc":
 			// First priority: cancel any pending confirmation
 			if m.confirmAction != confirmNone {
 				m.confirmAction = confirmNone
+				m.pendingLaunch = nil
 				m.statusLineText = "Action cancelled"
 				return m, nil
 			}
 			// Then handle other esc behaviors
-			if m.showHelp {
-				m.showHelp = false
+			if m.showBenchMatrix {
+				m.showBenchMatrix = false
+				return m, nil
+			}
+			if m.showDiagnostics {
+				m.showDiagnostics = false
+				return m, nil
+			}
+			if m.showLatency {
+				m.showLatency = false
+				return m, nil
+			}
+			if m.showTimeline {
+				m.showTimeline = false
+				return m, nil
+			}
+			if m.showStats {
+				m.showStats = false
 				return m, nil
 			}
 			// If port input is focused, blur it on esc
@@ -312,46 +2629,36 @@ func (m appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return m, nil
 		case "enter":
-			// Start server on selected model
-			if m.serverRunning || m.serverStopping {
-				m.statusLineText = "Server is already running or stopping"
-				return m, nil
+			// Second press on a launch with launch warnings - start anyway
+			if m.confirmAction == confirmLaunch && m.pendingLaunch != nil {
+				pending := *m.pendingLaunch
+				m.confirmAction = confirmNone
+				m.pendingLaunch = nil
+				ackCmd := m.acknowledgeLicense(pending.item)
+				next, cmd := m.beginStart(pending.item, pending.port, pending.warnings)
+				return next, tea.Batch(cmd, ackCmd)
 			}
+			if f, ok := m.modelsList.SelectedItem().(familyItem); ok {
+				return m.toggleFamily(f), nil
+			}
+			// Start server on selected model
 			item, ok := m.modelsList.SelectedItem().(modelItem)
 			if !ok {
 				m.statusLineText = "No model selected"
 				return m, nil
 			}
-			portStr := strings.TrimSpace(m.portInput.Value())
-			if portStr == "" {
-				portStr = defaultPort
-			}
-			// Validate port before starting server
-			portNum, err := validatePort(portStr)
-			if err != nil {
-				m.statusLineText = fmt.Sprintf("Invalid port: %v", err)
-				return m, nil
-			}
-			portStr = strconv.Itoa(portNum)
-			// Blur port input before starting server
-			if m.portInput.Focused() {
-				m.portInput.Blur()
-			}
-			// Clear logs for a new session and set initial message
-			m.logBuffer.Reset()
-			initialMsg := fmt.Sprintf("Starting llama-server with model: %s on port: %s...", item.name, portStr)
-			coloredMsg := m.colorLog(initialMsg)
-			_, _ = m.logBuffer.WriteString(coloredMsg)
-			m.logsViewport.SetContent(coloredMsg)
-			m.statusLineText = fmt.Sprintf("Starting %s on port %s...", item.name, portStr)
-			return m, m.startServerCmd(item, portStr)
+			return m.requestStart(item)
 		}
-		// Update nested components for unhandled keys
+		// Update nested components for unhandled keys; a hidden list
+		// doesn't move
 		var cmd tea.Cmd
-		m.modelsList, cmd = m.modelsList.Update(msg)
+		prev := m.selectedPath()
+		if !m.layout().HideModels {
+			m.modelsList, cmd = m.modelsList.Update(msg)
+		}
 		var portCmd tea.Cmd
 		m.portInput, portCmd = m.portInput.Update(msg)
-		return m, tea.Batch(cmd, portCmd)
+		return m, tea.Batch(cmd, portCmd, m.selectionMoved(prev))
 	}
 
 	// Default: update nested components
@@ -36,6 +36,152 @@ func formatBytes(bytes uint64) string {
 	return fmt.Sprintf("%.1f %s", float64(bytes)/float64(div), unitStr)
 }
 
+// memoryUsageStyle picks the status bar style for RSS based on how close the
+// server is to the configured share of total system memory.
+func (m appModel) memoryUsageStyle() lipgloss.Style {
+	if m.memTotalBytes == 0 {
+		return m.styles.accent
+	}
+	pct := float64(m.memRSSBytes) / float64(m.memTotalBytes) * 100
+	switch {
+	case pct >= m.memCritPercent:
+		return m.styles.usageCritical
+	case pct >= m.memWarnPercent:
+		return m.styles.usageWarn
+	default:
+		return m.styles.accent
+	}
+}
+
+// formatMemoryUsage renders RSS, and its share of system memory when known.
+func (m appModel) formatMemoryUsage() string {
+	if m.memTotalBytes == 0 {
+		return formatBytes(m.memRSSBytes)
+	}
+	pct := float64(m.memRSSBytes) / float64(m.memTotalBytes) * 100
+	return fmt.Sprintf("%s / %s (%.0f%%)", formatBytes(m.memRSSBytes), formatBytes(m.memTotalBytes), pct)
+}
+
+// statusChip labels the server state, with a spinner while it changes.
+func (m appModel) statusChip() (string, lipgloss.Style) {
+	var label, symbol string
+	style := m.styles.statusStopping
+	switch m.server {
+	case serverStarting:
+		label, symbol = "[STARTING]", "◐"
+	case serverLoading:
+		label, symbol = "[LOADING]", "◐"
+	case serverDraining, serverQuitting:
+		label, symbol = "[STOPPING]", "◐"
+	case serverReady:
+		if m.powerPaused {
+			return m.withStatusSymbol("◐", "[PAUSED]"), m.styles.statusStopping
+		}
+		if m.hung != "" {
+			return m.withStatusSymbol("✕", "[HUNG]"), m.styles.logError.Bold(true)
+		}
+		return m.withStatusSymbol("●", "[RUNNING]"), m.styles.statusRunning
+	case serverCrashed:
+		return m.withStatusSymbol("✕", "[CRASHED]"), m.styles.logError.Bold(true)
+	default:
+		return m.withStatusSymbol("○", "[STOPPED]"), m.styles.statusStopped
+	}
+	return m.withStatusSymbol(symbol, label) + " " + m.spinner.View(), style
+}
+
+// withStatusSymbol prefixes a state label with its symbol when the theme
+// asks for states that don't rely on color alone.
+func (m appModel) withStatusSymbol(symbol, label string) string {
+	if !m.styles.statusSymbols {
+		return label
+	}
+	return symbol + " " + label
+}
+
+// statusSegments describes the footer status bar. Priorities decide what
+// survives at narrow widths: status, then port, model, memory, and CPU.
+func (m appModel) statusSegments() []statusSegment {
+	label, style := m.statusChip()
+	segments := []statusSegment{{label: "Status: ", value: label, style: style}}
+
+	if m.currentModelName != "" {
+		segments = append(segments, statusSegment{label: "Model: ", value: m.displayName(m.currentModelName), style: m.styles.accent, priority: 2, truncatable: true, minWidth: 12})
+	}
+	if m.currentPort != "" {
+		segments = append(segments, statusSegment{label: "Port: ", value: m.currentPort, style: m.styles.accent, priority: 1})
+	}
+	if m.standby != nil {
+		segments = append(segments, statusSegment{label: "Standby: ", value: m.standby.describe(), style: m.instanceStyle(m.standby.color), priority: 4, truncatable: true, minWidth: 12})
+	}
+	if n := m.liveSideServers(); n > 0 {
+		segments = append(segments, statusSegment{label: "Also: ", value: pluralize(n, "server") + " [I]", style: m.styles.accent, priority: 4})
+	}
+	if len(m.launchQueue) > 0 {
+		segments = append(segments, statusSegment{label: "Queued: ", value: m.launchQueue[0].item.name, style: m.styles.accent, priority: 4, truncatable: true, minWidth: 8})
+	}
+	if m.tailPath != "" {
+		segments = append(segments, statusSegment{label: "Tail: ", value: filepath.Base(m.tailPath), style: m.styles.accent, priority: 5, truncatable: true, minWidth: 8})
+	}
+	if m.server.running() && m.clientCount >= 0 {
+		clients := fmt.Sprintf("%d clients connected", m.clientCount)
+		if m.clientCount == 1 {
+			clients = "1 client connected"
+		}
+		segments = append(segments, statusSegment{value: clients, style: m.styles.accent, priority: 3})
+	}
+	// Add CPU and memory usage when server is running and metrics are available
+	if m.server.running() && (m.cpuPercent > 0 || m.memRSSBytes > 0) {
+		segments = append(segments, statusSegment{label: "CPU: ", value: fmt.Sprintf("%.1f%%", m.cpuPercent), style: m.styles.accent, priority: 4})
+		if m.memRSSBytes > 0 {
+			segments = append(segments, statusSegment{label: "Mem: ", value: m.formatMemoryUsage(), style: m.memoryUsageStyle(), priority: 3})
+		}
+		if m.vramBytes > 0 {
+			segments = append(segments, statusSegment{label: "VRAM: ", value: formatBytes(m.vramBytes), style: m.styles.accent, priority: 4})
+		}
+	}
+	if m.server.serving() && m.health.state != healthUnknown {
+		segments = append(segments, statusSegment{label: "Health: ", value: m.health.state.String(), style: m.healthStyle(), priority: 2})
+	}
+	if seg, ok := m.failoverSegment(); ok {
+		segments = append(segments, seg)
+	}
+	if seg, ok := m.promptSegment(); ok {
+		segments = append(segments, seg)
+	}
+	if seg, ok := m.sloSegment(); ok {
+		segments = append(segments, seg)
+	}
+	if seg, ok := m.externalSegment(); ok {
+		segments = append(segments, seg)
+	}
+	if m.server.running() && m.spec.requests > 0 {
+		segments = append(segments, statusSegment{label: "Draft: ", value: fmt.Sprintf("%.0f%% ≤%.1fx", m.spec.acceptanceRate()*100, m.spec.speedup()), style: m.styles.accent, priority: 5})
+	}
+	if m.socket != nil {
+		segments = append(segments, statusSegment{label: "Socket: ", value: m.socket.path, style: m.styles.accent, priority: 5, truncatable: true, minWidth: 12})
+	}
+	if n := m.proxyRejected; n > 0 {
+		segments = append(segments, statusSegment{label: "Limited: ", value: pluralize(n, "request") + " [H]", style: m.styles.usageWarn, priority: 5})
+	}
+	if m.devWatch {
+		segments = append(segments, statusSegment{label: "Dev: ", value: "watching files", style: m.styles.accent, priority: 5})
+	}
+	if m.server.serving() && !m.nextRestart.IsZero() {
+		segments = append(segments, statusSegment{label: "Restart: ", value: m.config.Timestamps.dateTime(m.nextRestart), style: m.styles.accent, priority: 6})
+	}
+	if m.basicMode {
+		// Status, model, port, and health only
+		basic := segments[:0]
+		for _, s := range segments {
+			if s.priority <= 2 {
+				basic = append(basic, s)
+			}
+		}
+		return basic
+	}
+	return segments
+}
+
 func (m appModel) resizeComponents(width, height int) (tea.Model, tea.Cmd) {
 	if width <= 0 || height <= 0 {
 		return m, nil
@@ -47,46 +193,182 @@ func (m appModel) resizeComponents(width, height int) (tea.Model, tea.Cmd) {
 	if contentHeight < 5 {
 		contentHeight = 5
 	}
+	m = m.noteLayoutBucket()
+	prefs := m.layout()
 	leftWidth := width / 3
 	if leftWidth < 30 {
 		leftWidth = 30
 	}
+	// Wide terminals get a third column for model details and metrics
+	detailsWidth := 0
+	if m.detailsShown(prefs) {
+		leftWidth = width / 4
+		detailsWidth = width / 4
+	}
+	if prefs.Split > 0 {
+		leftWidth = max(width*prefs.Split/100, 20)
+	}
+	modelsHeight, logsHeight := contentHeight, contentHeight
+	switch {
+	case prefs.HideModels:
+		leftWidth = 0
+	case prefs.Stacked:
+		// Full-width panels, the models on top; the second panel's
+		// borders come out of the logs
+		split := prefs.Split
+		if split == 0 {
+			split = 35
+		}
+		leftWidth = width - 2
+		modelsHeight = max(contentHeight*split/100, 3)
+		logsHeight = max(contentHeight-modelsHeight-2, 3)
+	}
 	rightWidth := width - leftWidth - 4
+	switch {
+	case prefs.Stacked && !prefs.HideModels:
+		rightWidth = width - 2
+	case prefs.HideModels:
+		rightWidth = width - 2
+	}
+	if detailsWidth > 0 {
+		rightWidth -= detailsWidth + 2
+	}
 	if rightWidth < 20 {
 		rightWidth = 20
 	}
 
 	m.leftWidth = leftWidth
 	m.rightWidth = rightWidth
+	m.detailsWidth = detailsWidth
 	m.contentHeight = contentHeight
+	m.modelsHeight = modelsHeight
 
-	m.modelsList.SetSize(leftWidth, contentHeight)
-	m.logsViewport.Width = rightWidth
-	m.logsViewport.Height = contentHeight
+	m.modelsList.SetSize(max(leftWidth, 20), modelsHeight)
+	// Leave one column for the scrollbar
+	m.logsViewport.Width = rightWidth - 1
+	m.logsViewport.Height = logsHeight
 	return m, nil
 }
 
-func (m appModel) colorLog(line string) string {
+type logLevel int
+
+const (
+	logLevelNone logLevel = iota
+	logLevelInfo
+	logLevelWarn
+	logLevelError
+)
+
+func classifyLogLine(line string) logLevel {
 	lower := strings.ToLower(line)
 	switch {
 	case strings.Contains(lower, "error"):
-		return m.styles.logError.Render(line)
+		return logLevelError
 	case strings.Contains(lower, "warn"):
-		return m.styles.logWarn.Render(line)
+		return logLevelWarn
 	case strings.Contains(lower, "info"):
-		return m.styles.logInfo.Render(line)
+		return logLevelInfo
 	default:
+		return logLevelNone
+	}
+}
+
+func (m appModel) colorLog(line string) string {
+	if m.lowMemory || m.styles.monochrome {
 		return line
 	}
+	level := classifyLogLine(line)
+	switch level {
+	case logLevelError:
+		return m.styles.logError.Render(line)
+	case logLevelWarn:
+		return m.styles.logWarn.Render(line)
+	}
+	// Otherwise the component a llama.cpp line starts with gets its color,
+	// so server, slot, and loader lines stand apart
+	if name, start, end := logComponent(line); name != "" {
+		return line[:start] + m.styles.componentStyle(name).Render(name) + line[end:]
+	}
+	if level == logLevelInfo {
+		return m.styles.logInfo.Render(line)
+	}
+	return line
+}
+
+// logCountsTitle summarizes problems seen this session for the Logs title.
+func (m appModel) logCountsTitle() string {
+	var parts []string
+	if m.logErrorCount > 0 {
+		parts = append(parts, pluralize(m.logErrorCount, "error"))
+	}
+	if m.logWarnCount > 0 {
+		parts = append(parts, pluralize(m.logWarnCount, "warning"))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " • " + strings.Join(parts, " • ")
+}
+
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// renderWarmupPreview shows the tail of the streamed warm-up reply on one
+// line, so the newest tokens stay visible as they arrive.
+func (m appModel) renderWarmupPreview() string {
+	label := "Warm-up: "
+	if m.warmupActive {
+		label = "Warm-up (streaming): "
+	}
+	text := strings.Join(strings.Fields(m.warmupText), " ")
+	if text == "" {
+		text = "waiting for server..."
+	}
+	avail := m.width - lipgloss.Width(label)
+	if avail > 0 && lipgloss.Width(text) > avail {
+		runes := []rune(text)
+		for len(runes) > 0 && lipgloss.Width(string(runes))+1 > avail {
+			runes = runes[1:]
+		}
+		text = "…" + string(runes)
+	}
+	return m.styles.help.Render(label) + m.styles.accent.Render(text)
+}
+
+// renderMissingBarn is the models panel empty state when the barn directory
+// does not exist.
+func (m appModel) renderMissingBarn() string {
+	lines := []string{
+		m.styles.logWarn.Render("Models directory not found:"),
+		lipgloss.NewStyle().Width(m.leftWidth).Render(m.barnDir),
+		"",
+		m.styles.help.Render("[c] create this directory"),
+		m.styles.help.Render("[b] choose another directory"),
+	}
+	body := strings.Join(lines, "\n")
+	// Pad to the list height so the panel keeps its size
+	if pad := m.modelsHeight - lipgloss.Height(body); pad > 0 {
+		body += strings.Repeat("\n", pad)
+	}
+	return body
 }
 
 func (m appModel) renderPanelWithTitle(title, body string, contentWidth int) string {
 	borderStyle := m.styles.panelBorder
-	titleStyled := m.styles.panelTitle.Render(" " + title + " ")
 
 	// Total width includes border characters (2 chars for left/right borders)
 	total := contentWidth + 2
 
+	// Keep long titles (file names, scroll positions) inside the top border
+	if maxTitle := total - 5; lipgloss.Width(title) > maxTitle {
+		title = ellipsize(title, maxTitle)
+	}
+	titleStyled := m.styles.panelTitle.Render(" " + title + " ")
+
 	// Top line with title embedded
 	topLeft := borderStyle.Render("╭")
 	topRight := borderStyle.Render("╮")
@@ -123,24 +405,52 @@ func (m appModel) renderPanelWithTitle(title, body string, contentWidth int) str
 	return top + "\n" + b.String() + "\n" + bottom
 }
 
+// renderTooSmall renders a dedicated screen when the terminal is below the
+// minimum workable size, instead of drawing overlapping panels.
+func (m appModel) renderTooSmall() string {
+	msg := m.styles.confirmWarning.Render("Terminal too small") + "\n" +
+		m.styles.status.Render(fmt.Sprintf("need %dx%d, have %dx%d", minTerminalWidth, minTerminalHeight, m.width, m.height)) + "\n\n" +
+		m.styles.help.Render("[q] quit")
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, msg)
+}
+
 func (m appModel) View() string {
-	// Render status chip
-	var statusChip string
-	if m.serverStopping {
-		statusChip = m.styles.statusStopping.Render("[STOPPING]")
-	} else if m.serverRunning {
-		statusChip = m.styles.statusRunning.Render("[RUNNING]")
-	} else {
-		statusChip = m.styles.statusStopped.Render("[STOPPED]")
+	defer func() {
+		if r := recover(); r != nil {
+			recordPanic(r, &m)
+			panic(r)
+		}
+	}()
+	if m.quick != nil {
+		return m.renderQuick()
+	}
+	// Terminal size is known but too small to lay out the panels
+	if m.width > 0 && m.height > 0 && (m.width < minTerminalWidth || m.height < minTerminalHeight) {
+		return m.renderTooSmall()
 	}
 
+	// Render status chip
+	chipLabel, chipStyle := m.statusChip()
+	statusChip := chipStyle.Render(chipLabel)
+
 	// Build header with status chip and model info
 	headerParts := []string{
 		m.styles.title.Render(appTitle),
 		statusChip,
 	}
-	if m.serverRunning && m.currentModelName != "" && m.currentPort != "" {
-		headerParts = append(headerParts, m.styles.accent.Render(fmt.Sprintf("%s:%s", m.currentModelName, m.currentPort)))
+	if m.server.running() && m.currentModelName != "" && m.currentPort != "" {
+		servedStyle := m.styles.accent
+		if m.multiInstance() {
+			servedStyle = m.instanceStyle(m.serverColor)
+		}
+		headerParts = append(headerParts, servedStyle.Render(fmt.Sprintf("%s:%s", m.displayName(m.currentModelName), m.currentPort)))
+		// Only when the header still fits on its line
+		if label := m.shareLabel(); label != "" {
+			headerParts = append(headerParts, m.styles.confirmWarning.Render(label))
+		}
+		if sparks := m.headerSparklines(); sparks != "" && !m.basicMode && lipgloss.Width(strings.Join(append(headerParts, sparks, m.statusLineText), "  ")) <= m.width-4 {
+			headerParts = append(headerParts, sparks)
+		}
 	}
 	// Use warning style for confirmation messages, regular status style otherwise
 	if m.confirmAction != confirmNone {
@@ -157,62 +467,113 @@ func (m appModel) View() string {
 	}
 	header := headerStyle.Render(headerContent)
 
-	left := m.renderPanelWithTitle("Models", m.modelsList.View(), m.leftWidth)
-	logTitle := "Logs"
+	modelsBody := m.modelsList.View()
+	if m.barnMissing && len(m.modelsList.Items()) == 0 {
+		modelsBody = m.renderMissingBarn()
+	}
+	modelsTitle := "Models"
+	if pos := m.modelsPosition(); pos != "" {
+		modelsTitle += " " + pos
+	}
+	left := m.renderPanelWithTitle(modelsTitle, modelsBody, m.leftWidth)
+	logTitle := "Logs" + m.logViewTitle() + m.logCountsTitle() + m.logFilterTitle()
 	if m.logToFileEnabled {
 		logTitle += " (file: on)"
 	} else {
 		logTitle += " (file: off)"
 	}
-	if m.logFilePath != "" && m.serverRunning {
+	if m.logFilePath != "" && m.server.running() {
 		logTitle += " -> " + filepath.Base(m.logFilePath)
 	}
-	right := m.renderPanelWithTitle(logTitle, m.logsViewport.View(), m.rightWidth)
-
-	content := lipgloss.JoinHorizontal(lipgloss.Top, left, right)
-
-	// Build explicit status bar
-	var statusText string
-	if m.serverStopping {
-		statusText = "Status: " + m.styles.statusStopping.Render("[STOPPING]")
-	} else if m.serverRunning {
-		statusText = "Status: " + m.styles.statusRunning.Render("[RUNNING]")
-	} else {
-		statusText = "Status: " + m.styles.statusStopped.Render("[STOPPED]")
+	if pos := m.logsPosition(); pos != "" {
+		logTitle += " [" + pos + "]"
+	}
+	logsBar := m.renderScrollbar(m.logsViewport.Height, m.logsViewport.TotalLineCount(), m.logsViewport.YOffset, m.logsViewport.Height)
+	right := m.renderPanelWithTitle(logTitle, withScrollbar(m.logsViewport.View(), logsBar), m.rightWidth)
+	if m.previewing() {
+		right = m.renderPanelWithTitle("Preview", m.renderPreview(m.logsViewport.Width, m.logsViewport.Height), m.rightWidth)
 	}
 
-	if m.currentModelName != "" {
-		statusText += " • Model: " + m.styles.accent.Render(m.currentModelName)
+	panels := []string{right}
+	if m.detailsWidth > 0 {
+		panels = []string{m.renderPanelWithTitle("Details", m.renderDetailsPane(), m.detailsWidth), right}
 	}
-	if m.currentPort != "" {
-		statusText += " • Port: " + m.styles.accent.Render(m.currentPort)
+	if m.leftWidth > 0 {
+		panels = append([]string{left}, panels...)
 	}
-	// Add CPU and memory usage when server is running and metrics are available
-	if m.serverRunning && (m.cpuPercent > 0 || m.memRSSBytes > 0) {
-		statusText += " • CPU: " + m.styles.accent.Render(fmt.Sprintf("%.1f%%", m.cpuPercent))
-		if m.memRSSBytes > 0 {
-			statusText += " • Mem: " + m.styles.accent.Render(formatBytes(m.memRSSBytes))
-		}
+	content := lipgloss.JoinHorizontal(lipgloss.Top, panels...)
+	if m.layout().Stacked && m.leftWidth > 0 {
+		content = lipgloss.JoinVertical(lipgloss.Left, left, right)
 	}
-	statusBar := m.styles.status.Render(statusText)
 
-	// State-based help line
+	statusBar := renderStatusBar(m.statusSegments(), m.styles.status, m.width)
+
+	// State-based help line (clipped rather than wrapped on narrow terminals)
 	var helpLine string
 	if m.confirmAction == confirmQuit {
-		helpLine = m.styles.confirmWarning.Render("Quit? Press q again to confirm, esc to cancel")
+		helpLine = m.styles.confirmWarning.Render("Quit? Press q again to confirm, esc to cancel" + m.confirmCountdown())
 	} else if m.confirmAction == confirmStop {
-		helpLine = m.styles.confirmWarning.Render("Stop server? Press s again to confirm, esc to cancel")
-	} else if m.serverStopping {
+		prompt := "Stop server? Press s again to confirm, esc to cancel"
+		if m.clientCount > 0 {
+			prompt = fmt.Sprintf("Stop server with %d connected client(s)? Press s again to confirm, esc to cancel", m.clientCount)
+		}
+		helpLine = m.styles.confirmWarning.Render(prompt + m.confirmCountdown())
+	} else if m.confirmAction == confirmStopAll {
+		helpLine = m.styles.confirmWarning.Render("Stop server, benchmarks, and downloads? Press ctrl+k again to confirm, esc to cancel" + m.confirmCountdown())
+	} else if m.confirmAction == confirmLaunch {
+		helpLine = m.styles.confirmWarning.Render("Launch despite warnings? Press enter again to confirm, esc to cancel" + m.confirmCountdown())
+	} else if m.server.stopping() {
 		helpLine = m.styles.help.Render("Stopping server... Please wait")
-	} else if m.serverRunning {
-		helpLine = m.styles.help.Render("[s] stop  [h] help  [q] quit")
+	} else if m.flagRetry != nil && !m.server.busy() {
+		helpLine = m.styles.confirmWarning.Render(fmt.Sprintf("llama-server rejected %s - press R to retry %s", m.flagRetry.rejected, m.flagRetry.fix.describe()))
+	} else if m.attached != nil {
+		attachedHelp := fmt.Sprintf("Monitoring port %s", m.attached.port)
+		if m.attached.pid > 0 {
+			attachedHelp += fmt.Sprintf(" (pid %d)", m.attached.pid)
+		}
+		helpLine = m.styles.help.Render(attachedHelp + " - llama-tui won't stop it  [t] tail a log  [h] help  [q] quit")
+	} else if m.observer {
+		helpLine = m.styles.help.Render("Read-only observer - starting, stopping, and deleting are off  [I] servers  [v] switch logs  [h] help  [ctrl+c] quit")
+	} else if m.readOnly {
+		helpLine = m.styles.help.Render(fmt.Sprintf("Read-only (pid %d manages this barn)  [T] take over  [r] refresh  [h] help  [q] quit", m.lockOwner))
+	} else if m.basicMode && m.server.running() {
+		helpLine = m.styles.help.Render("[s] stop  [u] advanced mode  [h] help  [q] quit")
+	} else if m.basicMode {
+		helpLine = m.styles.help.Render("[enter] start  [p] port  [/] filter  [r] refresh  [b] models dir  [u] advanced mode  [h] help  [q] quit")
+	} else if m.server.running() {
+		runningHelp := "[s] stop  "
+		if m.currentMMProj != "" {
+			runningHelp += "[V] vision test  "
+		}
+		if m.lastLogFilePath != "" {
+			runningHelp += "[o] open log  [y] copy log path  "
+		}
+		if m.standby != nil && m.standby.ready {
+			runningHelp += "[P] promote standby  "
+		}
+		if len(m.sideServers) > 0 {
+			runningHelp += "[I] servers  [v] switch logs  "
+		} else {
+			runningHelp += "[enter] run another model too  "
+		}
+		runningHelp += "[h] help  [q] quit"
+		helpLine = m.styles.help.Render(runningHelp)
 	} else {
-		helpLine = m.styles.help.Render("[enter] start  [r] refresh  [p] toggle port  [l] toggle file log  [h] help  [q] quit")
+		helpLine = m.styles.help.Render("[enter] start  [r] refresh  [p] toggle port  [l] toggle file log  [f] options  [b] models dir  [h] help  [q] quit")
+	}
+
+	if m.width > 0 {
+		helpLine = lipgloss.NewStyle().MaxWidth(m.width).Render(helpLine)
 	}
 
 	// Render port input - dimmed if server is running/stopping
 	portInputView := m.portInput.View()
-	if m.serverRunning || m.serverStopping {
+	if item, ok := m.modelsList.SelectedItem().(modelItem); ok && m.config.AutoPorts.enabled() &&
+		strings.TrimSpace(m.portInput.Value()) == "" && !m.portInput.Focused() {
+		// Preview the port the selected model would launch on
+		portInputView = m.portInput.Prompt + m.styles.accent.Render("auto "+m.launchPort(item))
+	}
+	if m.server.busy() {
 		portInputView = m.styles.disabled.Render(portInputView)
 	}
 
@@ -221,34 +582,258 @@ func (m appModel) View() string {
 		helpLine,
 		m.styles.help.Render("Port: ") + portInputView,
 	}
+	if m.barnInput.Focused() {
+		helpLines = append(helpLines, m.barnInput.View())
+	}
+	if m.bookmarkInput.Focused() {
+		helpLines = append(helpLines, m.bookmarkInput.View())
+	}
+	if m.server.running() && (m.warmupActive || m.warmupText != "") {
+		helpLines = append(helpLines, m.renderWarmupPreview())
+	}
+	if m.availableUpdate != nil {
+		helpLines = append(helpLines, m.styles.help.Render(fmt.Sprintf("llama-tui %s is available (current %s) - press [U] to update", m.availableUpdate.TagName, version)))
+	}
 	footer := strings.Join(helpLines, "\n")
 
 	// Reduced spacing since bordered header provides visual separation
 	view := header + "\n" + content + "\n\n" + footer
 
+	// Show the open form as an overlay
+	if m.form != nil {
+		formWidth := m.width - 8
+		if formWidth < 50 {
+			formWidth = 50
+		}
+		title, body := m.form.title, m.form.View(m.styles, formWidth-4)
+		if m.flagSearch != nil {
+			rows := m.height - 16
+			if rows < 5 {
+				rows = 5
+			}
+			title, body = "llama-server Flags", m.flagSearch.View(m.styles, formWidth-4, rows)
+		}
+		panel := m.renderPanelWithTitle(title, body, formWidth)
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, panel)
+	}
+
+	// Show the diagnostics checklist
+	if m.showDiagnostics {
+		panelWidth := m.width - 8
+		if panelWidth < 50 {
+			panelWidth = 50
+		}
+		panel := m.renderPanelWithTitle("Diagnostics", m.renderDiagnostics(), panelWidth)
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, panel)
+	}
+
+	// Show the server lines of one proxied request
+	if m.requestLogs != nil {
+		panelWidth := m.width - 8
+		if panelWidth < 50 {
+			panelWidth = 50
+		}
+		panel := m.renderPanelWithTitle("Backend Logs · "+m.requestLogs.sample.id, m.renderRequestLogs(panelWidth-4), panelWidth)
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, panel)
+	}
+
+	// Show recent request latencies through the proxy
+	if m.showLatency {
+		panelWidth := m.width - 8
+		if panelWidth < 50 {
+			panelWidth = 50
+		}
+		panel := m.renderPanelWithTitle("Request Latency", m.renderLatencyView(panelWidth-4), panelWidth)
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, panel)
+	}
+
+	// Show the timeline of past server sessions
+	if m.showTimeline {
+		panelWidth := m.width - 8
+		if panelWidth < 50 {
+			panelWidth = 50
+		}
+		panel := m.renderPanelWithTitle("Session Timeline", m.renderTimelineView(panelWidth-4), panelWidth)
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, panel)
+	}
+
+	// Show usage stats from the session history
+	if m.showStats {
+		panelWidth := m.width - 8
+		if panelWidth < 50 {
+			panelWidth = 50
+		}
+		panel := m.renderPanelWithTitle("Usage Stats", m.renderStatsView(panelWidth-4), panelWidth)
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, panel)
+	}
+
+	// Show the Hugging Face download screen
+	if m.hub != nil {
+		panelWidth := m.width - 8
+		if panelWidth < 50 {
+			panelWidth = 50
+		}
+		panel := m.renderPanelWithTitle("Download from Hugging Face", m.renderHubView(panelWidth-4), panelWidth)
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, panel)
+	}
+
+	// Show the managed server and the side servers
+	if m.serversView != nil {
+		panelWidth := m.width - 8
+		if panelWidth < 50 {
+			panelWidth = 50
+		}
+		title := "Running Servers"
+		if len(m.config.ExternalEndpoints) > 0 {
+			title = "Servers & Endpoints"
+		}
+		panel := m.renderPanelWithTitle(title, m.renderServersView(panelWidth-4), panelWidth)
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, panel)
+	}
+
+	// Show why a launch failed and the ways out
+	if m.startFailure != nil {
+		panelWidth := m.width - 8
+		if panelWidth < 50 {
+			panelWidth = 50
+		}
+		panel := m.renderPanelWithTitle("Server Failed to Start", m.renderStartFailure(panelWidth-4), panelWidth)
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, panel)
+	}
+
+	// Offer smaller settings after an out-of-memory crash
+	if m.oomRecovery != nil {
+		panelWidth := m.width - 8
+		if panelWidth < 50 {
+			panelWidth = 50
+		}
+		panel := m.renderPanelWithTitle("Out of Memory", m.renderOOMRecovery(panelWidth-4), panelWidth)
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, panel)
+	}
+
+	// Show a JSON log line expanded
+	if m.jsonView != nil {
+		panelWidth := m.width - 8
+		if panelWidth < 50 {
+			panelWidth = 50
+		}
+		panel := m.renderPanelWithTitle("JSON Log Line", m.renderJSONView(panelWidth-4), panelWidth)
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, panel)
+	}
+
+	// Show the log bookmarks
+	if m.componentMenu != nil {
+		panelWidth := min(max(m.width-8, 50), 72)
+		panel := m.renderPanelWithTitle("Log Components", m.renderComponentMenu(panelWidth-4), panelWidth)
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, panel)
+	}
+
+	if m.eventsView != nil {
+		panelWidth := m.width - 8
+		if panelWidth < 50 {
+			panelWidth = 50
+		}
+		panel := m.renderPanelWithTitle("Events", m.renderEvents(panelWidth-4), panelWidth)
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, panel)
+	}
+
+	if m.promptLibrary != nil {
+		panelWidth := max(m.width-8, 50)
+		panel := m.renderPanelWithTitle(fmt.Sprintf("Prompt Library (%d)", len(m.promptLibrary.prompts)), m.renderPromptLibrary(panelWidth-4), panelWidth)
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, panel)
+	}
+
+	if m.tensorView != nil {
+		panelWidth := max(m.width-8, 60)
+		panel := m.renderPanelWithTitle("Tensors: "+m.displayName(m.tensorView.model), m.renderTensorView(panelWidth-4), panelWidth)
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, panel)
+	}
+
+	if m.bookmarkList != nil {
+		panelWidth := m.width - 8
+		if panelWidth < 50 {
+			panelWidth = 50
+		}
+		panel := m.renderPanelWithTitle(fmt.Sprintf("Log Bookmarks (%d)", len(m.bookmarks)), m.renderBookmarkList(panelWidth-4), panelWidth)
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, panel)
+	}
+
+	// Show saved slots
+	if m.slotView != nil {
+		panelWidth := m.width - 8
+		if panelWidth < 50 {
+			panelWidth = 50
+		}
+		panel := m.renderPanelWithTitle("Slots", m.renderSlotView(panelWidth-4), panelWidth)
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, panel)
+	}
+
+	// Show a sample conversation rendered through the chat template
+	if m.templateSandbox != nil {
+		panelWidth := m.width - 8
+		if panelWidth < 50 {
+			panelWidth = 50
+		}
+		panel := m.renderPanelWithTitle("Template Sandbox · "+m.displayName(m.templateSandbox.item.name), m.renderTemplateSandbox(panelWidth-4), panelWidth)
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, panel)
+	}
+
+	// Compare two running servers on the same prompts
+	if m.abChat != nil {
+		panelWidth := m.width - 8
+		if panelWidth < 50 {
+			panelWidth = 50
+		}
+		panel := m.renderPanelWithTitle("A/B Chat", m.renderABChat(panelWidth-4), panelWidth)
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, panel)
+	}
+
+	// Show the downloads folder being swept into the barn
+	if m.downloadSweep != nil {
+		panelWidth := m.width - 8
+		if panelWidth < 50 {
+			panelWidth = 50
+		}
+		panel := m.renderPanelWithTitle("Sweep Downloads", m.renderDownloadSweep(panelWidth-4), panelWidth)
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, panel)
+	}
+
+	// Show the served generation defaults and their overrides
+	if m.samplingView != nil {
+		panelWidth := m.width - 8
+		if panelWidth < 50 {
+			panelWidth = 50
+		}
+		panel := m.renderPanelWithTitle("Generation Defaults", m.renderSamplingView(panelWidth-4), panelWidth)
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, panel)
+	}
+
+	// Show the llama-server download cache
+	if m.cacheView != nil {
+		panelWidth := m.width - 8
+		if panelWidth < 50 {
+			panelWidth = 50
+		}
+		panel := m.renderPanelWithTitle("Hugging Face Cache", m.renderHFCacheView(panelWidth-4), panelWidth)
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, panel)
+	}
+
+	// Show benchmark matrix overlay if enabled
+	if m.showBenchMatrix {
+		matrixWidth := m.width - 8
+		if matrixWidth < 50 {
+			matrixWidth = 50
+		}
+		panel := m.renderPanelWithTitle("Benchmarks", m.renderBenchMatrix(), matrixWidth)
+		if m.showSweep {
+			panel = m.renderPanelWithTitle("Benchmark Sweep", m.renderSweepView(matrixWidth-4), matrixWidth)
+		}
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, panel)
+	}
+
 	// Show help overlay if enabled
 	if m.showHelp {
-		helpContent := []string{
-			"Keyboard Shortcuts:",
-			"",
-			"  [enter]  Start server with selected model",
-			"  [s]      Stop the running server (press twice to confirm)",
-			"  [r]      Refresh/rescan models list",
-			"  [p]      Focus/unfocus port input",
-			"  [l]      Toggle file logging (applies on next start)",
-			"  [h]      Toggle this help overlay",
-			"  [esc]    Cancel confirmation, close help, or unfocus port",
-			"  [q]      Quit (press twice to confirm; stops server if running)",
-			"  [ctrl+c] Quit immediately (bypasses confirmation)",
-			"",
-			"Status Indicators:",
-			"  [RUNNING]  Server is active",
-			"  [STOPPING] Server shutdown in progress",
-			"  [STOPPED]  No server running",
-			"",
-			"Press [h] or [esc] to close this help",
-		}
-		helpText := strings.Join(helpContent, "\n")
+		helpText := m.renderHelpView()
 		helpWidth := m.width - 8
 		if helpWidth < 50 {
 			helpWidth = 50
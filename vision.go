@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const visionTestPrompt = "Describe this image in one short sentence. What shape and colors do you see?"
+
+// chatCompletionResponse is the subset of an OpenAI chat completion we read.
+type chatCompletionResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// sampleVisionImage draws a red circle on a white background, which any
+// working vision model should describe recognizably.
+func sampleVisionImage() ([]byte, error) {
+	const size = 128
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	red := color.RGBA{R: 220, G: 30, B: 30, A: 255}
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dx, dy := x-size/2, y-size/2
+			if dx*dx+dy*dy <= (size/3)*(size/3) {
+				img.Set(x, y, red)
+			} else {
+				img.Set(x, y, color.White)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// visionTestImageDataURL loads the configured image (or the generated
+// sample) as a data URL suitable for an image_url content part.
+func visionTestImageDataURL(path string) (string, error) {
+	if path == "" {
+		data, err := sampleVisionImage()
+		if err != nil {
+			return "", err
+		}
+		return "data:image/png;base64," + base64.StdEncoding.EncodeToString(data), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	mimeType := mime.TypeByExtension(strings.ToLower(filepath.Ext(path)))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// visionTestCmd sends an image plus prompt to the running server's chat
+// completions endpoint to verify the mmproj pairing works.
+func visionTestCmd(port, imagePath string) tea.Cmd {
+	return func() tea.Msg {
+		dataURL, err := visionTestImageDataURL(imagePath)
+		if err != nil {
+			return visionTestDoneMsg{err: fmt.Errorf("load image: %w", err)}
+		}
+		body, err := json.Marshal(map[string]any{
+			"messages": []map[string]any{{
+				"role": "user",
+				"content": []map[string]any{
+					{"type": "text", "text": visionTestPrompt},
+					{"type": "image_url", "image_url": map[string]string{"url": dataURL}},
+				},
+			}},
+			"max_tokens": 128,
+		})
+		if err != nil {
+			return visionTestDoneMsg{err: err}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		url := fmt.Sprintf("http://127.0.0.1:%s/v1/chat/completions", port)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return visionTestDoneMsg{err: err}
+		}
+		req.Header.Set("Content-Type", "application/json")
+		start := time.Now()
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return visionTestDoneMsg{err: err}
+		}
+		defer resp.Body.Close()
+		raw, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return visionTestDoneMsg{err: err}
+		}
+		var parsed chatCompletionResponse
+		if err := json.Unmarshal(raw, &parsed); err != nil {
+			return visionTestDoneMsg{err: fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(raw)))}
+		}
+		if parsed.Error != nil {
+			return visionTestDoneMsg{err: fmt.Errorf("server error: %s", parsed.Error.Message)}
+		}
+		if len(parsed.Choices) == 0 {
+			return visionTestDoneMsg{err: fmt.Errorf("%s: empty response", resp.Status)}
+		}
+		return visionTestDoneMsg{reply: strings.TrimSpace(parsed.Choices[0].Message.Content), elapsed: time.Since(start)}
+	}
+}
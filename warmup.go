@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// warmupChunk is one piece of the streamed warm-up reply.
+type warmupChunk struct {
+	text string
+	err  error
+}
+
+// waitForHealthy polls /health until it returns 200. The port opens before
+// the model finishes loading; /health only turns 200 once requests can
+// actually be served.
+func waitForHealthy(ctx context.Context, base string) error {
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/health", nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("server not healthy: %w", ctx.Err())
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// streamWarmup waits for the server to report healthy, then streams a chat
+// completion for prompt, after system when set, pushing content deltas into
+// out. out is closed when the stream ends. Once ctx is done, nothing may be
+// reading out any more, so a send only waits while it isn't.
+func streamWarmup(ctx context.Context, port, system, prompt string, maxTokens int, out chan<- warmupChunk) {
+	defer close(out)
+	base := "http://127.0.0.1:" + port
+	send := func(chunk warmupChunk) {
+		select {
+		case out <- chunk:
+		default:
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+			}
+		}
+	}
+
+	if err := waitForHealthy(ctx, base); err != nil {
+		send(warmupChunk{err: err})
+		return
+	}
+
+	messages := []map[string]string{{"role": "user", "content": prompt}}
+	if system != "" {
+		messages = append([]map[string]string{{"role": "system", "content": system}}, messages...)
+	}
+	body, err := json.Marshal(map[string]any{
+		"messages":   messages,
+		"max_tokens": maxTokens,
+		"stream":     true,
+	})
+	if err != nil {
+		send(warmupChunk{err: err})
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/v1/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		send(warmupChunk{err: err})
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		send(warmupChunk{err: err})
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		send(warmupChunk{err: fmt.Errorf("warm-up request failed: %s", resp.Status)})
+		return
+	}
+
+	// Server-sent events: "data: {json}" lines, terminated by "data: [DONE]"
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			return
+		}
+		var event struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+		}
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			continue
+		}
+		for _, c := range event.Choices {
+			if c.Delta.Content != "" {
+				send(warmupChunk{text: c.Delta.Content})
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		send(warmupChunk{err: err})
+	}
+}
+
+// startWarmupCmd launches the warm-up stream in the background, ended by
+// parent, the server's session, when the server stops.
+func startWarmupCmd(parent context.Context, port, system, prompt string, maxTokens int, out chan warmupChunk) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(parent, warmupTimeout)
+		go func() {
+			defer cancel()
+			streamWarmup(ctx, port, system, prompt, maxTokens, out)
+		}()
+		return nil
+	}
+}
+
+func waitForWarmupChunk(ch chan warmupChunk) tea.Cmd {
+	if ch == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		chunk, ok := <-ch
+		if !ok {
+			return warmupDoneMsg{ch: ch}
+		}
+		return warmupChunkMsg{ch: ch, chunk: chunk}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const defaultWatchdogSeconds = 180
+
+// watchdogPolicy flags a server that wedged without exiting.
+type watchdogPolicy struct {
+	// Seconds without an answer from /health, or without log output while
+	// requests are active, before the server counts as hung. Default 180;
+	// negative disables the watchdog.
+	Seconds int `json:"seconds"`
+}
+
+func (p watchdogPolicy) timeout() time.Duration {
+	switch {
+	case p.Seconds < 0:
+		return 0
+	case p.Seconds == 0:
+		return defaultWatchdogSeconds * time.Second
+	}
+	return time.Duration(p.Seconds) * time.Second
+}
+
+// Why the watchdog flagged the server.
+const (
+	hangNoHealth = "/health stopped answering"
+	hangNoLogs   = "no log output while requests are active"
+)
+
+// requestsActive reports work in progress, from the proxy or the server's
+// own slots and metrics.
+func (m appModel) requestsActive() bool {
+	return m.proxyInFlight > 0 || m.health.inFlight > 0 || m.health.slotsBusy > 0
+}
+
+// checkWatchdog runs after each health poll and flags or clears a hang.
+func (m *appModel) checkWatchdog() {
+	timeout := m.config.Watchdog.timeout()
+	if timeout <= 0 || m.server != serverReady || m.powerPaused {
+		m.hung = ""
+		return
+	}
+	reason := ""
+	var since time.Time
+	switch {
+	case time.Since(m.lastHealthAnswer) > timeout:
+		reason, since = hangNoHealth, m.lastHealthAnswer
+	case m.attached == nil && m.requestsActive() && time.Since(m.lastServerLogAt) > timeout:
+		// An attached server's log reaches us only through --attach-log
+		reason, since = hangNoLogs, m.lastServerLogAt
+	}
+	if reason == m.hung {
+		return
+	}
+	was := m.hung
+	m.hung = reason
+	switch {
+	case reason == "":
+		m.logEvent("[watchdog] Server is responding again")
+		m.statusLineText = "Server is responding again"
+	case was == "":
+		detail := fmt.Sprintf("%s (for %s)", reason, time.Since(since).Round(time.Second))
+		m.logEvent("[watchdog] Server looks hung: " + detail)
+		m.statusLineText = "Server looks hung: " + detail
+		if m.attached == nil {
+			m.statusLineText += " - [R] restart it"
+		}
+	}
+}
+
+// restartHung stops a hung server and launches the same model, port, and
+// options again once it has exited.
+func (m appModel) restartHung() (appModel, tea.Cmd) {
+	m.logEvent("[watchdog] Restarting " + m.currentModelName)
+	return m.restartServer()
+}
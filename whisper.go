@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// modelKind is the server a model is launched with.
+type modelKind int
+
+const (
+	kindLLM     modelKind = iota // GGUF, served by llama-server
+	kindWhisper                  // whisper.cpp ggml speech model, served by whisper-server
+)
+
+// whisperFilePattern matches whisper.cpp's model names: ggml-base.en.bin,
+// ggml-large-v3-q5_0.bin.
+var whisperFilePattern = regexp.MustCompile(`(?i)^ggml-.+\.bin$`)
+
+// whisperModelSizes names a model by its encoder depth.
+var whisperModelSizes = map[int32]string{4: "tiny", 6: "base", 12: "small", 24: "medium", 32: "large"}
+
+// serverName is the server binary's name, for messages.
+func (k modelKind) serverName() string {
+	if k == kindWhisper {
+		return "whisper-server"
+	}
+	return "llama-server"
+}
+
+// serverBinary resolves the binary that serves models of kind k.
+func (k modelKind) serverBinary() (string, error) {
+	if k == kindWhisper {
+		return getWhisperServerBinary()
+	}
+	return getLlamaServerBinary()
+}
+
+// healthPath is the endpoint the http readiness probe asks; whisper-server
+// has no /health but serves its page once the model has loaded.
+func (k modelKind) healthPath() string {
+	if k == kindWhisper {
+		return "/"
+	}
+	return "/health"
+}
+
+func getWhisperServerBinary() (string, error) {
+	if envPath := strings.TrimSpace(os.Getenv("WHISPER_SERVER_BIN")); envPath != "" {
+		if info, err := os.Stat(envPath); err == nil && !info.IsDir() {
+			return envPath, nil
+		}
+		return "", fmt.Errorf("WHISPER_SERVER_BIN points to an invalid path: %q", envPath)
+	}
+	bin, err := exec.LookPath("whisper-server")
+	if err != nil {
+		return "", fmt.Errorf("whisper-server not found in PATH. Install whisper.cpp (e.g., brew install whisper-cpp) or set WHISPER_SERVER_BIN to its absolute path")
+	}
+	return bin, nil
+}
+
+// whisperHeader is the start of a whisper.cpp model file.
+type whisperHeader struct {
+	Magic      uint32
+	Vocab      int32
+	AudioCtx   int32
+	AudioState int32
+	AudioHead  int32
+	AudioLayer int32
+	TextCtx    int32
+	TextState  int32
+	TextHead   int32
+	TextLayer  int32
+	Mels       int32
+	FileType   int32
+}
+
+// ggmlMagic is "ggml" as whisper.cpp writes it.
+const ggmlMagic = 0x67676d6c
+
+// readWhisperHeader reads path's hyperparameters, failing for anything that
+// isn't a whisper.cpp model.
+func readWhisperHeader(path string) (whisperHeader, error) {
+	var h whisperHeader
+	f, err := os.Open(path)
+	if err != nil {
+		return h, err
+	}
+	defer f.Close()
+	if err := binary.Read(f, binary.LittleEndian, &h); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+			return h, fmt.Errorf("%s: too short for a whisper model", filepath.Base(path))
+		}
+		return h, err
+	}
+	if h.Magic != ggmlMagic {
+		return h, fmt.Errorf("%s: not a whisper.cpp model", filepath.Base(path))
+	}
+	return h, nil
+}
+
+// isWhisperModelFile reports whether path, found while scanning, is a
+// whisper.cpp model.
+func isWhisperModelFile(path string) bool {
+	if !whisperFilePattern.MatchString(filepath.Base(path)) {
+		return false
+	}
+	_, err := readWhisperHeader(path)
+	return err == nil
+}
+
+// enrichWhisperItem fills in what the header tells about a whisper model.
+func enrichWhisperItem(item modelItem) modelItem {
+	item.arch = "whisper"
+	h, err := readWhisperHeader(item.path)
+	if err != nil {
+		return item
+	}
+	if item.params == "" {
+		item.params = whisperModelSizes[h.AudioLayer]
+	}
+	if item.quant == "" {
+		// Quantized files add the quantization version times 1000
+		item.quant = ggufFileTypeName(uint64(h.FileType % 1000))
+	}
+	return item
+}
+
+// launchCommand is the command line serving item on port with bin:
+// llama-server gets the launch options with the metadata overrides, flag
+// fixes, and -hf; whisper-server gets the model's options as they are.
+func (m appModel) launchCommand(item modelItem, bin, port string) ([]string, error) {
+	if item.kind == kindWhisper {
+		return m.config.buildWhisperCommand(bin, item.path, port, m.argsFor(item.name))
+	}
+	argv, err := m.config.buildServerCommand(bin, item.path, item.mmproj, port, withSamplingFlags(item.name, withKVOverrides(item.name, m.argsFor(item.name))))
+	if err != nil {
+		return nil, err
+	}
+	return applyFlagFixes(withHFRepo(argv, item), m.flagFixes), nil
+}
+
+// servingWhisper reports whether the managed server is whisper-server,
+// which has none of llama-server's API.
+func (m appModel) servingWhisper() bool {
+	item, ok := m.findModelByName(m.currentModelName)
+	return ok && item.kind == kindWhisper
+}
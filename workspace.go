@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultWorkspace names the top-level configuration in the picker.
+const defaultWorkspace = "(default)"
+
+// workspaceConfig is a named set of models and presets, e.g. "work" and
+// "personal", with its own history.
+type workspaceConfig struct {
+	// BarnDir is the models directory; ~/.llamabarn when empty.
+	BarnDir string `json:"barn_dir"`
+	// Presets are added to the top-level presets, replacing any of the
+	// same name.
+	Presets map[string]launchPreset `json:"presets"`
+}
+
+// historyDir holds a workspace's history files (pins, sessions, last
+// launch, benchmarks, Hugging Face repos, smoke tests); workspace "" is the
+// default. The app keeps its workspace's directory in appModel.historyDir
+// and hands it to each command, so a command still running after a switch
+// writes to the workspace it was started in.
+func historyDir(workspace string) string {
+	stateDir := appStateDir()
+	if stateDir == "" || workspace == "" {
+		return stateDir
+	}
+	return filepath.Join(stateDir, "workspaces", sanitizeFileComponent(workspace))
+}
+
+// workspaceNames lists the configured workspaces in order.
+func (c appConfig) workspaceNames() []string {
+	names := make([]string, 0, len(c.Workspaces))
+	for name := range c.Workspaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkWorkspace reports an unknown workspace name, listing the known ones.
+func (c appConfig) checkWorkspace(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, ok := c.Workspaces[name]; ok {
+		return nil
+	}
+	if len(c.Workspaces) == 0 {
+		return fmt.Errorf("no workspaces are configured (add a \"workspaces\" object to the config file)")
+	}
+	return fmt.Errorf("unknown workspace %q (configured: %s)", name, strings.Join(c.workspaceNames(), ", "))
+}
+
+// inWorkspace is the configuration as seen from workspace name, with its
+// models directory (defaultBarn unless it sets one).
+func (c appConfig) inWorkspace(name, homeDir, defaultBarn string) (appConfig, string, error) {
+	ws, ok := c.Workspaces[name]
+	if !ok {
+		return c, defaultBarn, nil
+	}
+	if len(ws.Presets) > 0 {
+		presets := make(map[string]launchPreset, len(c.Presets)+len(ws.Presets))
+		for k, v := range c.Presets {
+			presets[k] = v
+		}
+		for k, v := range ws.Presets {
+			presets[k] = v
+		}
+		c.Presets = presets
+	}
+	barn := defaultBarn
+	if dir := strings.TrimSpace(ws.BarnDir); dir != "" {
+		dir, err := expandHome(homeDir, dir)
+		if err != nil {
+			return c, defaultBarn, fmt.Errorf("workspace %s: %w", name, err)
+		}
+		barn = filepath.Clean(dir)
+	}
+	return c, barn, nil
+}
+
+// openWorkspacePicker offers the configured workspaces in a form.
+func (m appModel) openWorkspacePicker() (appModel, tea.Cmd) {
+	if len(m.config.Workspaces) == 0 {
+		m.statusLineText = "No workspaces configured - add \"workspaces\" to " + m.configPath
+		return m, nil
+	}
+	choices := append([]string{defaultWorkspace}, m.config.workspaceNames()...)
+	current := m.workspace
+	if current == "" {
+		current = defaultWorkspace
+	}
+	m.portInput.Blur()
+	form := newForm("Workspace", []formField{
+		newChoiceField("workspace", "Workspace", "Models directory, presets, and history to switch to", choices, current),
+	})
+	m.form, m.formPurpose = &form, formWorkspace
+	return m, nil
+}
+
+// switchWorkspace reloads the models directory, presets, and history for
+// workspace name ("" for the default).
+func (m appModel) switchWorkspace(name string) (appModel, tea.Cmd) {
+	if m.server.busy() || m.standby != nil || m.attached != nil {
+		m.statusLineText = "Stop the server before switching workspaces"
+		return m, nil
+	}
+	cfg, err := loadConfig(m.configPath)
+	if err != nil {
+		m.statusLineText = fmt.Sprintf("Config error: %v", err)
+		return m, nil
+	}
+	if err := cfg.checkWorkspace(name); err != nil {
+		m.statusLineText = err.Error()
+		return m, nil
+	}
+	defaultBarn, _ := getDefaultBarnDir()
+	cfg, barn, err := cfg.inWorkspace(name, m.homeDir, defaultBarn)
+	if err != nil {
+		m.statusLineText = err.Error()
+		return m, nil
+	}
+	m.workspace, m.historyDir = name, historyDir(name)
+	m.config = cfg
+	migrate := m.setBarnDir(barn)
+
+	m.statusLineText = "Switched to workspace " + m.workspaceLabel()
+	m.pins, err = loadPins(m.historyDir)
+	if err != nil {
+		m.statusLineText = fmt.Sprintf("Pinned models unavailable: %v", err)
+	}
+	m.hfRepos, err = loadHFRepos(m.historyDir)
+	if err != nil {
+		m.statusLineText = fmt.Sprintf("Hugging Face repos unavailable: %v", err)
+	}
+	m.smokeResults, err = loadSmokeResults(m.historyDir)
+	if err != nil {
+		m.statusLineText = fmt.Sprintf("Smoke test results unavailable: %v", err)
+	}
+	m.sessions = nil
+	m.modelsList.SetDelegate(m.listDelegate())
+	m.logEvent("[workspace] " + m.workspaceLabel() + ": models in " + m.barnDir)
+	return m, tea.Batch(
+		m.scanModelsCmd(),
+		loadBenchResultsCmd(m.historyDir),
+		loadBenchSweepsCmd(m.historyDir),
+		loadLicenseAcksCmd(m.historyDir),
+		pruneLogsCmd(m.logsDir, m.config.LogRetention),
+		migrate,
+	)
+}
+
+// workspaceLabel names the active workspace for the UI.
+func (m appModel) workspaceLabel() string {
+	if m.workspace == "" {
+		return defaultWorkspace
+	}
+	return m.workspace
+}